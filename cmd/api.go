@@ -7,31 +7,72 @@
 package cmd
 
 import (
+	"container/list"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/swaggo/swag"
 	"github.com/valyala/fasthttp"
+	"google.golang.org/grpc"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/informers"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	// Import the docs package to ensure Swagger docs are registered
 	_ "github.com/obezsmertnyi/k8s-custom-controller/docs"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/audit"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/auth"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/capacity"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/circuit"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/cors"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/csrf"
 	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ctrl"
-	"github.com/obezsmertnyi/k8s-custom-controller/pkg/informer"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/debugcapture"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/drain"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/deprecation"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/drift"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/endpoints"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/exposure"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/history"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/hooksig"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/listcache"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/loadshed"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/lockout"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/middleware"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/migrate"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ownership"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/podaudit"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/precompute"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/rawapi"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/recommend"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/redact"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/report"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/rollout"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/secheaders"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/stdhttp"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/stream"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/vulnscan"
 )
 
 // apiServer holds the Kubernetes client and informer factory for API handlers
@@ -41,103 +82,86 @@ type apiServer struct {
 	config          *Config // Reference to application config for API settings
 	// Multi-cluster deployment controller manager
 	multiClusterManager *ctrl.MultiClusterManager
+	// Authentication and role-based authorization, nil when disabled
+	authorizer *auth.Authorizer
+	lockout    *lockout.Guard  // Failed-auth lockouts and brute-force alerts, nil when disabled
+	auditor    *audit.Logger   // Audit log for mutating requests, nil when disabled
+	csrf       *csrf.Protector // Double-submit CSRF protection, nil when disabled
+
+	hookVerifier *hooksig.Verifier // Signature checks of inbound webhooks, nil when disabled
+
+	dynamicClient dynamic.Interface // Primary cluster client for resources without typed clients, nil unless /raw is enabled
+	rawAllowlist  *rawapi.Allowlist // Resources served under /raw, nil when disabled
+	endpoints     *endpoints.Policy // Route groups removed by api_server.endpoints, nil when none are
+
+	securityHeaders *secheaders.Policy     // CSP, HSTS and related headers, nil keeps only the legacy headers
+	corsPolicy      *cors.Policy           // Cross-origin requests allowed by swagger_ui.cors_*, nil when disabled
+	shedder         *loadshed.Shedder      // Load shedding by route priority, nil when disabled
+	listCache       *listcache.Cache       // Short-lived cache of list calls, nil when disabled
+	listers         *primaryListers        // Pod, service and node listers of the primary cluster, nil without informer
+	debugCapture    *debugcapture.Recorder // Request and response capture for debugging, nil when disabled
+	drain           *drain.Tracker         // In-flight requests and streams waited for on shutdown
+
+	imageScanner       *vulnscan.Scanner       // Trivy image scanner, nil when disabled
+	driftDetector      *drift.Detector         // Desired-state drift detection, nil when disabled
+	exposurePolicy     *exposure.Policy        // Policies classifying node and load balancer exposure
+	workloadAuditor    *podaudit.Auditor       // Checks of pod specs for privileged workloads
+	deprecations       *deprecation.Table      // Deprecated APIs reported at /deprecations
+	stuckRollouts      *rollout.Detector       // Stuck rollout detection, nil when disabled
+	recommender        *recommend.Recommender  // Resource right-sizing recommendations, nil when disabled
+	capacityForecaster *capacity.Forecaster    // Capacity trend forecasting, nil when disabled
+	history            *history.Store          // Replica and node readiness history, nil when disabled
+	precomputed        *precompute.Cache       // Precomputed cross-cluster aggregations, nil when disabled
+	breaker            *circuit.Breaker        // Circuit breaking of clusters behind aggregated endpoints, nil when disabled
+	migrations         *migrate.Manager        // Deployment migrations between clusters run as jobs
+	standby            *migrate.Standby        // Mirroring into a warm standby cluster, nil unless enabled
+	reports            *report.Scheduler       // Scheduled report generation and delivery, nil when disabled
+	watchSources       map[string]*watchSource // Informer events for watch streams by kind, empty without informer
+	wsConnections      atomic.Int64            // Open WebSocket connections
+	firehose           *stream.Broadcaster     // Events of every managed cluster, nil when disabled
+	firehoseStreams    atomic.Int64            // Open firehose streams
+	execSessions       atomic.Int64            // Open exec sessions
+	portForwards       atomic.Int64            // Open port-forward tunnels and proxied requests
+	restConfig         *rest.Config            // Primary cluster REST config for exec and port-forward streams, nil unless enabled
 	// Request rate limiter
-	ipLimiter         *perIPLimiter // Per-IP rate limiter
+	ipLimiter         *perIPLimiter     // Per-IP rate limiter
+	routeLimiter      *routeRateLimiter // Per-route, per-IP rate limiters
 	requestLimiter    *time.Ticker  // Legacy global rate limiter (deprecated)
 	requestLimiterMux sync.Mutex    // Mutex to protect rate limiter initialization
+
+	router     *apiRouter // Built-in endpoints, built on first request
+	routerOnce sync.Once
+
+	chain     *middleware.Chain       // Middleware stages, built on first request
+	chained   fasthttp.RequestHandler // Endpoints wrapped in the chain
+	chainErr  error
+	chainOnce sync.Once
 }
 
-// requestHandler processes HTTP requests with logging
+// requestHandler serves a request through the middleware chain. The chain fails to build
+// only when a middleware plugin names an unknown stage, which StartAPIServer refuses.
 func (s *apiServer) requestHandler(ctx *fasthttp.RequestCtx) {
-	start := time.Now()
-	method := string(ctx.Method())
-	path := string(ctx.Path())
-	clientIP := ctx.RemoteIP().String()
-
-	// Generate unique request ID
-	requestID := uuid.New().String()
-	ctx.Response.Header.Set("X-Request-ID", requestID)
-
-	// Create logger with request ID
-	logger := log.With().Str("request_id", requestID).Logger()
-
-	// Apply rate limiting based on configuration
-	if s.config != nil && s.config.APIServer.Security.RateLimitRequestsPerSecond > 0 {
-		if !s.checkRateLimit(clientIP, logger) {
-			ctx.SetStatusCode(fasthttp.StatusTooManyRequests)
-			ctx.SetBodyString(`{"error": "Rate limit exceeded", "retry_after": "1s"}`)
-			logger.Warn().Str("client_ip", clientIP).Int("limit", s.config.APIServer.Security.RateLimitRequestsPerSecond).Msg("Rate limit exceeded")
-			return
-		}
+	defer s.drain.TrackRequest()()
+	if s.drain.Draining() {
+		// Send keep-alive clients to other replicas while the server drains
+		ctx.SetConnectionClose()
 	}
-
-	// Set security headers
-	ctx.Response.Header.Set("X-Content-Type-Options", "nosniff")
-	ctx.Response.Header.Set("X-Frame-Options", "DENY")
-
-	// Log request details
-	logger.Debug().Str("method", method).Str("path", path).Str("client", clientIP).Msg("Request received")
-
-	// Set content type for JSON responses
-	ctx.SetContentType("application/json; charset=utf8")
-
-	// Route handling based on path
-	switch {
-	case strings.HasPrefix(string(ctx.Path()), "/swagger/") || string(ctx.Path()) == "/swagger.json" || string(ctx.Path()) == "/swagger":
-		if s.config != nil && s.config.APIServer.EnableSwagger {
-			path := string(ctx.Path())
-
-			// Handle Swagger JSON endpoint
-			if path == "/swagger/swagger.json" || path == "/swagger.json" {
-				s.handleSwaggerJSON(ctx)
-				return
-			}
-
-			// Handle Swagger UI - redirect both /swagger and /swagger/ to index.html
-			if path == "/swagger/" || path == "/swagger" {
-				// Redirect to index.html
-				ctx.Redirect("/swagger/index.html", fasthttp.StatusFound)
-				return
-			}
-
-			// For Swagger index.html - serve UI
-			if path == "/swagger/index.html" {
-				s.serveSwaggerUI(ctx)
-				return
-			}
-		} else {
-			// Swagger is disabled
-			ctx.SetStatusCode(fasthttp.StatusNotFound)
-			json.NewEncoder(ctx).Encode(map[string]string{"error": "Not found"})
-		}
-	case string(ctx.Path()) == "/health":
-		s.handleHealth(ctx)
-	case string(ctx.Path()) == "/clusters":
-		s.handleClusters(ctx)
-	case string(ctx.Path()) == "/deployments":
-		s.handleDeployments(ctx)
-	case string(ctx.Path()) == "/pods":
-		s.handlePods(ctx)
-	case string(ctx.Path()) == "/services":
-		s.handleServices(ctx)
-	case string(ctx.Path()) == "/nodes":
-		s.handleNodes(ctx)
-	default:
-		// Handle unknown paths
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
-		json.NewEncoder(ctx).Encode(map[string]string{"error": "Not found"})
+	handler, err := s.handler()
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		ctx.SetContentType("application/json; charset=utf8")
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
 	}
-
-	// Log response details at info level
-	latency := time.Since(start)
-	statusCode := ctx.Response.StatusCode()
-	logger.Info().Str("method", method).Str("path", path).Int("status", statusCode).Dur("latency", latency).Msg("Request completed")
+	handler(ctx)
 }
 
 // @Summary Get API server health status
-// @Description Returns health status of the API server and Kubernetes connection state
+// @Description Returns health status of the API server, its build version and the Kubernetes connection state.
+// @Description With verbose=true, every registered cluster is asked for its version and the response lists each cluster's connectivity and latency; status is degraded while any is unreachable.
 // @Tags system
 // @Produce json
+// @Param verbose query bool false "Ping every cluster and report per-cluster connectivity"
 // @Success 200 {object} map[string]interface{}
 // @Router /health [get]
 func (s *apiServer) handleHealth(ctx *fasthttp.RequestCtx) {
@@ -145,10 +169,17 @@ func (s *apiServer) handleHealth(ctx *fasthttp.RequestCtx) {
 	logger := log.With().Str("request_id", string(ctx.Response.Header.Peek("X-Request-ID"))).Logger()
 	logger.Info().Msg("Health check request received")
 
+	build := buildVersion()
 	response := map[string]interface{}{
 		"status":  "ok",
 		"time":    time.Now().Format(time.RFC3339),
-		"version": "1.0.0", // Assuming you have a GetVersion function or constant
+		"version": build.Version,
+	}
+	if build.GitCommit != "" {
+		response["git_commit"] = build.GitCommit
+	}
+	if build.BuildDate != "" {
+		response["build_date"] = build.BuildDate
 	}
 
 	// Add Kubernetes client status if available
@@ -158,6 +189,28 @@ func (s *apiServer) handleHealth(ctx *fasthttp.RequestCtx) {
 		response["kubernetes_connected"] = false
 	}
 
+	// Verbose checks reach out to every cluster instead of only checking for a client
+	if ctx.QueryArgs().GetBool("verbose") {
+		clusters, err := s.pingClusters(requestContext(ctx))
+		if err != nil {
+			clusters = []clusterHealth{}
+			response["clusters_error"] = err.Error()
+		}
+		connected := 0
+		for _, cluster := range clusters {
+			if cluster.Connected {
+				connected++
+			} else {
+				logger.Warn().Str("cluster_id", cluster.ClusterID).Str("error", cluster.Error).Msg("Cluster is unreachable")
+			}
+		}
+		if err != nil || connected < len(clusters) {
+			response["status"] = "degraded"
+		}
+		response["clusters"] = clusters
+		response["clusters_connected"] = connected
+	}
+
 	ctx.SetStatusCode(fasthttp.StatusOK)
 	json.NewEncoder(ctx).Encode(response)
 }
@@ -175,8 +228,13 @@ type DeploymentCreateRequest struct {
 // @Summary Manage Kubernetes deployments
 // @Description Get, create and delete Kubernetes deployments
 // @Tags kubernetes,deployments
-// @Accept json
+// @Accept json,yaml
 // @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=web,tier!=cache"
+// @Param fieldSelector query string false "Field selector, e.g. metadata.name=web"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -188,23 +246,24 @@ func (s *apiServer) handleDeployments(ctx *fasthttp.RequestCtx) {
 	
 	logger.Info().Str("method", method).Msg("Deployments request received")
 
-	// Check if Kubernetes client is available
-	if !s.checkKubeClient(ctx, logger) {
+	// Select the target cluster and check that its client is available
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
 		return
 	}
 
 	switch method {
 	case "GET":
 		// Handle GET request for listing deployments
-		s.handleDeploymentsGet(ctx, logger)
+		s.handleDeploymentsGet(ctx, logger, target)
 		
 	case "POST":
 		// Handle POST request for creating deployments
-		s.handleDeploymentsPost(ctx, logger)
+		s.handleDeploymentsPost(ctx, logger, target)
 		
 	case "DELETE":
 		// Handle DELETE request for removing deployments
-		s.handleDeploymentsDelete(ctx, logger)
+		s.handleDeploymentsDelete(ctx, logger, target)
 		
 	default:
 		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
@@ -213,33 +272,44 @@ func (s *apiServer) handleDeployments(ctx *fasthttp.RequestCtx) {
 }
 
 // Handle GET request for listing deployments
-func (s *apiServer) handleDeploymentsGet(ctx *fasthttp.RequestCtx, logger zerolog.Logger) {
+func (s *apiServer) handleDeploymentsGet(ctx *fasthttp.RequestCtx, logger zerolog.Logger, target *clusterTarget) {
 	// Get namespace from query parameter
 	namespace := getNamespaceFromQuery(ctx)
 
-	// Try to get deployments from informer cache first
+	// Get paging parameters from query
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+	var listMeta metav1.ListMeta
+
+	// Try to get deployments from informer cache first; paged requests go to the API
+	// so that the Kubernetes continue token can be propagated
 	var deployments []*appsv1.Deployment
 	var source string = "informer-cache"
-	var err error
-
-	if s.informerFactory != nil {
-		// Get deployment informer
-		deploymentInformer := s.informerFactory.Apps().V1().Deployments().Informer()
 
-		// Get deployments from cache
-		deployments, err = informer.ListDeploymentsInCache(deploymentInformer, namespace)
+	if !isPaginated(listOpts) {
+		// Get deployments from the target cluster's cache, applying the same selectors the API would
+		deployments, err = s.cachedDeployments(target, namespace, listOpts)
 		if err != nil {
 			logger.Warn().Err(err).Str("namespace", namespace).Msg("Failed to list deployments from cache, falling back to direct API")
 			// Reset to try direct API approach
 			deployments = nil
 		}
+		// Lists over the response item limit are paged by the API so they carry a continue token
+		if exceedsItemLimit(ctx, len(deployments)) {
+			deployments = nil
+		}
 	}
 
 	// If informer cache is empty or not available, query directly from the Kubernetes API
 	if len(deployments) == 0 {
 		source = "direct-api"
 		// Query Kubernetes API directly
-		deploymentList, err := s.clientset.AppsV1().Deployments(namespace).List(context.Background(), metav1.ListOptions{})
+		deploymentList, err := target.Client.AppsV1().Deployments(namespace).List(requestContext(ctx), limitedOptions(ctx, listOpts))
+		if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+			return
+		}
 		if err != nil {
 			logger.Error().Err(err).Str("namespace", namespace).Msg("Failed to list deployments from API")
 			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
@@ -253,10 +323,25 @@ func (s *apiServer) handleDeploymentsGet(ctx *fasthttp.RequestCtx, logger zerolo
 		for i := range deploymentList.Items {
 			deployments = append(deployments, &deploymentList.Items[i])
 		}
+		listMeta = deploymentList.ListMeta
 	}
 
 	logger.Info().Int("count", len(deployments)).Str("namespace", namespace).Msg("Deployments retrieved from " + source)
 
+	// Paging metadata; also sets the continue token header for simple responses
+	pageMetadata := paginationMetadata(ctx, listOpts, listMeta, len(deployments))
+
+	// Let polling clients skip unchanged lists; pages carry continue tokens that change anyway
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(deployments))
+		for _, d := range deployments {
+			objects = append(objects, d)
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ID, objects)) {
+			return
+		}
+	}
+
 	// Set response headers
 	ctx.Response.Header.Set("Content-Type", "application/json")
 	ctx.SetStatusCode(fasthttp.StatusOK)
@@ -278,11 +363,13 @@ func (s *apiServer) handleDeploymentsGet(ctx *fasthttp.RequestCtx, logger zerolo
 
 	// Full detailed response (default)
 	response := map[string]interface{}{
+		"cluster":   target.ID,
 		"namespace": namespace,
 		"count":     len(deployments),
 		"source":    source,           // Indicate data source
 		"names":     names,            // Simple names array
 		"items":     []interface{}{},  // Detailed items
+		"metadata":  pageMetadata,     // Paging information
 	}
 
 	// Add detailed deployment items
@@ -300,104 +387,46 @@ func (s *apiServer) handleDeploymentsGet(ctx *fasthttp.RequestCtx, logger zerolo
 	json.NewEncoder(ctx).Encode(response)
 }
 
-// Handle POST request for creating deployments
-func (s *apiServer) handleDeploymentsPost(ctx *fasthttp.RequestCtx, logger zerolog.Logger) {
-	// Parse JSON from request body
-	var req DeploymentCreateRequest
-	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+// Handle POST request for creating deployments from a manifest or a simplified body
+func (s *apiServer) handleDeploymentsPost(ctx *fasthttp.RequestCtx, logger zerolog.Logger, target *clusterTarget) {
+	deployment, err := decodeDeployment(ctx.PostBody())
+	if err != nil {
 		logger.Error().Err(err).Msg("Failed to parse request body")
 		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		ctx.SetBodyString(`{"error": "Invalid JSON in request body"}`)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Invalid request body: %s", err)})
 		return
 	}
 
-	// Validate required fields
-	if req.Name == "" {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		ctx.SetBodyString(`{"error": "Name is required"}`)
-		return
+	// The body's namespace wins over ?namespace=
+	if deployment.Namespace == "" {
+		deployment.Namespace = getNamespaceFromQuery(ctx)
 	}
-	if req.Image == "" {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		ctx.SetBodyString(`{"error": "Image is required"}`)
-		return
-	}
-
-	// Set default values
-	if req.Namespace == "" {
-		req.Namespace = "default"
-	}
-	if req.Replicas == 0 {
-		req.Replicas = 1
-	}
-	if req.Port == 0 {
-		req.Port = 80
-	}
-
-	// Create labels
-	labels := req.Labels
-	if labels == nil {
-		labels = make(map[string]string)
-	}
-	// Add standard label
-	labels["app"] = req.Name
-
-	// Create Deployment object
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      req.Name,
-			Namespace: req.Namespace,
-			Labels:    labels,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &req.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": req.Name,
-				},
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app": req.Name,
-					},
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  req.Name,
-							Image: req.Image,
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: req.Port,
-								},
-							},
-						},
-					},
-				},
-			},
-		},
+	if deployment.Namespace == "" {
+		deployment.Namespace = "default"
 	}
 
 	// Create deployment in Kubernetes
-	created, err := s.clientset.AppsV1().Deployments(req.Namespace).Create(
-		context.Background(), 
-		deployment, 
+	created, err := target.Client.AppsV1().Deployments(deployment.Namespace).Create(
+		requestContext(ctx),
+		deployment,
 		metav1.CreateOptions{},
 	)
 	if err != nil {
 		logger.Error().Err(err).
-			Str("name", req.Name).
-			Str("namespace", req.Namespace).
+			Str("name", deployment.Name).
+			Str("namespace", deployment.Namespace).
 			Msg("Failed to create deployment")
-		
-		// Check if this is an "already exists" error
-		if strings.Contains(err.Error(), "already exists") {
+
+		switch {
+		case apierrors.IsAlreadyExists(err):
 			ctx.SetStatusCode(fasthttp.StatusConflict)
 			ctx.SetBodyString(`{"error": "Deployment already exists"}`)
-		} else {
+		case apierrors.IsInvalid(err):
+			ctx.SetStatusCode(fasthttp.StatusUnprocessableEntity)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		default:
 			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
-			ctx.SetBodyString(fmt.Sprintf(`{"error": "Failed to create deployment: %s"}`, err.Error()))
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to create deployment: %s", err)})
 		}
 		return
 	}
@@ -405,12 +434,12 @@ func (s *apiServer) handleDeploymentsPost(ctx *fasthttp.RequestCtx, logger zerol
 	logger.Info().
 		Str("name", created.Name).
 		Str("namespace", created.Namespace).
-		Str("image", req.Image).
-		Int32("replicas", req.Replicas).
+		Strs("images", deploymentImages(created)).
 		Msg("Deployment created successfully")
 
 	// Return created deployment
 	response := map[string]interface{}{
+		"cluster":   target.ID,
 		"name":      created.Name,
 		"namespace": created.Namespace,
 		"uid":       string(created.UID),
@@ -423,7 +452,7 @@ func (s *apiServer) handleDeploymentsPost(ctx *fasthttp.RequestCtx, logger zerol
 }
 
 // Handle DELETE request for removing deployments
-func (s *apiServer) handleDeploymentsDelete(ctx *fasthttp.RequestCtx, logger zerolog.Logger) {
+func (s *apiServer) handleDeploymentsDelete(ctx *fasthttp.RequestCtx, logger zerolog.Logger, target *clusterTarget) {
 	// Get deployment name from query parameters
 	name := string(ctx.QueryArgs().Peek("name"))
 	if name == "" {
@@ -437,11 +466,18 @@ func (s *apiServer) handleDeploymentsDelete(ctx *fasthttp.RequestCtx, logger zer
 		namespace = "default"
 	}
 
+	opts, err := deleteOptionsFromQuery(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
 	// Delete the deployment
-	err := s.clientset.AppsV1().Deployments(namespace).Delete(
-		context.Background(),
+	err = target.Client.AppsV1().Deployments(namespace).Delete(
+		requestContext(ctx),
 		name,
-		metav1.DeleteOptions{},
+		opts,
 	)
 	
 	if err != nil {
@@ -466,6 +502,7 @@ func (s *apiServer) handleDeploymentsDelete(ctx *fasthttp.RequestCtx, logger zer
 		Msg("Deployment deleted successfully")
 
 	response := map[string]interface{}{
+		"cluster":   target.ID,
 		"name":      name,
 		"namespace": namespace,
 		"message":   "Deployment deleted successfully",
@@ -479,7 +516,14 @@ func (s *apiServer) handleDeploymentsDelete(ctx *fasthttp.RequestCtx, logger zer
 // @Description Returns list of Kubernetes pods across all connected clusters
 // @Tags kubernetes,pods
 // @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=web,tier!=cache"
+// @Param fieldSelector query string false "Field selector, e.g. metadata.name=web"
+// @Param source query string false "cache (default) serves the primary cluster from the informer cache when it covers the request; api always lists from the API server"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /pods [get]
 func (s *apiServer) handlePods(ctx *fasthttp.RequestCtx) {
@@ -487,16 +531,32 @@ func (s *apiServer) handlePods(ctx *fasthttp.RequestCtx) {
 	logger := getRequestLogger(ctx)
 	logger.Info().Msg("Pods request received")
 
-	// Check if Kubernetes client is available
-	if !s.checkKubeClient(ctx, logger) {
+	// Select the target cluster and check that its client is available
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
 		return
 	}
 
 	// Get namespace from query parameter
 	namespace := getNamespaceFromQuery(ctx)
 
-	// Get pods directly from Kubernetes API
-	pods, err := s.clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
+	// Get paging parameters from query
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	// Get pods from the informer cache when it covers the request, the Kubernetes API otherwise
+	pods, source, err := listFromCacheOrAPI(s, ctx, target, listOpts, func(l *primaryListers) (*corev1.PodList, bool, error) {
+		return l.cachedPods(namespace, listOpts)
+	}, func() (*corev1.PodList, error) {
+		return cachedList(s, ctx, "/pods", target.ID, namespace, listOpts, func(c context.Context, opts metav1.ListOptions) (*corev1.PodList, error) {
+			return target.Client.CoreV1().Pods(namespace).List(c, opts)
+		})
+	})
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
 	if err != nil {
 		logger.Error().Err(err).Str("namespace", namespace).Msg("Failed to list pods")
 		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
@@ -506,7 +566,21 @@ func (s *apiServer) handlePods(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	logger.Info().Int("count", len(pods.Items)).Str("namespace", namespace).Msg("Pods retrieved")
+	logger.Info().Int("count", len(pods.Items)).Str("namespace", namespace).Str("source", source).Msg("Pods retrieved")
+
+	// Paging metadata; also sets the continue token header for simple responses
+	pageMetadata := paginationMetadata(ctx, listOpts, pods.ListMeta, len(pods.Items))
+
+	// Let polling clients skip unchanged lists
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(pods.Items))
+		for i := range pods.Items {
+			objects = append(objects, &pods.Items[i])
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ID, objects)) {
+			return
+		}
+	}
 
 	// Set response headers
 	ctx.Response.Header.Set("Content-Type", "application/json")
@@ -529,11 +603,13 @@ func (s *apiServer) handlePods(ctx *fasthttp.RequestCtx) {
 
 	// Full detailed response
 	response := map[string]interface{}{
+		"cluster":   target.ID,
 		"namespace": namespace,
 		"count":     len(pods.Items),
-		"source":    "kubernetes-api",
+		"source":    source,
 		"names":     names,
 		"items":     []interface{}{},
+		"metadata":  pageMetadata,
 	}
 
 	// Add detailed pod items
@@ -557,7 +633,14 @@ func (s *apiServer) handlePods(ctx *fasthttp.RequestCtx) {
 // @Description Returns list of Kubernetes services across all connected clusters
 // @Tags kubernetes,services
 // @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=web,tier!=cache"
+// @Param fieldSelector query string false "Field selector, e.g. metadata.name=web"
+// @Param source query string false "cache (default) serves the primary cluster from the informer cache when it covers the request; api always lists from the API server"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /services [get]
 func (s *apiServer) handleServices(ctx *fasthttp.RequestCtx) {
@@ -565,16 +648,32 @@ func (s *apiServer) handleServices(ctx *fasthttp.RequestCtx) {
 	logger := getRequestLogger(ctx)
 	logger.Info().Msg("Services request received")
 
-	// Check if Kubernetes client is available
-	if !s.checkKubeClient(ctx, logger) {
+	// Select the target cluster and check that its client is available
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
 		return
 	}
 
 	// Get namespace from query parameter
 	namespace := getNamespaceFromQuery(ctx)
 
-	// Get services from Kubernetes API
-	services, err := s.clientset.CoreV1().Services(namespace).List(context.Background(), metav1.ListOptions{})
+	// Get paging parameters from query
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	// Get services from the informer cache when it covers the request, the Kubernetes API otherwise
+	services, source, err := listFromCacheOrAPI(s, ctx, target, listOpts, func(l *primaryListers) (*corev1.ServiceList, bool, error) {
+		return l.cachedServices(namespace, listOpts)
+	}, func() (*corev1.ServiceList, error) {
+		return cachedList(s, ctx, "/services", target.ID, namespace, listOpts, func(c context.Context, opts metav1.ListOptions) (*corev1.ServiceList, error) {
+			return target.Client.CoreV1().Services(namespace).List(c, opts)
+		})
+	})
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
 	if err != nil {
 		logger.Error().Err(err).Str("namespace", namespace).Msg("Failed to list services")
 		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
@@ -584,7 +683,21 @@ func (s *apiServer) handleServices(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	logger.Info().Int("count", len(services.Items)).Str("namespace", namespace).Msg("Services retrieved")
+	logger.Info().Int("count", len(services.Items)).Str("namespace", namespace).Str("source", source).Msg("Services retrieved")
+
+	// Paging metadata; also sets the continue token header for simple responses
+	pageMetadata := paginationMetadata(ctx, listOpts, services.ListMeta, len(services.Items))
+
+	// Let polling clients skip unchanged lists
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(services.Items))
+		for i := range services.Items {
+			objects = append(objects, &services.Items[i])
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ID, objects)) {
+			return
+		}
+	}
 
 	// Set response headers
 	ctx.Response.Header.Set("Content-Type", "application/json")
@@ -607,11 +720,13 @@ func (s *apiServer) handleServices(ctx *fasthttp.RequestCtx) {
 
 	// Full detailed response
 	response := map[string]interface{}{
+		"cluster":   target.ID,
 		"namespace": namespace,
 		"count":     len(services.Items),
-		"source":    "kubernetes-api",
+		"source":    source,
 		"names":     names,
 		"items":     []interface{}{},
+		"metadata":  pageMetadata,
 	}
 
 	// Add detailed service items
@@ -645,7 +760,14 @@ func (s *apiServer) handleServices(ctx *fasthttp.RequestCtx) {
 // @Description Returns list of Kubernetes nodes across all connected clusters
 // @Tags kubernetes,nodes
 // @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=web,tier!=cache"
+// @Param fieldSelector query string false "Field selector, e.g. metadata.name=web"
+// @Param source query string false "cache (default) serves the primary cluster from the informer cache when it covers the request; api always lists from the API server"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /nodes [get]
 func (s *apiServer) handleNodes(ctx *fasthttp.RequestCtx) {
@@ -653,13 +775,29 @@ func (s *apiServer) handleNodes(ctx *fasthttp.RequestCtx) {
 	logger := getRequestLogger(ctx)
 	logger.Info().Msg("Nodes request received")
 
-	// Check if Kubernetes client is available
-	if !s.checkKubeClient(ctx, logger) {
+	// Select the target cluster and check that its client is available
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	// Get paging parameters from query
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
 		return
 	}
 
-	// Get nodes from Kubernetes API
-	nodes, err := s.clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	// Get nodes from the informer cache when it covers the request, the Kubernetes API otherwise
+	nodes, source, err := listFromCacheOrAPI(s, ctx, target, listOpts, func(l *primaryListers) (*corev1.NodeList, bool, error) {
+		return l.cachedNodes(listOpts)
+	}, func() (*corev1.NodeList, error) {
+		return cachedList(s, ctx, "/nodes", target.ID, "", listOpts, func(c context.Context, opts metav1.ListOptions) (*corev1.NodeList, error) {
+			return target.Client.CoreV1().Nodes().List(c, opts)
+		})
+	})
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to list nodes")
 		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
@@ -669,7 +807,21 @@ func (s *apiServer) handleNodes(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	logger.Info().Int("count", len(nodes.Items)).Msg("Nodes retrieved")
+	logger.Info().Int("count", len(nodes.Items)).Str("source", source).Msg("Nodes retrieved")
+
+	// Paging metadata; also sets the continue token header for simple responses
+	pageMetadata := paginationMetadata(ctx, listOpts, nodes.ListMeta, len(nodes.Items))
+
+	// Let polling clients skip unchanged lists
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(nodes.Items))
+		for i := range nodes.Items {
+			objects = append(objects, &nodes.Items[i])
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ID, objects)) {
+			return
+		}
+	}
 
 	// Set response headers
 	ctx.Response.Header.Set("Content-Type", "application/json")
@@ -692,10 +844,12 @@ func (s *apiServer) handleNodes(ctx *fasthttp.RequestCtx) {
 
 	// Full detailed response
 	response := map[string]interface{}{
-		"count":  len(nodes.Items),
-		"source": "kubernetes-api",
-		"names":  names,
-		"items":  []interface{}{},
+		"cluster":  target.ID,
+		"count":    len(nodes.Items),
+		"source":   source,
+		"names":    names,
+		"items":    []interface{}{},
+		"metadata": pageMetadata,
 	}
 
 	// Add detailed node items with key info
@@ -707,15 +861,13 @@ func (s *apiServer) handleNodes(ctx *fasthttp.RequestCtx) {
 			capacity[string(k)] = v.String()
 		}
 
-		// Get node conditions
+		// Get node conditions of every status, so a Ready=False or Unknown node is visible
 		conditions := make([]map[string]interface{}, 0, len(node.Status.Conditions))
 		for _, condition := range node.Status.Conditions {
-			if condition.Status == "True" {
-				conditions = append(conditions, map[string]interface{}{
-					"type":   string(condition.Type),
-					"status": string(condition.Status),
-				})
-			}
+			conditions = append(conditions, map[string]interface{}{
+				"type":   string(condition.Type),
+				"status": string(condition.Status),
+			})
 		}
 
 		// Get node addresses
@@ -739,6 +891,99 @@ func (s *apiServer) handleNodes(ctx *fasthttp.RequestCtx) {
 	json.NewEncoder(ctx).Encode(response)
 }
 
+// @Summary Get Kubernetes namespaces
+// @Description Returns list of namespaces with their owning team, owner and Slack channel
+// @Tags kubernetes,namespaces
+// @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=web,tier!=cache"
+// @Param fieldSelector query string false "Field selector, e.g. metadata.name=web"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /namespaces [get]
+func (s *apiServer) handleNamespaces(ctx *fasthttp.RequestCtx) {
+	// Get the logger with request ID
+	logger := getRequestLogger(ctx)
+	logger.Info().Msg("Namespaces request received")
+
+	// Select the target cluster and check that its client is available
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	// Get paging parameters from query
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	// Get namespaces from Kubernetes API
+	namespaces, err := cachedList(s, ctx, "/namespaces", target.ID, "", listOpts, func(c context.Context, opts metav1.ListOptions) (*corev1.NamespaceList, error) {
+		return target.Client.CoreV1().Namespaces().List(c, opts)
+	})
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to list namespaces")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{
+			"error": "Failed to list namespaces",
+		})
+		return
+	}
+
+	logger.Info().Int("count", len(namespaces.Items)).Msg("Namespaces retrieved")
+
+	// Paging metadata; also sets the continue token header for simple responses
+	pageMetadata := paginationMetadata(ctx, listOpts, namespaces.ListMeta, len(namespaces.Items))
+
+	// Set response headers
+	ctx.Response.Header.Set("Content-Type", "application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+
+	// Extract namespace names
+	names := make([]string, 0, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		names = append(names, ns.Name)
+	}
+
+	// Simple format if requested
+	if isSimpleFormat(ctx) {
+		writeSimpleJsonArray(ctx, names)
+		return
+	}
+
+	// Full detailed response
+	response := map[string]interface{}{
+		"cluster":  target.ID,
+		"count":    len(namespaces.Items),
+		"source":   "kubernetes-api",
+		"names":    names,
+		"items":    []interface{}{},
+		"metadata": pageMetadata,
+	}
+
+	// Add detailed namespace items enriched with ownership metadata
+	resolver := ownership.Default()
+	items := make([]interface{}, 0, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		items = append(items, map[string]interface{}{
+			"name":    ns.Name,
+			"phase":   string(ns.Status.Phase),
+			"owner":   resolver.FromAnnotations(ns.Name, ns.Annotations),
+			"created": ns.CreationTimestamp.Format(time.RFC3339),
+		})
+	}
+	response["items"] = items
+
+	// Return JSON response
+	json.NewEncoder(ctx).Encode(response)
+}
+
 // Helper functions to reduce code duplication
 
 // getRequestLogger creates a logger with the request ID from context
@@ -788,6 +1033,18 @@ func isSimpleFormat(ctx *fasthttp.RequestCtx) bool {
 // @Success 200 {object} map[string]interface{}
 // @Failure 500 {object} map[string]string
 // @Router /clusters [get]
+// @Summary Add a Kubernetes cluster
+// @Description Adds a cluster to the multi-cluster manager. The configuration is validated first: InCluster and KubeConfig are mutually exclusive, the context must exist in the kubeconfig and the cluster's API server must answer. Invalid fields are returned as a list of field errors.
+// @Tags kubernetes,clusters
+// @Accept json
+// @Produce json
+// @Param body body ctrl.ClusterConfig true "Cluster configuration"
+// @Success 201 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /clusters [post]
 func (s *apiServer) handleClusters(ctx *fasthttp.RequestCtx) {
 	logger := getRequestLogger(ctx)
 	method := string(ctx.Method())
@@ -852,7 +1109,30 @@ func (s *apiServer) handleClusters(ctx *fasthttp.RequestCtx) {
 			return
 		}
 
-		// Add the cluster to the manager
+		if _, err := s.multiClusterManager.GetClientset(clusterConfig.ClusterID); err == nil {
+			ctx.SetStatusCode(fasthttp.StatusConflict)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Cluster %s already exists", clusterConfig.ClusterID)})
+			return
+		}
+
+		// Check the fields and that the API server answers before adding the cluster, tuning
+		// its client like the primary cluster unless set
+		applyClusterDefaults(&clusterConfig, s.config)
+		if err := ctrl.ValidateClusterConfig(requestContext(ctx), clusterConfig); err != nil {
+			var validationErr *ctrl.ValidationError
+			if !errors.As(err, &validationErr) {
+				ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+				json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			logger.Warn().Err(err).Str("cluster_id", clusterConfig.ClusterID).Msg("Rejected invalid cluster configuration")
+			ctx.SetStatusCode(fasthttp.StatusUnprocessableEntity)
+			json.NewEncoder(ctx).Encode(map[string]interface{}{
+				"error":  "Invalid cluster configuration",
+				"errors": validationErr.Errors,
+			})
+			return
+		}
 		if err := s.multiClusterManager.AddCluster(ctx, clusterConfig); err != nil {
 			logger.Error().Err(err).Str("cluster_id", clusterConfig.ClusterID).Msg("Failed to add cluster")
 			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
@@ -874,12 +1154,46 @@ func (s *apiServer) handleClusters(ctx *fasthttp.RequestCtx) {
 			return
 		}
 
-		// Remove the cluster from the manager
-		s.multiClusterManager.RemoveCluster(clusterID)
+		force, err := parseForce(ctx)
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			ctx.SetBodyString(`{"error": "force must be true or false"}`)
+			return
+		}
+
+		// Stop the cluster's manager and drain its work before removing it, unless forced
+		drainCtx, cancel := context.WithTimeout(context.Background(), s.clusterDrainTimeout())
+		defer cancel()
+		report, err := s.multiClusterManager.RemoveClusterGracefully(drainCtx, clusterID, ctrl.RemoveOptions{Force: force})
+		switch {
+		case errors.Is(err, ctrl.ErrClusterNotFound):
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Cluster %s not found", clusterID)})
+			return
+		case errors.Is(err, ctrl.ErrDrainTimeout):
+			logger.Warn().Err(err).Str("cluster_id", clusterID).Msg("Cluster did not drain before removal")
+			ctx.SetStatusCode(fasthttp.StatusConflict)
+			json.NewEncoder(ctx).Encode(map[string]interface{}{
+				"error":   fmt.Sprintf("%s; its manager is stopped, retry the removal or pass force=true", err),
+				"removal": report,
+			})
+			return
+		case err != nil:
+			logger.Error().Err(err).Str("cluster_id", clusterID).Msg("Failed to remove cluster")
+			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to remove cluster: %s", err)})
+			return
+		}
 
-		logger.Info().Str("cluster_id", clusterID).Msg("Removed cluster from manager")
+		if s.breaker != nil {
+			s.breaker.Forget(clusterID)
+		}
+		logger.Info().Str("cluster_id", clusterID).Bool("forced", report.Forced).Msg("Removed cluster from manager")
 		ctx.SetStatusCode(fasthttp.StatusOK)
-		ctx.SetBodyString(fmt.Sprintf(`{"message": "Cluster %s removed successfully"}`, clusterID))
+		json.NewEncoder(ctx).Encode(map[string]interface{}{
+			"message": fmt.Sprintf("Cluster %s removed successfully", clusterID),
+			"removal": report,
+		})
 
 	default:
 		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
@@ -924,7 +1238,7 @@ func StartAPIServer(ctx context.Context, clientset *kubernetes.Clientset, factor
 
 		currentClusterConfig := ctrl.ClusterConfig{
 			Name:       "primary",
-			ClusterID:  "primary-cluster",
+			ClusterID:  primaryClusterID,
 			KubeConfig: kubePath,
 			InCluster:  inCluster, // Use the same setting as the main app
 		}
@@ -934,6 +1248,9 @@ func StartAPIServer(ctx context.Context, clientset *kubernetes.Clientset, factor
 			currentClusterConfig.LeaderElection.Enabled = true
 			currentClusterConfig.LeaderElection.Namespace = appConfig.ControllerRuntime.LeaderElection.Namespace
 			currentClusterConfig.LeaderElection.ID = appConfig.ControllerRuntime.LeaderElection.ID
+			currentClusterConfig.LeaderElection.LeaseDuration = appConfig.ControllerRuntime.LeaderElection.LeaseDuration
+			currentClusterConfig.LeaderElection.RenewDeadline = appConfig.ControllerRuntime.LeaderElection.RenewDeadline
+			currentClusterConfig.LeaderElection.RetryPeriod = appConfig.ControllerRuntime.LeaderElection.RetryPeriod
 
 			if appConfig.ControllerRuntime.LeaderElection.ID == "" {
 				currentClusterConfig.LeaderElection.ID = "k8s-custom-controller-leader-election"
@@ -943,7 +1260,11 @@ func StartAPIServer(ctx context.Context, clientset *kubernetes.Clientset, factor
 				currentClusterConfig.LeaderElection.Namespace = "default"
 			}
 
-			log.Debug().Bool("enabled", true).Str("id", currentClusterConfig.LeaderElection.ID).Str("namespace", currentClusterConfig.LeaderElection.Namespace).Msg("Configured leader election")
+			log.Debug().Bool("enabled", true).Str("id", currentClusterConfig.LeaderElection.ID).Str("namespace", currentClusterConfig.LeaderElection.Namespace).
+				Dur("lease_duration", currentClusterConfig.LeaderElection.LeaseDuration).
+				Dur("renew_deadline", currentClusterConfig.LeaderElection.RenewDeadline).
+				Dur("retry_period", currentClusterConfig.LeaderElection.RetryPeriod).
+				Msg("Configured leader election")
 		}
 
 		// Apply metrics settings if configured
@@ -952,6 +1273,23 @@ func StartAPIServer(ctx context.Context, clientset *kubernetes.Clientset, factor
 			log.Debug().Str("bind_address", currentClusterConfig.MetricsBindAddress).Msg("Configured metrics server")
 		}
 
+		// Apply the global client tuning and Job garbage collection
+		applyClusterDefaults(&currentClusterConfig, appConfig)
+
+		// Apply scheme groups and watched kinds
+		if appConfig != nil {
+			currentClusterConfig.SchemeGroups = appConfig.ControllerRuntime.SchemeGroups
+			for _, w := range appConfig.ControllerRuntime.Watches {
+				currentClusterConfig.Watches = append(currentClusterConfig.Watches, schema.GroupVersionKind{Group: w.Group, Version: w.Version, Kind: w.Kind})
+			}
+		}
+
+		// Apply health probe settings if configured
+		if appConfig != nil && appConfig.ControllerRuntime.HealthProbe.BindAddress != "" {
+			currentClusterConfig.HealthProbeBindAddress = appConfig.ControllerRuntime.HealthProbe.BindAddress
+			log.Debug().Str("bind_address", currentClusterConfig.HealthProbeBindAddress).Msg("Configured health probe server")
+		}
+
 		log.Info().Str("cluster_id", currentClusterConfig.ClusterID).Msg("Adding primary cluster to multi-cluster manager")
 
 		err := multiClusterManager.AddCluster(ctx, currentClusterConfig)
@@ -977,10 +1315,417 @@ func StartAPIServer(ctx context.Context, clientset *kubernetes.Clientset, factor
 		informerFactory:     factory,
 		config:              appConfig,
 		multiClusterManager: multiClusterManager,
+		drain:               drain.New(),
 		// Rate limiter will be initialized on first request
 		requestLimiter: nil,
 	}
 
+	// Build the authorizer when authentication is enabled
+	if appConfig != nil && appConfig.APIServer.Auth.Enabled {
+		authorizer, err := auth.NewAuthorizer(appConfig.APIServer.Auth)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.auth configuration")
+			return err
+		}
+		server.authorizer = authorizer
+		log.Info().
+			Int("api_keys", len(appConfig.APIServer.Auth.APIKeys)).
+			Bool("jwt_enabled", appConfig.APIServer.Auth.JWT.Secret != "").
+			Str("anonymous_role", appConfig.APIServer.Auth.AnonymousRole).
+			Msg("API authentication enabled")
+
+		// Lock out clients and principals that keep failing authentication
+		if appConfig.APIServer.Lockout.Enabled {
+			server.lockout = lockout.New(appConfig.APIServer.Lockout)
+			log.Info().
+				Int("max_failures", appConfig.APIServer.Lockout.MaxFailures).
+				Dur("window", appConfig.APIServer.Lockout.Window).
+				Dur("base_duration", appConfig.APIServer.Lockout.BaseDuration).
+				Msg("Authentication lockout enabled")
+		}
+	}
+
+	if appConfig != nil {
+		if err := appConfig.APIServer.Watch.Validate(); err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.watch configuration")
+			return err
+		}
+		if err := appConfig.APIServer.Timeouts.Validate(); err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.timeouts configuration")
+			return err
+		}
+		if err := appConfig.APIServer.LoadShedding.Validate(); err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.load_shedding configuration")
+			return err
+		}
+		if err := appConfig.APIServer.ListCache.Validate(); err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.list_cache configuration")
+			return err
+		}
+		if err := appConfig.Standby.Validate(); err != nil {
+			log.Error().Err(err).Msg("Invalid standby configuration")
+			return err
+		}
+		if err := appConfig.APIServer.ResponseLimits.Validate(); err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.response_limits configuration")
+			return err
+		}
+		if err := appConfig.APIServer.HTTPServer.Validate(); err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.http_server configuration")
+			return err
+		}
+		if err := appConfig.APIServer.Endpoints.Validate(); err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.endpoints configuration")
+			return err
+		}
+		server.endpoints = endpoints.New(appConfig.APIServer.Endpoints)
+	}
+
+	// Fan deployment informer events out to watch streams
+	if informerEnabled && factory != nil {
+		var watchConfig stream.Config
+		if appConfig != nil {
+			watchConfig = appConfig.APIServer.Watch
+		}
+		var extraKinds []string
+		if appConfig != nil && appConfig.APIServer.WebSocket.Enabled {
+			extraKinds = appConfig.APIServer.WebSocket.Kinds
+		}
+		sources, err := newWatchSources(factory, watchConfig, extraKinds)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to set up watch streams")
+			return err
+		}
+		server.watchSources = sources
+		// Serve pod, service and node lists from the informer cache
+		if appConfig == nil || appConfig.APIServer.Listers.Enabled {
+			var namespace string
+			if appConfig != nil {
+				namespace = appConfig.Informer.Namespace
+			}
+			server.listers = newPrimaryListers(factory, namespace)
+		}
+		// Start informers requested after the factory was first started
+		factory.Start(ctx.Done())
+	}
+
+	// Combine the events the cluster managers emit into one stream
+	if appConfig != nil && appConfig.APIServer.Firehose.Enabled {
+		if multiClusterManager == nil {
+			log.Warn().Msg("Firehose enabled but the multi-cluster manager is disabled, no events will be streamed")
+		}
+		server.firehose = stream.NewBroadcaster("firehose", appConfig.APIServer.Watch)
+		plugin.RegisterSink(&firehoseSink{events: server.firehose})
+		log.Info().Int("max_connections", appConfig.APIServer.Firehose.MaxConnections).Msg("Firehose enabled")
+	}
+
+	// Exec and port-forwarding open their own streams to the primary cluster
+	if appConfig != nil && (appConfig.APIServer.Exec.Enabled || appConfig.APIServer.PortForward.Enabled) && clientset != nil {
+		restConfig, err := newPrimaryRestConfig(appConfig)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create REST config for pod streams")
+			return err
+		}
+		server.restConfig = restConfig
+	}
+
+	// Bridge WebSocket terminals to the exec subresource of pods when enabled
+	if appConfig != nil && appConfig.APIServer.Exec.Enabled {
+		log.Info().
+			Int("max_sessions", appConfig.APIServer.Exec.MaxSessions).
+			Dur("idle_timeout", appConfig.APIServer.Exec.IdleTimeout).
+			Msg("Pod exec enabled")
+	}
+
+	// Tunnel TCP and HTTP traffic to pods and services when enabled
+	if appConfig != nil && appConfig.APIServer.PortForward.Enabled {
+		log.Info().
+			Int("max_connections", appConfig.APIServer.PortForward.MaxConnections).
+			Dur("proxy_timeout", appConfig.APIServer.PortForward.ProxyTimeout).
+			Msg("Port-forwarding enabled")
+	}
+
+	// Protect browser clients against cross-site request forgery when enabled
+	if appConfig != nil && appConfig.APIServer.CSRF.Enabled {
+		server.csrf = csrf.New(appConfig.APIServer.CSRF)
+		log.Info().
+			Str("cookie", server.csrf.CookieName()).
+			Str("header", server.csrf.HeaderName()).
+			Bool("exempt_token_auth", appConfig.APIServer.CSRF.ExemptTokenAuth).
+			Msg("CSRF protection enabled")
+	}
+
+	// Verify signatures of inbound webhooks such as GitHub and GitLab events when enabled
+	if appConfig != nil && appConfig.APIServer.InboundWebhooks.Enabled {
+		var loader hooksig.SecretLoader
+		if clientset != nil {
+			loader = hookSecretLoader(clientset)
+		}
+		verifier, err := hooksig.New(appConfig.APIServer.InboundWebhooks, loader)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.inbound_webhooks configuration")
+			return err
+		}
+		server.hookVerifier = verifier
+		log.Info().Int("hooks", len(appConfig.APIServer.InboundWebhooks.Hooks)).Msg("Inbound webhook verification enabled")
+	}
+
+	// Harden responses with CSP, HSTS, Referrer-Policy and Permissions-Policy headers
+	if appConfig != nil && appConfig.APIServer.SecurityHeaders.Enabled {
+		server.securityHeaders = secheaders.New(appConfig.APIServer.SecurityHeaders)
+		log.Info().Int("route_overrides", len(appConfig.APIServer.SecurityHeaders.Routes)).Msg("Security headers enabled")
+	}
+
+	// Shed low-priority requests under overload so probes and admin access stay responsive
+	if appConfig != nil && appConfig.APIServer.LoadShedding.Enabled {
+		server.shedder = loadshed.New(appConfig.APIServer.LoadShedding)
+		go server.shedder.Start(ctx)
+		log.Info().
+			Int("max_in_flight", appConfig.APIServer.LoadShedding.MaxInFlight).
+			Int64("memory_limit", appConfig.APIServer.LoadShedding.MemoryLimit).
+			Msg("Load shedding enabled")
+	}
+
+	// Share list calls between bursts of identical list requests
+	if appConfig != nil && appConfig.APIServer.ListCache.Enabled {
+		server.listCache = listcache.New(appConfig.APIServer.ListCache)
+		log.Info().
+			Dur("ttl", appConfig.APIServer.ListCache.TTL).
+			Strs("routes", appConfig.APIServer.ListCache.Routes).
+			Msg("List cache enabled")
+	}
+
+	// Answer cross-origin requests and preflights for every endpoint
+	if appConfig != nil && appConfig.APIServer.SwaggerUI.CORSEnabled {
+		swaggerUI := appConfig.APIServer.SwaggerUI
+		policy, err := cors.New(cors.Config{
+			AllowOrigin:  swaggerUI.CORSAllowOrigin,
+			AllowMethods: swaggerUI.CORSAllowMethods,
+			AllowHeaders: swaggerUI.CORSAllowHeaders,
+			MaxAge:       swaggerUI.CORSMaxAge,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.swagger_ui CORS configuration")
+			return err
+		}
+		server.corsPolicy = policy
+		log.Info().Str("allow_origin", swaggerUI.CORSAllowOrigin).Msg("CORS enabled")
+	}
+
+	// Classify node and load balancer exposure by the configured policies
+	if appConfig != nil {
+		policy, err := exposure.New(appConfig.Exposure)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid exposure configuration")
+			return err
+		}
+		server.exposurePolicy = policy
+	}
+
+	// Audit pod specs for privileged workloads, skipping the configured namespaces
+	if appConfig != nil {
+		auditor, err := podaudit.New(appConfig.WorkloadAudit)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid workload audit configuration")
+			return err
+		}
+		server.workloadAuditor = auditor
+	}
+
+	// Match API versions of workloads against the deprecation table and configured entries
+	if appConfig != nil {
+		table, err := deprecation.New(appConfig.Deprecations)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid deprecations configuration")
+			return err
+		}
+		server.deprecations = table
+	}
+
+	// Scan images of running deployments on a schedule when enabled
+	if appConfig != nil && appConfig.ImageScan.Enabled {
+		server.imageScanner = vulnscan.NewScanner(appConfig.ImageScan, server.deploymentImages, nil)
+		go server.imageScanner.Start(ctx)
+		log.Info().
+			Dur("interval", appConfig.ImageScan.Interval).
+			Str("server_url", appConfig.ImageScan.ServerURL).
+			Msg("Image vulnerability scanning enabled")
+	}
+
+	// Sample container usage and recommend requests and limits when enabled
+	if appConfig != nil && appConfig.Recommendations.Enabled && clientset != nil {
+		var annotator recommend.Annotator
+		if appConfig.Recommendations.Annotate {
+			annotator = annotateDeployment(clientset)
+		}
+		server.recommender = recommend.NewRecommender(appConfig.Recommendations, metricsServerUsage(clientset), server.deploymentSpecs, annotator)
+		go server.recommender.Start(ctx)
+		log.Info().
+			Dur("interval", appConfig.Recommendations.Interval).
+			Dur("window", appConfig.Recommendations.Window).
+			Bool("annotate", appConfig.Recommendations.Annotate).
+			Msg("Resource recommendations enabled")
+	}
+
+	// Sample allocatable and requested capacity for forecasting when enabled
+	if appConfig != nil && appConfig.Capacity.Enabled {
+		server.capacityForecaster = capacity.NewForecaster(appConfig.Capacity, server.collectCapacity)
+		go server.capacityForecaster.Start(ctx)
+		log.Info().
+			Dur("interval", appConfig.Capacity.Interval).
+			Dur("window", appConfig.Capacity.Window).
+			Msg("Capacity forecasting enabled")
+	}
+
+	// Keep replica and node readiness history for charts when enabled
+	if appConfig != nil && appConfig.History.Enabled {
+		store, err := history.New(appConfig.History, server.collectHistory)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load history")
+			return err
+		}
+		server.history = store
+		go store.Start(ctx)
+		log.Info().
+			Dur("interval", appConfig.History.Interval).
+			Dur("retention", appConfig.History.Retention).
+			Str("path", appConfig.History.Path).
+			Int("series", store.Series()).
+			Msg("History enabled")
+	}
+
+	// Skip failing clusters in aggregated endpoints and serve their last-known data instead
+	if appConfig != nil && appConfig.CircuitBreaker.Enabled {
+		server.breaker = circuit.New(appConfig.CircuitBreaker)
+		log.Info().
+			Int("failure_threshold", appConfig.CircuitBreaker.FailureThreshold).
+			Dur("open_duration", appConfig.CircuitBreaker.OpenDuration).
+			Msg("Cluster circuit breaking enabled")
+	}
+
+	// Run deployment migrations between clusters as background jobs
+	if appConfig != nil {
+		server.migrations = migrate.NewManager(ctx, appConfig.Migration)
+	}
+
+	// Mirror namespaces into a warm standby cluster until POST /failover activates it
+	if appConfig != nil && appConfig.Standby.Enabled {
+		server.standby = migrate.NewStandby(appConfig.Standby, server.clusterClient)
+		go server.standby.Run(ctx)
+		log.Info().
+			Str("source", appConfig.Standby.Source).
+			Str("target", appConfig.Standby.Target).
+			Strs("namespaces", appConfig.Standby.Namespaces).
+			Dur("interval", appConfig.Standby.Interval).
+			Msg("Warm standby mirroring enabled")
+	}
+
+	// Warm cross-cluster aggregations so dashboards do not wait for a fan-out after a restart
+	if appConfig != nil && appConfig.Precompute.Enabled {
+		server.precomputed = precompute.New(appConfig.Precompute)
+		server.registerAggregations(server.precomputed)
+		go server.precomputed.Start(ctx)
+		log.Info().
+			Dur("interval", appConfig.Precompute.Interval).
+			Dur("max_staleness", appConfig.Precompute.MaxStaleness).
+			Msg("Aggregation precomputation enabled")
+	}
+
+	// Serve allowlisted resources, including custom resources, under /raw when enabled
+	if appConfig != nil && appConfig.APIServer.Raw.Enabled {
+		allowlist, err := rawapi.New(appConfig.APIServer.Raw)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.raw configuration")
+			return err
+		}
+		if clientset != nil {
+			if server.dynamicClient, err = newPrimaryDynamicClient(appConfig); err != nil {
+				log.Error().Err(err).Msg("Failed to create dynamic client")
+				return err
+			}
+		}
+		server.rawAllowlist = allowlist
+		log.Info().Strs("allow", appConfig.APIServer.Raw.Allow).Msg("Raw resource endpoints enabled")
+	}
+
+	// Environments are custom resources, read and created through the dynamic client
+	if appConfig != nil && appConfig.ControllerRuntime.Environments.Enabled && clientset != nil && server.dynamicClient == nil {
+		dynamicClient, err := newPrimaryDynamicClient(appConfig)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create dynamic client")
+			return err
+		}
+		server.dynamicClient = dynamicClient
+	}
+
+	// Compare live objects with desired-state bundles when enabled
+	if appConfig != nil && appConfig.Drift.Enabled {
+		get, err := newDriftLiveGetter(clientset, appConfig)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create client for drift detection")
+			return err
+		}
+		server.driftDetector = drift.NewDetector(appConfig.Drift, get, nil)
+		go server.driftDetector.Start(ctx)
+		log.Info().
+			Dur("interval", appConfig.Drift.Interval).
+			Int("sources", len(appConfig.Drift.Sources)).
+			Msg("Drift detection enabled")
+	}
+
+	// Watch deployment rollouts for missing progress when enabled
+	if appConfig != nil && appConfig.StuckRollouts.Enabled {
+		server.stuckRollouts = rollout.NewDetector(appConfig.StuckRollouts, server.rolloutDeployments)
+		go server.stuckRollouts.Start(ctx)
+		log.Info().
+			Dur("interval", appConfig.StuckRollouts.Interval).
+			Dur("window", appConfig.StuckRollouts.Window).
+			Msg("Stuck rollout detection enabled")
+	}
+
+	// Generate configured reports on their schedules and deliver them when enabled
+	if appConfig != nil && appConfig.Reports.Enabled {
+		scheduler, err := report.NewScheduler(appConfig.Reports, server.reportSources(appConfig.Reports.Pricing))
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid reports configuration")
+			return err
+		}
+		server.reports = scheduler
+		go scheduler.Start(ctx)
+		log.Info().
+			Int("reports", len(appConfig.Reports.Reports)).
+			Str("smtp_host", appConfig.Reports.SMTP.Host).
+			Msg("Scheduled reports enabled")
+	}
+
+	// Record mutating API calls to the audit log when enabled
+	if appConfig != nil && appConfig.APIServer.Audit.Enabled {
+		auditor, err := audit.New(appConfig.APIServer.Audit)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid api_server.audit configuration")
+			return err
+		}
+		server.auditor = auditor
+		defer auditor.Close()
+		log.Info().
+			Strs("paths", appConfig.APIServer.Audit.Paths).
+			Bool("stdout", appConfig.APIServer.Audit.Stdout).
+			Str("file", appConfig.APIServer.Audit.File.Path).
+			Bool("webhook", appConfig.APIServer.Audit.Webhook.URL != "").
+			Msg("API audit logging enabled")
+	}
+
+	// Let admins capture full requests and responses while debugging client integrations
+	if appConfig != nil && appConfig.APIServer.DebugCapture.Enabled {
+		redaction := appConfig.Logging.Redaction
+		redaction.Enabled = true // Captured bodies are always redacted
+		server.debugCapture = debugcapture.New(appConfig.APIServer.DebugCapture, redact.New(redaction))
+		log.Info().
+			Int("max_body_bytes", appConfig.APIServer.DebugCapture.MaxBodyBytes).
+			Dur("max_duration", appConfig.APIServer.DebugCapture.MaxDuration).
+			Msg("Debug capture available at /admin/debug/captures")
+	}
+
 	address := fmt.Sprintf("%s:%d", host, port)
 
 	log.Info().Str("address", address).Msg("Starting API server")
@@ -991,6 +1736,13 @@ func StartAPIServer(ctx context.Context, clientset *kubernetes.Clientset, factor
 		log.Debug().Bool("enable_swagger", enableSwagger).Msg("Swagger configuration applied from config file")
 	}
 
+	// Refuse middleware plugins placed before unknown stages
+	if _, err := server.handler(); err != nil {
+		log.Error().Err(err).Msg("Failed to build the API middleware chain")
+		return err
+	}
+	log.Debug().Strs("stages", server.chain.Names()).Msg("API middleware chain built")
+
 	// Create a server instance with production-ready settings
 	fasthttpServer := &fasthttp.Server{
 		Handler:            server.requestHandler,
@@ -1026,20 +1778,80 @@ func StartAPIServer(ctx context.Context, clientset *kubernetes.Clientset, factor
 		fasthttpServer.TCPKeepalive = true
 	}
 
+	// Streaming endpoints such as /deployments/watch and followed pod logs outlive the regular write timeout
+	fasthttpServer.HeaderReceived = streamRequestConfig(server.streamMaxDuration())
+
+	// Load the certificate before accepting connections when HTTPS is enabled
+	var tlsConfig *tls.Config
+	if appConfig != nil && appConfig.APIServer.TLS.Enabled {
+		var err error
+		tlsConfig, err = buildServerTLSConfig(ctx, clientset, appConfig)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to configure TLS for API server")
+			return err
+		}
+	}
+
+	// Serve through net/http instead when selected, for HTTP/2 and h2c clients; upgrades
+	// such as WebSockets are still handled by the fasthttp server
+	var httpServer *http.Server
+	if appConfig != nil && appConfig.APIServer.HTTPServer.Implementation == stdhttp.NetHTTP {
+		httpServer = stdhttp.NewServer(appConfig.APIServer.HTTPServer, fasthttpServer, tlsConfig)
+		httpServer.Addr = address
+		httpServer.SetKeepAlivesEnabled(!appConfig.APIServer.Security.DisableKeepalive)
+		log.Info().Bool("h2c", appConfig.APIServer.HTTPServer.H2C).Msg("Serving the API through net/http")
+	}
+
+	// Build the routes with disabled groups removed; a group matching no route is most likely misspelled
+	if server.endpoints != nil {
+		server.routes()
+		if unmatched := server.endpoints.Unmatched(); len(unmatched) > 0 {
+			log.Warn().Strs("groups", unmatched).Msg("api_server.endpoints.disabled names groups that match no route")
+		}
+		log.Info().Strs("disabled", appConfig.APIServer.Endpoints.Disabled).Msg("Endpoint groups disabled")
+	}
+
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	// Start HTTP server in a goroutine
 	go func() {
-		log.Info().Msgf("Starting API server on %s:%d", host, port)
-		if err := fasthttpServer.ListenAndServe(address); err != nil {
+		var err error
+		switch {
+		case httpServer != nil && tlsConfig != nil:
+			log.Info().Msgf("Starting API server with TLS on %s:%d", host, port)
+			err = httpServer.ListenAndServeTLS("", "")
+		case httpServer != nil:
+			log.Info().Msgf("Starting API server on %s:%d", host, port)
+			err = httpServer.ListenAndServe()
+		case tlsConfig != nil:
+			log.Info().Msgf("Starting API server with TLS on %s:%d", host, port)
+			err = serveTLS(fasthttpServer, address, tlsConfig)
+		default:
+			log.Info().Msgf("Starting API server on %s:%d", host, port)
+			err = fasthttpServer.ListenAndServe(address)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error().Err(err).Msg("Failed to start API server")
 			// Signal the main goroutine that there was an error
 			close(sigChan)
 		}
 	}()
 
+	// Serve the gRPC API on its own port, sharing clients, caches and TLS settings
+	var grpcSrv *grpc.Server
+	if appConfig != nil && appConfig.APIServer.GRPC.Enabled {
+		grpcSrv = newGRPCServer(server, tlsConfig, appConfig.APIServer.GRPC.Reflection)
+		grpcAddress := fmt.Sprintf("%s:%d", host, appConfig.APIServer.GRPC.Port)
+		go func() {
+			log.Info().Bool("tls", tlsConfig != nil).Msgf("Starting gRPC server on %s", grpcAddress)
+			if err := serveGRPC(grpcSrv, grpcAddress); err != nil {
+				log.Error().Err(err).Msg("Failed to start gRPC server")
+			}
+		}()
+	}
+
 	// Wait for interrupt signal or context cancellation
 	select {
 	case <-sigChan:
@@ -1048,17 +1860,89 @@ func StartAPIServer(ctx context.Context, clientset *kubernetes.Clientset, factor
 		log.Info().Msg("Context canceled, shutting down")
 	}
 
-	// Shutdown multi-cluster manager
-	log.Info().Msg("Stopping multi-cluster manager")
-	multiClusterManager.StopAll(ctx)
+	// Fail readiness and stop accepting connections, then give in-flight work until the
+	// shutdown timeout to finish
+	shutdownTimeout := 30 * time.Second
+	if appConfig != nil && appConfig.APIServer.ShutdownTimeout > 0 {
+		shutdownTimeout = appConfig.APIServer.ShutdownTimeout
+	}
+	requests, streams := server.drain.InFlight()
+	log.Info().
+		Dur("timeout", shutdownTimeout).
+		Int64("requests", requests).
+		Int64("streams", streams).
+		Msg("Draining API server")
+	server.drain.Start()
+	drainCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		if httpServer != nil {
+			shutdownDone <- httpServer.Shutdown(drainCtx)
+			return
+		}
+		shutdownDone <- fasthttpServer.ShutdownWithContext(drainCtx)
+	}()
+	grpcDone := make(chan struct{})
+	if grpcSrv != nil {
+		// Stop accepting RPCs and let in-flight ones finish
+		go func() {
+			defer close(grpcDone)
+			grpcSrv.GracefulStop()
+		}()
+	}
+
+	if err := server.drain.WaitRequests(drainCtx); err != nil {
+		requests, _ := server.drain.InFlight()
+		log.Warn().Int64("requests", requests).Msg("Requests still in flight at the shutdown timeout")
+	}
 
-	// Shutdown server gracefully
-	log.Info().Msg("Shutting down API server")
-	if err := fasthttpServer.Shutdown(); err != nil {
+	// End watch streams, exec sessions and followed logs; clients resume on another replica
+	server.closeWatchSources()
+	server.closeFirehose()
+	server.drain.EndStreams()
+	if err := server.drain.WaitStreams(drainCtx); err != nil {
+		_, streams := server.drain.InFlight()
+		log.Warn().Int64("streams", streams).Msg("Streams still open at the shutdown timeout")
+	}
+
+	if grpcSrv != nil {
+		select {
+		case <-grpcDone:
+		case <-drainCtx.Done():
+			log.Warn().Msg("Forcing gRPC server shutdown")
+			grpcSrv.Stop()
+		}
+	}
+	err := <-shutdownDone
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
 		log.Error().Err(err).Msg("Error shutting down API server")
 		return err
 	}
 
+	// Persist the history sampled since the last write
+	if server.history != nil {
+		if err := server.history.Flush(); err != nil {
+			log.Error().Err(err).Msg("Failed to persist history")
+		}
+	}
+
+	// The manager's clients are no longer needed once requests have finished
+	log.Info().Msg("Stopping multi-cluster manager")
+	multiClusterManager.StopAll(ctx)
+
+	// Deliver the events the controllers emitted before stopping
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), plugin.SendTimeout)
+	defer cancelFlush()
+	if err := plugin.Flush(flushCtx); err != nil {
+		log.Warn().Err(err).Msg("Plugin events still queued at shutdown")
+	}
+
+	if err != nil {
+		log.Warn().Dur("timeout", shutdownTimeout).Msg("API server stopped at the shutdown timeout, closing remaining connections")
+		return nil
+	}
 	log.Info().Msg("API server gracefully stopped")
 	return nil
 }
@@ -1075,11 +1959,6 @@ func (s *apiServer) handleSwaggerJSON(ctx *fasthttp.RequestCtx) {
 	// Set security headers
 	ctx.Response.Header.Set("X-Content-Type-Options", "nosniff")
 
-	// Add CORS headers if enabled in config
-	if s.config != nil && s.config.APIServer.SwaggerUI.CORSEnabled {
-		ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
-	}
-
 	// Get swagger doc
 	doc, err := swag.ReadDoc()
 	if err != nil {
@@ -1089,9 +1968,18 @@ func (s *apiServer) handleSwaggerJSON(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// Leave out the routes of disabled endpoint groups
+	filtered, err := s.endpoints.FilterOpenAPI([]byte(doc))
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to filter Swagger documentation")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to read API documentation"})
+		return
+	}
+
 	// Write swagger JSON
 	ctx.SetStatusCode(fasthttp.StatusOK)
-	ctx.WriteString(doc)
+	ctx.Write(filtered)
 }
 
 // serveSwaggerUI serves Swagger UI HTML page
@@ -1105,9 +1993,25 @@ func (s *apiServer) serveSwaggerUI(ctx *fasthttp.RequestCtx) {
 	// Set security headers
 	ctx.Response.Header.Set("X-Content-Type-Options", "nosniff")
 
-	// Add CORS headers if enabled in config
-	if s.config != nil && s.config.APIServer.SwaggerUI.CORSEnabled {
-		ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
+	// The page needs its own policy: the API default denies all scripts and styles
+	nonce, err := newCSPNonce()
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to generate CSP nonce")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		ctx.SetBodyString(`{"error": "Failed to render Swagger UI"}`)
+		return
+	}
+	ctx.Response.Header.Set("Content-Security-Policy", swaggerUICSP(nonce, s.config != nil && s.config.APIServer.SwaggerUI.UseStrictCSP))
+
+	// Echo the CSRF cookie in the header so "Try it out" requests pass the double-submit check
+	requestInterceptor := ""
+	if s.csrf != nil {
+		requestInterceptor = fmt.Sprintf(`,
+        requestInterceptor: function(req) {
+          const match = document.cookie.match(/(?:^|; )%s=([^;]*)/);
+          if (match) { req.headers[%q] = decodeURIComponent(match[1]); }
+          return req;
+        }`, regexp.QuoteMeta(s.csrf.CookieName()), s.csrf.HeaderName())
 	}
 
 	// Simple Swagger UI HTML
@@ -1117,7 +2021,7 @@ func (s *apiServer) serveSwaggerUI(ctx *fasthttp.RequestCtx) {
   <meta charset="UTF-8">
   <title>API Documentation</title>
   <link rel="stylesheet" type="text/css" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@latest/swagger-ui.css">
-  <style>
+  <style nonce="` + nonce + `">
     html { box-sizing: border-box; overflow: -moz-scrollbars-vertical; overflow-y: scroll; }
     *, *:before, *:after { box-sizing: inherit; }
     body { margin: 0; background: #fafafa; }
@@ -1125,14 +2029,14 @@ func (s *apiServer) serveSwaggerUI(ctx *fasthttp.RequestCtx) {
 </head>
 <body>
   <div id="swagger-ui"></div>
-  <script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@latest/swagger-ui-bundle.js"></script>
-  <script>
+  <script nonce="` + nonce + `" src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@latest/swagger-ui-bundle.js"></script>
+  <script nonce="` + nonce + `">
     window.onload = function() {
       const ui = SwaggerUIBundle({
         url: "/swagger.json",
         dom_id: '#swagger-ui',
         presets: [SwaggerUIBundle.presets.apis],
-        layout: "BaseLayout"
+        layout: "BaseLayout"` + requestInterceptor + `
       });
     };
   </script>
@@ -1191,20 +2095,48 @@ func (tb *tokenBucket) take() bool {
 	return false
 }
 
-// perIPLimiter manages rate limiters for individual IP addresses
+// Defaults for per-IP limiter eviction when not configured
+const (
+	defaultRateLimitMaxEntries = 10000
+	defaultRateLimitIdleTTL    = 10 * time.Minute
+)
+
+// limiterEntry is a token bucket for one IP with its last access time
+type limiterEntry struct {
+	ip         string
+	bucket     *tokenBucket
+	lastAccess time.Time
+}
+
+// perIPLimiter manages rate limiters for individual IP addresses.
+// Entries are kept in least-recently-used order so that idle IPs expire
+// and the least recently seen IP is evicted once maxEntries is reached.
 type perIPLimiter struct {
-	limiters        map[string]*tokenBucket
+	limiters        map[string]*list.Element // IP -> element holding *limiterEntry
+	lru             *list.List               // Front is most recently used
 	mu              sync.Mutex
 	tokensPerSec    int
+	maxEntries      int
+	idleTTL         time.Duration
 	cleanupInterval time.Duration
 }
 
-// newPerIPLimiter creates a new per-IP rate limiter
-func newPerIPLimiter(tokensPerSecond int) *perIPLimiter {
+// newPerIPLimiter creates a new per-IP rate limiter; zero maxEntries or idleTTL use the defaults
+func newPerIPLimiter(tokensPerSecond, maxEntries int, idleTTL time.Duration) *perIPLimiter {
+	if maxEntries <= 0 {
+		maxEntries = defaultRateLimitMaxEntries
+	}
+	if idleTTL <= 0 {
+		idleTTL = defaultRateLimitIdleTTL
+	}
+
 	limiter := &perIPLimiter{
-		limiters:        make(map[string]*tokenBucket),
+		limiters:        make(map[string]*list.Element),
+		lru:             list.New(),
 		tokensPerSec:    tokensPerSecond,
-		cleanupInterval: 5 * time.Minute,
+		maxEntries:      maxEntries,
+		idleTTL:         idleTTL,
+		cleanupInterval: min(idleTTL, 5*time.Minute),
 	}
 
 	// Start background cleanup
@@ -1215,6 +2147,8 @@ func newPerIPLimiter(tokensPerSecond int) *perIPLimiter {
 
 // getLimit returns the current rate limit setting
 func (p *perIPLimiter) getLimit() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.tokensPerSec
 }
 
@@ -1227,8 +2161,8 @@ func (p *perIPLimiter) updateLimit(tokensPerSecond int) {
 	p.tokensPerSec = tokensPerSecond
 
 	// Create new buckets for all existing IPs
-	for ip := range p.limiters {
-		p.limiters[ip] = newTokenBucket(tokensPerSecond)
+	for _, elem := range p.limiters {
+		elem.Value.(*limiterEntry).bucket = newTokenBucket(tokensPerSecond)
 	}
 }
 
@@ -1236,49 +2170,143 @@ func (p *perIPLimiter) updateLimit(tokensPerSecond int) {
 func (p *perIPLimiter) allow(ip string) bool {
 	p.mu.Lock()
 
-	// Create a new limiter for this IP if it doesn't exist
-	limiter, exists := p.limiters[ip]
-	if !exists {
-		limiter = newTokenBucket(p.tokensPerSec)
-		p.limiters[ip] = limiter
+	now := time.Now()
+	var entry *limiterEntry
+	if elem, exists := p.limiters[ip]; exists {
+		// Mark this IP as most recently used
+		entry = elem.Value.(*limiterEntry)
+		entry.lastAccess = now
+		p.lru.MoveToFront(elem)
+	} else {
+		// Create a new limiter for this IP, evicting the least recently used one when full
+		entry = &limiterEntry{ip: ip, bucket: newTokenBucket(p.tokensPerSec), lastAccess: now}
+		p.limiters[ip] = p.lru.PushFront(entry)
+		for p.lru.Len() > p.maxEntries {
+			p.removeElement(p.lru.Back())
+		}
 	}
+	bucket := entry.bucket
 	p.mu.Unlock()
 
 	// Try to take a token
-	return limiter.take()
+	return bucket.take()
+}
+
+// size returns the number of tracked IPs
+func (p *perIPLimiter) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lru.Len()
+}
+
+// evictIdle removes limiters not used within idleTTL and returns how many were removed
+func (p *perIPLimiter) evictIdle(now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	removed := 0
+	for elem := p.lru.Back(); elem != nil; elem = p.lru.Back() {
+		if now.Sub(elem.Value.(*limiterEntry).lastAccess) < p.idleTTL {
+			break // Everything closer to the front was used more recently
+		}
+		p.removeElement(elem)
+		removed++
+	}
+	return removed
 }
 
-// cleanupRoutine periodically removes unused IP limiters
+// removeElement drops an entry from both the list and the map; callers hold p.mu
+func (p *perIPLimiter) removeElement(elem *list.Element) {
+	p.lru.Remove(elem)
+	delete(p.limiters, elem.Value.(*limiterEntry).ip)
+}
+
+// cleanupRoutine periodically removes idle IP limiters
 func (p *perIPLimiter) cleanupRoutine() {
 	ticker := time.NewTicker(p.cleanupInterval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		p.mu.Lock()
-		// In a real implementation, we would track last access time
-		// and remove limiters that haven't been used recently
-		// For now, we just keep the map to a reasonable size
-		if len(p.limiters) > 1000 {
-			// Create a new map with just the most recently used IPs
-			// This is a simple approach; a more sophisticated one would
-			// track actual usage timestamps
-			p.limiters = make(map[string]*tokenBucket)
-		}
-		p.mu.Unlock()
+		if removed := p.evictIdle(time.Now()); removed > 0 {
+			log.Debug().Int("removed", removed).Int("remaining", p.size()).Msg("Evicted idle rate limiter entries")
+		}
+	}
+}
+
+// routeLimit is a compiled route rate limit with its own per-IP buckets
+type routeLimit struct {
+	path    string
+	methods map[string]bool
+	limiter *perIPLimiter
+}
+
+// routeRateLimiter is a composite limiter keyed by (ip, route)
+type routeRateLimiter struct {
+	routes []routeLimit
+}
+
+// newRouteRateLimiter builds limiters for each configured route; the first matching route wins
+func newRouteRateLimiter(limits []RouteRateLimit, maxEntries int, idleTTL time.Duration) *routeRateLimiter {
+	r := &routeRateLimiter{}
+	for _, l := range limits {
+		if l.Path == "" || l.RequestsPerSecond <= 0 {
+			continue
+		}
+		methods := make(map[string]bool, len(l.Methods))
+		for _, m := range l.Methods {
+			methods[strings.ToUpper(m)] = true
+		}
+		r.routes = append(r.routes, routeLimit{
+			path:    strings.TrimSuffix(l.Path, "/"),
+			methods: methods,
+			limiter: newPerIPLimiter(l.RequestsPerSecond, maxEntries, idleTTL),
+		})
 	}
+	return r
 }
 
-// checkRateLimit implements a rate limiting mechanism on a per-IP basis
-func (s *apiServer) checkRateLimit(clientIP string, logger zerolog.Logger) bool {
+// match returns the route limit for the request, if any
+func (r *routeRateLimiter) match(method, path string) *routeLimit {
+	for i := range r.routes {
+		route := &r.routes[i]
+		if path != route.path && !strings.HasPrefix(path, route.path+"/") {
+			continue
+		}
+		if len(route.methods) > 0 && !route.methods[method] {
+			continue
+		}
+		return route
+	}
+	return nil
+}
+
+// checkRateLimit implements a rate limiting mechanism on a per-IP basis.
+// Requests matching a route limit use that route's buckets instead of the global one.
+// It returns whether the request is allowed and the limit that was applied.
+func (s *apiServer) checkRateLimit(clientIP, method, path string, logger zerolog.Logger) (bool, int) {
 	// Initialize rate limiter if not already created
 	s.requestLimiterMux.Lock()
+	var maxEntries int
+	var idleTTL time.Duration
+	if s.config != nil {
+		maxEntries = s.config.APIServer.Security.RateLimitMaxEntries
+		idleTTL = s.config.APIServer.Security.RateLimitIdleTTL
+	}
+	if s.routeLimiter == nil {
+		var limits []RouteRateLimit
+		if s.config != nil {
+			limits = s.config.APIServer.Security.RouteRateLimits
+		}
+		s.routeLimiter = newRouteRateLimiter(limits, maxEntries, idleTTL)
+		logger.Debug().Int("routes", len(s.routeLimiter.routes)).Msg("Per-route rate limiters initialized")
+	}
 	if s.ipLimiter == nil {
 		// Default to 10 requests per second, but use config value if available
 		rateLimit := 10 // Default value
 		if s.config != nil && s.config.APIServer.Security.RateLimitRequestsPerSecond > 0 {
 			rateLimit = s.config.APIServer.Security.RateLimitRequestsPerSecond
 		}
-		s.ipLimiter = newPerIPLimiter(rateLimit)
+		s.ipLimiter = newPerIPLimiter(rateLimit, maxEntries, idleTTL)
 		logger.Debug().Int("requests_per_second", rateLimit).Msg("Per-IP rate limiter initialized")
 	} else if s.config != nil && s.config.APIServer.Security.RateLimitRequestsPerSecond > 0 {
 		// Update rate limit if configuration has changed
@@ -1292,8 +2320,18 @@ func (s *apiServer) checkRateLimit(clientIP string, logger zerolog.Logger) bool
 	}
 	s.requestLimiterMux.Unlock()
 
+	// Route-specific limits take precedence over the global limit
+	if route := s.routeLimiter.match(method, path); route != nil {
+		return route.limiter.allow(clientIP), route.limiter.getLimit()
+	}
+
+	// Without a global limit only route limits apply
+	if s.config != nil && s.config.APIServer.Security.RateLimitRequestsPerSecond <= 0 {
+		return true, 0
+	}
+
 	// Check if this IP is allowed
-	return s.ipLimiter.allow(clientIP)
+	return s.ipLimiter.allow(clientIP), s.ipLimiter.getLimit()
 }
 
 func init() {
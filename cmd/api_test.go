@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lruOrder returns the tracked IPs from most to least recently used
+func lruOrder(p *perIPLimiter) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ips []string
+	for elem := p.lru.Front(); elem != nil; elem = elem.Next() {
+		ips = append(ips, elem.Value.(*limiterEntry).ip)
+	}
+	return ips
+}
+
+// TestPerIPLimiter_Eviction tests that the least recently used IP is evicted at capacity
+func TestPerIPLimiter_Eviction(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries int
+		requests   []string
+		want       []string
+	}{
+		{"below capacity", 3, []string{"a", "b"}, []string{"b", "a"}},
+		{"oldest evicted", 2, []string{"a", "b", "c"}, []string{"c", "b"}},
+		{"use refreshes", 2, []string{"a", "b", "a", "c"}, []string{"c", "a"}},
+		{"repeat does not grow", 2, []string{"a", "a", "a"}, []string{"a"}},
+		{"single entry", 1, []string{"a", "b", "c"}, []string{"c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPerIPLimiter(100, tt.maxEntries, time.Hour)
+			for _, ip := range tt.requests {
+				p.allow(ip)
+			}
+			assert.Equal(t, tt.want, lruOrder(p))
+			assert.Equal(t, len(tt.want), p.size())
+			assert.Len(t, p.limiters, len(tt.want), "the map follows the list")
+		})
+	}
+}
+
+// TestPerIPLimiter_EvictIdle tests that only entries idle for idleTTL are removed
+func TestPerIPLimiter_EvictIdle(t *testing.T) {
+	p := newPerIPLimiter(100, 10, time.Minute)
+	p.allow("a")
+	p.allow("b")
+	p.allow("c")
+	p.mu.Lock()
+	p.limiters["a"].Value.(*limiterEntry).lastAccess = time.Now().Add(-2 * time.Minute)
+	p.limiters["b"].Value.(*limiterEntry).lastAccess = time.Now().Add(-2 * time.Minute)
+	p.mu.Unlock()
+
+	assert.Equal(t, 2, p.evictIdle(time.Now()))
+	assert.Equal(t, []string{"c"}, lruOrder(p))
+	assert.Equal(t, 0, p.evictIdle(time.Now()))
+}
+
+// TestPerIPLimiter_Refill tests that each IP has its own bucket, refilled over time up to capacity
+func TestPerIPLimiter_Refill(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration // Time since the bucket was drained
+		want    int           // Requests allowed afterwards
+	}{
+		{"no time", 0, 0},
+		{"half a second", 500 * time.Millisecond, 1},
+		{"one second", time.Second, 3},
+		{"capped at capacity", time.Minute, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPerIPLimiter(3, 10, time.Hour)
+			for i := 0; i < 3; i++ {
+				assert.True(t, p.allow("a"))
+			}
+			assert.False(t, p.allow("a"), "bucket is drained")
+			assert.True(t, p.allow("b"), "other IPs are not affected")
+
+			p.mu.Lock()
+			bucket := p.limiters["a"].Value.(*limiterEntry).bucket
+			p.mu.Unlock()
+			bucket.mu.Lock()
+			bucket.lastRefill = time.Now().Add(-tt.elapsed)
+			bucket.mu.Unlock()
+
+			allowed := 0
+			for p.allow("a") {
+				allowed++
+			}
+			// Slow runs may refill one token more
+			assert.InDelta(t, tt.want, allowed, 1)
+			assert.GreaterOrEqual(t, allowed, tt.want)
+		})
+	}
+}
+
+// TestPerIPLimiter_UpdateLimit tests that a new limit applies to tracked IPs
+func TestPerIPLimiter_UpdateLimit(t *testing.T) {
+	p := newPerIPLimiter(1, 10, time.Hour)
+	assert.True(t, p.allow("a"))
+	assert.False(t, p.allow("a"))
+
+	p.updateLimit(2)
+	assert.Equal(t, 2, p.getLimit())
+	assert.True(t, p.allow("a"))
+	assert.True(t, p.allow("a"))
+	assert.False(t, p.allow("a"))
+}
+
+// TestRouteRateLimiter_Match tests route matching and that the first matching route wins
+func TestRouteRateLimiter_Match(t *testing.T) {
+	r := newRouteRateLimiter([]RouteRateLimit{
+		{Path: "/clusters/", Methods: []string{"post", "DELETE"}, RequestsPerSecond: 1},
+		{Path: "/clusters", RequestsPerSecond: 2},
+		{Path: "/pods", RequestsPerSecond: 3},
+		{Path: "", RequestsPerSecond: 4},          // Ignored without a path
+		{Path: "/services", RequestsPerSecond: 0}, // Ignored without a limit
+	}, 10, time.Hour)
+	require.Len(t, r.routes, 3)
+
+	tests := []struct {
+		method, path string
+		want         int // Limit of the matching route, 0 for none
+	}{
+		{"POST", "/clusters", 1},
+		{"DELETE", "/clusters/east", 1},
+		{"GET", "/clusters", 2},
+		{"GET", "/clusters/east/health", 2},
+		{"GET", "/pods", 3},
+		{"GET", "/pods/default/web", 3},
+		{"GET", "/podsecurity", 0},
+		{"GET", "/services", 0},
+		{"GET", "/", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			route := r.match(tt.method, tt.path)
+			if tt.want == 0 {
+				assert.Nil(t, route)
+				return
+			}
+			require.NotNil(t, route)
+			assert.Equal(t, tt.want, route.limiter.getLimit())
+		})
+	}
+}
+
+// TestCheckRateLimit_RouteOverride tests that route limits take precedence over the global
+// limit, with buckets of their own
+func TestCheckRateLimit_RouteOverride(t *testing.T) {
+	newServer := func(global int) *apiServer {
+		cfg := &Config{}
+		cfg.APIServer.Security.RateLimitRequestsPerSecond = global
+		cfg.APIServer.Security.RouteRateLimits = []RouteRateLimit{
+			{Path: "/clusters", Methods: []string{"POST"}, RequestsPerSecond: 1},
+			{Path: "/pods", RequestsPerSecond: 5},
+		}
+		return &apiServer{config: cfg}
+	}
+	logger := zerolog.Nop()
+
+	tests := []struct {
+		name         string
+		global       int
+		method, path string
+		wantLimit    int
+		wantAllowed  int // Requests allowed of 10 in a row
+	}{
+		{"stricter route", 3, "POST", "/clusters", 1, 1},
+		{"looser route", 3, "GET", "/pods/default", 5, 5},
+		{"method not limited", 3, "GET", "/clusters", 3, 3},
+		{"other route", 3, "GET", "/nodes", 3, 3},
+		{"route without global", 0, "POST", "/clusters", 1, 1},
+		{"no global limit", 0, "GET", "/nodes", 0, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(tt.global)
+			allowed := 0
+			for i := 0; i < 10; i++ {
+				ok, limit := s.checkRateLimit("10.0.0.1", tt.method, tt.path, logger)
+				assert.Equal(t, tt.wantLimit, limit)
+				if ok {
+					allowed++
+				}
+			}
+			assert.Equal(t, tt.wantAllowed, allowed)
+		})
+	}
+
+	// A route's buckets are separate from the global ones and from other clients
+	s := newServer(2)
+	ok, _ := s.checkRateLimit("10.0.0.1", "POST", "/clusters", logger)
+	assert.True(t, ok)
+	ok, _ = s.checkRateLimit("10.0.0.1", "POST", "/clusters", logger)
+	assert.False(t, ok, "the route bucket is drained")
+	ok, _ = s.checkRateLimit("10.0.0.1", "GET", "/nodes", logger)
+	assert.True(t, ok, "the global bucket is untouched")
+	ok, _ = s.checkRateLimit("10.0.0.2", "POST", "/clusters", logger)
+	assert.True(t, ok, "other clients have their own route bucket")
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/audit"
+)
+
+// auditObjectsUserValueKey stores the objects a bulk request changed, for its audit entry
+const auditObjectsUserValueKey = "audit_objects"
+
+// auditRequest records a completed request to the audit log when it matches the audit filter
+func (s *apiServer) auditRequest(ctx *fasthttp.RequestCtx, requestID, method, path, clientIP string, start time.Time) {
+	if s.auditor == nil || !s.auditor.Matches(method, path) {
+		return
+	}
+
+	body := ctx.PostBody()
+	status := ctx.Response.StatusCode()
+	entry := audit.Entry{
+		Time:       start.UTC(),
+		RequestID:  requestID,
+		ClientIP:   clientIP,
+		Method:     method,
+		Path:       path,
+		Query:      string(ctx.QueryArgs().QueryString()),
+		BodySHA256: audit.Digest(body),
+		BodySize:   len(body),
+		Status:     status,
+		Result:     audit.ResultForStatus(status),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if principal := getPrincipal(ctx); principal != nil {
+		entry.Principal = principal.Name
+		entry.Role = principal.Role.String()
+	}
+	if objects, ok := ctx.UserValue(auditObjectsUserValueKey).([]string); ok {
+		entry.Objects = objects
+	}
+
+	s.auditor.Record(entry)
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/auth"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/lockout"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+)
+
+// principalUserValueKey stores the authenticated principal on the request context
+const principalUserValueKey = "principal"
+
+// routeScope is the role and scope a single route and method requires
+type routeScope struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Scope  string `json:"scope,omitempty"` // Empty for public paths
+	Role   string `json:"role,omitempty"`
+	Plugin string `json:"plugin,omitempty"`
+}
+
+// authorizeRequest authenticates the caller and enforces role and scope rules.
+// It writes the error response and returns false when the request must stop.
+func (s *apiServer) authorizeRequest(ctx *fasthttp.RequestCtx, method, path string, logger zerolog.Logger) bool {
+	if s.authorizer.IsPublic(path) {
+		return true
+	}
+
+	// Locked out addresses are refused before their credentials are checked
+	clientIP := ctx.RemoteIP().String()
+	if s.lockout != nil && s.rejectLockedOut(ctx, lockout.KindIP, clientIP, logger) {
+		return false
+	}
+
+	principal, err := s.authorizer.Authenticate(
+		string(ctx.Request.Header.Peek("Authorization")),
+		string(ctx.Request.Header.Peek("X-API-Key")),
+	)
+	if err != nil {
+		logger.Warn().Err(err).Str("path", path).Str("client", clientIP).Msg("Authentication failed")
+		// Missing credentials are not an attack, only wrong ones count towards a lockout
+		if !errors.Is(err, auth.ErrNoCredentials) {
+			s.recordAuthFailure(lockout.EventAuthFailure, lockout.KindIP, clientIP, err.Error(), logger)
+		}
+		ctx.Response.Header.Set("WWW-Authenticate", `Bearer realm="k8s-cli"`)
+		ctx.SetStatusCode(fasthttp.StatusUnauthorized)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			ctx.SetBodyString(`{"error": "Authentication required"}`)
+		} else {
+			ctx.SetBodyString(`{"error": "Invalid credentials"}`)
+		}
+		return false
+	}
+
+	ctx.SetUserValue(principalUserValueKey, principal)
+
+	if s.lockout != nil && principal.Source != "anonymous" && s.rejectLockedOut(ctx, lockout.KindPrincipal, principal.Name, logger) {
+		return false
+	}
+
+	if !s.authorizer.Authorize(principal, method, path) {
+		// Anonymous callers share one principal, so their denials are not held against it
+		if principal.Source != "anonymous" {
+			s.recordAuthFailure(lockout.EventAuthzFailure, lockout.KindPrincipal, principal.Name, method+" "+path, logger)
+		}
+		required := s.authorizer.RequiredRole(method, path)
+		requiredScope := s.authorizer.RequiredScope(method, path)
+		logger.Warn().
+			Str("principal", principal.Name).
+			Str("role", principal.Role.String()).
+			Str("required_role", required.String()).
+			Strs("scopes", principal.Scopes).
+			Str("required_scope", requiredScope).
+			Str("method", method).
+			Str("path", path).
+			Msg("Authorization denied")
+		ctx.SetStatusCode(fasthttp.StatusForbidden)
+		if principal.Role < required {
+			ctx.SetBodyString(`{"error": "Forbidden: requires ` + required.String() + ` role"}`)
+		} else {
+			ctx.SetBodyString(`{"error": "Forbidden: requires ` + requiredScope + ` scope"}`)
+		}
+		return false
+	}
+
+	logger.Debug().Str("principal", principal.Name).Str("role", principal.Role.String()).Msg("Request authorized")
+	return true
+}
+
+// getPrincipal returns the authenticated principal for the request, if any
+func getPrincipal(ctx *fasthttp.RequestCtx) *auth.Principal {
+	if p, ok := ctx.UserValue(principalUserValueKey).(*auth.Principal); ok {
+		return p
+	}
+	return nil
+}
+
+// @Summary List authorization scopes per route
+// @Description Returns the scope and role every built-in and plugin route requires, together with the scope rules in evaluation order
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /admin/scopes [get]
+func (s *apiServer) handleAdminScopes(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	if s.authorizer == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Authorization is disabled"}`)
+		return
+	}
+
+	describe := func(method, path, pluginName string) routeScope {
+		route := routeScope{Method: method, Path: path, Plugin: pluginName}
+		if !s.authorizer.IsPublic(path) {
+			route.Scope = s.authorizer.RequiredScope(method, path)
+			route.Role = s.authorizer.RequiredRole(method, path).String()
+		}
+		return route
+	}
+
+	var items []routeScope
+	for _, route := range s.routes().Routes() {
+		items = append(items, describe(route.Method, route.Path, ""))
+	}
+	for _, p := range plugin.Default().Handlers() {
+		for _, route := range p.Routes() {
+			methods := []string{route.Method}
+			if route.Method == "" {
+				// Routes serving any method are shown with their read and write requirements
+				methods = []string{"GET", "POST"}
+			}
+			for _, method := range methods {
+				items = append(items, describe(method, route.Path, p.Name()))
+			}
+		}
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"count": len(items),
+		"items": items,
+		"rules": s.authorizer.ScopeRules(),
+	})
+}
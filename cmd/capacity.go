@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/capacity"
+)
+
+// activePodsSelector skips pods whose requests no longer hold node capacity
+const activePodsSelector = "status.phase!=Succeeded,status.phase!=Failed"
+
+// collectCapacity reads node allocatable and scheduled pod requests from every known cluster.
+// Clusters that cannot be read are skipped so one unreachable cluster does not stop the others.
+func (s *apiServer) collectCapacity(ctx context.Context) (map[string][]capacity.Node, error) {
+	clients, err := s.inventoryClients("")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]capacity.Node, len(clients))
+	for clusterID, client := range clients {
+		nodes, err := clusterCapacity(ctx, client)
+		if err != nil {
+			log.Warn().Err(err).Str("cluster_id", clusterID).Msg("Failed to collect cluster capacity")
+			continue
+		}
+		result[clusterID] = nodes
+	}
+	return result, nil
+}
+
+func clusterCapacity(ctx context.Context, client kubernetes.Interface) ([]capacity.Node, error) {
+	nodeList, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	podList, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: activePodsSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*capacity.Node, len(nodeList.Items))
+	result := make([]capacity.Node, len(nodeList.Items))
+	for i, n := range nodeList.Items {
+		result[i] = capacity.Node{
+			Name:                   n.Name,
+			Labels:                 n.Labels,
+			AllocatableCPUMilli:    n.Status.Allocatable.Cpu().MilliValue(),
+			AllocatableMemoryBytes: n.Status.Allocatable.Memory().Value(),
+		}
+		nodes[n.Name] = &result[i]
+	}
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		node, ok := nodes[pod.Spec.NodeName]
+		if !ok {
+			continue // Pending pods are not scheduled to a node yet
+		}
+		cpu, memory := podRequests(pod)
+		node.RequestedCPUMilli += cpu
+		node.RequestedMemoryBytes += memory
+	}
+	return result, nil
+}
+
+// podRequests returns the requests the scheduler reserves for a pod: the larger of the
+// summed app containers and the biggest init container, plus pod overhead
+func podRequests(pod *corev1.Pod) (int64, int64) {
+	var cpu, memory int64
+	for _, c := range pod.Spec.Containers {
+		cpu += c.Resources.Requests.Cpu().MilliValue()
+		memory += c.Resources.Requests.Memory().Value()
+	}
+	for _, c := range pod.Spec.InitContainers {
+		cpu = max(cpu, c.Resources.Requests.Cpu().MilliValue())
+		memory = max(memory, c.Resources.Requests.Memory().Value())
+	}
+	if pod.Spec.Overhead != nil {
+		cpu += pod.Spec.Overhead.Cpu().MilliValue()
+		memory += pod.Spec.Overhead.Memory().Value()
+	}
+	return cpu, memory
+}
+
+// @Summary Get capacity forecast
+// @Description Estimates days until requests exceed allocatable resources per cluster and node pool, from the trend of sampled requests
+// @Tags capacity
+// @Produce json
+// @Param cluster query string false "Only include this cluster"
+// @Param pool query string false "Only include this node pool"
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /capacity/forecast [get]
+func (s *apiServer) handleCapacityForecast(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	if s.capacityForecaster == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Capacity forecasting is disabled"}`)
+		return
+	}
+
+	pool := string(ctx.QueryArgs().Peek("pool"))
+	forecasts := s.capacityForecaster.Forecasts(string(ctx.QueryArgs().Peek("cluster")))
+
+	items := make([]capacity.Forecast, 0, len(forecasts))
+	for _, f := range forecasts {
+		if pool != "" && f.Pool != pool {
+			continue
+		}
+		items = append(items, f)
+	}
+
+	response := map[string]interface{}{
+		"count":  len(items),
+		"window": s.config.Capacity.Window.String(),
+		"items":  items,
+	}
+	if last := s.capacityForecaster.LastSample(); !last.IsZero() {
+		response["last_sample"] = last.UTC().Format(time.RFC3339)
+	}
+
+	logger.Debug().Int("forecasts", len(items)).Msg("Capacity forecast returned")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/circuit"
+)
+
+// Keys of the last-known data kept per cluster by the circuit breaker
+const (
+	circuitKeyResources   = "resources"
+	circuitKeyNodeMetrics = "node-metrics"
+)
+
+// staleness marks cluster data served from its last successful read, because the
+// cluster's circuit is open or reading it failed
+type staleness struct {
+	Stale      bool       `json:"stale,omitempty"`
+	CachedAt   *time.Time `json:"cached_at,omitempty"`
+	AgeSeconds *float64   `json:"age_seconds,omitempty"`
+}
+
+func newStaleness(result circuit.Result) staleness {
+	if !result.Stale {
+		return staleness{}
+	}
+	cachedAt := result.CachedAt.UTC()
+	age := result.Age.Round(time.Second).Seconds()
+	return staleness{Stale: true, CachedAt: &cachedAt, AgeSeconds: &age}
+}
+
+// readCluster reads a cluster for an aggregated endpoint through its circuit, so a failing
+// cluster answers with its last-known data. Without circuit breaking the read is made as is.
+func (s *apiServer) readCluster(ctx context.Context, clusterID, key string, read func(context.Context) (interface{}, error)) circuit.Result {
+	if s.breaker == nil {
+		value, err := read(ctx)
+		return circuit.Result{Value: value, Err: err}
+	}
+	return s.breaker.Do(ctx, clusterID, key, read)
+}
@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ctrl"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/informer"
+)
+
+// clusterCacheTimeout bounds how long a request waits for a managed cluster's cache
+const clusterCacheTimeout = 5 * time.Second
+
+// errKubeClientUnavailable is returned when the API server runs without a Kubernetes client
+var errKubeClientUnavailable = errors.New("Kubernetes client not configured")
+
+// applyClusterDefaults fills unset client tuning of a cluster from the global kubernetes
+// settings and applies the Job garbage collection and environment settings
+func applyClusterDefaults(cfg *ctrl.ClusterConfig, appConfig *Config) {
+	if appConfig == nil {
+		return
+	}
+	cfg.JobGC = appConfig.ControllerRuntime.JobGC
+	cfg.Environments = appConfig.ControllerRuntime.Environments
+	if cfg.QPS == 0 {
+		cfg.QPS = appConfig.Kubernetes.QPS
+	}
+	if cfg.Burst == 0 {
+		cfg.Burst = appConfig.Kubernetes.Burst
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = appConfig.Kubernetes.Timeout
+	}
+}
+
+// newPrimaryDynamicClient creates a dynamic client for the primary cluster, for resources
+// without typed clients
+func newPrimaryDynamicClient(appConfig *Config) (dynamic.Interface, error) {
+	restConfig, err := newPrimaryRestConfig(appConfig)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(restConfig)
+}
+
+// newPrimaryRestConfig builds the REST config of the primary cluster
+func newPrimaryRestConfig(appConfig *Config) (*rest.Config, error) {
+	// Use the same kubeconfig path determination logic as the multi-cluster manager
+	kubePath := kubeconfig
+	if kubePath == "" {
+		kubePath = appConfig.Kubernetes.Kubeconfig
+	}
+	return informer.CreateRestConfig(kubePath, appConfig.Kubernetes.InCluster, appConfig.ToInformerOptions())
+}
+
+// clusterTarget is the cluster a resource request operates on
+type clusterTarget struct {
+	ID     string
+	Client kubernetes.Interface
+}
+
+// isPrimary reports whether the target is the cluster the API server was started against
+func (t *clusterTarget) isPrimary() bool {
+	return t.ID == primaryClusterID
+}
+
+// getClusterFromQuery returns the cluster query parameter, defaulting to the primary cluster
+func getClusterFromQuery(ctx *fasthttp.RequestCtx) string {
+	if clusterID := string(ctx.QueryArgs().Peek("cluster")); clusterID != "" {
+		return clusterID
+	}
+	return primaryClusterID
+}
+
+// resolveCluster selects the client for the cluster named by the cluster query parameter.
+// It writes the error response and returns nil when the cluster is unknown or unavailable.
+func (s *apiServer) resolveCluster(ctx *fasthttp.RequestCtx, logger zerolog.Logger) *clusterTarget {
+	clusterID := getClusterFromQuery(ctx)
+
+	if clusterID == primaryClusterID && !s.checkKubeClient(ctx, logger) {
+		return nil
+	}
+
+	target, err := s.lookupCluster(clusterID)
+	if err != nil {
+		logger.Warn().Err(err).Str("cluster_id", clusterID).Msg("Requested cluster is not available")
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Cluster %s not found", clusterID)})
+		return nil
+	}
+	return target
+}
+
+// lookupCluster returns the client for a cluster ID. It fails with errKubeClientUnavailable
+// when the primary cluster has no client, and for clusters the manager does not know.
+func (s *apiServer) lookupCluster(clusterID string) (*clusterTarget, error) {
+	if clusterID == primaryClusterID {
+		if s.clientset == nil {
+			return nil, errKubeClientUnavailable
+		}
+		return &clusterTarget{ID: clusterID, Client: s.clientset}, nil
+	}
+
+	if s.multiClusterManager == nil {
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+	clientset, err := s.multiClusterManager.GetClientset(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterTarget{ID: clusterID, Client: clientset}, nil
+}
+
+// cachedDeployments lists deployments from the target cluster's cache: the shared informer
+// for the primary cluster, the controller manager cache for other managed clusters
+func (s *apiServer) cachedDeployments(target *clusterTarget, namespace string, opts metav1.ListOptions) ([]*appsv1.Deployment, error) {
+	if target.isPrimary() {
+		if s.informerFactory == nil {
+			return nil, nil
+		}
+		deploymentInformer := s.informerFactory.Apps().V1().Deployments().Informer()
+		return informer.ListDeploymentsInCacheWithSelectors(deploymentInformer, namespace, opts.LabelSelector, opts.FieldSelector)
+	}
+
+	if s.multiClusterManager == nil {
+		return nil, nil
+	}
+	clusterCache, err := s.multiClusterManager.GetCache(target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheCtx, cancel := context.WithTimeout(context.Background(), clusterCacheTimeout)
+	defer cancel()
+
+	var list appsv1.DeploymentList
+	if err := clusterCache.List(cacheCtx, &list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	deployments := make([]*appsv1.Deployment, 0, len(list.Items))
+	for i := range list.Items {
+		deployments = append(deployments, &list.Items[i])
+	}
+	return informer.FilterDeployments(deployments, opts.LabelSelector, opts.FieldSelector)
+}
+
+// @Summary Get cluster leader-election status
+// @Description Returns whether the controller manager of a cluster currently holds its leader-election lease
+// @Tags kubernetes,clusters
+// @Produce json
+// @Param id path string true "Cluster ID, e.g. primary-cluster"
+// @Success 200 {object} ctrl.LeaderStatus
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /clusters/{id}/leader [get]
+func (s *apiServer) handleClusterLeader(ctx *fasthttp.RequestCtx, clusterID string) {
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	if s.multiClusterManager == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Multi-cluster functionality is disabled because informer is disabled"}`)
+		return
+	}
+
+	status, ok := s.multiClusterManager.GetLeaderStatus(clusterID)
+	if !ok {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Cluster %s not found", clusterID)})
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(status)
+}
+
+// @Summary Get cluster lifecycle events
+// @Description Returns the recorded lifecycle events of a cluster newest first: added, connected, degraded, leader_acquired, leader_lost and removed
+// @Tags kubernetes,clusters
+// @Produce json
+// @Param id path string true "Cluster ID, e.g. primary-cluster"
+// @Param type query string false "Only return events of this type"
+// @Param limit query int false "Maximum number of events to return (default 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /clusters/{id}/events [get]
+func (s *apiServer) handleClusterEvents(ctx *fasthttp.RequestCtx, clusterID string) {
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	if s.multiClusterManager == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Multi-cluster functionality is disabled because informer is disabled"}`)
+		return
+	}
+
+	eventType := string(ctx.QueryArgs().Peek("type"))
+	if eventType != "" && !slices.Contains(ctrl.ClusterEventTypes, eventType) {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "Unknown event type"}`)
+		return
+	}
+
+	limit := ctrl.DefaultClusterEventHistory
+	if raw := ctx.QueryArgs().Peek("limit"); len(raw) > 0 {
+		n, err := strconv.Atoi(string(raw))
+		if err != nil || n <= 0 {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			ctx.SetBodyString(`{"error": "limit must be a positive integer"}`)
+			return
+		}
+		limit = n
+	}
+
+	events, ok := s.multiClusterManager.ClusterEvents(clusterID, eventType, limit)
+	if !ok {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Cluster %s not found", clusterID)})
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster_id": clusterID,
+		"count":      len(events),
+		"events":     events,
+	})
+}
+
+// clusterDrainTimeout bounds how long a cluster removal waits for the cluster to drain
+func (s *apiServer) clusterDrainTimeout() time.Duration {
+	if s.config != nil && s.config.ControllerRuntime.Removal.DrainTimeout > 0 {
+		return s.config.ControllerRuntime.Removal.DrainTimeout
+	}
+	return 60 * time.Second
+}
+
+// parseForce reads the optional ?force= flag of a cluster removal
+func parseForce(ctx *fasthttp.RequestCtx) (bool, error) {
+	raw := ctx.QueryArgs().Peek("force")
+	if len(raw) == 0 {
+		return false, nil
+	}
+	return strconv.ParseBool(string(raw))
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/compare"
+)
+
+// comparableKinds lists the workloads of a namespace by name for each kind /compare accepts
+var comparableKinds = map[string]func(ctx context.Context, client kubernetes.Interface, namespace string) (map[string]compare.Workload, error){
+	"deployments": func(ctx context.Context, client kubernetes.Interface, namespace string) (map[string]compare.Workload, error) {
+		list, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		workloads := make(map[string]compare.Workload, len(list.Items))
+		for _, d := range list.Items {
+			workloads[d.Name] = compare.Workload{Replicas: d.Spec.Replicas, Template: d.Spec.Template.Spec}
+		}
+		return workloads, nil
+	},
+	"statefulsets": func(ctx context.Context, client kubernetes.Interface, namespace string) (map[string]compare.Workload, error) {
+		list, err := client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		workloads := make(map[string]compare.Workload, len(list.Items))
+		for _, s := range list.Items {
+			workloads[s.Name] = compare.Workload{Replicas: s.Spec.Replicas, Template: s.Spec.Template.Spec}
+		}
+		return workloads, nil
+	},
+	"daemonsets": func(ctx context.Context, client kubernetes.Interface, namespace string) (map[string]compare.Workload, error) {
+		list, err := client.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		workloads := make(map[string]compare.Workload, len(list.Items))
+		for _, d := range list.Items {
+			workloads[d.Name] = compare.Workload{Template: d.Spec.Template.Spec}
+		}
+		return workloads, nil
+	},
+}
+
+// compareResponse is the response of GET /compare
+type compareResponse struct {
+	Namespace string           `json:"namespace"`
+	Kind      string           `json:"kind"`
+	Clusters  []string         `json:"clusters"`
+	Summary   map[string]int   `json:"summary"` // Workloads per status
+	Items     []compare.Result `json:"items"`
+}
+
+// compareClustersFromQuery parses ?clusters=, a comma-separated list of at least two distinct IDs
+func compareClustersFromQuery(ctx *fasthttp.RequestCtx) ([]string, error) {
+	var clusters []string
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(string(ctx.QueryArgs().Peek("clusters")), ",") {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			clusters = append(clusters, id)
+		}
+	}
+	if len(clusters) < 2 {
+		return nil, fmt.Errorf("clusters must name at least two clusters, e.g. clusters=staging,prod")
+	}
+	return clusters, nil
+}
+
+// @Summary Compare workloads across clusters
+// @Description Diffs the workloads of a namespace between clusters: replicas, images, environment variables, envFrom sources and resource requests and limits of every container. Workloads are matched by name and reported as same, different or missing. Values of environment variables whose names match the log redaction patterns are shown as digests.
+// @Tags kubernetes,clusters
+// @Produce json
+// @Param namespace query string true "Namespace to compare"
+// @Param clusters query string true "Comma-separated cluster IDs, at least two, e.g. staging,prod"
+// @Param kind query string false "deployments (default), statefulsets or daemonsets"
+// @Param divergent query bool false "Only return workloads that differ or are missing somewhere"
+// @Success 200 {object} compareResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /compare [get]
+func (s *apiServer) handleCompare(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	namespace := string(ctx.QueryArgs().Peek("namespace"))
+	if namespace == "" {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "namespace is required"})
+		return
+	}
+	clusters, err := compareClustersFromQuery(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	kind := strings.ToLower(string(ctx.QueryArgs().Peek("kind")))
+	if kind == "" {
+		kind = "deployments"
+	}
+	list, ok := comparableKinds[kind]
+	if !ok {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "kind must be deployments, statefulsets or daemonsets"})
+		return
+	}
+	divergent := false
+	if raw := ctx.QueryArgs().Peek("divergent"); len(raw) > 0 {
+		if divergent, err = strconv.ParseBool(string(raw)); err != nil {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": "divergent must be true or false"})
+			return
+		}
+	}
+
+	targets := make([]*clusterTarget, 0, len(clusters))
+	for _, id := range clusters {
+		target, err := s.lookupCluster(id)
+		if err != nil {
+			logger.Warn().Err(err).Str("cluster_id", id).Msg("Requested cluster is not available")
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Cluster %s not found", id)})
+			return
+		}
+		targets = append(targets, target)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	workloads := make(map[string]map[string]compare.Workload, len(targets))
+	errs := make(map[string]error)
+	for _, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			items, err := list(requestContext(ctx), target.Client, namespace)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[target.ID] = err
+				return
+			}
+			workloads[target.ID] = items
+		}()
+	}
+	wg.Wait()
+	for _, id := range clusters {
+		if err := errs[id]; err != nil {
+			logger.Error().Err(err).Str("cluster_id", id).Str("namespace", namespace).Str("kind", kind).Msg("Failed to list workloads for comparison")
+			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to list %s in cluster %s", kind, id)})
+			return
+		}
+	}
+
+	results := compare.Compare(clusters, workloads, compare.Options{Sensitive: s.strictRedactor().Sensitive})
+	response := compareResponse{
+		Namespace: namespace,
+		Kind:      kind,
+		Clusters:  clusters,
+		Summary:   map[string]int{compare.StatusSame: 0, compare.StatusDifferent: 0, compare.StatusMissing: 0},
+		Items:     make([]compare.Result, 0, len(results)),
+	}
+	for _, result := range results {
+		response.Summary[result.Status]++
+		if divergent && result.Status == compare.StatusSame {
+			continue
+		}
+		response.Items = append(response.Items, result)
+	}
+	logger.Info().Strs("clusters", clusters).Str("namespace", namespace).Str("kind", kind).
+		Int("different", response.Summary[compare.StatusDifferent]).Int("missing", response.Summary[compare.StatusMissing]).
+		Msg("Workloads compared")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"slices"
+
+	"github.com/valyala/fasthttp"
+)
+
+// defaultCompressionPaths are the list endpoints whose responses grow with cluster size
+var defaultCompressionPaths = []string{"/clusters", "/deployments", "/pods", "/services", "/nodes", "/namespaces"}
+
+// compressResponse gzips, or deflates, a buffered response body when the client accepts it
+// and the body reaches the configured minimum size. Streams, WebSocket upgrades, empty
+// bodies such as 304 responses and responses to HEAD requests are never compressed.
+func (s *apiServer) compressResponse(ctx *fasthttp.RequestCtx, path string) {
+	cfg := s.config.APIServer.Compression
+	if len(cfg.Paths) > 0 && !slices.Contains(cfg.Paths, path) {
+		return
+	}
+	if ctx.IsHead() || ctx.Hijacked() || ctx.Response.IsBodyStream() {
+		return
+	}
+	if len(ctx.Response.Header.ContentEncoding()) > 0 {
+		return
+	}
+
+	// The body may differ by encoding, so caches must key on Accept-Encoding even when
+	// this response stays uncompressed
+	ctx.Response.Header.Add("Vary", "Accept-Encoding")
+
+	body := ctx.Response.Body()
+	if len(body) == 0 || len(body) < cfg.MinSize {
+		return
+	}
+
+	level := cfg.Level
+	if level < fasthttp.CompressBestSpeed || level > fasthttp.CompressBestCompression {
+		level = fasthttp.CompressDefaultCompression
+	}
+
+	switch {
+	case ctx.Request.Header.HasAcceptEncoding("gzip"):
+		ctx.Response.SetBodyRaw(fasthttp.AppendGzipBytesLevel(nil, body, level))
+		ctx.Response.Header.SetContentEncoding("gzip")
+	case ctx.Request.Header.HasAcceptEncoding("deflate"):
+		ctx.Response.SetBodyRaw(fasthttp.AppendDeflateBytesLevel(nil, body, level))
+		ctx.Response.Header.SetContentEncoding("deflate")
+	}
+}
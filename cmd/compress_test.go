@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// newCompressingServer creates an API server compressing responses of at least minSize bytes
+func newCompressingServer(minSize int, paths ...string) *apiServer {
+	cfg := &Config{}
+	cfg.APIServer.Compression.Enabled = true
+	cfg.APIServer.Compression.MinSize = minSize
+	cfg.APIServer.Compression.Level = 6
+	cfg.APIServer.Compression.Paths = paths
+	return &apiServer{config: cfg}
+}
+
+// serveCompressed runs a handler behind the compression stage
+func serveCompressed(s *apiServer, method, path, acceptEncoding string, handler fasthttp.RequestHandler) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(path)
+	if acceptEncoding != "" {
+		ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, acceptEncoding)
+	}
+	s.compress(handler)(ctx)
+	return ctx
+}
+
+// TestCompress tests encoding selection, the minimum size and the responses left alone
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"name":"web"}`, 100)
+	writeBody := func(body string) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) { ctx.SetBodyString(body) }
+	}
+
+	tests := []struct {
+		name           string
+		server         *apiServer
+		method, path   string
+		acceptEncoding string
+		handler        fasthttp.RequestHandler
+		wantEncoding   string // Content-Encoding of the response, empty when left uncompressed
+		wantVary       bool
+	}{
+		{"gzip", newCompressingServer(1024), "GET", "/pods", "gzip", writeBody(large), "gzip", true},
+		{"gzip preferred", newCompressingServer(1024), "GET", "/pods", "deflate, gzip", writeBody(large), "gzip", true},
+		{"deflate", newCompressingServer(1024), "GET", "/pods", "deflate", writeBody(large), "deflate", true},
+		{"not accepted", newCompressingServer(1024), "GET", "/pods", "", writeBody(large), "", true},
+		{"unsupported encoding", newCompressingServer(1024), "GET", "/pods", "br", writeBody(large), "", true},
+		{"below min size", newCompressingServer(1024), "GET", "/pods", "gzip", writeBody(large[:1023]), "", true},
+		{"at min size", newCompressingServer(1024), "GET", "/pods", "gzip", writeBody(large[:1024]), "gzip", true},
+		{"path listed", newCompressingServer(0, "/pods"), "GET", "/pods", "gzip", writeBody(large), "gzip", true},
+		{"path not listed", newCompressingServer(0, "/pods"), "GET", "/nodes", "gzip", writeBody(large), "", false},
+		{"head", newCompressingServer(0), "HEAD", "/pods", "gzip", writeBody(large), "", false},
+		{"not modified", newCompressingServer(0), "GET", "/pods", "gzip", func(ctx *fasthttp.RequestCtx) {
+			ctx.SetStatusCode(fasthttp.StatusNotModified)
+		}, "", true},
+		{"already encoded", newCompressingServer(0), "GET", "/pods", "gzip", func(ctx *fasthttp.RequestCtx) {
+			ctx.Response.Header.SetContentEncoding("br")
+			ctx.SetBodyString(large)
+		}, "br", false},
+		{"stream", newCompressingServer(0), "GET", "/pods", "gzip", func(ctx *fasthttp.RequestCtx) {
+			ctx.SetBodyStream(strings.NewReader(large), -1)
+		}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := serveCompressed(tt.server, tt.method, tt.path, tt.acceptEncoding, tt.handler)
+
+			assert.Equal(t, tt.wantEncoding, string(ctx.Response.Header.ContentEncoding()))
+			if tt.wantVary {
+				assert.Equal(t, "Accept-Encoding", string(ctx.Response.Header.Peek("Vary")))
+			} else {
+				assert.Empty(t, ctx.Response.Header.Peek("Vary"))
+			}
+			if ctx.Response.IsBodyStream() || ctx.Response.StatusCode() == fasthttp.StatusNotModified {
+				return
+			}
+
+			var body []byte
+			var err error
+			switch tt.wantEncoding {
+			case "gzip":
+				body, err = fasthttp.AppendGunzipBytes(nil, ctx.Response.Body())
+			case "deflate":
+				body, err = fasthttp.AppendInflateBytes(nil, ctx.Response.Body())
+			default:
+				body = ctx.Response.Body()
+			}
+			require.NoError(t, err)
+			if tt.wantEncoding == "gzip" || tt.wantEncoding == "deflate" {
+				assert.Less(t, len(ctx.Response.Body()), len(body), "the body shrinks")
+			}
+			assert.Contains(t, large, string(body), "the body is kept")
+			assert.NotEmpty(t, body)
+		})
+	}
+
+	// Responses are left alone unless compression is enabled
+	s := newCompressingServer(0)
+	s.config.APIServer.Compression.Enabled = false
+	ctx := serveCompressed(s, "GET", "/pods", "gzip", writeBody(large))
+	assert.Empty(t, ctx.Response.Header.ContentEncoding())
+	assert.Empty(t, ctx.Response.Header.Peek("Vary"))
+	assert.Equal(t, large, string(ctx.Response.Body()))
+}
@@ -11,7 +11,41 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/audit"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/auth"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/capacity"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/circuit"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/csrf"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ctrl"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/debugcapture"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/deprecation"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/drift"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/endpoints"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/exposure"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/freeze"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/history"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/hooksig"
 	"github.com/obezsmertnyi/k8s-custom-controller/pkg/informer"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/listcache"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/loadshed"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/lockout"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/migrate"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ownership"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/podaudit"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/precompute"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/rawapi"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/recommend"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/redact"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/relabel"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/report"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/rollout"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/secheaders"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/stdhttp"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/stream"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/timeout"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/tracing"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/upgradecheck"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/vulnscan"
 )
 
 // Config structure for storing application configuration
@@ -25,6 +59,8 @@ type Config struct {
 		QPS        float32       `mapstructure:"qps"`
 		Burst      int           `mapstructure:"burst"`
 		InCluster  bool          `mapstructure:"in_cluster"`
+		// Directory sanitized API responses are recorded into, for replay in tests
+		RecordFixtures string `mapstructure:"record_fixtures"`
 		// Deprecated: Use Informer.Enabled and APIServer.Enabled instead
 		DisableInformer bool `mapstructure:"disable_informer"`
 		// Deprecated: Use Informer.Enabled and APIServer.Enabled instead
@@ -35,6 +71,9 @@ type Config struct {
 	Logging struct {
 		Level  string `mapstructure:"level"`
 		Format string `mapstructure:"format"`
+
+		// Masks credentials in log fields before they are written
+		Redaction redact.Config `mapstructure:"redaction"`
 	} `mapstructure:"logging"`
 
 	// Informer settings
@@ -67,12 +106,152 @@ type Config struct {
 		Security struct {
 			RateLimitRequestsPerSecond int  `mapstructure:"rate_limit_requests_per_second"`
 			MaxConnsPerIP              int  `mapstructure:"max_connections_per_ip"`
-			ReadTimeoutSeconds         int  `mapstructure:"read_timeout_seconds"`
-			WriteTimeoutSeconds        int  `mapstructure:"write_timeout_seconds"`
-			IdleTimeoutSeconds         int  `mapstructure:"idle_timeout_seconds"`
+			ReadTimeoutSeconds         int  `mapstructure:"read_timeout_seconds" unit:"seconds"`
+			WriteTimeoutSeconds        int  `mapstructure:"write_timeout_seconds" unit:"seconds"`
+			IdleTimeoutSeconds         int  `mapstructure:"idle_timeout_seconds" unit:"seconds"`
 			DisableKeepalive           bool `mapstructure:"disable_keepalive"`
+
+			// Per-IP limiter eviction: least recently used IPs are dropped beyond max entries,
+			// and IPs idle for longer than the TTL are removed
+			RateLimitMaxEntries int           `mapstructure:"rate_limit_max_entries"`
+			RateLimitIdleTTL    time.Duration `mapstructure:"rate_limit_idle_ttl"`
+
+			// Per-route limits applied instead of the global rate for matching requests
+			RouteRateLimits []RouteRateLimit `mapstructure:"route_rate_limits"`
 		} `mapstructure:"security"`
 
+		// Authentication and role-based authorization settings
+		Auth auth.Config `mapstructure:"auth"`
+
+		// Lockouts after repeated authentication failures and brute-force alerts
+		Lockout lockout.Config `mapstructure:"lockout"`
+
+		// Audit logging of mutating API calls
+		Audit audit.Config `mapstructure:"audit"`
+
+		// Admin-started capture of full requests and responses for debugging clients
+		DebugCapture debugcapture.Config `mapstructure:"debug_capture"`
+
+		// Double-submit CSRF protection for browser clients
+		CSRF csrf.Config `mapstructure:"csrf"`
+
+		// Signature verification of inbound webhooks such as GitHub, GitLab and CI events
+		InboundWebhooks hooksig.Config `mapstructure:"inbound_webhooks"`
+
+		// Server-Sent Events streams such as /deployments/watch; max_duration also ends followed pod logs
+		Watch stream.Config `mapstructure:"watch"`
+
+		// Content-Security-Policy, HSTS and related response headers
+		SecurityHeaders secheaders.Config `mapstructure:"security_headers"`
+
+		// Gzip or deflate compression of large responses
+		Compression struct {
+			Enabled bool     `mapstructure:"enabled"`
+			MinSize int      `mapstructure:"min_size" unit:"bytes"` // Smallest body in bytes worth compressing
+			Level   int      `mapstructure:"level"`                 // 1 (fastest) to 9 (smallest), 6 when out of range
+			Paths   []string `mapstructure:"paths"`                 // Endpoints to compress, empty compresses every buffered response
+		} `mapstructure:"compression"`
+
+		// Prometheus metrics served by the API server itself
+		Metrics struct {
+			Enabled   bool `mapstructure:"enabled"`   // Serve /metrics, which negotiates OpenMetrics unlike the controller-runtime endpoint
+			Exemplars bool `mapstructure:"exemplars"` // Attach request IDs to latency observations as exemplars
+		} `mapstructure:"metrics"`
+
+		// Per-route bounds on the time handlers may take
+		Timeouts timeout.Config `mapstructure:"timeouts"`
+
+		// Priority classes of routes and shedding of low-priority requests under overload
+		LoadShedding loadshed.Config `mapstructure:"load_shedding"`
+
+		// Short-lived cache of list calls shared by identical list requests
+		ListCache listcache.Config `mapstructure:"list_cache"`
+
+		// How long a shutdown waits for in-flight requests and streams before closing connections
+		ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+
+		// Items a list response returns before it is truncated with a continue token
+		ResponseLimits ResponseLimits `mapstructure:"response_limits"`
+
+		// Pod, service and node lists of the primary cluster served from informer listers
+		Listers struct {
+			Enabled bool `mapstructure:"enabled"` // Disable to list from the API server on every request, saving the memory of the informers
+		} `mapstructure:"listers"`
+
+		// pprof profiles and runtime statistics under /debug, served only with authentication
+		Profiling struct {
+			Enabled bool `mapstructure:"enabled"`
+		} `mapstructure:"profiling"`
+
+		// Endpoint groups removed at deploy time, such as secrets, exec or every write operation
+		Endpoints endpoints.Config `mapstructure:"endpoints"`
+
+		// Secrets endpoint, which only ever returns metadata, type and key names
+		Secrets struct {
+			Enabled bool `mapstructure:"enabled"` // Disable to remove /secrets entirely in hardened deployments
+		} `mapstructure:"secrets"`
+
+		// Generic /raw endpoints serving allowlisted resources through the dynamic client
+		Raw rawapi.Config `mapstructure:"raw"`
+
+		// gRPC API served on its own port next to the REST API
+		GRPC struct {
+			Enabled    bool `mapstructure:"enabled"`
+			Port       int  `mapstructure:"port"`
+			Reflection bool `mapstructure:"reflection"` // Let tools such as grpcurl discover the service
+		} `mapstructure:"grpc"`
+
+		// WebSocket endpoint for live resource updates
+		WebSocket struct {
+			Enabled          bool          `mapstructure:"enabled"`
+			Kinds            []string      `mapstructure:"kinds"`                          // Kinds streamed in addition to Deployment: Pod, Service, Node
+			AllowedOrigins   []string      `mapstructure:"allowed_origins"`                // Browser origins allowed to connect, empty allows same origin only
+			MaxConnections   int           `mapstructure:"max_connections"`                // Open connections across all clients, 0 for unlimited
+			MaxSubscriptions int           `mapstructure:"max_subscriptions"`              // Subscriptions per connection, 0 for unlimited
+			SendBuffer       int           `mapstructure:"send_buffer"`                    // Frames queued per connection before the watch overflow policy applies
+			MaxMessageBytes  int64         `mapstructure:"max_message_bytes" unit:"bytes"` // Largest accepted client message
+			PingInterval     time.Duration `mapstructure:"ping_interval"`                  // Keep-alive ping interval; clients must answer within two intervals
+		} `mapstructure:"websocket"`
+
+		// Combined event stream of every managed cluster at /firehose, over SSE or WebSocket
+		Firehose struct {
+			Enabled        bool     `mapstructure:"enabled"`
+			AllowedOrigins []string `mapstructure:"allowed_origins"` // Browser origins allowed to connect over WebSocket, empty allows same origin only
+			MaxConnections int      `mapstructure:"max_connections"` // Open streams across all clients, 0 for unlimited
+		} `mapstructure:"firehose"`
+
+		// Interactive exec into pod containers over WebSocket, for web terminals
+		Exec struct {
+			Enabled        bool          `mapstructure:"enabled"`
+			AllowedOrigins []string      `mapstructure:"allowed_origins"` // Browser origins allowed to connect, empty allows same origin only
+			MaxSessions    int           `mapstructure:"max_sessions"`    // Open sessions across all clients, 0 for unlimited
+			IdleTimeout    time.Duration `mapstructure:"idle_timeout"`    // Sessions without input or output for this long are closed, 0 disables
+			PingInterval   time.Duration `mapstructure:"ping_interval"`   // Keep-alive ping interval; clients must answer within two intervals
+		} `mapstructure:"exec"`
+
+		// Port-forwarding and HTTP proxying into pods and services through the API server
+		PortForward struct {
+			Enabled        bool          `mapstructure:"enabled"`
+			AllowedOrigins []string      `mapstructure:"allowed_origins"` // Browser origins allowed to open WebSocket tunnels, empty allows same origin only
+			MaxConnections int           `mapstructure:"max_connections"` // Open tunnels and proxied requests across all clients, 0 for unlimited
+			IdleTimeout    time.Duration `mapstructure:"idle_timeout"`    // WebSocket tunnels without traffic for this long are closed, 0 disables
+			ProxyTimeout   time.Duration `mapstructure:"proxy_timeout"`   // Time a proxied HTTP request may take, including its response body
+		} `mapstructure:"port_forward"`
+
+		// HTTP server implementation; net/http adds HTTP/2 over TLS and, with h2c, over cleartext
+		HTTPServer stdhttp.Config `mapstructure:"http_server"`
+
+		// Native HTTPS listener settings
+		TLS struct {
+			Enabled         bool          `mapstructure:"enabled"`
+			CertFile        string        `mapstructure:"cert_file"`        // PEM certificate path
+			KeyFile         string        `mapstructure:"key_file"`         // PEM private key path
+			SecretName      string        `mapstructure:"secret_name"`      // kubernetes.io/tls Secret, used instead of files when set
+			SecretNamespace string        `mapstructure:"secret_namespace"` // Namespace of the Secret
+			ReloadInterval  time.Duration `mapstructure:"reload_interval"`  // How often to check for changed certificates, 0 disables reload
+			MinVersion      string        `mapstructure:"min_version"`      // Minimum TLS version: 1.2 or 1.3
+		} `mapstructure:"tls"`
+
 		// Swagger UI specific settings
 		SwaggerUI struct {
 			Enabled          bool   `mapstructure:"enabled"`
@@ -80,7 +259,7 @@ type Config struct {
 			CORSAllowOrigin  string `mapstructure:"cors_allow_origin"`
 			CORSAllowMethods string `mapstructure:"cors_allow_methods"`
 			CORSAllowHeaders string `mapstructure:"cors_allow_headers"`
-			CORSMaxAge       int    `mapstructure:"cors_max_age"`
+			CORSMaxAge       int    `mapstructure:"cors_max_age" unit:"seconds"`
 			UseStrictCSP     bool   `mapstructure:"use_strict_csp"`
 		} `mapstructure:"swagger_ui"`
 	} `mapstructure:"api_server"`
@@ -92,12 +271,149 @@ type Config struct {
 			Enabled   bool   `mapstructure:"enabled"`
 			ID        string `mapstructure:"id"`
 			Namespace string `mapstructure:"namespace"`
+
+			// Lease timings; longer values tolerate flaky networks and cross-region API latency
+			// at the cost of slower failover
+			LeaseDuration time.Duration `mapstructure:"lease_duration"`
+			RenewDeadline time.Duration `mapstructure:"renew_deadline"`
+			RetryPeriod   time.Duration `mapstructure:"retry_period"`
 		} `mapstructure:"leader_election"`
 		// Metrics server settings
 		Metrics struct {
 			BindAddress string `mapstructure:"bind_address"`
 		} `mapstructure:"metrics"`
+
+		// Health probe settings; the primary cluster's manager serves /healthz and /readyz here
+		HealthProbe struct {
+			BindAddress string `mapstructure:"bind_address"`
+		} `mapstructure:"health_probe"`
+
+		// API groups registered in the managers' scheme: core, apps, batch, networking, policy,
+		// rbac, autoscaling or storage
+		SchemeGroups []string `mapstructure:"scheme_groups"`
+
+		// Kinds the managers report events for; custom resources are watched as unstructured
+		// objects and every kind must be served by the cluster
+		Watches []struct {
+			Group   string `mapstructure:"group"`
+			Version string `mapstructure:"version"`
+			Kind    string `mapstructure:"kind"`
+		} `mapstructure:"watches"`
+
+		// Deletion of finished Jobs after a TTL, for clusters whose API servers lack or
+		// disable the TTL-after-finished controller
+		JobGC ctrl.JobGCConfig `mapstructure:"job_gc"`
+
+		// Ephemeral environments: Environment resources clone a source namespace's workloads
+		// into a new namespace that is deleted after a TTL
+		Environments ctrl.EnvironmentConfig `mapstructure:"environments"`
+
+		// Cluster removal: how long DELETE /clusters waits for a manager to stop and its
+		// in-flight reconciles and notifications to finish
+		Removal struct {
+			DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+		} `mapstructure:"removal"`
 	} `mapstructure:"controller_runtime"`
+
+	// Namespace ownership metadata used to route problems to owning teams
+	Ownership ownership.Config `mapstructure:"ownership"`
+
+	// Change-freeze windows during which mutating requests and controllers refuse changes
+	Freeze freeze.Config `mapstructure:"freeze"`
+
+	// Trivy image vulnerability scanning
+	ImageScan vulnscan.Config `mapstructure:"image_scan"`
+
+	// Policies for /security/exposure: public exposure these rules allow is not flagged
+	Exposure exposure.Config `mapstructure:"exposure"`
+
+	// Settings of /security/workloads
+	WorkloadAudit podaudit.Config `mapstructure:"workload_audit"`
+
+	// Deprecated APIs reported at /deprecations, on top of the built-in table
+	Deprecations deprecation.Config `mapstructure:"deprecations"`
+
+	// Upgrade readiness checks served at /clusters/{id}/upgrade-check
+	UpgradeCheck upgradecheck.Config `mapstructure:"upgrade_check"`
+
+	// Bulk label and annotation edits through /labels/apply
+	LabelEdits relabel.Config `mapstructure:"label_edits"`
+
+	// Drift detection against desired-state manifests
+	Drift drift.Config `mapstructure:"drift"`
+
+	// Detection of deployment rollouts that stopped making progress
+	StuckRollouts rollout.Config `mapstructure:"stuck_rollouts"`
+
+	// Resource right-sizing recommendations from metrics-server usage
+	Recommendations recommend.Config `mapstructure:"recommendations"`
+
+	// Capacity forecasting from allocatable and requested resource trends
+	Capacity capacity.Config `mapstructure:"capacity"`
+
+	// Replica and node readiness history kept in an embedded store for /history
+	History history.Config `mapstructure:"history"`
+
+	// Background precomputation of cross-cluster aggregations such as /overview
+	Precompute precompute.Config `mapstructure:"precompute"`
+
+	// Circuit breaking of failing clusters behind aggregated endpoints such as /overview
+	CircuitBreaker circuit.Config `mapstructure:"circuit_breaker"`
+
+	// Deployment migrations between clusters through /migrate
+	Migration migrate.Config `mapstructure:"migration"`
+
+	// Namespaces mirrored into a warm standby cluster, activated through /failover
+	Standby migrate.StandbyConfig `mapstructure:"standby"`
+
+	// Scheduled inventory, drift, SLO and cost reports delivered by email, Slack or webhook
+	Reports report.Config `mapstructure:"reports"`
+
+	// OpenTelemetry tracing of API requests, Kubernetes API calls and reconciles
+	Tracing tracing.Config `mapstructure:"tracing"`
+
+	// Plugin settings
+	Plugins struct {
+		Paths []string `mapstructure:"paths"` // Go plugin (.so) files to load at startup
+	} `mapstructure:"plugins"`
+
+	// Where each option's value came from, keyed by configuration key
+	sources    map[string]*configSource
+	configFile string // Config file that was read, empty when there was none
+}
+
+// RouteRateLimit sets the per-IP request rate for a path and set of methods
+type RouteRateLimit struct {
+	Path              string   `mapstructure:"path"`    // Exact path or prefix, e.g. /clusters
+	Methods           []string `mapstructure:"methods"` // Empty matches all methods
+	RequestsPerSecond int      `mapstructure:"requests_per_second"`
+}
+
+// ResponseLimits caps the items a list response returns when the client does not page
+type ResponseLimits struct {
+	MaxItems   int64            `mapstructure:"max_items"`  // Items returned before a list is truncated with a continue token, 0 for no limit
+	Namespaces map[string]int64 `mapstructure:"namespaces"` // Limits of lists within particular namespaces, overriding max_items
+}
+
+// Validate checks that no limit is negative
+func (l ResponseLimits) Validate() error {
+	if l.MaxItems < 0 {
+		return fmt.Errorf("max_items must not be negative, got %d", l.MaxItems)
+	}
+	for namespace, limit := range l.Namespaces {
+		if limit < 0 {
+			return fmt.Errorf("namespaces.%s: limit must not be negative, got %d", namespace, limit)
+		}
+	}
+	return nil
+}
+
+// MaxItemsFor returns the limit of lists within a namespace, empty for all namespaces
+func (l ResponseLimits) MaxItemsFor(namespace string) int64 {
+	if limit, ok := l.Namespaces[namespace]; ok && namespace != "" {
+		return limit
+	}
+	return l.MaxItems
 }
 
 // homeDir returns the path to the user's home directory
@@ -108,10 +424,88 @@ func homeDir() string {
 	return ""
 }
 
-// LoadConfig loads configuration from file and environment variables
-// Exported function for use in tests and other packages
-func LoadConfig() (*Config, error) {
-	// Initialize config with default values
+// envBinding maps a configuration key to an environment variable
+type envBinding struct {
+	Key string
+	Env string
+}
+
+// legacyEnvBindings are the unprefixed variables some options were read from before every
+// option moved to KCUSTOM_<KEY>. Names such as KUBERNETES_NAMESPACE clash with variables
+// set by Kubernetes and Helm charts, so they are no longer read; a warning names the
+// replacement when one is set.
+var legacyEnvBindings = []envBinding{
+	// Kubernetes configuration
+	{"kubernetes.namespace", "KUBERNETES_NAMESPACE"},
+	{"kubernetes.kubeconfig", "KUBERNETES_KUBECONFIG"},
+	{"kubernetes.context", "KUBERNETES_CONTEXT"},
+	{"kubernetes.qps", "KUBERNETES_QPS"},
+	{"kubernetes.burst", "KUBERNETES_BURST"},
+	{"kubernetes.timeout", "KUBERNETES_TIMEOUT"},
+	{"kubernetes.in_cluster", "KUBERNETES_IN_CLUSTER"},
+	{"kubernetes.disable_informer", "KUBERNETES_DISABLE_INFORMER"},
+	{"kubernetes.disable_api", "KUBERNETES_DISABLE_API"},
+
+	// Logging configuration
+	{"logging.level", "LOGGING_LEVEL"},
+	{"logging.format", "LOGGING_FORMAT"},
+	{"logging.redaction.enabled", "LOGGING_REDACTION_ENABLED"},
+
+	// Informer configuration
+	{"informer.enabled", "INFORMER_ENABLED"},
+	{"informer.namespace", "INFORMER_NAMESPACE"},
+	{"informer.resync_period", "INFORMER_RESYNC_PERIOD"},
+	{"informer.label_selector", "INFORMER_LABEL_SELECTOR"},
+	{"informer.field_selector", "INFORMER_FIELD_SELECTOR"},
+	{"informer.logging.enable_event_logging", "INFORMER_LOGGING_ENABLE_EVENT_LOGGING"},
+	{"informer.logging.log_level", "INFORMER_LOGGING_LOG_LEVEL"},
+	{"informer.workers.count", "INFORMER_WORKERS_COUNT"},
+
+	// API Server configuration
+	{"api_server.enabled", "APISERVER_ENABLED"},
+	{"api_server.host", "APISERVER_HOST"},
+	{"api_server.port", "APISERVER_PORT"},
+	{"api_server.grpc.enabled", "APISERVER_GRPC_ENABLED"},
+	{"api_server.grpc.port", "APISERVER_GRPC_PORT"},
+	{"api_server.enable_swagger", "APISERVER_ENABLE_SWAGGER"},
+	{"api_server.security.rate_limit_requests_per_second", "APISERVER_RATE_LIMIT"},
+	{"api_server.security.rate_limit_max_entries", "APISERVER_RATE_LIMIT_MAX_ENTRIES"},
+	{"api_server.security.rate_limit_idle_ttl", "APISERVER_RATE_LIMIT_IDLE_TTL"},
+	{"api_server.audit.enabled", "APISERVER_AUDIT_ENABLED"},
+	{"api_server.audit.file.path", "APISERVER_AUDIT_FILE"},
+	{"api_server.audit.webhook.url", "APISERVER_AUDIT_WEBHOOK_URL"},
+	{"api_server.security.max_connections_per_ip", "APISERVER_MAX_CONNS_PER_IP"},
+	{"api_server.security.read_timeout_seconds", "APISERVER_READ_TIMEOUT"},
+	{"api_server.security.write_timeout_seconds", "APISERVER_WRITE_TIMEOUT"},
+	{"api_server.security.idle_timeout_seconds", "APISERVER_IDLE_TIMEOUT"},
+	{"api_server.auth.enabled", "APISERVER_AUTH_ENABLED"},
+	{"api_server.auth.anonymous_role", "APISERVER_AUTH_ANONYMOUS_ROLE"},
+	{"api_server.auth.jwt.secret", "APISERVER_AUTH_JWT_SECRET"},
+	{"api_server.auth.jwt.issuer", "APISERVER_AUTH_JWT_ISSUER"},
+	{"api_server.compression.enabled", "APISERVER_COMPRESSION_ENABLED"},
+	{"api_server.lockout.enabled", "APISERVER_LOCKOUT_ENABLED"},
+	{"api_server.lockout.alert_webhook_url", "APISERVER_LOCKOUT_ALERT_WEBHOOK_URL"},
+	{"api_server.csrf.enabled", "APISERVER_CSRF_ENABLED"},
+	{"api_server.csrf.secret", "APISERVER_CSRF_SECRET"},
+	{"api_server.tls.enabled", "APISERVER_TLS_ENABLED"},
+	{"api_server.tls.cert_file", "APISERVER_TLS_CERT_FILE"},
+	{"api_server.tls.key_file", "APISERVER_TLS_KEY_FILE"},
+	{"api_server.tls.secret_name", "APISERVER_TLS_SECRET_NAME"},
+	{"api_server.tls.secret_namespace", "APISERVER_TLS_SECRET_NAMESPACE"},
+
+	// Controller Runtime configuration
+	{"controller_runtime.leader_election.enabled", "CONTROLLER_LEADER_ELECTION_ENABLED"},
+	{"controller_runtime.leader_election.id", "CONTROLLER_LEADER_ELECTION_ID"},
+	{"controller_runtime.leader_election.namespace", "CONTROLLER_LEADER_ELECTION_NAMESPACE"},
+	{"controller_runtime.leader_election.lease_duration", "CONTROLLER_LEADER_ELECTION_LEASE_DURATION"},
+	{"controller_runtime.leader_election.renew_deadline", "CONTROLLER_LEADER_ELECTION_RENEW_DEADLINE"},
+	{"controller_runtime.leader_election.retry_period", "CONTROLLER_LEADER_ELECTION_RETRY_PERIOD"},
+	{"controller_runtime.metrics.bind_address", "CONTROLLER_METRICS_BIND_ADDRESS"},
+}
+
+// defaultConfig returns the configuration used before the config file and environment
+// variables are applied
+func defaultConfig() *Config {
 	config := &Config{}
 
 	// Set default values for Kubernetes
@@ -126,6 +520,9 @@ func LoadConfig() (*Config, error) {
 	// Default values for logging
 	config.Logging.Level = "info"
 	config.Logging.Format = "text"
+	config.Logging.Redaction.Enabled = true
+	config.Logging.Redaction.Patterns = redact.DefaultPatterns
+	config.Logging.Redaction.Replacement = redact.DefaultReplacement
 
 	// Default values for informer
 	config.Informer.Enabled = true // Enable informer by default
@@ -145,9 +542,121 @@ func LoadConfig() (*Config, error) {
 	config.APIServer.Security.RateLimitRequestsPerSecond = 100
 	config.APIServer.Security.MaxConnsPerIP = 10
 	config.APIServer.Security.ReadTimeoutSeconds = 30
+	config.APIServer.HTTPServer.Implementation = stdhttp.FastHTTP
 	config.APIServer.Security.WriteTimeoutSeconds = 30
 	config.APIServer.Security.IdleTimeoutSeconds = 60
 	config.APIServer.Security.DisableKeepalive = false
+	config.APIServer.Security.RateLimitMaxEntries = 10000
+	config.APIServer.Security.RateLimitIdleTTL = 10 * time.Minute
+	config.APIServer.Auth.Enabled = false // Authentication is opt-in
+	config.APIServer.Auth.PublicPaths = auth.DefaultPublicPaths
+	config.APIServer.Auth.JWT.RoleClaim = "role"
+	config.APIServer.Auth.JWT.ScopeClaim = "scope"
+	config.APIServer.Lockout.Enabled = true // Only applies when authentication is enabled
+	config.APIServer.Lockout.MaxFailures = 5
+	config.APIServer.Lockout.Window = 10 * time.Minute
+	config.APIServer.Lockout.BaseDuration = time.Minute
+	config.APIServer.Lockout.MaxDuration = time.Hour
+	config.APIServer.Lockout.ForgetAfter = 24 * time.Hour
+	config.APIServer.Lockout.DistributedThreshold = 20
+	config.APIServer.Lockout.MaxEvents = 1000
+	config.APIServer.CSRF.Enabled = false // Only needed for browser clients
+	config.APIServer.CSRF.CookieName = csrf.DefaultCookieName
+	config.APIServer.CSRF.HeaderName = csrf.DefaultHeaderName
+	config.APIServer.CSRF.CookieMaxAge = 12 * time.Hour
+	config.APIServer.CSRF.ExemptTokenAuth = true
+	config.APIServer.InboundWebhooks.Enabled = false
+	config.APIServer.InboundWebhooks.SecretTTL = time.Minute
+	config.APIServer.SecurityHeaders.Enabled = true
+	config.APIServer.SecurityHeaders.ContentSecurityPolicy = secheaders.DefaultContentSecurityPolicy
+	config.APIServer.SecurityHeaders.ReferrerPolicy = secheaders.DefaultReferrerPolicy
+	config.APIServer.SecurityHeaders.PermissionsPolicy = secheaders.DefaultPermissionsPolicy
+	config.APIServer.SecurityHeaders.HSTSMaxAge = secheaders.DefaultHSTSMaxAge
+	config.APIServer.SecurityHeaders.HSTSIncludeSubdomains = true
+	config.APIServer.Compression.Enabled = false
+	config.APIServer.Compression.MinSize = 1024
+	config.APIServer.Compression.Level = 6
+	config.APIServer.Compression.Paths = defaultCompressionPaths
+	config.APIServer.Metrics.Enabled = false
+	config.APIServer.Metrics.Exemplars = true
+	config.APIServer.Profiling.Enabled = false // Profiles expose internals, so admins opt in
+	config.APIServer.Timeouts.Enabled = true
+	config.APIServer.Timeouts.Default = timeout.DefaultTimeout
+	config.APIServer.Timeouts.Routes = []timeout.Route{
+		{Path: "/pods/*/*/logs", Timeout: 5 * time.Minute},
+		{Path: "/debug/pprof", Timeout: 5 * time.Minute}, // CPU profiles and traces run for ?seconds=
+	}
+	config.APIServer.LoadShedding.Enabled = false
+	config.APIServer.LoadShedding.MaxInFlight = 256
+	config.APIServer.LoadShedding.MemoryLimit = 0
+	config.APIServer.LoadShedding.SampleInterval = time.Second
+	config.APIServer.LoadShedding.Routes = []loadshed.Route{
+		{Path: "/health", Priority: loadshed.Critical},
+		{Path: "/livez", Priority: loadshed.Critical},
+		{Path: "/readyz", Priority: loadshed.Critical},
+		{Path: "/metrics", Priority: loadshed.High},
+		{Path: "/debug", Priority: loadshed.High},
+		{Path: "/admin", Priority: loadshed.High},
+		{Path: "/deployments/watch", Priority: loadshed.Low},
+		{Path: "/ws", Priority: loadshed.Low},
+		{Path: "/firehose", Priority: loadshed.Low},
+		{Path: "/pods/*/*/logs", Priority: loadshed.Low},
+		{Path: "/pods/*/*/exec", Priority: loadshed.Low},
+		{Path: "/pods/*/*/portforward", Priority: loadshed.Low},
+		{Path: "/services/*/*/portforward", Priority: loadshed.Low},
+		{Path: "/export", Priority: loadshed.Low},
+	}
+	config.APIServer.ListCache.Enabled = false
+	config.APIServer.ListCache.TTL = 5 * time.Second
+	config.APIServer.ListCache.MaxEntries = listcache.DefaultMaxEntries
+	config.APIServer.ListCache.Routes = defaultListCacheRoutes
+	config.APIServer.Secrets.Enabled = true
+	config.APIServer.Listers.Enabled = true
+	config.APIServer.ShutdownTimeout = 30 * time.Second
+	config.APIServer.Raw.Enabled = false
+	config.APIServer.GRPC.Enabled = false
+	config.APIServer.GRPC.Port = 9090
+	config.APIServer.GRPC.Reflection = true
+	config.APIServer.Watch.HistorySize = 1000
+	config.APIServer.Watch.BufferSize = 256
+	config.APIServer.Watch.Heartbeat = 15 * time.Second
+	config.APIServer.Watch.MaxDuration = time.Hour
+	config.APIServer.Watch.OverflowPolicy = stream.OverflowDisconnect
+	config.APIServer.WebSocket.Enabled = true
+	config.APIServer.WebSocket.MaxConnections = 100
+	config.APIServer.WebSocket.MaxSubscriptions = 20
+	config.APIServer.WebSocket.SendBuffer = 256
+	config.APIServer.WebSocket.MaxMessageBytes = 4096
+	config.APIServer.WebSocket.PingInterval = 30 * time.Second
+	config.APIServer.Firehose.Enabled = false
+	config.APIServer.Firehose.MaxConnections = 20
+	config.APIServer.Exec.Enabled = false
+	config.APIServer.Exec.MaxSessions = 20
+	config.APIServer.Exec.IdleTimeout = 15 * time.Minute
+	config.APIServer.Exec.PingInterval = 30 * time.Second
+	config.APIServer.PortForward.Enabled = false
+	config.APIServer.PortForward.MaxConnections = 50
+	config.APIServer.PortForward.IdleTimeout = 15 * time.Minute
+	config.APIServer.PortForward.ProxyTimeout = time.Minute
+	config.APIServer.Audit.Enabled = false
+	config.APIServer.Audit.Methods = audit.DefaultMethods
+	config.APIServer.Audit.Paths = []string{"/clusters", "/labels"}
+	config.APIServer.Audit.Stdout = false
+	config.APIServer.Audit.File.MaxSizeMB = 100
+	config.APIServer.Audit.File.MaxBackups = 10
+	config.APIServer.Audit.File.MaxAge = 30 * 24 * time.Hour
+	config.APIServer.Audit.Webhook.Timeout = 5 * time.Second
+	config.APIServer.Audit.QueueSize = 1000
+	config.APIServer.Audit.History = 1000
+	config.APIServer.DebugCapture.Enabled = false // Captures hold full bodies, so admins opt in
+	config.APIServer.DebugCapture.MaxBodyBytes = 64 << 10
+	config.APIServer.DebugCapture.MaxCaptures = 200
+	config.APIServer.DebugCapture.DefaultDuration = 5 * time.Minute
+	config.APIServer.DebugCapture.MaxDuration = time.Hour
+	config.APIServer.TLS.Enabled = false
+	config.APIServer.TLS.SecretNamespace = "default"
+	config.APIServer.TLS.ReloadInterval = 30 * time.Second
+	config.APIServer.TLS.MinVersion = "1.2"
 	config.APIServer.SwaggerUI.Enabled = true
 	config.APIServer.SwaggerUI.CORSEnabled = false
 	config.APIServer.SwaggerUI.CORSAllowOrigin = "*"
@@ -160,13 +669,114 @@ func LoadConfig() (*Config, error) {
 	config.ControllerRuntime.LeaderElection.Enabled = false
 	config.ControllerRuntime.LeaderElection.ID = "k8s-controller"
 	config.ControllerRuntime.LeaderElection.Namespace = "kube-system"
+	config.ControllerRuntime.LeaderElection.LeaseDuration = ctrl.DefaultLeaseDuration
+	config.ControllerRuntime.LeaderElection.RenewDeadline = ctrl.DefaultRenewDeadline
+	config.ControllerRuntime.LeaderElection.RetryPeriod = ctrl.DefaultRetryPeriod
 	config.ControllerRuntime.Metrics.BindAddress = ":8081"
+	config.ControllerRuntime.HealthProbe.BindAddress = ":8082"
+	config.ControllerRuntime.SchemeGroups = ctrl.DefaultSchemeGroups
+	config.ControllerRuntime.Removal.DrainTimeout = 60 * time.Second
+	config.ControllerRuntime.JobGC.Enabled = false
+	config.ControllerRuntime.JobGC.TTL = 24 * time.Hour
+	config.ControllerRuntime.Environments.Enabled = false
+	config.ControllerRuntime.Environments.DefaultTTL = 72 * time.Hour
+	config.ControllerRuntime.Environments.MaxTTL = 14 * 24 * time.Hour
+
+	// Default values for namespace ownership
+	config.Ownership.AnnotationPrefix = ownership.DefaultAnnotationPrefix
+	config.Ownership.CacheTTL = 5 * time.Minute
+
+	// Default values for image scanning
+	config.ImageScan.Enabled = false
+	config.ImageScan.TrivyPath = "trivy"
+	config.ImageScan.Interval = 6 * time.Hour
+	config.ImageScan.Timeout = 5 * time.Minute
+	config.ImageScan.Severities = []string{"CRITICAL", "HIGH"}
+
+	// Default values for drift detection
+	config.Drift.Enabled = false
+	config.Drift.Interval = 10 * time.Minute
+
+	// Default values for stuck rollout detection
+	config.StuckRollouts.Enabled = false
+	config.StuckRollouts.Interval = time.Minute
+	config.StuckRollouts.Window = 10 * time.Minute
+
+	// Default values for resource recommendations
+	config.Recommendations.Enabled = false
+	config.Recommendations.Interval = time.Minute
+	config.Recommendations.Window = 24 * time.Hour
+	config.Recommendations.MinSamples = 30
+	config.Recommendations.CPUPercentile = 0.95
+	config.Recommendations.Headroom = 0.15
+	config.Recommendations.Tolerance = 0.2
+	config.Recommendations.AnnotationPrefix = recommend.DefaultAnnotationPrefix
+
+	// Default values for capacity forecasting
+	config.Capacity.Enabled = false
+	config.Capacity.Interval = 15 * time.Minute
+	config.Capacity.Window = 7 * 24 * time.Hour
+	config.Capacity.MinSamples = 12
+	config.Capacity.PoolLabels = capacity.DefaultPoolLabels
+
+	// Default values for the replica and readiness history
+	config.History.Enabled = false
+	config.History.Interval = time.Minute
+	config.History.Retention = 30 * 24 * time.Hour
+	config.History.FlushInterval = 5 * time.Minute
+
+	config.UpgradeCheck.SurgeNodes = 0
+	config.UpgradeCheck.KubeletSkew = 3
+
+	config.LabelEdits.MaxObjects = relabel.DefaultMaxObjects
+
+	// Default values for aggregation precomputation
+	config.Precompute.Enabled = false
+	config.Precompute.Interval = time.Minute
+	config.Precompute.MaxStaleness = 5 * time.Minute
+	config.Precompute.Timeout = 30 * time.Second
+
+	// Default values for cluster circuit breaking
+	config.CircuitBreaker.Enabled = false
+	config.CircuitBreaker.FailureThreshold = 3
+	config.CircuitBreaker.OpenDuration = 30 * time.Second
+	config.CircuitBreaker.MaxAge = time.Hour
+
+	// Default values for deployment migrations
+	config.Migration.ReadyTimeout = 5 * time.Minute
+	config.Migration.MaxJobs = 100
+
+	// Default values for the warm standby
+	config.Standby.Enabled = false
+	config.Standby.Source = primaryClusterID
+	config.Standby.Interval = time.Minute
+
+	// Default values for scheduled reports
+	config.Reports.Enabled = false
+	config.Reports.SMTP.Port = 587
+	config.Reports.Pricing.Currency = "USD"
+
+	// Tracing is off until a collector is configured
+	config.Tracing.Enabled = false
+	config.Tracing.ServiceName = tracing.DefaultServiceName
+	config.Tracing.SampleRatio = 1
+	config.Tracing.ExportInterval = tracing.DefaultExportInterval
+	config.Tracing.Timeout = tracing.DefaultTimeout
 
 	// Set default kubeconfig path
 	if home := homeDir(); home != "" {
 		config.Kubernetes.Kubeconfig = filepath.Join(home, ".kube", "config")
 	}
 
+	return config
+}
+
+// LoadConfig loads configuration from file and environment variables
+// Exported function for use in tests and other packages
+func LoadConfig() (*Config, error) {
+	// Initialize config with default values
+	config := defaultConfig()
+
 	// Configure Viper
 	// Clear any potential previous configuration to avoid conflicts
 	viper.Reset()
@@ -174,10 +784,8 @@ func LoadConfig() (*Config, error) {
 	// Set config type explicitly
 	viper.SetConfigType("yaml")
 
-	// Automatically use environment variables
-	viper.AutomaticEnv()
-	viper.SetEnvPrefix("KCUSTOM") // KCUSTOM_KUBERNETES_NAMESPACE
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	// Read every option from KCUSTOM_<KEY>, e.g. KCUSTOM_KUBERNETES_NAMESPACE
+	bindConfigEnv()
 
 	// Set search paths for config file
 	if cfgFile != "" {
@@ -194,50 +802,6 @@ func LoadConfig() (*Config, error) {
 		viper.AddConfigPath("/etc/k8s-custom-controller")
 	}
 
-	// Explicitly bind environment variables to configuration keys
-
-	// Kubernetes configuration
-	viper.BindEnv("kubernetes.namespace", "KUBERNETES_NAMESPACE")
-	viper.BindEnv("kubernetes.kubeconfig", "KUBERNETES_KUBECONFIG")
-	viper.BindEnv("kubernetes.context", "KUBERNETES_CONTEXT")
-	viper.BindEnv("kubernetes.qps", "KUBERNETES_QPS")
-	viper.BindEnv("kubernetes.burst", "KUBERNETES_BURST")
-	viper.BindEnv("kubernetes.timeout", "KUBERNETES_TIMEOUT")
-	viper.BindEnv("kubernetes.in_cluster", "KUBERNETES_IN_CLUSTER")
-	viper.BindEnv("kubernetes.disable_informer", "KUBERNETES_DISABLE_INFORMER")
-	viper.BindEnv("kubernetes.disable_api", "KUBERNETES_DISABLE_API")
-
-	// Logging configuration
-	viper.BindEnv("logging.level", "LOGGING_LEVEL")
-	viper.BindEnv("logging.format", "LOGGING_FORMAT")
-
-	// Informer configuration
-	viper.BindEnv("informer.enabled", "INFORMER_ENABLED")
-	viper.BindEnv("informer.namespace", "INFORMER_NAMESPACE")
-	viper.BindEnv("informer.resync_period", "INFORMER_RESYNC_PERIOD")
-	viper.BindEnv("informer.label_selector", "INFORMER_LABEL_SELECTOR")
-	viper.BindEnv("informer.field_selector", "INFORMER_FIELD_SELECTOR")
-	viper.BindEnv("informer.logging.enable_event_logging", "INFORMER_LOGGING_ENABLE_EVENT_LOGGING")
-	viper.BindEnv("informer.logging.log_level", "INFORMER_LOGGING_LOG_LEVEL")
-	viper.BindEnv("informer.workers.count", "INFORMER_WORKERS_COUNT")
-
-	// API Server configuration
-	viper.BindEnv("api_server.enabled", "APISERVER_ENABLED")
-	viper.BindEnv("api_server.host", "APISERVER_HOST")
-	viper.BindEnv("api_server.port", "APISERVER_PORT")
-	viper.BindEnv("api_server.enable_swagger", "APISERVER_ENABLE_SWAGGER")
-	viper.BindEnv("api_server.security.rate_limit_requests_per_second", "APISERVER_RATE_LIMIT")
-	viper.BindEnv("api_server.security.max_connections_per_ip", "APISERVER_MAX_CONNS_PER_IP")
-	viper.BindEnv("api_server.security.read_timeout_seconds", "APISERVER_READ_TIMEOUT")
-	viper.BindEnv("api_server.security.write_timeout_seconds", "APISERVER_WRITE_TIMEOUT")
-	viper.BindEnv("api_server.security.idle_timeout_seconds", "APISERVER_IDLE_TIMEOUT")
-
-	// Controller Runtime configuration
-	viper.BindEnv("controller_runtime.leader_election.enabled", "CONTROLLER_LEADER_ELECTION_ENABLED")
-	viper.BindEnv("controller_runtime.leader_election.id", "CONTROLLER_LEADER_ELECTION_ID")
-	viper.BindEnv("controller_runtime.leader_election.namespace", "CONTROLLER_LEADER_ELECTION_NAMESPACE")
-	viper.BindEnv("controller_runtime.metrics.bind_address", "CONTROLLER_METRICS_BIND_ADDRESS")
-
 	// Attempt to read configuration file
 	err := viper.ReadInConfig()
 	if err != nil {
@@ -253,36 +817,31 @@ func LoadConfig() (*Config, error) {
 		}
 	} else {
 		log.Info().Str("config", viper.ConfigFileUsed()).Msg("Using config file")
+	}
 
-		// Dump all keys found in the configuration file for debugging
-		fmt.Println("DEBUG: All config keys and values:")
-		for _, key := range viper.AllKeys() {
-			fmt.Printf("DEBUG: Key: '%s' = '%v'\n", key, viper.Get(key))
-		}
-
-		// Specifically check if logging.level exists and what its value is
-		if viper.IsSet("logging.level") {
-			fmt.Printf("DEBUG: Found logging.level = '%s'\n", viper.GetString("logging.level"))
-		} else {
-			fmt.Println("DEBUG: logging.level is NOT SET in config")
-		}
+	// Accept durations and sizes such as 90s or 64KiB, naming the key of invalid values
+	if err := normalizeConfigUnits(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 
-		// Unmarshal configuration before explicit overrides
-		err = viper.Unmarshal(config)
-		if err != nil {
-			return nil, fmt.Errorf("unable to decode config: %v", err)
-		}
+	// Unmarshal the config file and environment variables over the defaults
+	err = viper.Unmarshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode config: %v", err)
+	}
+	resolveConfigSources(config)
 
+	if viper.ConfigFileUsed() != "" {
 		// Explicitly apply logging configuration
 		// This ensures the values from the config file are properly applied
 		if viper.IsSet("logging.level") {
 			config.Logging.Level = viper.GetString("logging.level")
-			fmt.Printf("DEBUG: Explicitly set config.Logging.Level = '%s'\n", config.Logging.Level)
+			log.Debug().Str("level", config.Logging.Level).Msg("Applied logging level from config")
 		}
 
 		if viper.IsSet("logging.format") {
 			config.Logging.Format = viper.GetString("logging.format")
-			fmt.Printf("DEBUG: Explicitly set config.Logging.Format = '%s'\n", config.Logging.Format)
+			log.Debug().Str("format", config.Logging.Format).Msg("Applied logging format from config")
 		}
 
 		// Explicitly set important values from Viper to ensure they're properly loaded
@@ -347,6 +906,9 @@ func LoadConfig() (*Config, error) {
 	// Add detailed debug logging for metrics configuration
 	log.Debug().
 		Str("metrics_bind_address", config.ControllerRuntime.Metrics.BindAddress).
+		Str("health_probe_bind_address", config.ControllerRuntime.HealthProbe.BindAddress).
+		Strs("scheme_groups", config.ControllerRuntime.SchemeGroups).
+		Int("watches", len(config.ControllerRuntime.Watches)).
 		Msg("Loaded metrics configuration")
 
 	// Add detailed debug logging for leader election configuration
@@ -354,6 +916,9 @@ func LoadConfig() (*Config, error) {
 		Bool("leader_election_enabled", config.ControllerRuntime.LeaderElection.Enabled).
 		Str("leader_election_id", config.ControllerRuntime.LeaderElection.ID).
 		Str("leader_election_namespace", config.ControllerRuntime.LeaderElection.Namespace).
+		Dur("leader_election_lease_duration", config.ControllerRuntime.LeaderElection.LeaseDuration).
+		Dur("leader_election_renew_deadline", config.ControllerRuntime.LeaderElection.RenewDeadline).
+		Dur("leader_election_retry_period", config.ControllerRuntime.LeaderElection.RetryPeriod).
 		Msg("Loaded leader election configuration")
 
 	// Add detailed debug logging for informer configuration
@@ -385,6 +950,8 @@ func (c *Config) ToInformerOptions() *informer.InformerOptions {
 		Burst:              c.Kubernetes.Burst,
 		Timeout:            c.Kubernetes.Timeout,
 		DisableInformer:    disableInformer,
+		RecordFixtures:     c.Kubernetes.RecordFixtures,
+		ClusterID:          primaryClusterID,
 	}
 
 	log.Debug().
@@ -452,11 +1019,32 @@ func ConfigCmd() *cobra.Command {
 			fmt.Printf("    Enabled: %t\n", config.ControllerRuntime.LeaderElection.Enabled)
 			fmt.Printf("    ID: %s\n", config.ControllerRuntime.LeaderElection.ID)
 			fmt.Printf("    Namespace: %s\n", config.ControllerRuntime.LeaderElection.Namespace)
+			fmt.Printf("    LeaseDuration: %s\n", config.ControllerRuntime.LeaderElection.LeaseDuration)
+			fmt.Printf("    RenewDeadline: %s\n", config.ControllerRuntime.LeaderElection.RenewDeadline)
+			fmt.Printf("    RetryPeriod: %s\n", config.ControllerRuntime.LeaderElection.RetryPeriod)
 			fmt.Println("  Metrics:")
 			fmt.Printf("    BindAddress: %s\n", config.ControllerRuntime.Metrics.BindAddress)
+			fmt.Println("  HealthProbe:")
+			fmt.Printf("    BindAddress: %s\n", config.ControllerRuntime.HealthProbe.BindAddress)
+			fmt.Printf("  SchemeGroups: %s\n", strings.Join(config.ControllerRuntime.SchemeGroups, ", "))
+			for _, w := range config.ControllerRuntime.Watches {
+				fmt.Printf("  Watch: %s/%s %s\n", w.Group, w.Version, w.Kind)
+			}
+			fmt.Println("  JobGC:")
+			fmt.Printf("    Enabled: %t\n", config.ControllerRuntime.JobGC.Enabled)
+			fmt.Printf("    TTL: %s\n", config.ControllerRuntime.JobGC.TTL)
+			fmt.Printf("    FailedTTL: %s\n", config.ControllerRuntime.JobGC.FailedTTL)
+			fmt.Printf("    Namespaces: %d\n", len(config.ControllerRuntime.JobGC.Namespaces))
+			fmt.Println("  Environments:")
+			fmt.Printf("    Enabled: %t\n", config.ControllerRuntime.Environments.Enabled)
+			fmt.Printf("    DefaultTTL: %s\n", config.ControllerRuntime.Environments.DefaultTTL)
+			fmt.Printf("    MaxTTL: %s\n", config.ControllerRuntime.Environments.MaxTTL)
+			fmt.Println("  Removal:")
+			fmt.Printf("    DrainTimeout: %s\n", config.ControllerRuntime.Removal.DrainTimeout)
 		},
 	}
 
 	configCmd.AddCommand(configViewCmd)
+	configCmd.AddCommand(configOptionsCmd())
 	return configCmd
 }
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/units"
+)
+
+// envPrefix is the prefix of every option's environment variable
+const envPrefix = "KCUSTOM"
+
+// Sources of an option's effective value, from lowest to highest precedence
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// configSource records where an option's effective value came from
+type configSource struct {
+	Source     string   `json:"source"`
+	SetBy      string   `json:"set_by,omitempty"`      // Config file path, environment variable or --flag
+	Overrides  []string `json:"overrides,omitempty"`   // Lower-precedence sources that also set the option
+	IgnoredEnv string   `json:"ignored_env,omitempty"` // Unprefixed variable that is set but no longer read
+}
+
+// envVarName returns the environment variable of a configuration key, e.g.
+// KCUSTOM_KUBERNETES_NAMESPACE for kubernetes.namespace
+func envVarName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// bindConfigEnv binds every option to its KCUSTOM_ variable, so the environment applies
+// with or without a config file
+func bindConfigEnv() {
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	for _, field := range configFields() {
+		viper.BindEnv(field.Key, envVarName(field.Key))
+	}
+}
+
+// normalizeConfigUnits rewrites the durations and sizes read from the config file and
+// environment into the values the Config struct holds, so 90s, 1h30m and 64KiB are
+// accepted wherever a duration, number of seconds or size is expected. Bare numbers keep
+// their meaning: seconds for durations, bytes or megabytes for sizes. Every invalid value
+// is reported with its key.
+func normalizeConfigUnits() error {
+	var errs []error
+	for _, field := range configFields() {
+		if field.Unit == "" {
+			continue
+		}
+		raw := viper.Get(field.Key)
+		if raw == nil {
+			continue
+		}
+		value, err := units.Parse(field.Unit, raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field.Key, err))
+			continue
+		}
+		viper.Set(field.Key, value)
+	}
+	return errors.Join(errs...)
+}
+
+// resolveConfigSources records the source of every option after the config file and
+// environment were read, and warns when several sources set the same option or an
+// unprefixed variable is set that is no longer read
+func resolveConfigSources(config *Config) {
+	legacy := make(map[string]string, len(legacyEnvBindings))
+	for _, b := range legacyEnvBindings {
+		legacy[b.Key] = b.Env
+	}
+	configFile := viper.ConfigFileUsed()
+	config.configFile = configFile
+
+	config.sources = make(map[string]*configSource)
+	for _, field := range configFields() {
+		source := &configSource{Source: sourceDefault}
+		inFile := configFile != "" && viper.InConfig(field.Key)
+		if inFile {
+			source.Source, source.SetBy = sourceFile, configFile
+		}
+		if name := envVarName(field.Key); os.Getenv(name) != "" {
+			if inFile {
+				source.Overrides = append(source.Overrides, sourceFile)
+				log.Warn().
+					Str("key", field.Key).
+					Str("env", name).
+					Str("config_file", configFile).
+					Msg("Environment variable overrides the config file")
+			}
+			source.Source, source.SetBy = sourceEnv, name
+		}
+		if name, ok := legacy[field.Key]; ok && os.Getenv(name) != "" {
+			source.IgnoredEnv = name
+			log.Warn().
+				Str("key", field.Key).
+				Str("env", name).
+				Str("use", envVarName(field.Key)).
+				Msg("Ignoring unprefixed environment variable, set the KCUSTOM_ variable instead")
+		}
+		config.sources[field.Key] = source
+	}
+}
+
+// setFlagSource records that a command-line flag set an option, warning when it overrides
+// the config file or an environment variable
+func (c *Config) setFlagSource(key, flag string) {
+	if c.sources == nil {
+		c.sources = make(map[string]*configSource)
+	}
+	previous := c.sources[key]
+	source := &configSource{Source: sourceFlag, SetBy: "--" + flag}
+	if previous != nil {
+		source.IgnoredEnv = previous.IgnoredEnv
+		if previous.Source != sourceDefault {
+			source.Overrides = append([]string{previous.Source}, previous.Overrides...)
+			log.Warn().
+				Str("key", key).
+				Str("flag", source.SetBy).
+				Str("overrides", previous.SetBy).
+				Msg("Command-line flag overrides the configured value")
+		}
+	}
+	c.sources[key] = source
+}
+
+// source returns where an option's effective value came from
+func (c *Config) source(key string) configSource {
+	if source, ok := c.sources[key]; ok {
+		return *source
+	}
+	return configSource{Source: sourceDefault}
+}
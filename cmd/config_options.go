@@ -0,0 +1,394 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/valyala/fasthttp"
+	"sigs.k8s.io/yaml"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/redact"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/units"
+)
+
+// configOption describes one configuration key found on the Config struct
+type configOption struct {
+	Key       string `json:"key"`
+	Type      string `json:"type"`
+	Default   string `json:"default"`
+	Value     string `json:"value"` // Effective value after the config file, environment and flags
+	Env       string `json:"env"`
+	Sensitive bool   `json:"sensitive,omitempty"` // Default and value are masked
+
+	configSource
+}
+
+// durationType is kept as a leaf rather than walked as an integer
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// configField is a leaf option of the Config struct
+type configField struct {
+	Key   string // Dotted key, e.g. api_server.auth.enabled
+	Name  string // Last segment of the key
+	Type  reflect.Type
+	Unit  string // units.Duration for durations, otherwise the field's unit tag
+	Index []int  // Index sequence for reflect.Value.FieldByIndex
+}
+
+// configFields lists the options of the Config struct by following its mapstructure tags.
+// Structs other than durations are walked; lists and maps are single options.
+func configFields() []configField {
+	var fields []configField
+	var walk func(t reflect.Type, prefix string, index []int)
+	walk = func(t reflect.Type, prefix string, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if name == "-" {
+				continue
+			}
+			key := prefix
+			if opts != "squash" {
+				if name == "" {
+					name = strings.ToLower(field.Name)
+				}
+				key = joinConfigKey(prefix, name)
+			}
+
+			fieldIndex := append(append([]int(nil), index...), i)
+			if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+				walk(field.Type, key, fieldIndex)
+				continue
+			}
+			unit := field.Tag.Get("unit")
+			if field.Type == durationType {
+				unit = units.Duration
+			}
+			fields = append(fields, configField{Key: key, Name: name, Type: field.Type, Unit: unit, Index: fieldIndex})
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "", nil)
+	return fields
+}
+
+// configOptions lists every option of the Config struct with its default and effective
+// values and the source the effective value came from. Values of sensitive keys are
+// masked with the logging redaction patterns.
+func configOptions(defaults, effective *Config) []configOption {
+	redactCfg := effective.Logging.Redaction
+	redactCfg.Enabled = true
+	redactor := redact.New(redactCfg)
+	replacement := redactCfg.Replacement
+	if replacement == "" {
+		replacement = redact.DefaultReplacement
+	}
+
+	d, e := reflect.ValueOf(defaults).Elem(), reflect.ValueOf(effective).Elem()
+	fields := configFields()
+	options := make([]configOption, 0, len(fields))
+	for _, field := range fields {
+		option := configOption{
+			Key:     field.Key,
+			Type:    optionType(field),
+			Default: formatOptionValue(d.FieldByIndex(field.Index)),
+			Value:   formatOptionValue(e.FieldByIndex(field.Index)),
+			Env:     envVarName(field.Key),
+
+			configSource: effective.source(field.Key),
+		}
+		if redactor.Sensitive(field.Name) && maskable(field.Type) {
+			option.Sensitive = true
+			option.Default = maskOptionValue(option.Default, replacement)
+			option.Value = maskOptionValue(option.Value, replacement)
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+// joinConfigKey appends a segment to a dotted configuration key
+func joinConfigKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// optionType names a field's type the way it is written in the config file
+func optionType(field configField) string {
+	if field.Unit != "" {
+		return field.Unit
+	}
+	return kindName(field.Type)
+}
+
+// kindName names a type by its kind, describing lists by their elements
+func kindName(t reflect.Type) string {
+	switch {
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct:
+		return "list"
+	case t.Kind() == reflect.Slice:
+		return "[]" + kindName(t.Elem())
+	case t.Kind() == reflect.Map:
+		return "map"
+	}
+	return t.Kind().String()
+}
+
+// maskable reports whether values of a type can carry secrets; flags and numbers cannot
+func maskable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// maskOptionValue hides a sensitive value unless it is unset
+func maskOptionValue(value, replacement string) string {
+	if value == "" {
+		return ""
+	}
+	return replacement
+}
+
+// formatOptionValue renders a value as it would be written in the config file or environment
+func formatOptionValue(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			items := make([]string, v.Len())
+			for i := range items {
+				items[i] = formatOptionValue(v.Index(i))
+			}
+			return strings.Join(items, ",")
+		}
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Map {
+		if v.Len() == 0 {
+			return ""
+		}
+		data, err := json.Marshal(configData(v))
+		if err != nil {
+			return fmt.Sprintf("%v", v.Interface())
+		}
+		return string(data)
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// configData converts a value to plain maps and slices keyed by mapstructure tags, so
+// lists of structs print with the keys used in the config file
+func configData(v reflect.Value) interface{} {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Struct:
+		data := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			data[name] = configData(v.Field(i))
+		}
+		return data
+	case v.Kind() == reflect.Slice:
+		data := make([]interface{}, v.Len())
+		for i := range data {
+			data[i] = configData(v.Index(i))
+		}
+		return data
+	case v.Kind() == reflect.Map:
+		data := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			data[fmt.Sprint(iter.Key().Interface())] = configData(iter.Value())
+		}
+		return data
+	}
+	return v.Interface()
+}
+
+// filterConfigOptions keeps the options whose key is one of the prefixes or below it
+func filterConfigOptions(options []configOption, prefixes []string) []configOption {
+	if len(prefixes) == 0 {
+		return options
+	}
+	var filtered []configOption
+	for _, option := range options {
+		for _, prefix := range prefixes {
+			prefix = strings.TrimSuffix(prefix, ".")
+			if option.Key == prefix || strings.HasPrefix(option.Key, prefix+".") {
+				filtered = append(filtered, option)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// configOptionSources are the values accepted by ?source= on GET /config
+var configOptionSources = map[string]bool{sourceDefault: true, sourceFile: true, sourceEnv: true, sourceFlag: true}
+
+// configResponse is the body of GET /config
+type configResponse struct {
+	ConfigFile string         `json:"config_file,omitempty"`
+	Count      int            `json:"count"`
+	Conflicts  int            `json:"conflicts"` // Options set by several sources or by an ignored variable
+	Options    []configOption `json:"options"`
+}
+
+// conflicted reports whether several sources set an option or an ignored variable is set
+func (o configOption) conflicted() bool {
+	return len(o.Overrides) > 0 || o.IgnoredEnv != ""
+}
+
+// @Summary Get the effective configuration
+// @Description Lists every configuration option with its effective value, default, environment variable and the source that set it: default, file, env or flag. Options also set by a lower-precedence source list it in overrides, and unprefixed variables that are set but no longer read are reported as ignored_env. Sensitive values are masked.
+// @Tags config
+// @Produce json
+// @Param prefix query string false "Only options at or below this key, e.g. api_server.auth"
+// @Param source query string false "Only options set by this source: default, file, env or flag"
+// @Param conflicts query bool false "Only options set by several sources or by an ignored variable"
+// @Success 200 {object} configResponse
+// @Failure 400 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /config [get]
+func (s *apiServer) handleConfig(ctx *fasthttp.RequestCtx) {
+	if s.config == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Configuration is not available"}`)
+		return
+	}
+
+	source := string(ctx.QueryArgs().Peek("source"))
+	if source != "" && !configOptionSources[source] {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "source must be default, file, env or flag"}`)
+		return
+	}
+	onlyConflicts := false
+	if raw := ctx.QueryArgs().Peek("conflicts"); len(raw) > 0 {
+		var err error
+		if onlyConflicts, err = strconv.ParseBool(string(raw)); err != nil {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			ctx.SetBodyString(`{"error": "conflicts must be true or false"}`)
+			return
+		}
+	}
+
+	var prefixes []string
+	if prefix := string(ctx.QueryArgs().Peek("prefix")); prefix != "" {
+		prefixes = []string{prefix}
+	}
+	response := configResponse{ConfigFile: s.config.configFile, Options: []configOption{}}
+	for _, option := range filterConfigOptions(configOptions(defaultConfig(), s.config), prefixes) {
+		if option.conflicted() {
+			response.Conflicts++
+		}
+		if (source != "" && option.Source != source) || (onlyConflicts && !option.conflicted()) {
+			continue
+		}
+		response.Options = append(response.Options, option)
+	}
+	sort.SliceStable(response.Options, func(i, j int) bool { return response.Options[i].Key < response.Options[j].Key })
+	response.Count = len(response.Options)
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
+
+// configOptionsCmd prints a reference of every configuration option
+func configOptionsCmd() *cobra.Command {
+	var output string
+	var changed bool
+
+	cmd := &cobra.Command{
+		Use:   "options [key-prefix...]",
+		Short: "List every configuration option",
+		Long: `List every configuration key with its type, default, effective value and the
+environment variables that set it. The reference is built from the Config struct, so
+new options appear without further changes. Sensitive values are masked.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "" && output != outputJSON && output != outputYAML {
+				return fmt.Errorf("invalid output %q: must be json or yaml", output)
+			}
+			config, err := LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load configuration: %w", err)
+			}
+
+			options := filterConfigOptions(configOptions(defaultConfig(), config), args)
+			if changed {
+				kept := options[:0]
+				for _, option := range options {
+					if option.Value != option.Default {
+						kept = append(kept, option)
+					}
+				}
+				options = kept
+			}
+			sort.SliceStable(options, func(i, j int) bool { return options[i].Key < options[j].Key })
+
+			switch output {
+			case outputJSON:
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(options)
+			case outputYAML:
+				data, err := yaml.Marshal(options)
+				if err != nil {
+					return err
+				}
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "KEY\tTYPE\tDEFAULT\tVALUE\tSOURCE\tENV")
+			for _, option := range options {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", option.Key, option.Type,
+					optionCell(option.Default), optionCell(option.Value), option.Source, option.Env)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format: json or yaml (default table)")
+	cmd.Flags().BoolVar(&changed, "changed", false, "Only list options whose effective value differs from the default")
+	return cmd
+}
+
+// maxOptionCell is the widest value shown in the table; -o json prints values in full
+const maxOptionCell = 48
+
+// optionCell fits a value into a table cell, keeping empty cells visible
+func optionCell(s string) string {
+	if s == "" {
+		return "-"
+	}
+	if len(s) > maxOptionCell {
+		return s[:maxOptionCell-3] + "..."
+	}
+	return s
+}
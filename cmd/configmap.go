@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// configMapKey describes one key of a ConfigMap without its value
+type configMapKey struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`             // Bytes of the value
+	Binary bool   `json:"binary,omitempty"` // Stored in binaryData rather than data
+}
+
+// configMapSummary lists a ConfigMap's keys and sizes, and its values when requested
+type configMapSummary struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	UID               string            `json:"uid"`
+	ResourceVersion   string            `json:"resource_version"`
+	CreationTimestamp metav1.Time       `json:"creation_timestamp"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Immutable         bool              `json:"immutable"`
+	Keys              []configMapKey    `json:"keys"`
+	TotalSize         int               `json:"total_size"`
+	Data              map[string]string `json:"data,omitempty"`
+	BinaryData        map[string][]byte `json:"binary_data,omitempty"` // Base64-encoded in JSON
+}
+
+// configMapDetail is the response of GET /configmaps/{namespace}/{name}
+type configMapDetail struct {
+	Cluster string `json:"cluster"`
+	configMapSummary
+}
+
+// newConfigMapSummary describes a ConfigMap's keys, sorted by name, and copies its values
+// only when includeData is set
+func newConfigMapSummary(cm *corev1.ConfigMap, includeData bool) configMapSummary {
+	summary := configMapSummary{
+		Name:              cm.Name,
+		Namespace:         cm.Namespace,
+		UID:               string(cm.UID),
+		ResourceVersion:   cm.ResourceVersion,
+		CreationTimestamp: cm.CreationTimestamp,
+		Labels:            cm.Labels,
+		Immutable:         cm.Immutable != nil && *cm.Immutable,
+		Keys:              make([]configMapKey, 0, len(cm.Data)+len(cm.BinaryData)),
+	}
+	for key, value := range cm.Data {
+		summary.Keys = append(summary.Keys, configMapKey{Name: key, Size: len(value)})
+		summary.TotalSize += len(value)
+	}
+	for key, value := range cm.BinaryData {
+		summary.Keys = append(summary.Keys, configMapKey{Name: key, Size: len(value), Binary: true})
+		summary.TotalSize += len(value)
+	}
+	sort.Slice(summary.Keys, func(i, j int) bool { return summary.Keys[i].Name < summary.Keys[j].Name })
+
+	if includeData {
+		summary.Data = cm.Data
+		summary.BinaryData = cm.BinaryData
+	}
+	return summary
+}
+
+// includeDataFromQuery reads ?includeData=, which defaults to false
+func includeDataFromQuery(ctx *fasthttp.RequestCtx) (bool, error) {
+	raw := ctx.QueryArgs().Peek("includeData")
+	if len(raw) == 0 {
+		return false, nil
+	}
+	include, err := strconv.ParseBool(string(raw))
+	if err != nil {
+		return false, errors.New("includeData must be true or false")
+	}
+	return include, nil
+}
+
+// @Summary List ConfigMaps
+// @Description Lists ConfigMaps of a namespace, or of all namespaces, with their key names and value sizes. Values are left out unless includeData=true.
+// @Tags kubernetes,configmaps
+// @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param namespace query string false "Namespace, all namespaces when empty"
+// @Param includeData query bool false "Include the values of data and binaryData"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=web,tier!=cache"
+// @Param fieldSelector query string false "Field selector, e.g. metadata.name=web"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /configmaps [get]
+func (s *apiServer) handleConfigMaps(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	includeData, err := includeDataFromQuery(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	namespace := getNamespaceFromQuery(ctx)
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	configMaps, err := target.Client.CoreV1().ConfigMaps(namespace).List(requestContext(ctx), limitedOptions(ctx, listOpts))
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Msg("Failed to list config maps")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to list config maps"})
+		return
+	}
+
+	pageMetadata := paginationMetadata(ctx, listOpts, configMaps.ListMeta, len(configMaps.Items))
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(configMaps.Items))
+		for i := range configMaps.Items {
+			objects = append(objects, &configMaps.Items[i])
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ID, objects)) {
+			return
+		}
+	}
+
+	names := make([]string, 0, len(configMaps.Items))
+	items := make([]configMapSummary, 0, len(configMaps.Items))
+	for i := range configMaps.Items {
+		names = append(names, configMaps.Items[i].Name)
+		items = append(items, newConfigMapSummary(&configMaps.Items[i], includeData))
+	}
+	logger.Info().Int("count", len(items)).Str("namespace", namespace).Msg("Config maps retrieved")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if isSimpleFormat(ctx) {
+		writeSimpleJsonArray(ctx, names)
+		return
+	}
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster":   target.ID,
+		"namespace": namespace,
+		"count":     len(items),
+		"source":    "kubernetes-api",
+		"names":     names,
+		"items":     items,
+		"metadata":  pageMetadata,
+	})
+}
+
+// @Summary Get a ConfigMap
+// @Description Returns a ConfigMap's key names and value sizes, and its values with includeData=true
+// @Tags kubernetes,configmaps
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "ConfigMap name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Param includeData query bool false "Include the values of data and binaryData"
+// @Success 200 {object} configMapDetail
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /configmaps/{namespace}/{name} [get]
+func (s *apiServer) handleConfigMapDetail(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	includeData, err := includeDataFromQuery(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	cm, err := target.Client.CoreV1().ConfigMaps(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("ConfigMap %s/%s not found", namespace, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get config map")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get config map"})
+		return
+	}
+
+	if writeNotModified(ctx, listETag(ctx, target.ID, []metav1.Object{cm})) {
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(configMapDetail{Cluster: target.ID, configMapSummary: newConfigMapSummary(cm, includeData)})
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+// csrfTokenUserValueKey stores a token issued while handling the request
+const csrfTokenUserValueKey = "csrf_token"
+
+// csrfTokenCookie returns the request's CSRF cookie when it is one the server issued
+func (s *apiServer) csrfTokenCookie(ctx *fasthttp.RequestCtx) string {
+	token := string(ctx.Request.Header.Cookie(s.csrf.CookieName()))
+	if !s.csrf.Valid(token) {
+		return ""
+	}
+	return token
+}
+
+// issueCSRFCookie sets a fresh token cookie. It is readable by scripts on purpose: the
+// double-submit check relies on the page copying it into the request header.
+func (s *apiServer) issueCSRFCookie(ctx *fasthttp.RequestCtx) (string, error) {
+	token, err := s.csrf.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(s.csrf.CookieName())
+	cookie.SetValue(token)
+	cookie.SetPath("/")
+	cookie.SetMaxAge(int(s.csrf.CookieMaxAge().Seconds()))
+	cookie.SetSameSite(fasthttp.CookieSameSiteStrictMode)
+	cookie.SetSecure(s.config != nil && s.config.APIServer.TLS.Enabled)
+	ctx.Response.Header.SetCookie(cookie)
+	ctx.SetUserValue(csrfTokenUserValueKey, token)
+	return token, nil
+}
+
+// checkCSRF issues token cookies on safe requests and requires mutating requests to echo
+// the cookie in the CSRF header. It writes the error response and returns false when the
+// request must stop.
+func (s *apiServer) checkCSRF(ctx *fasthttp.RequestCtx, method, path string, logger zerolog.Logger) bool {
+	hasTokenAuth := len(ctx.Request.Header.Peek("Authorization")) > 0 || len(ctx.Request.Header.Peek("X-API-Key")) > 0
+
+	if !s.csrf.RequiresCheck(method, path, hasTokenAuth) {
+		// Browsers pick up a token on their first page load
+		if (method == "GET" || method == "HEAD") && s.csrfTokenCookie(ctx) == "" {
+			if _, err := s.issueCSRFCookie(ctx); err != nil {
+				logger.Error().Err(err).Msg("Failed to issue CSRF token")
+			}
+		}
+		return true
+	}
+
+	err := s.csrf.Validate(
+		string(ctx.Request.Header.Cookie(s.csrf.CookieName())),
+		string(ctx.Request.Header.Peek(s.csrf.HeaderName())),
+	)
+	if err == nil {
+		return true
+	}
+
+	logger.Warn().
+		Err(err).
+		Str("method", method).
+		Str("path", path).
+		Str("client", ctx.RemoteIP().String()).
+		Msg("CSRF check failed")
+	ctx.SetStatusCode(fasthttp.StatusForbidden)
+	json.NewEncoder(ctx).Encode(map[string]string{
+		"error": fmt.Sprintf("%s: send the %s cookie value in the %s header", err, s.csrf.CookieName(), s.csrf.HeaderName()),
+	})
+	return false
+}
+
+// @Summary Get a CSRF token
+// @Description Returns the current double-submit token, issuing a new cookie when the request has none.
+// @Description Browser clients send the token in the CSRF header on mutating requests.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /csrf [get]
+func (s *apiServer) handleCSRFToken(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	if s.csrf == nil {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		ctx.SetBodyString(`{"error": "CSRF protection is disabled"}`)
+		return
+	}
+
+	// checkCSRF may already have issued a cookie for this request
+	token, _ := ctx.UserValue(csrfTokenUserValueKey).(string)
+	if token == "" {
+		token = s.csrfTokenCookie(ctx)
+	}
+	if token == "" {
+		var err error
+		if token, err = s.issueCSRFCookie(ctx); err != nil {
+			logger.Error().Err(err).Msg("Failed to issue CSRF token")
+			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+			ctx.SetBodyString(`{"error": "Failed to issue CSRF token"}`)
+			return
+		}
+	}
+
+	ctx.Response.Header.Set("Cache-Control", "no-store")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]string{
+		"token":  token,
+		"cookie": s.csrf.CookieName(),
+		"header": s.csrf.HeaderName(),
+	})
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/debugcapture"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/units"
+)
+
+// debugCapturePath serves the captures; its own requests are never captured
+const debugCapturePath = "/admin/debug/captures"
+
+// debugCaptureRequest starts a capture. Duration accepts a number of seconds or a value
+// such as 10m, and defaults to api_server.debug_capture.default_duration.
+type debugCaptureRequest struct {
+	debugcapture.Filter
+	Duration interface{} `json:"duration,omitempty"`
+	Limit    int         `json:"limit,omitempty"` // Stop after this many captured requests
+}
+
+// debugCaptureResponse describes the latest capture and the recorded exchanges
+type debugCaptureResponse struct {
+	Active   bool                    `json:"active"`
+	Session  *debugcapture.Session   `json:"session,omitempty"`
+	Count    int                     `json:"count"`
+	Captures []debugcapture.Exchange `json:"captures"`
+}
+
+// captureDebug hands the completed exchange to the debug recorder when a capture is running
+func (s *apiServer) captureDebug(ctx *fasthttp.RequestCtx, requestID, method, path, clientIP string, start time.Time) {
+	if s.debugCapture == nil || strings.HasPrefix(path, debugCapturePath) || !s.debugCapture.Wants(method, path, clientIP) {
+		return
+	}
+
+	exchange := debugcapture.Exchange{
+		Time:       start.UTC(),
+		RequestID:  requestID,
+		Method:     method,
+		Path:       path,
+		Query:      string(ctx.QueryArgs().QueryString()),
+		ClientIP:   clientIP,
+		Status:     ctx.Response.StatusCode(),
+		DurationMs: time.Since(start).Milliseconds(),
+		Request:    debugcapture.Message{Headers: make(map[string]string)},
+		Response:   debugcapture.Message{Headers: make(map[string]string)},
+	}
+	if principal := getPrincipal(ctx); principal != nil {
+		exchange.Principal = principal.Name
+	}
+	ctx.Request.Header.VisitAll(func(key, value []byte) {
+		addCapturedHeader(exchange.Request.Headers, string(key), string(value))
+	})
+	ctx.Response.Header.VisitAll(func(key, value []byte) {
+		addCapturedHeader(exchange.Response.Headers, string(key), string(value))
+	})
+
+	// Streams and hijacked connections have no buffered body, and compressed bodies are
+	// captured as the handler wrote them
+	var responseBody []byte
+	if ctx.Hijacked() || ctx.Response.IsBodyStream() {
+		exchange.Response.Streamed = true
+	} else if body, err := ctx.Response.BodyUncompressed(); err == nil {
+		responseBody = body
+	} else {
+		responseBody = ctx.Response.Body()
+	}
+
+	s.debugCapture.Record(exchange, ctx.PostBody(), responseBody)
+}
+
+// addCapturedHeader joins repeated headers such as Set-Cookie into one value
+func addCapturedHeader(headers map[string]string, name, value string) {
+	if existing, ok := headers[name]; ok {
+		value = existing + ", " + value
+	}
+	headers[name] = value
+}
+
+// @Summary Manage debug captures
+// @Description GET returns the latest capture window and the recorded requests and responses, newest first, with credentials redacted. POST starts a capture for a limited time, optionally only for requests matching a filter, replacing a running one. DELETE stops the capture; clear=true also drops the recorded exchanges.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param path query string false "GET: only include exchanges whose path starts with this prefix"
+// @Param limit query int false "GET: maximum number of exchanges to return (default 100)"
+// @Param clear query bool false "DELETE: also drop the recorded exchanges"
+// @Param capture body debugCaptureRequest false "POST: duration, limit and filter of the capture"
+// @Success 200 {object} debugCaptureResponse
+// @Success 201 {object} debugcapture.Session
+// @Failure 400 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /admin/debug/captures [get]
+// @Router /admin/debug/captures [post]
+// @Router /admin/debug/captures [delete]
+func (s *apiServer) handleAdminDebugCaptures(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if s.debugCapture == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Debug capture is disabled"}`)
+		return
+	}
+
+	switch {
+	case ctx.IsPost():
+		var req debugCaptureRequest
+		if len(ctx.PostBody()) > 0 {
+			if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+				ctx.SetStatusCode(fasthttp.StatusBadRequest)
+				json.NewEncoder(ctx).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+				return
+			}
+		}
+		var duration time.Duration
+		if req.Duration != nil {
+			d, err := units.ParseDuration(req.Duration)
+			if err != nil {
+				ctx.SetStatusCode(fasthttp.StatusBadRequest)
+				json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			duration = d
+		}
+
+		startedBy := ctx.RemoteIP().String()
+		if principal := getPrincipal(ctx); principal != nil {
+			startedBy = principal.Name
+		}
+		session, err := s.debugCapture.Start(req.Filter, duration, req.Limit, startedBy)
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		logger.Warn().
+			Str("started_by", startedBy).
+			Time("until", session.Until).
+			Interface("filter", session.Filter).
+			Int("limit", session.Limit).
+			Msg("Debug capture started, request and response bodies are being recorded")
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		json.NewEncoder(ctx).Encode(session)
+
+	case ctx.IsDelete():
+		session, stopped := s.debugCapture.Stop()
+		response := map[string]interface{}{"stopped": stopped}
+		if stopped {
+			response["session"] = session
+			logger.Info().Int("captured", session.Captured).Msg("Debug capture stopped")
+		}
+		if clear, _ := strconv.ParseBool(string(ctx.QueryArgs().Peek("clear"))); clear {
+			response["cleared"] = s.debugCapture.Clear()
+		}
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		json.NewEncoder(ctx).Encode(response)
+
+	default:
+		limit := 100
+		if raw := ctx.QueryArgs().Peek("limit"); len(raw) > 0 {
+			n, err := strconv.Atoi(string(raw))
+			if err != nil || n <= 0 {
+				ctx.SetStatusCode(fasthttp.StatusBadRequest)
+				ctx.SetBodyString(`{"error": "limit must be a positive integer"}`)
+				return
+			}
+			limit = n
+		}
+
+		session, active := s.debugCapture.Latest()
+		captures := s.debugCapture.Exchanges(string(ctx.QueryArgs().Peek("path")), limit)
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		json.NewEncoder(ctx).Encode(debugCaptureResponse{
+			Active:   active,
+			Session:  session,
+			Count:    len(captures),
+			Captures: captures,
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// newDeployment builds the single-container deployment described by the create command
+// flags or the simplified POST /deployments body. Pods are selected by an app label
+// carrying the deployment name.
+func newDeployment(req DeploymentCreateRequest) *appsv1.Deployment {
+	labels := make(map[string]string, len(req.Labels)+1)
+	for k, v := range req.Labels {
+		labels[k] = v
+	}
+	labels["app"] = req.Name
+
+	replicas := req.Replicas
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": req.Name},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": req.Name},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  req.Name,
+							Image: req.Image,
+							Ports: []corev1.ContainerPort{{ContainerPort: req.Port}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// decodeDeployment reads a deployment from a JSON or YAML body. A body with a kind is a
+// Deployment manifest; anything else is the simplified name/image/replicas form, whose
+// defaults match the create command. The namespace is left empty when the body has none.
+func decodeDeployment(body []byte) (*appsv1.Deployment, error) {
+	data, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON or YAML: %w", err)
+	}
+
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return nil, fmt.Errorf("expected an object: %w", err)
+	}
+	if typeMeta.Kind != "" || typeMeta.APIVersion != "" {
+		return decodeDeploymentManifest(typeMeta, data)
+	}
+
+	var req DeploymentCreateRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if req.Image == "" {
+		return nil, errors.New("image is required")
+	}
+	if req.Replicas == 0 {
+		req.Replicas = 1
+	}
+	if req.Port == 0 {
+		req.Port = 80
+	}
+	return newDeployment(req), nil
+}
+
+// decodeDeploymentManifest decodes an apps/v1 Deployment manifest. Fields the API server
+// manages are cleared, so the output of kubectl get -o yaml can be created elsewhere.
+func decodeDeploymentManifest(typeMeta metav1.TypeMeta, data []byte) (*appsv1.Deployment, error) {
+	if typeMeta.Kind != "Deployment" || typeMeta.APIVersion != appsv1.SchemeGroupVersion.String() {
+		return nil, fmt.Errorf("expected kind Deployment in apps/v1, got %s in %q", typeMeta.Kind, typeMeta.APIVersion)
+	}
+
+	var deployment appsv1.Deployment
+	if err := json.Unmarshal(data, &deployment); err != nil {
+		return nil, fmt.Errorf("invalid Deployment: %w", err)
+	}
+	if deployment.Name == "" {
+		return nil, errors.New("metadata.name is required")
+	}
+
+	deployment.UID = ""
+	deployment.ResourceVersion = ""
+	deployment.Generation = 0
+	deployment.CreationTimestamp = metav1.Time{}
+	deployment.ManagedFields = nil
+	deployment.Status = appsv1.DeploymentStatus{}
+	return &deployment, nil
+}
+
+// deploymentImages returns the images of a deployment's containers for logging
+func deploymentImages(d *appsv1.Deployment) []string {
+	images := make([]string, 0, len(d.Spec.Template.Spec.Containers))
+	for _, c := range d.Spec.Template.Spec.Containers {
+		images = append(images, c.Image)
+	}
+	return images
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// propagationPolicies are the accepted propagation policies, keyed by lower-case name
+var propagationPolicies = map[string]metav1.DeletionPropagation{
+	"foreground": metav1.DeletePropagationForeground,
+	"background": metav1.DeletePropagationBackground,
+	"orphan":     metav1.DeletePropagationOrphan,
+}
+
+// deploymentDeleteResponse is returned once the API server has accepted a deletion
+type deploymentDeleteResponse struct {
+	Cluster            string `json:"cluster"`
+	Namespace          string `json:"namespace"`
+	Name               string `json:"name"`
+	PropagationPolicy  string `json:"propagation_policy,omitempty"`
+	GracePeriodSeconds *int64 `json:"grace_period_seconds,omitempty"`
+	Message            string `json:"message"`
+}
+
+// newDeleteOptions builds the delete options shared by the CLI and the API. An empty
+// policy and a negative grace period keep the API server's defaults.
+func newDeleteOptions(policy string, gracePeriodSeconds int64) (metav1.DeleteOptions, error) {
+	var opts metav1.DeleteOptions
+	if policy != "" {
+		propagation, ok := propagationPolicies[strings.ToLower(policy)]
+		if !ok {
+			return opts, fmt.Errorf("invalid propagationPolicy %q: must be Foreground, Background or Orphan", policy)
+		}
+		opts.PropagationPolicy = &propagation
+	}
+	if gracePeriodSeconds >= 0 {
+		opts.GracePeriodSeconds = &gracePeriodSeconds
+	}
+	return opts, nil
+}
+
+// deleteOptionsFromQuery reads ?propagationPolicy= and ?gracePeriodSeconds=
+func deleteOptionsFromQuery(ctx *fasthttp.RequestCtx) (metav1.DeleteOptions, error) {
+	gracePeriod := int64(-1)
+	if raw := ctx.QueryArgs().Peek("gracePeriodSeconds"); len(raw) > 0 {
+		seconds, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil || seconds < 0 {
+			return metav1.DeleteOptions{}, fmt.Errorf("invalid gracePeriodSeconds %q: must be a non-negative integer", raw)
+		}
+		gracePeriod = seconds
+	}
+	return newDeleteOptions(string(ctx.QueryArgs().Peek("propagationPolicy")), gracePeriod)
+}
+
+// @Summary Delete a deployment
+// @Description Deletes a deployment. propagationPolicy decides whether its ReplicaSets and pods are deleted before it (Foreground), after it (Background) or kept (Orphan); gracePeriodSeconds overrides the pods' termination grace period, 0 deleting them at once.
+// @Tags deployments
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Param propagationPolicy query string false "Foreground, Background or Orphan (default Background)"
+// @Param gracePeriodSeconds query int false "Termination grace period in seconds, 0 for immediate deletion"
+// @Success 200 {object} deploymentDeleteResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /deployments/{namespace}/{name} [delete]
+func (s *apiServer) handleDeploymentDelete(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	opts, err := deleteOptionsFromQuery(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	err = target.Client.AppsV1().Deployments(namespace).Delete(requestContext(ctx), name, opts)
+	switch {
+	case apierrors.IsNotFound(err):
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Deployment %s/%s not found", namespace, name)})
+		return
+	case apierrors.IsConflict(err):
+		ctx.SetStatusCode(fasthttp.StatusConflict)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to delete deployment")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to delete deployment: %s", err)})
+		return
+	}
+
+	response := deploymentDeleteResponse{
+		Cluster:            target.ID,
+		Namespace:          namespace,
+		Name:               name,
+		GracePeriodSeconds: opts.GracePeriodSeconds,
+		Message:            "Deployment deleted successfully",
+	}
+	if opts.PropagationPolicy != nil {
+		response.PropagationPolicy = string(*opts.PropagationPolicy)
+		if *opts.PropagationPolicy == metav1.DeletePropagationForeground {
+			response.Message = "Deployment is being deleted after its ReplicaSets and pods"
+		}
+	}
+
+	logger.Info().
+		Str("cluster_id", target.ID).
+		Str("namespace", namespace).
+		Str("name", name).
+		Str("propagation_policy", response.PropagationPolicy).
+		Msg("Deployment deleted successfully")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/informer"
+)
+
+// containerImage is an image run by one of a deployment's containers
+type containerImage struct {
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	Init      bool   `json:"init,omitempty"`
+}
+
+// deploymentDetail is the response of GET /deployments/{namespace}/{name}
+type deploymentDetail struct {
+	Cluster           string                       `json:"cluster"`
+	Source            string                       `json:"source"`
+	Name              string                       `json:"name"`
+	Namespace         string                       `json:"namespace"`
+	UID               string                       `json:"uid"`
+	ResourceVersion   string                       `json:"resource_version"`
+	Generation        int64                        `json:"generation"`
+	CreationTimestamp metav1.Time                  `json:"creation_timestamp"`
+	Labels            map[string]string            `json:"labels,omitempty"`
+	Annotations       map[string]string            `json:"annotations,omitempty"`
+	OwnerReferences   []metav1.OwnerReference      `json:"owner_references,omitempty"`
+	Images            []containerImage             `json:"images"`
+	Strategy          appsv1.DeploymentStrategy    `json:"strategy"`
+	Conditions        []appsv1.DeploymentCondition `json:"conditions"`
+	Spec              appsv1.DeploymentSpec        `json:"spec"`
+	Status            appsv1.DeploymentStatus      `json:"status"`
+}
+
+// @Summary Get a deployment
+// @Description Returns the full spec and status of a deployment with its images, strategy, conditions and owner references, read from the informer cache when possible
+// @Tags deployments
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {object} deploymentDetail
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /deployments/{namespace}/{name} [get]
+func (s *apiServer) handleDeploymentDetail(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	source := "informer-cache"
+	deployment, err := s.cachedDeployment(target, namespace, name)
+	if err != nil || deployment == nil {
+		if err != nil {
+			logger.Debug().Err(err).Str("namespace", namespace).Str("name", name).Msg("Deployment not in cache, falling back to direct API")
+		}
+		source = "direct-api"
+		deployment, err = target.Client.AppsV1().Deployments(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Deployment %s/%s not found", namespace, name)})
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get deployment from API")
+			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get deployment from API"})
+			return
+		}
+	}
+
+	if writeNotModified(ctx, listETag(ctx, target.ID, []metav1.Object{deployment})) {
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(newDeploymentDetail(target.ID, source, deployment))
+}
+
+// cachedDeployment reads one deployment from the target cluster's cache. It returns nil
+// without an error when the cluster has no cache.
+func (s *apiServer) cachedDeployment(target *clusterTarget, namespace, name string) (*appsv1.Deployment, error) {
+	if target.isPrimary() {
+		if s.informerFactory == nil {
+			return nil, nil
+		}
+		return informer.FindDeploymentInCache(s.informerFactory.Apps().V1().Deployments().Informer(), namespace, name)
+	}
+
+	if s.multiClusterManager == nil {
+		return nil, nil
+	}
+	clusterCache, err := s.multiClusterManager.GetCache(target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheCtx, cancel := context.WithTimeout(context.Background(), clusterCacheTimeout)
+	defer cancel()
+
+	var deployment appsv1.Deployment
+	if err := clusterCache.Get(cacheCtx, client.ObjectKey{Namespace: namespace, Name: name}, &deployment); err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+func newDeploymentDetail(clusterID, source string, d *appsv1.Deployment) deploymentDetail {
+	images := make([]containerImage, 0, len(d.Spec.Template.Spec.InitContainers)+len(d.Spec.Template.Spec.Containers))
+	for _, c := range d.Spec.Template.Spec.InitContainers {
+		images = append(images, containerImage{Container: c.Name, Image: c.Image, Init: true})
+	}
+	for _, c := range d.Spec.Template.Spec.Containers {
+		images = append(images, containerImage{Container: c.Name, Image: c.Image})
+	}
+
+	conditions := d.Status.Conditions
+	if conditions == nil {
+		conditions = []appsv1.DeploymentCondition{}
+	}
+
+	return deploymentDetail{
+		Cluster:           clusterID,
+		Source:            source,
+		Name:              d.Name,
+		Namespace:         d.Namespace,
+		UID:               string(d.UID),
+		ResourceVersion:   d.ResourceVersion,
+		Generation:        d.Generation,
+		CreationTimestamp: d.CreationTimestamp,
+		Labels:            d.Labels,
+		Annotations:       d.Annotations,
+		OwnerReferences:   d.OwnerReferences,
+		Images:            images,
+		Strategy:          d.Spec.Strategy,
+		Conditions:        conditions,
+		Spec:              d.Spec,
+		Status:            d.Status,
+	}
+}
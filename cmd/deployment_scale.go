@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// Wait-for-ready limits of PATCH /deployments/{namespace}/{name}/scale
+const (
+	defaultScaleWaitTimeout = 2 * time.Minute
+	maxScaleWaitTimeout     = 10 * time.Minute
+)
+
+// scaleReadyPollInterval is how often a waiting scale request checks the rollout
+var scaleReadyPollInterval = time.Second
+
+// scaleRequest is the body of PATCH /deployments/{namespace}/{name}/scale
+type scaleRequest struct {
+	Replicas *int32 `json:"replicas"`
+}
+
+// scaleResponse reports a scaled deployment and, when waited for, its readiness
+type scaleResponse struct {
+	Cluster          string `json:"cluster"`
+	Namespace        string `json:"namespace"`
+	Name             string `json:"name"`
+	PreviousReplicas int32  `json:"previous_replicas"`
+	Replicas         int32  `json:"replicas"`
+	Waited           bool   `json:"waited"`
+	Ready            *bool  `json:"ready,omitempty"`
+	ReadyReplicas    *int32 `json:"ready_replicas,omitempty"`
+}
+
+// @Summary Scale a deployment
+// @Description Sets the replicas of a deployment through the scale subresource. With wait=true the request returns once the rollout has updated and readied every replica, or fails with 504 after timeout.
+// @Tags deployments
+// @Accept json
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Param wait query bool false "Wait until the deployment is ready at the new scale"
+// @Param timeout query string false "How long to wait, e.g. 90s (default 2m, at most 10m)"
+// @Param body body scaleRequest true "Desired replicas"
+// @Success 200 {object} scaleResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 504 {object} map[string]interface{}
+// @Router /deployments/{namespace}/{name}/scale [patch]
+func (s *apiServer) handleDeploymentScale(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	var req scaleRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "Invalid JSON in request body"}`)
+		return
+	}
+	if req.Replicas == nil || *req.Replicas < 0 {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "replicas must be a non-negative integer"}`)
+		return
+	}
+
+	waitForReady, timeout, err := parseScaleWait(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	previous, err := scaleDeployment(requestContext(ctx), target.Client, namespace, name, *req.Replicas)
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Deployment %s/%s not found", namespace, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to scale deployment")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to scale deployment: %s", err)})
+		return
+	}
+
+	logger.Info().
+		Str("cluster_id", target.ID).
+		Str("namespace", namespace).
+		Str("name", name).
+		Int32("previous_replicas", previous).
+		Int32("replicas", *req.Replicas).
+		Msg("Scaled deployment")
+
+	response := scaleResponse{
+		Cluster:          target.ID,
+		Namespace:        namespace,
+		Name:             name,
+		PreviousReplicas: previous,
+		Replicas:         *req.Replicas,
+		Waited:           waitForReady,
+	}
+	if !waitForReady {
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		json.NewEncoder(ctx).Encode(response)
+		return
+	}
+
+	waitCtx, cancel := context.WithTimeout(requestContext(ctx), timeout)
+	defer cancel()
+	deployment, err := waitForDeploymentReady(waitCtx, target.Client, namespace, name, *req.Replicas)
+	ready := err == nil
+	response.Ready = &ready
+	if deployment != nil {
+		response.ReadyReplicas = &deployment.Status.ReadyReplicas
+	}
+	if err != nil {
+		status := fasthttp.StatusGatewayTimeout
+		message := fmt.Sprintf("Deployment was scaled but is not ready after %s", timeout)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			status = fasthttp.StatusInternalServerError
+			message = fmt.Sprintf("Deployment was scaled but its readiness could not be checked: %s", err)
+		}
+		logger.Warn().Err(err).Str("namespace", namespace).Str("name", name).Msg("Scaled deployment did not become ready")
+		ctx.SetStatusCode(status)
+		json.NewEncoder(ctx).Encode(map[string]interface{}{"error": message, "scale": response})
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
+
+// parseScaleWait reads the wait and timeout query parameters of a scale request
+func parseScaleWait(ctx *fasthttp.RequestCtx) (bool, time.Duration, error) {
+	waitForReady := false
+	if raw := ctx.QueryArgs().Peek("wait"); len(raw) > 0 {
+		var err error
+		if waitForReady, err = strconv.ParseBool(string(raw)); err != nil {
+			return false, 0, errors.New("wait must be true or false")
+		}
+	}
+
+	timeout := defaultScaleWaitTimeout
+	if raw := ctx.QueryArgs().Peek("timeout"); len(raw) > 0 {
+		d, err := time.ParseDuration(string(raw))
+		if err != nil || d <= 0 || d > maxScaleWaitTimeout {
+			return false, 0, fmt.Errorf("timeout must be a positive duration of at most %s", maxScaleWaitTimeout)
+		}
+		timeout = d
+	}
+	return waitForReady, timeout, nil
+}
+
+// scaleDeployment sets a deployment's replicas through the scale subresource, retrying
+// on conflicts, and returns the replicas it had before
+func scaleDeployment(ctx context.Context, client kubernetes.Interface, namespace, name string, replicas int32) (int32, error) {
+	var previous int32
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		scale, err := client.AppsV1().Deployments(namespace).GetScale(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		previous = scale.Spec.Replicas
+		if previous == replicas {
+			return nil
+		}
+		scale.Spec.Replicas = replicas
+		_, err = client.AppsV1().Deployments(namespace).UpdateScale(ctx, name, scale, metav1.UpdateOptions{})
+		return err
+	})
+	return previous, err
+}
+
+// waitForDeploymentReady polls a deployment until its controller has rolled out the
+// requested replicas and all of them are updated, ready and no old ones remain. It
+// returns the last deployment seen.
+func waitForDeploymentReady(ctx context.Context, client kubernetes.Interface, namespace, name string, replicas int32) (*appsv1.Deployment, error) {
+	var last *appsv1.Deployment
+	err := wait.PollUntilContextCancel(ctx, scaleReadyPollInterval, true, func(ctx context.Context) (bool, error) {
+		deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		last = deployment
+		return deploymentReadyAt(deployment, replicas), nil
+	})
+	return last, err
+}
+
+// deploymentReadyAt reports whether a deployment has settled at the given replicas
+func deploymentReadyAt(d *appsv1.Deployment, replicas int32) bool {
+	status := d.Status
+	return status.ObservedGeneration >= d.Generation &&
+		status.Replicas == replicas &&
+		status.UpdatedReplicas == replicas &&
+		status.ReadyReplicas == replicas
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/deprecation"
+)
+
+// clusterDeprecations is the deprecation report of one cluster
+type clusterDeprecations struct {
+	ID       string                `json:"id"`
+	Version  string                `json:"version,omitempty"`
+	Served   []deprecation.API     `json:"served_deprecated_apis"` // Deprecated APIs the cluster still serves
+	Count    int                   `json:"findings"`
+	Error    string                `json:"error,omitempty"`
+	findings []deprecation.Finding // Every finding, before the request's filters
+}
+
+// deprecationTable returns the configured deprecation table, or the built-in one
+func (s *apiServer) deprecationTable() *deprecation.Table {
+	if s.deprecations != nil {
+		return s.deprecations
+	}
+	table, _ := deprecation.New(deprecation.Config{})
+	return table
+}
+
+// collectDeprecations checks every cluster concurrently, or only clusterID when set. Findings
+// removed in target are blocking. Clusters that cannot be read carry their error.
+func (s *apiServer) collectDeprecations(ctx context.Context, clusterID string, target deprecation.Version) ([]*clusterDeprecations, error) {
+	clients, err := s.inventoryClients(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	table := s.deprecationTable()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	reports := make([]*clusterDeprecations, 0, len(clients))
+	for id, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report := &clusterDeprecations{ID: id, Served: []deprecation.API{}}
+			if err := checkClusterDeprecations(ctx, table, client, target, report); err != nil {
+				report.Error = err.Error()
+			}
+			mu.Lock()
+			reports = append(reports, report)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	slices.SortFunc(reports, func(a, b *clusterDeprecations) int {
+		if a.ID < b.ID {
+			return -1
+		}
+		if a.ID > b.ID {
+			return 1
+		}
+		return 0
+	})
+	return reports, nil
+}
+
+// checkClusterDeprecations reads the version, served APIs and workload objects of a cluster
+func checkClusterDeprecations(ctx context.Context, table *deprecation.Table, client kubernetes.Interface, target deprecation.Version, report *clusterDeprecations) error {
+	info, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get server version: %w", err)
+	}
+	version, err := deprecation.ParseVersion(info.GitVersion)
+	if err != nil {
+		return err
+	}
+	report.Version = version.String()
+
+	groups, err := client.Discovery().ServerGroups()
+	if err != nil {
+		return fmt.Errorf("failed to get served APIs: %w", err)
+	}
+	var groupVersions []string
+	for _, group := range groups.Groups {
+		for _, v := range group.Versions {
+			groupVersions = append(groupVersions, v.GroupVersion)
+		}
+	}
+	report.Served = table.Served(groupVersions)
+
+	objects, err := deprecationObjects(ctx, client)
+	if err != nil {
+		return err
+	}
+	report.findings = table.Check(report.ID, version, target, objects)
+	report.Count = len(report.findings)
+	return nil
+}
+
+// deprecationObjects lists the workload kinds whose APIs have been replaced. Kinds the cluster
+// does not serve are skipped.
+func deprecationObjects(ctx context.Context, client kubernetes.Interface) ([]deprecation.Object, error) {
+	opts := metav1.ListOptions{}
+	listers := []struct {
+		kind string
+		list func() ([]deprecation.Object, error)
+	}{
+		{"Deployment", func() ([]deprecation.Object, error) {
+			list, err := client.AppsV1().Deployments("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return deprecationItems("Deployment", list.Items), nil
+		}},
+		{"StatefulSet", func() ([]deprecation.Object, error) {
+			list, err := client.AppsV1().StatefulSets("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return deprecationItems("StatefulSet", list.Items), nil
+		}},
+		{"DaemonSet", func() ([]deprecation.Object, error) {
+			list, err := client.AppsV1().DaemonSets("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return deprecationItems("DaemonSet", list.Items), nil
+		}},
+		{"CronJob", func() ([]deprecation.Object, error) {
+			list, err := client.BatchV1().CronJobs("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return deprecationItems("CronJob", list.Items), nil
+		}},
+		{"Ingress", func() ([]deprecation.Object, error) {
+			list, err := client.NetworkingV1().Ingresses("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return deprecationItems("Ingress", list.Items), nil
+		}},
+		{"NetworkPolicy", func() ([]deprecation.Object, error) {
+			list, err := client.NetworkingV1().NetworkPolicies("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return deprecationItems("NetworkPolicy", list.Items), nil
+		}},
+		{"PodDisruptionBudget", func() ([]deprecation.Object, error) {
+			list, err := client.PolicyV1().PodDisruptionBudgets("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return deprecationItems("PodDisruptionBudget", list.Items), nil
+		}},
+		{"HorizontalPodAutoscaler", func() ([]deprecation.Object, error) {
+			list, err := client.AutoscalingV2().HorizontalPodAutoscalers("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return deprecationItems("HorizontalPodAutoscaler", list.Items), nil
+		}},
+	}
+
+	var objects []deprecation.Object
+	for _, l := range listers {
+		items, err := l.list()
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s objects: %w", l.kind, err)
+		}
+		objects = append(objects, items...)
+	}
+	return objects, nil
+}
+
+// deprecationItems collects the API versions of listed objects
+func deprecationItems[T any, PT interface {
+	*T
+	metav1.Object
+}](kind string, items []T) []deprecation.Object {
+	objects := make([]deprecation.Object, 0, len(items))
+	for i := range items {
+		objects = append(objects, deprecation.ObjectFrom(kind, PT(&items[i])))
+	}
+	return objects
+}
+
+// parseTargetVersion reads the target query parameter, returning a zero version when unset
+func parseTargetVersion(ctx *fasthttp.RequestCtx) (deprecation.Version, error) {
+	raw := string(ctx.QueryArgs().Peek("target"))
+	if raw == "" {
+		return deprecation.Version{}, nil
+	}
+	return deprecation.ParseVersion(raw)
+}
+
+// @Summary Get deprecated API usage
+// @Description Reports workloads written through Kubernetes APIs that are deprecated or removed, per cluster. API versions are read from the managed fields and kubectl last-applied configuration of deployments, statefulsets, daemonsets, cronjobs, ingresses, network policies, PDBs and HPAs, and compared with each cluster's version: removed, deprecated or scheduled. With target, findings removed in that version are blocking. Clusters also list the deprecated APIs they still serve.
+// @Tags kubernetes
+// @Produce json
+// @Param cluster query string false "Only include this cluster"
+// @Param namespace query string false "Only include this namespace"
+// @Param status query string false "Only include findings with this status: removed, deprecated or scheduled"
+// @Param target query string false "Kubernetes version being upgraded to, e.g. 1.31"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /deprecations [get]
+func (s *apiServer) handleDeprecations(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	clusterFilter := string(ctx.QueryArgs().Peek("cluster"))
+	namespace := getNamespaceFromQuery(ctx)
+	status := string(ctx.QueryArgs().Peek("status"))
+	if status != "" && !slices.Contains(deprecation.Statuses, status) {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Invalid status %q, expected removed, deprecated or scheduled", status)})
+		return
+	}
+	target, err := parseTargetVersion(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	reports, err := s.collectDeprecations(requestContext(ctx), clusterFilter, target)
+	if err != nil {
+		logger.Warn().Err(err).Str("cluster_id", clusterFilter).Msg("Failed to resolve clusters for deprecation report")
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	items := make([]deprecation.Finding, 0)
+	totals := map[string]int{}
+	blocking := 0
+	for _, report := range reports {
+		if report.Error != "" {
+			logger.Error().Str("cluster_id", report.ID).Str("error", report.Error).Msg("Failed to check cluster for deprecated APIs")
+		}
+		for _, f := range report.findings {
+			if (namespace != "" && f.Namespace != namespace) || (status != "" && f.Status != status) {
+				continue
+			}
+			totals[f.Status]++
+			if f.Blocking {
+				blocking++
+			}
+			items = append(items, f)
+		}
+	}
+
+	response := map[string]interface{}{
+		"clusters": reports,
+		"count":    len(items),
+		"totals":   totals,
+		"items":    items,
+	}
+	if !target.IsZero() {
+		response["target"] = target.String()
+		response["blocking"] = blocking
+	}
+
+	logger.Debug().Int("findings", len(items)).Msg("Deprecation report returned")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
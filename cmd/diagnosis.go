@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/diagnosis"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/redact"
+)
+
+const (
+	defaultDiagnosisTailLines = 50
+	maxDiagnosisTailLines     = 1000
+	maxDiagnosisLogBytes      = 256 << 10 // Per container, so a noisy container cannot exhaust memory
+)
+
+// podDiagnosisResponse is the response of GET /pods/{namespace}/{name}/diagnosis
+type podDiagnosisResponse struct {
+	Cluster string `json:"cluster"`
+	diagnosis.Report
+}
+
+// tailLinesFromQuery parses ?tailLines=; 0 leaves the logs out
+func tailLinesFromQuery(ctx *fasthttp.RequestCtx) (int64, error) {
+	raw := string(ctx.QueryArgs().Peek("tailLines"))
+	if raw == "" {
+		return defaultDiagnosisTailLines, nil
+	}
+	lines, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || lines < 0 || lines > maxDiagnosisTailLines {
+		return 0, fmt.Errorf("tailLines must be between 0 and %d", maxDiagnosisTailLines)
+	}
+	return lines, nil
+}
+
+// attachLogTails adds the log tail of every container that restarted or is not ready. A
+// restarted container's logs come from the instance that exited, which holds the crash.
+func attachLogTails(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod, report *diagnosis.Report, tailLines int64, redactor *redact.Redactor) {
+	limitBytes := int64(maxDiagnosisLogBytes)
+	for i := range report.Containers {
+		c := &report.Containers[i]
+		if c.Ready && c.RestartCount == 0 {
+			continue
+		}
+		opts := &corev1.PodLogOptions{
+			Container:  c.Name,
+			Previous:   c.RestartCount > 0,
+			TailLines:  &tailLines,
+			LimitBytes: &limitBytes,
+		}
+		raw, err := client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts).DoRaw(ctx)
+		if err != nil {
+			c.LogsError = err.Error()
+			continue
+		}
+		c.LogsPrevious = opts.Previous
+		for _, line := range strings.Split(strings.TrimRight(string(raw), "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+			c.Logs = append(c.Logs, redactor.RedactText(string(redactor.Redact([]byte(line)))))
+		}
+	}
+}
+
+// strictRedactor masks credentials in responses, whether or not log redaction is enabled
+func (s *apiServer) strictRedactor() *redact.Redactor {
+	var cfg redact.Config
+	if s.config != nil {
+		cfg = s.config.Logging.Redaction
+	}
+	cfg.Enabled = true
+	return redact.New(cfg)
+}
+
+// @Summary Diagnose a restarting pod
+// @Description Collects the usual triage data of a pod in one call: every container's state, restart count, last termination (exit code, reason, signal, runtime) with the exit code's meaning, root-cause hints and the redacted tail of its logs. Restarted containers return the logs of the instance that exited.
+// @Tags kubernetes,pods
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Pod name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Param tailLines query int false "Log lines per container (0-1000, default 50); 0 leaves the logs out"
+// @Success 200 {object} podDiagnosisResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /pods/{namespace}/{name}/diagnosis [get]
+func (s *apiServer) handlePodDiagnosis(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	tailLines, err := tailLinesFromQuery(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	pod, err := target.Client.CoreV1().Pods(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Pod %s/%s not found", namespace, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get pod")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get pod"})
+		return
+	}
+
+	report := diagnosis.Diagnose(pod)
+	if tailLines > 0 {
+		attachLogTails(requestContext(ctx), target.Client, pod, &report, tailLines, s.strictRedactor())
+	}
+	logger.Info().Str("namespace", namespace).Str("name", name).Bool("crash_looping", report.CrashLooping).
+		Int32("restarts", report.Restarts).Msg("Pod diagnosed")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(podDiagnosisResponse{Cluster: target.ID, Report: report})
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/drift"
+)
+
+// newDriftLiveGetter reads live objects of any kind through the dynamic client
+func newDriftLiveGetter(clientset *kubernetes.Clientset, appConfig *Config) (drift.LiveGetter, error) {
+	if clientset == nil {
+		return nil, fmt.Errorf("kubernetes client is not available")
+	}
+
+	dynamicClient, err := newPrimaryDynamicClient(appConfig)
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+
+	return func(ctx context.Context, desired *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+		gvk := desired.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			// The kind may have been installed after discovery was cached
+			mapper.Reset()
+			mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+			return dynamicClient.Resource(mapping.Resource).Get(ctx, desired.GetName(), metav1.GetOptions{})
+		}
+		return dynamicClient.Resource(mapping.Resource).Namespace(desired.GetNamespace()).Get(ctx, desired.GetName(), metav1.GetOptions{})
+	}, nil
+}
+
+// @Summary Get drift reports
+// @Description Returns the latest comparison of live objects with desired-state bundles, with per-field diffs
+// @Tags drift
+// @Produce json
+// @Param namespace query string false "Only include resources in this namespace"
+// @Param status query string false "Only include resources with this status (in-sync, drifted, missing, error)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /drift [get]
+func (s *apiServer) handleDrift(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	if s.driftDetector == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Drift detection is disabled"}`)
+		return
+	}
+
+	status := string(ctx.QueryArgs().Peek("status"))
+	reports := s.driftDetector.Reports(string(ctx.QueryArgs().Peek("namespace")))
+
+	items := make([]drift.ResourceReport, 0, len(reports))
+	totals := map[string]int{}
+	for _, report := range reports {
+		totals[report.Status]++
+		if status != "" && report.Status != status {
+			continue
+		}
+		items = append(items, report)
+	}
+
+	response := map[string]interface{}{
+		"count":  len(items),
+		"totals": totals,
+		"items":  items,
+	}
+	if last := s.driftDetector.LastCheck(); !last.IsZero() {
+		response["last_check"] = last.UTC().Format(time.RFC3339)
+	}
+
+	logger.Debug().Int("resources", len(items)).Msg("Drift report returned")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
+
+// @Summary Upload or remove a desired-state bundle
+// @Description POST replaces the desired manifests (multi-document YAML or JSON) for a namespace, DELETE removes them
+// @Tags drift
+// @Accept plain
+// @Produce json
+// @Param namespace query string true "Namespace the bundle applies to"
+// @Success 200 {object} map[string]string
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /drift/bundles [post]
+// @Router /drift/bundles [delete]
+func (s *apiServer) handleDriftBundles(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if s.driftDetector == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Drift detection is disabled"}`)
+		return
+	}
+
+	namespace := string(ctx.QueryArgs().Peek("namespace"))
+	if namespace == "" {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "Missing namespace parameter"}`)
+		return
+	}
+
+	switch string(ctx.Method()) {
+	case "POST", "PUT":
+		objects, err := drift.ParseManifests(ctx.PostBody())
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if len(objects) == 0 {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			ctx.SetBodyString(`{"error": "Bundle contains no manifests"}`)
+			return
+		}
+
+		s.driftDetector.SetBundle(namespace, objects)
+		logger.Info().Str("namespace", namespace).Int("resources", len(objects)).Msg("Desired-state bundle uploaded")
+
+		// Compare right away instead of waiting for the next interval
+		// The check outlives the request, so it keeps the trace but not the request's
+		// cancellation; the RequestCtx is also recycled once the handler returns
+		checkCtx := context.WithoutCancel(requestContext(ctx))
+		go func() {
+			if err := s.driftDetector.Check(checkCtx); err != nil {
+				logger.Error().Err(err).Msg("Drift check failed")
+			}
+		}()
+
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		json.NewEncoder(ctx).Encode(map[string]interface{}{
+			"namespace": namespace,
+			"resources": len(objects),
+		})
+
+	case "DELETE":
+		if !s.driftDetector.DeleteBundle(namespace) {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+			ctx.SetBodyString(`{"error": "No uploaded bundle for namespace"}`)
+			return
+		}
+		logger.Info().Str("namespace", namespace).Msg("Desired-state bundle removed")
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		ctx.SetBodyString(fmt.Sprintf(`{"message": "Bundle for namespace %s removed"}`, namespace))
+
+	default:
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+	}
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/environment"
+)
+
+// environmentCreatedByAnnotation records the principal that created an Environment
+const environmentCreatedByAnnotation = "environments.k8s-custom-controller.io/created-by"
+
+// environmentRequest is the body of POST /environments
+type environmentRequest struct {
+	Name string `json:"name"`
+	environment.Spec
+}
+
+// environmentItem is an Environment with its cluster
+type environmentItem struct {
+	Cluster   string             `json:"cluster"`
+	Name      string             `json:"name"`
+	CreatedBy string             `json:"created_by,omitempty"`
+	Created   time.Time          `json:"created"`
+	Spec      environment.Spec   `json:"spec"`
+	Status    environment.Status `json:"status"`
+}
+
+// newEnvironmentItem converts an Environment read through the dynamic client
+func newEnvironmentItem(clusterID string, u *unstructured.Unstructured) (environmentItem, error) {
+	env, err := environment.FromUnstructured(u)
+	if err != nil {
+		return environmentItem{}, err
+	}
+	return environmentItem{
+		Cluster:   clusterID,
+		Name:      env.Name,
+		CreatedBy: env.Annotations[environmentCreatedByAnnotation],
+		Created:   env.CreationTimestamp.UTC(),
+		Spec:      env.Spec,
+		Status:    env.Status,
+	}, nil
+}
+
+// resolveEnvironments returns the Environment client of the requested cluster. It writes the
+// error response and returns nil when environments are disabled for the cluster.
+func (s *apiServer) resolveEnvironments(ctx *fasthttp.RequestCtx, logger zerolog.Logger) (*clusterTarget, dynamic.ResourceInterface) {
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return nil, nil
+	}
+	if s.config == nil || !s.config.ControllerRuntime.Environments.AppliesTo(target.ID) {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Environments are disabled for cluster %s", target.ID)})
+		return nil, nil
+	}
+	dynamicClient, err := s.lookupDynamicClient(target.ID)
+	if err != nil {
+		logger.Error().Err(err).Str("cluster_id", target.ID).Msg("Failed to create dynamic client")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to create dynamic client"})
+		return nil, nil
+	}
+	return target, dynamicClient.Resource(environment.GVR)
+}
+
+// writeEnvironmentError maps errors of the Environment API to responses. A missing resource
+// means the CRD is not installed.
+func writeEnvironmentError(ctx *fasthttp.RequestCtx, logger zerolog.Logger, err error, action, name string) {
+	switch {
+	case apierrors.IsNotFound(err) && name != "":
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Environment %s not found", name)})
+	case apierrors.IsNotFound(err):
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "The Environment CRD is not installed, apply config/crd"})
+	default:
+		logger.Error().Err(err).Str("environment", name).Msgf("Failed to %s environment", action)
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to %s environment: %s", action, err)})
+	}
+}
+
+// @Summary List ephemeral environments
+// @Description Lists the Environments of a cluster: namespaces cloned from a source namespace, with their phase, cloned object count and expiry.
+// @Tags environments
+// @Produce json
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /environments [get]
+func (s *apiServer) handleEnvironments(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	target, client := s.resolveEnvironments(ctx, logger)
+	if client == nil {
+		return
+	}
+	list, err := client.List(requestContext(ctx), metav1.ListOptions{})
+	if err != nil {
+		writeEnvironmentError(ctx, logger, err, "list", "")
+		return
+	}
+
+	items := make([]environmentItem, 0, len(list.Items))
+	for i := range list.Items {
+		item, err := newEnvironmentItem(target.ID, &list.Items[i])
+		if err != nil {
+			logger.Warn().Err(err).Msg("Skipping invalid environment")
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster": target.ID,
+		"count":   len(items),
+		"items":   items,
+	})
+}
+
+// @Summary Create an ephemeral environment
+// @Description Creates an Environment that clones the ConfigMaps, Services, Deployments and StatefulSets of a source namespace, and its Secrets with includeSecrets, into a new namespace. Names get the suffix, images can be overridden by container name, and the namespace is deleted with the Environment after its ttl. Cloning happens in the background; poll GET /environments/{name} for the phase.
+// @Tags environments
+// @Accept json
+// @Produce json
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Param environment body environmentRequest true "Name and spec, e.g. {\"name\":\"pr-42\",\"source\":\"shop\",\"images\":{\"web\":\"registry/web:pr-42\"},\"ttl\":\"48h\"}"
+// @Success 201 {object} environmentItem
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /environments [post]
+func (s *apiServer) handleEnvironmentCreate(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	var req environmentRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Invalid request body: %s", err)})
+		return
+	}
+	env := &environment.Environment{ObjectMeta: metav1.ObjectMeta{Name: req.Name}, Spec: req.Spec}
+	if err := env.Validate(); err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	target, client := s.resolveEnvironments(ctx, logger)
+	if client == nil {
+		return
+	}
+	if maxTTL := s.config.ControllerRuntime.Environments.MaxTTL; maxTTL > 0 && env.Spec.TTL != "" {
+		if ttl, _ := time.ParseDuration(env.Spec.TTL); ttl > maxTTL {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("ttl %s exceeds the maximum of %s", env.Spec.TTL, maxTTL)})
+			return
+		}
+	}
+	if principal := getPrincipal(ctx); principal != nil {
+		env.Annotations = map[string]string{environmentCreatedByAnnotation: principal.Name}
+	}
+
+	u, err := env.ToUnstructured()
+	if err != nil {
+		writeEnvironmentError(ctx, logger, err, "create", "")
+		return
+	}
+	delete(u.Object, "status") // Written by the controller through the status subresource
+	created, err := client.Create(requestContext(ctx), u, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		ctx.SetStatusCode(fasthttp.StatusConflict)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Environment %s already exists", env.Name)})
+		return
+	}
+	if apierrors.IsInvalid(err) {
+		ctx.SetStatusCode(fasthttp.StatusUnprocessableEntity)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeEnvironmentError(ctx, logger, err, "create", "")
+		return
+	}
+
+	item, err := newEnvironmentItem(target.ID, created)
+	if err != nil {
+		writeEnvironmentError(ctx, logger, err, "create", "")
+		return
+	}
+	logger.Info().
+		Str("cluster_id", target.ID).
+		Str("environment", item.Name).
+		Str("source", item.Spec.Source).
+		Str("namespace", env.TargetNamespace()).
+		Str("created_by", item.CreatedBy).
+		Msg("Environment created")
+
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	json.NewEncoder(ctx).Encode(item)
+}
+
+// @Summary Get an ephemeral environment
+// @Description Returns an Environment with its phase, namespace, cloned object count and expiry.
+// @Tags environments
+// @Produce json
+// @Param name path string true "Environment name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {object} environmentItem
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /environments/{name} [get]
+func (s *apiServer) handleEnvironment(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	name := pathParam(ctx, "name")
+
+	target, client := s.resolveEnvironments(ctx, logger)
+	if client == nil {
+		return
+	}
+	u, err := client.Get(requestContext(ctx), name, metav1.GetOptions{})
+	if err != nil {
+		writeEnvironmentError(ctx, logger, err, "get", name)
+		return
+	}
+	item, err := newEnvironmentItem(target.ID, u)
+	if err != nil {
+		writeEnvironmentError(ctx, logger, err, "get", name)
+		return
+	}
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(item)
+}
+
+// @Summary Delete an ephemeral environment
+// @Description Deletes an Environment before its ttl. Its namespace and everything cloned into it are garbage collected with it.
+// @Tags environments
+// @Produce json
+// @Param name path string true "Environment name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /environments/{name} [delete]
+func (s *apiServer) handleEnvironmentDelete(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	name := pathParam(ctx, "name")
+
+	target, client := s.resolveEnvironments(ctx, logger)
+	if client == nil {
+		return
+	}
+	propagation := metav1.DeletePropagationBackground
+	if err := client.Delete(requestContext(ctx), name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+		writeEnvironmentError(ctx, logger, err, "delete", name)
+		return
+	}
+
+	logger.Info().Str("cluster_id", target.ID).Str("environment", name).Msg("Environment deleted")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]string{
+		"cluster": target.ID,
+		"name":    name,
+		"message": "Environment deleted, its namespace is being removed",
+	})
+}
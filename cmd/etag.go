@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// listETag derives a weak ETag from the namespace, name and resourceVersion of every returned
+// object, in response order. The query string and negotiated output format are included
+// because they shape the body as well. The list's own resourceVersion is left out on
+// purpose: it advances with every change anywhere in the cluster.
+func listETag(ctx *fasthttp.RequestCtx, clusterID string, objects []metav1.Object) string {
+	h := sha256.New()
+	output, _ := outputFormat(ctx)
+	h.Write([]byte(clusterID + "\n" + output + "\n"))
+	h.Write(ctx.URI().QueryString())
+	for _, obj := range objects {
+		h.Write([]byte("\n" + obj.GetNamespace() + "/" + obj.GetName() + "@" + obj.GetResourceVersion()))
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// writeNotModified sets the ETag header and answers 304 Not Modified when If-None-Match
+// already names it. It returns true when the response is complete. Responses with
+// ?relative=true carry no ETag: their ages change while the objects do not.
+func writeNotModified(ctx *fasthttp.RequestCtx, etag string) bool {
+	if ctx.QueryArgs().GetBool("relative") {
+		return false
+	}
+	ctx.Response.Header.Set("ETag", etag)
+	if !etagMatches(string(ctx.Request.Header.Peek("If-None-Match")), etag) {
+		return false
+	}
+	ctx.SetStatusCode(fasthttp.StatusNotModified)
+	ctx.ResetBody()
+	return true
+}
+
+// etagMatches applies the weak comparison If-None-Match requires to a list of tags or "*"
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/terminal"
+)
+
+// lookupRestConfig returns the REST config of a cluster, for subresources that need their own
+// transport such as exec
+func (s *apiServer) lookupRestConfig(clusterID string) (*rest.Config, error) {
+	if clusterID == primaryClusterID {
+		if s.restConfig == nil {
+			return nil, errKubeClientUnavailable
+		}
+		config := rest.CopyConfig(s.restConfig)
+		config.Timeout = 0
+		return config, nil
+	}
+	if s.multiClusterManager == nil {
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+	return s.multiClusterManager.GetRestConfig(clusterID)
+}
+
+// @Summary Exec into a pod over WebSocket
+// @Description Upgrades to a WebSocket bridged to the exec subresource of a container, for web terminals. Binary frames are prefixed with a channel byte:
+// @Description 0 carries stdin from the client, 1 stdout and 2 stderr to the client, 4 resizes the terminal with {"Width":80,"Height":24}.
+// @Description When the command ends a text frame {"type":"exit","exit_code":0} is sent and the connection is closed. Requires the editor role.
+// @Tags kubernetes,pods
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Pod name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Param container query string false "Container name"
+// @Param command query []string false "Command and arguments, repeated, defaults to /bin/sh" collectionFormat(multi)
+// @Param tty query bool false "Allocate a terminal, defaults to true"
+// @Param stdin query bool false "Attach stdin, defaults to true"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /pods/{namespace}/{name}/exec [get]
+func (s *apiServer) handlePodExec(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	if s.config == nil || !s.config.APIServer.Exec.Enabled {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Pod exec is disabled"}`)
+		return
+	}
+	cfg := s.config.APIServer.Exec
+
+	if !websocket.FastHTTPIsWebSocketUpgrade(ctx) {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "Expected a WebSocket upgrade request"}`)
+		return
+	}
+
+	args := ctx.QueryArgs()
+	var command []string
+	for _, arg := range args.PeekMulti("command") {
+		command = append(command, string(arg))
+	}
+	if len(command) == 0 {
+		command = []string{"/bin/sh"}
+	}
+	tty := string(args.Peek("tty")) != "false"
+	stdin := string(args.Peek("stdin")) != "false"
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	pod, err := target.Client.CoreV1().Pods(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Pod %s/%s not found", namespace, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get pod")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get pod"})
+		return
+	}
+	container, err := logContainer(pod, string(args.Peek("container")))
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Pod %s/%s is %s, not Running", namespace, name, pod.Status.Phase)})
+		return
+	}
+
+	restConfig, err := s.lookupRestConfig(target.ID)
+	if err != nil {
+		logger.Error().Err(err).Str("cluster_id", target.ID).Msg("Failed to get REST config for pod exec")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Pod exec is not available for this cluster"})
+		return
+	}
+	req := target.Client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin,
+			Stdout:    true,
+			Stderr:    !tty, // A terminal merges stderr into stdout
+			TTY:       tty,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(restConfig, "POST", req.URL())
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to create pod exec executor")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to start pod exec"})
+		return
+	}
+
+	if n := s.execSessions.Add(1); cfg.MaxSessions > 0 && n > int64(cfg.MaxSessions) {
+		s.execSessions.Add(-1)
+		logger.Warn().Int("max_sessions", cfg.MaxSessions).Msg("Pod exec session limit reached")
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Too many exec sessions"}`)
+		return
+	}
+
+	sessionLogger := logger.With().
+		Str("cluster_id", target.ID).
+		Str("namespace", namespace).
+		Str("name", name).
+		Str("container", container).
+		Strs("command", command).
+		Logger()
+	if principal := getPrincipal(ctx); principal != nil {
+		sessionLogger = sessionLogger.With().Str("principal", principal.Name).Logger()
+	}
+	upgrader := newUpgrader(cfg.AllowedOrigins)
+
+	err = upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+		defer s.drain.TrackStream()()
+		defer s.execSessions.Add(-1)
+		defer conn.Close()
+
+		started := time.Now()
+		sessionLogger.Info().Bool("tty", tty).Msg("Pod exec session started")
+		exit := terminal.Serve(s.drain.StreamContext(), conn, executor, terminal.Options{
+			Stdin:        stdin,
+			TTY:          tty,
+			PingInterval: cfg.PingInterval,
+			IdleTimeout:  cfg.IdleTimeout,
+		})
+		sessionLogger.Info().
+			Int("exit_code", exit.ExitCode).
+			Str("reason", exit.Error).
+			Dur("duration", time.Since(started)).
+			Msg("Pod exec session ended")
+	})
+	if err != nil {
+		s.execSessions.Add(-1)
+		logger.Warn().Err(err).Msg("WebSocket upgrade failed")
+	}
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/inventory"
+)
+
+// @Summary Export workload inventory
+// @Description Returns a flattened inventory of workloads across clusters (cluster, namespace, kind, name, image, replicas, requests) as CSV or Parquet. Clusters that cannot be read are left out and listed in the X-Skipped-Clusters header.
+// @Tags kubernetes,export
+// @Produce text/csv
+// @Produce application/vnd.apache.parquet
+// @Param format query string false "Export format: csv (default) or parquet"
+// @Param namespace query string false "Namespace to export, all namespaces when empty"
+// @Param cluster query string false "Cluster ID to export, all clusters when empty"
+// @Success 200 {file} file
+// @Header 200 {string} X-Skipped-Clusters "Comma-separated IDs of clusters left out because they could not be read"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /export/inventory [get]
+func (s *apiServer) handleExportInventory(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	format := string(ctx.QueryArgs().Peek("format"))
+	if format == "" {
+		format = inventory.FormatCSV
+	}
+	if format != inventory.FormatCSV && format != inventory.FormatParquet {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Unsupported format, expected csv or parquet"})
+		return
+	}
+
+	clients, err := s.inventoryClients(string(ctx.QueryArgs().Peek("cluster")))
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to resolve clusters for inventory export")
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	rows, skipped := collectInventory(requestContext(ctx), clients, getNamespaceFromQuery(ctx))
+	if len(skipped) == len(clients) {
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{
+			"error": fmt.Sprintf("Failed to collect inventory for cluster %s", strings.Join(skipped, ", ")),
+		})
+		return
+	}
+
+	// Encode into a buffer so encoding errors can still produce a JSON error response
+	var buf bytes.Buffer
+	if err := inventory.Write(&buf, format, rows); err != nil {
+		logger.Error().Err(err).Str("format", format).Msg("Failed to encode inventory")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to encode inventory"})
+		return
+	}
+
+	filename := fmt.Sprintf("inventory-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	ctx.SetContentType(inventory.ContentType(format))
+	ctx.Response.Header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if len(skipped) > 0 {
+		ctx.Response.Header.Set("X-Skipped-Clusters", strings.Join(skipped, ","))
+	}
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.SetBody(buf.Bytes())
+
+	logger.Info().
+		Str("format", format).
+		Int("clusters", len(clients)).
+		Int("rows", len(rows)).
+		Strs("skipped_clusters", skipped).
+		Msg("Inventory exported")
+}
+
+// collectInventory reads the workloads of every cluster, sorted, and the sorted IDs of the
+// clusters that could not be read. Those are skipped so one unreachable cluster does not
+// stop the export of the others.
+func collectInventory(ctx context.Context, clients map[string]kubernetes.Interface, namespace string) ([]inventory.Row, []string) {
+	var rows []inventory.Row
+	var skipped []string
+	for clusterID, client := range clients {
+		clusterRows, err := inventory.Collect(ctx, client, clusterID, namespace)
+		if err != nil {
+			log.Warn().Err(err).Str("cluster_id", clusterID).Msg("Failed to collect cluster inventory")
+			skipped = append(skipped, clusterID)
+			continue
+		}
+		rows = append(rows, clusterRows...)
+	}
+	inventory.Sort(rows)
+	sort.Strings(skipped)
+	return rows, skipped
+}
+
+// inventoryClients returns clientsets keyed by cluster ID, limited to clusterID when set.
+// Without a multi-cluster manager only the primary cluster is available.
+func (s *apiServer) inventoryClients(clusterID string) (map[string]kubernetes.Interface, error) {
+	clients := make(map[string]kubernetes.Interface)
+
+	if s.multiClusterManager == nil || s.multiClusterManager.GetClusterCount() == 0 {
+		if s.clientset == nil {
+			return nil, fmt.Errorf("kubernetes client is not available")
+		}
+		if clusterID != "" && clusterID != primaryClusterID {
+			return nil, fmt.Errorf("cluster %s not found", clusterID)
+		}
+		clients[primaryClusterID] = s.clientset
+		return clients, nil
+	}
+
+	for _, cfg := range s.multiClusterManager.GetClusters() {
+		if clusterID != "" && cfg.ClusterID != clusterID {
+			continue
+		}
+		client, err := s.multiClusterManager.GetClientset(cfg.ClusterID)
+		if err != nil {
+			return nil, err
+		}
+		clients[cfg.ClusterID] = client
+	}
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+	return clients, nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCollectInventory_SkipsFailedClusters(t *testing.T) {
+	healthy := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "web", Image: "nginx:1.27"}},
+		}}},
+	})
+	unreachable := fake.NewSimpleClientset()
+	unreachable.PrependReactor("list", "*", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+
+	rows, skipped := collectInventory(context.Background(), map[string]kubernetes.Interface{
+		"east": healthy,
+		"west": unreachable,
+	}, "")
+	assert.Equal(t, []string{"west"}, skipped)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, "east", rows[0].Cluster)
+		assert.Equal(t, "nginx:1.27", rows[0].Image)
+	}
+}
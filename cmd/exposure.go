@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/exposure"
+)
+
+// @Summary Get the exposure report
+// @Description Summarizes NodePort and LoadBalancer services and hostNetwork and hostPort workloads of every cluster. Exposure is public when nodes have public addresses or a load balancer is not internal, and unexpected when it is public and no exposure.allow rule matches. Conflicts list host ports bound by several workloads on a node and host ports that are also node ports.
+// @Tags security
+// @Produce json
+// @Param cluster query string false "Only include this cluster"
+// @Param namespace query string false "Only include this namespace"
+// @Param kind query string false "Only include NodePort, LoadBalancer, HostNetwork or HostPort exposure"
+// @Param unexpected query bool false "Only include unexpected public exposure"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /security/exposure [get]
+func (s *apiServer) handleSecurityExposure(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	clusterFilter := string(ctx.QueryArgs().Peek("cluster"))
+	namespace := getNamespaceFromQuery(ctx)
+	kind := string(ctx.QueryArgs().Peek("kind"))
+	onlyUnexpected := ctx.QueryArgs().GetBool("unexpected")
+	if kind != "" && !slices.Contains(exposure.Kinds, kind) {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{
+			"error": fmt.Sprintf("Invalid kind %q, expected one of %s", kind, strings.Join(exposure.Kinds, ", ")),
+		})
+		return
+	}
+
+	policy := s.exposurePolicy
+	if policy == nil {
+		policy, _ = exposure.New(exposure.Config{})
+	}
+
+	results, err := s.collectClusters(requestContext(ctx))
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to resolve clusters for exposure report")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if _, ok := results[clusterFilter]; clusterFilter != "" && !ok {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Cluster %s not found", clusterFilter)})
+		return
+	}
+
+	clusterIDs := make([]string, 0, len(results))
+	for clusterID := range results {
+		if clusterFilter == "" || clusterID == clusterFilter {
+			clusterIDs = append(clusterIDs, clusterID)
+		}
+	}
+	slices.Sort(clusterIDs)
+
+	items := make([]exposure.Exposure, 0)
+	conflicts := make([]exposure.Conflict, 0)
+	totals := map[string]int{}
+	unexpected := 0
+	clusterErrors := map[string]string{}
+	stale := map[string]staleness{}
+	for _, clusterID := range clusterIDs {
+		result := results[clusterID]
+		if result.err != nil {
+			logger.Error().Err(result.err).Str("cluster_id", clusterID).Msg("Failed to read cluster for exposure report")
+			clusterErrors[clusterID] = result.err.Error()
+			continue
+		}
+		if result.Stale {
+			stale[clusterID] = result.staleness
+		}
+		exposures, clusterConflicts := policy.Analyze(exposure.Cluster{
+			ID:       clusterID,
+			Nodes:    result.resources.nodes,
+			Pods:     result.resources.pods,
+			Services: result.resources.services,
+		})
+		for _, e := range exposures {
+			if (namespace != "" && e.Namespace != namespace) || (kind != "" && e.Kind != kind) || (onlyUnexpected && !e.Unexpected) {
+				continue
+			}
+			totals[e.Kind]++
+			if e.Unexpected {
+				unexpected++
+			}
+			items = append(items, e)
+		}
+		for _, c := range clusterConflicts {
+			if namespace != "" && !conflictInNamespace(c, namespace) {
+				continue
+			}
+			conflicts = append(conflicts, c)
+		}
+	}
+
+	response := map[string]interface{}{
+		"clusters":   clusterIDs,
+		"count":      len(items),
+		"unexpected": unexpected,
+		"totals":     totals,
+		"items":      items,
+		"conflicts":  conflicts,
+	}
+	if len(clusterErrors) > 0 {
+		response["errors"] = clusterErrors
+	}
+	if len(stale) > 0 {
+		response["stale"] = stale
+	}
+
+	logger.Debug().Int("exposures", len(items)).Int("unexpected", unexpected).Int("conflicts", len(conflicts)).Msg("Exposure report returned")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
+
+// conflictInNamespace reports whether a conflict involves a resource of the namespace
+func conflictInNamespace(c exposure.Conflict, namespace string) bool {
+	for _, r := range c.Resources {
+		if strings.HasPrefix(r, namespace+"/") {
+			return true
+		}
+	}
+	return false
+}
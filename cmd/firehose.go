@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/valyala/fasthttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/stream"
+)
+
+// firehoseEventTypes maps controller event types to watch event types; other types such as
+// LEADER_ELECTED and ROLLOUT_STUCK are passed through
+var firehoseEventTypes = map[string]string{
+	"CREATE": stream.EventAdded,
+	"UPDATE": stream.EventModified,
+	"DELETE": stream.EventDeleted,
+}
+
+// firehoseSink publishes the events the cluster managers emit to sink plugins, so the
+// firehose sees every cluster without informers of its own
+type firehoseSink struct {
+	events *stream.Broadcaster
+}
+
+func (f *firehoseSink) Name() string { return "firehose" }
+
+func (f *firehoseSink) Send(_ context.Context, event plugin.Event) error {
+	f.events.Publish(newFirehoseEvent(event))
+	return nil
+}
+
+// newFirehoseEvent converts a controller event. Secrets are reduced to their identity so
+// the stream never carries their data.
+func newFirehoseEvent(event plugin.Event) stream.Event {
+	e := stream.Event{
+		Type:      event.Type,
+		Cluster:   event.ClusterID,
+		Kind:      event.ResourceType,
+		Namespace: event.Namespace,
+		Name:      event.Name,
+		Time:      time.Now(),
+		Object:    event.Object,
+	}
+	if t, ok := firehoseEventTypes[event.Type]; ok {
+		e.Type = t
+	}
+	if obj, ok := event.Object.(metav1.Object); ok {
+		e.ResourceVersion = obj.GetResourceVersion()
+	}
+	if event.ResourceType == "Secret" {
+		e.Object = nil
+	}
+	return e
+}
+
+// @Summary Stream events of every cluster
+// @Description Streams the resource events of every managed cluster, as reported by the cluster managers, filtered by an expression evaluated per connection,
+// @Description e.g. cluster=prod* AND kind=Deployment AND namespace!=kube-system. Fields are cluster, type, kind, namespace, name and label.<key>;
+// @Description values may use * as a wildcard, and comparisons combine with AND, OR, NOT and parentheses.
+// @Description Served as Server-Sent Events, or as a WebSocket of {"type":"event","event":{...}} frames when the request is an upgrade.
+// @Description Only live events are sent; the firehose keeps no history to resume from.
+// @Tags watch
+// @Produce text/event-stream
+// @Param filter query string false "Filter expression, all events when empty"
+// @Success 200 {string} string "text/event-stream"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /firehose [get]
+func (s *apiServer) handleFirehose(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if s.firehose == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "The firehose is disabled"}`)
+		return
+	}
+
+	filter, err := stream.ParseExpression(string(ctx.QueryArgs().Peek("filter")))
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("invalid filter: %v", err)})
+		return
+	}
+
+	cfg := s.config.APIServer.Firehose
+	if n := s.firehoseStreams.Add(1); cfg.MaxConnections > 0 && n > int64(cfg.MaxConnections) {
+		s.firehoseStreams.Add(-1)
+		logger.Warn().Int("max_connections", cfg.MaxConnections).Msg("Firehose connection limit reached")
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Too many firehose connections"}`)
+		return
+	}
+
+	sub, _, err := s.firehose.Subscribe("")
+	if err != nil {
+		s.firehoseStreams.Add(-1)
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	logger.Info().Str("filter", filter.String()).Bool("websocket", websocket.FastHTTPIsWebSocketUpgrade(ctx)).Msg("Firehose started")
+
+	if websocket.FastHTTPIsWebSocketUpgrade(ctx) {
+		s.serveFirehoseWebSocket(ctx, sub, filter)
+		return
+	}
+
+	ctx.SetContentType("text/event-stream")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.Response.Header.Set("X-Accel-Buffering", "no") // Disable buffering in nginx-style proxies
+	ctx.SetStatusCode(fasthttp.StatusOK)
+
+	heartbeat := s.firehose.Heartbeat()
+	maxDuration := s.firehose.MaxDuration()
+
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer s.drain.TrackStream()()
+		defer s.firehoseStreams.Add(-1)
+		defer s.firehose.Unsubscribe(sub)
+
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis)
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		deadline := time.NewTimer(maxDuration)
+		defer deadline.Stop()
+
+		for {
+			select {
+			case event, ok := <-sub.Events():
+				if !ok {
+					if sub.Overflowed() {
+						logger.Warn().Msg("Firehose client fell behind, closing stream")
+					}
+					return
+				}
+				if filter.Matches(event) && writeSSEEvent(w, event) != nil {
+					logger.Debug().Msg("Firehose client disconnected")
+					return
+				}
+			case <-ticker.C:
+				if _, err := w.WriteString(": keep-alive\n\n"); err != nil || w.Flush() != nil {
+					logger.Debug().Msg("Firehose client disconnected")
+					return
+				}
+			case <-deadline.C:
+				return
+			}
+		}
+	})
+}
+
+// serveFirehoseWebSocket upgrades the request and forwards matching events as event frames.
+// Messages from the client are ignored.
+func (s *apiServer) serveFirehoseWebSocket(ctx *fasthttp.RequestCtx, sub *stream.Subscription, filter *stream.Expression) {
+	logger := getRequestLogger(ctx)
+	sendBuffer := s.config.APIServer.Watch.BufferSize
+	if sendBuffer <= 0 {
+		sendBuffer = 256
+	}
+	pingInterval := s.firehose.Heartbeat()
+
+	upgrader := newUpgrader(s.config.APIServer.Firehose.AllowedOrigins)
+	err := upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+		defer s.drain.TrackStream()()
+		defer s.firehoseStreams.Add(-1)
+
+		session := &wsSession{
+			server:   s,
+			conn:     conn,
+			logger:   logger,
+			send:     make(chan wsMessage, sendBuffer),
+			overflow: s.firehose.OverflowPolicy(),
+			stream:   "firehose",
+			done:     make(chan struct{}),
+			subs:     map[string]func(){"firehose": func() { s.firehose.Unsubscribe(sub) }},
+		}
+		go session.writeLoop(pingInterval)
+		go func() {
+			for event := range sub.Events() {
+				if filter.Matches(event) && !session.enqueue(wsMessage{Type: "event", Event: &event}) {
+					return
+				}
+			}
+			if sub.Overflowed() {
+				logger.Warn().Msg("Firehose client fell behind, closing connection")
+				session.close(websocket.ClosePolicyViolation, "client too slow")
+				return
+			}
+			session.close(websocket.CloseGoingAway, "server shutting down")
+		}()
+		session.discardReads(pingInterval)
+		logger.Debug().Msg("Firehose client disconnected")
+	})
+	if err != nil {
+		s.firehoseStreams.Add(-1)
+		s.firehose.Unsubscribe(sub)
+		logger.Warn().Err(err).Msg("WebSocket upgrade failed")
+	}
+}
+
+// discardReads answers pings and drops client messages until the connection ends
+func (ws *wsSession) discardReads(pingInterval time.Duration) {
+	defer ws.close(websocket.CloseNormalClosure, "")
+
+	ws.conn.SetReadLimit(4096)
+	ws.conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+	ws.conn.SetPongHandler(func(string) error {
+		return ws.conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+	})
+	for {
+		if _, _, err := ws.conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				ws.logger.Debug().Err(err).Msg("WebSocket read failed")
+			}
+			return
+		}
+	}
+}
+
+// closeFirehose ends every firehose stream
+func (s *apiServer) closeFirehose() {
+	if s.firehose != nil {
+		s.firehose.Close()
+	}
+}
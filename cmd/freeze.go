@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/auth"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/freeze"
+)
+
+// primaryClusterID identifies the cluster the API server was started against
+const primaryClusterID = "primary-cluster"
+
+// freezeOverrideHeader lets admins perform changes during a freeze; its value is logged as the reason
+const freezeOverrideHeader = "X-Freeze-Override"
+
+// freezeExemptRoutes are mutating routes the freeze stage leaves alone because ?cluster= does
+// not name what they change. Routes acting on clusters given in their body check each of them
+// in their handler; the others change no cluster at all.
+var freezeExemptRoutes = map[route]bool{
+	// Checked per cluster by the handler
+	{Method: "POST", Path: "/labels/apply"}: true,
+	{Method: "POST", Path: "/migrate"}:      true,
+	{Method: "POST", Path: "/failover"}:     true,
+	// Change no cluster: reports only read and deliver, bundles and captures live in the server
+	{Method: "POST", Path: "/reports/{name}/run"}: true,
+	{Method: "POST", Path: "/drift/bundles"}:      true,
+	{Method: "PUT", Path: "/drift/bundles"}:       true,
+	{Method: "DELETE", Path: "/drift/bundles"}:    true,
+	{Method: "POST", Path: debugCapturePath}:      true,
+	{Method: "DELETE", Path: debugCapturePath}:    true,
+}
+
+// checkFreeze refuses mutating requests while a change freeze is in effect for the target cluster,
+// the one named by ?cluster= or the primary cluster. It writes the error response and returns
+// false when the request must stop.
+func (s *apiServer) checkFreeze(ctx *fasthttp.RequestCtx, method, path string, logger zerolog.Logger) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	if freezeExemptRoutes[route{Method: method, Path: getRequestInfo(ctx).route}] {
+		return true
+	}
+
+	clusterID := string(ctx.QueryArgs().Peek("cluster"))
+	if clusterID == "" {
+		clusterID = primaryClusterID
+	}
+	return s.checkClusterFreeze(ctx, clusterID, method, path, logger)
+}
+
+// checkClusterFreeze refuses a change to clusterID while a freeze is in effect for it, unless an
+// admin overrides it. Requests that change several clusters check each of them.
+func (s *apiServer) checkClusterFreeze(ctx *fasthttp.RequestCtx, clusterID, method, path string, logger zerolog.Logger) bool {
+	err := freeze.Check(clusterID)
+	var freezeErr *freeze.Error
+	if !errors.As(err, &freezeErr) {
+		return true
+	}
+
+	// Admins may override the freeze by stating a reason
+	overrideReason := string(ctx.Request.Header.Peek(freezeOverrideHeader))
+	if principal := getPrincipal(ctx); principal != nil && principal.Role >= auth.RoleAdmin && overrideReason != "" {
+		logger.Warn().
+			Str("principal", principal.Name).
+			Str("window", freezeErr.Window).
+			Str("cluster_id", clusterID).
+			Str("override_reason", overrideReason).
+			Msg("Change freeze overridden by admin")
+		return true
+	}
+
+	logger.Warn().
+		Str("window", freezeErr.Window).
+		Str("cluster_id", clusterID).
+		Str("method", method).
+		Str("path", path).
+		Msg("Request refused by change freeze")
+	ctx.SetStatusCode(fasthttp.StatusLocked)
+	json.NewEncoder(ctx).Encode(map[string]string{
+		"error":     err.Error(),
+		"window":    freezeErr.Window,
+		"cluster":   clusterID,
+		"until":     freezeErr.Until.UTC().Format(time.RFC3339),
+		"overrides": "admins may send the " + freezeOverrideHeader + " header with a reason",
+	})
+	return false
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/freeze"
+)
+
+// TestCheckFreeze_RouteTargets tests that the freeze stage checks the cluster a route changes
+func TestCheckFreeze_RouteTargets(t *testing.T) {
+	checker, err := freeze.NewChecker(freeze.Config{Enabled: true, Windows: []freeze.Window{{
+		Name:     "release",
+		Clusters: []string{primaryClusterID},
+		Start:    time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		End:      time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	}}})
+	require.NoError(t, err)
+	freeze.SetDefault(checker)
+	defer freeze.SetDefault(nil)
+
+	s := &apiServer{}
+	check := func(method, uri, route string) int {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod(method)
+		ctx.Request.SetRequestURI(uri)
+		ctx.SetUserValue(requestInfoUserValueKey, &requestInfo{route: route})
+		if !s.checkFreeze(ctx, method, string(ctx.Path()), zerolog.Nop()) {
+			return ctx.Response.StatusCode()
+		}
+		return fasthttp.StatusOK
+	}
+
+	assert.Equal(t, fasthttp.StatusOK, check("GET", "/deployments", "/deployments"))
+	assert.Equal(t, fasthttp.StatusLocked, check("POST", "/deployments", "/deployments"))
+	assert.Equal(t, fasthttp.StatusOK, check("POST", "/deployments?cluster=staging", "/deployments"))
+
+	// Routes without ?cluster= that change other clusters, or none, do not follow the primary
+	assert.Equal(t, fasthttp.StatusOK, check("POST", "/migrate", "/migrate"))
+	assert.Equal(t, fasthttp.StatusOK, check("POST", "/reports/weekly/run", "/reports/{name}/run"))
+	assert.Equal(t, fasthttp.StatusOK, check("DELETE", debugCapturePath, debugCapturePath))
+}
@@ -0,0 +1,502 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	controllerv1 "github.com/obezsmertnyi/k8s-custom-controller/api/proto/controller/v1"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/audit"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/auth"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ctrl"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/freeze"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/lockout"
+)
+
+// grpcRoutes maps each RPC to the REST method and path it mirrors, so role, scope, freeze
+// and audit rules configured for the REST API apply to gRPC as well
+var grpcRoutes = map[string]route{
+	controllerv1.ControllerService_ListClusters_FullMethodName:    {Method: "GET", Path: "/clusters"},
+	controllerv1.ControllerService_AddCluster_FullMethodName:      {Method: "POST", Path: "/clusters"},
+	controllerv1.ControllerService_RemoveCluster_FullMethodName:   {Method: "DELETE", Path: "/clusters"},
+	controllerv1.ControllerService_ListDeployments_FullMethodName: {Method: "GET", Path: "/deployments"},
+	controllerv1.ControllerService_ListPods_FullMethodName:        {Method: "GET", Path: "/pods"},
+	controllerv1.ControllerService_ListNodes_FullMethodName:       {Method: "GET", Path: "/nodes"},
+}
+
+// grpcServer implements controllerv1.ControllerServiceServer on top of the REST server's
+// clients, informer cache and multi-cluster manager
+type grpcServer struct {
+	controllerv1.UnimplementedControllerServiceServer
+	api *apiServer
+}
+
+// newGRPCServer builds the gRPC server with logging, auth, freeze and audit interceptors
+func newGRPCServer(api *apiServer, tlsConfig *tls.Config, enableReflection bool) *grpc.Server {
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(api.grpcUnaryInterceptor)}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(opts...)
+	controllerv1.RegisterControllerServiceServer(server, &grpcServer{api: api})
+	if enableReflection {
+		reflection.Register(server)
+	}
+	return server
+}
+
+// serveGRPC listens on the address and serves until the server is stopped
+func serveGRPC(server *grpc.Server, address string) error {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return server.Serve(ln)
+}
+
+// grpcUnaryInterceptor applies the REST request pipeline to a unary RPC: request ID,
+// authentication and authorization, change freezes, audit logging and access logging
+func (s *apiServer) grpcUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	requestID := uuid.New().String()
+	logger := log.With().Str("request_id", requestID).Str("rpc", info.FullMethod).Logger()
+	ctx = logger.WithContext(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+
+	clientIP := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		clientIP, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+
+	// Unknown methods, such as reflection, are not mapped and only pass through logging
+	route, mapped := grpcRoutes[info.FullMethod]
+	method, path := "", ""
+	if mapped {
+		method, path = route.Method, route.Path
+	}
+
+	var principal *auth.Principal
+	resp, err := func() (interface{}, error) {
+		if mapped && s.authorizer != nil {
+			var err error
+			if principal, err = s.authorizeRPC(ctx, method, path, clientIP, logger); err != nil {
+				return nil, err
+			}
+		}
+		if mapped {
+			if err := checkFreezeRPC(ctx, principal, method, path, logger); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}()
+
+	code := status.Code(err)
+	if mapped && s.auditor != nil && s.auditor.Matches(method, path) {
+		entry := audit.Entry{
+			Time:       start.UTC(),
+			RequestID:  requestID,
+			ClientIP:   clientIP,
+			Method:     method,
+			Path:       path,
+			Status:     httpStatusFromCode(code),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		entry.Result = audit.ResultForStatus(entry.Status)
+		if principal != nil {
+			entry.Principal = principal.Name
+			entry.Role = principal.Role.String()
+		}
+		s.auditor.Record(entry)
+	}
+
+	event := logger.Info()
+	if err != nil {
+		event = logger.Warn().Err(err)
+	}
+	event.Str("code", code.String()).Str("client", clientIP).Dur("duration", time.Since(start)).Msg("gRPC request completed")
+	return resp, err
+}
+
+// authorizeRPC authenticates the caller from the authorization or x-api-key metadata and
+// enforces the role and scope rules of the equivalent REST route. Lockouts apply as for REST.
+func (s *apiServer) authorizeRPC(ctx context.Context, method, path, clientIP string, logger zerolog.Logger) (*auth.Principal, error) {
+	if s.authorizer.IsPublic(path) {
+		return nil, nil
+	}
+
+	if s.lockout != nil {
+		if _, locked := s.lockout.Locked(lockout.KindIP, clientIP); locked {
+			logger.Warn().Str("client", clientIP).Msg("Request from locked out client refused")
+			return nil, status.Error(codes.ResourceExhausted, "too many failed authentication attempts, try again later")
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := s.authorizer.Authenticate(firstMetadata(md, "authorization"), firstMetadata(md, "x-api-key"))
+	if err != nil {
+		logger.Warn().Err(err).Msg("Authentication failed")
+		if errors.Is(err, auth.ErrNoCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+		s.recordAuthFailure(lockout.EventAuthFailure, lockout.KindIP, clientIP, err.Error(), logger)
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+
+	if s.lockout != nil && principal.Source != "anonymous" {
+		if _, locked := s.lockout.Locked(lockout.KindPrincipal, principal.Name); locked {
+			logger.Warn().Str("principal", principal.Name).Msg("Request from locked out principal refused")
+			return nil, status.Error(codes.ResourceExhausted, "too many failed authorization attempts, try again later")
+		}
+	}
+
+	if !s.authorizer.Authorize(principal, method, path) {
+		if principal.Source != "anonymous" {
+			s.recordAuthFailure(lockout.EventAuthzFailure, lockout.KindPrincipal, principal.Name, method+" "+path, logger)
+		}
+		required := s.authorizer.RequiredRole(method, path)
+		requiredScope := s.authorizer.RequiredScope(method, path)
+		logger.Warn().
+			Str("principal", principal.Name).
+			Str("role", principal.Role.String()).
+			Str("required_role", required.String()).
+			Str("required_scope", requiredScope).
+			Msg("Authorization denied")
+		if principal.Role < required {
+			return nil, status.Errorf(codes.PermissionDenied, "requires %s role", required)
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "requires %s scope", requiredScope)
+	}
+	return principal, nil
+}
+
+// checkFreezeRPC refuses mutating RPCs while a freeze window covers the primary cluster,
+// unless an admin states an override reason in the x-freeze-override metadata
+func checkFreezeRPC(ctx context.Context, principal *auth.Principal, method, path string, logger zerolog.Logger) error {
+	if method == "GET" {
+		return nil
+	}
+
+	err := freeze.Check(primaryClusterID)
+	var freezeErr *freeze.Error
+	if !errors.As(err, &freezeErr) {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if reason := firstMetadata(md, freezeOverrideHeader); reason != "" && principal != nil && principal.Role >= auth.RoleAdmin {
+		logger.Warn().Str("principal", principal.Name).Str("window", freezeErr.Window).Str("override_reason", reason).Msg("Change freeze overridden by admin")
+		return nil
+	}
+
+	logger.Warn().Str("window", freezeErr.Window).Str("path", path).Msg("Request refused by change freeze")
+	return status.Error(codes.FailedPrecondition, err.Error())
+}
+
+// firstMetadata returns the first value of a metadata key; keys are matched case-insensitively
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// httpStatusFromCode maps a gRPC status code to the HTTP status recorded in audit entries
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return 200
+	case codes.InvalidArgument, codes.OutOfRange:
+		return 400
+	case codes.Unauthenticated:
+		return 401
+	case codes.PermissionDenied:
+		return 403
+	case codes.NotFound:
+		return 404
+	case codes.AlreadyExists, codes.Aborted:
+		return 409
+	case codes.FailedPrecondition:
+		return 412
+	case codes.ResourceExhausted:
+		return 429
+	case codes.Unavailable:
+		return 503
+	default:
+		return 500
+	}
+}
+
+// grpcError converts a Kubernetes or lookup error into a gRPC status
+func grpcError(err error, message string) error {
+	switch {
+	case errors.Is(err, errKubeClientUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case apierrors.IsResourceExpired(err), apierrors.IsGone(err):
+		return status.Error(codes.OutOfRange, "continue token has expired, restart the list without it")
+	case apierrors.IsBadRequest(err):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case apierrors.IsForbidden(err):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "%s: %v", message, err)
+	}
+}
+
+// resolveTarget validates the list request and selects its cluster
+func (g *grpcServer) resolveTarget(req *controllerv1.ListRequest) (*clusterTarget, metav1.ListOptions, error) {
+	opts := metav1.ListOptions{
+		LabelSelector: req.GetLabelSelector(),
+		FieldSelector: req.GetFieldSelector(),
+		Limit:         req.GetLimit(),
+		Continue:      req.GetContinue(),
+	}
+	if err := validateListOptions(opts); err != nil {
+		return nil, opts, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	clusterID := req.GetCluster()
+	if clusterID == "" {
+		clusterID = primaryClusterID
+	}
+	target, err := g.api.lookupCluster(clusterID)
+	if errors.Is(err, errKubeClientUnavailable) {
+		return nil, opts, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		return nil, opts, status.Errorf(codes.NotFound, "cluster %s not found", clusterID)
+	}
+	return target, opts, nil
+}
+
+// listMeta converts Kubernetes list metadata into the response metadata
+func listMeta(clusterID, source string, meta metav1.ListMeta) *controllerv1.ListMeta {
+	out := &controllerv1.ListMeta{
+		Cluster:         clusterID,
+		Source:          source,
+		ResourceVersion: meta.ResourceVersion,
+		Continue:        meta.Continue,
+	}
+	if meta.RemainingItemCount != nil {
+		out.RemainingItemCount = *meta.RemainingItemCount
+	}
+	return out
+}
+
+// ListClusters returns the clusters registered with the multi-cluster manager
+func (g *grpcServer) ListClusters(ctx context.Context, _ *controllerv1.ListClustersRequest) (*controllerv1.ListClustersResponse, error) {
+	if g.api.multiClusterManager == nil {
+		return nil, status.Error(codes.Unavailable, "multi-cluster functionality is disabled because informer is disabled")
+	}
+
+	resp := &controllerv1.ListClustersResponse{}
+	for _, cfg := range g.api.multiClusterManager.GetClusters() {
+		resp.Clusters = append(resp.Clusters, &controllerv1.Cluster{
+			ClusterId:   cfg.ClusterID,
+			Name:        cfg.Name,
+			Kubeconfig:  cfg.KubeConfig,
+			Context:     cfg.Context,
+			InCluster:   cfg.InCluster,
+			Namespace:   cfg.Namespace,
+			ApiEndpoint: cfg.APIEndpoint,
+		})
+	}
+	sort.Slice(resp.Clusters, func(i, j int) bool { return resp.Clusters[i].ClusterId < resp.Clusters[j].ClusterId })
+	return resp, nil
+}
+
+// AddCluster registers a cluster with the multi-cluster manager
+func (g *grpcServer) AddCluster(ctx context.Context, req *controllerv1.AddClusterRequest) (*controllerv1.AddClusterResponse, error) {
+	if g.api.multiClusterManager == nil {
+		return nil, status.Error(codes.Unavailable, "multi-cluster functionality is disabled because informer is disabled")
+	}
+	cluster := req.GetCluster()
+	if cluster.GetClusterId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "cluster.cluster_id is required")
+	}
+
+	if _, err := g.api.multiClusterManager.GetClientset(cluster.GetClusterId()); err == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "cluster %s already exists", cluster.GetClusterId())
+	}
+
+	// Validate like POST /clusters before adding the cluster
+	cfg := ctrl.ClusterConfig{
+		ClusterID:   cluster.GetClusterId(),
+		Name:        cluster.GetName(),
+		KubeConfig:  cluster.GetKubeconfig(),
+		Context:     cluster.GetContext(),
+		InCluster:   cluster.GetInCluster(),
+		Namespace:   cluster.GetNamespace(),
+		APIEndpoint: cluster.GetApiEndpoint(),
+	}
+	if err := ctrl.ValidateClusterConfig(ctx, cfg); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err := g.api.multiClusterManager.AddCluster(ctx, cfg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to add cluster: %v", err)
+	}
+
+	zerolog.Ctx(ctx).Info().Str("cluster_id", cluster.GetClusterId()).Msg("Added new cluster to manager")
+	return &controllerv1.AddClusterResponse{ClusterId: cluster.GetClusterId()}, nil
+}
+
+// RemoveCluster unregisters a cluster from the multi-cluster manager
+func (g *grpcServer) RemoveCluster(ctx context.Context, req *controllerv1.RemoveClusterRequest) (*controllerv1.RemoveClusterResponse, error) {
+	if g.api.multiClusterManager == nil {
+		return nil, status.Error(codes.Unavailable, "multi-cluster functionality is disabled because informer is disabled")
+	}
+	if req.GetClusterId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "cluster_id is required")
+	}
+
+	// Drain like DELETE /clusters; the request has no force option
+	drainCtx, cancel := context.WithTimeout(ctx, g.api.clusterDrainTimeout())
+	defer cancel()
+	if _, err := g.api.multiClusterManager.RemoveClusterGracefully(drainCtx, req.GetClusterId(), ctrl.RemoveOptions{}); err != nil {
+		if errors.Is(err, ctrl.ErrDrainTimeout) {
+			return nil, status.Error(codes.DeadlineExceeded, err.Error())
+		}
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	zerolog.Ctx(ctx).Info().Str("cluster_id", req.GetClusterId()).Msg("Removed cluster from manager")
+	return &controllerv1.RemoveClusterResponse{ClusterId: req.GetClusterId()}, nil
+}
+
+// ListDeployments serves unpaged requests from the cluster's cache and falls back to the
+// API, like GET /deployments
+func (g *grpcServer) ListDeployments(ctx context.Context, req *controllerv1.ListRequest) (*controllerv1.ListDeploymentsResponse, error) {
+	target, opts, err := g.resolveTarget(req)
+	if err != nil {
+		return nil, err
+	}
+	namespace := req.GetNamespace()
+
+	var deployments []*appsv1.Deployment
+	source := "informer-cache"
+	var meta metav1.ListMeta
+	if !isPaginated(opts) {
+		deployments, err = g.api.cachedDeployments(target, namespace, opts)
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Str("namespace", namespace).Msg("Failed to list deployments from cache, falling back to direct API")
+			deployments = nil
+		}
+	}
+
+	if len(deployments) == 0 {
+		source = "direct-api"
+		list, err := target.Client.AppsV1().Deployments(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, grpcError(err, "failed to list deployments")
+		}
+		for i := range list.Items {
+			deployments = append(deployments, &list.Items[i])
+		}
+		meta = list.ListMeta
+	}
+
+	resp := &controllerv1.ListDeploymentsResponse{Metadata: listMeta(target.ID, source, meta)}
+	for _, d := range deployments {
+		item := &controllerv1.Deployment{
+			Name:              d.Name,
+			Namespace:         d.Namespace,
+			Labels:            d.Labels,
+			Replicas:          d.Status.Replicas,
+			ReadyReplicas:     d.Status.ReadyReplicas,
+			AvailableReplicas: d.Status.AvailableReplicas,
+			CreatedUnix:       d.CreationTimestamp.Unix(),
+		}
+		if d.Spec.Replicas != nil {
+			item.DesiredReplicas = *d.Spec.Replicas
+		}
+		for _, c := range d.Spec.Template.Spec.Containers {
+			item.Images = append(item.Images, c.Image)
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	return resp, nil
+}
+
+// ListPods lists pods from the Kubernetes API, like GET /pods
+func (g *grpcServer) ListPods(ctx context.Context, req *controllerv1.ListRequest) (*controllerv1.ListPodsResponse, error) {
+	target, opts, err := g.resolveTarget(req)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := target.Client.CoreV1().Pods(req.GetNamespace()).List(ctx, opts)
+	if err != nil {
+		return nil, grpcError(err, "failed to list pods")
+	}
+
+	resp := &controllerv1.ListPodsResponse{Metadata: listMeta(target.ID, "kubernetes-api", pods.ListMeta)}
+	for _, pod := range pods.Items {
+		resp.Items = append(resp.Items, &controllerv1.Pod{
+			Name:        pod.Name,
+			Namespace:   pod.Namespace,
+			Labels:      pod.Labels,
+			Phase:       string(pod.Status.Phase),
+			Node:        pod.Spec.NodeName,
+			Ip:          pod.Status.PodIP,
+			CreatedUnix: pod.CreationTimestamp.Unix(),
+		})
+	}
+	return resp, nil
+}
+
+// ListNodes lists nodes from the Kubernetes API, like GET /nodes
+func (g *grpcServer) ListNodes(ctx context.Context, req *controllerv1.ListRequest) (*controllerv1.ListNodesResponse, error) {
+	target, opts, err := g.resolveTarget(req)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := target.Client.CoreV1().Nodes().List(ctx, opts)
+	if err != nil {
+		return nil, grpcError(err, "failed to list nodes")
+	}
+
+	resp := &controllerv1.ListNodesResponse{Metadata: listMeta(target.ID, "kubernetes-api", nodes.ListMeta)}
+	for _, node := range nodes.Items {
+		item := &controllerv1.Node{
+			Name:           node.Name,
+			Labels:         node.Labels,
+			Addresses:      make(map[string]string, len(node.Status.Addresses)),
+			Capacity:       make(map[string]string, len(node.Status.Capacity)),
+			KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+			CreatedUnix:    node.CreationTimestamp.Unix(),
+		}
+		for _, addr := range node.Status.Addresses {
+			item.Addresses[string(addr.Type)] = addr.Address
+		}
+		for name, quantity := range node.Status.Capacity {
+			item.Capacity[string(name)] = quantity.String()
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Status == "True" {
+				item.Conditions = append(item.Conditions, string(condition.Type))
+			}
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	return resp, nil
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/valyala/fasthttp"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewHandler returns the API server's request handler for a clientset, without informers,
+// controllers or background workers, so reads go straight to the clientset. Tests pair it
+// with fixture.NewClientset to run the real handlers against recorded cluster responses. A
+// nil appConfig applies the defaults.
+func NewHandler(clientset *kubernetes.Clientset, appConfig *Config) fasthttp.RequestHandler {
+	if appConfig == nil {
+		appConfig = defaultConfig()
+	}
+	s := &apiServer{clientset: clientset, config: appConfig}
+	return s.requestHandler
+}
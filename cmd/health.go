@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+)
+
+// healthPingTimeout bounds the version request to each cluster of a verbose health check
+const healthPingTimeout = 3 * time.Second
+
+// clusterHealth is the connectivity of one cluster, as reported by /health?verbose=true
+type clusterHealth struct {
+	ClusterID string `json:"cluster_id"`
+	Connected bool   `json:"connected"`
+	LatencyMs int64  `json:"latency_ms"`
+	Version   string `json:"version,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// serverVersion asks a cluster's API server for its version. Unlike
+// Discovery().ServerVersion, it honours the context's deadline.
+func serverVersion(ctx context.Context, client kubernetes.Interface) (*version.Info, error) {
+	body, err := client.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		return nil, err
+	}
+	var info version.Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// pingClusters requests the version of every known cluster concurrently, sorted by cluster ID
+func (s *apiServer) pingClusters(ctx context.Context) ([]clusterHealth, error) {
+	clients, err := s.inventoryClients("")
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make([]clusterHealth, 0, len(clients))
+	for clusterID, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+			defer cancel()
+
+			start := time.Now()
+			info, err := serverVersion(pingCtx, client)
+			result := clusterHealth{ClusterID: clusterID, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Connected = true
+				result.Version = info.GitVersion
+			}
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].ClusterID < results[j].ClusterID })
+	return results, nil
+}
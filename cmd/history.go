@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/history"
+)
+
+// defaultHistoryRange is the range of history requests without ?range
+const defaultHistoryRange = "24h"
+
+// collectHistory reads deployment replicas and node readiness from every known cluster.
+// Clusters that cannot be read are skipped so one unreachable cluster does not stop the others.
+func (s *apiServer) collectHistory(ctx context.Context) ([]history.Sample, error) {
+	clients, err := s.inventoryClients("")
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []history.Sample
+	for clusterID, client := range clients {
+		clusterSamples, err := clusterHistory(ctx, clusterID, client)
+		if err != nil {
+			log.Warn().Err(err).Str("cluster_id", clusterID).Msg("Failed to collect cluster history")
+			continue
+		}
+		samples = append(samples, clusterSamples...)
+	}
+	return samples, nil
+}
+
+func clusterHistory(ctx context.Context, clusterID string, client kubernetes.Interface) ([]history.Sample, error) {
+	deployments, err := client.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]history.Sample, 0, len(deployments.Items)+len(nodes.Items))
+	for _, d := range deployments.Items {
+		desired := int32(1)
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		samples = append(samples, history.Sample{
+			Key:    history.Key{Kind: history.KindDeployment, Cluster: clusterID, Namespace: d.Namespace, Name: d.Name},
+			Values: []float64{float64(desired), float64(d.Status.ReadyReplicas), float64(d.Status.AvailableReplicas), float64(d.Status.UpdatedReplicas)},
+		})
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		samples = append(samples, history.Sample{
+			Key:    history.Key{Kind: history.KindNode, Cluster: clusterID, Name: node.Name},
+			Values: []float64{boolValue(nodeReady(node)), boolValue(node.Spec.Unschedulable)},
+		})
+	}
+	return samples, nil
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// @Summary Get deployment replica history
+// @Description Returns the desired, ready, available and updated replicas of a deployment over a range such as 7d, from samples kept by the API server. Raw samples are returned for ranges up to a day, 10-minute averages up to a week and hourly averages beyond.
+// @Tags history
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Param range query string false "Range such as 7d, 12h or 30m" default(24h)
+// @Param cluster query string false "Cluster ID, the primary cluster by default"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /history/deployments/{namespace}/{name} [get]
+func (s *apiServer) handleDeploymentHistory(ctx *fasthttp.RequestCtx) {
+	s.serveHistory(ctx, history.Key{Kind: history.KindDeployment, Namespace: pathParam(ctx, "namespace"), Name: pathParam(ctx, "name")})
+}
+
+// @Summary Get node readiness history
+// @Description Returns whether a node was ready and unschedulable over a range such as 7d, as the share of the time once averaged. Raw samples are returned for ranges up to a day, 10-minute averages up to a week and hourly averages beyond.
+// @Tags history
+// @Produce json
+// @Param name path string true "Node name"
+// @Param range query string false "Range such as 7d, 12h or 30m" default(24h)
+// @Param cluster query string false "Cluster ID, the primary cluster by default"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /history/nodes/{name} [get]
+func (s *apiServer) handleNodeHistory(ctx *fasthttp.RequestCtx) {
+	s.serveHistory(ctx, history.Key{Kind: history.KindNode, Name: pathParam(ctx, "name")})
+}
+
+// serveHistory writes the points of a series with their values named by the kind's fields
+func (s *apiServer) serveHistory(ctx *fasthttp.RequestCtx, key history.Key) {
+	if s.history == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "History is disabled"})
+		return
+	}
+
+	rangeParam := string(ctx.QueryArgs().Peek("range"))
+	if rangeParam == "" {
+		rangeParam = defaultHistoryRange
+	}
+	window, err := history.ParseRange(rangeParam)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	key.Cluster = string(ctx.QueryArgs().Peek("cluster"))
+	if key.Cluster == "" {
+		key.Cluster = primaryClusterID
+	}
+
+	now := time.Now()
+	result, ok := s.history.Query(key, now.Add(-window), now)
+	if !ok {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("No history for %s %s in cluster %s", key.Kind, key.Name, key.Cluster)})
+		return
+	}
+
+	fields := history.Fields[key.Kind]
+	points := make([]map[string]interface{}, 0, len(result.Points))
+	for _, p := range result.Points {
+		point := map[string]interface{}{"time": p.Time}
+		for i, field := range fields {
+			if i < len(p.Values) {
+				point[field] = p.Values[i]
+			}
+		}
+		points = append(points, point)
+	}
+	response := map[string]interface{}{
+		"cluster": key.Cluster,
+		"name":    key.Name,
+		"range":   rangeParam,
+		"step":    result.Step.String(),
+		"fields":  fields,
+		"points":  points,
+	}
+	if key.Namespace != "" {
+		response["namespace"] = key.Namespace
+	}
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/auth"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/hooksig"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/lockout"
+)
+
+// hookSecretLoader reads inbound webhook secrets from the primary cluster
+func hookSecretLoader(clientset kubernetes.Interface) hooksig.SecretLoader {
+	return func(ctx context.Context, namespace, name, key string) (string, error) {
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		value, ok := secret.Data[key]
+		if !ok {
+			return "", fmt.Errorf("secret %s/%s has no key %s", namespace, name, key)
+		}
+		return string(value), nil
+	}
+}
+
+// checkInboundHook verifies requests to configured inbound webhook paths. Senders such as
+// GitHub cannot present API credentials, so a verified request is attributed to the hook
+// and skips CSRF and API authentication. matched reports whether the path is a hook; ok is
+// false when the response has been written and the request must stop.
+func (s *apiServer) checkInboundHook(ctx *fasthttp.RequestCtx, path string, logger zerolog.Logger) (matched, ok bool) {
+	hook, matched := s.hookVerifier.Match(path)
+	if !matched {
+		return false, true
+	}
+
+	clientIP := ctx.RemoteIP().String()
+	if s.lockout != nil && s.rejectLockedOut(ctx, lockout.KindIP, clientIP, logger) {
+		return true, false
+	}
+
+	header := func(name string) string { return string(ctx.Request.Header.Peek(name)) }
+	err := s.hookVerifier.Verify(context.Background(), hook, header, ctx.PostBody())
+	switch {
+	case err == nil:
+		ctx.SetUserValue(principalUserValueKey, &auth.Principal{Name: "hook:" + hook.Name, Source: "webhook"})
+		return true, true
+	case errors.Is(err, hooksig.ErrMissingSignature):
+		// Like missing credentials, a missing signature does not count towards a lockout
+		logger.Warn().Str("hook", hook.Name).Str("provider", hook.Provider).Str("client", clientIP).Msg("Inbound webhook without signature")
+		ctx.SetStatusCode(fasthttp.StatusUnauthorized)
+		ctx.SetBodyString(`{"error": "Missing webhook signature"}`)
+	case errors.Is(err, hooksig.ErrInvalidSignature):
+		logger.Warn().Str("hook", hook.Name).Str("provider", hook.Provider).Str("client", clientIP).Msg("Inbound webhook signature is invalid")
+		s.recordAuthFailure(lockout.EventAuthFailure, lockout.KindIP, clientIP, "invalid signature for webhook "+hook.Name, logger)
+		ctx.SetStatusCode(fasthttp.StatusUnauthorized)
+		ctx.SetBodyString(`{"error": "Invalid webhook signature"}`)
+	default:
+		logger.Error().Err(err).Str("hook", hook.Name).Msg("Failed to verify inbound webhook")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		ctx.SetBodyString(`{"error": "Failed to verify webhook"}`)
+	}
+	return true, false
+}
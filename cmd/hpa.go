@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// hpaMetric is one metric an HPA scales on with its target and latest observed value
+type hpaMetric struct {
+	Type       autoscalingv2.MetricSourceType `json:"type"`
+	Name       string                         `json:"name"`             // Resource, container/resource or metric name
+	Object     string                         `json:"object,omitempty"` // Kind/name described by Object metrics
+	TargetType autoscalingv2.MetricTargetType `json:"target_type"`
+	Target     string                         `json:"target"`            // e.g. 70% or 500m
+	Current    string                         `json:"current,omitempty"` // Omitted until the metric has been read
+}
+
+// hpaCondition is a scaling condition such as AbleToScale, ScalingActive or ScalingLimited
+type hpaCondition struct {
+	Type               autoscalingv2.HorizontalPodAutoscalerConditionType `json:"type"`
+	Status             corev1.ConditionStatus                             `json:"status"`
+	Reason             string                                             `json:"reason,omitempty"`
+	Message            string                                             `json:"message,omitempty"`
+	LastTransitionTime metav1.Time                                        `json:"last_transition_time"`
+}
+
+// hpaSummary describes an HPA's replica bounds, current state, metrics and conditions
+type hpaSummary struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	UID               string            `json:"uid"`
+	ResourceVersion   string            `json:"resource_version"`
+	CreationTimestamp metav1.Time       `json:"creation_timestamp"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	ScaleTarget       string            `json:"scale_target"` // Kind/name of the scaled workload
+	MinReplicas       int32             `json:"min_replicas"`
+	MaxReplicas       int32             `json:"max_replicas"`
+	CurrentReplicas   int32             `json:"current_replicas"`
+	DesiredReplicas   int32             `json:"desired_replicas"`
+	LastScaleTime     *metav1.Time      `json:"last_scale_time,omitempty"`
+	Limited           bool              `json:"limited"` // ScalingLimited: desired replicas were capped by min or max
+	Metrics           []hpaMetric       `json:"metrics"`
+	Conditions        []hpaCondition    `json:"conditions"`
+}
+
+// newHPASummary flattens an HPA's spec and status, pairing each metric with its current value
+func newHPASummary(hpa *autoscalingv2.HorizontalPodAutoscaler) hpaSummary {
+	summary := hpaSummary{
+		Name:              hpa.Name,
+		Namespace:         hpa.Namespace,
+		UID:               string(hpa.UID),
+		ResourceVersion:   hpa.ResourceVersion,
+		CreationTimestamp: hpa.CreationTimestamp,
+		Labels:            hpa.Labels,
+		ScaleTarget:       hpa.Spec.ScaleTargetRef.Kind + "/" + hpa.Spec.ScaleTargetRef.Name,
+		MinReplicas:       1,
+		MaxReplicas:       hpa.Spec.MaxReplicas,
+		CurrentReplicas:   hpa.Status.CurrentReplicas,
+		DesiredReplicas:   hpa.Status.DesiredReplicas,
+		LastScaleTime:     hpa.Status.LastScaleTime,
+		Metrics:           make([]hpaMetric, 0, len(hpa.Spec.Metrics)),
+		Conditions:        make([]hpaCondition, 0, len(hpa.Status.Conditions)),
+	}
+	if hpa.Spec.MinReplicas != nil {
+		summary.MinReplicas = *hpa.Spec.MinReplicas
+	}
+
+	current := make(map[string]string, len(hpa.Status.CurrentMetrics))
+	for _, status := range hpa.Status.CurrentMetrics {
+		name, object, value := hpaMetricStatus(status)
+		current[string(status.Type)+"/"+object+"/"+name] = value
+	}
+	for _, spec := range hpa.Spec.Metrics {
+		metric := hpaMetricSpec(spec)
+		metric.Current = current[string(metric.Type)+"/"+metric.Object+"/"+metric.Name]
+		summary.Metrics = append(summary.Metrics, metric)
+	}
+
+	for _, c := range hpa.Status.Conditions {
+		summary.Conditions = append(summary.Conditions, hpaCondition{
+			Type:               c.Type,
+			Status:             c.Status,
+			Reason:             c.Reason,
+			Message:            c.Message,
+			LastTransitionTime: c.LastTransitionTime,
+		})
+		if c.Type == autoscalingv2.ScalingLimited && c.Status == corev1.ConditionTrue {
+			summary.Limited = true
+		}
+	}
+	return summary
+}
+
+// hpaMetricSpec describes a metric's source and target
+func hpaMetricSpec(spec autoscalingv2.MetricSpec) hpaMetric {
+	metric := hpaMetric{Type: spec.Type}
+	var target autoscalingv2.MetricTarget
+	switch {
+	case spec.Resource != nil:
+		metric.Name, target = string(spec.Resource.Name), spec.Resource.Target
+	case spec.ContainerResource != nil:
+		metric.Name, target = spec.ContainerResource.Container+"/"+string(spec.ContainerResource.Name), spec.ContainerResource.Target
+	case spec.Pods != nil:
+		metric.Name, target = spec.Pods.Metric.Name, spec.Pods.Target
+	case spec.Object != nil:
+		metric.Name, target = spec.Object.Metric.Name, spec.Object.Target
+		metric.Object = spec.Object.DescribedObject.Kind + "/" + spec.Object.DescribedObject.Name
+	case spec.External != nil:
+		metric.Name, target = spec.External.Metric.Name, spec.External.Target
+	}
+	metric.TargetType = target.Type
+	metric.Target = formatMetricValue(target.AverageUtilization, target.AverageValue, target.Value)
+	return metric
+}
+
+// hpaMetricStatus returns the name, described object and formatted value of a current metric
+func hpaMetricStatus(status autoscalingv2.MetricStatus) (name, object, value string) {
+	var current autoscalingv2.MetricValueStatus
+	switch {
+	case status.Resource != nil:
+		name, current = string(status.Resource.Name), status.Resource.Current
+	case status.ContainerResource != nil:
+		name, current = status.ContainerResource.Container+"/"+string(status.ContainerResource.Name), status.ContainerResource.Current
+	case status.Pods != nil:
+		name, current = status.Pods.Metric.Name, status.Pods.Current
+	case status.Object != nil:
+		name, current = status.Object.Metric.Name, status.Object.Current
+		object = status.Object.DescribedObject.Kind + "/" + status.Object.DescribedObject.Name
+	case status.External != nil:
+		name, current = status.External.Metric.Name, status.External.Current
+	}
+	return name, object, formatMetricValue(current.AverageUtilization, current.AverageValue, current.Value)
+}
+
+// formatMetricValue renders whichever of utilization, average value or value is set
+func formatMetricValue(utilization *int32, averageValue, value *resource.Quantity) string {
+	switch {
+	case utilization != nil:
+		return strconv.Itoa(int(*utilization)) + "%"
+	case averageValue != nil:
+		return averageValue.String()
+	case value != nil:
+		return value.String()
+	}
+	return ""
+}
+
+// @Summary List HorizontalPodAutoscalers
+// @Description Lists HPAs with their scale target, replica bounds, current and desired replicas, last scale time, each metric's target and current value, and scaling conditions. limited is set while ScalingLimited is true, meaning the desired replica count was capped by the bounds.
+// @Tags kubernetes,autoscaling
+// @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param namespace query string false "Namespace, all namespaces when empty"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=web"
+// @Param fieldSelector query string false "Field selector, e.g. metadata.name=web"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /hpa [get]
+func (s *apiServer) handleHPAs(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	namespace := getNamespaceFromQuery(ctx)
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	hpas, err := target.Client.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(requestContext(ctx), limitedOptions(ctx, listOpts))
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Msg("Failed to list horizontal pod autoscalers")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to list horizontal pod autoscalers"})
+		return
+	}
+
+	pageMetadata := paginationMetadata(ctx, listOpts, hpas.ListMeta, len(hpas.Items))
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(hpas.Items))
+		for i := range hpas.Items {
+			objects = append(objects, &hpas.Items[i])
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ID, objects)) {
+			return
+		}
+	}
+
+	names := make([]string, 0, len(hpas.Items))
+	items := make([]hpaSummary, 0, len(hpas.Items))
+	for i := range hpas.Items {
+		names = append(names, hpas.Items[i].Name)
+		items = append(items, newHPASummary(&hpas.Items[i]))
+	}
+	logger.Info().Int("count", len(items)).Str("namespace", namespace).Msg("Horizontal pod autoscalers retrieved")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if isSimpleFormat(ctx) {
+		writeSimpleJsonArray(ctx, names)
+		return
+	}
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster":   target.ID,
+		"namespace": namespace,
+		"count":     len(items),
+		"source":    "kubernetes-api",
+		"names":     names,
+		"items":     items,
+		"metadata":  pageMetadata,
+	})
+}
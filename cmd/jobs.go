@@ -0,0 +1,504 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotations of Jobs created by POST /cronjobs/{namespace}/{name}/trigger
+const (
+	cronJobInstantiateAnnotation = "cronjob.kubernetes.io/instantiate" // Set to manual, as kubectl create job --from does
+	jobTriggeredByAnnotation     = "jobs.k8s-custom-controller.io/triggered-by"
+)
+
+// Job statuses derived from conditions and pod counts
+const (
+	jobStatusComplete  = "complete"
+	jobStatusFailed    = "failed"
+	jobStatusSuspended = "suspended"
+	jobStatusRunning   = "running"
+	jobStatusPending   = "pending"
+)
+
+// jobSummary describes a Job's progress
+type jobSummary struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	UID               string            `json:"uid"`
+	ResourceVersion   string            `json:"resource_version"`
+	CreationTimestamp metav1.Time       `json:"creation_timestamp"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	CronJob           string            `json:"cronjob,omitempty"` // Owning CronJob
+	Status            string            `json:"status"`
+	Completions       int32             `json:"completions"` // Successful pods required
+	Parallelism       int32             `json:"parallelism"`
+	Active            int32             `json:"active"`
+	Succeeded         int32             `json:"succeeded"`
+	Failed            int32             `json:"failed"`
+	StartTime         *metav1.Time      `json:"start_time,omitempty"`
+	CompletionTime    *metav1.Time      `json:"completion_time,omitempty"`
+	DurationSeconds   *float64          `json:"duration_seconds,omitempty"` // Until completion, or until now while running
+}
+
+// cronJobSummary describes a CronJob's schedule and its latest runs
+type cronJobSummary struct {
+	Name               string                    `json:"name"`
+	Namespace          string                    `json:"namespace"`
+	UID                string                    `json:"uid"`
+	ResourceVersion    string                    `json:"resource_version"`
+	CreationTimestamp  metav1.Time               `json:"creation_timestamp"`
+	Labels             map[string]string         `json:"labels,omitempty"`
+	Schedule           string                    `json:"schedule"`
+	TimeZone           string                    `json:"time_zone,omitempty"`
+	Suspend            bool                      `json:"suspend"`
+	ConcurrencyPolicy  batchv1.ConcurrencyPolicy `json:"concurrency_policy"`
+	Active             []string                  `json:"active"` // Names of running Jobs
+	LastScheduleTime   *metav1.Time              `json:"last_schedule_time,omitempty"`
+	LastSuccessfulTime *metav1.Time              `json:"last_successful_time,omitempty"`
+}
+
+// jobDetail is the response of GET /jobs/{namespace}/{name} and of a trigger
+type jobDetail struct {
+	Cluster string `json:"cluster"`
+	jobSummary
+}
+
+// cronJobDetail is the response of GET /cronjobs/{namespace}/{name}
+type cronJobDetail struct {
+	Cluster string `json:"cluster"`
+	cronJobSummary
+}
+
+// newJobSummary reports a Job's counts, derived status and duration at now
+func newJobSummary(job *batchv1.Job, now time.Time) jobSummary {
+	summary := jobSummary{
+		Name:              job.Name,
+		Namespace:         job.Namespace,
+		UID:               string(job.UID),
+		ResourceVersion:   job.ResourceVersion,
+		CreationTimestamp: job.CreationTimestamp,
+		Labels:            job.Labels,
+		Status:            jobStatus(job),
+		Completions:       1,
+		Parallelism:       1,
+		Active:            job.Status.Active,
+		Succeeded:         job.Status.Succeeded,
+		Failed:            job.Status.Failed,
+		StartTime:         job.Status.StartTime,
+		CompletionTime:    job.Status.CompletionTime,
+	}
+	if job.Spec.Completions != nil {
+		summary.Completions = *job.Spec.Completions
+	}
+	if job.Spec.Parallelism != nil {
+		summary.Parallelism = *job.Spec.Parallelism
+	}
+	if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == "CronJob" {
+		summary.CronJob = owner.Name
+	}
+	if job.Status.StartTime != nil {
+		end := now
+		if job.Status.CompletionTime != nil {
+			end = job.Status.CompletionTime.Time
+		} else if summary.Status == jobStatusFailed {
+			end = jobConditionTime(job, batchv1.JobFailed, now)
+		}
+		duration := math.Round(end.Sub(job.Status.StartTime.Time).Seconds()*10) / 10
+		summary.DurationSeconds = &duration
+	}
+	return summary
+}
+
+// jobStatus derives a single status from a Job's conditions and active pods
+func jobStatus(job *batchv1.Job) string {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return jobStatusComplete
+		case batchv1.JobFailed:
+			return jobStatusFailed
+		case batchv1.JobSuspended:
+			return jobStatusSuspended
+		}
+	}
+	if job.Status.Active > 0 {
+		return jobStatusRunning
+	}
+	return jobStatusPending
+}
+
+// jobConditionTime returns when a Job's condition last changed, or fallback when unset
+func jobConditionTime(job *batchv1.Job, conditionType batchv1.JobConditionType, fallback time.Time) time.Time {
+	for _, c := range job.Status.Conditions {
+		if c.Type == conditionType && !c.LastTransitionTime.IsZero() {
+			return c.LastTransitionTime.Time
+		}
+	}
+	return fallback
+}
+
+// newCronJobSummary copies a CronJob's schedule settings and latest runs
+func newCronJobSummary(cronJob *batchv1.CronJob) cronJobSummary {
+	summary := cronJobSummary{
+		Name:               cronJob.Name,
+		Namespace:          cronJob.Namespace,
+		UID:                string(cronJob.UID),
+		ResourceVersion:    cronJob.ResourceVersion,
+		CreationTimestamp:  cronJob.CreationTimestamp,
+		Labels:             cronJob.Labels,
+		Schedule:           cronJob.Spec.Schedule,
+		Suspend:            cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend,
+		ConcurrencyPolicy:  cronJob.Spec.ConcurrencyPolicy,
+		Active:             make([]string, 0, len(cronJob.Status.Active)),
+		LastScheduleTime:   cronJob.Status.LastScheduleTime,
+		LastSuccessfulTime: cronJob.Status.LastSuccessfulTime,
+	}
+	if cronJob.Spec.TimeZone != nil {
+		summary.TimeZone = *cronJob.Spec.TimeZone
+	}
+	for _, ref := range cronJob.Status.Active {
+		summary.Active = append(summary.Active, ref.Name)
+	}
+	return summary
+}
+
+// manualJobName names a manually triggered Job after its CronJob and the trigger time,
+// shortening the CronJob part so the name stays a valid label value
+func manualJobName(cronJob string, now time.Time) string {
+	suffix := "-manual-" + strconv.FormatInt(now.Unix(), 10)
+	if limit := 63 - len(suffix); len(cronJob) > limit {
+		cronJob = cronJob[:limit]
+	}
+	return cronJob + suffix
+}
+
+// newManualJob builds a Job from a CronJob's template the way kubectl create job --from does
+func newManualJob(cronJob *batchv1.CronJob, name, triggeredBy string) *batchv1.Job {
+	annotations := map[string]string{cronJobInstantiateAnnotation: "manual"}
+	for k, v := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+	if triggeredBy != "" {
+		annotations[jobTriggeredByAnnotation] = triggeredBy
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   cronJob.Namespace,
+			Labels:      cronJob.Spec.JobTemplate.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: cronJob.Spec.JobTemplate.Spec,
+	}
+}
+
+// @Summary List Jobs
+// @Description Lists Jobs with their status, required completions, active, succeeded and failed pods, start and completion times and duration.
+// @Tags kubernetes,jobs
+// @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param namespace query string false "Namespace, all namespaces when empty"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=report"
+// @Param fieldSelector query string false "Field selector, e.g. status.successful=1"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /jobs [get]
+func (s *apiServer) handleJobs(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	namespace := getNamespaceFromQuery(ctx)
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	jobs, err := target.Client.BatchV1().Jobs(namespace).List(requestContext(ctx), limitedOptions(ctx, listOpts))
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Msg("Failed to list jobs")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to list jobs"})
+		return
+	}
+
+	pageMetadata := paginationMetadata(ctx, listOpts, jobs.ListMeta, len(jobs.Items))
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(jobs.Items))
+		for i := range jobs.Items {
+			objects = append(objects, &jobs.Items[i])
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ID, objects)) {
+			return
+		}
+	}
+
+	now := time.Now()
+	names := make([]string, 0, len(jobs.Items))
+	items := make([]jobSummary, 0, len(jobs.Items))
+	for i := range jobs.Items {
+		names = append(names, jobs.Items[i].Name)
+		items = append(items, newJobSummary(&jobs.Items[i], now))
+	}
+	logger.Info().Int("count", len(items)).Str("namespace", namespace).Msg("Jobs retrieved")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if isSimpleFormat(ctx) {
+		writeSimpleJsonArray(ctx, names)
+		return
+	}
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster":   target.ID,
+		"namespace": namespace,
+		"count":     len(items),
+		"source":    "kubernetes-api",
+		"names":     names,
+		"items":     items,
+		"metadata":  pageMetadata,
+	})
+}
+
+// @Summary Get a Job
+// @Description Returns a Job's status, pod counts, start and completion times and duration.
+// @Tags kubernetes,jobs
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Job name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {object} jobDetail
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /jobs/{namespace}/{name} [get]
+func (s *apiServer) handleJobDetail(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	job, err := target.Client.BatchV1().Jobs(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Job %s/%s not found", namespace, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get job")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get job"})
+		return
+	}
+
+	if writeNotModified(ctx, listETag(ctx, target.ID, []metav1.Object{job})) {
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(jobDetail{Cluster: target.ID, jobSummary: newJobSummary(job, time.Now())})
+}
+
+// @Summary List CronJobs
+// @Description Lists CronJobs with their schedule, time zone, suspend flag, concurrency policy, running Jobs and last schedule and success times.
+// @Tags kubernetes,jobs
+// @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param namespace query string false "Namespace, all namespaces when empty"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=report"
+// @Param fieldSelector query string false "Field selector, e.g. metadata.name=nightly"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cronjobs [get]
+func (s *apiServer) handleCronJobs(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	namespace := getNamespaceFromQuery(ctx)
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	cronJobs, err := target.Client.BatchV1().CronJobs(namespace).List(requestContext(ctx), limitedOptions(ctx, listOpts))
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Msg("Failed to list cronjobs")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to list cronjobs"})
+		return
+	}
+
+	pageMetadata := paginationMetadata(ctx, listOpts, cronJobs.ListMeta, len(cronJobs.Items))
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(cronJobs.Items))
+		for i := range cronJobs.Items {
+			objects = append(objects, &cronJobs.Items[i])
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ID, objects)) {
+			return
+		}
+	}
+
+	names := make([]string, 0, len(cronJobs.Items))
+	items := make([]cronJobSummary, 0, len(cronJobs.Items))
+	for i := range cronJobs.Items {
+		names = append(names, cronJobs.Items[i].Name)
+		items = append(items, newCronJobSummary(&cronJobs.Items[i]))
+	}
+	logger.Info().Int("count", len(items)).Str("namespace", namespace).Msg("CronJobs retrieved")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if isSimpleFormat(ctx) {
+		writeSimpleJsonArray(ctx, names)
+		return
+	}
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster":   target.ID,
+		"namespace": namespace,
+		"count":     len(items),
+		"source":    "kubernetes-api",
+		"names":     names,
+		"items":     items,
+		"metadata":  pageMetadata,
+	})
+}
+
+// @Summary Get a CronJob
+// @Description Returns a CronJob's schedule, suspend flag, running Jobs and last schedule and success times.
+// @Tags kubernetes,jobs
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "CronJob name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {object} cronJobDetail
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cronjobs/{namespace}/{name} [get]
+func (s *apiServer) handleCronJobDetail(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	cronJob, err := target.Client.BatchV1().CronJobs(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("CronJob %s/%s not found", namespace, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get cronjob")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get cronjob"})
+		return
+	}
+
+	if writeNotModified(ctx, listETag(ctx, target.ID, []metav1.Object{cronJob})) {
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(cronJobDetail{Cluster: target.ID, cronJobSummary: newCronJobSummary(cronJob)})
+}
+
+// @Summary Trigger a CronJob
+// @Description Creates a Job from the CronJob's template right away, like kubectl create job --from=cronjob/<name>. The Job is owned by the CronJob and annotated with the caller. Suspended CronJobs can be triggered too. Requires the editor role and the write:cronjobs scope.
+// @Tags kubernetes,jobs
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "CronJob name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 201 {object} jobDetail
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cronjobs/{namespace}/{name}/trigger [post]
+func (s *apiServer) handleCronJobTrigger(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	cronJob, err := target.Client.BatchV1().CronJobs(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("CronJob %s/%s not found", namespace, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get cronjob")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get cronjob"})
+		return
+	}
+
+	triggeredBy := ""
+	if principal := getPrincipal(ctx); principal != nil {
+		triggeredBy = principal.Name
+	}
+	now := time.Now()
+	job := newManualJob(cronJob, manualJobName(name, now), triggeredBy)
+	created, err := target.Client.BatchV1().Jobs(namespace).Create(requestContext(ctx), job, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		ctx.SetStatusCode(fasthttp.StatusConflict)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Job %s/%s already exists, retry in a second", namespace, job.Name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to trigger cronjob")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to trigger cronjob: %s", err)})
+		return
+	}
+
+	logger.Info().
+		Str("cluster_id", target.ID).
+		Str("namespace", namespace).
+		Str("cronjob", name).
+		Str("job", created.Name).
+		Str("triggered_by", triggeredBy).
+		Msg("Triggered cronjob")
+
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	json.NewEncoder(ctx).Encode(jobDetail{Cluster: target.ID, jobSummary: newJobSummary(created, now)})
+}
@@ -4,12 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -27,6 +27,13 @@ var (
 	image          string
 	replicas       int32
 	port           int32
+	manifestFile   string
+)
+
+// Variables for delete command
+var (
+	propagationPolicy string
+	gracePeriod       int64
 )
 
 // getKubeClient creates a Kubernetes clientset from the provided kubeconfig path
@@ -81,7 +88,7 @@ var listCmd = &cobra.Command{
 			available := fmt.Sprintf("%d", d.Status.AvailableReplicas)
 			age := "<unknown>"
 			if !d.CreationTimestamp.IsZero() {
-				age = fmt.Sprintf("%s", time.Since(d.CreationTimestamp.Time).Round(time.Second))
+				age = formatAge(d.CreationTimestamp.Time, time.Now())
 			}
 			
 			// Log detailed info
@@ -109,47 +116,49 @@ var createCmd = &cobra.Command{
 			return
 		}
 
-		// Create deployment object
-		deployment := &appsv1.Deployment{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: deploymentName,
-			},
-			Spec: appsv1.DeploymentSpec{
-				Replicas: &replicas,
-				Selector: &metav1.LabelSelector{
-					MatchLabels: map[string]string{
-						"app": deploymentName,
-					},
-				},
-				Template: corev1.PodTemplateSpec{
-					ObjectMeta: metav1.ObjectMeta{
-						Labels: map[string]string{
-							"app": deploymentName,
-						},
-					},
-					Spec: corev1.PodSpec{
-						Containers: []corev1.Container{
-							{
-								Name:  deploymentName,
-								Image: image,
-								Ports: []corev1.ContainerPort{
-									{
-										ContainerPort: port,
-									},
-								},
-							},
-						},
-					},
-				},
-			},
+		// Create deployment object from the manifest file or the flags
+		var deployment *appsv1.Deployment
+		if manifestFile != "" {
+			data, err := os.ReadFile(manifestFile)
+			if err != nil {
+				log.Error().Err(err).Str("file", manifestFile).Msg("Failed to read manifest")
+				return
+			}
+			if deployment, err = decodeDeployment(data); err != nil {
+				log.Error().Err(err).Str("file", manifestFile).Msg("Failed to parse manifest")
+				return
+			}
+		} else {
+			if deploymentName == "" || image == "" {
+				log.Error().Msg("Either --filename or both --name and --image are required")
+				return
+			}
+			deployment = newDeployment(DeploymentCreateRequest{
+				Name:     deploymentName,
+				Image:    image,
+				Replicas: replicas,
+				Port:     port,
+			})
+		}
+
+		// The manifest's namespace wins over --namespace
+		if deployment.Namespace == "" {
+			deployment.Namespace = namespace
 		}
 
 		// Create the deployment
-		logDeploymentAction("Creating", deploymentName, namespace, image, replicas, port)
+		var desired, containerPort int32
+		if deployment.Spec.Replicas != nil {
+			desired = *deployment.Spec.Replicas
+		}
+		if manifestFile == "" {
+			containerPort = port
+		}
+		logDeploymentAction("Creating", deployment.Name, deployment.Namespace, strings.Join(deploymentImages(deployment), ","), desired, containerPort)
 
-		result, err := clientset.AppsV1().Deployments(namespace).Create(context.Background(), deployment, metav1.CreateOptions{})
+		result, err := clientset.AppsV1().Deployments(deployment.Namespace).Create(context.Background(), deployment, metav1.CreateOptions{})
 		if err != nil {
-			log.Error().Err(err).Str("name", deploymentName).Str("namespace", namespace).Msg("Failed to create deployment")
+			log.Error().Err(err).Str("name", deployment.Name).Str("namespace", deployment.Namespace).Msg("Failed to create deployment")
 			return
 		}
 
@@ -167,6 +176,12 @@ var deleteCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		deploymentToDelete := args[0]
+		opts, err := newDeleteOptions(propagationPolicy, gracePeriod)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid delete options")
+			return
+		}
+
 		clientset, err := getKubeClientOrError(kubeconfig)
 		if err != nil {
 			return
@@ -178,7 +193,7 @@ var deleteCmd = &cobra.Command{
 		err = clientset.AppsV1().Deployments(namespace).Delete(
 			context.Background(),
 			deploymentToDelete,
-			metav1.DeleteOptions{},
+			opts,
 		)
 		
 		if err != nil {
@@ -246,8 +261,14 @@ func init() {
 	createCmd.Flags().StringVar(&image, "image", "", "Container image to use (required)")
 	createCmd.Flags().Int32Var(&replicas, "replicas", 1, "Number of replicas")
 	createCmd.Flags().Int32Var(&port, "port", 80, "Container port")
-	
-	// Mark required flags
-	createCmd.MarkFlagRequired("name")
-	createCmd.MarkFlagRequired("image")
+	createCmd.Flags().StringVarP(&manifestFile, "filename", "f", "", "Deployment manifest (JSON or YAML) to create instead of --name and --image")
+
+	// Either a manifest or a name and image
+	createCmd.MarkFlagsOneRequired("filename", "name")
+	createCmd.MarkFlagsMutuallyExclusive("filename", "name")
+	createCmd.MarkFlagsMutuallyExclusive("filename", "image")
+
+	// Flags specific to delete command
+	deleteCmd.Flags().StringVar(&propagationPolicy, "propagation-policy", "", "Foreground, Background or Orphan (default Background)")
+	deleteCmd.Flags().Int64Var(&gracePeriod, "grace-period", -1, "Pod termination grace period in seconds, 0 for immediate deletion, -1 for the pods' own")
 }
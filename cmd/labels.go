@@ -0,0 +1,428 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/relabel"
+)
+
+// Statuses of objects in a relabeling response
+const (
+	relabelChanged     = "changed"
+	relabelWouldChange = "would_change" // Dry run: the change passed API server validation and admission
+	relabelFailed      = "failed"
+)
+
+// relabelKind lists and patches one namespaced kind through the typed clients
+type relabelKind struct {
+	Kind  string
+	list  func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) ([]metav1.Object, error)
+	patch func(ctx context.Context, client kubernetes.Interface, namespace, name string, data []byte, opts metav1.PatchOptions) error
+}
+
+// relabelKinds are the resources POST /labels/apply can change, by plural name. Secrets are
+// left out so relabeling never reads their data.
+var relabelKinds = map[string]relabelKind{
+	"deployments": {"Deployment",
+		func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := c.AppsV1().Deployments(ns).List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return relabelObjects(list.Items), nil
+		},
+		func(ctx context.Context, c kubernetes.Interface, ns, name string, data []byte, opts metav1.PatchOptions) error {
+			_, err := c.AppsV1().Deployments(ns).Patch(ctx, name, types.MergePatchType, data, opts)
+			return err
+		}},
+	"statefulsets": {"StatefulSet",
+		func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := c.AppsV1().StatefulSets(ns).List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return relabelObjects(list.Items), nil
+		},
+		func(ctx context.Context, c kubernetes.Interface, ns, name string, data []byte, opts metav1.PatchOptions) error {
+			_, err := c.AppsV1().StatefulSets(ns).Patch(ctx, name, types.MergePatchType, data, opts)
+			return err
+		}},
+	"daemonsets": {"DaemonSet",
+		func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := c.AppsV1().DaemonSets(ns).List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return relabelObjects(list.Items), nil
+		},
+		func(ctx context.Context, c kubernetes.Interface, ns, name string, data []byte, opts metav1.PatchOptions) error {
+			_, err := c.AppsV1().DaemonSets(ns).Patch(ctx, name, types.MergePatchType, data, opts)
+			return err
+		}},
+	"jobs": {"Job",
+		func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := c.BatchV1().Jobs(ns).List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return relabelObjects(list.Items), nil
+		},
+		func(ctx context.Context, c kubernetes.Interface, ns, name string, data []byte, opts metav1.PatchOptions) error {
+			_, err := c.BatchV1().Jobs(ns).Patch(ctx, name, types.MergePatchType, data, opts)
+			return err
+		}},
+	"cronjobs": {"CronJob",
+		func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := c.BatchV1().CronJobs(ns).List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return relabelObjects(list.Items), nil
+		},
+		func(ctx context.Context, c kubernetes.Interface, ns, name string, data []byte, opts metav1.PatchOptions) error {
+			_, err := c.BatchV1().CronJobs(ns).Patch(ctx, name, types.MergePatchType, data, opts)
+			return err
+		}},
+	"pods": {"Pod",
+		func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := c.CoreV1().Pods(ns).List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return relabelObjects(list.Items), nil
+		},
+		func(ctx context.Context, c kubernetes.Interface, ns, name string, data []byte, opts metav1.PatchOptions) error {
+			_, err := c.CoreV1().Pods(ns).Patch(ctx, name, types.MergePatchType, data, opts)
+			return err
+		}},
+	"services": {"Service",
+		func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := c.CoreV1().Services(ns).List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return relabelObjects(list.Items), nil
+		},
+		func(ctx context.Context, c kubernetes.Interface, ns, name string, data []byte, opts metav1.PatchOptions) error {
+			_, err := c.CoreV1().Services(ns).Patch(ctx, name, types.MergePatchType, data, opts)
+			return err
+		}},
+	"configmaps": {"ConfigMap",
+		func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := c.CoreV1().ConfigMaps(ns).List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return relabelObjects(list.Items), nil
+		},
+		func(ctx context.Context, c kubernetes.Interface, ns, name string, data []byte, opts metav1.PatchOptions) error {
+			_, err := c.CoreV1().ConfigMaps(ns).Patch(ctx, name, types.MergePatchType, data, opts)
+			return err
+		}},
+	"persistentvolumeclaims": {"PersistentVolumeClaim",
+		func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := c.CoreV1().PersistentVolumeClaims(ns).List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return relabelObjects(list.Items), nil
+		},
+		func(ctx context.Context, c kubernetes.Interface, ns, name string, data []byte, opts metav1.PatchOptions) error {
+			_, err := c.CoreV1().PersistentVolumeClaims(ns).Patch(ctx, name, types.MergePatchType, data, opts)
+			return err
+		}},
+	"ingresses": {"Ingress",
+		func(ctx context.Context, c kubernetes.Interface, ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := c.NetworkingV1().Ingresses(ns).List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return relabelObjects(list.Items), nil
+		},
+		func(ctx context.Context, c kubernetes.Interface, ns, name string, data []byte, opts metav1.PatchOptions) error {
+			_, err := c.NetworkingV1().Ingresses(ns).Patch(ctx, name, types.MergePatchType, data, opts)
+			return err
+		}},
+}
+
+// relabelObjects returns the object metadata of listed items
+func relabelObjects[T any, PT interface {
+	*T
+	metav1.Object
+}](items []T) []metav1.Object {
+	objects := make([]metav1.Object, 0, len(items))
+	for i := range items {
+		objects = append(objects, PT(&items[i]))
+	}
+	return objects
+}
+
+// labelApplyRequest is the body of POST /labels/apply
+type labelApplyRequest struct {
+	Clusters   []string `json:"clusters,omitempty"`   // Every known cluster when empty
+	Namespaces []string `json:"namespaces,omitempty"` // Every namespace when empty
+	Kinds      []string `json:"kinds"`                // Plural resource names, e.g. deployments
+	Selector   string   `json:"selector"`             // Label selector the objects must match
+	relabel.Spec
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// labelApplyItem is one object a relabeling request changes
+type labelApplyItem struct {
+	Cluster     string       `json:"cluster"`
+	Kind        string       `json:"kind"`
+	Namespace   string       `json:"namespace"`
+	Name        string       `json:"name"`
+	Status      string       `json:"status"`
+	Labels      relabel.Diff `json:"labels"`
+	Annotations relabel.Diff `json:"annotations"`
+	Error       string       `json:"error,omitempty"`
+	patch       []byte
+	kind        relabelKind
+}
+
+// planRelabel lists the objects of one cluster matching the request and computes their patches.
+// It returns the objects to change and how many matched without needing a change.
+func planRelabel(ctx context.Context, clusterID string, client kubernetes.Interface, req *labelApplyRequest) ([]*labelApplyItem, int, error) {
+	namespaces := req.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	var items []*labelApplyItem
+	unchanged := 0
+	for _, name := range req.Kinds {
+		kind := relabelKinds[name]
+		for _, namespace := range namespaces {
+			objects, err := kind.list(ctx, client, namespace, metav1.ListOptions{LabelSelector: req.Selector})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to list %s: %w", name, err)
+			}
+			for _, obj := range objects {
+				patch, labelDiff, annotationDiff, err := req.Spec.Patch(obj)
+				if err != nil {
+					return nil, 0, err
+				}
+				if patch == nil {
+					unchanged++
+					continue
+				}
+				items = append(items, &labelApplyItem{
+					Cluster:     clusterID,
+					Kind:        kind.Kind,
+					Namespace:   obj.GetNamespace(),
+					Name:        obj.GetName(),
+					Labels:      labelDiff,
+					Annotations: annotationDiff,
+					patch:       patch,
+					kind:        kind,
+				})
+			}
+		}
+	}
+	return items, unchanged, nil
+}
+
+// @Summary Bulk edit labels and annotations
+// @Description Adds and removes labels or annotations on every object of the given kinds that matches a label selector, across namespaces and clusters, for fleet-wide relabeling campaigns. Objects already in the desired state are not touched. Each patch carries the listed resourceVersion, so objects changed concurrently fail instead of being overwritten. With dry_run the patches are validated by the API servers, including admission, without being persisted. Requests changing more objects than label_edits.max_objects are refused before any change. Change freezes apply to every targeted cluster, and the changed objects are recorded in the audit log.
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param body body labelApplyRequest true "Objects to match and the changes to apply"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Failure 423 {object} map[string]string
+// @Router /labels/apply [post]
+func (s *apiServer) handleLabelsApply(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	var req labelApplyRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if err := req.Spec.Validate(); err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	// An empty selector would relabel every object of the kinds
+	selector, err := labels.Parse(req.Selector)
+	if err != nil || selector.Empty() {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "selector must be a non-empty label selector, e.g. team=payments"})
+		return
+	}
+	if len(req.Kinds) == 0 {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "kinds must list at least one resource, e.g. deployments"})
+		return
+	}
+	for _, kind := range req.Kinds {
+		if _, ok := relabelKinds[kind]; !ok {
+			supported := make([]string, 0, len(relabelKinds))
+			for name := range relabelKinds {
+				supported = append(supported, name)
+			}
+			sort.Strings(supported)
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Unsupported kind %q, expected one of %s", kind, strings.Join(supported, ", "))})
+			return
+		}
+	}
+	req.Kinds = slices.Compact(slices.Sorted(slices.Values(req.Kinds)))
+
+	// Resolve every cluster before changing any
+	clients := map[string]kubernetes.Interface{}
+	if len(req.Clusters) == 0 {
+		req.Clusters = []string{""}
+	}
+	for _, clusterID := range req.Clusters {
+		resolved, err := s.inventoryClients(clusterID)
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		for id, client := range resolved {
+			clients[id] = client
+		}
+	}
+	clusterIDs := make([]string, 0, len(clients))
+	for id := range clients {
+		clusterIDs = append(clusterIDs, id)
+	}
+	sort.Strings(clusterIDs)
+
+	if !req.DryRun {
+		for _, clusterID := range clusterIDs {
+			if !s.checkClusterFreeze(ctx, clusterID, "POST", string(ctx.Path()), logger) {
+				return
+			}
+		}
+	}
+
+	// List and plan every cluster concurrently; clusters that cannot be read are reported
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var items []*labelApplyItem
+	unchanged := 0
+	clusterErrors := map[string]string{}
+	for _, clusterID := range clusterIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			planned, same, err := planRelabel(requestContext(ctx), clusterID, clients[clusterID], &req)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				logger.Error().Err(err).Str("cluster_id", clusterID).Msg("Failed to list objects to relabel")
+				clusterErrors[clusterID] = err.Error()
+				return
+			}
+			items = append(items, planned...)
+			unchanged += same
+		}()
+	}
+	wg.Wait()
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	maxObjects := s.config.LabelEdits.MaxObjects
+	if maxObjects <= 0 {
+		maxObjects = relabel.DefaultMaxObjects
+	}
+	if !req.DryRun && len(items) > maxObjects {
+		logger.Warn().Int("objects", len(items)).Int("max_objects", maxObjects).Msg("Relabeling refused, too many objects")
+		ctx.SetStatusCode(fasthttp.StatusUnprocessableEntity)
+		json.NewEncoder(ctx).Encode(map[string]interface{}{
+			"error":       fmt.Sprintf("Request would change %d objects, more than the limit of %d; narrow the selector or split it by cluster or namespace", len(items), maxObjects),
+			"objects":     len(items),
+			"max_objects": maxObjects,
+		})
+		return
+	}
+
+	opts := metav1.PatchOptions{}
+	if req.DryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	var changedObjects []string
+	failed := 0
+	for _, item := range items {
+		err := item.kind.patch(requestContext(ctx), clients[item.Cluster], item.Namespace, item.Name, item.patch, opts)
+		switch {
+		case apierrors.IsConflict(err):
+			item.Status, item.Error = relabelFailed, "Object changed since it was listed; retry the request"
+		case err != nil:
+			item.Status, item.Error = relabelFailed, err.Error()
+		case req.DryRun:
+			item.Status = relabelWouldChange
+		default:
+			item.Status = relabelChanged
+			changedObjects = append(changedObjects, fmt.Sprintf("%s/%s/%s/%s", item.Cluster, item.Kind, item.Namespace, item.Name))
+			logger.Info().
+				Str("cluster_id", item.Cluster).
+				Str("kind", item.Kind).
+				Str("namespace", item.Namespace).
+				Str("name", item.Name).
+				Interface("labels", item.Labels).
+				Interface("annotations", item.Annotations).
+				Msg("Object relabeled")
+		}
+		if item.Status == relabelFailed {
+			failed++
+			logger.Warn().Str("cluster_id", item.Cluster).Str("kind", item.Kind).Str("namespace", item.Namespace).
+				Str("name", item.Name).Str("error", item.Error).Msg("Failed to relabel object")
+		}
+	}
+	if len(changedObjects) > 0 {
+		ctx.SetUserValue(auditObjectsUserValueKey, changedObjects)
+	}
+
+	if items == nil {
+		items = []*labelApplyItem{}
+	}
+	logger.Info().Bool("dry_run", req.DryRun).Int("changed", len(items)-failed).Int("unchanged", unchanged).
+		Int("failed", failed).Msg("Relabeling completed")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"dry_run":   req.DryRun,
+		"clusters":  clusterIDs,
+		"matched":   len(items) + unchanged,
+		"changed":   len(items) - failed,
+		"unchanged": unchanged,
+		"failed":    failed,
+		"items":     items,
+		"errors":    clusterErrors,
+	})
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/listcache"
+)
+
+// defaultListCacheRoutes are the list endpoints cached by default, whose lists are the
+// largest and are polled the most by dashboards
+var defaultListCacheRoutes = []string{"/nodes", "/pods"}
+
+// cachedList lists through the list cache when lists of the route are cached, the request
+// is not paged and it does not ask for a fresh list with Cache-Control: no-cache. A list
+// served from the cache sets the Age header. The cache holds whole lists; lists read past
+// it, or larger than the response item limit, are asked for within the limit so they carry
+// a continue token.
+func cachedList[T runtime.Object](s *apiServer, ctx *fasthttp.RequestCtx, route, clusterID, namespace string, opts metav1.ListOptions, list func(context.Context, metav1.ListOptions) (T, error)) (T, error) {
+	if s.listCache == nil || !s.listCache.Caches(route) || isPaginated(opts) || noCacheRequested(ctx) {
+		return list(requestContext(ctx), limitedOptions(ctx, opts))
+	}
+	key := listcache.Key{
+		Route:         route,
+		Cluster:       clusterID,
+		Namespace:     namespace,
+		LabelSelector: opts.LabelSelector,
+		FieldSelector: opts.FieldSelector,
+	}
+	value, age, result, err := s.listCache.Get(requestContext(ctx), key, func(c context.Context) (interface{}, error) {
+		return list(c, opts)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if exceedsItemLimit(ctx, meta.LenList(value.(T))) {
+		return list(requestContext(ctx), limitedOptions(ctx, opts))
+	}
+	if result == listcache.ResultHit {
+		ctx.Response.Header.Set("Age", strconv.Itoa(int(age.Seconds())))
+	}
+	return value.(T), nil
+}
+
+// noCacheRequested reports whether the client asked for a fresh list
+func noCacheRequested(ctx *fasthttp.RequestCtx) bool {
+	for _, directive := range strings.Split(string(ctx.Request.Header.Peek("Cache-Control")), ",") {
+		if directive = strings.TrimSpace(strings.ToLower(directive)); directive == "no-cache" || directive == "no-store" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Sources of list responses, reported in their source field
+const (
+	listSourceCache = "informer-cache"
+	listSourceAPI   = "kubernetes-api"
+)
+
+// primaryListers serve pod, service and node lists of the primary cluster from the shared
+// informer factory
+type primaryListers struct {
+	namespace string // Namespace the factory is limited to, empty for all namespaces
+
+	pods     corev1listers.PodLister
+	services corev1listers.ServiceLister
+	nodes    corev1listers.NodeLister
+
+	podsSynced     cache.InformerSynced
+	servicesSynced cache.InformerSynced
+	nodesSynced    cache.InformerSynced
+}
+
+// newPrimaryListers registers pod, service and node informers on the factory; they start
+// with the factory's next Start
+func newPrimaryListers(factory informers.SharedInformerFactory, namespace string) *primaryListers {
+	pods := factory.Core().V1().Pods()
+	services := factory.Core().V1().Services()
+	nodes := factory.Core().V1().Nodes()
+	return &primaryListers{
+		namespace:      namespace,
+		pods:           pods.Lister(),
+		services:       services.Lister(),
+		nodes:          nodes.Lister(),
+		podsSynced:     pods.Informer().HasSynced,
+		servicesSynced: services.Informer().HasSynced,
+		nodesSynced:    nodes.Informer().HasSynced,
+	}
+}
+
+// Fields the cached objects can be selected by; field selectors on other fields are
+// answered by the API server
+func podFields(p *corev1.Pod) fields.Set {
+	return fields.Set{
+		"metadata.name":      p.Name,
+		"metadata.namespace": p.Namespace,
+		"spec.nodeName":      p.Spec.NodeName,
+		"status.phase":       string(p.Status.Phase),
+	}
+}
+
+func serviceFields(s *corev1.Service) fields.Set {
+	return fields.Set{"metadata.name": s.Name, "metadata.namespace": s.Namespace}
+}
+
+func nodeFields(n *corev1.Node) fields.Set {
+	return fields.Set{"metadata.name": n.Name, "spec.unschedulable": strconv.FormatBool(n.Spec.Unschedulable)}
+}
+
+// useListers reports whether a list of the target cluster can be served from the listers:
+// the cluster is the primary one and the request is not paged and does not ask for
+// ?source=api. An invalid source is an error.
+func (s *apiServer) useListers(ctx *fasthttp.RequestCtx, target *clusterTarget, opts metav1.ListOptions) (bool, error) {
+	switch source := string(ctx.QueryArgs().Peek("source")); source {
+	case "", "cache":
+	case "api":
+		return false, nil
+	default:
+		return false, apierrors.NewBadRequest(fmt.Sprintf("invalid source %q, expected cache or api", source))
+	}
+	return s.listers != nil && target.isPrimary() && !isPaginated(opts), nil
+}
+
+// covers reports whether the informers hold the objects of a namespace, empty for all
+func (l *primaryListers) covers(namespace string) bool {
+	return l.namespace == "" || l.namespace == namespace
+}
+
+// listFromLister lists cached objects matching the label and field selectors, sorted by
+// namespace and name as the API server returns them. It returns false when the cache
+// cannot answer: its informer has not synced or the field selector uses fields that
+// fieldsOf does not return for the empty object.
+func listFromLister[T metav1.Object](synced cache.InformerSynced, opts metav1.ListOptions, list func(labels.Selector) ([]T, error), fieldsOf func(T) fields.Set, empty T) ([]T, bool, error) {
+	if !synced() {
+		return nil, false, nil
+	}
+	labelSel, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, false, apierrors.NewBadRequest(fmt.Sprintf("invalid label selector %q: %v", opts.LabelSelector, err))
+	}
+	fieldSel, err := fields.ParseSelector(opts.FieldSelector)
+	if err != nil {
+		return nil, false, apierrors.NewBadRequest(fmt.Sprintf("invalid field selector %q: %v", opts.FieldSelector, err))
+	}
+
+	objects, err := list(labelSel)
+	if err != nil {
+		return nil, false, err
+	}
+	if !fieldSel.Empty() {
+		supported := fieldsOf(empty)
+		for _, req := range fieldSel.Requirements() {
+			if _, ok := supported[req.Field]; !ok {
+				return nil, false, nil
+			}
+		}
+		matched := objects[:0:0]
+		for _, obj := range objects {
+			if fieldSel.Matches(fieldsOf(obj)) {
+				matched = append(matched, obj)
+			}
+		}
+		objects = matched
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].GetNamespace() != objects[j].GetNamespace() {
+			return objects[i].GetNamespace() < objects[j].GetNamespace()
+		}
+		return objects[i].GetName() < objects[j].GetName()
+	})
+	return objects, true, nil
+}
+
+// cachedPods lists pods of the namespace from the pod lister
+func (l *primaryListers) cachedPods(namespace string, opts metav1.ListOptions) (*corev1.PodList, bool, error) {
+	if !l.covers(namespace) {
+		return nil, false, nil
+	}
+	pods, ok, err := listFromLister(l.podsSynced, opts, func(sel labels.Selector) ([]*corev1.Pod, error) {
+		return l.pods.Pods(namespace).List(sel)
+	}, podFields, &corev1.Pod{})
+	if !ok {
+		return nil, false, err
+	}
+	list := &corev1.PodList{Items: make([]corev1.Pod, 0, len(pods))}
+	for _, p := range pods {
+		list.Items = append(list.Items, *p)
+	}
+	return list, true, nil
+}
+
+// cachedServices lists services of the namespace from the service lister
+func (l *primaryListers) cachedServices(namespace string, opts metav1.ListOptions) (*corev1.ServiceList, bool, error) {
+	if !l.covers(namespace) {
+		return nil, false, nil
+	}
+	services, ok, err := listFromLister(l.servicesSynced, opts, func(sel labels.Selector) ([]*corev1.Service, error) {
+		return l.services.Services(namespace).List(sel)
+	}, serviceFields, &corev1.Service{})
+	if !ok {
+		return nil, false, err
+	}
+	list := &corev1.ServiceList{Items: make([]corev1.Service, 0, len(services))}
+	for _, svc := range services {
+		list.Items = append(list.Items, *svc)
+	}
+	return list, true, nil
+}
+
+// cachedNodes lists nodes from the node lister
+func (l *primaryListers) cachedNodes(opts metav1.ListOptions) (*corev1.NodeList, bool, error) {
+	nodes, ok, err := listFromLister(l.nodesSynced, opts, l.nodes.List, nodeFields, &corev1.Node{})
+	if !ok {
+		return nil, false, err
+	}
+	list := &corev1.NodeList{Items: make([]corev1.Node, 0, len(nodes))}
+	for _, n := range nodes {
+		list.Items = append(list.Items, *n)
+	}
+	return list, true, nil
+}
+
+// listFromCacheOrAPI serves a list from the listers when useListers allows it and the cache
+// can answer with a list within the response item limit, from the API server otherwise, and
+// returns the source it came from
+func listFromCacheOrAPI[T runtime.Object](s *apiServer, ctx *fasthttp.RequestCtx, target *clusterTarget, opts metav1.ListOptions, cached func(*primaryListers) (T, bool, error), api func() (T, error)) (T, string, error) {
+	useCache, err := s.useListers(ctx, target, opts)
+	if err != nil {
+		var zero T
+		return zero, "", err
+	}
+	if useCache {
+		list, ok, err := cached(s.listers)
+		if err != nil || (ok && !exceedsItemLimit(ctx, meta.LenList(list))) {
+			return list, listSourceCache, err
+		}
+	}
+	list, err := api()
+	return list, listSourceAPI, err
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/lockout"
+)
+
+// rejectLockedOut answers 429 with Retry-After when the subject is locked out
+func (s *apiServer) rejectLockedOut(ctx *fasthttp.RequestCtx, kind, subject string, logger zerolog.Logger) bool {
+	remaining, locked := s.lockout.Locked(kind, subject)
+	if !locked {
+		return false
+	}
+
+	logger.Warn().Str("kind", kind).Str("subject", subject).Dur("remaining", remaining).Msg("Request from locked out client refused")
+	ctx.Response.Header.Set("Retry-After", retryAfterSeconds(remaining))
+	ctx.SetStatusCode(fasthttp.StatusTooManyRequests)
+	ctx.SetBodyString(`{"error": "Too many failed authentication attempts, try again later"}`)
+	return true
+}
+
+// recordAuthFailure counts a failure towards a lockout and logs when one starts
+func (s *apiServer) recordAuthFailure(eventType, kind, subject, detail string, logger zerolog.Logger) {
+	if s.lockout == nil || subject == "" {
+		return
+	}
+	if until, locked := s.lockout.RecordFailure(eventType, kind, subject, detail); locked {
+		logger.Warn().Str("kind", kind).Str("subject", subject).Time("until", until).Msg("Locked out after repeated authentication failures")
+	}
+}
+
+// retryAfterSeconds formats a Retry-After value, rounding up so clients never retry early
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// @Summary List security events
+// @Description Returns recent authentication failures, lockouts and brute-force alerts, newest first, together with the active lockouts
+// @Tags admin
+// @Produce json
+// @Param type query string false "Only include events of this type: auth_failure, authz_failure, lockout or brute_force"
+// @Param limit query int false "Maximum number of events to return (default 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /admin/security/events [get]
+func (s *apiServer) handleAdminSecurityEvents(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	if s.lockout == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Authentication lockout is disabled"}`)
+		return
+	}
+
+	eventType := string(ctx.QueryArgs().Peek("type"))
+	switch eventType {
+	case "", lockout.EventAuthFailure, lockout.EventAuthzFailure, lockout.EventLockout, lockout.EventBruteForce:
+	default:
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "Unknown event type"}`)
+		return
+	}
+
+	limit := 100
+	if raw := ctx.QueryArgs().Peek("limit"); len(raw) > 0 {
+		n, err := strconv.Atoi(string(raw))
+		if err != nil || n <= 0 {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			ctx.SetBodyString(`{"error": "limit must be a positive integer"}`)
+			return
+		}
+		limit = n
+	}
+
+	events := s.lockout.Events(eventType, limit)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"count":    len(events),
+		"events":   events,
+		"lockouts": s.lockout.Lockouts(),
+	})
+}
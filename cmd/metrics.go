@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/httpmetrics"
+)
+
+// metricsHandler adapts the Prometheus handler, built once for every scrape
+var metricsHandler = fasthttpadaptor.NewFastHTTPHandler(httpmetrics.Handler())
+
+// @Summary Prometheus metrics
+// @Description Serves the controller and API server metrics in the Prometheus text format, or in OpenMetrics with request ID exemplars when the scraper asks for application/openmetrics-text. Registered only when api_server.metrics.enabled is set.
+// @Tags system
+// @Produce plain
+// @Success 200 {string} string
+// @Router /metrics [get]
+func (s *apiServer) handleMetrics(ctx *fasthttp.RequestCtx) {
+	metricsHandler(ctx)
+}
+
+// routePattern returns the pattern of the route a request will be dispatched to, or an
+// empty string for paths served by no route, so rejected requests are labeled as well
+func (s *apiServer) routePattern(ctx *fasthttp.RequestCtx, method, path string) string {
+	return s.routes().Pattern(ctx, method, path)
+}
+
+// observeRequest counts a completed request and records its latency
+func (s *apiServer) observeRequest(ctx *fasthttp.RequestCtx, requestID, route, method string, start time.Time) {
+	var exemplar map[string]string
+	if s.config != nil && s.config.APIServer.Metrics.Exemplars {
+		exemplar = map[string]string{httpmetrics.RequestIDLabel: requestID}
+		if id := traceID(ctx); id != "" {
+			exemplar[httpmetrics.TraceIDLabel] = id
+		}
+	}
+	httpmetrics.ObserveRequest(route, method, ctx.Response.StatusCode(), time.Since(start), exemplar)
+}
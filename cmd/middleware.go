@@ -0,0 +1,383 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/cors"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/httpmetrics"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/middleware"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+)
+
+// requestInfoUserValueKey holds what the request-id stage learns about a request
+const requestInfoUserValueKey = "request_info"
+
+// requestInfo is shared by the middleware stages of a request
+type requestInfo struct {
+	id       string
+	route    string // Matched route pattern, empty when no route matches
+	clientIP string
+	start    time.Time
+	isHook   bool // Inbound webhook verified by its signature instead of API credentials
+}
+
+func getRequestInfo(ctx *fasthttp.RequestCtx) *requestInfo {
+	if info, ok := ctx.UserValue(requestInfoUserValueKey).(*requestInfo); ok {
+		return info
+	}
+	return &requestInfo{clientIP: ctx.RemoteIP().String(), start: ctx.Time()}
+}
+
+// handler returns the endpoints wrapped in the middleware chain, built on first use
+func (s *apiServer) handler() (fasthttp.RequestHandler, error) {
+	s.chainOnce.Do(func() {
+		s.chain, s.chainErr = s.newMiddlewareChain()
+		if s.chainErr == nil {
+			s.chained = s.chain.Then(s.routes().Handler)
+		}
+	})
+	return s.chained, s.chainErr
+}
+
+// newMiddlewareChain returns the built-in stages, outermost first, with the middleware
+// plugins inserted before the stages they name
+func (s *apiServer) newMiddlewareChain() (*middleware.Chain, error) {
+	chain, err := middleware.New(
+		middleware.Stage{Name: middleware.RequestID, Middleware: s.assignRequestID},
+		middleware.Stage{Name: middleware.Metrics, Middleware: s.instrument},
+		middleware.Stage{Name: middleware.Tracing, Middleware: traceRequest},
+		middleware.Stage{Name: middleware.Audit, Middleware: s.audit},
+		middleware.Stage{Name: middleware.DebugCapture, Middleware: s.debugCaptureStage},
+		middleware.Stage{Name: middleware.Logging, Middleware: logRequest},
+		middleware.Stage{Name: middleware.Recovery, Middleware: recoverPanics},
+		middleware.Stage{Name: middleware.LoadShedding, Middleware: s.shedLoad},
+		middleware.Stage{Name: middleware.RateLimit, Middleware: s.rateLimit},
+		middleware.Stage{Name: middleware.SecurityHeaders, Middleware: s.securityHeadersStage},
+		middleware.Stage{Name: middleware.CORS, Middleware: s.cors},
+		middleware.Stage{Name: middleware.InboundWebhooks, Middleware: s.verifyInboundHooks},
+		middleware.Stage{Name: middleware.CSRF, Middleware: s.csrfStage},
+		middleware.Stage{Name: middleware.Auth, Middleware: s.authenticate},
+		middleware.Stage{Name: middleware.Freeze, Middleware: s.freezeStage},
+		middleware.Stage{Name: middleware.Compression, Middleware: s.compress},
+		middleware.Stage{Name: middleware.Format, Middleware: formatResponse},
+		middleware.Stage{Name: middleware.Timeout, Middleware: s.timeoutStage},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range plugin.Default().Middlewares() {
+		stage := middleware.Stage{Name: p.Name(), Middleware: p.Middleware}
+		if p.Before() == "" {
+			err = chain.Append(stage)
+		} else {
+			err = chain.InsertBefore(p.Before(), stage)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("middleware plugin %s: %w", p.Name(), err)
+		}
+	}
+	return chain, nil
+}
+
+// assignRequestID sets X-Request-ID and matches the route, so every later stage can label
+// the request, rejected ones included
+func (s *apiServer) assignRequestID(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		info := &requestInfo{
+			id:       uuid.New().String(),
+			route:    s.routePattern(ctx, string(ctx.Method()), string(ctx.Path())),
+			clientIP: ctx.RemoteIP().String(),
+			start:    time.Now(),
+		}
+		ctx.SetUserValue(requestInfoUserValueKey, info)
+		ctx.Response.Header.Set("X-Request-ID", info.id)
+		next(ctx)
+	}
+}
+
+// instrument counts the request under its route pattern and records the latency, with the
+// request ID as exemplar
+func (s *apiServer) instrument(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		info, method := getRequestInfo(ctx), string(ctx.Method())
+		defer httpmetrics.TrackInFlight(info.route, method)()
+		defer s.observeRequest(ctx, info.id, info.route, method, info.start)
+		next(ctx)
+	}
+}
+
+// traceRequest traces the request, continuing the caller's trace from traceparent
+func traceRequest(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		info := getRequestInfo(ctx)
+		span := startRequestSpan(ctx, info.id, string(ctx.Method()), info.route)
+		defer endRequestSpan(ctx, span)
+		next(ctx)
+	}
+}
+
+// audit records mutating requests whatever their outcome, including rejected ones
+func (s *apiServer) audit(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		info := getRequestInfo(ctx)
+		defer s.auditRequest(ctx, info.id, string(ctx.Method()), string(ctx.Path()), info.clientIP, info.start)
+		next(ctx)
+	}
+}
+
+// debugCaptureStage records the full exchange while an admin's debug capture matches it
+func (s *apiServer) debugCaptureStage(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		info := getRequestInfo(ctx)
+		defer s.captureDebug(ctx, info.id, string(ctx.Method()), string(ctx.Path()), info.clientIP, info.start)
+		next(ctx)
+	}
+}
+
+// logRequest logs the request when it arrives and once it is answered
+func logRequest(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		info, method, path := getRequestInfo(ctx), string(ctx.Method()), string(ctx.Path())
+		logger := getRequestLogger(ctx)
+		logger.Debug().Str("method", method).Str("path", path).Str("client", info.clientIP).Msg("Request received")
+
+		next(ctx)
+
+		completed := logger.Info().Str("method", method).Str("path", path).Int("status", ctx.Response.StatusCode()).Dur("latency", time.Since(info.start))
+		if principal := getPrincipal(ctx); principal != nil {
+			completed = completed.Str("principal", principal.Name)
+		}
+		if id := traceID(ctx); id != "" {
+			completed = completed.Str("trace_id", id)
+		}
+		completed.Msg("Request completed")
+	}
+}
+
+// recoverPanics answers 500 when a handler panics instead of taking the server down
+func recoverPanics(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			logger := getRequestLogger(ctx)
+			logger.Error().
+				Str("method", string(ctx.Method())).
+				Str("path", string(ctx.Path())).
+				Interface("panic", recovered).
+				Bytes("stack", debug.Stack()).
+				Msg("Request handler panicked")
+			if ctx.Hijacked() {
+				return
+			}
+			ctx.Response.ResetBody()
+			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+			ctx.SetContentType("application/json; charset=utf8")
+			json.NewEncoder(ctx).Encode(map[string]string{
+				"error":      "Internal server error",
+				"request_id": getRequestInfo(ctx).id,
+			})
+		}()
+		next(ctx)
+	}
+}
+
+// shedLoad answers 503 to requests whose priority class the current load leaves no room for
+func (s *apiServer) shedLoad(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if s.shedder == nil {
+			next(ctx)
+			return
+		}
+		priority := s.shedder.Priority(string(ctx.Path()))
+		done, reason, ok := s.shedder.Admit(priority)
+		if !ok {
+			info, method := getRequestInfo(ctx), string(ctx.Method())
+			httpmetrics.Shed(info.route, method, priority, reason)
+			logger := getRequestLogger(ctx)
+			logger.Warn().Str("priority", priority).Str("reason", reason).Int64("in_flight", s.shedder.InFlight()).Msg("Request shed under load")
+			ctx.Response.Header.Set("Retry-After", "1")
+			ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+			ctx.SetContentType("application/json; charset=utf8")
+			json.NewEncoder(ctx).Encode(map[string]string{
+				"error":    "Server is overloaded, try again later",
+				"priority": priority,
+				"reason":   reason,
+			})
+			return
+		}
+		defer done()
+		next(ctx)
+	}
+}
+
+// rateLimit applies the global, per-IP and per-route rate limits
+func (s *apiServer) rateLimit(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if s.config != nil && (s.config.APIServer.Security.RateLimitRequestsPerSecond > 0 || len(s.config.APIServer.Security.RouteRateLimits) > 0) {
+			info, method, path := getRequestInfo(ctx), string(ctx.Method()), string(ctx.Path())
+			logger := getRequestLogger(ctx)
+			if allowed, limit := s.checkRateLimit(info.clientIP, method, path, logger); !allowed {
+				httpmetrics.RateLimited(info.route, method)
+				ctx.SetStatusCode(fasthttp.StatusTooManyRequests)
+				ctx.SetBodyString(`{"error": "Rate limit exceeded", "retry_after": "1s"}`)
+				logger.Warn().Str("client_ip", info.clientIP).Str("path", path).Int("limit", limit).Msg("Rate limit exceeded")
+				return
+			}
+		}
+		next(ctx)
+	}
+}
+
+func (s *apiServer) securityHeadersStage(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		s.setSecurityHeaders(ctx, string(ctx.Path()))
+		next(ctx)
+	}
+}
+
+// cors answers preflight requests and lets pages on the allowed origins read responses,
+// errors of later stages included, when enabled
+func (s *apiServer) cors(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		origin := string(ctx.Request.Header.Peek(cors.Origin))
+		if s.corsPolicy == nil || origin == "" {
+			next(ctx)
+			return
+		}
+		if method := ctx.Request.Header.Peek(cors.RequestMethod); ctx.IsOptions() && len(method) > 0 {
+			headers, err := s.corsPolicy.Preflight(origin, string(method), string(ctx.Request.Header.Peek(cors.RequestHeaders)))
+			if err != nil {
+				logger := getRequestLogger(ctx)
+				logger.Debug().Err(err).Str("origin", origin).Msg("CORS preflight refused")
+				ctx.SetStatusCode(fasthttp.StatusForbidden)
+				ctx.SetContentType("application/json; charset=utf8")
+				json.NewEncoder(ctx).Encode(map[string]string{"error": "CORS preflight refused: " + err.Error()})
+				return
+			}
+			setCORSHeaders(ctx, headers)
+			ctx.SetStatusCode(fasthttp.StatusNoContent)
+			return
+		}
+		if headers, ok := s.corsPolicy.Headers(origin); ok {
+			setCORSHeaders(ctx, headers)
+		}
+		next(ctx)
+	}
+}
+
+// setCORSHeaders sets the headers of a cross-origin response, adding to the Vary of later stages
+func setCORSHeaders(ctx *fasthttp.RequestCtx, headers []cors.Header) {
+	for _, h := range headers {
+		if h.Name == cors.Vary {
+			ctx.Response.Header.Add(h.Name, h.Value)
+		} else {
+			ctx.Response.Header.Set(h.Name, h.Value)
+		}
+	}
+}
+
+// verifyInboundHooks checks the signature of inbound webhooks, which prove their sender with
+// it instead of API credentials
+func (s *apiServer) verifyInboundHooks(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if s.hookVerifier != nil {
+			info := getRequestInfo(ctx)
+			var ok bool
+			if info.isHook, ok = s.checkInboundHook(ctx, string(ctx.Path()), getRequestLogger(ctx)); !ok {
+				return
+			}
+		}
+		next(ctx)
+	}
+}
+
+// csrfStage requires browser clients to echo the CSRF cookie on mutating requests
+func (s *apiServer) csrfStage(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if s.csrf != nil && !getRequestInfo(ctx).isHook && !s.checkCSRF(ctx, string(ctx.Method()), string(ctx.Path()), getRequestLogger(ctx)) {
+			return
+		}
+		next(ctx)
+	}
+}
+
+// authenticate authenticates and authorizes the request when auth is enabled
+func (s *apiServer) authenticate(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if s.authorizer != nil && !getRequestInfo(ctx).isHook && !s.authorizeRequest(ctx, string(ctx.Method()), string(ctx.Path()), getRequestLogger(ctx)) {
+			return
+		}
+		next(ctx)
+	}
+}
+
+// freezeStage refuses changes while a freeze window is in effect
+func (s *apiServer) freezeStage(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if !s.checkFreeze(ctx, string(ctx.Method()), string(ctx.Path()), getRequestLogger(ctx)) {
+			return
+		}
+		next(ctx)
+	}
+}
+
+// compress compresses large list responses for clients that accept it
+func (s *apiServer) compress(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		next(ctx)
+		if s.config != nil && s.config.APIServer.Compression.Enabled {
+			s.compressResponse(ctx, string(ctx.Path()))
+		}
+	}
+}
+
+// formatResponse sets the JSON content type and renders JSON payloads as YAML when
+// requested via ?output=yaml or the Accept header, and timestamps in another zone or
+// relative to now via ?tz= and ?relative=true
+func formatResponse(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("application/json; charset=utf8")
+		ctx.Response.Header.Add("Vary", "Accept")
+		output, err := outputFormat(ctx)
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		times, err := parseTimeFormat(ctx)
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		next(ctx)
+
+		if times != nil {
+			times.render(ctx)
+		}
+		if output == outputYAML {
+			renderYAML(ctx)
+		}
+	}
+}
+
+// timeoutStage cancels the handler's Kubernetes API calls once the route's timeout expires
+// and answers 504 when the handler gave up because of it
+func (s *apiServer) timeoutStage(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		timeout, cancel := s.startTimeout(ctx, string(ctx.Method()), string(ctx.Path()))
+		defer cancel()
+		next(ctx)
+		writeTimeout(ctx, timeout)
+	}
+}
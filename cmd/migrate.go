@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/migrate"
+)
+
+// @Summary Migrate a deployment to another cluster
+// @Description Starts a job that exports a deployment with the services selecting its pods and the configmaps it references from the source cluster, creates or updates them on the target cluster, waits until the target deployment is available and, with scale_down_source, scales the source deployment to zero. The job runs in the background; poll the URL in the Location header for its steps. Change freezes apply to the target cluster, and to the source when it is scaled down.
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param body body migrate.Request true "Deployment to migrate and the clusters involved"
+// @Success 202 {object} migrate.Job
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 423 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /migrate [post]
+func (s *apiServer) handleMigrate(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if s.migrations == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Migrations are unavailable"})
+		return
+	}
+
+	var req migrate.Request
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Resolve both clusters before starting
+	var clients [2]kubernetes.Interface
+	for i, clusterID := range []string{req.Source, req.Target} {
+		resolved, err := s.inventoryClients(clusterID)
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		clients[i] = resolved[clusterID]
+	}
+
+	if !s.checkClusterFreeze(ctx, req.Target, "POST", string(ctx.Path()), logger) {
+		return
+	}
+	if req.ScaleDownSource && !s.checkClusterFreeze(ctx, req.Source, "POST", string(ctx.Path()), logger) {
+		return
+	}
+
+	job := s.migrations.Start(req, clients[0], clients[1])
+	logger.Info().
+		Str("job", job.ID).
+		Str("source", req.Source).
+		Str("target", req.Target).
+		Str("namespace", req.Namespace).
+		Str("deployment", req.Deployment).
+		Msg("Migration job created")
+
+	ctx.Response.Header.Set("Location", "/migrate/"+job.ID)
+	ctx.SetStatusCode(fasthttp.StatusAccepted)
+	json.NewEncoder(ctx).Encode(job)
+}
+
+// @Summary List migration jobs
+// @Description Lists running and recent migration jobs, newest first, with the state of every step and the objects applied to the target cluster.
+// @Tags kubernetes
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /migrate [get]
+func (s *apiServer) handleMigrations(ctx *fasthttp.RequestCtx) {
+	if s.migrations == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Migrations are unavailable"})
+		return
+	}
+
+	jobs := s.migrations.List()
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"count": len(jobs),
+		"items": jobs,
+	})
+}
+
+// @Summary Get a migration job
+// @Description Returns a migration job with the state and message of every step: export, apply, wait_ready and scale_down_source.
+// @Tags kubernetes
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} migrate.Job
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /migrate/{id} [get]
+func (s *apiServer) handleMigration(ctx *fasthttp.RequestCtx) {
+	if s.migrations == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Migrations are unavailable"})
+		return
+	}
+
+	id := pathParam(ctx, "id")
+	job, ok := s.migrations.Get(id)
+	if !ok {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Migration job %s not found", id)})
+		return
+	}
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(job)
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// nodeResource compares what a node has, what it offers to pods and what its pods request
+type nodeResource struct {
+	Capacity         string  `json:"capacity"`
+	Allocatable      string  `json:"allocatable"`
+	Requested        string  `json:"requested"`
+	RequestedPercent float64 `json:"requested_percent"` // Of allocatable
+}
+
+// nodePod is a pod scheduled to a node with the resources it reserves there
+type nodePod struct {
+	Namespace          string `json:"namespace"`
+	Name               string `json:"name"`
+	Phase              string `json:"phase"`
+	OwnerKind          string `json:"owner_kind,omitempty"`
+	OwnerName          string `json:"owner_name,omitempty"`
+	CPURequestMilli    int64  `json:"cpu_request_milli"`
+	MemoryRequestBytes int64  `json:"memory_request_bytes"`
+}
+
+// nodeDetail is the response of GET /nodes/{name}
+type nodeDetail struct {
+	Cluster           string                  `json:"cluster"`
+	Name              string                  `json:"name"`
+	UID               string                  `json:"uid"`
+	CreationTimestamp metav1.Time             `json:"creation_timestamp"`
+	Labels            map[string]string       `json:"labels,omitempty"`
+	Annotations       map[string]string       `json:"annotations,omitempty"`
+	Unschedulable     bool                    `json:"unschedulable"`
+	Taints            []corev1.Taint          `json:"taints"`
+	Addresses         map[string]string       `json:"addresses"`
+	NodeInfo          corev1.NodeSystemInfo   `json:"node_info"`
+	Conditions        []corev1.NodeCondition  `json:"conditions"` // Every condition, whatever its status
+	Capacity          map[string]string       `json:"capacity"`
+	Allocatable       map[string]string       `json:"allocatable"`
+	Resources         map[string]nodeResource `json:"resources"` // cpu, memory and pods
+	Pods              []nodePod               `json:"pods"`
+}
+
+// @Summary Get a node
+// @Description Returns a node with its taints, addresses, system info and conditions of every status, its capacity and allocatable resources, and the pods scheduled to it with their CPU and memory requests. resources compares the capacity, allocatable and requested CPU, memory and pod count; succeeded and failed pods hold no capacity and are not counted.
+// @Tags kubernetes,nodes
+// @Produce json
+// @Param name path string true "Node name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {object} nodeDetail
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /nodes/{name} [get]
+func (s *apiServer) handleNodeDetail(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	name := pathParam(ctx, "name")
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	node, err := target.Client.CoreV1().Nodes().Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Node %s not found", name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("node", name).Msg("Failed to get node")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get node"})
+		return
+	}
+
+	pods, err := target.Client.CoreV1().Pods("").List(requestContext(ctx), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("node", name).Msg("Failed to list pods of node")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to list pods of node"})
+		return
+	}
+
+	detail := newNodeDetail(target.ID, node, pods.Items)
+	logger.Debug().Str("node", name).Int("pods", len(detail.Pods)).Msg("Node detail returned")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(detail)
+}
+
+func newNodeDetail(clusterID string, node *corev1.Node, pods []corev1.Pod) nodeDetail {
+	items := make([]nodePod, 0, len(pods))
+	var cpuRequests, memoryRequests, activePods int64
+	for i := range pods {
+		pod := &pods[i]
+		cpu, memory := podRequests(pod)
+		item := nodePod{
+			Namespace:          pod.Namespace,
+			Name:               pod.Name,
+			Phase:              string(pod.Status.Phase),
+			CPURequestMilli:    cpu,
+			MemoryRequestBytes: memory,
+		}
+		if owner := metav1.GetControllerOf(pod); owner != nil {
+			item.OwnerKind, item.OwnerName = owner.Kind, owner.Name
+		}
+		items = append(items, item)
+
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			cpuRequests += cpu
+			memoryRequests += memory
+			activePods++
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Namespace != items[j].Namespace {
+			return items[i].Namespace < items[j].Namespace
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	allocatable := node.Status.Allocatable
+	resources := map[string]nodeResource{
+		"cpu": newNodeResource(node.Status.Capacity.Cpu().String(), allocatable.Cpu().String(),
+			fmt.Sprintf("%dm", cpuRequests), cpuRequests, allocatable.Cpu().MilliValue()),
+		"memory": newNodeResource(node.Status.Capacity.Memory().String(), allocatable.Memory().String(),
+			fmt.Sprintf("%dMi", memoryRequests>>20), memoryRequests, allocatable.Memory().Value()),
+		"pods": newNodeResource(node.Status.Capacity.Pods().String(), allocatable.Pods().String(),
+			fmt.Sprintf("%d", activePods), activePods, allocatable.Pods().Value()),
+	}
+
+	addresses := make(map[string]string, len(node.Status.Addresses))
+	for _, addr := range node.Status.Addresses {
+		addresses[string(addr.Type)] = addr.Address
+	}
+	taints := node.Spec.Taints
+	if taints == nil {
+		taints = []corev1.Taint{}
+	}
+	conditions := node.Status.Conditions
+	if conditions == nil {
+		conditions = []corev1.NodeCondition{}
+	}
+
+	return nodeDetail{
+		Cluster:           clusterID,
+		Name:              node.Name,
+		UID:               string(node.UID),
+		CreationTimestamp: node.CreationTimestamp,
+		Labels:            node.Labels,
+		Annotations:       node.Annotations,
+		Unschedulable:     node.Spec.Unschedulable,
+		Taints:            taints,
+		Addresses:         addresses,
+		NodeInfo:          node.Status.NodeInfo,
+		Conditions:        conditions,
+		Capacity:          resourceStrings(node.Status.Capacity),
+		Allocatable:       resourceStrings(allocatable),
+		Resources:         resources,
+		Pods:              items,
+	}
+}
+
+func newNodeResource(capacity, allocatable, requested string, used, available int64) nodeResource {
+	r := nodeResource{Capacity: capacity, Allocatable: allocatable, Requested: requested}
+	if available > 0 {
+		r.RequestedPercent = float64(used*10000/available) / 100
+	}
+	return r
+}
+
+// resourceStrings formats a resource list like kubectl does
+func resourceStrings(list corev1.ResourceList) map[string]string {
+	result := make(map[string]string, len(list))
+	for name, quantity := range list {
+		result[string(name)] = quantity.String()
+	}
+	return result
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/metricsapi"
+)
+
+// allClusters selects every known cluster in ?cluster= of aggregating endpoints
+const allClusters = "all"
+
+// nodeUsage is the usage of one node against its allocatable resources
+type nodeUsage struct {
+	Cluster                string    `json:"cluster"`
+	Name                   string    `json:"name"`
+	Timestamp              time.Time `json:"timestamp"`
+	Window                 string    `json:"window"`
+	CPUUsageMilli          int64     `json:"cpu_usage_milli"`
+	CPUAllocatableMilli    int64     `json:"cpu_allocatable_milli"`
+	CPUPercent             float64   `json:"cpu_percent"`
+	MemoryUsageBytes       int64     `json:"memory_usage_bytes"`
+	MemoryAllocatableBytes int64     `json:"memory_allocatable_bytes"`
+	MemoryPercent          float64   `json:"memory_percent"`
+}
+
+// clusterUsage sums the usage of the nodes of a cluster that report metrics
+type clusterUsage struct {
+	ID                     string      `json:"id"`
+	Nodes                  int         `json:"nodes"`
+	CPUUsageMilli          int64       `json:"cpu_usage_milli"`
+	CPUAllocatableMilli    int64       `json:"cpu_allocatable_milli"`
+	CPUPercent             float64     `json:"cpu_percent"`
+	MemoryUsageBytes       int64       `json:"memory_usage_bytes"`
+	MemoryAllocatableBytes int64       `json:"memory_allocatable_bytes"`
+	MemoryPercent          float64     `json:"memory_percent"`
+	Error                  string      `json:"error,omitempty"`
+	items                  []nodeUsage // Per-node usage, sorted by name
+	err                    error
+	staleness              // Set when the usage is the last-known one of a failing cluster
+}
+
+// clusterNodeUsage joins the node metrics of a cluster with the allocatable resources of its
+// nodes. Nodes without metrics, such as ones still joining, are left out.
+func clusterNodeUsage(ctx context.Context, clusterID string, client kubernetes.Interface) *clusterUsage {
+	usage := &clusterUsage{ID: clusterID, items: []nodeUsage{}}
+	metrics, err := metricsapi.New(client.Discovery().RESTClient()).Nodes(ctx)
+	if err != nil {
+		usage.err, usage.Error = err, err.Error()
+		return usage
+	}
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		usage.err = fmt.Errorf("failed to list nodes: %w", err)
+		usage.Error = usage.err.Error()
+		return usage
+	}
+	allocatable := make(map[string][2]int64, len(nodes.Items))
+	for _, node := range nodes.Items {
+		allocatable[node.Name] = [2]int64{node.Status.Allocatable.Cpu().MilliValue(), node.Status.Allocatable.Memory().Value()}
+	}
+
+	for _, m := range metrics {
+		alloc, ok := allocatable[m.Name]
+		if !ok {
+			continue // Deleted since metrics-server last scraped it
+		}
+		usage.items = append(usage.items, nodeUsage{
+			Cluster:                clusterID,
+			Name:                   m.Name,
+			Timestamp:              m.Timestamp,
+			Window:                 m.Window.String(),
+			CPUUsageMilli:          m.CPUMilli,
+			CPUAllocatableMilli:    alloc[0],
+			CPUPercent:             metricsapi.Percent(m.CPUMilli, alloc[0]),
+			MemoryUsageBytes:       m.MemoryBytes,
+			MemoryAllocatableBytes: alloc[1],
+			MemoryPercent:          metricsapi.Percent(m.MemoryBytes, alloc[1]),
+		})
+		usage.CPUUsageMilli += m.CPUMilli
+		usage.CPUAllocatableMilli += alloc[0]
+		usage.MemoryUsageBytes += m.MemoryBytes
+		usage.MemoryAllocatableBytes += alloc[1]
+	}
+	sort.Slice(usage.items, func(i, j int) bool { return usage.items[i].Name < usage.items[j].Name })
+	usage.Nodes = len(usage.items)
+	usage.CPUPercent = metricsapi.Percent(usage.CPUUsageMilli, usage.CPUAllocatableMilli)
+	usage.MemoryPercent = metricsapi.Percent(usage.MemoryUsageBytes, usage.MemoryAllocatableBytes)
+	return usage
+}
+
+// @Summary Get node usage
+// @Description Returns the current CPU and memory usage of every node from the metrics.k8s.io API (metrics-server), with utilization as a percentage of allocatable resources and totals for the cluster. With cluster=all every known cluster is read and totals are reported per cluster; clusters without metrics carry their error.
+// @Tags kubernetes,nodes
+// @Produce json
+// @Param cluster query string false "Cluster ID, all for every cluster, defaults to the primary cluster"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /nodes/metrics [get]
+func (s *apiServer) handleNodeMetrics(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if string(ctx.QueryArgs().Peek("cluster")) != allClusters {
+		target := s.resolveCluster(ctx, logger)
+		if target == nil {
+			return
+		}
+		usage := clusterNodeUsage(requestContext(ctx), target.ID, target.Client)
+		if usage.err != nil {
+			logger.Error().Err(usage.err).Str("cluster_id", target.ID).Msg("Failed to read node metrics")
+			status := fasthttp.StatusInternalServerError
+			if errors.Is(usage.err, metricsapi.ErrUnavailable) {
+				status = fasthttp.StatusServiceUnavailable
+			}
+			ctx.SetStatusCode(status)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": usage.Error})
+			return
+		}
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		json.NewEncoder(ctx).Encode(map[string]interface{}{
+			"cluster": target.ID,
+			"count":   len(usage.items),
+			"totals":  usage,
+			"items":   usage.items,
+		})
+		return
+	}
+
+	clients, err := s.inventoryClients("")
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	clusters := make([]*clusterUsage, 0, len(clients))
+	for id, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read := s.readCluster(requestContext(ctx), id, circuitKeyNodeMetrics, func(c context.Context) (interface{}, error) {
+				usage := clusterNodeUsage(c, id, client)
+				return usage, usage.err
+			})
+			var usage *clusterUsage
+			switch {
+			case read.Err == nil:
+				copied := *read.Value.(*clusterUsage) // Last-known usage is shared by later requests
+				usage = &copied
+				usage.staleness = newStaleness(read)
+			case read.Value != nil:
+				usage = read.Value.(*clusterUsage)
+			default:
+				usage = &clusterUsage{ID: id, Error: read.Err.Error(), items: []nodeUsage{}, err: read.Err}
+			}
+			if usage.err != nil {
+				logger.Warn().Err(usage.err).Str("cluster_id", id).Msg("Failed to read node metrics")
+			}
+			mu.Lock()
+			clusters = append(clusters, usage)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].ID < clusters[j].ID })
+
+	items := make([]nodeUsage, 0)
+	for _, c := range clusters {
+		items = append(items, c.items...)
+	}
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster":  allClusters,
+		"clusters": clusters,
+		"count":    len(items),
+		"items":    items,
+	})
+}
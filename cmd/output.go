@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"sigs.k8s.io/yaml"
+)
+
+// Response formats selected by content negotiation
+const (
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// yamlContentType is sent with YAML responses
+const yamlContentType = "application/yaml; charset=utf-8"
+
+// yamlMediaTypes are the Accept values that select YAML output
+var yamlMediaTypes = map[string]bool{
+	"application/yaml":   true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+	"text/x-yaml":        true,
+}
+
+// outputFormat resolves the response format from ?output=, which takes precedence,
+// or from the Accept header
+func outputFormat(ctx *fasthttp.RequestCtx) (string, error) {
+	switch output := string(ctx.QueryArgs().Peek("output")); output {
+	case "":
+	case outputJSON, outputYAML:
+		return output, nil
+	default:
+		return "", fmt.Errorf("invalid output %q: must be json or yaml", output)
+	}
+	return negotiateOutput(string(ctx.Request.Header.Peek("Accept"))), nil
+}
+
+// negotiateOutput picks YAML when the Accept header ranks a YAML media type above
+// application/json. Wildcards keep the JSON default, and on equal quality the type
+// listed first wins.
+func negotiateOutput(accept string) string {
+	yamlQ, jsonQ := 0.0, 0.0
+	yamlFirst := false
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, q := parseMediaRange(mediaRange)
+		switch {
+		case yamlMediaTypes[mediaType] && q > yamlQ:
+			if yamlQ == 0 && jsonQ == 0 {
+				yamlFirst = true
+			}
+			yamlQ = q
+		case mediaType == "application/json" && q > jsonQ:
+			jsonQ = q
+		}
+	}
+
+	if yamlQ > jsonQ || (yamlQ > 0 && yamlQ == jsonQ && yamlFirst) {
+		return outputYAML
+	}
+	return outputJSON
+}
+
+// parseMediaRange returns the lower-cased media type and its q parameter, 1 when absent
+func parseMediaRange(mediaRange string) (string, float64) {
+	parts := strings.Split(mediaRange, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(parts[0]))
+	q := 1.0
+	for _, param := range parts[1:] {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.ToLower(strings.TrimSpace(name)) != "q" {
+			continue
+		}
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			q = parsed
+		}
+	}
+	return mediaType, q
+}
+
+// renderYAML rewrites a buffered JSON response body as YAML. Streams, WebSocket upgrades,
+// empty bodies such as 304 responses and other content types like CSV exports are left untouched.
+func renderYAML(ctx *fasthttp.RequestCtx) {
+	if ctx.Response.IsBodyStream() || ctx.Hijacked() || len(ctx.Response.Body()) == 0 {
+		return
+	}
+	if !strings.HasPrefix(string(ctx.Response.Header.ContentType()), "application/json") {
+		return
+	}
+
+	body, err := yaml.JSONToYAML(ctx.Response.Body())
+	if err != nil {
+		logger := getRequestLogger(ctx)
+		logger.Warn().Err(err).Msg("Failed to render response as YAML, returning JSON")
+		return
+	}
+	ctx.SetContentType(yamlContentType)
+	ctx.SetBody(body)
+}
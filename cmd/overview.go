@@ -0,0 +1,426 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ownership"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/precompute"
+)
+
+// Names of the precomputed aggregations
+const (
+	aggregationOverview           = "overview"
+	aggregationNamespaceSummaries = "namespace-summaries"
+)
+
+// resourceCounts are object counts of a cluster or of all clusters
+type resourceCounts struct {
+	Namespaces           int            `json:"namespaces"`
+	Nodes                int            `json:"nodes"`
+	ReadyNodes           int            `json:"ready_nodes"`
+	Deployments          int            `json:"deployments"`
+	AvailableDeployments int            `json:"available_deployments"` // Every desired replica available
+	Pods                 int            `json:"pods"`
+	PodPhases            map[string]int `json:"pod_phases"`
+	Services             int            `json:"services"`
+}
+
+// clusterOverview counts the objects of one cluster
+type clusterOverview struct {
+	Cluster string `json:"cluster"`
+	Error   string `json:"error,omitempty"` // Set when the cluster could not be read
+	staleness
+	resourceCounts
+}
+
+// namespaceSummary aggregates the workloads of one namespace in one cluster
+type namespaceSummary struct {
+	Cluster              string          `json:"cluster"`
+	Namespace            string          `json:"namespace"`
+	Owner                ownership.Owner `json:"owner"`
+	Deployments          int             `json:"deployments"`
+	AvailableDeployments int             `json:"available_deployments"`
+	Pods                 int             `json:"pods"`
+	RunningPods          int             `json:"running_pods"`
+	PendingPods          int             `json:"pending_pods"`
+	Services             int             `json:"services"`
+	CPURequestsMilli     int64           `json:"cpu_requests_milli"`    // Requests of pods that are not finished
+	MemoryRequestsBytes  int64           `json:"memory_requests_bytes"` // Requests of pods that are not finished
+	staleness
+}
+
+// clusterResources are the objects of one cluster the aggregations are built from
+type clusterResources struct {
+	namespaces  []corev1.Namespace
+	nodes       []corev1.Node
+	deployments []appsv1.Deployment
+	pods        []corev1.Pod
+	services    []corev1.Service
+}
+
+// clusterResult is the outcome of reading one cluster
+type clusterResult struct {
+	resources *clusterResources
+	err       error
+	staleness // Set when resources are the last-known ones of a failing cluster
+}
+
+// registerAggregations adds the cross-cluster aggregations to the precompute cache
+func (s *apiServer) registerAggregations(cache *precompute.Cache) {
+	cache.Register(aggregationOverview, func(ctx context.Context) (interface{}, error) {
+		return s.computeOverview(ctx)
+	})
+	cache.Register(aggregationNamespaceSummaries, func(ctx context.Context) (interface{}, error) {
+		return s.computeNamespaceSummaries(ctx)
+	})
+}
+
+// aggregation returns the precomputed result of an aggregation. It is computed for the
+// request when precomputation is disabled, the result is not warmed yet or refresh is set.
+func (s *apiServer) aggregation(ctx context.Context, name string, refresh bool, compute precompute.Func) (interface{}, precompute.Metadata, error) {
+	if s.precomputed == nil {
+		start := time.Now()
+		value, err := compute(ctx)
+		return value, precompute.LiveMetadata(start, time.Since(start)), err
+	}
+	if !refresh {
+		if value, meta, ok := s.precomputed.Get(name); ok {
+			return value, meta, nil
+		}
+	}
+	if err := s.precomputed.Refresh(ctx, name); err != nil {
+		return nil, precompute.Metadata{}, err
+	}
+	value, meta, _ := s.precomputed.Get(name)
+	meta.Source = precompute.SourceLive
+	return value, meta, nil
+}
+
+// collectClusters reads every known cluster concurrently. A cluster that cannot be read
+// is returned with its error so the others are still aggregated, or with its last-known
+// resources marked stale when circuit breaking is enabled.
+func (s *apiServer) collectClusters(ctx context.Context) (map[string]clusterResult, error) {
+	clients, err := s.inventoryClients("")
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]clusterResult, len(clients))
+	for clusterID, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read := s.readCluster(ctx, clusterID, circuitKeyResources, func(ctx context.Context) (interface{}, error) {
+				return collectClusterResources(ctx, client)
+			})
+			result := clusterResult{err: read.Err, staleness: newStaleness(read)}
+			if read.Err == nil {
+				result.resources = read.Value.(*clusterResources)
+			}
+			mu.Lock()
+			results[clusterID] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func collectClusterResources(ctx context.Context, client kubernetes.Interface) (*clusterResources, error) {
+	var r clusterResources
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	r.namespaces = namespaces.Items
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	r.nodes = nodes.Items
+	deployments, err := client.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	r.deployments = deployments.Items
+	pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	r.pods = pods.Items
+	services, err := client.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	r.services = services.Items
+	return &r, nil
+}
+
+// computeOverview counts the objects of every cluster, sorted by cluster ID
+func (s *apiServer) computeOverview(ctx context.Context) ([]clusterOverview, error) {
+	results, err := s.collectClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]clusterOverview, 0, len(results))
+	for clusterID, result := range results {
+		overview := clusterOverview{Cluster: clusterID, staleness: result.staleness, resourceCounts: resourceCounts{PodPhases: map[string]int{}}}
+		if result.err != nil {
+			overview.Error = result.err.Error()
+			clusters = append(clusters, overview)
+			continue
+		}
+		r := result.resources
+		overview.Namespaces = len(r.namespaces)
+		overview.Nodes = len(r.nodes)
+		for i := range r.nodes {
+			if nodeReady(&r.nodes[i]) {
+				overview.ReadyNodes++
+			}
+		}
+		overview.Deployments = len(r.deployments)
+		for i := range r.deployments {
+			if deploymentAvailable(&r.deployments[i]) {
+				overview.AvailableDeployments++
+			}
+		}
+		overview.Pods = len(r.pods)
+		for i := range r.pods {
+			overview.PodPhases[string(r.pods[i].Status.Phase)]++
+		}
+		overview.Services = len(r.services)
+		clusters = append(clusters, overview)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Cluster < clusters[j].Cluster })
+	return clusters, nil
+}
+
+// computeNamespaceSummaries aggregates every namespace of every cluster, sorted by cluster
+// and namespace. Clusters that cannot be read are left out.
+func (s *apiServer) computeNamespaceSummaries(ctx context.Context) ([]namespaceSummary, error) {
+	results, err := s.collectClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := ownership.Default()
+	var summaries []namespaceSummary
+	for clusterID, result := range results {
+		if result.err != nil {
+			continue
+		}
+		r := result.resources
+		byName := make(map[string]*namespaceSummary, len(r.namespaces))
+		for _, ns := range r.namespaces {
+			byName[ns.Name] = &namespaceSummary{
+				Cluster:   clusterID,
+				Namespace: ns.Name,
+				Owner:     resolver.FromAnnotations(ns.Name, ns.Annotations),
+				staleness: result.staleness,
+			}
+		}
+		for i := range r.deployments {
+			if summary, ok := byName[r.deployments[i].Namespace]; ok {
+				summary.Deployments++
+				if deploymentAvailable(&r.deployments[i]) {
+					summary.AvailableDeployments++
+				}
+			}
+		}
+		for i := range r.pods {
+			pod := &r.pods[i]
+			summary, ok := byName[pod.Namespace]
+			if !ok {
+				continue
+			}
+			summary.Pods++
+			switch pod.Status.Phase {
+			case corev1.PodRunning:
+				summary.RunningPods++
+			case corev1.PodPending:
+				summary.PendingPods++
+			}
+			if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+				cpu, memory := podRequests(pod)
+				summary.CPURequestsMilli += cpu
+				summary.MemoryRequestsBytes += memory
+			}
+		}
+		for i := range r.services {
+			if summary, ok := byName[r.services[i].Namespace]; ok {
+				summary.Services++
+			}
+		}
+		for _, summary := range byName {
+			summaries = append(summaries, *summary)
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Cluster != summaries[j].Cluster {
+			return summaries[i].Cluster < summaries[j].Cluster
+		}
+		return summaries[i].Namespace < summaries[j].Namespace
+	})
+	return summaries, nil
+}
+
+// deploymentAvailable reports whether every desired replica of a deployment is available
+func deploymentAvailable(d *appsv1.Deployment) bool {
+	desired := int32(1)
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+	return d.Status.AvailableReplicas >= desired
+}
+
+// nodeReady reports whether a node's Ready condition is true
+func nodeReady(node *corev1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// refreshFromQuery reads ?refresh=, which forces a live computation
+func refreshFromQuery(ctx *fasthttp.RequestCtx) (bool, bool) {
+	raw := ctx.QueryArgs().Peek("refresh")
+	if len(raw) == 0 {
+		return false, true
+	}
+	refresh, err := strconv.ParseBool(string(raw))
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "refresh must be true or false"}`)
+		return false, false
+	}
+	return refresh, true
+}
+
+// setAgeHeader sets the standard Age header on results that were not computed for the request
+func setAgeHeader(ctx *fasthttp.RequestCtx, meta precompute.Metadata) {
+	if meta.Source == precompute.SourcePrecomputed {
+		ctx.Response.Header.Set("Age", strconv.Itoa(int(meta.AgeSeconds)))
+	}
+}
+
+// @Summary Get a cross-cluster overview
+// @Description Counts namespaces, nodes, deployments, pods and services of every cluster, with totals. The result is precomputed in the background when precompute.enabled is set; cache reports when it was computed and whether it is stale.
+// @Tags kubernetes,overview
+// @Produce json
+// @Param cluster query string false "Only include this cluster"
+// @Param refresh query bool false "Compute the overview now instead of serving the precomputed one"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /overview [get]
+func (s *apiServer) handleOverview(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	refresh, ok := refreshFromQuery(ctx)
+	if !ok {
+		return
+	}
+
+	value, meta, err := s.aggregation(context.Background(), aggregationOverview, refresh, func(c context.Context) (interface{}, error) {
+		return s.computeOverview(c)
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to compute overview")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to compute overview: " + err.Error()})
+		return
+	}
+
+	clusterID := string(ctx.QueryArgs().Peek("cluster"))
+	clusters := make([]clusterOverview, 0)
+	totals := resourceCounts{PodPhases: map[string]int{}}
+	for _, c := range value.([]clusterOverview) {
+		if clusterID != "" && c.Cluster != clusterID {
+			continue
+		}
+		clusters = append(clusters, c)
+		totals.Namespaces += c.Namespaces
+		totals.Nodes += c.Nodes
+		totals.ReadyNodes += c.ReadyNodes
+		totals.Deployments += c.Deployments
+		totals.AvailableDeployments += c.AvailableDeployments
+		totals.Pods += c.Pods
+		for phase, n := range c.PodPhases {
+			totals.PodPhases[phase] += n
+		}
+		totals.Services += c.Services
+	}
+
+	setAgeHeader(ctx, meta)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"count":    len(clusters),
+		"clusters": clusters,
+		"totals":   totals,
+		"cache":    meta,
+	})
+}
+
+// @Summary Get namespace summaries
+// @Description Aggregates deployments, pods, services and resource requests per namespace across clusters, with the namespace owner. The result is precomputed in the background when precompute.enabled is set; cache reports when it was computed and whether it is stale.
+// @Tags kubernetes,overview
+// @Produce json
+// @Param cluster query string false "Only include this cluster"
+// @Param namespace query string false "Only include this namespace"
+// @Param refresh query bool false "Compute the summaries now instead of serving the precomputed ones"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /namespaces/summary [get]
+func (s *apiServer) handleNamespaceSummaries(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	refresh, ok := refreshFromQuery(ctx)
+	if !ok {
+		return
+	}
+
+	value, meta, err := s.aggregation(context.Background(), aggregationNamespaceSummaries, refresh, func(c context.Context) (interface{}, error) {
+		return s.computeNamespaceSummaries(c)
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to compute namespace summaries")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to compute namespace summaries: " + err.Error()})
+		return
+	}
+
+	clusterID := string(ctx.QueryArgs().Peek("cluster"))
+	namespace := string(ctx.QueryArgs().Peek("namespace"))
+	items := make([]namespaceSummary, 0)
+	for _, summary := range value.([]namespaceSummary) {
+		if (clusterID != "" && summary.Cluster != clusterID) || (namespace != "" && summary.Namespace != namespace) {
+			continue
+		}
+		items = append(items, summary)
+	}
+
+	setAgeHeader(ctx, meta)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"count": len(items),
+		"items": items,
+		"cache": meta,
+	})
+}
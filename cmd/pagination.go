@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// maxPageLimit caps the page size a client may request
+const maxPageLimit = 1000
+
+// continueTokenHeader carries the continue token for responses without an envelope (format=simple)
+const continueTokenHeader = "X-Continue-Token"
+
+// Headers marking lists truncated by api_server.response_limits for responses without an envelope
+const (
+	truncatedHeader  = "X-Truncated"
+	totalCountHeader = "X-Total-Count"
+)
+
+// itemLimitKey is the user value holding the response item limit the server applies to a
+// list request, set only when the client did not ask for a smaller page
+const itemLimitKey = "__list_item_limit"
+
+// itemTotalKey is the user value holding the full count of a list read whole from a cache
+// that exceeded the item limit
+const itemTotalKey = "__list_item_total"
+
+// getListOptionsFromQuery builds list options from the limit, continue, labelSelector
+// and fieldSelector query parameters
+func getListOptionsFromQuery(ctx *fasthttp.RequestCtx) (metav1.ListOptions, error) {
+	opts := metav1.ListOptions{
+		Continue:      string(ctx.QueryArgs().Peek("continue")),
+		LabelSelector: string(ctx.QueryArgs().Peek("labelSelector")),
+		FieldSelector: string(ctx.QueryArgs().Peek("fieldSelector")),
+	}
+
+	if raw := string(ctx.QueryArgs().Peek("limit")); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || limit <= 0 {
+			return opts, fmt.Errorf("limit must be a positive integer")
+		}
+		opts.Limit = limit
+	}
+	return opts, validateListOptions(opts)
+}
+
+// listOptions returns the list options of the query and records the configured response
+// item limit for the request. A larger limit asked for by the client is lowered to it; an
+// unpaged request stays unpaged, so it can still be served from the informer and list
+// caches, and the limit is applied by limitedOptions and exceedsItemLimit instead.
+func (s *apiServer) listOptions(ctx *fasthttp.RequestCtx) (metav1.ListOptions, error) {
+	opts, err := getListOptionsFromQuery(ctx)
+	if err != nil || s.config == nil {
+		return opts, err
+	}
+	limit := s.config.APIServer.ResponseLimits.MaxItemsFor(getNamespaceFromQuery(ctx))
+	if limit > 0 && (opts.Limit == 0 || opts.Limit > limit) {
+		if opts.Limit > limit {
+			opts.Limit = limit
+		}
+		ctx.SetUserValue(itemLimitKey, limit)
+	}
+	return opts, nil
+}
+
+// limitedOptions returns the options for listing from the API server, asking for a page of
+// at most the response item limit so an oversized list comes back truncated with a continue
+// token rather than whole
+func limitedOptions(ctx *fasthttp.RequestCtx, opts metav1.ListOptions) metav1.ListOptions {
+	if limit, ok := ctx.UserValue(itemLimitKey).(int64); ok && opts.Limit == 0 {
+		opts.Limit = limit
+	}
+	return opts
+}
+
+// exceedsItemLimit reports whether a list of count items read whole from a cache is larger
+// than the response item limit, and records its count as the total for paginationMetadata.
+// Only the API server hands out continue tokens, so such lists are listed again from it
+// within the limit.
+func exceedsItemLimit(ctx *fasthttp.RequestCtx, count int) bool {
+	limit, ok := ctx.UserValue(itemLimitKey).(int64)
+	if !ok || int64(count) <= limit {
+		return false
+	}
+	ctx.SetUserValue(itemTotalKey, count)
+	return true
+}
+
+// validateListOptions rejects malformed selectors and oversized pages before they reach
+// the API or the cache
+func validateListOptions(opts metav1.ListOptions) error {
+	if _, err := labels.Parse(opts.LabelSelector); err != nil {
+		return fmt.Errorf("invalid labelSelector: %v", err)
+	}
+	if _, err := fields.ParseSelector(opts.FieldSelector); err != nil {
+		return fmt.Errorf("invalid fieldSelector: %v", err)
+	}
+	if opts.Limit < 0 {
+		return fmt.Errorf("limit must be a positive integer")
+	}
+	if opts.Limit > maxPageLimit {
+		return fmt.Errorf("limit must not exceed %d", maxPageLimit)
+	}
+	return nil
+}
+
+// isPaginated reports whether the client asked for a page rather than the full list
+func isPaginated(opts metav1.ListOptions) bool {
+	return opts.Limit > 0 || opts.Continue != ""
+}
+
+// writePaginationError responds to invalid paging or selector parameters; it returns false when err is nil
+func writePaginationError(ctx *fasthttp.RequestCtx, err error) bool {
+	if err == nil {
+		return false
+	}
+	ctx.SetStatusCode(fasthttp.StatusBadRequest)
+	json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+	return true
+}
+
+// writeContinueExpired responds when the Kubernetes continue token has expired.
+// It returns false when err is not an expiry error.
+func writeContinueExpired(ctx *fasthttp.RequestCtx, err error) bool {
+	if !apierrors.IsResourceExpired(err) && !apierrors.IsGone(err) {
+		return false
+	}
+	ctx.SetStatusCode(fasthttp.StatusGone)
+	json.NewEncoder(ctx).Encode(map[string]string{
+		"error": "Continue token has expired, restart the listing without continue",
+	})
+	return true
+}
+
+// writeInvalidSelector responds when the API rejects the list request, e.g. a field
+// selector on a field the resource does not support. It returns false for other errors.
+func writeInvalidSelector(ctx *fasthttp.RequestCtx, err error) bool {
+	if !apierrors.IsBadRequest(err) {
+		return false
+	}
+	ctx.SetStatusCode(fasthttp.StatusBadRequest)
+	json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+	return true
+}
+
+// paginationMetadata describes the returned page of count items and sets the continue token
+// header. Lists cut short by the response item limit are marked truncated, with their total
+// when the API server reports the remaining count on the first page or a cache held the
+// whole list, so clients page on rather than taking part of a list for all of it.
+func paginationMetadata(ctx *fasthttp.RequestCtx, opts metav1.ListOptions, listMeta metav1.ListMeta, count int) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"limit":    opts.Limit,
+		"continue": listMeta.Continue,
+	}
+	if listMeta.RemainingItemCount != nil {
+		metadata["remaining_item_count"] = *listMeta.RemainingItemCount
+	}
+	if listMeta.Continue != "" {
+		ctx.Response.Header.Set(continueTokenHeader, listMeta.Continue)
+	}
+	limit, limited := ctx.UserValue(itemLimitKey).(int64)
+	if limited && listMeta.Continue != "" {
+		metadata["truncated"] = true
+		metadata["max_items"] = limit
+		metadata["returned"] = count
+		ctx.Response.Header.Set(truncatedHeader, "true")
+		total := int64(-1)
+		if cachedTotal, ok := ctx.UserValue(itemTotalKey).(int); ok {
+			total = int64(cachedTotal)
+		}
+		if listMeta.RemainingItemCount != nil && opts.Continue == "" {
+			total = int64(count) + *listMeta.RemainingItemCount
+		}
+		if total >= 0 {
+			metadata["total"] = total
+			ctx.Response.Header.Set(totalCountHeader, strconv.FormatInt(total, 10))
+		}
+	}
+	return metadata
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/listcache"
+)
+
+// newListRequest creates a request context for a list with the given query
+func newListRequest(query string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/pods?" + query)
+	return ctx
+}
+
+// newLimitedServer creates an API server with the given response limits
+func newLimitedServer(limits ResponseLimits) *apiServer {
+	cfg := &Config{}
+	cfg.APIServer.ResponseLimits = limits
+	return &apiServer{config: cfg}
+}
+
+// TestResponseLimits_Validate tests that negative limits are rejected
+func TestResponseLimits_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  ResponseLimits
+		wantErr bool
+	}{
+		{"empty", ResponseLimits{}, false},
+		{"limits", ResponseLimits{MaxItems: 500, Namespaces: map[string]int64{"kube-system": 100, "batch": 0}}, false},
+		{"negative max items", ResponseLimits{MaxItems: -1}, true},
+		{"negative namespace limit", ResponseLimits{MaxItems: 500, Namespaces: map[string]int64{"kube-system": -5}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestResponseLimits_MaxItemsFor tests that namespace limits override the default
+func TestResponseLimits_MaxItemsFor(t *testing.T) {
+	limits := ResponseLimits{MaxItems: 500, Namespaces: map[string]int64{"kube-system": 100, "batch": 0, "": 7}}
+
+	assert.Equal(t, int64(100), limits.MaxItemsFor("kube-system"))
+	assert.Equal(t, int64(0), limits.MaxItemsFor("batch"), "a namespace can lift the limit")
+	assert.Equal(t, int64(500), limits.MaxItemsFor("default"))
+	assert.Equal(t, int64(500), limits.MaxItemsFor(""), "lists of all namespaces use max_items")
+	assert.Equal(t, int64(0), ResponseLimits{}.MaxItemsFor("default"))
+}
+
+// TestListOptions_ItemLimit tests that the item limit is recorded without paging unpaged requests
+func TestListOptions_ItemLimit(t *testing.T) {
+	s := newLimitedServer(ResponseLimits{MaxItems: 50, Namespaces: map[string]int64{"kube-system": 10}})
+	tests := []struct {
+		name        string
+		query       string
+		wantLimit   int64 // Limit of the client options
+		wantApplied int64 // Limit of the options for the API server
+		wantMarked  bool
+	}{
+		{"unpaged", "", 0, 50, true},
+		{"namespace limit", "namespace=kube-system", 0, 10, true},
+		{"larger limit lowered", "limit=200", 50, 50, true},
+		{"smaller limit kept", "limit=20", 20, 20, false},
+		{"continue without limit", "continue=abc", 0, 50, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newListRequest(tt.query)
+			opts, err := s.listOptions(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLimit, opts.Limit)
+			assert.Equal(t, tt.wantApplied, limitedOptions(ctx, opts).Limit)
+			_, marked := ctx.UserValue(itemLimitKey).(int64)
+			assert.Equal(t, tt.wantMarked, marked)
+		})
+	}
+
+	// Unpaged requests stay eligible for the informer and list caches
+	opts, err := s.listOptions(newListRequest(""))
+	require.NoError(t, err)
+	assert.False(t, isPaginated(opts))
+
+	// Without limits nothing is recorded
+	ctx := newListRequest("")
+	opts, err = newLimitedServer(ResponseLimits{}).listOptions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), limitedOptions(ctx, opts).Limit)
+	assert.False(t, exceedsItemLimit(ctx, 3))
+}
+
+// TestPaginationMetadata_Truncated tests the truncation metadata and headers of lists cut
+// by the API server and of lists cut after reading them from a cache
+func TestPaginationMetadata_Truncated(t *testing.T) {
+	s := newLimitedServer(ResponseLimits{MaxItems: 2})
+
+	t.Run("api page", func(t *testing.T) {
+		ctx := newListRequest("")
+		opts, err := s.listOptions(ctx)
+		require.NoError(t, err)
+		remaining := int64(3)
+		metadata := paginationMetadata(ctx, opts, metav1.ListMeta{Continue: "next", RemainingItemCount: &remaining}, 2)
+
+		assert.Equal(t, true, metadata["truncated"])
+		assert.Equal(t, int64(2), metadata["max_items"])
+		assert.Equal(t, 2, metadata["returned"])
+		assert.Equal(t, int64(5), metadata["total"])
+		assert.Equal(t, "next", metadata["continue"])
+		assert.Equal(t, "true", string(ctx.Response.Header.Peek(truncatedHeader)))
+		assert.Equal(t, "5", string(ctx.Response.Header.Peek(totalCountHeader)))
+		assert.Equal(t, "next", string(ctx.Response.Header.Peek(continueTokenHeader)))
+	})
+
+	t.Run("relisted from a cache", func(t *testing.T) {
+		ctx := newListRequest("")
+		opts, err := s.listOptions(ctx)
+		require.NoError(t, err)
+		require.True(t, exceedsItemLimit(ctx, 7))
+		metadata := paginationMetadata(ctx, opts, metav1.ListMeta{Continue: "next"}, 2)
+
+		assert.Equal(t, true, metadata["truncated"])
+		assert.Equal(t, 2, metadata["returned"])
+		assert.Equal(t, int64(7), metadata["total"], "the count the cache held stands in for the remaining count")
+		assert.Equal(t, "true", string(ctx.Response.Header.Peek(truncatedHeader)))
+		assert.Equal(t, "7", string(ctx.Response.Header.Peek(totalCountHeader)))
+		assert.Equal(t, "next", string(ctx.Response.Header.Peek(continueTokenHeader)))
+	})
+
+	t.Run("later page", func(t *testing.T) {
+		ctx := newListRequest("continue=abc")
+		opts, err := s.listOptions(ctx)
+		require.NoError(t, err)
+		remaining := int64(1)
+		metadata := paginationMetadata(ctx, opts, metav1.ListMeta{Continue: "next", RemainingItemCount: &remaining}, 2)
+
+		assert.Equal(t, true, metadata["truncated"])
+		assert.NotContains(t, metadata, "total", "the total is only known on the first page")
+		assert.Empty(t, ctx.Response.Header.Peek(totalCountHeader))
+	})
+
+	t.Run("whole list", func(t *testing.T) {
+		ctx := newListRequest("")
+		opts, err := s.listOptions(ctx)
+		require.NoError(t, err)
+		require.False(t, exceedsItemLimit(ctx, 2))
+		metadata := paginationMetadata(ctx, opts, metav1.ListMeta{}, 2)
+
+		assert.NotContains(t, metadata, "truncated")
+		assert.Empty(t, ctx.Response.Header.Peek(truncatedHeader))
+	})
+
+	t.Run("client page", func(t *testing.T) {
+		ctx := newListRequest("limit=1")
+		opts, err := s.listOptions(ctx)
+		require.NoError(t, err)
+		metadata := paginationMetadata(ctx, opts, metav1.ListMeta{Continue: "next"}, 1)
+
+		assert.NotContains(t, metadata, "truncated", "pages the client asked for are not truncated")
+		assert.Equal(t, "next", string(ctx.Response.Header.Peek(continueTokenHeader)))
+	})
+}
+
+// TestCachedList_ItemLimit tests that cached lists over the item limit are listed again from
+// the API server as a page with a continue token
+func TestCachedList_ItemLimit(t *testing.T) {
+	s := newLimitedServer(ResponseLimits{MaxItems: 2})
+	s.listCache = listcache.New(listcache.Config{Enabled: true, TTL: time.Minute, Routes: []string{"/pods"}})
+
+	var calls []metav1.ListOptions
+	list := func(count int) func(context.Context, metav1.ListOptions) (*corev1.PodList, error) {
+		return func(_ context.Context, opts metav1.ListOptions) (*corev1.PodList, error) {
+			calls = append(calls, opts)
+			pods := &corev1.PodList{Items: make([]corev1.Pod, count)}
+			if opts.Limit > 0 && int64(count) > opts.Limit {
+				pods.Items = pods.Items[:opts.Limit]
+				pods.Continue = "next"
+			}
+			return pods, nil
+		}
+	}
+
+	t.Run("within limit", func(t *testing.T) {
+		calls = nil
+		ctx := newListRequest("namespace=small")
+		opts, err := s.listOptions(ctx)
+		require.NoError(t, err)
+		pods, err := cachedList(s, ctx, "/pods", "primary", "small", opts, list(2))
+		require.NoError(t, err)
+		assert.Len(t, pods.Items, 2)
+		require.Len(t, calls, 1)
+		assert.Equal(t, int64(0), calls[0].Limit, "the cache holds whole lists")
+	})
+
+	t.Run("over limit", func(t *testing.T) {
+		calls = nil
+		ctx := newListRequest("namespace=large")
+		opts, err := s.listOptions(ctx)
+		require.NoError(t, err)
+		pods, err := cachedList(s, ctx, "/pods", "primary", "large", opts, list(5))
+		require.NoError(t, err)
+		assert.Len(t, pods.Items, 2)
+		assert.Equal(t, "next", pods.Continue)
+		require.Len(t, calls, 2)
+		assert.Equal(t, int64(2), calls[1].Limit, "the list is asked for again as a page")
+
+		metadata := paginationMetadata(ctx, opts, pods.ListMeta, len(pods.Items))
+		assert.Equal(t, true, metadata["truncated"])
+		assert.Equal(t, int64(5), metadata["total"])
+		assert.Equal(t, "next", metadata["continue"])
+		assert.Empty(t, ctx.Response.Header.Peek("Age"))
+	})
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/podaudit"
+)
+
+// @Summary Get the privileged workload audit
+// @Description Checks the pod specs of every cluster for privileged containers, hostPID and hostIPC, dangerous added capabilities, privilege escalation, running as root and missing securityContext. Each workload is reported once with findings of critical, high, medium or low severity, and namespaces are rolled up with their most severe finding.
+// @Tags security
+// @Produce json
+// @Param cluster query string false "Only include this cluster"
+// @Param namespace query string false "Only include this namespace"
+// @Param severity query string false "Only include findings of this severity or more severe: critical, high, medium or low"
+// @Param check query string false "Only include findings of this check, e.g. privileged"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /security/workloads [get]
+func (s *apiServer) handleSecurityWorkloads(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	clusterFilter := string(ctx.QueryArgs().Peek("cluster"))
+	namespace := getNamespaceFromQuery(ctx)
+	severity := strings.ToLower(string(ctx.QueryArgs().Peek("severity")))
+	check := string(ctx.QueryArgs().Peek("check"))
+	if severity != "" && !slices.Contains(podaudit.Severities, severity) {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{
+			"error": fmt.Sprintf("Invalid severity %q, expected one of %s", severity, strings.Join(podaudit.Severities, ", ")),
+		})
+		return
+	}
+	if check != "" && !slices.Contains(podaudit.Checks, check) {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{
+			"error": fmt.Sprintf("Invalid check %q, expected one of %s", check, strings.Join(podaudit.Checks, ", ")),
+		})
+		return
+	}
+
+	auditor := s.workloadAuditor
+	if auditor == nil {
+		auditor, _ = podaudit.New(podaudit.Config{})
+	}
+
+	results, err := s.collectClusters(requestContext(ctx))
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to resolve clusters for workload audit")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if _, ok := results[clusterFilter]; clusterFilter != "" && !ok {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Cluster %s not found", clusterFilter)})
+		return
+	}
+
+	clusterIDs := make([]string, 0, len(results))
+	for clusterID := range results {
+		if clusterFilter == "" || clusterID == clusterFilter {
+			clusterIDs = append(clusterIDs, clusterID)
+		}
+	}
+	slices.Sort(clusterIDs)
+
+	items := make([]podaudit.Finding, 0)
+	totals := map[string]int{}
+	clusterErrors := map[string]string{}
+	stale := map[string]staleness{}
+	for _, clusterID := range clusterIDs {
+		result := results[clusterID]
+		if result.err != nil {
+			logger.Error().Err(result.err).Str("cluster_id", clusterID).Msg("Failed to read cluster for workload audit")
+			clusterErrors[clusterID] = result.err.Error()
+			continue
+		}
+		if result.Stale {
+			stale[clusterID] = result.staleness
+		}
+		for _, f := range auditor.Audit(clusterID, result.resources.pods) {
+			if (namespace != "" && f.Namespace != namespace) || (severity != "" && !auditor.AtLeast(f.Severity, severity)) || (check != "" && f.Check != check) {
+				continue
+			}
+			totals[f.Severity]++
+			items = append(items, f)
+		}
+	}
+
+	response := map[string]interface{}{
+		"clusters":   clusterIDs,
+		"count":      len(items),
+		"totals":     totals,
+		"items":      items,
+		"namespaces": auditor.Rollup(items),
+	}
+	if len(clusterErrors) > 0 {
+		response["errors"] = clusterErrors
+	}
+	if len(stale) > 0 {
+		response["stale"] = stale
+	}
+
+	logger.Debug().Int("findings", len(items)).Msg("Workload audit returned")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	maxPodLogBytes = 8 << 20 // Logs returned without follow, so a noisy container cannot exhaust memory
+
+	// defaultContainerAnnotation names the container kubectl picks when none is given
+	defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+)
+
+// podLogOptionsFromQuery parses container, tailLines, sinceSeconds, previous, timestamps and
+// follow
+func podLogOptionsFromQuery(ctx *fasthttp.RequestCtx) (*corev1.PodLogOptions, error) {
+	args := ctx.QueryArgs()
+	opts := &corev1.PodLogOptions{
+		Container:  string(args.Peek("container")),
+		Previous:   args.GetBool("previous"),
+		Timestamps: args.GetBool("timestamps"),
+		Follow:     args.GetBool("follow"),
+	}
+	if raw := string(args.Peek("tailLines")); raw != "" {
+		lines, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || lines < 0 {
+			return nil, fmt.Errorf("tailLines must be a non-negative integer")
+		}
+		opts.TailLines = &lines
+	}
+	if raw := string(args.Peek("sinceSeconds")); raw != "" {
+		seconds, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("sinceSeconds must be a positive integer")
+		}
+		opts.SinceSeconds = &seconds
+	}
+	if opts.Previous && opts.Follow {
+		return nil, fmt.Errorf("previous logs cannot be followed")
+	}
+	if !opts.Follow {
+		limitBytes := int64(maxPodLogBytes)
+		opts.LimitBytes = &limitBytes
+	}
+	return opts, nil
+}
+
+// logContainer returns the container whose logs are read: the requested one, the pod's
+// default-container annotation or its first container
+func logContainer(pod *corev1.Pod, requested string) (string, error) {
+	var names []string
+	for _, c := range pod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		names = append(names, c.Name)
+	}
+
+	if requested == "" {
+		if annotated := pod.Annotations[defaultContainerAnnotation]; annotated != "" {
+			requested = annotated
+		} else if len(pod.Spec.Containers) > 0 {
+			return pod.Spec.Containers[0].Name, nil
+		}
+	}
+	for _, name := range names {
+		if name == requested {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("container %s not found in pod %s, expected one of %s", requested, pod.Name, strings.Join(names, ", "))
+}
+
+// streamMaxDuration returns how long streaming responses stay open
+func (s *apiServer) streamMaxDuration() time.Duration {
+	if s.config != nil && s.config.APIServer.Watch.MaxDuration > 0 {
+		return s.config.APIServer.Watch.MaxDuration
+	}
+	return time.Hour
+}
+
+// @Summary Get pod logs
+// @Description Returns the logs of one container of a pod as plain text, with credentials redacted. Without container the pod's kubectl.kubernetes.io/default-container annotation or its first container is used. With follow=true the logs are streamed with chunked transfer encoding until the container exits, the client disconnects or the stream's maximum duration passes. Logs returned without follow are limited to 8 MiB.
+// @Tags kubernetes,pods
+// @Produce plain
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Pod name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Param container query string false "Container name"
+// @Param tailLines query int false "Only return this many lines from the end of the logs"
+// @Param sinceSeconds query int false "Only return logs newer than this many seconds"
+// @Param previous query bool false "Return the logs of the previous, terminated container instance"
+// @Param timestamps query bool false "Prefix every line with its RFC3339 timestamp"
+// @Param follow query bool false "Stream new log lines as they are written"
+// @Success 200 {string} string "text/plain"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /pods/{namespace}/{name}/logs [get]
+func (s *apiServer) handlePodLogs(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	opts, err := podLogOptionsFromQuery(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	pod, err := target.Client.CoreV1().Pods(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Pod %s/%s not found", namespace, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get pod")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get pod"})
+		return
+	}
+	if opts.Container, err = logContainer(pod, opts.Container); err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	streamCtx, cancel := context.WithTimeout(s.drain.StreamContext(), s.streamMaxDuration())
+	logs, err := target.Client.CoreV1().Pods(namespace).GetLogs(name, opts).Stream(streamCtx)
+	if err != nil {
+		cancel()
+		var status apierrors.APIStatus
+		if errors.As(err, &status) && status.Status().Code == fasthttp.StatusBadRequest {
+			// Containers that have not started, or have no previous instance, have no logs
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": status.Status().Message})
+			return
+		}
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Str("container", opts.Container).Msg("Failed to get pod logs")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get pod logs"})
+		return
+	}
+
+	logger.Info().
+		Str("namespace", namespace).
+		Str("name", name).
+		Str("container", opts.Container).
+		Bool("follow", opts.Follow).
+		Msg("Pod logs requested")
+
+	redactor := s.strictRedactor()
+	redactLine := func(line string) string {
+		return redactor.RedactText(string(redactor.Redact([]byte(line))))
+	}
+	ctx.SetContentType("text/plain; charset=utf-8")
+	ctx.Response.Header.Set("X-Container", opts.Container)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+
+	if !opts.Follow {
+		defer cancel()
+		defer logs.Close()
+		var body bytes.Buffer
+		if err := copyRedactedLines(&body, logs, redactLine); err != nil {
+			logger.Warn().Err(err).Str("namespace", namespace).Str("name", name).Msg("Pod logs ended early")
+		}
+		ctx.SetBody(body.Bytes())
+		return
+	}
+
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.Response.Header.Set("X-Accel-Buffering", "no") // Disable buffering in nginx-style proxies
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer s.drain.TrackStream()()
+		defer cancel()
+		defer logs.Close()
+		if err := copyRedactedLines(w, logs, redactLine); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			logger.Debug().Err(err).Str("namespace", namespace).Str("name", name).Msg("Pod log stream closed")
+		}
+	})
+}
+
+// copyRedactedLines copies log lines through redact, flushing each line when dst is buffered
+// so followers see lines as they are written
+func copyRedactedLines(dst io.Writer, src io.Reader, redact func(string) string) error {
+	flusher, _ := dst.(interface{ Flush() error })
+	reader := bufio.NewReader(src)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			if _, err := io.WriteString(dst, redact(line)); err != nil {
+				return err
+			}
+			if flusher != nil {
+				if err := flusher.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/metricsapi"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/recommend"
+)
+
+// provisioningRank orders usage statuses from most to least urgent; a pod takes the status
+// of its most urgent container
+var provisioningRank = map[string]int{
+	recommend.StatusUnderProvisioned: 0,
+	recommend.StatusMissingRequests:  1,
+	recommend.StatusOverProvisioned:  2,
+	recommend.StatusOK:               3,
+}
+
+// resourceUsage is current usage against requests and limits; percentages are 0 when the
+// request or limit is unset
+type resourceUsage struct {
+	CPUUsageMilli        int64   `json:"cpu_usage_milli"`
+	CPURequestMilli      int64   `json:"cpu_request_milli"`
+	CPULimitMilli        int64   `json:"cpu_limit_milli"`
+	CPURequestPercent    float64 `json:"cpu_request_percent"`
+	CPULimitPercent      float64 `json:"cpu_limit_percent"`
+	MemoryUsageBytes     int64   `json:"memory_usage_bytes"`
+	MemoryRequestBytes   int64   `json:"memory_request_bytes"`
+	MemoryLimitBytes     int64   `json:"memory_limit_bytes"`
+	MemoryRequestPercent float64 `json:"memory_request_percent"`
+	MemoryLimitPercent   float64 `json:"memory_limit_percent"`
+}
+
+func newResourceUsage(cpuMilli, memoryBytes int64, r recommend.Resources) resourceUsage {
+	return resourceUsage{
+		CPUUsageMilli:        cpuMilli,
+		CPURequestMilli:      r.CPURequestMilli,
+		CPULimitMilli:        r.CPULimitMilli,
+		CPURequestPercent:    metricsapi.Percent(cpuMilli, r.CPURequestMilli),
+		CPULimitPercent:      metricsapi.Percent(cpuMilli, r.CPULimitMilli),
+		MemoryUsageBytes:     memoryBytes,
+		MemoryRequestBytes:   r.MemoryRequestBytes,
+		MemoryLimitBytes:     r.MemoryLimitBytes,
+		MemoryRequestPercent: metricsapi.Percent(memoryBytes, r.MemoryRequestBytes),
+		MemoryLimitPercent:   metricsapi.Percent(memoryBytes, r.MemoryLimitBytes),
+	}
+}
+
+// containerUsage is the usage of one container with its provisioning status
+type containerUsage struct {
+	Name string `json:"name"`
+	resourceUsage
+	Status string `json:"status"`
+}
+
+// podUsage sums the usage of a pod's containers. Its limits are 0 unless every container
+// sets one.
+type podUsage struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Node      string    `json:"node,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Window    string    `json:"window"`
+	resourceUsage
+	Status     string           `json:"status"`
+	Containers []containerUsage `json:"containers"`
+}
+
+// newPodUsage joins the metrics of a pod with the requests and limits of its containers
+func newPodUsage(m metricsapi.PodUsage, pod *corev1.Pod) podUsage {
+	specs := make(map[string]recommend.Resources, len(pod.Spec.Containers))
+	for _, c := range pod.Spec.Containers {
+		specs[c.Name] = recommend.Resources{
+			CPURequestMilli:    c.Resources.Requests.Cpu().MilliValue(),
+			CPULimitMilli:      c.Resources.Limits.Cpu().MilliValue(),
+			MemoryRequestBytes: c.Resources.Requests.Memory().Value(),
+			MemoryLimitBytes:   c.Resources.Limits.Memory().Value(),
+		}
+	}
+
+	usage := podUsage{
+		Namespace:  m.Namespace,
+		Name:       m.Name,
+		Node:       pod.Spec.NodeName,
+		Timestamp:  m.Timestamp,
+		Window:     m.Window.String(),
+		Status:     recommend.StatusOK,
+		Containers: make([]containerUsage, 0, len(m.Containers)),
+	}
+	var cpu, memory int64
+	var total recommend.Resources
+	cpuLimited, memoryLimited := true, true
+	for _, c := range m.Containers {
+		spec := specs[c.Name]
+		status := recommend.ClassifyUsage(c.CPUMilli, c.MemoryBytes, spec)
+		usage.Containers = append(usage.Containers, containerUsage{
+			Name:          c.Name,
+			resourceUsage: newResourceUsage(c.CPUMilli, c.MemoryBytes, spec),
+			Status:        status,
+		})
+		if provisioningRank[status] < provisioningRank[usage.Status] {
+			usage.Status = status
+		}
+		cpu += c.CPUMilli
+		memory += c.MemoryBytes
+		total.CPURequestMilli += spec.CPURequestMilli
+		total.CPULimitMilli += spec.CPULimitMilli
+		total.MemoryRequestBytes += spec.MemoryRequestBytes
+		total.MemoryLimitBytes += spec.MemoryLimitBytes
+		cpuLimited = cpuLimited && spec.CPULimitMilli > 0
+		memoryLimited = memoryLimited && spec.MemoryLimitBytes > 0
+	}
+	if !cpuLimited {
+		total.CPULimitMilli = 0
+	}
+	if !memoryLimited {
+		total.MemoryLimitBytes = 0
+	}
+	sort.Slice(usage.Containers, func(i, j int) bool { return usage.Containers[i].Name < usage.Containers[j].Name })
+	usage.resourceUsage = newResourceUsage(cpu, memory, total)
+	return usage
+}
+
+// @Summary Get pod usage
+// @Description Returns the current CPU and memory usage of pods and their containers from the metrics.k8s.io API (metrics-server) next to their requests and limits. Each container is classified as under-provisioned (usage above a request, or memory above 90% of its limit), over-provisioned (CPU and memory below 30% of their requests), missing-requests or ok, and a pod takes the status of its most urgent container.
+// @Tags kubernetes,pods
+// @Produce json
+// @Param namespace query string false "Namespace, all namespaces when empty"
+// @Param labelSelector query string false "Label selector, e.g. app=web"
+// @Param status query string false "Only pods with this status: under-provisioned, over-provisioned, missing-requests or ok"
+// @Param sort query string false "name (default), cpu or memory; usage sorts descend"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /pods/metrics [get]
+func (s *apiServer) handlePodMetrics(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	namespace := getNamespaceFromQuery(ctx)
+	selector := string(ctx.QueryArgs().Peek("labelSelector"))
+	if _, err := labels.Parse(selector); err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("invalid labelSelector: %v", err)})
+		return
+	}
+	status := string(ctx.QueryArgs().Peek("status"))
+	if _, ok := provisioningRank[status]; status != "" && !ok {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Unknown status %q", status)})
+		return
+	}
+	sortBy := string(ctx.QueryArgs().Peek("sort"))
+	switch sortBy {
+	case "", "name", "cpu", "memory":
+	default:
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Unknown sort %q, expected name, cpu or memory", sortBy)})
+		return
+	}
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	metrics, err := metricsapi.New(target.Client.Discovery().RESTClient()).Pods(requestContext(ctx), namespace, selector)
+	if err != nil {
+		logger.Error().Err(err).Str("cluster_id", target.ID).Msg("Failed to read pod metrics")
+		code := fasthttp.StatusInternalServerError
+		if errors.Is(err, metricsapi.ErrUnavailable) {
+			code = fasthttp.StatusServiceUnavailable
+		}
+		ctx.SetStatusCode(code)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	pods, err := target.Client.CoreV1().Pods(namespace).List(requestContext(ctx), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		logger.Error().Err(err).Str("cluster_id", target.ID).Msg("Failed to list pods")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to list pods"})
+		return
+	}
+	byName := make(map[string]*corev1.Pod, len(pods.Items))
+	for i := range pods.Items {
+		byName[pods.Items[i].Namespace+"/"+pods.Items[i].Name] = &pods.Items[i]
+	}
+
+	summary := map[string]int{}
+	items := make([]podUsage, 0, len(metrics))
+	for _, m := range metrics {
+		pod, ok := byName[m.Namespace+"/"+m.Name]
+		if !ok {
+			continue // Deleted since metrics-server last scraped it
+		}
+		usage := newPodUsage(m, pod)
+		summary[usage.Status]++
+		if status != "" && usage.Status != status {
+			continue
+		}
+		items = append(items, usage)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		switch {
+		case sortBy == "cpu" && items[i].CPUUsageMilli != items[j].CPUUsageMilli:
+			return items[i].CPUUsageMilli > items[j].CPUUsageMilli
+		case sortBy == "memory" && items[i].MemoryUsageBytes != items[j].MemoryUsageBytes:
+			return items[i].MemoryUsageBytes > items[j].MemoryUsageBytes
+		case items[i].Namespace != items[j].Namespace:
+			return items[i].Namespace < items[j].Namespace
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster": target.ID,
+		"count":   len(items),
+		"summary": summary,
+		"items":   items,
+	})
+}
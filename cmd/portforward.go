@@ -0,0 +1,583 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/tunnel"
+)
+
+// proxyMethods are the methods proxied to pods and services
+var proxyMethods = []string{
+	fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodPut, fasthttp.MethodPatch, fasthttp.MethodDelete,
+}
+
+// portForwardPingInterval is how often WebSocket tunnels are pinged; clients must answer
+// within two intervals
+const portForwardPingInterval = 30 * time.Second
+
+// hopHeaders apply to one connection and are not forwarded. Credentials for this API are not
+// passed on to workloads.
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Te", "Trailer",
+	"Transfer-Encoding", "Upgrade", "Authorization", "X-API-Key",
+}
+
+// forwardTarget is the pod and port a port-forward or proxy request reaches
+type forwardTarget struct {
+	cluster *clusterTarget
+	pod     *corev1.Pod
+	port    int32
+}
+
+// portForwardEnabled writes 503 and returns false when port-forwarding is disabled
+func (s *apiServer) portForwardEnabled(ctx *fasthttp.RequestCtx) bool {
+	if s.config == nil || !s.config.APIServer.PortForward.Enabled {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Port-forwarding is disabled"}`)
+		return false
+	}
+	return true
+}
+
+// resolveForwardTarget resolves the namespace, name and port path parameters of a pod, or of a
+// service to a ready pod behind it, writing the error response when that fails
+func (s *apiServer) resolveForwardTarget(ctx *fasthttp.RequestCtx, logger zerolog.Logger, service bool) *forwardTarget {
+	namespace, name, portParam := pathParam(ctx, "namespace"), pathParam(ctx, "name"), pathParam(ctx, "port")
+	port, err := strconv.ParseInt(portParam, 10, 32)
+	if (err != nil && !service) || (err == nil && (port < 1 || port > 65535)) {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Invalid port %q, expected a number from 1 to 65535", portParam)})
+		return nil
+	}
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return nil
+	}
+	if !service {
+		pod, err := target.Client.CoreV1().Pods(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+		if !s.checkForwardGet(ctx, logger, "Pod", namespace, name, err) {
+			return nil
+		}
+		if pod.Status.Phase != corev1.PodRunning {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Pod %s/%s is %s, not Running", namespace, name, pod.Status.Phase)})
+			return nil
+		}
+		return &forwardTarget{cluster: target, pod: pod, port: int32(port)}
+	}
+
+	svc, err := target.Client.CoreV1().Services(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if !s.checkForwardGet(ctx, logger, "Service", namespace, name, err) {
+		return nil
+	}
+	var svcPort *corev1.ServicePort
+	for i, p := range svc.Spec.Ports {
+		if p.Name == portParam || strconv.Itoa(int(p.Port)) == portParam {
+			svcPort = &svc.Spec.Ports[i]
+			break
+		}
+	}
+	if svcPort == nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Service %s/%s has no port %s", namespace, name, portParam)})
+		return nil
+	}
+	if len(svc.Spec.Selector) == 0 {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Service %s/%s has no selector", namespace, name)})
+		return nil
+	}
+
+	pods, err := target.Client.CoreV1().Pods(namespace).List(requestContext(ctx), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to list pods of service")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to list pods of service"})
+		return nil
+	}
+	pod := readyPod(pods.Items)
+	if pod == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Service %s/%s has no ready pods", namespace, name)})
+		return nil
+	}
+	podPort, err := containerPort(pod, svcPort.TargetPort, svcPort.Port)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return nil
+	}
+	return &forwardTarget{cluster: target, pod: pod, port: podPort}
+}
+
+// checkForwardGet writes the response for a failed get of the forwarded object
+func (s *apiServer) checkForwardGet(ctx *fasthttp.RequestCtx, logger zerolog.Logger, kind, namespace, name string, err error) bool {
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("%s %s/%s not found", kind, namespace, name)})
+		return false
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msgf("Failed to get %s", strings.ToLower(kind))
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to get %s", strings.ToLower(kind))})
+		return false
+	}
+	return true
+}
+
+// readyPod returns the first running and ready pod by name, as kubectl port-forward picks one
+func readyPod(pods []corev1.Pod) *corev1.Pod {
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	for i := range pods {
+		if pods[i].Status.Phase != corev1.PodRunning || pods[i].DeletionTimestamp != nil {
+			continue
+		}
+		for _, c := range pods[i].Status.Conditions {
+			if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+				return &pods[i]
+			}
+		}
+	}
+	return nil
+}
+
+// containerPort resolves a service's target port to a port of the pod; named ports are looked
+// up in its containers and an unset target port is the service port
+func containerPort(pod *corev1.Pod, targetPort intstr.IntOrString, servicePort int32) (int32, error) {
+	if targetPort.Type == intstr.Int {
+		if targetPort.IntVal == 0 {
+			return servicePort, nil
+		}
+		return targetPort.IntVal, nil
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == targetPort.StrVal {
+				return p.ContainerPort, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s has no container port named %s", pod.Name, targetPort.StrVal)
+}
+
+// acquirePortForward counts a tunnel or proxied request against the connection limit,
+// writing 503 and returning false when it is reached
+func (s *apiServer) acquirePortForward(ctx *fasthttp.RequestCtx, logger zerolog.Logger) bool {
+	limit := s.config.APIServer.PortForward.MaxConnections
+	if n := s.portForwards.Add(1); limit > 0 && n > int64(limit) {
+		s.portForwards.Add(-1)
+		logger.Warn().Int("max_connections", limit).Msg("Port-forward connection limit reached")
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Too many port-forward connections"}`)
+		return false
+	}
+	return true
+}
+
+// openForward dials the pod's portforward subresource and connects to the target port
+func (s *apiServer) openForward(target *forwardTarget) (*tunnel.Tunnel, *tunnel.Conn, error) {
+	restConfig, err := s.lookupRestConfig(target.cluster.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	req := target.cluster.Client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(target.pod.Namespace).
+		Name(target.pod.Name).
+		SubResource("portforward")
+	tun, err := tunnel.Dial(restConfig, req.URL())
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := tun.Open(target.port)
+	if err != nil {
+		tun.Close()
+		return nil, nil, err
+	}
+	return tun, conn, nil
+}
+
+// @Summary Port-forward to a pod over WebSocket
+// @Description Upgrades to a WebSocket tunnelled to a port of a pod through the Kubernetes portforward subresource. Binary frames carry the raw TCP stream in both directions; the connection closes when either side does, with the error the kubelet reported as close reason. Requires the editor role.
+// @Tags kubernetes,pods
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Pod name"
+// @Param port path int true "Container port"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /pods/{namespace}/{name}/portforward/{port} [get]
+func (s *apiServer) handlePodPortForward(ctx *fasthttp.RequestCtx) {
+	s.servePortForward(ctx, false)
+}
+
+// @Summary Port-forward to a service over WebSocket
+// @Description Like the pod port-forward, to the target port of the first ready pod behind a service, as kubectl port-forward svc/name does. The port is a service port number or name. Requires the editor role.
+// @Tags kubernetes,services
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Service name"
+// @Param port path string true "Service port number or name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /services/{namespace}/{name}/portforward/{port} [get]
+func (s *apiServer) handleServicePortForward(ctx *fasthttp.RequestCtx) {
+	s.servePortForward(ctx, true)
+}
+
+func (s *apiServer) servePortForward(ctx *fasthttp.RequestCtx, service bool) {
+	logger := getRequestLogger(ctx)
+	if !s.portForwardEnabled(ctx) {
+		return
+	}
+	cfg := s.config.APIServer.PortForward
+
+	if !websocket.FastHTTPIsWebSocketUpgrade(ctx) {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "Expected a WebSocket upgrade request"}`)
+		return
+	}
+	target := s.resolveForwardTarget(ctx, logger, service)
+	if target == nil || !s.acquirePortForward(ctx, logger) {
+		return
+	}
+
+	tun, remote, err := s.openForward(target)
+	if err != nil {
+		s.portForwards.Add(-1)
+		logger.Error().Err(err).Str("namespace", target.pod.Namespace).Str("pod", target.pod.Name).Msg("Failed to open port-forward")
+		ctx.SetStatusCode(fasthttp.StatusBadGateway)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to open port-forward"})
+		return
+	}
+
+	sessionLogger := logger.With().
+		Str("cluster_id", target.cluster.ID).
+		Str("namespace", target.pod.Namespace).
+		Str("pod", target.pod.Name).
+		Int32("port", target.port).
+		Logger()
+	if principal := getPrincipal(ctx); principal != nil {
+		sessionLogger = sessionLogger.With().Str("principal", principal.Name).Logger()
+	}
+	upgrader := newUpgrader(cfg.AllowedOrigins)
+
+	err = upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+		defer s.drain.TrackStream()()
+		defer s.portForwards.Add(-1)
+		defer tun.Close()
+		defer remote.Close()
+		defer conn.Close()
+
+		started := time.Now()
+		sessionLogger.Info().Msg("Port-forward opened")
+		sent, received, err := bridgeTunnel(conn, remote, cfg.IdleTimeout)
+		event := sessionLogger.Info()
+		if err != nil {
+			event = sessionLogger.Warn().Err(err)
+		}
+		event.Int64("bytes_sent", sent).
+			Int64("bytes_received", received).
+			Dur("duration", time.Since(started)).
+			Msg("Port-forward closed")
+	})
+	if err != nil {
+		s.portForwards.Add(-1)
+		tun.Close()
+		logger.Warn().Err(err).Msg("WebSocket upgrade failed")
+	}
+}
+
+// bridgeTunnel copies frames of the client to the remote port and its output back until
+// either side closes or the tunnel is idle. It returns the bytes sent to and received from
+// the port, and the error that ended the tunnel, if any.
+func bridgeTunnel(conn *websocket.Conn, remote *tunnel.Conn, idleTimeout time.Duration) (int64, int64, error) {
+	var sent, received, activity atomic.Int64
+	touch := func() { activity.Store(time.Now().UnixNano()) }
+	touch()
+
+	var writeMu sync.Mutex
+	done := make(chan struct{})
+	var closeOnce sync.Once
+	finish := func() { closeOnce.Do(func() { close(done) }) }
+
+	// Client to port; the port's write side is closed when the client stops sending
+	go func() {
+		defer finish()
+		defer remote.CloseWrite()
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * portForwardPingInterval))
+		})
+		conn.SetReadDeadline(time.Now().Add(2 * portForwardPingInterval))
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			touch()
+			if _, err := remote.Write(data); err != nil {
+				return
+			}
+			sent.Add(int64(len(data)))
+		}
+	}()
+
+	// Keep-alive pings and the idle timeout
+	go func() {
+		ticker := time.NewTicker(portForwardPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if idleTimeout > 0 && time.Since(time.Unix(0, activity.Load())) >= idleTimeout {
+				writeMu.Lock()
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "idle"), time.Now().Add(wsWriteTimeout))
+				writeMu.Unlock()
+				remote.Close()
+				finish()
+				return
+			}
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)) != nil {
+				remote.Close()
+				finish()
+				return
+			}
+		}
+	}()
+
+	// Port to client
+	var remoteErr error
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := remote.Read(buf)
+		if n > 0 {
+			touch()
+			writeMu.Lock()
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			writeErr := conn.WriteMessage(websocket.BinaryMessage, buf[:n])
+			writeMu.Unlock()
+			if writeErr != nil {
+				break
+			}
+			received.Add(int64(n))
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				remoteErr = err
+			}
+			break
+		}
+	}
+
+	reason := ""
+	if remoteErr != nil {
+		reason = remoteErr.Error()
+		if len(reason) > 120 {
+			reason = reason[:120] // Close reasons are limited to 123 bytes
+		}
+	}
+	writeMu.Lock()
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason), time.Now().Add(wsWriteTimeout))
+	writeMu.Unlock()
+	finish()
+	return sent.Load(), received.Load(), remoteErr
+}
+
+// @Summary Proxy HTTP to a pod
+// @Description Forwards the request to a port of a pod through the Kubernetes portforward subresource, with the path after the port and the query string except cluster. The X-API-Key and Authorization headers are not forwarded, X-Forwarded-Prefix names the proxy path and redirects to absolute paths are rewritten under it. Requires the editor role.
+// @Tags kubernetes,pods
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Pod name"
+// @Param port path int true "Container port"
+// @Param path path string false "Path forwarded to the pod"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {string} string "Response of the pod"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /pods/{namespace}/{name}/proxy/{port}/{path} [get]
+func (s *apiServer) handlePodProxy(ctx *fasthttp.RequestCtx) {
+	s.serveProxy(ctx, "pods", false)
+}
+
+// @Summary Proxy HTTP to a service
+// @Description Like the pod proxy, to the target port of the first ready pod behind a service. The port is a service port number or name. Requires the editor role.
+// @Tags kubernetes,services
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Service name"
+// @Param port path string true "Service port number or name"
+// @Param path path string false "Path forwarded to the pod"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {string} string "Response of the pod"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /services/{namespace}/{name}/proxy/{port}/{path} [get]
+func (s *apiServer) handleServiceProxy(ctx *fasthttp.RequestCtx) {
+	s.serveProxy(ctx, "services", true)
+}
+
+func (s *apiServer) serveProxy(ctx *fasthttp.RequestCtx, resource string, service bool) {
+	logger := getRequestLogger(ctx)
+	if !s.portForwardEnabled(ctx) {
+		return
+	}
+	cfg := s.config.APIServer.PortForward
+
+	target := s.resolveForwardTarget(ctx, logger, service)
+	if target == nil || !s.acquirePortForward(ctx, logger) {
+		return
+	}
+	tun, remote, err := s.openForward(target)
+	if err != nil {
+		s.portForwards.Add(-1)
+		logger.Error().Err(err).Str("namespace", target.pod.Namespace).Str("pod", target.pod.Name).Msg("Failed to open port-forward")
+		ctx.SetStatusCode(fasthttp.StatusBadGateway)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to open port-forward"})
+		return
+	}
+
+	// The tunnel is closed once the response body is sent, or when the request takes too long
+	var releaseOnce sync.Once
+	var timer *time.Timer
+	release := func() {
+		releaseOnce.Do(func() {
+			if timer != nil {
+				timer.Stop()
+			}
+			remote.Close()
+			tun.Close()
+			s.portForwards.Add(-1)
+		})
+	}
+	if cfg.ProxyTimeout > 0 {
+		timer = time.AfterFunc(cfg.ProxyTimeout, release)
+	}
+
+	prefix := fmt.Sprintf("/%s/%s/%s/proxy/%s", resource, pathParam(ctx, "namespace"), pathParam(ctx, "name"), pathParam(ctx, "port"))
+	req, err := proxyRequest(ctx, prefix, target.port)
+	if err != nil {
+		release()
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err := req.Write(remote); err != nil {
+		release()
+		logger.Warn().Err(err).Msg("Failed to send proxied request")
+		ctx.SetStatusCode(fasthttp.StatusBadGateway)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to send request to pod"})
+		return
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(remote), req)
+	if err != nil {
+		release()
+		logger.Warn().Err(err).Str("pod", target.pod.Name).Int32("port", target.port).Msg("Failed to read proxied response")
+		ctx.SetStatusCode(fasthttp.StatusBadGateway)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to read response from pod: %v", err)})
+		return
+	}
+
+	ctx.SetStatusCode(resp.StatusCode)
+	for key, values := range resp.Header {
+		if isHopHeader(key) {
+			continue
+		}
+		for _, value := range values {
+			if key == "Location" && strings.HasPrefix(value, "/") && !strings.HasPrefix(value, "//") {
+				value = prefix + value
+			}
+			ctx.Response.Header.Add(key, value)
+		}
+	}
+	logger.Debug().
+		Str("cluster_id", target.cluster.ID).
+		Str("pod", target.pod.Name).
+		Int32("port", target.port).
+		Int("status", resp.StatusCode).
+		Msg("Proxied request to pod")
+	ctx.SetBodyStream(&releasingBody{ReadCloser: resp.Body, release: release}, int(resp.ContentLength))
+}
+
+// proxyRequest builds the request forwarded to the pod from the client's request
+func proxyRequest(ctx *fasthttp.RequestCtx, prefix string, port int32) (*http.Request, error) {
+	var query fasthttp.Args
+	ctx.QueryArgs().CopyTo(&query)
+	query.Del("cluster")
+
+	body := ctx.PostBody()
+	req, err := http.NewRequest(string(ctx.Method()), "/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Path = "/" + pathParam(ctx, "path")
+	req.URL.RawQuery = query.String()
+	req.ContentLength = int64(len(body))
+	req.Host = fmt.Sprintf("localhost:%d", port)
+	ctx.Request.Header.VisitAll(func(key, value []byte) {
+		if name := string(key); !isHopHeader(name) && name != "Host" && name != "Content-Length" {
+			req.Header.Add(name, string(value))
+		}
+	})
+	req.Header.Set("X-Forwarded-Prefix", prefix)
+	req.Header.Set("X-Forwarded-For", ctx.RemoteIP().String())
+	req.Close = true
+	return req, nil
+}
+
+func isHopHeader(name string) bool {
+	for _, h := range hopHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// releasingBody closes the tunnel once fasthttp has sent the response body
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
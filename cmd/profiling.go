@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http/pprof"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/runtimeinfo"
+)
+
+// pprofHandlers serve the profiles of net/http/pprof. Index also serves the named
+// profiles, such as heap and goroutine.
+var pprofHandlers = map[string]fasthttp.RequestHandler{
+	"cmdline": fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Cmdline),
+	"profile": fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Profile),
+	"symbol":  fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Symbol),
+	"trace":   fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Trace),
+}
+
+var pprofIndex = fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Index)
+
+// profilingEnabled reports whether the /debug endpoints are served. Profiles reveal the
+// command line, memory contents and code paths, so they are only served when requests
+// are authenticated; the default rules restrict /debug to admins.
+func (s *apiServer) profilingEnabled() bool {
+	if s.config == nil || !s.config.APIServer.Profiling.Enabled {
+		return false
+	}
+	if s.authorizer == nil {
+		log.Warn().Msg("Profiling is enabled but api_server.auth is not, /debug endpoints are not served")
+		return false
+	}
+	return true
+}
+
+// @Summary Go profiles
+// @Description Serves the net/http/pprof profiles: the index, heap, goroutine, allocs, block, mutex and threadcreate profiles, a CPU profile over ?seconds= and an execution trace. Registered only when api_server.profiling and authentication are enabled; requires the admin role.
+// @Tags system
+// @Produce octet-stream
+// @Param profile path string false "Profile name, empty for the index"
+// @Param seconds query int false "Duration of CPU profiles and traces"
+// @Success 200 {string} string
+// @Failure 403 {object} map[string]string
+// @Router /debug/pprof/{profile} [get]
+func (s *apiServer) handlePprof(ctx *fasthttp.RequestCtx) {
+	if handler, ok := pprofHandlers[pathParam(ctx, "profile")]; ok {
+		handler(ctx)
+		return
+	}
+	pprofIndex(ctx)
+}
+
+// @Summary Runtime statistics
+// @Description Returns a snapshot of the Go runtime in the spirit of expvar's /debug/vars: goroutines, heap and memory obtained from the OS, garbage collector activity and the GOGC and GOMEMLIMIT settings. Registered only when api_server.profiling and authentication are enabled; requires the admin role.
+// @Tags system
+// @Produce json
+// @Success 200 {object} runtimeinfo.Snapshot
+// @Failure 403 {object} map[string]string
+// @Router /debug/vars [get]
+func (s *apiServer) handleDebugVars(ctx *fasthttp.RequestCtx) {
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(runtimeinfo.Take())
+}
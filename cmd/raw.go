@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/rawapi"
+)
+
+// rawTarget is an allowlisted resource of a cluster, resolved through discovery
+type rawTarget struct {
+	ClusterID  string
+	GVR        schema.GroupVersionResource
+	Namespaced bool
+	Client     dynamic.NamespaceableResourceInterface
+}
+
+// lookupDynamicClient returns the dynamic client of a cluster
+func (s *apiServer) lookupDynamicClient(clusterID string) (dynamic.Interface, error) {
+	if clusterID == primaryClusterID {
+		if s.dynamicClient == nil {
+			return nil, errKubeClientUnavailable
+		}
+		return s.dynamicClient, nil
+	}
+	if s.multiClusterManager == nil {
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+	return s.multiClusterManager.GetDynamicClient(clusterID)
+}
+
+// resolveRawResource checks the path's resource against the allowlist and the cluster's
+// discovery API. It writes the error response and returns nil when the resource cannot be
+// served.
+func (s *apiServer) resolveRawResource(ctx *fasthttp.RequestCtx, logger zerolog.Logger) *rawTarget {
+	gvr := rawapi.Resource(pathParam(ctx, "group"), pathParam(ctx, "version"), pathParam(ctx, "resource"))
+	if !s.rawAllowlist.Allowed(gvr) {
+		ctx.SetStatusCode(fasthttp.StatusForbidden)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Resource %s is not allowed", rawapi.Key(gvr))})
+		return nil
+	}
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return nil
+	}
+	dynamicClient, err := s.lookupDynamicClient(target.ID)
+	if err != nil {
+		logger.Error().Err(err).Str("cluster_id", target.ID).Msg("Failed to create dynamic client")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to create dynamic client"})
+		return nil
+	}
+
+	resources, err := target.Client.Discovery().ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.Error().Err(err).Str("group_version", gvr.GroupVersion().String()).Msg("Failed to discover resources")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to discover resources"})
+		return nil
+	}
+	if resources != nil {
+		for _, r := range resources.APIResources {
+			if r.Name == gvr.Resource {
+				return &rawTarget{ClusterID: target.ID, GVR: gvr, Namespaced: r.Namespaced, Client: dynamicClient.Resource(gvr)}
+			}
+		}
+	}
+	ctx.SetStatusCode(fasthttp.StatusNotFound)
+	json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Cluster %s does not serve %s", target.ID, rawapi.Key(gvr))})
+	return nil
+}
+
+// @Summary List any resource
+// @Description Lists objects of any allowlisted resource, including custom resources, through the dynamic client. Use core as the group of core resources such as events. Objects are returned as the Kubernetes API serves them. Secrets are never served.
+// @Tags kubernetes,raw
+// @Produce json
+// @Param group path string true "API group, core for the core group"
+// @Param version path string true "API version, e.g. v1"
+// @Param resource path string true "Plural resource name, e.g. certificates"
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param namespace query string false "Namespace of namespaced resources, all namespaces when empty"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=web"
+// @Param fieldSelector query string false "Field selector, e.g. metadata.name=web"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /raw/{group}/{version}/{resource} [get]
+func (s *apiServer) handleRawList(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	target := s.resolveRawResource(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	namespace := getNamespaceFromQuery(ctx)
+	if namespace != "" && !target.Namespaced {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("%s is cluster-scoped", target.GVR.Resource)})
+		return
+	}
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	var client dynamic.ResourceInterface = target.Client
+	if target.Namespaced {
+		client = target.Client.Namespace(namespace)
+	}
+	list, err := client.List(requestContext(ctx), limitedOptions(ctx, listOpts))
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("resource", rawapi.Key(target.GVR)).Str("namespace", namespace).Msg("Failed to list resources")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to list %s", target.GVR.Resource)})
+		return
+	}
+
+	listMeta := metav1.ListMeta{Continue: list.GetContinue(), RemainingItemCount: list.GetRemainingItemCount()}
+	pageMetadata := paginationMetadata(ctx, listOpts, listMeta, len(list.Items))
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(list.Items))
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ClusterID, objects)) {
+			return
+		}
+	}
+
+	names := make([]string, 0, len(list.Items))
+	items := make([]map[string]interface{}, 0, len(list.Items))
+	for i := range list.Items {
+		names = append(names, list.Items[i].GetName())
+		items = append(items, list.Items[i].Object)
+	}
+	logger.Info().Int("count", len(items)).Str("resource", rawapi.Key(target.GVR)).Str("namespace", namespace).Msg("Resources retrieved")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if isSimpleFormat(ctx) {
+		writeSimpleJsonArray(ctx, names)
+		return
+	}
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster":   target.ClusterID,
+		"namespace": namespace,
+		"resource":  rawapi.Key(target.GVR),
+		"count":     len(items),
+		"source":    "kubernetes-api",
+		"names":     names,
+		"items":     items,
+		"metadata":  pageMetadata,
+	})
+}
+
+// @Summary Get any resource
+// @Description Returns one object of any allowlisted resource, including custom resources, as the Kubernetes API serves it. Namespaced resources require the namespace query parameter.
+// @Tags kubernetes,raw
+// @Produce json
+// @Param group path string true "API group, core for the core group"
+// @Param version path string true "API version, e.g. v1"
+// @Param resource path string true "Plural resource name, e.g. certificates"
+// @Param name path string true "Object name"
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param namespace query string false "Namespace, required for namespaced resources"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /raw/{group}/{version}/{resource}/{name} [get]
+func (s *apiServer) handleRawGet(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	name := pathParam(ctx, "name")
+
+	target := s.resolveRawResource(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	namespace := getNamespaceFromQuery(ctx)
+	var client dynamic.ResourceInterface = target.Client
+	switch {
+	case target.Namespaced && namespace == "":
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("namespace is required for %s", target.GVR.Resource)})
+		return
+	case target.Namespaced:
+		client = target.Client.Namespace(namespace)
+	case namespace != "":
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("%s is cluster-scoped", target.GVR.Resource)})
+		return
+	}
+
+	obj, err := client.Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("%s %s not found", target.GVR.Resource, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("resource", rawapi.Key(target.GVR)).Str("namespace", namespace).Str("name", name).Msg("Failed to get resource")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Failed to get %s", target.GVR.Resource)})
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(obj.Object)
+}
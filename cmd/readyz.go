@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ctrl"
+)
+
+// Readiness checks of the API server itself, next to the checks of every cluster's manager
+const (
+	checkInformerSync  = "informer-sync"  // The API server's informer caches have synced
+	checkKubernetesAPI = "kubernetes-api" // The primary cluster's API server answers
+	checkShutdown      = "shutdown"       // The API server is not draining for a shutdown
+)
+
+// readyzAPITimeout bounds the request to the primary cluster's API server
+const readyzAPITimeout = 2 * time.Second
+
+// @Summary Liveness probe
+// @Description Reports that the process is alive and serving requests. It never calls Kubernetes, so a slow or unreachable cluster does not get the pod restarted.
+// @Tags system
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /livez [get]
+func (s *apiServer) handleLivez(ctx *fasthttp.RequestCtx) {
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]string{"status": "alive"})
+}
+
+// handleReadyz reports ready only when the API server's informer caches have synced, the
+// primary cluster's API server answers, and the health and readiness checks of every
+// cluster's controller manager pass, so a single stuck manager is visible to probes and
+// load balancers
+func (s *apiServer) handleReadyz(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+	logger := getRequestLogger(ctx)
+
+	checks := []ctrl.ProbeCheck{
+		probeCheck(checkInformerSync, s.checkInformerSync()),
+		probeCheck(checkKubernetesAPI, s.checkKubernetesAPI()),
+		probeCheck(checkShutdown, s.checkShutdown()),
+	}
+	clusters := []ctrl.ClusterProbe{}
+	if s.multiClusterManager != nil {
+		clusters = s.multiClusterManager.Probe(ctx)
+	}
+
+	status := "ready"
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	for _, check := range checks {
+		if !check.OK {
+			status = "not_ready"
+			ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+			logger.Warn().Str("check", check.Name).Str("error", check.Error).Msg("API server is not ready")
+		}
+	}
+	for _, cluster := range clusters {
+		if !cluster.Healthy || !cluster.Ready {
+			status = "not_ready"
+			ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+			logger.Warn().Str("cluster_id", cluster.ClusterID).Msg("Cluster manager is not ready")
+		}
+	}
+
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"status":   status,
+		"checks":   checks,
+		"clusters": clusters,
+	})
+}
+
+func probeCheck(name string, err error) ctrl.ProbeCheck {
+	if err != nil {
+		return ctrl.ProbeCheck{Name: name, Error: err.Error()}
+	}
+	return ctrl.ProbeCheck{Name: name, OK: true}
+}
+
+// checkShutdown fails once the API server drains, so load balancers stop sending it requests
+func (s *apiServer) checkShutdown() error {
+	if s.drain.Draining() {
+		return errors.New("draining in-flight requests before shutdown")
+	}
+	return nil
+}
+
+// checkInformerSync fails while any informer started from the API server's factory, such as
+// those serving deployment lists and watch streams, has not synced. It does not wait.
+func (s *apiServer) checkInformerSync() error {
+	if s.informerFactory == nil {
+		return nil // Informer disabled, every request reads from the API
+	}
+	synced := make(chan struct{})
+	close(synced)
+	var pending []string
+	for informerType, ok := range s.informerFactory.WaitForCacheSync(synced) {
+		if !ok {
+			pending = append(pending, typeName(informerType))
+		}
+	}
+	if len(pending) > 0 {
+		sort.Strings(pending)
+		return fmt.Errorf("informer caches have not synced: %v", pending)
+	}
+	return nil
+}
+
+// checkKubernetesAPI fails when the primary cluster's API server does not answer in time
+func (s *apiServer) checkKubernetesAPI() error {
+	if s.clientset == nil {
+		return errors.New("no Kubernetes client, check the kubeconfig")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), readyzAPITimeout)
+	defer cancel()
+	if _, err := serverVersion(ctx, s.clientset); err != nil {
+		return fmt.Errorf("kubernetes API unreachable: %w", err)
+	}
+	return nil
+}
+
+// typeName returns the name of an informer's object type, e.g. Deployment
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
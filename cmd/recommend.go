@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/freeze"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/metricsapi"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/recommend"
+)
+
+// podDeploymentName derives the owning deployment from a pod name such as web-5d9c7b6f4-x2k8p
+func podDeploymentName(podName string, labels map[string]string) (string, bool) {
+	hash := labels["pod-template-hash"]
+	if hash == "" {
+		return "", false
+	}
+	idx := strings.LastIndex(podName, "-"+hash+"-")
+	if idx <= 0 {
+		return "", false
+	}
+	return podName[:idx], true
+}
+
+// metricsServerUsage reads current container usage of deployment pods from metrics-server
+func metricsServerUsage(clientset kubernetes.Interface) recommend.UsageSource {
+	return func(ctx context.Context) ([]recommend.Usage, error) {
+		pods, err := metricsapi.New(clientset.Discovery().RESTClient()).Pods(ctx, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("metrics-server is not available: %w", err)
+		}
+
+		var usage []recommend.Usage
+		for _, pod := range pods {
+			deployment, ok := podDeploymentName(pod.Name, pod.Labels)
+			if !ok {
+				continue
+			}
+			for _, c := range pod.Containers {
+				usage = append(usage, recommend.Usage{
+					Container:   recommend.Container{Namespace: pod.Namespace, Workload: deployment, Container: c.Name},
+					CPUMilli:    c.CPUMilli,
+					MemoryBytes: c.MemoryBytes,
+				})
+			}
+		}
+		return usage, nil
+	}
+}
+
+// deploymentSpecs returns configured requests and limits of deployment containers
+func (s *apiServer) deploymentSpecs(ctx context.Context) ([]recommend.Spec, error) {
+	deployments, err := s.allDeployments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []recommend.Spec
+	for _, d := range deployments {
+		for _, c := range d.Spec.Template.Spec.Containers {
+			specs = append(specs, recommend.Spec{
+				Container: recommend.Container{Namespace: d.Namespace, Workload: d.Name, Container: c.Name},
+				Resources: recommend.Resources{
+					CPURequestMilli:    c.Resources.Requests.Cpu().MilliValue(),
+					CPULimitMilli:      c.Resources.Limits.Cpu().MilliValue(),
+					MemoryRequestBytes: c.Resources.Requests.Memory().Value(),
+					MemoryLimitBytes:   c.Resources.Limits.Memory().Value(),
+				},
+			})
+		}
+	}
+	return specs, nil
+}
+
+// annotateDeployment writes recommendation annotations to a deployment. Annotations on the
+// deployment object do not change the pod template, so no rollout is triggered.
+func annotateDeployment(clientset kubernetes.Interface) recommend.Annotator {
+	return func(ctx context.Context, namespace, workload string, annotations map[string]string) error {
+		// Annotation writes are changes too and wait for freezes to end
+		if err := freeze.Check(primaryClusterID); err != nil {
+			return err
+		}
+
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": annotations},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = clientset.AppsV1().Deployments(namespace).Patch(ctx, workload, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	}
+}
+
+// @Summary Get resource right-sizing recommendations
+// @Description Compares observed container usage with configured requests and limits and recommends new values
+// @Tags recommendations
+// @Produce json
+// @Param namespace query string false "Only include workloads in this namespace"
+// @Param status query string false "Only include recommendations with this status (ok, over-provisioned, under-provisioned, missing-requests, insufficient-data)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /recommendations [get]
+func (s *apiServer) handleRecommendations(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	if s.recommender == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Resource recommendations are disabled"}`)
+		return
+	}
+
+	status := string(ctx.QueryArgs().Peek("status"))
+	recommendations := s.recommender.Recommendations(string(ctx.QueryArgs().Peek("namespace")))
+
+	items := make([]recommend.Recommendation, 0, len(recommendations))
+	totals := map[string]int{}
+	for _, rec := range recommendations {
+		totals[rec.Status]++
+		if status != "" && rec.Status != status {
+			continue
+		}
+		items = append(items, rec)
+	}
+
+	response := map[string]interface{}{
+		"count":     len(items),
+		"totals":    totals,
+		"items":     items,
+		"annotated": s.config.Recommendations.Annotate,
+	}
+	if last := s.recommender.LastSample(); !last.IsZero() {
+		response["last_sample"] = last.UTC().Format(time.RFC3339)
+	}
+
+	logger.Debug().Int("recommendations", len(items)).Msg("Resource recommendations returned")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotations the deployment controller sets on the ReplicaSets it manages
+const (
+	replicaSetRevisionAnnotation    = "deployment.kubernetes.io/revision"
+	replicaSetChangeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// replicaSetSummary describes a ReplicaSet, the Deployment owning it and the rollout
+// revision it holds
+type replicaSetSummary struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	UID               string            `json:"uid"`
+	ResourceVersion   string            `json:"resource_version"`
+	CreationTimestamp metav1.Time       `json:"creation_timestamp"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Deployment        string            `json:"deployment,omitempty"` // Owning Deployment
+	DeploymentUID     string            `json:"deployment_uid,omitempty"`
+	Revision          int64             `json:"revision,omitempty"`     // Rollout revision of the owning Deployment
+	ChangeCause       string            `json:"change_cause,omitempty"` // kubernetes.io/change-cause of the revision
+	TemplateHash      string            `json:"pod_template_hash,omitempty"`
+	Images            []string          `json:"images"`
+	Replicas          int32             `json:"replicas"`
+	ReadyReplicas     int32             `json:"ready_replicas"`
+	AvailableReplicas int32             `json:"available_replicas"`
+	Active            bool              `json:"active"` // Has desired replicas, false for old revisions kept for rollback
+}
+
+// newReplicaSetSummary reports a ReplicaSet's owner, revision, images and replica counts
+func newReplicaSetSummary(rs *appsv1.ReplicaSet) replicaSetSummary {
+	summary := replicaSetSummary{
+		Name:              rs.Name,
+		Namespace:         rs.Namespace,
+		UID:               string(rs.UID),
+		ResourceVersion:   rs.ResourceVersion,
+		CreationTimestamp: rs.CreationTimestamp,
+		Labels:            rs.Labels,
+		ChangeCause:       rs.Annotations[replicaSetChangeCauseAnnotation],
+		TemplateHash:      rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey],
+		Images:            make([]string, 0, len(rs.Spec.Template.Spec.Containers)),
+		Replicas:          rs.Status.Replicas,
+		ReadyReplicas:     rs.Status.ReadyReplicas,
+		AvailableReplicas: rs.Status.AvailableReplicas,
+	}
+	if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == "Deployment" {
+		summary.Deployment = owner.Name
+		summary.DeploymentUID = string(owner.UID)
+	}
+	if revision, err := strconv.ParseInt(rs.Annotations[replicaSetRevisionAnnotation], 10, 64); err == nil {
+		summary.Revision = revision
+	}
+	for _, c := range rs.Spec.Template.Spec.Containers {
+		summary.Images = append(summary.Images, c.Image)
+	}
+	if rs.Spec.Replicas == nil || *rs.Spec.Replicas > 0 {
+		summary.Active = true
+	}
+	return summary
+}
+
+// @Summary List ReplicaSets
+// @Description Lists ReplicaSets with their owning Deployment, the rollout revision and change cause from the deployment controller's annotations, images, and replica counts. With deployment, only ReplicaSets controlled by that Deployment are returned, newest revision first, which is its rollout history.
+// @Tags kubernetes,replicasets
+// @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param namespace query string false "Namespace, all namespaces when empty; required with deployment"
+// @Param deployment query string false "Only ReplicaSets owned by this Deployment"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=web"
+// @Param fieldSelector query string false "Field selector, e.g. metadata.name=web-5d8f7c9b4"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /replicasets [get]
+func (s *apiServer) handleReplicaSets(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	namespace := getNamespaceFromQuery(ctx)
+	deployment := string(ctx.QueryArgs().Peek("deployment"))
+	if deployment != "" && namespace == "" {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "namespace is required with deployment"})
+		return
+	}
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	replicaSets, err := target.Client.AppsV1().ReplicaSets(namespace).List(requestContext(ctx), limitedOptions(ctx, listOpts))
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Msg("Failed to list replica sets")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to list replica sets"})
+		return
+	}
+
+	pageMetadata := paginationMetadata(ctx, listOpts, replicaSets.ListMeta, len(replicaSets.Items))
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(replicaSets.Items))
+		for i := range replicaSets.Items {
+			objects = append(objects, &replicaSets.Items[i])
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ID, objects)) {
+			return
+		}
+	}
+
+	items := make([]replicaSetSummary, 0, len(replicaSets.Items))
+	for i := range replicaSets.Items {
+		summary := newReplicaSetSummary(&replicaSets.Items[i])
+		if deployment != "" && summary.Deployment != deployment {
+			continue
+		}
+		items = append(items, summary)
+	}
+	if deployment != "" {
+		// Rollout history: the current revision first
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Revision > items[j].Revision })
+	}
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	logger.Info().Int("count", len(items)).Str("namespace", namespace).Str("deployment", deployment).Msg("Replica sets retrieved")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if isSimpleFormat(ctx) {
+		writeSimpleJsonArray(ctx, names)
+		return
+	}
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster":   target.ID,
+		"namespace": namespace,
+		"count":     len(items),
+		"source":    "kubernetes-api",
+		"names":     names,
+		"items":     items,
+		"metadata":  pageMetadata,
+	})
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/drift"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/inventory"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/report"
+)
+
+// reportSources builds the rows of each report kind from this server's clusters and detectors
+func (s *apiServer) reportSources(pricing report.Pricing) map[string]report.Source {
+	return map[string]report.Source{
+		report.KindInventory: s.inventoryReport,
+		report.KindDrift:     s.driftReport,
+		report.KindSLO:       s.sloReport,
+		report.KindCost: func(ctx context.Context, def report.Definition) (*report.Report, error) {
+			return s.costReport(ctx, def, pricing)
+		},
+	}
+}
+
+// reportInventory collects the inventory rows of the definition's cluster and namespace
+func (s *apiServer) reportInventory(ctx context.Context, def report.Definition) ([]inventory.Row, error) {
+	clients, err := s.inventoryClients(def.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	var rows []inventory.Row
+	for clusterID, client := range clients {
+		clusterRows, err := inventory.Collect(ctx, client, clusterID, def.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", clusterID, err)
+		}
+		rows = append(rows, clusterRows...)
+	}
+	inventory.Sort(rows)
+	return rows, nil
+}
+
+// reportScope describes the clusters and namespace a definition covers
+func reportScope(def report.Definition) string {
+	scope := "all clusters"
+	if def.Cluster != "" {
+		scope = "cluster " + def.Cluster
+	}
+	if def.Namespace != "" {
+		scope += ", namespace " + def.Namespace
+	}
+	return scope
+}
+
+func (s *apiServer) inventoryReport(ctx context.Context, def report.Definition) (*report.Report, error) {
+	rows, err := s.reportInventory(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+	r := &report.Report{
+		Title:   "Workload inventory: " + reportScope(def),
+		Columns: []string{"cluster", "namespace", "kind", "name", "container", "image", "replicas", "cpu_request", "memory_request"},
+	}
+	workloads := make(map[string]bool)
+	for _, row := range rows {
+		workloads[row.Cluster+"/"+row.Namespace+"/"+row.Kind+"/"+row.Name] = true
+		r.Rows = append(r.Rows, []string{
+			row.Cluster, row.Namespace, row.Kind, row.Name, row.Container, row.Image,
+			strconv.Itoa(int(row.Replicas)), fmt.Sprintf("%dm", row.CPURequestMillis), fmt.Sprintf("%dMi", row.MemoryRequestBytes>>20),
+		})
+	}
+	r.Summary = []string{fmt.Sprintf("%d workloads with %d containers", len(workloads), len(rows))}
+	return r, nil
+}
+
+func (s *apiServer) driftReport(_ context.Context, def report.Definition) (*report.Report, error) {
+	if s.driftDetector == nil {
+		return nil, fmt.Errorf("drift detection is disabled")
+	}
+	r := &report.Report{
+		Title:   "Drift from desired state: " + reportScope(def),
+		Columns: []string{"namespace", "kind", "name", "status", "fields", "error"},
+	}
+	totals := make(map[string]int)
+	for _, res := range s.driftDetector.Reports(def.Namespace) {
+		totals[res.Status]++
+		if res.Status == drift.StatusInSync {
+			continue
+		}
+		fields := make([]string, 0, len(res.Diffs))
+		for _, d := range res.Diffs {
+			fields = append(fields, d.Path)
+		}
+		r.Rows = append(r.Rows, []string{res.Namespace, res.Kind, res.Name, res.Status, strings.Join(fields, ", "), res.Error})
+	}
+	r.Summary = []string{fmt.Sprintf("%d in sync, %d drifted, %d missing, %d errors",
+		totals[drift.StatusInSync], totals[drift.StatusDrifted], totals[drift.StatusMissing], totals[drift.StatusError])}
+	if last := s.driftDetector.LastCheck(); !last.IsZero() {
+		r.Summary = append(r.Summary, "Last checked "+last.UTC().Format(time.RFC3339))
+	}
+	return r, nil
+}
+
+// sloReport compares the share of available replicas of every deployment with the target
+// at the time the report is generated
+func (s *apiServer) sloReport(ctx context.Context, def report.Definition) (*report.Report, error) {
+	clients, err := s.inventoryClients(def.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	r := &report.Report{
+		Title:   fmt.Sprintf("Deployment availability against %g%%: %s", def.SLOTarget, reportScope(def)),
+		Columns: []string{"cluster", "namespace", "name", "desired", "available", "availability", "status"},
+	}
+	var met, total int
+	for clusterID, client := range clients {
+		list, err := client.AppsV1().Deployments(def.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: failed to list deployments: %w", clusterID, err)
+		}
+		for _, d := range list.Items {
+			desired := int32(1)
+			if d.Spec.Replicas != nil {
+				desired = *d.Spec.Replicas
+			}
+			if desired == 0 {
+				continue // Scaled to zero on purpose
+			}
+			availability := float64(d.Status.AvailableReplicas) / float64(desired) * 100
+			if availability > 100 {
+				availability = 100
+			}
+			status := "met"
+			if availability < def.SLOTarget {
+				status = "breached"
+			} else {
+				met++
+			}
+			total++
+			r.Rows = append(r.Rows, []string{
+				clusterID, d.Namespace, d.Name, strconv.Itoa(int(desired)), strconv.Itoa(int(d.Status.AvailableReplicas)),
+				strconv.FormatFloat(availability, 'f', 1, 64) + "%", status,
+			})
+		}
+	}
+	// Breaches first, then by cluster, namespace and name
+	sort.SliceStable(r.Rows, func(i, j int) bool {
+		a, b := r.Rows[i], r.Rows[j]
+		if a[6] != b[6] {
+			return a[6] == "breached"
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		if a[1] != b[1] {
+			return a[1] < b[1]
+		}
+		return a[2] < b[2]
+	})
+	r.Summary = []string{fmt.Sprintf("%d of %d deployments meet the %g%% availability target", met, total, def.SLOTarget)}
+	return r, nil
+}
+
+// costReport prices the CPU and memory requested by the workloads of every namespace
+func (s *apiServer) costReport(ctx context.Context, def report.Definition, pricing report.Pricing) (*report.Report, error) {
+	rows, err := s.reportInventory(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+	type namespaceCost struct {
+		cluster, namespace string
+		cpuCores, memGiB   float64
+	}
+	byNamespace := make(map[string]*namespaceCost)
+	var keys []string
+	for _, row := range rows {
+		key := row.Cluster + "/" + row.Namespace
+		c := byNamespace[key]
+		if c == nil {
+			c = &namespaceCost{cluster: row.Cluster, namespace: row.Namespace}
+			byNamespace[key] = c
+			keys = append(keys, key)
+		}
+		c.cpuCores += float64(row.CPURequestMillis) * float64(row.Replicas) / 1000
+		c.memGiB += float64(row.MemoryRequestBytes) * float64(row.Replicas) / (1 << 30)
+	}
+
+	r := &report.Report{
+		Title:   "Monthly cost of resource requests: " + reportScope(def),
+		Columns: []string{"cluster", "namespace", "cpu_cores", "memory_gib", "monthly_cost"},
+	}
+	costs := make(map[string]float64, len(keys))
+	var total float64
+	for _, key := range keys {
+		c := byNamespace[key]
+		costs[key] = c.cpuCores*pricing.CPUCoreMonthly + c.memGiB*pricing.MemoryGiBMonthly
+		total += costs[key]
+	}
+	// Most expensive namespaces first
+	sort.SliceStable(keys, func(i, j int) bool { return costs[keys[i]] > costs[keys[j]] })
+	for _, key := range keys {
+		c := byNamespace[key]
+		r.Rows = append(r.Rows, []string{
+			c.cluster, c.namespace, strconv.FormatFloat(c.cpuCores, 'f', 2, 64), strconv.FormatFloat(c.memGiB, 'f', 2, 64),
+			fmt.Sprintf("%.2f %s", costs[key], pricing.Currency),
+		})
+	}
+	r.Summary = []string{
+		fmt.Sprintf("Total %.2f %s per month across %d namespaces", total, pricing.Currency, len(keys)),
+		fmt.Sprintf("Priced at %.2f %s per CPU core and %.2f %s per GiB of memory per month",
+			pricing.CPUCoreMonthly, pricing.Currency, pricing.MemoryGiBMonthly, pricing.Currency),
+	}
+	return r, nil
+}
+
+// @Summary List scheduled reports
+// @Description Returns every configured report with its kind, schedule, delivery channels, next scheduled run and the outcome of its last run. Webhook URLs are not returned.
+// @Tags reports
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /reports [get]
+func (s *apiServer) handleReports(ctx *fasthttp.RequestCtx) {
+	if s.reports == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Scheduled reports are disabled"})
+		return
+	}
+
+	items := s.reports.Status(time.Now())
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"count": len(items),
+		"items": items,
+	})
+}
+
+// @Summary Run a scheduled report now
+// @Description Generates the report and delivers it through its configured channels immediately, outside its schedule, and returns the outcome. Channels that could be reached are listed in delivered even when another one failed.
+// @Tags reports
+// @Produce json
+// @Param name path string true "Report name"
+// @Success 200 {object} report.Run
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} report.Run
+// @Failure 503 {object} map[string]string
+// @Router /reports/{name}/run [post]
+func (s *apiServer) handleReportRun(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	name := pathParam(ctx, "name")
+
+	if s.reports == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Scheduled reports are disabled"})
+		return
+	}
+
+	run, err := s.reports.Run(requestContext(ctx), name, report.TriggerManual)
+	switch {
+	case errors.Is(err, report.ErrNotFound):
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Report %s not found", name)})
+		return
+	case errors.Is(err, report.ErrRunning):
+		ctx.SetStatusCode(fasthttp.StatusConflict)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Report %s is already running", name)})
+		return
+	case err != nil:
+		logger.Error().Err(err).Str("report", name).Msg("Manual report run failed")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+	default:
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	}
+	json.NewEncoder(ctx).Encode(run)
+}
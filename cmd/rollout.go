@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// rolloutDeployments lists the deployments of every cluster for stuck rollout detection
+func (s *apiServer) rolloutDeployments(ctx context.Context) (map[string][]appsv1.Deployment, error) {
+	clients, err := s.inventoryClients("")
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := make(map[string][]appsv1.Deployment, len(clients))
+	for clusterID, client := range clients {
+		list, err := client.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		deployments[clusterID] = list.Items
+	}
+	return deployments, nil
+}
+
+// @Summary List stuck rollouts
+// @Description Returns deployments whose Progressing condition reports ProgressDeadlineExceeded or whose rollout has not advanced within the configured window, longest stuck first
+// @Tags kubernetes,deployments
+// @Produce json
+// @Param cluster query string false "Only include deployments of this cluster"
+// @Param namespace query string false "Only include deployments in this namespace"
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /deployments/stuck [get]
+func (s *apiServer) handleStuckDeployments(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if s.stuckRollouts == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Stuck rollout detection is disabled"}`)
+		return
+	}
+
+	items := s.stuckRollouts.List(string(ctx.QueryArgs().Peek("cluster")), getNamespaceFromQuery(ctx))
+	response := map[string]interface{}{
+		"count": len(items),
+		"items": items,
+	}
+	if last := s.stuckRollouts.LastCheck(); !last.IsZero() {
+		response["last_check"] = last.UTC().Format(time.RFC3339)
+	}
+
+	logger.Debug().Int("deployments", len(items)).Msg("Stuck rollouts returned")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
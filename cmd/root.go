@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/obezsmertnyi/k8s-custom-controller/pkg/informer"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/redact"
 )
 
 var (
@@ -24,12 +25,17 @@ var (
 	leaderElectionNS     string
 	metricsPort          int
 	metricsBindAddress   string
+	healthProbeAddress   string
 	// kubeconfig defined in kubernetes.go
+
+	// logRedactor masks credentials in log output; redaction is on until the config says otherwise
+	logRedactor = redact.New(redact.Config{Enabled: true})
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "k8s-cli",
-	Short: "Kubernetes custom controller and CLI tool",
+	Use:     "k8s-cli",
+	Short:   "Kubernetes custom controller and CLI tool",
+	Version: appVersion,
 	Long: `k8s-cli is a CLI tool and custom controller for Kubernetes.
 
 It provides functionality for interacting with Kubernetes clusters,
@@ -57,6 +63,7 @@ Supports configuration via config files, command-line flags, and environment var
 		}
 		
 		// Configure logger with the correct parameters
+		logRedactor = redact.New(config.Logging.Redaction)
 		configureLogger(level, logFormat)
 		
 		// Now the logger works with the correct level
@@ -69,6 +76,7 @@ Supports configuration via config files, command-line flags, and environment var
 			config.Logging.Level = logLevel
 			level := parseLogLevel(logLevel)
 			log.Debug().Str("log_level", logLevel).Msg("Overriding log level from command line")
+			config.setFlagSource("logging.level", "log-level")
 			configureLogger(level, config.Logging.Format)
 		}
 
@@ -78,40 +86,50 @@ Supports configuration via config files, command-line flags, and environment var
 		if cmd.Flags().Changed("host") {
 			config.APIServer.Host = serverHost
 			log.Debug().Str("host", serverHost).Msg("Applied API server host from command line")
+			config.setFlagSource("api_server.host", "host")
 		}
 
 		if cmd.Flags().Changed("port") {
 			config.APIServer.Port = serverPort
 			log.Debug().Int("port", serverPort).Msg("Applied API server port from command line")
+			config.setFlagSource("api_server.port", "port")
 		}
 
 		if cmd.Flags().Changed("enable-swagger") {
 			config.APIServer.EnableSwagger = enableSwagger
-			// Also set the global variable used in api.go
-			enableSwagger = enableSwagger
 			log.Debug().Bool("enable_swagger", enableSwagger).Msg("Applied Swagger UI setting from command line")
+			config.setFlagSource("api_server.enable_swagger", "enable-swagger")
 		}
 
 		// Apply leader election settings
 		if cmd.Flags().Changed("enable-leader-election") {
 			config.ControllerRuntime.LeaderElection.Enabled = enableLeaderElection
 			log.Debug().Bool("enabled", enableLeaderElection).Msg("Applied leader election flag from command line")
+			config.setFlagSource("controller_runtime.leader_election.enabled", "enable-leader-election")
 		}
 
 		if cmd.Flags().Changed("leader-election-id") {
 			config.ControllerRuntime.LeaderElection.ID = leaderElectionID
 			log.Debug().Str("id", leaderElectionID).Msg("Applied leader election ID from command line")
+			config.setFlagSource("controller_runtime.leader_election.id", "leader-election-id")
 		}
 
 		if cmd.Flags().Changed("leader-election-namespace") {
 			config.ControllerRuntime.LeaderElection.Namespace = leaderElectionNS
 			log.Debug().Str("namespace", leaderElectionNS).Msg("Applied leader election namespace from command line")
+			config.setFlagSource("controller_runtime.leader_election.namespace", "leader-election-namespace")
 		}
 
 		// Apply metrics settings
 		if cmd.Flags().Changed("metrics-port") {
 			config.ControllerRuntime.Metrics.BindAddress = fmt.Sprintf("%s:%d", metricsBindAddress, metricsPort)
 			log.Debug().Str("bind_address", config.ControllerRuntime.Metrics.BindAddress).Msg("Applied metrics settings from command line")
+			config.setFlagSource("controller_runtime.metrics.bind_address", "metrics-port")
+		}
+		if cmd.Flags().Changed("health-probe-bind-address") {
+			config.ControllerRuntime.HealthProbe.BindAddress = healthProbeAddress
+			log.Debug().Str("bind_address", healthProbeAddress).Msg("Applied health probe settings from command line")
+			config.setFlagSource("controller_runtime.health_probe.bind_address", "health-probe-bind-address")
 		}
 
 		// Start all components (API server and informer)
@@ -154,7 +172,7 @@ func configureLogger(level zerolog.Level, format string) {
 	switch strings.ToLower(format) {
 	case "json":
 		// JSON format for structured logging (better for machine processing)
-		logger := zerolog.New(os.Stderr).With().Timestamp()
+		logger := zerolog.New(redact.NewWriter(logRedactor, os.Stderr)).With().Timestamp()
 		if level == zerolog.TraceLevel {
 			logger = logger.Caller()
 		}
@@ -177,14 +195,14 @@ func configureLogger(level zerolog.Level, format string) {
 				zerolog.CallerFieldName,
 				zerolog.MessageFieldName,
 			}
-			log.Logger = log.Output(console).With().Caller().Logger()
+			log.Logger = log.Output(redact.NewWriter(logRedactor, console)).With().Caller().Logger()
 		} else {
 			console.PartsOrder = []string{
 				zerolog.TimestampFieldName,
 				zerolog.LevelFieldName,
 				zerolog.MessageFieldName,
 			}
-			log.Logger = log.Output(console)
+			log.Logger = log.Output(redact.NewWriter(logRedactor, console))
 		}
 	}
 }
@@ -222,6 +240,7 @@ func init() {
 	// Add flags for metrics
 	rootCmd.Flags().IntVar(&metricsPort, "metrics-port", 8081, "Port for controller manager metrics")
 	rootCmd.Flags().StringVar(&metricsBindAddress, "metrics-bind-address", "0.0.0.0", "Bind address for metrics server")
+	rootCmd.Flags().StringVar(&healthProbeAddress, "health-probe-bind-address", ":8082", "Address for controller manager health probes, empty to disable")
 
 	rootCmd.AddCommand(ConfigCmd())
 
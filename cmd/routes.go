@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/fasthttp/router"
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+)
+
+// routePatternKey is the user value holding the pattern of the route a request matched
+const routePatternKey = "route_pattern"
+
+// route is a registered method and path pattern
+type route struct {
+	Method string
+	Path   string
+}
+
+// apiRouter registers the built-in endpoints on a fasthttp/router. Routes switched off
+// by api_server.endpoints.disabled are answered as though they did not exist, with 404
+// rather than 405, and every GET route answers HEAD as well.
+type apiRouter struct {
+	mux      *router.Router
+	patterns *router.Router // Same routes, each only recording its pattern, for labeling requests before dispatch
+	routes   []route
+	disabled func(method, path string) bool
+}
+
+func newAPIRouter(disabled func(method, path string) bool) *apiRouter {
+	mux := router.New()
+	// A path differing from a route only in its trailing slash is redirected to it; other
+	// paths are matched exactly, and OPTIONS is not answered automatically
+	mux.RedirectFixedPath = false
+	mux.HandleOPTIONS = false
+	return &apiRouter{mux: mux, patterns: router.New(), disabled: disabled}
+}
+
+// GET registers a handler for GET and HEAD requests
+func (r *apiRouter) GET(path string, handler fasthttp.RequestHandler) {
+	r.Handle(fasthttp.MethodGet, path, handler)
+}
+
+// POST registers a handler for POST requests
+func (r *apiRouter) POST(path string, handler fasthttp.RequestHandler) {
+	r.Handle(fasthttp.MethodPost, path, handler)
+}
+
+// PUT registers a handler for PUT requests
+func (r *apiRouter) PUT(path string, handler fasthttp.RequestHandler) {
+	r.Handle(fasthttp.MethodPut, path, handler)
+}
+
+// PATCH registers a handler for PATCH requests
+func (r *apiRouter) PATCH(path string, handler fasthttp.RequestHandler) {
+	r.Handle(fasthttp.MethodPatch, path, handler)
+}
+
+// DELETE registers a handler for DELETE requests
+func (r *apiRouter) DELETE(path string, handler fasthttp.RequestHandler) {
+	r.Handle(fasthttp.MethodDelete, path, handler)
+}
+
+// Handle registers a handler for a method and path pattern, or the not-found answer
+// when the route is disabled
+func (r *apiRouter) Handle(method, path string, handler fasthttp.RequestHandler) {
+	methods := []string{method}
+	if method == fasthttp.MethodGet {
+		methods = append(methods, fasthttp.MethodHead)
+	}
+	if r.disabled != nil && r.disabled(method, path) {
+		for _, m := range methods {
+			r.mux.Handle(m, path, r.notFound)
+		}
+		return
+	}
+	for _, m := range methods {
+		r.mux.Handle(m, path, handler)
+		r.patterns.Handle(m, path, func(ctx *fasthttp.RequestCtx) {
+			ctx.SetUserValue(routePatternKey, path)
+		})
+	}
+	r.routes = append(r.routes, route{Method: method, Path: path})
+}
+
+// Routes returns the enabled routes in registration order
+func (r *apiRouter) Routes() []route {
+	return append([]route(nil), r.routes...)
+}
+
+// Pattern returns the pattern of the route serving a method and path, or an empty
+// string when no enabled route serves it
+func (r *apiRouter) Pattern(ctx *fasthttp.RequestCtx, method, path string) string {
+	if record, _ := r.patterns.Lookup(method, path, nil); record != nil {
+		record(ctx)
+	}
+	pattern, _ := ctx.UserValue(routePatternKey).(string)
+	return pattern
+}
+
+// Handler dispatches a request to its route
+func (r *apiRouter) Handler(ctx *fasthttp.RequestCtx) {
+	r.mux.Handler(ctx)
+}
+
+// notFound gives registered handler plugins a chance to serve the path before answering 404
+func (r *apiRouter) notFound(ctx *fasthttp.RequestCtx) {
+	if handler, ok := plugin.LookupHandler(string(ctx.Method()), string(ctx.Path())); ok {
+		handler(ctx)
+		return
+	}
+	ctx.SetStatusCode(fasthttp.StatusNotFound)
+	json.NewEncoder(ctx).Encode(map[string]string{"error": "Not found"})
+}
+
+// routes returns the router of the built-in endpoints, building it on first use
+func (s *apiServer) routes() *apiRouter {
+	s.routerOnce.Do(func() {
+		s.router = s.newRouter()
+	})
+	return s.router
+}
+
+// newRouter registers the built-in endpoints. Handlers serving several methods still
+// switch on the method themselves; the router answers every other method with 405.
+func (s *apiServer) newRouter() *apiRouter {
+	r := newAPIRouter(s.endpoints.Disabled)
+
+	r.GET("/swagger", s.swagger(s.redirectSwaggerUI))
+	r.GET("/swagger/index.html", s.swagger(s.serveSwaggerUI))
+	r.GET("/swagger/swagger.json", s.swagger(s.handleSwaggerJSON))
+	r.GET("/swagger.json", s.swagger(s.handleSwaggerJSON))
+
+	r.GET("/health", s.handleHealth)
+	r.GET("/version", s.handleVersion)
+	r.GET("/livez", s.handleLivez)
+	r.GET("/readyz", s.handleReadyz)
+	r.GET("/csrf", s.handleCSRFToken)
+	r.GET("/config", s.handleConfig)
+	if s.config != nil && s.config.APIServer.Metrics.Enabled {
+		r.GET("/metrics", s.handleMetrics)
+	}
+	if s.profilingEnabled() {
+		r.GET("/debug/vars", s.handleDebugVars)
+		r.GET("/debug/pprof", s.handlePprof)
+		r.GET("/debug/pprof/{profile:*}", s.handlePprof)
+	}
+
+	r.GET("/clusters", s.handleClusters)
+	r.POST("/clusters", s.handleClusters)
+	r.DELETE("/clusters", s.handleClusters)
+	r.GET("/clusters/{id}/leader", func(ctx *fasthttp.RequestCtx) {
+		s.handleClusterLeader(ctx, pathParam(ctx, "id"))
+	})
+	r.GET("/clusters/{id}/events", func(ctx *fasthttp.RequestCtx) {
+		s.handleClusterEvents(ctx, pathParam(ctx, "id"))
+	})
+	r.GET("/clusters/{id}/upgrade-check", func(ctx *fasthttp.RequestCtx) {
+		s.handleClusterUpgradeCheck(ctx, pathParam(ctx, "id"))
+	})
+
+	r.GET("/deployments", s.handleDeployments)
+	r.POST("/deployments", s.handleDeployments)
+	r.DELETE("/deployments", s.handleDeployments)
+	r.GET("/deployments/watch", s.handleDeploymentsWatch)
+	r.GET("/deployments/stuck", s.handleStuckDeployments)
+	r.GET("/deployments/{namespace}/{name}", s.handleDeploymentDetail)
+	r.DELETE("/deployments/{namespace}/{name}", s.handleDeploymentDelete)
+	r.PATCH("/deployments/{namespace}/{name}/scale", s.handleDeploymentScale)
+	r.GET("/deployments/{namespace}/{name}/timeline", s.handleDeploymentTimeline)
+	r.GET("/ws", s.handleWebSocket)
+	r.GET("/firehose", s.handleFirehose)
+	r.GET("/pods", s.handlePods)
+	r.GET("/pods/pending", s.handlePendingPods)
+	r.GET("/pods/metrics", s.handlePodMetrics)
+	r.GET("/pods/{namespace}/{name}/why-pending", s.handlePodWhyPending)
+	r.GET("/pods/{namespace}/{name}/diagnosis", s.handlePodDiagnosis)
+	r.GET("/pods/{namespace}/{name}/logs", s.handlePodLogs)
+	r.GET("/pods/{namespace}/{name}/exec", s.handlePodExec)
+	r.GET("/pods/{namespace}/{name}/portforward/{port}", s.handlePodPortForward)
+	r.GET("/services/{namespace}/{name}/portforward/{port}", s.handleServicePortForward)
+	for _, method := range proxyMethods {
+		r.Handle(method, "/pods/{namespace}/{name}/proxy/{port}", s.handlePodProxy)
+		r.Handle(method, "/pods/{namespace}/{name}/proxy/{port}/{path:*}", s.handlePodProxy)
+		r.Handle(method, "/services/{namespace}/{name}/proxy/{port}", s.handleServiceProxy)
+		r.Handle(method, "/services/{namespace}/{name}/proxy/{port}/{path:*}", s.handleServiceProxy)
+	}
+	r.GET("/services", s.handleServices)
+	r.GET("/nodes", s.handleNodes)
+	r.GET("/nodes/metrics", s.handleNodeMetrics)
+	r.GET("/nodes/{name}", s.handleNodeDetail)
+	r.GET("/namespaces", s.handleNamespaces)
+	r.GET("/namespaces/summary", s.handleNamespaceSummaries)
+	r.GET("/overview", s.handleOverview)
+	r.GET("/compare", s.handleCompare)
+	r.GET("/reports", s.handleReports)
+	r.POST("/reports/{name}/run", s.handleReportRun)
+	r.GET("/configmaps", s.handleConfigMaps)
+	r.GET("/configmaps/{namespace}/{name}", s.handleConfigMapDetail)
+	r.GET("/jobs", s.handleJobs)
+	r.GET("/jobs/{namespace}/{name}", s.handleJobDetail)
+	r.GET("/cronjobs", s.handleCronJobs)
+	r.GET("/cronjobs/{namespace}/{name}", s.handleCronJobDetail)
+	r.POST("/cronjobs/{namespace}/{name}/trigger", s.handleCronJobTrigger)
+	r.GET("/hpa", s.handleHPAs)
+	r.GET("/replicasets", s.handleReplicaSets)
+	if s.secretsEnabled() {
+		r.GET("/secrets", s.handleSecrets)
+		r.GET("/secrets/{namespace}/{name}", s.handleSecretDetail)
+	}
+	if s.rawAllowlist != nil {
+		r.GET("/raw/{group}/{version}/{resource}", s.handleRawList)
+		r.GET("/raw/{group}/{version}/{resource}/{name}", s.handleRawGet)
+	}
+
+	r.GET("/export/inventory", s.handleExportInventory)
+	r.GET("/security/images", s.handleSecurityImages)
+	r.GET("/security/exposure", s.handleSecurityExposure)
+	r.GET("/security/workloads", s.handleSecurityWorkloads)
+	r.GET("/capacity/forecast", s.handleCapacityForecast)
+	r.GET("/history/deployments/{namespace}/{name}", s.handleDeploymentHistory)
+	r.GET("/history/nodes/{name}", s.handleNodeHistory)
+	r.GET("/recommendations", s.handleRecommendations)
+	r.GET("/deprecations", s.handleDeprecations)
+	r.POST("/labels/apply", s.handleLabelsApply)
+	r.GET("/drift", s.handleDrift)
+	r.POST("/drift/bundles", s.handleDriftBundles)
+	r.PUT("/drift/bundles", s.handleDriftBundles)
+	r.DELETE("/drift/bundles", s.handleDriftBundles)
+	r.GET("/environments", s.handleEnvironments)
+	r.POST("/environments", s.handleEnvironmentCreate)
+	r.GET("/environments/{name}", s.handleEnvironment)
+	r.DELETE("/environments/{name}", s.handleEnvironmentDelete)
+	r.GET("/migrate", s.handleMigrations)
+	r.POST("/migrate", s.handleMigrate)
+	r.GET("/migrate/{id}", s.handleMigration)
+	r.GET("/standby", s.handleStandby)
+	r.POST("/failover", s.handleFailover)
+
+	r.GET("/admin/scopes", s.handleAdminScopes)
+	r.GET("/admin/security/events", s.handleAdminSecurityEvents)
+	r.GET(debugCapturePath, s.handleAdminDebugCaptures)
+	r.POST(debugCapturePath, s.handleAdminDebugCaptures)
+	r.DELETE(debugCapturePath, s.handleAdminDebugCaptures)
+
+	r.mux.NotFound = r.notFound
+	r.mux.MethodNotAllowed = func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+	}
+	return r
+}
+
+// pathParam returns a parameter matched from the route pattern, e.g. {namespace}
+func pathParam(ctx *fasthttp.RequestCtx, name string) string {
+	value, _ := ctx.UserValue(name).(string)
+	return value
+}
+
+// swagger serves the Swagger endpoints only while they are enabled
+func (s *apiServer) swagger(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if s.config == nil || !s.config.APIServer.EnableSwagger {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": "Not found"})
+			return
+		}
+		handler(ctx)
+	}
+}
+
+// redirectSwaggerUI sends /swagger, and /swagger/ through the router's redirect, to the UI page
+func (s *apiServer) redirectSwaggerUI(ctx *fasthttp.RequestCtx) {
+	ctx.Redirect("/swagger/index.html", fasthttp.StatusFound)
+}
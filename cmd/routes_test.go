@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// serveRoute runs a request through the router and returns its context
+func serveRoute(r *apiRouter, method, path string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(path)
+	r.Handler(ctx)
+	return ctx
+}
+
+// namedHandler returns a handler that writes its name
+func namedHandler(name string) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) { ctx.SetBodyString(name) }
+}
+
+func TestAPIRouter_StaticAndParams(t *testing.T) {
+	r := newAPIRouter(nil)
+	r.GET("/deployments", namedHandler("list"))
+	r.GET("/deployments/watch", namedHandler("watch"))
+	r.GET("/deployments/{namespace}/{name}", func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyString(pathParam(ctx, "namespace") + "/" + pathParam(ctx, "name"))
+	})
+	r.GET("/pods/{namespace}/{name}/proxy/{port}/{path:*}", func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyString(pathParam(ctx, "path"))
+	})
+
+	assert.Equal(t, "list", string(serveRoute(r, "GET", "/deployments").Response.Body()))
+	assert.Equal(t, "watch", string(serveRoute(r, "GET", "/deployments/watch").Response.Body()))
+	assert.Equal(t, "default/web", string(serveRoute(r, "GET", "/deployments/default/web?x=1").Response.Body()))
+	assert.Equal(t, "watch/web", string(serveRoute(r, "GET", "/deployments/watch/web").Response.Body()))
+	assert.Equal(t, "api/v1/status", string(serveRoute(r, "GET", "/pods/prod/web/proxy/8080/api/v1/status").Response.Body()))
+
+	// HEAD is served by the GET handler
+	assert.Equal(t, fasthttp.StatusOK, serveRoute(r, "HEAD", "/deployments").Response.StatusCode())
+
+	// A trailing slash is redirected, other unknown paths fall through to 404
+	ctx := serveRoute(r, "GET", "/deployments/")
+	assert.Equal(t, fasthttp.StatusMovedPermanently, ctx.Response.StatusCode())
+	assert.Equal(t, fasthttp.StatusNotFound, serveRoute(r, "GET", "/deployments/default").Response.StatusCode())
+}
+
+func TestAPIRouter_MethodNotAllowed(t *testing.T) {
+	r := newAPIRouter(nil)
+	r.GET("/clusters", namedHandler("list"))
+	r.POST("/clusters", namedHandler("add"))
+
+	ctx := serveRoute(r, "DELETE", "/clusters")
+	assert.Equal(t, fasthttp.StatusMethodNotAllowed, ctx.Response.StatusCode())
+	assert.Contains(t, string(ctx.Response.Header.Peek("Allow")), "GET, HEAD")
+	assert.Contains(t, string(ctx.Response.Header.Peek("Allow")), "POST")
+}
+
+func TestAPIRouter_Disabled(t *testing.T) {
+	r := newAPIRouter(func(method, path string) bool {
+		return method == fasthttp.MethodDelete || path == "/secrets"
+	})
+	r.GET("/clusters", namedHandler("list"))
+	r.DELETE("/clusters", namedHandler("remove"))
+	r.GET("/secrets", namedHandler("secrets"))
+
+	// Disabled routes answer as though they did not exist
+	assert.Equal(t, fasthttp.StatusNotFound, serveRoute(r, "DELETE", "/clusters").Response.StatusCode())
+	assert.Equal(t, fasthttp.StatusNotFound, serveRoute(r, "GET", "/secrets").Response.StatusCode())
+	assert.Equal(t, fasthttp.StatusNotFound, serveRoute(r, "HEAD", "/secrets").Response.StatusCode())
+	assert.Equal(t, "list", string(serveRoute(r, "GET", "/clusters").Response.Body()))
+	assert.Equal(t, []route{{Method: "GET", Path: "/clusters"}}, r.Routes())
+}
+
+func TestAPIRouter_Pattern(t *testing.T) {
+	r := newAPIRouter(func(method, path string) bool { return path == "/secrets" })
+	r.GET("/deployments/{namespace}/{name}", namedHandler("detail"))
+	r.GET("/secrets", namedHandler("secrets"))
+
+	// Patterns are found without running the handler
+	ctx := &fasthttp.RequestCtx{}
+	assert.Equal(t, "/deployments/{namespace}/{name}", r.Pattern(ctx, "GET", "/deployments/default/web"))
+	assert.Empty(t, ctx.Response.Body())
+	assert.Equal(t, "/deployments/{namespace}/{name}", r.Pattern(&fasthttp.RequestCtx{}, "HEAD", "/deployments/default/web"))
+
+	assert.Empty(t, r.Pattern(&fasthttp.RequestCtx{}, "POST", "/deployments/default/web"))
+	assert.Empty(t, r.Pattern(&fasthttp.RequestCtx{}, "GET", "/secrets"))
+	assert.Empty(t, r.Pattern(&fasthttp.RequestCtx{}, "GET", "/unknown"))
+}
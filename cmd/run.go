@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Variables for run debug command
+var (
+	debugImage          string
+	debugNode           string
+	debugRemove         bool
+	debugStdin          bool
+	debugTTY            bool
+	debugHostNamespaces bool
+	debugStartupTimeout time.Duration
+)
+
+const (
+	debugPodNamePrefix   = "k8s-cli-debug-"
+	debugContainerName   = "debug"
+	debugManagedByLabel  = "app.kubernetes.io/managed-by"
+	debugManagedByValue  = "k8s-cli"
+	debugNodeHostnameKey = "kubernetes.io/hostname"
+)
+
+// getKubeRestConfig builds a REST config from the provided kubeconfig path
+func getKubeRestConfig(kubeconfigPath string) (*rest.Config, error) {
+	return clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+}
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run ephemeral workloads in the cluster",
+}
+
+// runDebugCmd represents the run debug command
+var runDebugCmd = &cobra.Command{
+	Use:   "debug [-- command args...]",
+	Short: "Start an ephemeral debugging pod, optionally pinned to a node",
+	Long: `Start an ephemeral debugging pod in the specified namespace.
+
+The pod can be pinned to a node with --node (via node affinity) and can share
+the node's network, PID and IPC namespaces when --host-namespaces is set.
+With -i/-t the terminal is attached to the pod, and with --rm the pod is
+deleted when the session ends or the command is interrupted.`,
+	Example: `  k8s-cli run debug --image busybox --rm -it --node worker-1
+  k8s-cli run debug --image nicolaka/netshoot --rm -it --host-namespaces --node worker-1 -- bash`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateDebugFlags(); err != nil {
+			return err
+		}
+
+		restConfig, err := getKubeRestConfig(kubeconfig)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to build Kubernetes client config")
+			return err
+		}
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create Kubernetes client")
+			return err
+		}
+
+		command := []string{"sh"}
+		if len(args) > 0 {
+			command = args
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		pod := buildDebugPod(command)
+		created, err := clientset.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+		if err != nil {
+			log.Error().Err(err).Str("namespace", namespace).Msg("Failed to create debug pod")
+			return err
+		}
+
+		log.Info().
+			Str("name", created.Name).
+			Str("namespace", created.Namespace).
+			Str("image", debugImage).
+			Str("node", debugNode).
+			Bool("host_namespaces", debugHostNamespaces).
+			Msg("Debug pod created")
+
+		// Make sure the pod is removed on every exit path when --rm is set
+		if debugRemove {
+			defer deleteDebugPod(clientset, created.Namespace, created.Name)
+		}
+
+		if err := waitForDebugPod(ctx, clientset, created.Namespace, created.Name); err != nil {
+			log.Error().Err(err).Str("name", created.Name).Msg("Debug pod did not become ready")
+			return err
+		}
+
+		if !debugStdin && !debugTTY {
+			fmt.Printf("pod/%s started in namespace %s\n", created.Name, created.Namespace)
+			return nil
+		}
+
+		return attachDebugPod(ctx, restConfig, clientset, created.Namespace, created.Name)
+	},
+}
+
+// validateDebugFlags rejects --rm without a session to end it: a detached pod would be
+// deleted as soon as it started
+func validateDebugFlags() error {
+	if debugRemove && !debugStdin && !debugTTY {
+		return fmt.Errorf("--rm requires -i or -t, since the pod is deleted when the attached session ends")
+	}
+	return nil
+}
+
+// buildDebugPod creates the Pod object for a debugging session
+func buildDebugPod(command []string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      debugPodNamePrefix + uuid.New().String()[:8],
+			Namespace: namespace,
+			Labels: map[string]string{
+				debugManagedByLabel: debugManagedByValue,
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    debugContainerName,
+					Image:   debugImage,
+					Command: command,
+					Stdin:   debugStdin,
+					TTY:     debugTTY,
+				},
+			},
+			// Debug pods must be schedulable on tainted nodes as well
+			Tolerations: []corev1.Toleration{
+				{Operator: corev1.TolerationOpExists},
+			},
+		},
+	}
+
+	// Pin the pod to the requested node via node affinity
+	if debugNode != "" {
+		pod.Spec.Affinity = &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{
+							MatchExpressions: []corev1.NodeSelectorRequirement{
+								{
+									Key:      debugNodeHostnameKey,
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{debugNode},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// Host namespaces are only shared when explicitly requested
+	if debugHostNamespaces {
+		privileged := true
+		pod.Spec.HostNetwork = true
+		pod.Spec.HostPID = true
+		pod.Spec.HostIPC = true
+		pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+			Privileged: &privileged,
+		}
+	}
+
+	return pod
+}
+
+// waitForDebugPod waits until the debug pod is running or has failed
+func waitForDebugPod(ctx context.Context, clientset kubernetes.Interface, ns, name string) error {
+	return wait.PollUntilContextTimeout(ctx, time.Second, debugStartupTimeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := clientset.CoreV1().Pods(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		switch pod.Status.Phase {
+		case corev1.PodRunning:
+			return true, nil
+		case corev1.PodSucceeded, corev1.PodFailed:
+			return false, fmt.Errorf("pod %s/%s terminated with phase %s", ns, name, pod.Status.Phase)
+		}
+
+		log.Debug().Str("name", name).Str("phase", string(pod.Status.Phase)).Msg("Waiting for debug pod")
+		return false, nil
+	})
+}
+
+// attachDebugPod attaches the local terminal to the debug container
+func attachDebugPod(ctx context.Context, restConfig *rest.Config, clientset kubernetes.Interface, ns, name string) error {
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(ns).
+		Name(name).
+		SubResource("attach").
+		VersionedParams(&corev1.PodAttachOptions{
+			Container: debugContainerName,
+			Stdin:     debugStdin,
+			Stdout:    true,
+			Stderr:    !debugTTY,
+			TTY:       debugTTY,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(restConfig, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create attach executor: %w", err)
+	}
+
+	// Put the local terminal into raw mode so control sequences reach the pod
+	stdinFd := int(os.Stdin.Fd())
+	if debugTTY && term.IsTerminal(stdinFd) {
+		oldState, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return fmt.Errorf("failed to set terminal raw mode: %w", err)
+		}
+		defer term.Restore(stdinFd, oldState)
+	}
+
+	streamOptions := remotecommand.StreamOptions{
+		Stdout: os.Stdout,
+		Tty:    debugTTY,
+	}
+	if debugStdin {
+		streamOptions.Stdin = os.Stdin
+	}
+	if !debugTTY {
+		streamOptions.Stderr = os.Stderr
+	}
+
+	return executor.StreamWithContext(ctx, streamOptions)
+}
+
+// deleteDebugPod removes the debug pod, ignoring the already-canceled command context
+func deleteDebugPod(clientset kubernetes.Interface, ns, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	gracePeriod := int64(0)
+	err := clientset.CoreV1().Pods(ns).Delete(ctx, name, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+	if err != nil {
+		log.Error().Err(err).Str("name", name).Str("namespace", ns).Msg("Failed to delete debug pod")
+		return
+	}
+
+	log.Info().Str("name", name).Str("namespace", ns).Msg("Debug pod deleted")
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.AddCommand(runDebugCmd)
+
+	runDebugCmd.Flags().StringVar(&kubeconfig, "kubeconfig", getDefaultKubeconfig(), "Path to the kubeconfig file (default: ~/.kube/config)")
+	runDebugCmd.Flags().StringVar(&namespace, "namespace", "default", "Kubernetes namespace")
+	runDebugCmd.Flags().StringVar(&debugImage, "image", "busybox", "Container image for the debug pod")
+	runDebugCmd.Flags().StringVar(&debugNode, "node", "", "Node to schedule the debug pod on")
+	runDebugCmd.Flags().BoolVar(&debugRemove, "rm", false, "Delete the debug pod when the session ends (requires -i or -t)")
+	runDebugCmd.Flags().BoolVarP(&debugStdin, "stdin", "i", false, "Keep stdin open and attach to the debug pod")
+	runDebugCmd.Flags().BoolVarP(&debugTTY, "tty", "t", false, "Allocate a TTY for the debug container")
+	runDebugCmd.Flags().BoolVar(&debugHostNamespaces, "host-namespaces", false, "Share the node's network, PID and IPC namespaces (runs privileged)")
+	runDebugCmd.Flags().DurationVar(&debugStartupTimeout, "timeout", 2*time.Minute, "Time to wait for the debug pod to start")
+}
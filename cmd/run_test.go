@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// setDebugFlags sets the run debug flags for a test and restores them afterwards
+func setDebugFlags(t *testing.T, node string, hostNamespaces, stdin, tty, remove bool) {
+	savedNamespace, savedImage, savedNode := namespace, debugImage, debugNode
+	savedHost, savedStdin, savedTTY, savedRemove := debugHostNamespaces, debugStdin, debugTTY, debugRemove
+	t.Cleanup(func() {
+		namespace, debugImage, debugNode = savedNamespace, savedImage, savedNode
+		debugHostNamespaces, debugStdin, debugTTY, debugRemove = savedHost, savedStdin, savedTTY, savedRemove
+	})
+	namespace, debugImage = "tools", "busybox"
+	debugNode, debugHostNamespaces = node, hostNamespaces
+	debugStdin, debugTTY, debugRemove = stdin, tty, remove
+}
+
+// TestBuildDebugPod tests the debug pod's command, node pinning and host namespaces
+func TestBuildDebugPod(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		setDebugFlags(t, "", false, true, true, false)
+		pod := buildDebugPod([]string{"sh"})
+
+		assert.True(t, strings.HasPrefix(pod.Name, debugPodNamePrefix))
+		assert.Equal(t, "tools", pod.Namespace)
+		assert.Equal(t, debugManagedByValue, pod.Labels[debugManagedByLabel])
+		assert.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+		require.Len(t, pod.Spec.Containers, 1)
+		container := pod.Spec.Containers[0]
+		assert.Equal(t, "busybox", container.Image)
+		assert.Equal(t, []string{"sh"}, container.Command)
+		assert.True(t, container.Stdin)
+		assert.True(t, container.TTY)
+		assert.Nil(t, container.SecurityContext)
+		assert.Nil(t, pod.Spec.Affinity)
+		assert.False(t, pod.Spec.HostNetwork || pod.Spec.HostPID || pod.Spec.HostIPC)
+		assert.Equal(t, []corev1.Toleration{{Operator: corev1.TolerationOpExists}}, pod.Spec.Tolerations)
+	})
+
+	t.Run("command", func(t *testing.T) {
+		setDebugFlags(t, "", false, false, false, false)
+		pod := buildDebugPod([]string{"ping", "-c", "3", "10.0.0.1"})
+
+		assert.Equal(t, []string{"ping", "-c", "3", "10.0.0.1"}, pod.Spec.Containers[0].Command)
+		assert.False(t, pod.Spec.Containers[0].Stdin)
+		assert.NotEqual(t, pod.Name, buildDebugPod(nil).Name, "every pod gets its own name")
+	})
+
+	t.Run("node", func(t *testing.T) {
+		setDebugFlags(t, "worker-1", false, true, true, false)
+		pod := buildDebugPod([]string{"sh"})
+
+		require.NotNil(t, pod.Spec.Affinity)
+		terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		require.Len(t, terms, 1)
+		assert.Equal(t, []corev1.NodeSelectorRequirement{{
+			Key:      debugNodeHostnameKey,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{"worker-1"},
+		}}, terms[0].MatchExpressions)
+	})
+
+	t.Run("host namespaces", func(t *testing.T) {
+		setDebugFlags(t, "worker-1", true, true, true, false)
+		pod := buildDebugPod([]string{"bash"})
+
+		assert.True(t, pod.Spec.HostNetwork)
+		assert.True(t, pod.Spec.HostPID)
+		assert.True(t, pod.Spec.HostIPC)
+		require.NotNil(t, pod.Spec.Containers[0].SecurityContext)
+		assert.True(t, *pod.Spec.Containers[0].SecurityContext.Privileged)
+	})
+}
+
+// TestValidateDebugFlags tests that --rm is only accepted for attached sessions
+func TestValidateDebugFlags(t *testing.T) {
+	tests := []struct {
+		name               string
+		stdin, tty, remove bool
+		wantErr            bool
+	}{
+		{"detached", false, false, false, false},
+		{"rm detached", false, false, true, true},
+		{"rm with stdin", true, false, true, false},
+		{"rm with tty", false, true, true, false},
+		{"rm interactive", true, true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setDebugFlags(t, "", false, tt.stdin, tt.tty, tt.remove)
+			err := validateDebugFlags()
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "--rm requires -i or -t")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
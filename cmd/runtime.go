@@ -9,10 +9,15 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/freeze"
 	"github.com/obezsmertnyi/k8s-custom-controller/pkg/informer"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ownership"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/tracing"
 )
 
 // StartComponents initializes and runs all enabled components (informer and API server)
@@ -31,10 +36,48 @@ func StartComponents(config *Config) error {
 
 	log.Debug().Bool("api_server_enabled", apiServerEnabled).Bool("informer_enabled", informerEnabled).Msg("Component activation status")
 
+	// Load external plugins before any component registers routes or controllers
+	if len(config.Plugins.Paths) > 0 {
+		if err := plugin.LoadFiles(config.Plugins.Paths); err != nil {
+			log.Error().Err(err).Msg("Failed to load plugins")
+			return err
+		}
+	}
+
+	// Validate change-freeze windows so a bad schedule fails fast
+	freezeChecker, err := freeze.NewChecker(config.Freeze)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid freeze configuration")
+		return err
+	}
+	freeze.SetDefault(freezeChecker)
+
+	// Trace requests, Kubernetes API calls and reconciles when a collector is configured
+	if config.Tracing.Enabled {
+		if config.Tracing.Endpoint == "" {
+			// Fall back to the standard OpenTelemetry variable
+			config.Tracing.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		}
+		provider, err := tracing.NewProvider(config.Tracing)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid tracing configuration")
+			return err
+		}
+		tracing.SetDefault(provider)
+		defer func() {
+			// Export the spans of the final requests before exiting
+			flushCtx, cancel := context.WithTimeout(context.Background(), max(config.Tracing.Timeout, tracing.DefaultTimeout))
+			defer cancel()
+			if err := provider.Shutdown(flushCtx); err != nil {
+				log.Warn().Err(err).Msg("Failed to export remaining spans")
+			}
+		}()
+		log.Info().Str("endpoint", config.Tracing.Endpoint).Float64("sample_ratio", config.Tracing.SampleRatio).Msg("Tracing enabled")
+	}
+
 	// Always initialize Kubernetes client for both CLI commands and services
 	var clientset *kubernetes.Clientset
 	var factory informers.SharedInformerFactory
-	var err error
 
 	// Initialize kubernetes client
 	log.Debug().Msg("Initializing Kubernetes client")
@@ -72,6 +115,18 @@ func StartComponents(config *Config) error {
 
 	log.Debug().Msg("Successfully connected to Kubernetes cluster")
 
+	// Resolve namespace ownership from configuration and namespace annotations; the cluster
+	// managers add the getters of the clusters they run
+	resolver := ownership.NewResolver(config.Ownership)
+	resolver.SetGetter(primaryClusterID, func(ctx context.Context, name string) (map[string]string, error) {
+		ns, err := clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return ns.Annotations, nil
+	})
+	ownership.SetDefault(resolver)
+
 	// Create shared informer factory only if informer is enabled
 	var factoryOptions []informers.SharedInformerOption
 
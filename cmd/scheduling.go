@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/scheduling"
+)
+
+// whyPendingResponse is the response of GET /pods/{namespace}/{name}/why-pending
+type whyPendingResponse struct {
+	Cluster string `json:"cluster"`
+	scheduling.Analysis
+	UnboundClaims []string `json:"unbound_claims,omitempty"` // PersistentVolumeClaims of the pod that are not Bound
+}
+
+// pendingPodsResponse is the response of GET /pods/pending
+type pendingPodsResponse struct {
+	Cluster   string                `json:"cluster"`
+	Namespace string                `json:"namespace"`
+	Count     int                   `json:"count"`
+	ByReason  map[string]int        `json:"by_reason"` // Pending pods per cause; a pod with several causes counts for each
+	Items     []scheduling.Analysis `json:"items"`
+}
+
+// failedSchedulingSelector selects FailedScheduling events, optionally of one pod
+func failedSchedulingSelector(pod *corev1.Pod) string {
+	set := fields.Set{"reason": "FailedScheduling"}
+	if pod != nil {
+		set["involvedObject.kind"] = "Pod"
+		set["involvedObject.name"] = pod.Name
+		set["involvedObject.uid"] = string(pod.UID)
+	}
+	return fields.SelectorFromSet(set).String()
+}
+
+// unboundClaims returns the PersistentVolumeClaims the pod mounts that are missing or not Bound
+func unboundClaims(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod) ([]string, error) {
+	var claims []string
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		name := volume.PersistentVolumeClaim.ClaimName
+		pvc, err := client.CoreV1().PersistentVolumeClaims(pod.Namespace).Get(ctx, name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			claims = append(claims, name+" (not found)")
+		case err != nil:
+			return nil, err
+		case pvc.Status.Phase != corev1.ClaimBound:
+			claims = append(claims, fmt.Sprintf("%s (%s)", name, pvc.Status.Phase))
+		}
+	}
+	return claims, nil
+}
+
+// @Summary Explain why a pod is pending
+// @Description Classifies the scheduler's latest FailedScheduling message into causes such as insufficient CPU or memory, taints, affinity and volume binding, with an explanation of each. Scheduled pods that have not started report their containers' waiting reasons instead. Pods that are not Pending return pending=false.
+// @Tags kubernetes,pods
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Pod name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {object} whyPendingResponse
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /pods/{namespace}/{name}/why-pending [get]
+func (s *apiServer) handlePodWhyPending(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	pod, err := target.Client.CoreV1().Pods(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Pod %s/%s not found", namespace, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get pod")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get pod"})
+		return
+	}
+
+	response := whyPendingResponse{Cluster: target.ID}
+	if pod.Status.Phase == corev1.PodPending {
+		events, err := target.Client.CoreV1().Events(namespace).List(requestContext(ctx), metav1.ListOptions{FieldSelector: failedSchedulingSelector(pod)})
+		if err != nil {
+			// The pod status alone still explains most failures
+			logger.Warn().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to list scheduling events")
+			events = &corev1.EventList{}
+		}
+		response.Analysis = scheduling.Analyze(pod, events.Items, time.Now())
+
+		if !response.Scheduled {
+			claims, err := unboundClaims(requestContext(ctx), target.Client, pod)
+			if err != nil {
+				logger.Warn().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to check persistent volume claims")
+			}
+			response.UnboundClaims = claims
+			if len(claims) > 0 && !hasCause(response.Causes, scheduling.CauseVolumeBinding) {
+				response.Causes = append(response.Causes, scheduling.Cause{
+					Reason:      scheduling.CauseVolumeBinding,
+					Detail:      strings.Join(claims, ", "),
+					Explanation: scheduling.Explanation(scheduling.CauseVolumeBinding),
+				})
+			}
+		}
+	} else {
+		response.Analysis = scheduling.Analyze(pod, nil, time.Now())
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
+
+// @Summary Summarize pending pods
+// @Description Lists the Pending pods of a cluster with the causes the scheduler gave for each, and counts pods per cause.
+// @Tags kubernetes,pods
+// @Produce json
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Param namespace query string false "Namespace, all namespaces when empty"
+// @Success 200 {object} pendingPodsResponse
+// @Failure 500 {object} map[string]string
+// @Router /pods/pending [get]
+func (s *apiServer) handlePendingPods(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+	namespace := getNamespaceFromQuery(ctx)
+
+	pods, err := target.Client.CoreV1().Pods(namespace).List(requestContext(ctx), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("status.phase", string(corev1.PodPending)).String(),
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Msg("Failed to list pending pods")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to list pending pods"})
+		return
+	}
+
+	// One list of events instead of one per pod; matched to pods by UID
+	eventsByPod := make(map[string][]corev1.Event)
+	if len(pods.Items) > 0 {
+		events, err := target.Client.CoreV1().Events(namespace).List(requestContext(ctx), metav1.ListOptions{FieldSelector: failedSchedulingSelector(nil)})
+		if err != nil {
+			logger.Warn().Err(err).Str("namespace", namespace).Msg("Failed to list scheduling events")
+		} else {
+			for _, e := range events.Items {
+				uid := string(e.InvolvedObject.UID)
+				eventsByPod[uid] = append(eventsByPod[uid], e)
+			}
+		}
+	}
+
+	now := time.Now()
+	response := pendingPodsResponse{
+		Cluster:   target.ID,
+		Namespace: namespace,
+		ByReason:  make(map[string]int),
+		Items:     make([]scheduling.Analysis, 0, len(pods.Items)),
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		analysis := scheduling.Analyze(pod, eventsByPod[string(pod.UID)], now)
+		for _, cause := range analysis.Causes {
+			response.ByReason[cause.Reason]++
+		}
+		response.Items = append(response.Items, analysis)
+	}
+	// Longest pending first
+	sort.SliceStable(response.Items, func(i, j int) bool {
+		return response.Items[i].PendingSeconds > response.Items[j].PendingSeconds
+	})
+	response.Count = len(response.Items)
+	logger.Info().Int("count", response.Count).Str("namespace", namespace).Msg("Pending pods analyzed")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
+
+func hasCause(causes []scheduling.Cause, reason string) bool {
+	for _, c := range causes {
+		if c.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/valyala/fasthttp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// secretSummary describes a Secret without any of its values. Annotations are left out
+// because kubectl.kubernetes.io/last-applied-configuration holds the full data.
+type secretSummary struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	UID               string            `json:"uid"`
+	ResourceVersion   string            `json:"resource_version"`
+	CreationTimestamp metav1.Time       `json:"creation_timestamp"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Type              corev1.SecretType `json:"type"`
+	Immutable         bool              `json:"immutable"`
+	Keys              []string          `json:"keys"`
+}
+
+// secretDetail is the response of GET /secrets/{namespace}/{name}
+type secretDetail struct {
+	Cluster string `json:"cluster"`
+	secretSummary
+}
+
+// newSecretSummary copies a Secret's metadata and sorted key names, never its values
+func newSecretSummary(secret *corev1.Secret) secretSummary {
+	summary := secretSummary{
+		Name:              secret.Name,
+		Namespace:         secret.Namespace,
+		UID:               string(secret.UID),
+		ResourceVersion:   secret.ResourceVersion,
+		CreationTimestamp: secret.CreationTimestamp,
+		Labels:            secret.Labels,
+		Type:              secret.Type,
+		Immutable:         secret.Immutable != nil && *secret.Immutable,
+		Keys:              make([]string, 0, len(secret.Data)),
+	}
+	for key := range secret.Data {
+		summary.Keys = append(summary.Keys, key)
+	}
+	sort.Strings(summary.Keys)
+	return summary
+}
+
+// secretsEnabled reports whether /secrets is served; hardened deployments switch it off
+func (s *apiServer) secretsEnabled() bool {
+	return s.config == nil || s.config.APIServer.Secrets.Enabled
+}
+
+// @Summary List Secrets
+// @Description Lists Secrets of a namespace, or of all namespaces, with their metadata, type and key names. Values are never returned.
+// @Tags kubernetes,secrets
+// @Produce json
+// @Param cluster query string false "Target cluster ID, defaults to the primary cluster"
+// @Param namespace query string false "Namespace, all namespaces when empty"
+// @Param limit query int false "Maximum number of items to return (1-1000)"
+// @Param continue query string false "Continue token from the previous page"
+// @Param labelSelector query string false "Label selector, e.g. app=web,tier!=cache"
+// @Param fieldSelector query string false "Field selector, e.g. type=kubernetes.io/tls"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /secrets [get]
+func (s *apiServer) handleSecrets(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	namespace := getNamespaceFromQuery(ctx)
+	listOpts, err := s.listOptions(ctx)
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	secrets, err := target.Client.CoreV1().Secrets(namespace).List(requestContext(ctx), limitedOptions(ctx, listOpts))
+	if writeContinueExpired(ctx, err) || writeInvalidSelector(ctx, err) {
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Msg("Failed to list secrets")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to list secrets"})
+		return
+	}
+
+	pageMetadata := paginationMetadata(ctx, listOpts, secrets.ListMeta, len(secrets.Items))
+	if !isPaginated(listOpts) {
+		objects := make([]metav1.Object, 0, len(secrets.Items))
+		for i := range secrets.Items {
+			objects = append(objects, &secrets.Items[i])
+		}
+		if writeNotModified(ctx, listETag(ctx, target.ID, objects)) {
+			return
+		}
+	}
+
+	names := make([]string, 0, len(secrets.Items))
+	items := make([]secretSummary, 0, len(secrets.Items))
+	for i := range secrets.Items {
+		names = append(names, secrets.Items[i].Name)
+		items = append(items, newSecretSummary(&secrets.Items[i]))
+	}
+	logger.Info().Int("count", len(items)).Str("namespace", namespace).Msg("Secrets retrieved")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if isSimpleFormat(ctx) {
+		writeSimpleJsonArray(ctx, names)
+		return
+	}
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster":   target.ID,
+		"namespace": namespace,
+		"count":     len(items),
+		"source":    "kubernetes-api",
+		"names":     names,
+		"items":     items,
+		"metadata":  pageMetadata,
+	})
+}
+
+// @Summary Get a Secret
+// @Description Returns a Secret's metadata, type and key names. Values are never returned.
+// @Tags kubernetes,secrets
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Secret name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Success 200 {object} secretDetail
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /secrets/{namespace}/{name} [get]
+func (s *apiServer) handleSecretDetail(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	secret, err := target.Client.CoreV1().Secrets(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Secret %s/%s not found", namespace, name)})
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get secret")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get secret"})
+		return
+	}
+
+	if writeNotModified(ctx, listETag(ctx, target.ID, []metav1.Object{secret})) {
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(secretDetail{Cluster: target.ID, secretSummary: newSecretSummary(secret)})
+}
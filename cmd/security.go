@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/informer"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/vulnscan"
+)
+
+// allDeployments returns deployments of the primary cluster from the informer cache,
+// falling back to the API when the cache is empty or unavailable
+func (s *apiServer) allDeployments(ctx context.Context) ([]*appsv1.Deployment, error) {
+	var deployments []*appsv1.Deployment
+	if s.informerFactory != nil {
+		cached, err := informer.ListDeploymentsInCache(s.informerFactory.Apps().V1().Deployments().Informer(), "")
+		if err != nil {
+			return nil, err
+		}
+		deployments = cached
+	}
+
+	if len(deployments) == 0 && s.clientset != nil {
+		list, err := s.clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			deployments = append(deployments, &list.Items[i])
+		}
+	}
+	return deployments, nil
+}
+
+// deploymentImages maps images referenced by cached deployments to the deployments using them
+func (s *apiServer) deploymentImages(ctx context.Context) (map[string][]string, error) {
+	deployments, err := s.allDeployments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[string][]string)
+	for _, d := range deployments {
+		workload := d.Namespace + "/" + d.Name
+		containers := append(append([]corev1.Container{}, d.Spec.Template.Spec.InitContainers...), d.Spec.Template.Spec.Containers...)
+		for _, c := range containers {
+			if c.Image != "" && !slices.Contains(images[c.Image], workload) {
+				images[c.Image] = append(images[c.Image], workload)
+			}
+		}
+	}
+	return images, nil
+}
+
+// @Summary Get image vulnerability findings
+// @Description Returns the latest Trivy scan results for images used by running deployments
+// @Tags security
+// @Produce json
+// @Param severity query string false "Only include findings with this severity, e.g. CRITICAL"
+// @Param image query string false "Only include images containing this substring"
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /security/images [get]
+func (s *apiServer) handleSecurityImages(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	if s.imageScanner == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Image scanning is disabled"}`)
+		return
+	}
+
+	severity := strings.ToUpper(string(ctx.QueryArgs().Peek("severity")))
+	imageFilter := string(ctx.QueryArgs().Peek("image"))
+
+	reports := s.imageScanner.Reports()
+	items := make([]vulnscan.ImageReport, 0, len(reports))
+	totals := map[string]int{}
+	for _, report := range reports {
+		if imageFilter != "" && !strings.Contains(report.Image, imageFilter) {
+			continue
+		}
+		if severity != "" {
+			findings := make([]vulnscan.Finding, 0, len(report.Findings))
+			for _, f := range report.Findings {
+				if f.Severity == severity {
+					findings = append(findings, f)
+				}
+			}
+			report.Findings = findings
+		}
+		for sev, count := range report.Counts {
+			totals[sev] += count
+		}
+		items = append(items, report)
+	}
+
+	response := map[string]interface{}{
+		"count":  len(items),
+		"totals": totals,
+		"items":  items,
+	}
+	if last := s.imageScanner.LastScan(); !last.IsZero() {
+		response["last_scan"] = last.UTC().Format(time.RFC3339)
+	}
+
+	logger.Debug().Int("images", len(items)).Msg("Image vulnerability report returned")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/smoke"
+)
+
+// smokeCmd validates a live deployment of the API server
+func smokeCmd() *cobra.Command {
+	var (
+		opts     smoke.Options
+		url      string
+		token    string
+		timeout  time.Duration
+		insecure bool
+		output   string
+	)
+	cmd := &cobra.Command{
+		Use:   "smoke",
+		Short: "Check the core endpoints of a running API server",
+		Long: `Check the core endpoints of a running API server and print a pass/fail matrix.
+
+The checks call /health, /readyz, /clusters and /deployments, then relabel the
+deployments matching --selector in dry-run mode, which goes through
+authorization, change freezes and admission without persisting anything. The
+token therefore needs the editor role. The command exits non-zero when any
+check fails, for post-deploy validation in CD pipelines.`,
+		Example: `  k8s-cli smoke --url https://kcc.example.com --token "$TOKEN"
+  K8S_CLI_TOKEN="$TOKEN" k8s-cli smoke --url https://kcc.example.com --namespace payments -o json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "" && output != outputJSON {
+				return fmt.Errorf("unknown output format %q, expected json", output)
+			}
+			if token == "" {
+				token = os.Getenv("K8S_CLI_TOKEN")
+			}
+			opts.RunID = uuid.New().String()
+
+			client := &http.Client{Timeout: timeout}
+			if insecure {
+				client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+			}
+			runner := &smoke.Runner{URL: url, Token: token, Client: client}
+			results := runner.Run(context.Background(), smoke.CoreChecks(opts))
+
+			if output == outputJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(map[string]interface{}{"url": url, "passed": smoke.Passed(results), "checks": results}); err != nil {
+					return err
+				}
+			} else if err := printSmokeResults(results); err != nil {
+				return err
+			}
+
+			if !smoke.Passed(results) {
+				cmd.SilenceUsage = true
+				return errors.New("smoke checks failed")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&url, "url", "", "Base URL of the API server, e.g. https://kcc.example.com (required)")
+	cmd.Flags().StringVar(&token, "token", "", "Bearer token with the editor role (default $K8S_CLI_TOKEN)")
+	cmd.Flags().StringVar(&opts.Namespace, "namespace", "default", "Namespace of the deployments list and the dry-run relabeling")
+	cmd.Flags().StringVar(&opts.Cluster, "cluster", "", "Cluster ID to check, empty for the primary cluster")
+	cmd.Flags().StringVar(&opts.Selector, "selector", "app", "Label selector of the deployments relabeled in dry-run mode")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout of each check")
+	cmd.Flags().BoolVar(&insecure, "insecure-skip-tls-verify", false, "Skip verification of the server's certificate")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format: json (default table)")
+	cmd.MarkFlagRequired("url")
+	return cmd
+}
+
+// printSmokeResults prints one row per check, followed by the errors of failed checks
+func printSmokeResults(results []smoke.Result) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tREQUEST\tSTATUS\tRESULT\tLATENCY\tREQUEST ID")
+	for _, result := range results {
+		status, verdict := "-", "FAIL"
+		if result.Status != 0 {
+			status = fmt.Sprintf("%d", result.Status)
+		}
+		if result.Passed {
+			verdict = "PASS"
+		}
+		fmt.Fprintf(w, "%s\t%s %s\t%s\t%s\t%dms\t%s\n", result.Name, result.Method, result.Path, status, verdict,
+			result.LatencyMs, optionCell(result.RequestID))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if !result.Passed {
+			fmt.Printf("\n%s: %s", result.Name, result.Error)
+		}
+	}
+	if !smoke.Passed(results) {
+		fmt.Println()
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(smokeCmd())
+}
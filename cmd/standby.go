@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/valyala/fasthttp"
+	"k8s.io/client-go/kubernetes"
+)
+
+// clusterClient returns the client of a registered cluster
+func (s *apiServer) clusterClient(clusterID string) (kubernetes.Interface, error) {
+	clients, err := s.inventoryClients(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return clients[clusterID], nil
+}
+
+// @Summary Get warm standby status
+// @Description Returns the source and standby clusters, the mirrored namespaces, the number of deployments mirrored by the latest pass, when it last succeeded and its error, and whether the standby has failed over.
+// @Tags kubernetes
+// @Produce json
+// @Success 200 {object} migrate.StandbyStatus
+// @Failure 503 {object} map[string]string
+// @Router /standby [get]
+func (s *apiServer) handleStandby(ctx *fasthttp.RequestCtx) {
+	if s.standby == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Warm standby is not enabled"})
+		return
+	}
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(s.standby.Status())
+}
+
+// @Summary Fail over to the warm standby cluster
+// @Description Stops mirroring and scales every mirrored deployment on the standby cluster to the replicas of its source. Only the standby cluster is contacted. The namespaces are marked as failed over, so mirroring does not resume after a restart. Calling it again retries deployments that could not be scaled. Change freezes on the standby cluster apply.
+// @Tags kubernetes
+// @Produce json
+// @Success 200 {object} migrate.FailoverResult
+// @Failure 423 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /failover [post]
+func (s *apiServer) handleFailover(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if s.standby == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Warm standby is not enabled"})
+		return
+	}
+
+	target := s.standby.Status().Target
+	if !s.checkClusterFreeze(ctx, target, "POST", string(ctx.Path()), logger) {
+		return
+	}
+
+	result, err := s.standby.Failover(requestContext(ctx))
+	if err != nil {
+		logger.Error().Err(err).Str("target", target).Msg("Failover failed")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failover failed: " + err.Error()})
+		return
+	}
+	logger.Warn().
+		Str("target", target).
+		Int("activated", result.Activated).
+		Int("failed", result.Failed).
+		Msg("Failed over to the warm standby cluster")
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(result)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // ?tz= must resolve zone names in images without a zoneinfo database
+
+	"github.com/valyala/fasthttp"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// timeFormat rewrites the timestamps of a JSON response into a requested time zone
+// (?tz=Europe/Kyiv) or as durations relative to the time of the request (?relative=true)
+type timeFormat struct {
+	location *time.Location // nil keeps the zone the handler rendered
+	relative bool
+	now      time.Time
+}
+
+// parseTimeFormat reads ?tz= and ?relative=. It returns nil when neither is set.
+func parseTimeFormat(ctx *fasthttp.RequestCtx) (*timeFormat, error) {
+	tz := string(ctx.QueryArgs().Peek("tz"))
+	relative := ctx.QueryArgs().GetBool("relative")
+	if tz == "" && !relative {
+		return nil, nil
+	}
+
+	f := &timeFormat{relative: relative, now: time.Now()}
+	if tz != "" {
+		// Local is rejected, it would expose and depend on the server's zone
+		location, err := time.LoadLocation(tz)
+		if err != nil || strings.EqualFold(tz, "local") {
+			return nil, fmt.Errorf("invalid tz %q: use an IANA zone name such as Europe/Berlin or UTC", tz)
+		}
+		f.location = location
+	}
+	return f, nil
+}
+
+// formatAge renders how long ago a time was, in the style of kubectl's AGE column. Future
+// times are prefixed with "in".
+func formatAge(t, now time.Time) string {
+	if t.After(now.Add(time.Second)) {
+		return "in " + duration.HumanDuration(t.Sub(now))
+	}
+	return duration.HumanDuration(now.Sub(t))
+}
+
+// render rewrites a buffered JSON response body. Like renderYAML, it leaves streams,
+// WebSocket upgrades, empty bodies and other content types untouched.
+func (f *timeFormat) render(ctx *fasthttp.RequestCtx) {
+	if ctx.Response.IsBodyStream() || ctx.Hijacked() || len(ctx.Response.Body()) == 0 {
+		return
+	}
+	if !strings.HasPrefix(string(ctx.Response.Header.ContentType()), "application/json") {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(ctx.Response.Body()))
+	decoder.UseNumber() // Keep large integers such as byte counts exact
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		logger := getRequestLogger(ctx)
+		logger.Warn().Err(err).Msg("Failed to localize response timestamps")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(f.convert(body)); err != nil {
+		return
+	}
+	ctx.SetBody(buf.Bytes())
+}
+
+// convert rewrites timestamp values below v. Labels and annotations are user data and are
+// never rewritten, even when a value looks like a timestamp.
+func (f *timeFormat) convert(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if key == "labels" || key == "annotations" {
+				continue
+			}
+			if s, ok := value.(string); ok && isTimestampKey(key) {
+				v[key] = f.format(s)
+				continue
+			}
+			v[key] = f.convert(value)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = f.convert(v[i])
+		}
+	}
+	return v
+}
+
+// format renders one RFC 3339 timestamp; other strings are returned as they are
+func (f *timeFormat) format(value string) string {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return value
+	}
+	if f.relative {
+		return formatAge(t, f.now)
+	}
+	layout := time.RFC3339
+	if strings.Contains(value, ".") {
+		layout = time.RFC3339Nano
+	}
+	return t.In(f.location).Format(layout)
+}
+
+// isTimestampKey reports whether a JSON key names a point in time, such as created,
+// creationTimestamp, lastTransitionTime, expiresAt or last_seen_at
+func isTimestampKey(key string) bool {
+	switch key {
+	case "created", "time", "timestamp", "since", "until":
+		return true
+	}
+	for _, suffix := range []string{"Timestamp", "_timestamp", "Time", "_time", "At", "_at"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
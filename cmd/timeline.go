@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/audit"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/stream"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/timeline"
+)
+
+const (
+	defaultTimelineLimit = 200
+	maxTimelineLimit     = 1000
+)
+
+// timelineResponse is the response of GET /deployments/{namespace}/{name}/timeline
+type timelineResponse struct {
+	Cluster   string           `json:"cluster"`
+	Namespace string           `json:"namespace"`
+	Name      string           `json:"name"`
+	Count     int              `json:"count"`
+	Truncated bool             `json:"truncated"`          // Older entries were left out to respect the limit
+	Warnings  []string         `json:"warnings,omitempty"` // Sources that could not be read
+	Entries   []timeline.Entry `json:"entries"`
+}
+
+// timelineLimitFromQuery parses ?limit= of a timeline request
+func timelineLimitFromQuery(ctx *fasthttp.RequestCtx) (int, error) {
+	raw := string(ctx.QueryArgs().Peek("limit"))
+	if raw == "" {
+		return defaultTimelineLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxTimelineLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxTimelineLimit)
+	}
+	return limit, nil
+}
+
+// watchedDeploymentChanges returns the versions of a deployment the primary cluster's
+// informer has observed and still keeps
+func (s *apiServer) watchedDeploymentChanges(target *clusterTarget, namespace, name string) []timeline.Change {
+	source := s.watchSources["Deployment"]
+	if !target.isPrimary() || source == nil {
+		return nil
+	}
+	events := source.events.History(func(e stream.Event) bool {
+		return e.Namespace == namespace && e.Name == name
+	})
+	changes := make([]timeline.Change, 0, len(events))
+	for _, e := range events {
+		deployment, ok := e.Object.(*appsv1.Deployment)
+		if !ok {
+			continue
+		}
+		changes = append(changes, timeline.Change{Time: e.Time, Deleted: e.Type == stream.EventDeleted, Deployment: deployment})
+	}
+	return changes
+}
+
+// auditedDeploymentCalls returns the audit entries kept in memory for calls on a deployment
+// and its subresources in the target cluster
+func (s *apiServer) auditedDeploymentCalls(clusterID, namespace, name string) []audit.Entry {
+	if s.auditor == nil {
+		return nil
+	}
+	path := "/deployments/" + namespace + "/" + name
+	return s.auditor.Recent(func(e audit.Entry) bool {
+		if e.Path != path && !strings.HasPrefix(e.Path, path+"/") {
+			return false
+		}
+		query, _ := url.ParseQuery(e.Query)
+		cluster := query.Get("cluster")
+		if cluster == "" {
+			cluster = primaryClusterID
+		}
+		return cluster == clusterID
+	})
+}
+
+// ownedReplicaSets returns the ReplicaSets a deployment controls
+func ownedReplicaSets(ctx context.Context, target *clusterTarget, deployment *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	list, err := target.Client.AppsV1().ReplicaSets(deployment.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	var owned []appsv1.ReplicaSet
+	for _, rs := range list.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.UID == deployment.UID {
+			owned = append(owned, rs)
+		}
+	}
+	return owned, nil
+}
+
+// workloadEvents returns the events of a deployment, its ReplicaSets and their pods
+func workloadEvents(ctx context.Context, target *clusterTarget, namespace, name string, replicaSets []appsv1.ReplicaSet) ([]corev1.Event, error) {
+	list, err := target.Client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	rsNames := make(map[string]bool, len(replicaSets))
+	for _, rs := range replicaSets {
+		rsNames[rs.Name] = true
+	}
+	var events []corev1.Event
+	for _, e := range list.Items {
+		obj := e.InvolvedObject
+		switch obj.Kind {
+		case "Deployment":
+			if obj.Name != name {
+				continue
+			}
+		case "ReplicaSet":
+			if !rsNames[obj.Name] {
+				continue
+			}
+		case "Pod":
+			// Pods are named <replicaset>-<suffix>
+			i := strings.LastIndex(obj.Name, "-")
+			if i < 0 || !rsNames[obj.Name[:i]] {
+				continue
+			}
+		default:
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// @Summary Deployment change timeline
+// @Description Merges a deployment's history into one chronological view, oldest first: its creation, scalings, image changes, restarts and other spec changes observed by the informer (primary cluster only), ReplicaSet revisions, Kubernetes events with warnings as alerts, and audited API calls on it when api_server.audit.history is set. Deleted deployments still show what the informer observed.
+// @Tags deployments
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Param cluster query string false "Cluster ID, defaults to the primary cluster"
+// @Param limit query int false "Most recent entries to return (1-1000, default 200)"
+// @Success 200 {object} timelineResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /deployments/{namespace}/{name}/timeline [get]
+func (s *apiServer) handleDeploymentTimeline(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+	namespace, name := pathParam(ctx, "namespace"), pathParam(ctx, "name")
+
+	limit, err := timelineLimitFromQuery(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	target := s.resolveCluster(ctx, logger)
+	if target == nil {
+		return
+	}
+
+	changes := s.watchedDeploymentChanges(target, namespace, name)
+	calls := s.auditedDeploymentCalls(target.ID, namespace, name)
+
+	deployment, err := target.Client.AppsV1().Deployments(namespace).Get(requestContext(ctx), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		deployment = nil
+		if len(changes) == 0 && len(calls) == 0 {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+			json.NewEncoder(ctx).Encode(map[string]string{"error": fmt.Sprintf("Deployment %s/%s not found", namespace, name)})
+			return
+		}
+	} else if err != nil {
+		logger.Error().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to get deployment")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "Failed to get deployment"})
+		return
+	}
+
+	response := timelineResponse{Cluster: target.ID, Namespace: namespace, Name: name}
+	entries := timeline.DeploymentChanges(changes)
+	entries = append(entries, timeline.AuditEntries(calls)...)
+
+	var replicaSets []appsv1.ReplicaSet
+	if deployment != nil {
+		entries = append(entries, timeline.Created(deployment))
+		if replicaSets, err = ownedReplicaSets(requestContext(ctx), target, deployment); err != nil {
+			logger.Warn().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to list replica sets")
+			response.Warnings = append(response.Warnings, "Revision history unavailable: "+err.Error())
+		}
+		entries = append(entries, timeline.Revisions(replicaSets)...)
+	}
+	events, err := workloadEvents(requestContext(ctx), target, namespace, name, replicaSets)
+	if err != nil {
+		logger.Warn().Err(err).Str("namespace", namespace).Str("name", name).Msg("Failed to list events")
+		response.Warnings = append(response.Warnings, "Events unavailable: "+err.Error())
+	}
+	entries = append(entries, timeline.Events(events)...)
+
+	if !target.isPrimary() || s.watchSources["Deployment"] == nil {
+		response.Warnings = append(response.Warnings, "Observed changes are only recorded for the primary cluster with the informer enabled")
+	}
+
+	timeline.Sort(entries)
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+		response.Truncated = true
+	}
+	if entries == nil {
+		entries = []timeline.Entry{}
+	}
+	response.Entries = entries
+	response.Count = len(entries)
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(response)
+}
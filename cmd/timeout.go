@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/timeout"
+)
+
+// requestContextUserValueKey holds the context of a request's Kubernetes API calls
+const requestContextUserValueKey = "request_context"
+
+// requestContext returns the context for the Kubernetes API calls a handler makes. It
+// carries the request's span, so the calls are traced as its children, and is canceled
+// when the route's timeout expires or the handler returns. Work that outlives the handler
+// must detach from it with context.WithoutCancel.
+func requestContext(ctx *fasthttp.RequestCtx) context.Context {
+	if reqCtx, ok := ctx.UserValue(requestContextUserValueKey).(context.Context); ok {
+		return reqCtx
+	}
+	return context.Background()
+}
+
+func setRequestContext(ctx *fasthttp.RequestCtx, reqCtx context.Context) {
+	ctx.SetUserValue(requestContextUserValueKey, reqCtx)
+}
+
+// startTimeout bounds the request context by the timeout configured for the route. The
+// returned function releases the context once the handler returns.
+func (s *apiServer) startTimeout(ctx *fasthttp.RequestCtx, method, path string) (time.Duration, context.CancelFunc) {
+	var d time.Duration
+	if s.config != nil {
+		d = s.config.APIServer.Timeouts.For(method, path)
+	}
+	var reqCtx context.Context
+	var cancel context.CancelFunc
+	if d > 0 {
+		reqCtx, cancel = context.WithTimeoutCause(requestContext(ctx), d, timeout.ErrTimeout)
+	} else {
+		reqCtx, cancel = context.WithCancel(requestContext(ctx))
+	}
+	setRequestContext(ctx, reqCtx)
+	return d, cancel
+}
+
+// writeTimeout replaces the response of a request whose timeout expired with 504, whatever
+// the handler made of its canceled calls. Streams and upgraded connections have already
+// started and are left alone.
+func writeTimeout(ctx *fasthttp.RequestCtx, d time.Duration) bool {
+	if !errors.Is(context.Cause(requestContext(ctx)), timeout.ErrTimeout) {
+		return false
+	}
+	if ctx.Hijacked() || ctx.Response.IsBodyStream() {
+		return false
+	}
+	logger := getRequestLogger(ctx)
+	logger.Warn().Dur("timeout", d).Msg("Request timed out")
+
+	ctx.Response.ResetBody()
+	ctx.Response.Header.Del(fasthttp.HeaderETag)
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusGatewayTimeout)
+	json.NewEncoder(ctx).Encode(map[string]string{
+		"error":   "Request timed out",
+		"timeout": d.String(),
+		"path":    string(ctx.Path()),
+	})
+	return true
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/tlsutil"
+)
+
+// buildServerTLSConfig loads the API server certificate from files or a Secret
+// and starts a background reloader that picks up rotated certificates
+func buildServerTLSConfig(ctx context.Context, clientset *kubernetes.Clientset, appConfig *Config) (*tls.Config, error) {
+	tlsCfg := appConfig.APIServer.TLS
+
+	minVersion, err := tlsutil.ParseMinVersion(tlsCfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var source tlsutil.CertSource
+	switch {
+	case tlsCfg.SecretName != "":
+		if clientset == nil {
+			return nil, fmt.Errorf("api_server.tls.secret_name requires a Kubernetes client")
+		}
+		source = tlsutil.SecretSource{Client: clientset, Namespace: tlsCfg.SecretNamespace, Name: tlsCfg.SecretName}
+	case tlsCfg.CertFile != "" && tlsCfg.KeyFile != "":
+		source = tlsutil.FileSource{CertFile: tlsCfg.CertFile, KeyFile: tlsCfg.KeyFile}
+	default:
+		return nil, fmt.Errorf("api_server.tls requires either cert_file and key_file or secret_name")
+	}
+
+	reloader, err := tlsutil.NewReloader(ctx, source, tlsCfg.ReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	go reloader.Start(ctx)
+
+	log.Info().
+		Str("source", source.String()).
+		Dur("reload_interval", tlsCfg.ReloadInterval).
+		Str("min_version", tlsCfg.MinVersion).
+		Msg("Loaded TLS certificate for API server")
+
+	return reloader.ServerConfig(minVersion), nil
+}
+
+// serveTLS serves the fasthttp server over a TLS listener using the given configuration
+func serveTLS(server *fasthttp.Server, address string, tlsConfig *tls.Config) error {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return server.Serve(tls.NewListener(ln, tlsConfig))
+}
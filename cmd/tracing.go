@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/httpmetrics"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/tracing"
+)
+
+// startRequestSpan starts the server span of a request. It continues the caller's trace from
+// traceparent; otherwise the trace ID is the X-Request-ID, so logs and audit entries lead
+// straight to the trace.
+func startRequestSpan(ctx *fasthttp.RequestCtx, requestID, method, route string) trace.Span {
+	parent := otel.GetTextMapPropagator().Extract(context.Background(), requestHeaderCarrier{&ctx.Request.Header})
+	if !trace.SpanContextFromContext(parent).IsValid() {
+		if id, ok := tracing.TraceIDFromUUID(requestID); ok {
+			parent = tracing.ContextWithTraceID(parent, id)
+		}
+	}
+	if route == "" {
+		route = httpmetrics.Unmatched
+	}
+	spanCtx, span := tracing.Tracer().Start(parent, method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", string(ctx.Path())),
+			attribute.String("client.address", ctx.RemoteIP().String()),
+			attribute.String("request_id", requestID)))
+	setRequestContext(ctx, spanCtx)
+	return span
+}
+
+// endRequestSpan records the outcome of a request on its span
+func endRequestSpan(ctx *fasthttp.RequestCtx, span trace.Span) {
+	status := ctx.Response.StatusCode()
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if cluster := ctx.QueryArgs().Peek("cluster"); len(cluster) > 0 {
+		span.SetAttributes(attribute.String("k8s.cluster.id", string(cluster)))
+	}
+	if principal := getPrincipal(ctx); principal != nil {
+		span.SetAttributes(attribute.String("enduser.id", principal.Name))
+	}
+	if status >= fasthttp.StatusInternalServerError {
+		span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+	}
+	span.End()
+}
+
+// traceID returns the trace ID of a request's span, empty when the request is not traced
+func traceID(ctx *fasthttp.RequestCtx) string {
+	if sc := trace.SpanContextFromContext(requestContext(ctx)); sc.IsSampled() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// requestHeaderCarrier reads propagated trace context from fasthttp request headers
+type requestHeaderCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c requestHeaderCarrier) Get(key string) string { return string(c.header.Peek(key)) }
+func (c requestHeaderCarrier) Set(key, value string) { c.header.Set(key, value) }
+
+func (c requestHeaderCarrier) Keys() []string {
+	var keys []string
+	c.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
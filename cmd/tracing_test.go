@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/tracing"
+)
+
+// TestStartRequestSpan tests that requests continue the caller's trace from traceparent and
+// otherwise take their trace ID from the request ID
+func TestStartRequestSpan(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer collector.Close()
+	provider, err := tracing.NewProvider(tracing.Config{Enabled: true, Endpoint: collector.URL, SampleRatio: 1})
+	require.NoError(t, err)
+	defer provider.Shutdown(context.Background())
+	tracing.SetDefault(provider)
+	defer func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	}()
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	span := startRequestSpan(ctx, "4c2b1ee6-5202-468b-a67b-1bbe21feae45", "GET", "/pods")
+	endRequestSpan(ctx, span)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID(ctx))
+
+	ctx = &fasthttp.RequestCtx{}
+	span = startRequestSpan(ctx, "4c2b1ee6-5202-468b-a67b-1bbe21feae45", "GET", "/pods")
+	endRequestSpan(ctx, span)
+	assert.Equal(t, "4c2b1ee65202468ba67b1bbe21feae45", traceID(ctx))
+
+	// Unsampled traces are not reported
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	span = startRequestSpan(ctx, "4c2b1ee6-5202-468b-a67b-1bbe21feae45", "GET", "/pods")
+	endRequestSpan(ctx, span)
+	assert.Empty(t, traceID(ctx))
+}
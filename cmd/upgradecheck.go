@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/capacity"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/deprecation"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/upgradecheck"
+)
+
+// upgradeCheckInput reads everything the upgrade readiness checks need from a cluster
+func (s *apiServer) upgradeCheckInput(ctx context.Context, clusterID string, client kubernetes.Interface, target deprecation.Version) (upgradecheck.Input, error) {
+	report := &clusterDeprecations{ID: clusterID}
+	if err := checkClusterDeprecations(ctx, s.deprecationTable(), client, target, report); err != nil {
+		return upgradecheck.Input{}, err
+	}
+	in := upgradecheck.Input{
+		Version:      deprecation.MustParseVersion(report.Version),
+		Target:       target,
+		Deprecations: report.findings,
+		PoolLabels:   s.config.Capacity.PoolLabels,
+	}
+	if len(in.PoolLabels) == 0 {
+		in.PoolLabels = capacity.DefaultPoolLabels
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return in, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	in.KubeletVersions = make(map[string]string, len(nodes.Items))
+	for _, node := range nodes.Items {
+		in.KubeletVersions[node.Name] = node.Status.NodeInfo.KubeletVersion
+	}
+	if in.Nodes, err = clusterCapacity(ctx, client); err != nil {
+		return in, fmt.Errorf("failed to read node capacity: %w", err)
+	}
+
+	deployments, err := client.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return in, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	in.Deployments = deployments.Items
+	statefulSets, err := client.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return in, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	in.StatefulSets = statefulSets.Items
+	pdbs, err := client.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return in, fmt.Errorf("failed to list pod disruption budgets: %w", err)
+	}
+	in.PDBs = pdbs.Items
+	return in, nil
+}
+
+// @Summary Check cluster upgrade readiness
+// @Description Decides whether a cluster can be upgraded to the target Kubernetes version. Combines the deprecation report, PodDisruptionBudget coverage and node surge capacity into a verdict of ready, ready_with_warnings or blocked. Blocking findings: the target skips a minor version, kubelets too old for the target, workloads written through APIs the target removes, budgets that allow no disruptions, and pools where the pods of the busiest node do not fit on the rest of the pool plus the configured surge nodes. Warnings: APIs the target deprecates, replicated workloads without a budget and single-replica workloads.
+// @Tags kubernetes
+// @Produce json
+// @Param id path string true "Cluster ID"
+// @Param target query string true "Kubernetes version being upgraded to, e.g. 1.31"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /clusters/{id}/upgrade-check [get]
+func (s *apiServer) handleClusterUpgradeCheck(ctx *fasthttp.RequestCtx, clusterID string) {
+	logger := getRequestLogger(ctx)
+
+	target, err := parseTargetVersion(ctx)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if target.IsZero() {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": "target query parameter is required, e.g. target=1.31"})
+		return
+	}
+
+	clients, err := s.inventoryClients(clusterID)
+	if err != nil {
+		logger.Warn().Err(err).Str("cluster_id", clusterID).Msg("Failed to resolve cluster for upgrade check")
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	in, err := s.upgradeCheckInput(requestContext(ctx), clusterID, clients[clusterID], target)
+	if err != nil {
+		logger.Error().Err(err).Str("cluster_id", clusterID).Msg("Failed to read cluster for upgrade check")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		json.NewEncoder(ctx).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	report := upgradecheck.Check(s.config.UpgradeCheck, in)
+
+	logger.Info().
+		Str("cluster_id", clusterID).
+		Str("target", report.Target).
+		Str("verdict", report.Verdict).
+		Int("blocking", report.Blocking).
+		Int("warnings", report.Warnings).
+		Msg("Upgrade check completed")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(map[string]interface{}{
+		"cluster_id": clusterID,
+		"version":    report.Version,
+		"target":     report.Target,
+		"verdict":    report.Verdict,
+		"blocking":   report.Blocking,
+		"warnings":   report.Warnings,
+		"findings":   report.Findings,
+	})
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Build metadata, set at link time by the Makefile and Dockerfile:
+//
+//	-ldflags "-X=github.com/obezsmertnyi/k8s-custom-controller/cmd.appVersion=v1.4.0
+//	          -X=github.com/obezsmertnyi/k8s-custom-controller/cmd.gitCommit=3f2c1ab
+//	          -X=github.com/obezsmertnyi/k8s-custom-controller/cmd.buildDate=2025-06-01T12:00:00Z"
+var (
+	appVersion = "dev"
+	gitCommit  = ""
+	buildDate  = ""
+)
+
+// versionInfo describes the running binary
+type versionInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a checkout with uncommitted changes
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// buildVersion returns the build metadata. Without ldflags the commit and date fall back to
+// the VCS stamp go build embeds when run inside the repository.
+func buildVersion() versionInfo {
+	info := versionInfo{
+		Version:   appVersion,
+		GitCommit: gitCommit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.GitCommit == "" {
+					info.GitCommit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// @Summary Get build information
+// @Description Returns the version, git commit and build date of the running binary, with the Go version and platform it was built for.
+// @Tags system
+// @Produce json
+// @Success 200 {object} versionInfo
+// @Router /version [get]
+func (s *apiServer) handleVersion(ctx *fasthttp.RequestCtx) {
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	json.NewEncoder(ctx).Encode(buildVersion())
+}
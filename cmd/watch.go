@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/stream"
+)
+
+// sseRetryMillis tells EventSource clients how long to wait before reconnecting
+const sseRetryMillis = 3000
+
+// streamingPaths are served as long-lived streams and get a write timeout matching the stream duration
+var streamingPaths = map[string]bool{
+	"/deployments/watch": true,
+	"/firehose":          true,
+}
+
+// streamRequestConfig extends the write timeout of streaming requests so fasthttp does not
+// cut them off after the regular write timeout
+func streamRequestConfig(maxDuration time.Duration) func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+	return func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		if isStreamingRequest(header.RequestURI()) {
+			return fasthttp.RequestConfig{WriteTimeout: maxDuration + time.Minute}
+		}
+		return fasthttp.RequestConfig{}
+	}
+}
+
+// isStreamingRequest reports whether a request URI is served as a long-lived stream
+func isStreamingRequest(uri []byte) bool {
+	path, query, _ := bytes.Cut(uri, []byte("?"))
+	if streamingPaths[string(path)] {
+		return true
+	}
+	// Followed pod logs stream until the container exits
+	if bytes.HasPrefix(path, []byte("/pods/")) && bytes.HasSuffix(path, []byte("/logs")) {
+		var args fasthttp.Args
+		args.ParseBytes(query)
+		return args.GetBool("follow")
+	}
+	return false
+}
+
+// watchableKind is a kind whose shared informer events can be streamed to clients
+type watchableKind struct {
+	resource string // Lowercase plural used in scopes, e.g. read:pods
+	informer func(factory informers.SharedInformerFactory) cache.SharedIndexInformer
+}
+
+// watchableKinds lists the kinds that can be enabled for streaming
+var watchableKinds = map[string]watchableKind{
+	"Deployment": {"deployments", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Apps().V1().Deployments().Informer()
+	}},
+	"Pod": {"pods", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().Pods().Informer()
+	}},
+	"Service": {"services", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().Services().Informer()
+	}},
+	"Node": {"nodes", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+		return f.Core().V1().Nodes().Informer()
+	}},
+}
+
+// watchSource fans the events of one informer out to streaming clients
+type watchSource struct {
+	kind     string
+	resource string
+	informer cache.SharedIndexInformer
+	events   *stream.Broadcaster
+}
+
+// newWatchSources registers event handlers for deployments and the extra kinds on the factory
+func newWatchSources(factory informers.SharedInformerFactory, cfg stream.Config, extraKinds []string) (map[string]*watchSource, error) {
+	sources := make(map[string]*watchSource)
+	for _, kind := range append([]string{"Deployment"}, extraKinds...) {
+		if sources[kind] != nil {
+			continue
+		}
+		k, ok := watchableKinds[kind]
+		if !ok {
+			return nil, fmt.Errorf("kind %q cannot be watched", kind)
+		}
+		source := &watchSource{
+			kind:     kind,
+			resource: k.resource,
+			informer: k.informer(factory),
+			events:   stream.NewBroadcaster(k.resource, cfg),
+		}
+		if _, err := source.informer.AddEventHandler(stream.EventHandler(source.events, kind)); err != nil {
+			return nil, fmt.Errorf("failed to watch %s: %w", kind, err)
+		}
+		sources[kind] = source
+	}
+	return sources, nil
+}
+
+// snapshot returns the cached objects matching the filter as ADDED events
+func (w *watchSource) snapshot(filter stream.Filter) []stream.Event {
+	var events []stream.Event
+	for _, obj := range w.informer.GetStore().List() {
+		o, ok := obj.(metav1.Object)
+		if !ok {
+			continue
+		}
+		if event := stream.NewEvent(stream.EventAdded, w.kind, o); filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// closeWatchSources ends every open stream
+func (s *apiServer) closeWatchSources() {
+	for _, source := range s.watchSources {
+		source.events.Close()
+	}
+}
+
+// writeSSEEvent writes an event in text/event-stream format with its resourceVersion as the ID
+func writeSSEEvent(w *bufio.Writer, event stream.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ResourceVersion, event.Type, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// @Summary Stream deployment events
+// @Description Streams deployment ADDED, MODIFIED and DELETED events from the informer as Server-Sent Events.
+// @Description Without a resume point the current deployments are sent first as ADDED events.
+// @Description Reconnecting clients resume after the Last-Event-ID header or resourceVersion parameter.
+// @Tags deployments
+// @Produce text/event-stream
+// @Param namespace query string false "Only stream deployments in this namespace"
+// @Param labelSelector query string false "Only stream deployments matching this label selector"
+// @Param fieldSelector query string false "Only stream deployments matching this field selector (metadata.name, metadata.namespace)"
+// @Param resourceVersion query string false "Resume after this resourceVersion; the Last-Event-ID header takes precedence"
+// @Success 200 {string} string "text/event-stream"
+// @Failure 400 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /deployments/watch [get]
+func (s *apiServer) handleDeploymentsWatch(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if !ctx.IsGet() {
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		ctx.SetBodyString(`{"error": "Method not allowed"}`)
+		return
+	}
+
+	source := s.watchSources["Deployment"]
+	if source == nil {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Deployment watch is disabled because the informer is disabled"}`)
+		return
+	}
+
+	if getClusterFromQuery(ctx) != primaryClusterID {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "Watching is only supported on the primary cluster"}`)
+		return
+	}
+
+	namespace := getNamespaceFromQuery(ctx)
+	filter, err := stream.NewFilter("Deployment", namespace,
+		string(ctx.QueryArgs().Peek("labelSelector")), string(ctx.QueryArgs().Peek("fieldSelector")))
+	if writePaginationError(ctx, err) {
+		return
+	}
+
+	resourceVersion := string(ctx.Request.Header.Peek("Last-Event-ID"))
+	if resourceVersion == "" {
+		resourceVersion = string(ctx.QueryArgs().Peek("resourceVersion"))
+	}
+	if resourceVersion == "0" {
+		resourceVersion = "" // As in Kubernetes, 0 means start from the current state
+	}
+
+	sub, replay, err := source.events.Subscribe(resourceVersion)
+	if errors.Is(err, stream.ErrResourceVersionTooOld) {
+		ctx.SetStatusCode(fasthttp.StatusGone)
+		json.NewEncoder(ctx).Encode(map[string]string{
+			"error": fmt.Sprintf("resourceVersion %s is too old, restart the watch without it", resourceVersion),
+		})
+		return
+	}
+
+	// A fresh watch starts with the current state so clients need no separate listing
+	if resourceVersion == "" {
+		replay = source.snapshot(filter)
+	}
+
+	logger.Info().
+		Str("namespace", namespace).
+		Str("resource_version", resourceVersion).
+		Int("replay", len(replay)).
+		Msg("Deployment watch started")
+
+	ctx.SetContentType("text/event-stream")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.Response.Header.Set("X-Accel-Buffering", "no") // Disable buffering in nginx-style proxies
+	ctx.SetStatusCode(fasthttp.StatusOK)
+
+	heartbeat := source.events.Heartbeat()
+	maxDuration := source.events.MaxDuration()
+
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer s.drain.TrackStream()()
+		defer source.events.Unsubscribe(sub)
+
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis)
+		for _, event := range replay {
+			if filter.Matches(event) && writeSSEEvent(w, event) != nil {
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		deadline := time.NewTimer(maxDuration)
+		defer deadline.Stop()
+
+		for {
+			select {
+			case event, ok := <-sub.Events():
+				if !ok {
+					if sub.Overflowed() {
+						logger.Warn().Msg("Deployment watch client fell behind, closing stream")
+					}
+					return
+				}
+				if filter.Matches(event) && writeSSEEvent(w, event) != nil {
+					logger.Debug().Msg("Deployment watch client disconnected")
+					return
+				}
+			case <-ticker.C:
+				// Comments keep proxies from closing idle streams and detect gone clients
+				if _, err := w.WriteString(": keep-alive\n\n"); err != nil || w.Flush() != nil {
+					logger.Debug().Msg("Deployment watch client disconnected")
+					return
+				}
+			case <-deadline.C:
+				// Clients reconnect after the retry delay and resume from the last event ID
+				return
+			}
+		}
+	})
+}
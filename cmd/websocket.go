@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/auth"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/stream"
+)
+
+// wsWriteTimeout bounds how long a single frame may take to reach a client
+const wsWriteTimeout = 10 * time.Second
+
+// wsRequest is a message sent by WebSocket clients
+type wsRequest struct {
+	Type            string `json:"type"` // subscribe or unsubscribe
+	ID              string `json:"id"`   // Client-chosen subscription ID echoed in responses
+	Kind            string `json:"kind"`
+	Namespace       string `json:"namespace"`
+	LabelSelector   string `json:"labelSelector"`
+	FieldSelector   string `json:"fieldSelector"`
+	ResourceVersion string `json:"resourceVersion"` // Resume after this version instead of sending the current state
+}
+
+// wsMessage is a frame sent to WebSocket clients
+type wsMessage struct {
+	Type  string        `json:"type"` // subscribed, unsubscribed, event or error
+	ID    string        `json:"id,omitempty"`
+	Event *stream.Event `json:"event,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// wsSession is one WebSocket connection and its subscriptions
+type wsSession struct {
+	server    *apiServer
+	conn      *websocket.Conn
+	principal *auth.Principal
+	logger    zerolog.Logger
+
+	send      chan wsMessage // Frames waiting for the writer; see overflow
+	overflow  string         // stream.OverflowDisconnect closes the connection when send is full, stream.OverflowDropOldest drops queued frames
+	stream    string         // Labels the slow consumer metrics
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu   sync.Mutex
+	subs map[string]func() // Cancel functions by subscription ID
+}
+
+// @Summary Live resource updates over WebSocket
+// @Description Upgrades to a WebSocket. Clients send {"type":"subscribe","id":"web","kind":"Deployment","namespace":"default","labelSelector":"app=web"}
+// @Description and receive {"type":"event","id":"web","event":{...}} frames with ADDED, MODIFIED and DELETED events from the informers.
+// @Description {"type":"unsubscribe","id":"web"} ends a subscription. Slow clients are disconnected.
+// @Tags watch
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /ws [get]
+func (s *apiServer) handleWebSocket(ctx *fasthttp.RequestCtx) {
+	logger := getRequestLogger(ctx)
+
+	if s.config == nil || !s.config.APIServer.WebSocket.Enabled || len(s.watchSources) == 0 {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "WebSocket updates are disabled"}`)
+		return
+	}
+	cfg := s.config.APIServer.WebSocket
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = 30 * time.Second
+	}
+	if cfg.SendBuffer <= 0 {
+		cfg.SendBuffer = 256
+	}
+
+	if !websocket.FastHTTPIsWebSocketUpgrade(ctx) {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.SetBodyString(`{"error": "Expected a WebSocket upgrade request"}`)
+		return
+	}
+
+	if n := s.wsConnections.Add(1); cfg.MaxConnections > 0 && n > int64(cfg.MaxConnections) {
+		s.wsConnections.Add(-1)
+		logger.Warn().Int("max_connections", cfg.MaxConnections).Msg("WebSocket connection limit reached")
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetBodyString(`{"error": "Too many WebSocket connections"}`)
+		return
+	}
+
+	principal := getPrincipal(ctx)
+	upgrader := newUpgrader(cfg.AllowedOrigins)
+
+	err := upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+		defer s.drain.TrackStream()()
+		defer s.wsConnections.Add(-1)
+
+		session := &wsSession{
+			server:    s,
+			conn:      conn,
+			principal: principal,
+			logger:    logger,
+			send:      make(chan wsMessage, cfg.SendBuffer),
+			overflow:  s.config.APIServer.Watch.OverflowPolicy,
+			stream:    "websocket",
+			done:      make(chan struct{}),
+			subs:      make(map[string]func()),
+		}
+		logger.Info().Msg("WebSocket client connected")
+		go session.writeLoop(cfg.PingInterval)
+		session.readLoop(cfg.MaxMessageBytes, cfg.MaxSubscriptions, cfg.PingInterval)
+		logger.Info().Msg("WebSocket client disconnected")
+	})
+	if err != nil {
+		s.wsConnections.Add(-1)
+		logger.Warn().Err(err).Msg("WebSocket upgrade failed")
+	}
+}
+
+// newUpgrader accepts WebSocket upgrades from the allowed browser origins, or from the same
+// origin only when none are configured
+func newUpgrader(allowedOrigins []string) websocket.FastHTTPUpgrader {
+	upgrader := websocket.FastHTTPUpgrader{HandshakeTimeout: 10 * time.Second}
+	if len(allowedOrigins) > 0 {
+		upgrader.CheckOrigin = func(ctx *fasthttp.RequestCtx) bool {
+			origin := string(ctx.Request.Header.Peek("Origin"))
+			for _, allowed := range allowedOrigins {
+				if allowed == "*" || allowed == origin {
+					return true
+				}
+			}
+			return origin == ""
+		}
+	}
+	return upgrader
+}
+
+// readLoop handles subscription messages until the connection ends
+func (ws *wsSession) readLoop(maxMessageBytes int64, maxSubscriptions int, pingInterval time.Duration) {
+	defer ws.close(websocket.CloseNormalClosure, "")
+
+	ws.conn.SetReadLimit(maxMessageBytes)
+	// Clients must answer pings; a missing pong means the connection is dead
+	ws.conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+	ws.conn.SetPongHandler(func(string) error {
+		return ws.conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+	})
+
+	for {
+		_, data, err := ws.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.Is(err, websocket.ErrReadLimit) {
+				ws.close(websocket.CloseMessageTooBig, "message too large")
+			} else if !errors.As(err, &closeErr) {
+				ws.logger.Debug().Err(err).Msg("WebSocket read failed")
+			}
+			return
+		}
+
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			ws.enqueue(wsMessage{Type: "error", Error: fmt.Sprintf("invalid message: %v", err)})
+			continue
+		}
+
+		switch req.Type {
+		case "subscribe":
+			ws.subscribe(req, maxSubscriptions)
+		case "unsubscribe":
+			ws.unsubscribe(req.ID)
+		default:
+			ws.enqueue(wsMessage{Type: "error", ID: req.ID, Error: fmt.Sprintf("unknown message type %q", req.Type)})
+		}
+	}
+}
+
+// writeLoop is the only writer of data frames; it also sends keep-alive pings
+func (ws *wsSession) writeLoop(pingInterval time.Duration) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-ws.send:
+			ws.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := ws.conn.WriteJSON(msg); err != nil {
+				ws.close(websocket.CloseGoingAway, "")
+				return
+			}
+		case <-ticker.C:
+			if err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				ws.close(websocket.CloseGoingAway, "")
+				return
+			}
+		case <-ws.done:
+			return
+		}
+	}
+}
+
+// subscribe validates the request and starts forwarding matching events
+func (ws *wsSession) subscribe(req wsRequest, maxSubscriptions int) {
+	fail := func(format string, args ...interface{}) {
+		ws.enqueue(wsMessage{Type: "error", ID: req.ID, Error: fmt.Sprintf(format, args...)})
+	}
+
+	if req.ID == "" {
+		fail("subscription id is required")
+		return
+	}
+	source := ws.server.watchSources[req.Kind]
+	if source == nil {
+		fail("kind %q is not available for watching", req.Kind)
+		return
+	}
+	// Scoped credentials may only watch the resources they can read
+	if ws.principal != nil && !ws.principal.HasScope(auth.ScopeActionRead+":"+source.resource) {
+		fail("requires %s:%s scope", auth.ScopeActionRead, source.resource)
+		return
+	}
+	filter, err := stream.NewFilter(req.Kind, req.Namespace, req.LabelSelector, req.FieldSelector)
+	if err != nil {
+		fail("%v", err)
+		return
+	}
+
+	ws.mu.Lock()
+	if _, exists := ws.subs[req.ID]; exists {
+		ws.mu.Unlock()
+		fail("subscription %q already exists", req.ID)
+		return
+	}
+	if maxSubscriptions > 0 && len(ws.subs) >= maxSubscriptions {
+		ws.mu.Unlock()
+		fail("at most %d subscriptions per connection", maxSubscriptions)
+		return
+	}
+
+	sub, replay, err := source.events.Subscribe(req.ResourceVersion)
+	if err != nil {
+		ws.mu.Unlock()
+		fail("resourceVersion %s is too old, subscribe again without it", req.ResourceVersion)
+		return
+	}
+	ws.subs[req.ID] = func() { source.events.Unsubscribe(sub) }
+	ws.mu.Unlock()
+
+	if req.ResourceVersion == "" {
+		replay = source.snapshot(filter)
+	}
+
+	ws.logger.Debug().Str("id", req.ID).Str("kind", req.Kind).Str("namespace", req.Namespace).Msg("WebSocket subscription added")
+	ws.enqueue(wsMessage{Type: "subscribed", ID: req.ID})
+
+	go func() {
+		for i := range replay {
+			if filter.Matches(replay[i]) && !ws.enqueue(wsMessage{Type: "event", ID: req.ID, Event: &replay[i]}) {
+				return
+			}
+		}
+		for event := range sub.Events() {
+			if filter.Matches(event) && !ws.enqueue(wsMessage{Type: "event", ID: req.ID, Event: &event}) {
+				return
+			}
+		}
+		if sub.Overflowed() {
+			ws.logger.Warn().Str("id", req.ID).Msg("WebSocket client fell behind, closing connection")
+			ws.close(websocket.ClosePolicyViolation, "client too slow")
+			return
+		}
+
+		// A subscription that ends without being unsubscribed means the server is shutting down
+		ws.mu.Lock()
+		_, active := ws.subs[req.ID]
+		ws.mu.Unlock()
+		if active {
+			ws.close(websocket.CloseGoingAway, "server shutting down")
+		}
+	}()
+}
+
+// unsubscribe ends a subscription; its forwarder stops when the channel closes
+func (ws *wsSession) unsubscribe(id string) {
+	ws.mu.Lock()
+	cancel, ok := ws.subs[id]
+	delete(ws.subs, id)
+	ws.mu.Unlock()
+
+	if !ok {
+		ws.enqueue(wsMessage{Type: "error", ID: id, Error: fmt.Sprintf("subscription %q does not exist", id)})
+		return
+	}
+	cancel()
+	ws.enqueue(wsMessage{Type: "unsubscribed", ID: id})
+}
+
+// enqueue queues a frame without blocking. A full queue means the client cannot keep up,
+// so its oldest frames are dropped or the connection is closed rather than letting it hold
+// events back.
+func (ws *wsSession) enqueue(msg wsMessage) bool {
+	select {
+	case <-ws.done:
+		return false
+	default:
+	}
+
+	if !stream.Offer(ws.send, msg, ws.overflow, ws.stream) {
+		ws.logger.Warn().Int("buffer", cap(ws.send)).Msg("WebSocket send buffer full, closing connection")
+		ws.close(websocket.ClosePolicyViolation, "client too slow")
+		return false
+	}
+	return true
+}
+
+// close ends all subscriptions and the connection once
+func (ws *wsSession) close(code int, reason string) {
+	ws.closeOnce.Do(func() {
+		close(ws.done)
+
+		ws.mu.Lock()
+		for id, cancel := range ws.subs {
+			cancel()
+			delete(ws.subs, id)
+		}
+		ws.mu.Unlock()
+
+		ws.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+		ws.conn.Close()
+	})
+}
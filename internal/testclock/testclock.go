@@ -0,0 +1,33 @@
+// Package testclock provides a clock that tests move forward by hand, to be injected where
+// code reads the time through a func() time.Time
+package testclock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a manually advanced clock, safe for concurrent use
+type Clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// New returns a clock reading start
+func New(start time.Time) *Clock {
+	return &Clock{t: start}
+}
+
+// Now returns the current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// Advance moves the clock forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
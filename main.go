@@ -4,6 +4,8 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package main
 
 import (
+	"os"
+
 	"github.com/obezsmertnyi/k8s-custom-controller/cmd"
 	"github.com/rs/zerolog/log"
 )
@@ -11,5 +13,6 @@ import (
 func main() {
 	if err := cmd.Execute(); err != nil {
 		log.Error().Err(err).Msg("CLI execution failed")
+		os.Exit(1)
 	}
 }
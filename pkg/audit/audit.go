@@ -0,0 +1,236 @@
+// Package audit records mutating API calls to pluggable audit sinks
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Entry is a single audited request
+type Entry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id"`
+	Principal  string    `json:"principal,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	ClientIP   string    `json:"client_ip"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	BodySHA256 string    `json:"body_sha256,omitempty"`
+	BodySize   int       `json:"body_size"`
+	Status     int       `json:"status"`
+	Result     string    `json:"result"` // success, denied or error
+	DurationMs int64     `json:"duration_ms"`
+	Objects    []string  `json:"objects,omitempty"` // Objects changed by bulk requests, as cluster/kind/namespace/name
+}
+
+// Sink persists audit entries
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, entry Entry) error
+	Close() error
+}
+
+// FileConfig configures the JSON lines file sink and its retention
+type FileConfig struct {
+	Path       string        `mapstructure:"path"`
+	MaxSizeMB  int           `mapstructure:"max_size_mb" unit:"megabytes"` // Rotate when the file grows beyond this size, 0 disables rotation
+	MaxBackups int           `mapstructure:"max_backups"`                  // Rotated files to keep, 0 keeps all
+	MaxAge     time.Duration `mapstructure:"max_age"`                      // Delete rotated files older than this, 0 keeps all
+}
+
+// WebhookConfig configures the HTTP webhook sink
+type WebhookConfig struct {
+	URL     string            `mapstructure:"url"`
+	Timeout time.Duration     `mapstructure:"timeout"`
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// Config holds audit logging settings
+type Config struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Methods   []string      `mapstructure:"methods"`    // Audited methods, defaults to POST, PUT, PATCH and DELETE
+	Paths     []string      `mapstructure:"paths"`      // Audited path prefixes, empty audits every path
+	Stdout    bool          `mapstructure:"stdout"`     // Write entries to standard output
+	File      FileConfig    `mapstructure:"file"`       // Write entries to a file when path is set
+	Webhook   WebhookConfig `mapstructure:"webhook"`    // POST entries to a URL when set
+	QueueSize int           `mapstructure:"queue_size"` // Entries buffered before new ones are dropped
+	History   int           `mapstructure:"history"`    // Entries kept in memory for deployment timelines, 0 keeps none
+}
+
+// DefaultMethods are audited when no methods are configured
+var DefaultMethods = []string{"POST", "PUT", "PATCH", "DELETE"}
+
+// Logger filters requests and delivers entries to sinks from a background worker
+type Logger struct {
+	methods map[string]bool
+	paths   []string
+	sinks   []Sink
+	memory  *MemorySink // Recent entries, nil when none are kept
+
+	mu     sync.RWMutex // Guards sends on queue against Close
+	queue  chan Entry
+	closed bool
+	done   chan struct{}
+	once   sync.Once
+}
+
+// New builds the sinks described by the configuration and starts the delivery worker
+func New(cfg Config) (*Logger, error) {
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = DefaultMethods
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+
+	var sinks []Sink
+	var memory *MemorySink
+	if cfg.History > 0 {
+		memory = NewMemorySink(cfg.History)
+		sinks = append(sinks, memory)
+	}
+	if cfg.Stdout {
+		sinks = append(sinks, NewStdoutSink())
+	}
+	if cfg.File.Path != "" {
+		sink, err := NewFileSink(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.Webhook.URL != "" {
+		sinks = append(sinks, NewWebhookSink(cfg.Webhook))
+	}
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("audit logging is enabled but no sinks are configured")
+	}
+
+	l := NewWithSinks(methods, cfg.Paths, cfg.QueueSize, sinks...)
+	l.memory = memory
+	return l, nil
+}
+
+// NewWithSinks creates a logger for the given methods and path prefixes using existing sinks
+func NewWithSinks(methods, paths []string, queueSize int, sinks ...Sink) *Logger {
+	l := &Logger{
+		methods: make(map[string]bool, len(methods)),
+		paths:   paths,
+		sinks:   sinks,
+		queue:   make(chan Entry, queueSize),
+		done:    make(chan struct{}),
+	}
+	for _, m := range methods {
+		l.methods[strings.ToUpper(m)] = true
+	}
+	go l.run()
+	return l
+}
+
+// Matches reports whether a request should be audited
+func (l *Logger) Matches(method, path string) bool {
+	if !l.methods[strings.ToUpper(method)] {
+		return false
+	}
+	if len(l.paths) == 0 {
+		return true
+	}
+	for _, prefix := range l.paths {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Record queues an entry for delivery without blocking the request. Entries recorded after
+// Close, such as those of requests still running at the shutdown timeout, are dropped.
+func (l *Logger) Record(entry Entry) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		log.Warn().Str("request_id", entry.RequestID).Msg("Audit log closed, dropping entry")
+		return
+	}
+	select {
+	case l.queue <- entry:
+	default:
+		log.Warn().Str("request_id", entry.RequestID).Msg("Audit queue full, dropping entry")
+	}
+}
+
+// Recent returns the entries kept in memory that match, oldest first. Entries are kept only
+// when history is configured and appear once the delivery worker has handled them.
+func (l *Logger) Recent(match func(Entry) bool) []Entry {
+	if l.memory == nil {
+		return nil
+	}
+	return l.memory.Entries(match)
+}
+
+// Close flushes queued entries and closes all sinks
+func (l *Logger) Close() error {
+	l.once.Do(func() {
+		l.mu.Lock()
+		l.closed = true
+		close(l.queue)
+		l.mu.Unlock()
+		<-l.done
+	})
+
+	var errs []string
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", sink.Name(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close audit sinks: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	for entry := range l.queue {
+		for _, sink := range l.sinks {
+			if err := sink.Write(context.Background(), entry); err != nil {
+				log.Error().Err(err).
+					Str("sink", sink.Name()).
+					Str("request_id", entry.RequestID).
+					Msg("Failed to write audit entry")
+			}
+		}
+	}
+}
+
+// Digest returns the hex SHA-256 of a request body, empty for empty bodies
+func Digest(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// ResultForStatus classifies an HTTP status code
+func ResultForStatus(status int) string {
+	switch {
+	case status == 401 || status == 403 || status == 423 || status == 429:
+		return "denied"
+	case status >= 400:
+		return "error"
+	default:
+		return "success"
+	}
+}
@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Matches(t *testing.T) {
+	l := NewWithSinks(DefaultMethods, []string{"/clusters"}, 10, NewWriterSink("buf", &bytes.Buffer{}))
+	defer l.Close()
+
+	assert.True(t, l.Matches("POST", "/clusters"))
+	assert.True(t, l.Matches("delete", "/clusters/prod"))
+	assert.False(t, l.Matches("GET", "/clusters"))
+	assert.False(t, l.Matches("POST", "/clustersx"))
+	assert.False(t, l.Matches("POST", "/deployments"))
+}
+
+func TestLogger_RecordAndClose(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWithSinks(DefaultMethods, nil, 10, NewWriterSink("buf", &buf))
+
+	body := []byte(`{"cluster_id":"prod"}`)
+	l.Record(Entry{RequestID: "req-1", Method: "POST", Path: "/clusters", BodySHA256: Digest(body), Status: 201, Result: ResultForStatus(201)})
+	require.NoError(t, l.Close())
+
+	var entry Entry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, "success", entry.Result)
+	assert.Len(t, entry.BodySHA256, 64)
+}
+
+func TestLogger_RecordAfterClose(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWithSinks(DefaultMethods, nil, 10, NewWriterSink("buf", &buf))
+	l.Record(Entry{RequestID: "req-1", Method: "POST", Path: "/clusters"})
+	require.NoError(t, l.Close())
+
+	// Requests still running at the shutdown timeout record after the log is closed
+	assert.NotPanics(t, func() {
+		l.Record(Entry{RequestID: "req-2", Method: "POST", Path: "/clusters"})
+	})
+	assert.NotContains(t, buf.String(), "req-2")
+}
+
+func TestLogger_RecordDuringClose(t *testing.T) {
+	l := NewWithSinks(DefaultMethods, nil, 10, NewWriterSink("buf", &bytes.Buffer{}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Record(Entry{Method: "POST", Path: "/clusters"})
+			}
+		}()
+	}
+	require.NoError(t, l.Close())
+	wg.Wait()
+}
+
+func TestLogger_Recent(t *testing.T) {
+	l, err := New(Config{History: 2})
+	require.NoError(t, err, "the memory sink alone is enough")
+
+	for _, path := range []string{"/deployments/default/web", "/clusters", "/deployments/default/web/scale"} {
+		l.Record(Entry{Method: "POST", Path: path})
+	}
+	require.NoError(t, l.Close())
+
+	recent := l.Recent(nil)
+	require.Len(t, recent, 2)
+	assert.Equal(t, "/clusters", recent[0].Path)
+
+	scaled := l.Recent(func(e Entry) bool { return e.Path == "/deployments/default/web/scale" })
+	assert.Len(t, scaled, 1)
+}
+
+func TestFileSink_RotationAndRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	sink, err := NewFileSink(FileConfig{Path: path, MaxSizeMB: 1, MaxBackups: 1})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	// Each entry is small, so force rotation by pretending the file is full
+	for i := 0; i < 3; i++ {
+		require.NoError(t, sink.Write(t.Context(), Entry{RequestID: "req"}))
+		sink.size = 1024 * 1024
+		time.Sleep(time.Millisecond)
+	}
+
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Len(t, backups, 1, "only MaxBackups rotated files are kept")
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	lines := 0
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		lines++
+	}
+	assert.Equal(t, 1, lines)
+}
+
+func TestWebhookSink(t *testing.T) {
+	received := make(chan Entry, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		var entry Entry
+		json.NewDecoder(r.Body).Decode(&entry)
+		received <- entry
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(WebhookConfig{URL: server.URL, Headers: map[string]string{"X-Token": "secret"}})
+	require.NoError(t, sink.Write(t.Context(), Entry{RequestID: "req-2"}))
+	assert.Equal(t, "req-2", (<-received).RequestID)
+}
+
+func TestNew_RequiresSink(t *testing.T) {
+	_, err := New(Config{Enabled: true})
+	assert.Error(t, err)
+}
+
+func TestResultForStatus(t *testing.T) {
+	assert.Equal(t, "success", ResultForStatus(200))
+	assert.Equal(t, "denied", ResultForStatus(403))
+	assert.Equal(t, "error", ResultForStatus(500))
+}
@@ -0,0 +1,250 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriterSink writes entries as JSON lines to an io.Writer
+type WriterSink struct {
+	name string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+// NewStdoutSink writes entries to standard output
+func NewStdoutSink() *WriterSink {
+	return &WriterSink{name: "stdout", w: os.Stdout}
+}
+
+// NewWriterSink writes entries to w
+func NewWriterSink(name string, w io.Writer) *WriterSink {
+	return &WriterSink{name: name, w: w}
+}
+
+// Name implements Sink
+func (s *WriterSink) Name() string { return s.name }
+
+// Write implements Sink
+func (s *WriterSink) Write(ctx context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.NewEncoder(s.w).Encode(entry)
+}
+
+// Close implements Sink
+func (s *WriterSink) Close() error { return nil }
+
+// MemorySink keeps the most recent entries in memory so they can be queried
+type MemorySink struct {
+	mu      sync.Mutex
+	size    int
+	entries []Entry
+}
+
+// NewMemorySink keeps up to size entries, dropping the oldest first
+func NewMemorySink(size int) *MemorySink {
+	return &MemorySink{size: size}
+}
+
+// Name implements Sink
+func (s *MemorySink) Name() string { return "memory" }
+
+// Write implements Sink
+func (s *MemorySink) Write(ctx context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry)
+	if excess := len(s.entries) - s.size; excess > 0 {
+		s.entries = append([]Entry(nil), s.entries[excess:]...)
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *MemorySink) Close() error { return nil }
+
+// Entries returns the kept entries that match, oldest first
+func (s *MemorySink) Entries(match func(Entry) bool) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []Entry
+	for _, entry := range s.entries {
+		if match == nil || match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// FileSink appends JSON lines to a file and rotates it by size
+type FileSink struct {
+	config FileConfig
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+}
+
+// NewFileSink opens (or creates) the audit file
+func NewFileSink(cfg FileConfig) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	s := &FileSink{config: cfg}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Name implements Sink
+func (s *FileSink) Name() string { return "file" }
+
+// Write implements Sink
+func (s *FileSink) Write(ctx context.Context, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	maxSize := int64(s.config.MaxSizeMB) * 1024 * 1024
+	if maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// Close implements Sink
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file with a timestamp suffix, opens a new one and applies retention
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	rotated := s.config.Path + "." + time.Now().UTC().Format("20060102T150405.000000000")
+	if err := os.Rename(s.config.Path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	return s.prune(time.Now())
+}
+
+// prune removes rotated files beyond MaxBackups or older than MaxAge
+func (s *FileSink) prune(now time.Time) error {
+	backups, err := filepath.Glob(s.config.Path + ".*")
+	if err != nil {
+		return err
+	}
+	// Timestamp suffixes sort chronologically; newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	for i, backup := range backups {
+		remove := s.config.MaxBackups > 0 && i >= s.config.MaxBackups
+		if !remove && s.config.MaxAge > 0 {
+			if info, err := os.Stat(backup); err == nil && now.Sub(info.ModTime()) > s.config.MaxAge {
+				remove = true
+			}
+		}
+		if remove {
+			if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WebhookSink POSTs each entry as JSON to a URL
+type WebhookSink struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookSink creates a webhook sink; the timeout defaults to 5 seconds
+func NewWebhookSink(cfg WebhookConfig) *WebhookSink {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	return &WebhookSink{config: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Name implements Sink
+func (s *WebhookSink) Name() string { return "webhook" }
+
+// Write implements Sink
+func (s *WebhookSink) Write(ctx context.Context, entry Entry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", strings.TrimSpace(resp.Status))
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *WebhookSink) Close() error { return nil }
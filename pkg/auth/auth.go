@@ -0,0 +1,383 @@
+// Package auth implements authentication, role-based and scope-based authorization for the API server
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Role is a coarse-grained permission level; higher roles include lower ones
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleEditor
+	RoleAdmin
+)
+
+// String returns the configuration name of the role
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleEditor:
+		return "editor"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// ParseRole converts a role name into a Role
+func ParseRole(name string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "none":
+		return RoleNone, nil
+	case "viewer":
+		return RoleViewer, nil
+	case "editor":
+		return RoleEditor, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return RoleNone, fmt.Errorf("unknown role %q", name)
+	}
+}
+
+// Errors returned by Authenticate
+var (
+	ErrNoCredentials      = errors.New("no credentials provided")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// APIKey maps a static key to a principal, role and optional scopes
+type APIKey struct {
+	Name   string   `mapstructure:"name" json:"name"`
+	Key    string   `mapstructure:"key" json:"-"`
+	Role   string   `mapstructure:"role" json:"role"`
+	Scopes []string `mapstructure:"scopes" json:"scopes,omitempty"` // Empty leaves the key limited by role only
+}
+
+// JWTConfig configures HS256 bearer token validation
+type JWTConfig struct {
+	Secret     string `mapstructure:"secret" json:"-"`
+	Issuer     string `mapstructure:"issuer" json:"issuer"`
+	RoleClaim  string `mapstructure:"role_claim" json:"role_claim"`
+	ScopeClaim string `mapstructure:"scope_claim" json:"scope_claim"` // Space-separated string or list of scopes
+}
+
+// Rule sets the minimum role for requests matching a path prefix and methods
+type Rule struct {
+	Path    string   `mapstructure:"path" json:"path"`
+	Methods []string `mapstructure:"methods" json:"methods"` // Empty matches all methods
+	Role    string   `mapstructure:"role" json:"role"`
+}
+
+// Config holds authentication and authorization settings
+type Config struct {
+	Enabled       bool        `mapstructure:"enabled" json:"enabled"`
+	AnonymousRole string      `mapstructure:"anonymous_role" json:"anonymous_role"` // Role granted without credentials, empty denies
+	PublicPaths   []string    `mapstructure:"public_paths" json:"public_paths"`     // Path prefixes that skip authentication
+	APIKeys       []APIKey    `mapstructure:"api_keys" json:"api_keys"`
+	JWT           JWTConfig   `mapstructure:"jwt" json:"jwt"`
+	Rules         []Rule      `mapstructure:"rules" json:"rules"`
+	Scopes        []ScopeRule `mapstructure:"scopes" json:"scopes"` // Scope rules evaluated before DefaultScopeRules
+}
+
+// PlaceholderKey is the API key of the example configuration, rejected so it never guards a server
+const PlaceholderKey = "change-me"
+
+// DefaultPublicPaths are reachable without credentials when no public paths are configured
+var DefaultPublicPaths = []string{"/health", "/livez", "/readyz", "/swagger"}
+
+// DefaultRules are appended after configured rules: cluster management, failover, the effective
+// configuration, the administrative and the profiling endpoints require admin, and exec, port-forwarding and
+// proxying into pods and services editor
+var DefaultRules = []Rule{
+	{Path: "/clusters", Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Role: "admin"},
+	{Path: "/failover", Role: "admin"},
+	{Path: "/config", Role: "admin"},
+	{Path: "/admin", Role: "admin"},
+	{Path: "/debug", Role: "admin"},
+	{Path: "/pods/*/*/exec", Role: "editor"},
+	{Path: "/pods/*/*/portforward", Role: "editor"},
+	{Path: "/pods/*/*/proxy", Role: "editor"},
+	{Path: "/services/*/*/portforward", Role: "editor"},
+	{Path: "/services/*/*/proxy", Role: "editor"},
+}
+
+// Principal is an authenticated caller
+type Principal struct {
+	Name   string   `json:"name"`
+	Role   Role     `json:"-"`
+	Source string   `json:"source"`           // api-key, jwt or anonymous
+	Scopes []string `json:"scopes,omitempty"` // Empty means the role alone decides access
+}
+
+// Authorizer authenticates requests and enforces role and scope rules
+type Authorizer struct {
+	config        Config
+	anonymousRole Role
+	apiKeys       []APIKey
+	rules         []compiledRule
+	scopeRules    []compiledScopeRule
+}
+
+type compiledRule struct {
+	path    string
+	methods map[string]bool
+	role    Role
+}
+
+type compiledScopeRule struct {
+	path    string
+	methods map[string]bool
+	scope   string
+}
+
+// NewAuthorizer validates the configuration and builds an Authorizer
+func NewAuthorizer(cfg Config) (*Authorizer, error) {
+	anonymousRole, err := ParseRole(cfg.AnonymousRole)
+	if err != nil {
+		return nil, fmt.Errorf("anonymous_role: %w", err)
+	}
+
+	for i, key := range cfg.APIKeys {
+		if key.Key == "" {
+			return nil, fmt.Errorf("api_keys[%d]: key must not be empty", i)
+		}
+		if key.Key == PlaceholderKey {
+			return nil, fmt.Errorf("api_keys[%d]: key is the %q placeholder from the example configuration", i, PlaceholderKey)
+		}
+		if _, err := ParseRole(key.Role); err != nil {
+			return nil, fmt.Errorf("api_keys[%d]: %w", i, err)
+		}
+		for _, scope := range key.Scopes {
+			if _, _, err := ParseScope(scope); err != nil {
+				return nil, fmt.Errorf("api_keys[%d]: %w", i, err)
+			}
+		}
+	}
+
+	if len(cfg.PublicPaths) == 0 {
+		cfg.PublicPaths = DefaultPublicPaths
+	}
+	if cfg.JWT.RoleClaim == "" {
+		cfg.JWT.RoleClaim = "role"
+	}
+	if cfg.JWT.ScopeClaim == "" {
+		cfg.JWT.ScopeClaim = "scope"
+	}
+
+	a := &Authorizer{
+		config:        cfg,
+		anonymousRole: anonymousRole,
+		apiKeys:       cfg.APIKeys,
+	}
+
+	for i, rule := range append(append([]Rule{}, cfg.Rules...), DefaultRules...) {
+		role, err := ParseRole(rule.Role)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		methods := make(map[string]bool, len(rule.Methods))
+		for _, m := range rule.Methods {
+			methods[strings.ToUpper(m)] = true
+		}
+		a.rules = append(a.rules, compiledRule{path: rule.Path, methods: methods, role: role})
+	}
+
+	for i, rule := range a.ScopeRules() {
+		if _, _, err := ParseScope(rule.Scope); err != nil {
+			return nil, fmt.Errorf("scopes[%d]: %w", i, err)
+		}
+		methods := make(map[string]bool, len(rule.Methods))
+		for _, m := range rule.Methods {
+			methods[strings.ToUpper(m)] = true
+		}
+		a.scopeRules = append(a.scopeRules, compiledScopeRule{path: rule.Path, methods: methods, scope: rule.Scope})
+	}
+
+	return a, nil
+}
+
+// IsPublic reports whether the path skips authentication entirely
+func (a *Authorizer) IsPublic(path string) bool {
+	for _, prefix := range a.config.PublicPaths {
+		if matchPath(prefix, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate resolves the principal from the Authorization and X-API-Key header values.
+// Requests without credentials receive the anonymous role when one is configured.
+func (a *Authorizer) Authenticate(authorization, apiKey string) (*Principal, error) {
+	token := apiKey
+	if token == "" {
+		if bearer, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+			token = strings.TrimSpace(bearer)
+		}
+	}
+
+	if token == "" {
+		if a.anonymousRole == RoleNone {
+			return nil, ErrNoCredentials
+		}
+		return &Principal{Name: "anonymous", Role: a.anonymousRole, Source: "anonymous"}, nil
+	}
+
+	// Static API keys are compared in constant time
+	for _, key := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key.Key), []byte(token)) == 1 {
+			role, _ := ParseRole(key.Role)
+			return &Principal{Name: key.Name, Role: role, Source: "api-key", Scopes: key.Scopes}, nil
+		}
+	}
+
+	// Fall back to JWT validation when a secret is configured
+	if a.config.JWT.Secret != "" && strings.Count(token, ".") == 2 {
+		return a.authenticateJWT(token)
+	}
+
+	return nil, ErrInvalidCredentials
+}
+
+// RequiredRole returns the minimum role for a method and path
+func (a *Authorizer) RequiredRole(method, path string) Role {
+	method = strings.ToUpper(method)
+	for _, rule := range a.rules {
+		if !matchPath(rule.path, path) {
+			continue
+		}
+		if len(rule.methods) > 0 && !rule.methods[method] {
+			continue
+		}
+		return rule.role
+	}
+
+	// Read-only methods default to viewer, everything else to editor
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return RoleViewer
+	default:
+		return RoleEditor
+	}
+}
+
+// Authorize reports whether the principal has both the role and the scope the request requires
+func (a *Authorizer) Authorize(p *Principal, method, path string) bool {
+	if p == nil {
+		return false
+	}
+	return p.Role >= a.RequiredRole(method, path) && p.HasScope(a.RequiredScope(method, path))
+}
+
+// authenticateJWT validates an HS256 token and extracts the principal
+func (a *Authorizer) authenticateJWT(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, ErrInvalidCredentials
+	}
+
+	mac := hmac.New(sha256.New, []byte(a.config.JWT.Secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, ErrInvalidCredentials
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	now := float64(time.Now().Unix())
+	if exp, ok := claims["exp"].(float64); ok && now >= exp {
+		return nil, ErrInvalidCredentials
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return nil, ErrInvalidCredentials
+	}
+	if a.config.JWT.Issuer != "" && claims["iss"] != a.config.JWT.Issuer {
+		return nil, ErrInvalidCredentials
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		subject = "jwt"
+	}
+
+	return &Principal{
+		Name:   subject,
+		Role:   roleFromClaim(claims[a.config.JWT.RoleClaim]),
+		Source: "jwt",
+		Scopes: scopesFromClaim(claims[a.config.JWT.ScopeClaim]),
+	}, nil
+}
+
+// roleFromClaim accepts a single role name or a list, picking the highest known role
+func roleFromClaim(value interface{}) Role {
+	best := RoleNone
+	consider := func(v interface{}) {
+		if name, ok := v.(string); ok {
+			if role, err := ParseRole(name); err == nil && role > best {
+				best = role
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			consider(item)
+		}
+	default:
+		consider(v)
+	}
+	return best
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// matchPath reports whether path equals prefix or is nested below it. A * segment in the
+// prefix matches any one path segment, as in /pods/*/*/exec.
+func matchPath(prefix, path string) bool {
+	if prefix == "" || prefix == "/" {
+		return true
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	if strings.Contains(prefix, "*") {
+		prefixSegments, pathSegments := strings.Split(prefix, "/"), strings.Split(path, "/")
+		if len(pathSegments) < len(prefixSegments) {
+			return false
+		}
+		for i, segment := range prefixSegments {
+			if segment != pathSegments[i] && (segment != "*" || pathSegments[i] == "") {
+				return false
+			}
+		}
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/") || strings.HasPrefix(path, prefix+".")
+}
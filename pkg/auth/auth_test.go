@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signToken builds an HS256 JWT for tests
+func signToken(t *testing.T, secret string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("Admin")
+	require.NoError(t, err)
+	assert.Equal(t, RoleAdmin, role)
+
+	_, err = ParseRole("superuser")
+	assert.Error(t, err)
+}
+
+func TestNewAuthorizer_InvalidConfig(t *testing.T) {
+	_, err := NewAuthorizer(Config{AnonymousRole: "root"})
+	assert.ErrorContains(t, err, "anonymous_role")
+
+	_, err = NewAuthorizer(Config{APIKeys: []APIKey{{Name: "ci", Role: "editor"}}})
+	assert.ErrorContains(t, err, "api_keys[0]")
+
+	_, err = NewAuthorizer(Config{APIKeys: []APIKey{{Name: "ok", Key: "k", Role: "viewer"}, {Name: "ci", Key: PlaceholderKey, Role: "editor"}}})
+	assert.ErrorContains(t, err, "api_keys[1]: key is the \"change-me\" placeholder")
+}
+
+func TestAuthenticate_APIKeys(t *testing.T) {
+	a, err := NewAuthorizer(Config{APIKeys: []APIKey{
+		{Name: "ci", Key: "ci-key", Role: "editor"},
+	}})
+	require.NoError(t, err)
+
+	p, err := a.Authenticate("", "ci-key")
+	require.NoError(t, err)
+	assert.Equal(t, "ci", p.Name)
+	assert.Equal(t, RoleEditor, p.Role)
+
+	p, err = a.Authenticate("Bearer ci-key", "")
+	require.NoError(t, err)
+	assert.Equal(t, "api-key", p.Source)
+
+	_, err = a.Authenticate("", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = a.Authenticate("", "")
+	assert.ErrorIs(t, err, ErrNoCredentials)
+}
+
+func TestAuthenticate_Anonymous(t *testing.T) {
+	a, err := NewAuthorizer(Config{AnonymousRole: "viewer"})
+	require.NoError(t, err)
+
+	p, err := a.Authenticate("", "")
+	require.NoError(t, err)
+	assert.Equal(t, RoleViewer, p.Role)
+	assert.True(t, a.Authorize(p, "GET", "/pods"))
+	assert.False(t, a.Authorize(p, "POST", "/deployments"))
+}
+
+func TestAuthenticate_JWT(t *testing.T) {
+	a, err := NewAuthorizer(Config{JWT: JWTConfig{Secret: "s3cret", Issuer: "kcc"}})
+	require.NoError(t, err)
+
+	token := signToken(t, "s3cret", map[string]interface{}{
+		"sub":  "alice",
+		"iss":  "kcc",
+		"role": []string{"viewer", "admin"},
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	})
+	p, err := a.Authenticate("Bearer "+token, "")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", p.Name)
+	assert.Equal(t, RoleAdmin, p.Role)
+
+	expired := signToken(t, "s3cret", map[string]interface{}{"sub": "bob", "iss": "kcc", "exp": time.Now().Add(-time.Minute).Unix()})
+	_, err = a.Authenticate("Bearer "+expired, "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	forged := signToken(t, "other", map[string]interface{}{"sub": "eve", "iss": "kcc", "role": "admin"})
+	_, err = a.Authenticate("Bearer "+forged, "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	wrongIssuer := signToken(t, "s3cret", map[string]interface{}{"sub": "carol", "iss": "other", "role": "admin"})
+	_, err = a.Authenticate("Bearer "+wrongIssuer, "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestRequiredRole(t *testing.T) {
+	a, err := NewAuthorizer(Config{Rules: []Rule{
+		{Path: "/nodes", Role: "editor"},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, RoleViewer, a.RequiredRole("GET", "/pods"))
+	assert.Equal(t, RoleEditor, a.RequiredRole("POST", "/deployments"))
+	assert.Equal(t, RoleAdmin, a.RequiredRole("POST", "/clusters"))
+	assert.Equal(t, RoleAdmin, a.RequiredRole("DELETE", "/clusters"))
+	assert.Equal(t, RoleAdmin, a.RequiredRole("POST", "/failover"))
+	assert.Equal(t, RoleViewer, a.RequiredRole("GET", "/clusters"))
+	assert.Equal(t, RoleEditor, a.RequiredRole("GET", "/nodes"))
+	assert.Equal(t, RoleAdmin, a.RequiredRole("GET", "/admin/scopes"))
+	assert.Equal(t, RoleAdmin, a.RequiredRole("GET", "/debug/pprof/heap"))
+	assert.Equal(t, RoleAdmin, a.RequiredRole("GET", "/config"))
+	assert.Equal(t, RoleEditor, a.RequiredRole("GET", "/pods/shop/web-1/exec"))
+	assert.Equal(t, RoleEditor, a.RequiredRole("GET", "/pods/shop/web-1/proxy/8080/metrics"))
+	assert.Equal(t, RoleEditor, a.RequiredRole("GET", "/services/shop/web/portforward/80"))
+	assert.Equal(t, RoleViewer, a.RequiredRole("GET", "/pods/shop/web-1/logs"))
+	assert.Equal(t, RoleViewer, a.RequiredRole("GET", "/pods/shop/exec"))
+}
+
+func TestIsPublic(t *testing.T) {
+	a, err := NewAuthorizer(Config{})
+	require.NoError(t, err)
+
+	assert.True(t, a.IsPublic("/health"))
+	assert.True(t, a.IsPublic("/swagger/index.html"))
+	assert.True(t, a.IsPublic("/swagger.json"))
+	assert.False(t, a.IsPublic("/healthz-internal"))
+	assert.False(t, a.IsPublic("/pods"))
+}
+
+func TestParseScope(t *testing.T) {
+	action, resource, err := ParseScope("Write:Deployments")
+	require.NoError(t, err)
+	assert.Equal(t, ScopeActionWrite, action)
+	assert.Equal(t, "deployments", resource)
+
+	action, resource, err = ParseScope("*")
+	require.NoError(t, err)
+	assert.Equal(t, ScopeActionAdmin, action)
+	assert.Equal(t, ScopeWildcard, resource)
+
+	for _, invalid := range []string{"pods", "read:", "delete:pods"} {
+		_, _, err = ParseScope(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	_, err = NewAuthorizer(Config{APIKeys: []APIKey{{Name: "ci", Key: "k", Role: "editor", Scopes: []string{"pods"}}}})
+	assert.ErrorContains(t, err, "api_keys[0]")
+	_, err = NewAuthorizer(Config{Scopes: []ScopeRule{{Path: "/pods", Scope: "list:pods"}}})
+	assert.ErrorContains(t, err, "scopes[0]")
+}
+
+func TestRequiredScope(t *testing.T) {
+	a, err := NewAuthorizer(Config{Scopes: []ScopeRule{
+		{Path: "/export", Scope: "read:inventory"},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "read:pods", a.RequiredScope("GET", "/pods"))
+	assert.Equal(t, "write:deployments", a.RequiredScope("POST", "/deployments"))
+	assert.Equal(t, "write:drift", a.RequiredScope("PUT", "/drift/bundles"))
+	assert.Equal(t, "read:clusters", a.RequiredScope("GET", "/clusters"))
+	assert.Equal(t, "admin:clusters", a.RequiredScope("DELETE", "/clusters"))
+	assert.Equal(t, "admin:failover", a.RequiredScope("POST", "/failover"))
+	assert.Equal(t, "admin:server", a.RequiredScope("GET", "/admin/scopes"))
+	assert.Equal(t, "admin:server", a.RequiredScope("GET", "/config"))
+	assert.Equal(t, "admin:server", a.RequiredScope("GET", "/debug/vars"))
+	assert.Equal(t, "read:inventory", a.RequiredScope("GET", "/export/inventory"))
+	assert.Equal(t, "write:pods", a.RequiredScope("GET", "/pods/shop/web-1/exec"))
+	assert.Equal(t, "write:services", a.RequiredScope("GET", "/services/shop/web/proxy/80/"))
+	assert.Empty(t, a.RequiredScope("GET", "/health"))
+
+	rules := a.ScopeRules()
+	require.Len(t, rules, 1+len(DefaultScopeRules))
+	assert.Equal(t, "/export", rules[0].Path)
+}
+
+func TestAuthorize_Scopes(t *testing.T) {
+	a, err := NewAuthorizer(Config{APIKeys: []APIKey{
+		{Name: "dashboard", Key: "dash-key", Role: "editor", Scopes: []string{"read:*", "write:deployments"}},
+		{Name: "ops", Key: "ops-key", Role: "admin", Scopes: []string{"admin:clusters"}},
+		{Name: "legacy", Key: "legacy-key", Role: "editor"},
+	}})
+	require.NoError(t, err)
+
+	dashboard, err := a.Authenticate("", "dash-key")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read:*", "write:deployments"}, dashboard.Scopes)
+	assert.True(t, a.Authorize(dashboard, "GET", "/nodes"))
+	assert.True(t, a.Authorize(dashboard, "POST", "/deployments"))
+	assert.False(t, a.Authorize(dashboard, "POST", "/drift/bundles"))
+
+	// Admin implies write and read on the same resource only
+	ops, err := a.Authenticate("", "ops-key")
+	require.NoError(t, err)
+	assert.True(t, a.Authorize(ops, "POST", "/clusters"))
+	assert.True(t, a.Authorize(ops, "GET", "/clusters"))
+	assert.False(t, a.Authorize(ops, "GET", "/pods"))
+	assert.False(t, a.Authorize(ops, "GET", "/admin/scopes"))
+
+	// Keys without scopes are limited by their role alone
+	legacy, err := a.Authenticate("", "legacy-key")
+	require.NoError(t, err)
+	assert.True(t, a.Authorize(legacy, "POST", "/drift/bundles"))
+	assert.False(t, a.Authorize(legacy, "POST", "/clusters"))
+}
+
+func TestAuthenticate_JWTScopes(t *testing.T) {
+	a, err := NewAuthorizer(Config{JWT: JWTConfig{Secret: "s3cret"}})
+	require.NoError(t, err)
+
+	token := signToken(t, "s3cret", map[string]interface{}{"sub": "alice", "role": "editor", "scope": "read:pods write:deployments"})
+	p, err := a.Authenticate("Bearer "+token, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read:pods", "write:deployments"}, p.Scopes)
+	assert.True(t, a.Authorize(p, "GET", "/deployments"))
+	assert.False(t, a.Authorize(p, "GET", "/nodes"))
+
+	a, err = NewAuthorizer(Config{JWT: JWTConfig{Secret: "s3cret", ScopeClaim: "scp"}})
+	require.NoError(t, err)
+	token = signToken(t, "s3cret", map[string]interface{}{"sub": "bob", "role": "viewer", "scp": []string{"read:nodes"}})
+	p, err = a.Authenticate("Bearer "+token, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read:nodes"}, p.Scopes)
+}
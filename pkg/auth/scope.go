@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scope actions; admin includes write and write includes read on the same resource
+const (
+	ScopeActionRead  = "read"
+	ScopeActionWrite = "write"
+	ScopeActionAdmin = "admin"
+)
+
+// ScopeWildcard grants every action on every resource, or every resource when used as the resource
+const ScopeWildcard = "*"
+
+// ScopeRule sets the scope required by requests matching a path prefix and methods
+type ScopeRule struct {
+	Path    string   `mapstructure:"path" json:"path"`
+	Methods []string `mapstructure:"methods" json:"methods,omitempty"` // Empty matches all methods
+	Scope   string   `mapstructure:"scope" json:"scope"`
+}
+
+// DefaultScopeRules are appended after configured scope rules. Paths without a matching rule
+// require read:<resource> for GET, HEAD and OPTIONS and write:<resource> otherwise, where the
+// resource is the first path segment (read:pods for GET /pods).
+var DefaultScopeRules = []ScopeRule{
+	{Path: "/clusters", Methods: []string{"POST", "PUT", "PATCH", "DELETE"}, Scope: "admin:clusters"},
+	{Path: "/failover", Scope: "admin:failover"},
+	{Path: "/config", Scope: "admin:server"},
+	{Path: "/admin", Scope: "admin:server"},
+	{Path: "/debug", Scope: "admin:server"},
+	{Path: "/pods/*/*/exec", Scope: "write:pods"},
+	{Path: "/pods/*/*/portforward", Scope: "write:pods"},
+	{Path: "/pods/*/*/proxy", Scope: "write:pods"},
+	{Path: "/services/*/*/portforward", Scope: "write:services"},
+	{Path: "/services/*/*/proxy", Scope: "write:services"},
+}
+
+var scopeActionRank = map[string]int{
+	ScopeActionRead:  1,
+	ScopeActionWrite: 2,
+	ScopeActionAdmin: 3,
+}
+
+// ParseScope splits a scope such as read:pods into its action and resource
+func ParseScope(scope string) (action, resource string, err error) {
+	scope = strings.TrimSpace(scope)
+	if scope == ScopeWildcard {
+		return ScopeActionAdmin, ScopeWildcard, nil
+	}
+	action, resource, ok := strings.Cut(scope, ":")
+	if !ok || resource == "" {
+		return "", "", fmt.Errorf("invalid scope %q, expected <action>:<resource>", scope)
+	}
+	action = strings.ToLower(action)
+	if _, known := scopeActionRank[action]; !known {
+		return "", "", fmt.Errorf("unknown scope action %q in %q", action, scope)
+	}
+	return action, strings.ToLower(resource), nil
+}
+
+// scopeGrants reports whether a granted scope satisfies a required one
+func scopeGrants(granted, required string) bool {
+	grantedAction, grantedResource, err := ParseScope(granted)
+	if err != nil {
+		return false
+	}
+	requiredAction, requiredResource, err := ParseScope(required)
+	if err != nil {
+		return false
+	}
+	if grantedResource != ScopeWildcard && grantedResource != requiredResource {
+		return false
+	}
+	return scopeActionRank[grantedAction] >= scopeActionRank[requiredAction]
+}
+
+// HasScope reports whether the principal was granted the scope. Principals without any
+// scopes are limited by their role alone, so existing keys and tokens keep working.
+func (p *Principal) HasScope(scope string) bool {
+	if len(p.Scopes) == 0 || scope == "" {
+		return true
+	}
+	for _, granted := range p.Scopes {
+		if scopeGrants(granted, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiredScope returns the scope needed for a method and path, empty for public paths
+func (a *Authorizer) RequiredScope(method, path string) string {
+	if a.IsPublic(path) {
+		return ""
+	}
+
+	method = strings.ToUpper(method)
+	for _, rule := range a.scopeRules {
+		if !matchPath(rule.path, path) {
+			continue
+		}
+		if len(rule.methods) > 0 && !rule.methods[method] {
+			continue
+		}
+		return rule.scope
+	}
+
+	resource, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if resource == "" {
+		return ""
+	}
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return ScopeActionRead + ":" + resource
+	default:
+		return ScopeActionWrite + ":" + resource
+	}
+}
+
+// ScopeRules returns the configured scope rules followed by the defaults, in evaluation order
+func (a *Authorizer) ScopeRules() []ScopeRule {
+	return append(append([]ScopeRule{}, a.config.Scopes...), DefaultScopeRules...)
+}
+
+// scopesFromClaim accepts a space-separated string (the OAuth 2.0 form) or a list of scopes
+func scopesFromClaim(value interface{}) []string {
+	var scopes []string
+	switch v := value.(type) {
+	case string:
+		scopes = strings.Fields(v)
+	case []interface{}:
+		for _, item := range v {
+			if scope, ok := item.(string); ok && scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
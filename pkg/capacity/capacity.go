@@ -0,0 +1,301 @@
+// Package capacity forecasts when clusters and node pools run out of allocatable resources
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// UnlabeledPool groups nodes that carry none of the configured pool labels
+const UnlabeledPool = "unlabeled"
+
+// DefaultPoolLabels are node labels used by common providers to name node pools
+var DefaultPoolLabels = []string{
+	"node.kubernetes.io/pool",
+	"cloud.google.com/gke-nodepool",
+	"eks.amazonaws.com/nodegroup",
+	"kubernetes.azure.com/agentpool",
+}
+
+// Config holds capacity forecasting settings
+type Config struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Interval   time.Duration `mapstructure:"interval"`    // Time between samples
+	Window     time.Duration `mapstructure:"window"`      // Sample history used for the trend
+	MinSamples int           `mapstructure:"min_samples"` // Samples needed before forecasting
+	PoolLabels []string      `mapstructure:"pool_labels"` // Node labels naming the pool, first match wins
+}
+
+// Node is the allocatable and requested resources of one node
+type Node struct {
+	Name                   string
+	Labels                 map[string]string
+	AllocatableCPUMilli    int64
+	AllocatableMemoryBytes int64
+	RequestedCPUMilli      int64
+	RequestedMemoryBytes   int64
+}
+
+// Collector returns the nodes of every cluster keyed by cluster ID
+type Collector func(ctx context.Context) (map[string][]Node, error)
+
+// totals are aggregated resources of a group of nodes
+type totals struct {
+	allocCPU, allocMemory float64
+	reqCPU, reqMemory     float64
+}
+
+type sample struct {
+	at     time.Time
+	groups map[string]totals // Keyed by group: "" for the cluster, pool name otherwise
+	nodes  map[string]int    // Node counts per group
+}
+
+// ResourceForecast is the trend of one resource
+type ResourceForecast struct {
+	Allocatable   string   `json:"allocatable"`
+	Requested     string   `json:"requested"`
+	Utilization   float64  `json:"utilization"`               // Requested share of allocatable, 0-1
+	GrowthPerDay  string   `json:"growth_per_day"`            // Requested growth per day, negative when shrinking
+	DaysUntilFull *float64 `json:"days_until_full,omitempty"` // Omitted when requests are not growing
+}
+
+// Forecast is the capacity outlook of a cluster or node pool
+type Forecast struct {
+	Cluster       string           `json:"cluster"`
+	Pool          string           `json:"pool,omitempty"` // Empty for the cluster as a whole
+	Nodes         int              `json:"nodes"`
+	Samples       int              `json:"samples"`
+	CPU           ResourceForecast `json:"cpu"`
+	Memory        ResourceForecast `json:"memory"`
+	DaysUntilFull *float64         `json:"days_until_full,omitempty"` // Earliest of CPU and memory
+}
+
+// Forecaster samples capacity periodically and fits a linear trend to requests
+type Forecaster struct {
+	config  Config
+	collect Collector
+
+	mu         sync.RWMutex
+	history    map[string][]sample // Keyed by cluster ID
+	lastSample time.Time
+}
+
+// NewForecaster creates a forecaster reading nodes from collect
+func NewForecaster(cfg Config, collect Collector) *Forecaster {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Minute
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 7 * 24 * time.Hour
+	}
+	if cfg.MinSamples < 2 {
+		cfg.MinSamples = 2
+	}
+	if len(cfg.PoolLabels) == 0 {
+		cfg.PoolLabels = DefaultPoolLabels
+	}
+	return &Forecaster{
+		config:  cfg,
+		collect: collect,
+		history: make(map[string][]sample),
+	}
+}
+
+// Start samples immediately and then on every interval until the context is canceled
+func (f *Forecaster) Start(ctx context.Context) {
+	ticker := time.NewTicker(f.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.Sample(ctx, time.Now()); err != nil {
+			log.Error().Err(err).Msg("Capacity sampling failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sample records the current capacity of every cluster and drops samples outside the window
+func (f *Forecaster) Sample(ctx context.Context, now time.Time) error {
+	clusters, err := f.collect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to collect node capacity: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for clusterID, nodes := range clusters {
+		s := sample{at: now, groups: map[string]totals{}, nodes: map[string]int{}}
+		for _, node := range nodes {
+			for _, group := range []string{"", f.pool(node)} {
+				t := s.groups[group]
+				t.allocCPU += float64(node.AllocatableCPUMilli)
+				t.allocMemory += float64(node.AllocatableMemoryBytes)
+				t.reqCPU += float64(node.RequestedCPUMilli)
+				t.reqMemory += float64(node.RequestedMemoryBytes)
+				s.groups[group] = t
+				s.nodes[group]++
+			}
+		}
+		f.history[clusterID] = append(f.history[clusterID], s)
+	}
+
+	cutoff := now.Add(-f.config.Window)
+	for clusterID, samples := range f.history {
+		i := 0
+		for i < len(samples) && samples[i].at.Before(cutoff) {
+			i++
+		}
+		if i == len(samples) {
+			delete(f.history, clusterID)
+			continue
+		}
+		f.history[clusterID] = samples[i:]
+	}
+	f.lastSample = now
+	return nil
+}
+
+// Forecasts returns the outlook per cluster followed by its pools, optionally for one cluster.
+// Clusters with fewer than MinSamples samples report current usage without a trend.
+func (f *Forecaster) Forecasts(clusterID string) []Forecast {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	clusterIDs := make([]string, 0, len(f.history))
+	for id := range f.history {
+		if clusterID == "" || id == clusterID {
+			clusterIDs = append(clusterIDs, id)
+		}
+	}
+	sort.Strings(clusterIDs)
+
+	var forecasts []Forecast
+	for _, id := range clusterIDs {
+		samples := f.history[id]
+		latest := samples[len(samples)-1]
+
+		groups := make([]string, 0, len(latest.groups))
+		for group := range latest.groups {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups) // The cluster ("") sorts first
+
+		for _, group := range groups {
+			forecasts = append(forecasts, f.forecast(id, group, samples))
+		}
+	}
+	return forecasts
+}
+
+// LastSample returns when capacity was last sampled
+func (f *Forecaster) LastSample() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.lastSample
+}
+
+// pool returns the pool of a node from the first configured label it carries
+func (f *Forecaster) pool(node Node) string {
+	for _, label := range f.config.PoolLabels {
+		if value := node.Labels[label]; value != "" {
+			return value
+		}
+	}
+	return UnlabeledPool
+}
+
+func (f *Forecaster) forecast(clusterID, group string, samples []sample) Forecast {
+	latest := samples[len(samples)-1]
+	current := latest.groups[group]
+
+	// Only samples that saw this group contribute to its trend
+	var days, cpu, memory []float64
+	for _, s := range samples {
+		t, ok := s.groups[group]
+		if !ok {
+			continue
+		}
+		days = append(days, s.at.Sub(samples[0].at).Hours()/24)
+		cpu = append(cpu, t.reqCPU)
+		memory = append(memory, t.reqMemory)
+	}
+
+	result := Forecast{
+		Cluster: clusterID,
+		Pool:    group,
+		Nodes:   latest.nodes[group],
+		Samples: len(days),
+	}
+	enough := len(days) >= f.config.MinSamples
+	result.CPU = resourceForecast(current.allocCPU, current.reqCPU, days, cpu, enough, formatCPU)
+	result.Memory = resourceForecast(current.allocMemory, current.reqMemory, days, memory, enough, formatMemory)
+
+	for _, d := range []*float64{result.CPU.DaysUntilFull, result.Memory.DaysUntilFull} {
+		if d != nil && (result.DaysUntilFull == nil || *d < *result.DaysUntilFull) {
+			result.DaysUntilFull = d
+		}
+	}
+	return result
+}
+
+func resourceForecast(allocatable, requested float64, days, values []float64, enough bool, format func(float64) string) ResourceForecast {
+	rf := ResourceForecast{
+		Allocatable: format(allocatable),
+		Requested:   format(requested),
+	}
+	if allocatable > 0 {
+		rf.Utilization = math.Round(requested/allocatable*1000) / 1000
+	}
+	if !enough {
+		return rf
+	}
+
+	slope := linearSlope(days, values)
+	rf.GrowthPerDay = format(slope)
+	if slope > 0 {
+		remaining := math.Max(allocatable-requested, 0)
+		d := math.Round(remaining/slope*10) / 10
+		rf.DaysUntilFull = &d
+	}
+	return rf
+}
+
+// linearSlope fits values = a + b*x with least squares and returns b
+func linearSlope(x, y []float64) float64 {
+	n := float64(len(x))
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+		sumXY += x[i] * y[i]
+		sumXX += x[i] * x[i]
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+func formatCPU(milli float64) string {
+	return resource.NewMilliQuantity(int64(math.Round(milli)), resource.DecimalSI).String()
+}
+
+func formatMemory(bytes float64) string {
+	// Round to MiB so trends read naturally
+	mib := int64(math.Round(bytes / (1024 * 1024)))
+	return resource.NewQuantity(mib*1024*1024, resource.BinarySI).String()
+}
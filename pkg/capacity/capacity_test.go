@@ -0,0 +1,86 @@
+package capacity
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gib = 1024 * 1024 * 1024
+
+func TestForecaster_DaysUntilFull(t *testing.T) {
+	day := 0
+	collect := func(ctx context.Context) (map[string][]Node, error) {
+		// CPU requests grow by 1 core per day in the batch pool, memory stays flat
+		return map[string][]Node{"prod": {
+			{Name: "a", Labels: map[string]string{"node.kubernetes.io/pool": "batch"}, AllocatableCPUMilli: 8000, AllocatableMemoryBytes: 32 * gib, RequestedCPUMilli: int64(2000 + 1000*day), RequestedMemoryBytes: 8 * gib},
+			{Name: "b", AllocatableCPUMilli: 4000, AllocatableMemoryBytes: 16 * gib, RequestedCPUMilli: 1000, RequestedMemoryBytes: 4 * gib},
+		}}, nil
+	}
+	f := NewForecaster(Config{MinSamples: 3}, collect)
+
+	start := time.Now()
+	require.NoError(t, f.Sample(context.Background(), start))
+	forecasts := f.Forecasts("")
+	require.Len(t, forecasts, 3)
+	assert.Nil(t, forecasts[0].DaysUntilFull, "no trend before MinSamples")
+	assert.Equal(t, "3", forecasts[0].CPU.Requested)
+
+	for day = 1; day <= 3; day++ {
+		require.NoError(t, f.Sample(context.Background(), start.Add(time.Duration(day)*24*time.Hour)))
+	}
+
+	forecasts = f.Forecasts("prod")
+	require.Len(t, forecasts, 3)
+
+	cluster := forecasts[0]
+	assert.Empty(t, cluster.Pool)
+	assert.Equal(t, 2, cluster.Nodes)
+	assert.Equal(t, 4, cluster.Samples)
+	assert.Equal(t, "12", cluster.CPU.Allocatable)
+	assert.Equal(t, "1", cluster.CPU.GrowthPerDay)
+	// 12 cores allocatable, 6 requested, growing 1 core per day
+	require.NotNil(t, cluster.DaysUntilFull)
+	assert.Equal(t, 6.0, *cluster.DaysUntilFull)
+	assert.Nil(t, cluster.Memory.DaysUntilFull)
+	assert.Equal(t, 0.5, cluster.CPU.Utilization)
+
+	batch := forecasts[1]
+	assert.Equal(t, "batch", batch.Pool)
+	require.NotNil(t, batch.DaysUntilFull)
+	assert.Equal(t, 3.0, *batch.DaysUntilFull)
+
+	assert.Equal(t, UnlabeledPool, forecasts[2].Pool)
+	assert.Nil(t, forecasts[2].DaysUntilFull)
+
+	assert.Empty(t, f.Forecasts("staging"))
+}
+
+func TestForecaster_Window(t *testing.T) {
+	collect := func(ctx context.Context) (map[string][]Node, error) {
+		return map[string][]Node{"prod": {{Name: "a", AllocatableCPUMilli: 1000}}}, nil
+	}
+	f := NewForecaster(Config{Window: time.Hour}, collect)
+
+	start := time.Now()
+	require.NoError(t, f.Sample(context.Background(), start))
+	require.NoError(t, f.Sample(context.Background(), start.Add(2*time.Hour)))
+	assert.Equal(t, 1, f.Forecasts("prod")[0].Samples)
+}
+
+func TestForecaster_CollectError(t *testing.T) {
+	f := NewForecaster(Config{}, func(ctx context.Context) (map[string][]Node, error) {
+		return nil, errors.New("forbidden")
+	})
+	assert.ErrorContains(t, f.Sample(context.Background(), time.Now()), "forbidden")
+	assert.True(t, f.LastSample().IsZero())
+}
+
+func TestLinearSlope(t *testing.T) {
+	assert.InDelta(t, 2.0, linearSlope([]float64{0, 1, 2}, []float64{1, 3, 5}), 1e-9)
+	assert.Equal(t, 0.0, linearSlope([]float64{1, 1}, []float64{1, 5}))
+}
@@ -0,0 +1,222 @@
+// Package circuit stops aggregated endpoints from waiting on clusters that keep failing.
+// Each cluster has a circuit that opens after consecutive failed reads; while it is open
+// the cluster is skipped and the last successful read is served instead, marked stale.
+package circuit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// States of a cluster's circuit
+const (
+	Closed   = "closed"    // Reads go to the cluster
+	Open     = "open"      // Reads are skipped until the open duration has passed
+	HalfOpen = "half-open" // One probe read decides whether the circuit closes again
+)
+
+// ErrOpen is returned for a cluster whose circuit is open and that has no last-known data
+var ErrOpen = errors.New("circuit open after repeated failures")
+
+// Config holds circuit breaker settings
+type Config struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	FailureThreshold int           `mapstructure:"failure_threshold"` // Consecutive failed reads that open a cluster's circuit
+	OpenDuration     time.Duration `mapstructure:"open_duration"`     // Time a cluster is skipped before a probe read
+	MaxAge           time.Duration `mapstructure:"max_age"`           // Last-known data older than this is not served
+}
+
+// Circuit breaker metrics, served by the controller-runtime metrics endpoint and /metrics
+var (
+	stateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_custom_controller_cluster_circuit_state",
+		Help: "Circuit state of each cluster: 0 closed, 1 half-open, 2 open",
+	}, []string{"cluster"})
+	staleTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_custom_controller_cluster_stale_reads_total",
+		Help: "Reads of a cluster answered with last-known data because its circuit was open or the read failed",
+	}, []string{"cluster"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(stateGauge, staleTotal)
+}
+
+var stateValues = map[string]float64{Closed: 0, HalfOpen: 1, Open: 2}
+
+// Result is the outcome of a read through a circuit
+type Result struct {
+	Value    interface{}
+	Err      error         // Set when neither a fresh nor a last-known value is available
+	Stale    bool          // Value is the last-known one
+	CachedAt time.Time     // When a stale value was read
+	Age      time.Duration // Age of a stale value
+}
+
+type snapshot struct {
+	value interface{}
+	at    time.Time
+}
+
+type cluster struct {
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool // A half-open probe read is running
+	lastKnown map[string]snapshot
+}
+
+// Breaker holds the circuit and last-known data of every cluster
+type Breaker struct {
+	config Config
+	now    func() time.Time
+
+	mu       sync.Mutex
+	clusters map[string]*cluster
+}
+
+// New creates a breaker with defaults applied to unset settings
+func New(cfg Config) *Breaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = 30 * time.Second
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = time.Hour
+	}
+	return &Breaker{config: cfg, now: time.Now, clusters: make(map[string]*cluster)}
+}
+
+// Do reads the key of a cluster unless its circuit is open. A successful read is kept as
+// the last-known value of the key. When the circuit is open or the read fails, the
+// last-known value is returned marked stale; without one, the error is returned.
+// Reads failing because ctx ended do not count against the cluster.
+func (b *Breaker) Do(ctx context.Context, clusterID, key string, read func(context.Context) (interface{}, error)) Result {
+	if wait, ok := b.allow(clusterID); !ok {
+		return b.fallback(clusterID, key, fmt.Errorf("%w, next attempt in %s", ErrOpen, wait.Round(time.Second)))
+	}
+
+	value, err := read(ctx)
+	if err != nil {
+		b.failure(clusterID, ctx.Err() != nil)
+		return b.fallback(clusterID, key, err)
+	}
+	b.success(clusterID, key, value)
+	return Result{Value: value}
+}
+
+// State returns the circuit state of a cluster
+func (b *Breaker) State(clusterID string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.clusters[clusterID]; ok {
+		return c.state
+	}
+	return Closed
+}
+
+// Forget drops the circuit and last-known data of a cluster, e.g. once it is removed
+func (b *Breaker) Forget(clusterID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.clusters, clusterID)
+	stateGauge.DeleteLabelValues(clusterID)
+}
+
+// allow reports whether a read may go to the cluster, turning an open circuit half-open
+// once its open duration has passed. Otherwise it returns the time left until a probe.
+func (b *Breaker) allow(clusterID string) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.cluster(clusterID)
+	switch c.state {
+	case Open:
+		wait := c.openedAt.Add(b.config.OpenDuration).Sub(b.now())
+		if wait > 0 {
+			return wait, false
+		}
+		b.setState(clusterID, c, HalfOpen)
+		c.probing = true
+		return 0, true
+	case HalfOpen:
+		if c.probing {
+			return 0, false // Another request is probing the cluster
+		}
+		c.probing = true
+	}
+	return 0, true
+}
+
+func (b *Breaker) success(clusterID, key string, value interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.cluster(clusterID)
+	c.failures, c.probing = 0, false
+	c.lastKnown[key] = snapshot{value: value, at: b.now()}
+	b.setState(clusterID, c, Closed)
+}
+
+func (b *Breaker) failure(clusterID string, canceled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.cluster(clusterID)
+	probe := c.probing
+	c.probing = false
+	if canceled {
+		return
+	}
+	c.failures++
+	if probe || c.failures >= b.config.FailureThreshold {
+		c.openedAt = b.now()
+		b.setState(clusterID, c, Open)
+	}
+}
+
+// fallback returns the last-known value of the key, or err when there is none recent enough
+func (b *Breaker) fallback(clusterID, key string, err error) Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snap, ok := b.cluster(clusterID).lastKnown[key]
+	if !ok {
+		return Result{Err: err}
+	}
+	age := b.now().Sub(snap.at)
+	if age > b.config.MaxAge {
+		return Result{Err: err}
+	}
+	staleTotal.WithLabelValues(clusterID).Inc()
+	return Result{Value: snap.value, Stale: true, CachedAt: snap.at, Age: age}
+}
+
+func (b *Breaker) cluster(clusterID string) *cluster {
+	c, ok := b.clusters[clusterID]
+	if !ok {
+		c = &cluster{state: Closed, lastKnown: make(map[string]snapshot)}
+		b.clusters[clusterID] = c
+		stateGauge.WithLabelValues(clusterID).Set(stateValues[Closed])
+	}
+	return c
+}
+
+func (b *Breaker) setState(clusterID string, c *cluster, state string) {
+	if c.state == state {
+		return
+	}
+	c.state = state
+	stateGauge.WithLabelValues(clusterID).Set(stateValues[state])
+	switch state {
+	case Open:
+		log.Warn().Str("cluster_id", clusterID).Int("failures", c.failures).Dur("open_duration", b.config.OpenDuration).Msg("Cluster circuit opened, serving last-known data")
+	case Closed:
+		log.Info().Str("cluster_id", clusterID).Msg("Cluster circuit closed")
+	}
+}
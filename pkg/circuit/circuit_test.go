@@ -0,0 +1,128 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/internal/testclock"
+)
+
+func newTestBreaker(cfg Config) (*Breaker, *testclock.Clock) {
+	clock := testclock.New(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	b := New(cfg)
+	b.now = clock.Now
+	return b, clock
+}
+
+var errUnreachable = errors.New("dial tcp: connection refused")
+
+func succeed(value interface{}) func(context.Context) (interface{}, error) {
+	return func(context.Context) (interface{}, error) { return value, nil }
+}
+
+func fail(calls *int) func(context.Context) (interface{}, error) {
+	return func(context.Context) (interface{}, error) {
+		*calls++
+		return nil, errUnreachable
+	}
+}
+
+func TestDo_OpensAfterThresholdAndServesLastKnown(t *testing.T) {
+	b, clock := newTestBreaker(Config{FailureThreshold: 2, OpenDuration: time.Minute})
+	ctx := context.Background()
+
+	result := b.Do(ctx, "prod", "resources", succeed("v1"))
+	require.NoError(t, result.Err)
+	assert.Equal(t, "v1", result.Value)
+	assert.False(t, result.Stale)
+
+	clock.Advance(10 * time.Second)
+	calls := 0
+	result = b.Do(ctx, "prod", "resources", fail(&calls))
+	require.NoError(t, result.Err, "a failed read falls back to the last-known value")
+	assert.True(t, result.Stale)
+	assert.Equal(t, "v1", result.Value)
+	assert.Equal(t, 10*time.Second, result.Age)
+	assert.Equal(t, Closed, b.State("prod"), "below the threshold")
+
+	b.Do(ctx, "prod", "resources", fail(&calls))
+	assert.Equal(t, Open, b.State("prod"))
+	assert.Equal(t, 2.0, promtestutil.ToFloat64(stateGauge.WithLabelValues("prod")))
+
+	result = b.Do(ctx, "prod", "resources", fail(&calls))
+	assert.Equal(t, 2, calls, "an open circuit skips the cluster")
+	assert.True(t, result.Stale)
+	assert.Equal(t, "v1", result.Value)
+
+	result = b.Do(ctx, "prod", "node-metrics", fail(&calls))
+	assert.ErrorIs(t, result.Err, ErrOpen, "no last-known value of this key")
+	assert.Equal(t, 2, calls)
+}
+
+func TestDo_HalfOpenProbe(t *testing.T) {
+	b, clock := newTestBreaker(Config{FailureThreshold: 1, OpenDuration: time.Minute})
+	ctx := context.Background()
+	calls := 0
+
+	b.Do(ctx, "prod", "resources", fail(&calls))
+	require.Equal(t, Open, b.State("prod"))
+
+	// A failed probe opens the circuit for another full duration
+	clock.Advance(time.Minute)
+	b.Do(ctx, "prod", "resources", fail(&calls))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, Open, b.State("prod"))
+	clock.Advance(30 * time.Second)
+	b.Do(ctx, "prod", "resources", fail(&calls))
+	assert.Equal(t, 2, calls)
+
+	// Only one request probes; others are answered as if the circuit were open
+	clock.Advance(30 * time.Second)
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan Result)
+	go func() {
+		done <- b.Do(ctx, "prod", "resources", func(context.Context) (interface{}, error) {
+			close(probing)
+			<-release
+			return "v2", nil
+		})
+	}()
+	<-probing
+	assert.Equal(t, HalfOpen, b.State("prod"))
+	assert.ErrorIs(t, b.Do(ctx, "prod", "resources", fail(&calls)).Err, ErrOpen)
+	close(release)
+	assert.Equal(t, "v2", (<-done).Value)
+	assert.Equal(t, Closed, b.State("prod"))
+}
+
+func TestDo_CanceledReadsDoNotCount(t *testing.T) {
+	b, _ := newTestBreaker(Config{FailureThreshold: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := b.Do(ctx, "prod", "resources", func(ctx context.Context) (interface{}, error) { return nil, ctx.Err() })
+	assert.ErrorIs(t, result.Err, context.Canceled)
+	assert.Equal(t, Closed, b.State("prod"))
+}
+
+func TestDo_MaxAgeAndForget(t *testing.T) {
+	b, clock := newTestBreaker(Config{FailureThreshold: 1, OpenDuration: time.Hour, MaxAge: 5 * time.Minute})
+	ctx := context.Background()
+	calls := 0
+
+	b.Do(ctx, "prod", "resources", succeed("v1"))
+	b.Do(ctx, "prod", "resources", fail(&calls))
+	clock.Advance(6 * time.Minute)
+	assert.ErrorIs(t, b.Do(ctx, "prod", "resources", fail(&calls)).Err, ErrOpen, "too old to serve")
+
+	b.Forget("prod")
+	assert.Equal(t, Closed, b.State("prod"))
+	assert.NoError(t, b.Do(ctx, "prod", "resources", succeed("v2")).Err)
+}
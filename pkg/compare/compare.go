@@ -0,0 +1,178 @@
+// Package compare diffs the settings of same-named workloads across clusters: replicas,
+// images, environment and resources, so divergence between stages stands out
+package compare
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Comparison statuses of a workload
+const (
+	StatusSame      = "same"      // Present everywhere with identical settings
+	StatusDifferent = "different" // Present everywhere with differing settings
+	StatusMissing   = "missing"   // Absent from at least one cluster
+)
+
+// Workload is the comparable part of a workload in one cluster
+type Workload struct {
+	Replicas *int32 // Nil for kinds without replicas, such as DaemonSets
+	Template corev1.PodSpec
+}
+
+// Options tune how values are reported
+type Options struct {
+	// Sensitive reports environment variable names whose values must not be shown. Their
+	// values are still compared but reported as a short digest.
+	Sensitive func(name string) bool
+}
+
+// Difference is one field whose value is not the same in every cluster
+type Difference struct {
+	Field  string            `json:"field"`
+	Values map[string]string `json:"values"` // Value per cluster; clusters without the field are left out
+}
+
+// Result is the comparison of one workload
+type Result struct {
+	Name        string       `json:"name"`
+	Status      string       `json:"status"`
+	PresentIn   []string     `json:"present_in"`
+	MissingIn   []string     `json:"missing_in,omitempty"`
+	Differences []Difference `json:"differences,omitempty"`
+}
+
+// Compare compares the workloads of the same name across clusters. workloads maps a cluster
+// to its workloads by name, and clusters sets the order clusters are reported in. Results
+// are sorted by name; fields are compared only between the clusters that have the workload.
+func Compare(clusters []string, workloads map[string]map[string]Workload, opts Options) []Result {
+	names := make(map[string]bool)
+	for _, byName := range workloads {
+		for name := range byName {
+			names[name] = true
+		}
+	}
+
+	results := make([]Result, 0, len(names))
+	for name := range names {
+		result := Result{Name: name, PresentIn: []string{}}
+		fields := make(map[string]map[string]string) // Cluster by field
+		for _, cluster := range clusters {
+			w, ok := workloads[cluster][name]
+			if !ok {
+				result.MissingIn = append(result.MissingIn, cluster)
+				continue
+			}
+			result.PresentIn = append(result.PresentIn, cluster)
+			for field, value := range Fields(w, opts) {
+				if fields[field] == nil {
+					fields[field] = make(map[string]string)
+				}
+				fields[field][cluster] = value
+			}
+		}
+
+		for field, values := range fields {
+			if !uniform(values, len(result.PresentIn)) {
+				result.Differences = append(result.Differences, Difference{Field: field, Values: values})
+			}
+		}
+		sort.Slice(result.Differences, func(i, j int) bool { return result.Differences[i].Field < result.Differences[j].Field })
+
+		switch {
+		case len(result.MissingIn) > 0:
+			result.Status = StatusMissing
+		case len(result.Differences) > 0:
+			result.Status = StatusDifferent
+		default:
+			result.Status = StatusSame
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
+
+// Fields flattens a workload into comparable field paths such as replicas,
+// containers.app.image, containers.app.env.LOG_LEVEL and containers.app.limits.memory
+func Fields(w Workload, opts Options) map[string]string {
+	fields := make(map[string]string)
+	if w.Replicas != nil {
+		fields["replicas"] = strconv.Itoa(int(*w.Replicas))
+	}
+	addContainers(fields, "initContainers", w.Template.InitContainers, opts)
+	addContainers(fields, "containers", w.Template.Containers, opts)
+	return fields
+}
+
+func addContainers(fields map[string]string, prefix string, containers []corev1.Container, opts Options) {
+	for _, c := range containers {
+		base := prefix + "." + c.Name + "."
+		fields[base+"image"] = c.Image
+		for _, env := range c.Env {
+			fields[base+"env."+env.Name] = envValue(env, opts)
+		}
+		if len(c.EnvFrom) > 0 {
+			sources := make([]string, 0, len(c.EnvFrom))
+			for _, from := range c.EnvFrom {
+				switch {
+				case from.ConfigMapRef != nil:
+					sources = append(sources, from.Prefix+"configMap:"+from.ConfigMapRef.Name)
+				case from.SecretRef != nil:
+					sources = append(sources, from.Prefix+"secret:"+from.SecretRef.Name)
+				}
+			}
+			fields[base+"envFrom"] = strings.Join(sources, ", ")
+		}
+		for resource, quantity := range c.Resources.Requests {
+			fields[base+"requests."+string(resource)] = quantity.String()
+		}
+		for resource, quantity := range c.Resources.Limits {
+			fields[base+"limits."+string(resource)] = quantity.String()
+		}
+	}
+}
+
+// envValue renders an environment variable's value or the reference it is read from
+func envValue(env corev1.EnvVar, opts Options) string {
+	if from := env.ValueFrom; from != nil {
+		switch {
+		case from.SecretKeyRef != nil:
+			return "secretKeyRef:" + from.SecretKeyRef.Name + "/" + from.SecretKeyRef.Key
+		case from.ConfigMapKeyRef != nil:
+			return "configMapKeyRef:" + from.ConfigMapKeyRef.Name + "/" + from.ConfigMapKeyRef.Key
+		case from.FieldRef != nil:
+			return "fieldRef:" + from.FieldRef.FieldPath
+		case from.ResourceFieldRef != nil:
+			return "resourceFieldRef:" + from.ResourceFieldRef.Resource
+		}
+	}
+	if opts.Sensitive != nil && opts.Sensitive(env.Name) {
+		sum := sha256.Sum256([]byte(env.Value))
+		return "sha256:" + hex.EncodeToString(sum[:])[:12]
+	}
+	return env.Value
+}
+
+// uniform reports whether every one of present clusters has the same value
+func uniform(values map[string]string, present int) bool {
+	if len(values) != present {
+		return false
+	}
+	var first *string
+	for _, v := range values {
+		if first == nil {
+			first = &v
+			continue
+		}
+		if v != *first {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package compare
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func workload(replicas int32, image, logLevel, password, memory string) Workload {
+	return Workload{
+		Replicas: &replicas,
+		Template: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "app",
+			Image: image,
+			Env: []corev1.EnvVar{
+				{Name: "LOG_LEVEL", Value: logLevel},
+				{Name: "DB_PASSWORD", Value: password},
+				{Name: "API_TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "api"}, Key: "token",
+				}}},
+			},
+			Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(memory)}},
+		}}},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	opts := Options{Sensitive: func(name string) bool { return strings.Contains(strings.ToLower(name), "password") }}
+	results := Compare([]string{"staging", "prod"}, map[string]map[string]Workload{
+		"staging": {
+			"web":    workload(1, "web:2", "debug", "s3cret", "512Mi"),
+			"api":    workload(2, "api:1", "info", "x", "1Gi"),
+			"worker": workload(1, "worker:1", "info", "x", "1Gi"),
+		},
+		"prod": {
+			"web": workload(3, "web:1", "info", "other", "512Mi"),
+			"api": workload(2, "api:1", "info", "x", "1Gi"),
+		},
+	}, opts)
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "api", results[0].Name)
+	assert.Equal(t, StatusSame, results[0].Status)
+	assert.Empty(t, results[0].Differences)
+
+	web := results[1]
+	assert.Equal(t, StatusDifferent, web.Status)
+	fields := make([]string, 0, len(web.Differences))
+	for _, d := range web.Differences {
+		fields = append(fields, d.Field)
+	}
+	assert.Equal(t, []string{"containers.app.env.DB_PASSWORD", "containers.app.env.LOG_LEVEL", "containers.app.image", "replicas"}, fields)
+	assert.Equal(t, map[string]string{"staging": "web:2", "prod": "web:1"}, web.Differences[2].Values)
+	assert.True(t, strings.HasPrefix(web.Differences[0].Values["prod"], "sha256:"), "sensitive values are not shown")
+	assert.NotContains(t, web.Differences[0].Values["staging"], "s3cret")
+
+	worker := results[2]
+	assert.Equal(t, StatusMissing, worker.Status)
+	assert.Equal(t, []string{"staging"}, worker.PresentIn)
+	assert.Equal(t, []string{"prod"}, worker.MissingIn)
+}
+
+func TestCompare_FieldOnlyInOneCluster(t *testing.T) {
+	withDebug := workload(1, "web:1", "info", "x", "1Gi")
+	withDebug.Template.Containers[0].Env = append(withDebug.Template.Containers[0].Env, corev1.EnvVar{Name: "DEBUG", Value: "1"})
+
+	results := Compare([]string{"a", "b"}, map[string]map[string]Workload{
+		"a": {"web": withDebug},
+		"b": {"web": workload(1, "web:1", "info", "x", "1Gi")},
+	}, Options{})
+
+	require.Len(t, results, 1)
+	require.Len(t, results[0].Differences, 1)
+	assert.Equal(t, "containers.app.env.DEBUG", results[0].Differences[0].Field)
+	assert.Equal(t, map[string]string{"a": "1"}, results[0].Differences[0].Values)
+}
+
+func TestFields(t *testing.T) {
+	fields := Fields(workload(2, "web:1", "info", "x", "256Mi"), Options{})
+	assert.Equal(t, "2", fields["replicas"])
+	assert.Equal(t, "256Mi", fields["containers.app.limits.memory"])
+	assert.Equal(t, "secretKeyRef:api/token", fields["containers.app.env.API_TOKEN"])
+
+	_, ok := Fields(Workload{}, Options{})["replicas"]
+	assert.False(t, ok, "kinds without replicas have no replicas field")
+}
@@ -0,0 +1,181 @@
+// Package cors decides which cross-origin requests browsers may make to the API and
+// computes the headers answering them and their preflight requests
+package cors
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Header names read and set by the policy
+const (
+	Origin         = "Origin"
+	RequestMethod  = "Access-Control-Request-Method"
+	RequestHeaders = "Access-Control-Request-Headers"
+	AllowOrigin    = "Access-Control-Allow-Origin"
+	AllowMethods   = "Access-Control-Allow-Methods"
+	AllowHeaders   = "Access-Control-Allow-Headers"
+	MaxAge         = "Access-Control-Max-Age"
+	Vary           = "Vary"
+)
+
+// Defaults applied to unset configuration
+const (
+	DefaultAllowMethods = "GET, POST, PUT, DELETE, OPTIONS"
+	DefaultAllowHeaders = "Content-Type, Authorization"
+)
+
+const wildcard = "*"
+
+// Config holds the cross-origin settings, read from the swagger_ui.cors_* options
+type Config struct {
+	AllowOrigin  string // Comma-separated origins such as https://dash.example.com, or * for any
+	AllowMethods string // Comma-separated methods
+	AllowHeaders string // Comma-separated request headers, or * for any
+	MaxAge       int    // Seconds browsers may cache a preflight answer, 0 leaves it to the browser
+}
+
+// Header is a single response header
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Policy answers cross-origin requests
+type Policy struct {
+	anyOrigin    bool
+	origins      map[string]bool
+	methods      map[string]bool
+	anyHeader    bool
+	headers      map[string]bool
+	allowMethods string
+	allowHeaders string
+	maxAge       int
+}
+
+// New creates a policy with defaults applied to unset settings. Origins must be a scheme
+// and host, without a path.
+func New(cfg Config) (*Policy, error) {
+	if strings.TrimSpace(cfg.AllowOrigin) == "" {
+		cfg.AllowOrigin = wildcard
+	}
+	if strings.TrimSpace(cfg.AllowMethods) == "" {
+		cfg.AllowMethods = DefaultAllowMethods
+	}
+	if strings.TrimSpace(cfg.AllowHeaders) == "" {
+		cfg.AllowHeaders = DefaultAllowHeaders
+	}
+	if cfg.MaxAge < 0 {
+		return nil, fmt.Errorf("cors max age must not be negative, got %d", cfg.MaxAge)
+	}
+
+	p := &Policy{
+		origins: make(map[string]bool),
+		methods: make(map[string]bool),
+		headers: make(map[string]bool),
+		maxAge:  cfg.MaxAge,
+	}
+	for _, origin := range splitList(cfg.AllowOrigin) {
+		if origin == wildcard {
+			p.anyOrigin = true
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return nil, fmt.Errorf("invalid cors origin %q, expected a scheme and host such as https://dash.example.com", origin)
+		}
+		p.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+
+	methods := splitList(cfg.AllowMethods)
+	for i, method := range methods {
+		methods[i] = strings.ToUpper(method)
+		p.methods[methods[i]] = true
+	}
+	p.allowMethods = strings.Join(methods, ", ")
+
+	headers := splitList(cfg.AllowHeaders)
+	for _, header := range headers {
+		if header == wildcard {
+			p.anyHeader = true
+		}
+		p.headers[strings.ToLower(header)] = true
+	}
+	p.allowHeaders = strings.Join(headers, ", ")
+	return p, nil
+}
+
+// Headers returns the headers of a response to a cross-origin request. ok is false when
+// the origin is not allowed, and the response then carries no CORS headers.
+func (p *Policy) Headers(origin string) (headers []Header, ok bool) {
+	value, ok := p.allowOrigin(origin)
+	if !ok {
+		return nil, false
+	}
+	headers = []Header{{Name: AllowOrigin, Value: value}}
+	if value != wildcard {
+		headers = append(headers, Header{Name: Vary, Value: Origin})
+	}
+	return headers, true
+}
+
+// Preflight returns the headers answering a preflight request, which asks whether a request
+// with the method and comma-separated headers may be sent. The error names what is not
+// allowed.
+func (p *Policy) Preflight(origin, method, requestHeaders string) ([]Header, error) {
+	value, ok := p.allowOrigin(origin)
+	if !ok {
+		return nil, fmt.Errorf("origin %s is not allowed", origin)
+	}
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if !p.methods[method] {
+		return nil, fmt.Errorf("method %s is not allowed, expected one of %s", method, p.allowMethods)
+	}
+	if !p.anyHeader {
+		for _, header := range splitList(requestHeaders) {
+			if !p.headers[strings.ToLower(header)] {
+				return nil, fmt.Errorf("header %s is not allowed, expected one of %s", header, p.allowHeaders)
+			}
+		}
+	}
+
+	headers := []Header{
+		{Name: AllowOrigin, Value: value},
+		{Name: AllowMethods, Value: p.allowMethods},
+		{Name: AllowHeaders, Value: p.allowHeaders},
+		{Name: Vary, Value: strings.Join([]string{Origin, RequestMethod, RequestHeaders}, ", ")},
+	}
+	if p.anyHeader && requestHeaders != "" {
+		headers[2].Value = requestHeaders // Credentialed requests do not honor a * wildcard
+	}
+	if p.maxAge > 0 {
+		headers = append(headers, Header{Name: MaxAge, Value: strconv.Itoa(p.maxAge)})
+	}
+	return headers, nil
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for an origin
+func (p *Policy) allowOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	if p.anyOrigin {
+		return wildcard, true
+	}
+	if p.origins[strings.ToLower(origin)] {
+		return origin, true
+	}
+	return "", false
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,73 @@
+package cors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func headerMap(headers []Header) map[string]string {
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		m[h.Name] = h.Value
+	}
+	return m
+}
+
+func TestHeaders(t *testing.T) {
+	wildcardPolicy, err := New(Config{})
+	require.NoError(t, err)
+	headers, ok := wildcardPolicy.Headers("https://dash.example.com")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{AllowOrigin: "*"}, headerMap(headers))
+
+	_, ok = wildcardPolicy.Headers("")
+	assert.False(t, ok, "same-origin and non-browser requests carry no Origin")
+
+	listed, err := New(Config{AllowOrigin: "https://dash.example.com, http://localhost:3000/"})
+	require.NoError(t, err)
+	headers, ok = listed.Headers("http://localhost:3000")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{AllowOrigin: "http://localhost:3000", Vary: Origin}, headerMap(headers))
+	_, ok = listed.Headers("https://evil.example.com")
+	assert.False(t, ok)
+}
+
+func TestPreflight(t *testing.T) {
+	p, err := New(Config{AllowOrigin: "https://dash.example.com", AllowMethods: "get, post", MaxAge: 600})
+	require.NoError(t, err)
+
+	headers, err := p.Preflight("https://dash.example.com", "POST", "content-type, Authorization")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		AllowOrigin:  "https://dash.example.com",
+		AllowMethods: "GET, POST",
+		AllowHeaders: DefaultAllowHeaders,
+		MaxAge:       "600",
+		Vary:         "Origin, Access-Control-Request-Method, Access-Control-Request-Headers",
+	}, headerMap(headers))
+
+	_, err = p.Preflight("https://evil.example.com", "GET", "")
+	assert.ErrorContains(t, err, "origin https://evil.example.com is not allowed")
+	_, err = p.Preflight("https://dash.example.com", "DELETE", "")
+	assert.ErrorContains(t, err, "method DELETE is not allowed")
+	_, err = p.Preflight("https://dash.example.com", "GET", "X-Custom")
+	assert.ErrorContains(t, err, "header X-Custom is not allowed")
+
+	anyHeader, err := New(Config{AllowHeaders: "*"})
+	require.NoError(t, err)
+	headers, err = anyHeader.Preflight("https://dash.example.com", "PUT", "X-Custom")
+	require.NoError(t, err)
+	assert.Equal(t, "X-Custom", headerMap(headers)[AllowHeaders])
+	assert.NotContains(t, headerMap(headers), MaxAge)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for _, origin := range []string{"dash.example.com", "https://dash.example.com/app", "https://"} {
+		_, err := New(Config{AllowOrigin: origin})
+		assert.Error(t, err, origin)
+	}
+	_, err := New(Config{MaxAge: -1})
+	assert.Error(t, err)
+}
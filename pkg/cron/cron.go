@@ -0,0 +1,159 @@
+// Package cron parses five-field cron expressions shared by change freezes and report schedules
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five-field cron expression: minute hour day-of-month month day-of-week
+type Schedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	domAny, dowAny                bool
+}
+
+// Parse parses lists, ranges, steps and "*" in each field
+func Parse(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+	sets := make([]map[int]bool, 5)
+	for i, field := range fields {
+		set, err := parseField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("field %d (%q): %w", i+1, field, err)
+		}
+		sets[i] = set
+	}
+
+	// Sunday may be written as 7
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+
+	return &Schedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	if field == "*" {
+		for v := min; v <= max; v++ {
+			set[v] = true
+		}
+		return set, nil
+	}
+
+	// Day-of-week accepts 7 for Sunday
+	if max == 6 {
+		max = 7
+	}
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if rangePart, stepPart, ok := strings.Cut(part, "/"); ok {
+			s, err := strconv.Atoi(stepPart)
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = s
+			part = rangePart
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			from, to, isRange := strings.Cut(part, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid value %q", from)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return nil, fmt.Errorf("invalid value %q", to)
+				}
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("value out of range %d-%d", min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// Matches reports whether the schedule fires at the minute of t
+func (s *Schedule) Matches(t time.Time) bool {
+	return s.minute[t.Minute()] && s.hour[t.Hour()] && s.matchesDay(t)
+}
+
+// Previous returns the latest minute at or before t, in t's location, at which the schedule
+// fires, if one is later than after. Days and hours the schedule skips are passed over whole,
+// so a search back over weeks only visits the minutes of matching hours.
+func (s *Schedule) Previous(t, after time.Time) (time.Time, bool) {
+	for t = t.Truncate(time.Minute); t.After(after); {
+		year, month, day := t.Date()
+		switch {
+		case !s.matchesDay(t):
+			t = time.Date(year, month, day, 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+		case !s.hour[t.Hour()]:
+			t = time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location()).Add(-time.Minute)
+		case !s.minute[t.Minute()]:
+			t = t.Add(-time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// matchesDay reports whether the schedule fires on the day of t
+func (s *Schedule) matchesDay(t time.Time) bool {
+	if !s.month[int(t.Month())] {
+		return false
+	}
+
+	// Standard cron semantics: when both day fields are restricted, either may match
+	domMatch := s.dom[t.Day()]
+	dowMatch := s.dow[int(t.Weekday())]
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// maxLookahead bounds Next; every valid schedule fires at least once in four years
+const maxLookahead = 4 * 366 * 24 * time.Hour
+
+// Next returns the first minute after t, in t's location, at which the schedule fires.
+// It returns the zero time for schedules that never fire, such as "0 0 31 2 *".
+func (s *Schedule) Next(t time.Time) time.Time {
+	end := t.Add(maxLookahead)
+	for next := t.Truncate(time.Minute).Add(time.Minute); next.Before(end); next = next.Add(time.Minute) {
+		if s.Matches(next) {
+			return next
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,95 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	require.NoError(t, err)
+	return parsed
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse("*/15 9-17 * * 1-5")
+	require.NoError(t, err)
+
+	assert.True(t, s.Matches(mustTime(t, "2025-06-02T09:45:00Z")))  // Monday
+	assert.False(t, s.Matches(mustTime(t, "2025-06-02T09:50:00Z"))) // Not on a 15-minute step
+	assert.False(t, s.Matches(mustTime(t, "2025-06-01T09:45:00Z"))) // Sunday
+
+	s, err = Parse("0 0 * * 7")
+	require.NoError(t, err)
+	assert.True(t, s.Matches(mustTime(t, "2025-06-01T00:00:00Z")), "7 means Sunday")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	s, err := Parse("0 8 * * 1")
+	require.NoError(t, err)
+	assert.Equal(t, mustTime(t, "2025-06-02T08:00:00Z"), s.Next(mustTime(t, "2025-05-30T12:34:56Z")))
+	assert.Equal(t, mustTime(t, "2025-06-09T08:00:00Z"), s.Next(mustTime(t, "2025-06-02T08:00:00Z")), "next is strictly after t")
+
+	never, err := Parse("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, never.Next(mustTime(t, "2025-01-01T00:00:00Z")).IsZero())
+}
+
+func TestSchedule_Previous(t *testing.T) {
+	s, err := Parse("30 8 * * 1")
+	require.NoError(t, err)
+
+	prev, ok := s.Previous(mustTime(t, "2025-06-05T12:34:56Z"), mustTime(t, "2025-05-01T00:00:00Z"))
+	require.True(t, ok)
+	assert.Equal(t, mustTime(t, "2025-06-02T08:30:00Z"), prev)
+
+	prev, ok = s.Previous(mustTime(t, "2025-06-02T08:30:59Z"), mustTime(t, "2025-05-01T00:00:00Z"))
+	require.True(t, ok)
+	assert.Equal(t, mustTime(t, "2025-06-02T08:30:00Z"), prev, "t itself counts")
+
+	_, ok = s.Previous(mustTime(t, "2025-06-05T12:00:00Z"), mustTime(t, "2025-06-02T08:30:00Z"))
+	assert.False(t, ok, "after is exclusive")
+
+	// The local time of t's location decides
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	prev, ok = s.Previous(mustTime(t, "2025-06-05T12:00:00Z").In(berlin), mustTime(t, "2025-05-01T00:00:00Z"))
+	require.True(t, ok)
+	assert.Equal(t, mustTime(t, "2025-06-02T06:30:00Z"), prev.UTC())
+}
+
+// TestSchedule_PreviousMatchesScan tests Previous against a minute by minute scan
+func TestSchedule_PreviousMatchesScan(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	start := mustTime(t, "2025-03-01T00:00:00Z").In(newYork) // Spans the March DST change
+
+	for _, expr := range []string{"*/7 9-17 * * 1-5", "0 0 1,15 * *", "45 2 * * 0", "0 12 13 * 5"} {
+		s, err := Parse(expr)
+		require.NoError(t, err)
+		for now := start; now.Before(start.Add(40 * 24 * time.Hour)); now = now.Add(97 * time.Minute) {
+			after := now.Add(-10 * 24 * time.Hour)
+			var want time.Time
+			for m := now.Truncate(time.Minute); m.After(after); m = m.Add(-time.Minute) {
+				if s.Matches(m) {
+					want = m
+					break
+				}
+			}
+			got, ok := s.Previous(now, after)
+			assert.Equal(t, !want.IsZero(), ok, "%s at %s", expr, now)
+			assert.True(t, want.Equal(got), "%s at %s: want %s, got %s", expr, now, want, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package csrf implements session-less double-submit cookie CSRF protection
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Defaults applied to unset configuration
+const (
+	DefaultCookieName = "csrf_token"
+	DefaultHeaderName = "X-CSRF-Token"
+)
+
+// Errors returned by Validate
+var (
+	ErrMissingToken  = errors.New("CSRF token missing")
+	ErrInvalidToken  = errors.New("CSRF token invalid")
+	ErrTokenMismatch = errors.New("CSRF token does not match cookie")
+)
+
+// Config holds CSRF protection settings
+type Config struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	CookieName      string        `mapstructure:"cookie_name"`
+	HeaderName      string        `mapstructure:"header_name"`
+	CookieMaxAge    time.Duration `mapstructure:"cookie_max_age"`
+	Secret          string        `mapstructure:"secret"`            // Signs tokens so injected cookies are rejected, empty uses plain random tokens
+	ExemptTokenAuth bool          `mapstructure:"exempt_token_auth"` // Skip requests carrying Authorization or X-API-Key, which browsers never send on their own
+	ExemptPaths     []string      `mapstructure:"exempt_paths"`      // Path prefixes that are never checked
+}
+
+// Protector issues and validates double-submit tokens
+type Protector struct {
+	config Config
+}
+
+// New creates a protector with defaults applied to unset settings
+func New(cfg Config) *Protector {
+	if cfg.CookieName == "" {
+		cfg.CookieName = DefaultCookieName
+	}
+	if cfg.HeaderName == "" {
+		cfg.HeaderName = DefaultHeaderName
+	}
+	if cfg.CookieMaxAge <= 0 {
+		cfg.CookieMaxAge = 12 * time.Hour
+	}
+	return &Protector{config: cfg}
+}
+
+// CookieName returns the name of the token cookie
+func (p *Protector) CookieName() string {
+	return p.config.CookieName
+}
+
+// HeaderName returns the header that must echo the cookie on mutating requests
+func (p *Protector) HeaderName() string {
+	return p.config.HeaderName
+}
+
+// CookieMaxAge returns how long issued cookies stay valid in the browser
+func (p *Protector) CookieMaxAge() time.Duration {
+	return p.config.CookieMaxAge
+}
+
+// RequiresCheck reports whether a request must carry a matching token. Safe methods,
+// exempt paths and, when configured, requests with explicit credentials are skipped.
+func (p *Protector) RequiresCheck(method, path string, hasTokenAuth bool) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return false
+	}
+	if p.config.ExemptTokenAuth && hasTokenAuth {
+		return false
+	}
+	for _, prefix := range p.config.ExemptPaths {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// NewToken returns a random token, signed when a secret is configured
+func (p *Protector) NewToken() (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(nonce)
+	if p.config.Secret != "" {
+		token += "." + p.sign(token)
+	}
+	return token, nil
+}
+
+// Valid reports whether a cookie value is a token this protector could have issued
+func (p *Protector) Valid(token string) bool {
+	if token == "" {
+		return false
+	}
+	if p.config.Secret == "" {
+		return true
+	}
+	nonce, signature, ok := strings.Cut(token, ".")
+	return ok && hmac.Equal([]byte(signature), []byte(p.sign(nonce)))
+}
+
+// Validate checks that the header echoes the cookie and that the cookie is genuine
+func (p *Protector) Validate(cookie, header string) error {
+	if cookie == "" || header == "" {
+		return ErrMissingToken
+	}
+	if subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) != 1 {
+		return ErrTokenMismatch
+	}
+	if !p.Valid(cookie) {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (p *Protector) sign(nonce string) string {
+	mac := hmac.New(sha256.New, []byte(p.config.Secret))
+	mac.Write([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,55 @@
+package csrf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Defaults(t *testing.T) {
+	p := New(Config{Enabled: true})
+	assert.Equal(t, DefaultCookieName, p.CookieName())
+	assert.Equal(t, DefaultHeaderName, p.HeaderName())
+	assert.Positive(t, p.CookieMaxAge())
+}
+
+func TestRequiresCheck(t *testing.T) {
+	p := New(Config{ExemptTokenAuth: true, ExemptPaths: []string{"/hooks/"}})
+
+	assert.False(t, p.RequiresCheck("GET", "/deployments", false))
+	assert.True(t, p.RequiresCheck("POST", "/deployments", false))
+	assert.False(t, p.RequiresCheck("POST", "/deployments", true))
+	assert.False(t, p.RequiresCheck("POST", "/hooks/github", false))
+	assert.True(t, p.RequiresCheck("POST", "/hooksmith", false))
+
+	strict := New(Config{})
+	assert.True(t, strict.RequiresCheck("DELETE", "/clusters", true))
+}
+
+func TestValidate_Unsigned(t *testing.T) {
+	p := New(Config{})
+	token, err := p.NewToken()
+	require.NoError(t, err)
+
+	assert.NoError(t, p.Validate(token, token))
+	assert.ErrorIs(t, p.Validate(token, ""), ErrMissingToken)
+	assert.ErrorIs(t, p.Validate("", token), ErrMissingToken)
+	assert.ErrorIs(t, p.Validate(token, token+"x"), ErrTokenMismatch)
+}
+
+func TestValidate_Signed(t *testing.T) {
+	p := New(Config{Secret: "s3cret"})
+	token, err := p.NewToken()
+	require.NoError(t, err)
+	assert.Contains(t, token, ".")
+	assert.True(t, p.Valid(token))
+	assert.NoError(t, p.Validate(token, token))
+
+	// A cookie planted by another origin matches its header but lacks a valid signature
+	assert.ErrorIs(t, p.Validate("attacker", "attacker"), ErrInvalidToken)
+
+	other, err := New(Config{Secret: "other"}).NewToken()
+	require.NoError(t, err)
+	assert.False(t, p.Valid(other))
+}
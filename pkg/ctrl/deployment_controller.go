@@ -3,23 +3,41 @@ package ctrl
 
 import (
 	context "context"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/environment"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/freeze"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/kubemetrics"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ownership"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/tracing"
 )
 
 // DeploymentReconciler handles basic deployment reconciliation
@@ -44,23 +62,58 @@ type ClusterConfig struct {
 	Namespace   string // Namespace to watch (empty for all)
 	ClusterID   string // Unique ID for this cluster
 	APIEndpoint string // API server endpoint
+
+	// Client tuning, zero keeps the client-go defaults
+	QPS     float32       // Sustained requests per second to the API server, for the manager and clientset
+	Burst   int           // Requests allowed above QPS in a burst, for the manager and clientset
+	Timeout time.Duration // Timeout of each clientset request; the manager's watches are long-lived and exempt
 	
 	// Leader election settings
 	LeaderElection struct {
 		Enabled   bool   // Enable leader election
 		Namespace string // Namespace for leader election resources
 		ID        string // Unique ID for leader election
+
+		// Lease timings, zero keeps the controller-runtime defaults (15s, 10s and 2s)
+		LeaseDuration time.Duration // How long non-leaders wait before taking over an unrenewed lease
+		RenewDeadline time.Duration // How long the leader keeps retrying to renew before giving up
+		RetryPeriod   time.Duration // Interval between acquire and renew attempts
 	}
 	
 	// Metrics settings
 	MetricsBindAddress string // Address for metrics server, empty to disable
+
+	// Address serving the manager's /healthz and /readyz probes, empty to disable
+	HealthProbeBindAddress string
+
+	SchemeGroups []string                  // API groups registered in the scheme, empty for DefaultSchemeGroups
+	Watches      []schema.GroupVersionKind // Kinds to report events for, checked against discovery
+
+	// Deletion of finished Jobs after a TTL, for clusters without the TTL-after-finished controller
+	JobGC JobGCConfig
+
+	// Ephemeral environments cloned from a source namespace and deleted after a TTL
+	Environments EnvironmentConfig
 }
 
 // MultiClusterManager manages controllers for multiple Kubernetes clusters
 type MultiClusterManager struct {
+	// mu guards the per-cluster maps below, which API requests read while clusters are added
+	// and removed. Code that also takes leaderMu or runMu takes those first.
+	mu          sync.RWMutex
 	managers    map[string]manager.Manager
 	configs     map[string]ClusterConfig
 	controllers map[string]controller.Controller
+	clientsets  map[string]kubernetes.Interface // Shared per cluster so QPS and Burst limit all requests together
+	probes      map[string]*managerProbes      // Health and readiness checks of each manager
+
+	leaderMu sync.Mutex               // Guards leaders, which is updated from manager goroutines
+	leaders  map[string]*LeaderStatus // Leader-election status of started managers
+
+	events *clusterEventLog // Lifecycle history of current and removed clusters
+
+	runMu   sync.Mutex                 // Guards running, which is updated from manager goroutines
+	running map[string]*runningManager // Started managers that have not stopped yet
 }
 
 // Reconcile handles reconciliation of Deployment objects for basic reconciler
@@ -71,6 +124,8 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 // Reconcile handles reconciliation of Deployment objects for the enhanced controller
 func (r *DeploymentController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	defer clusterWork.begin(r.clusterID)()
+
 	// Get the deployment
 	var deployment appsv1.Deployment
 	if err := r.client.Get(ctx, req.NamespacedName, &deployment); err != nil {
@@ -85,6 +140,19 @@ func (r *DeploymentController) Reconcile(ctx context.Context, req ctrl.Request)
 		Str("name", deployment.Name).
 		Msg("Reconciling deployment")
 
+	// Automated changes wait until any change freeze for this cluster has ended
+	var freezeErr *freeze.Error
+	if err := freeze.Check(r.clusterID); errors.As(err, &freezeErr) {
+		log.Info().
+			Str("cluster_id", r.clusterID).
+			Str("namespace", deployment.Namespace).
+			Str("name", deployment.Name).
+			Str("window", freezeErr.Window).
+			Time("until", freezeErr.Until).
+			Msg("Change freeze in effect, deferring reconciliation")
+		return ctrl.Result{RequeueAfter: time.Until(freezeErr.Until)}, nil
+	}
+
 	// No need to do anything else since our goal is just to log events
 
 	return ctrl.Result{}, nil
@@ -92,6 +160,13 @@ func (r *DeploymentController) Reconcile(ctx context.Context, req ctrl.Request)
 
 // NewManager creates a new controller manager for a specific cluster
 func NewManager(cfg ClusterConfig) (manager.Manager, error) {
+	mgr, _, err := newManager(cfg)
+	return mgr, err
+}
+
+// clusterRestConfig builds the REST config of a cluster from its in-cluster or kubeconfig
+// settings, with the client tuning applied
+func clusterRestConfig(cfg ClusterConfig) (*rest.Config, error) {
 	var config *rest.Config
 	var err error
 
@@ -125,13 +200,81 @@ func NewManager(cfg ClusterConfig) (manager.Manager, error) {
 		}
 	}
 
+	if cfg.QPS < 0 || cfg.Burst < 0 || cfg.Timeout < 0 {
+		return nil, errors.New("client qps, burst and timeout must not be negative")
+	}
+	if cfg.QPS > 0 {
+		config.QPS = cfg.QPS
+	}
+	if cfg.Burst > 0 {
+		config.Burst = cfg.Burst
+	} else if config.QPS > 0 && config.Burst == 0 {
+		// client-go refuses a QPS without a burst; allow at least one second's worth
+		config.Burst = max(rest.DefaultBurst, int(config.QPS))
+	}
+	return config, nil
+}
+
+// newManager creates a cluster's controller manager along with the probe checks registered on it
+func newManager(cfg ClusterConfig) (manager.Manager, *managerProbes, error) {
+	config, err := clusterRestConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Record the API calls made on behalf of traced requests and reconciles, and the latency
+	// of every call
+	config.Wrap(tracing.WrapTransport(cfg.ClusterID))
+	config.Wrap(kubemetrics.WrapTransport(cfg.ClusterID))
+
+	groups := cfg.SchemeGroups
+	if cfg.JobGC.AppliesTo(cfg.ClusterID) {
+		// The Job garbage collector needs the batch types whatever else is configured
+		if len(groups) == 0 {
+			groups = DefaultSchemeGroups
+		}
+		groups = append(slices.Clone(groups), "batch")
+	}
+	if cfg.Environments.AppliesTo(cfg.ClusterID) {
+		// Environments clone ConfigMaps, Secrets and Services and create namespaces
+		if len(groups) == 0 {
+			groups = DefaultSchemeGroups
+		}
+		groups = append(slices.Clone(groups), "core")
+	}
+	scheme, err := NewScheme(groups)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Create manager options
+	skipNameValidation := true
 	options := ctrl.Options{
-		Scheme: Scheme(),
+		Scheme: scheme,
 		// Leader election settings
 		LeaderElection:          cfg.LeaderElection.Enabled,
 		LeaderElectionNamespace: cfg.LeaderElection.Namespace,
 		LeaderElectionID:        cfg.LeaderElection.ID,
+		// Every cluster's manager runs the same controllers in one process, and a cluster
+		// removed and added again registers them again
+		Controller: ctrlconfig.Controller{SkipNameValidation: &skipNameValidation},
+	}
+	if cfg.LeaderElection.Enabled {
+		// Give the lease up when the manager stops, after its controllers have finished, so
+		// another replica takes over without waiting for the lease to expire
+		options.LeaderElectionReleaseOnCancel = true
+		if err := ValidateLeaderElectionTimings(cfg.LeaderElection.LeaseDuration, cfg.LeaderElection.RenewDeadline, cfg.LeaderElection.RetryPeriod); err != nil {
+			return nil, nil, err
+		}
+		if d := cfg.LeaderElection.LeaseDuration; d > 0 {
+			options.LeaseDuration = &d
+		}
+		if d := cfg.LeaderElection.RenewDeadline; d > 0 {
+			options.RenewDeadline = &d
+		}
+		if d := cfg.LeaderElection.RetryPeriod; d > 0 {
+			options.RetryPeriod = &d
+		}
 	}
 	
 	// Add metrics server if configured
@@ -139,6 +282,11 @@ func NewManager(cfg ClusterConfig) (manager.Manager, error) {
 		options.Metrics.BindAddress = cfg.MetricsBindAddress
 	}
 
+	// Serve health probes if configured; the checks are registered either way
+	options.HealthProbeBindAddress = cfg.HealthProbeBindAddress
+	webhookServer := &trackedWebhookServer{Server: webhook.NewServer(webhook.Options{})}
+	options.WebhookServer = webhookServer
+
 	// Apply namespace filter if specified
 	if cfg.Namespace != "" {
 		options.Cache.DefaultNamespaces = map[string]cache.Config{
@@ -149,16 +297,26 @@ func NewManager(cfg ClusterConfig) (manager.Manager, error) {
 	// Create manager
 	mgr, err := ctrl.NewManager(config, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create manager: %w", err)
+		return nil, nil, fmt.Errorf("failed to create manager: %w", err)
 	}
 
-	return mgr, nil
+	probes := &managerProbes{}
+	if err := probes.addHealthz(mgr, CheckPing, healthz.Ping); err != nil {
+		return nil, nil, fmt.Errorf("failed to add health check: %w", err)
+	}
+	if err := probes.addReadyz(mgr, CheckCacheSync, cacheSyncChecker(mgr.GetCache())); err != nil {
+		return nil, nil, fmt.Errorf("failed to add readiness check: %w", err)
+	}
+	if err := probes.addReadyz(mgr, CheckWebhook, webhookServer.checker); err != nil {
+		return nil, nil, fmt.Errorf("failed to add readiness check: %w", err)
+	}
+
+	return mgr, probes, nil
 }
 
-// Scheme creates and returns a new scheme with required types registered
+// Scheme creates and returns a new scheme with the default API groups registered
 func Scheme() *runtime.Scheme {
-	scheme := runtime.NewScheme()
-	_ = appsv1.AddToScheme(scheme)
+	scheme, _ := NewScheme(nil)
 	return scheme
 }
 
@@ -189,6 +347,15 @@ func AddDeploymentControllerWithLogging(mgr manager.Manager, clusterID string) e
 		clusterID: clusterID,
 	}
 
+	// Read namespace ownership annotations from this cluster
+	ownership.Default().SetGetter(clusterID, func(ctx context.Context, name string) (map[string]string, error) {
+		ns, err := clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return ns.Annotations, nil
+	})
+
 	// Define event handlers that will log all events
 	eventLogger := predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
@@ -203,6 +370,7 @@ func AddDeploymentControllerWithLogging(mgr manager.Manager, clusterID string) e
 					Str("name", deployment.Name).
 					Int32("replicas", *deployment.Spec.Replicas).
 					Msg("Deployment created")
+				emitDeploymentEvent(eventID, r.clusterID, "CREATE", deployment)
 			}
 			return true
 		},
@@ -234,6 +402,7 @@ func AddDeploymentControllerWithLogging(mgr manager.Manager, clusterID string) e
 						Int32("replicas", *deployment.Spec.Replicas).
 						Msg("Deployment updated")
 				}
+				emitDeploymentEvent(eventID, r.clusterID, "UPDATE", deployment)
 			}
 			return true
 		},
@@ -248,6 +417,7 @@ func AddDeploymentControllerWithLogging(mgr manager.Manager, clusterID string) e
 					Str("namespace", deployment.Namespace).
 					Str("name", deployment.Name).
 					Msg("Deployment deleted")
+				emitDeploymentEvent(eventID, r.clusterID, "DELETE", deployment)
 			}
 			return true
 		},
@@ -271,7 +441,7 @@ func AddDeploymentControllerWithLogging(mgr manager.Manager, clusterID string) e
 	err = ctrl.NewControllerManagedBy(mgr).
 		For(&appsv1.Deployment{}).
 		WithEventFilter(eventLogger).
-		Complete(r)
+		Complete(traced("deployment", clusterID, r))
 	if err != nil {
 		return fmt.Errorf("failed to watch deployments: %w", err)
 	}
@@ -282,27 +452,68 @@ func AddDeploymentControllerWithLogging(mgr manager.Manager, clusterID string) e
 	return nil
 }
 
+// emitDeploymentEvent forwards a deployment event, enriched with the ownership of its namespace
+// in the cluster, to registered sink plugins. Without sinks there is nothing to look up.
+func emitDeploymentEvent(eventID, clusterID, eventType string, deployment *appsv1.Deployment) {
+	if len(plugin.Default().Sinks()) == 0 {
+		return
+	}
+	defer clusterWork.begin(clusterID)()
+
+	ctx := context.Background()
+	evt := plugin.Event{
+		ID:           eventID,
+		ClusterID:    clusterID,
+		Type:         eventType,
+		ResourceType: "Deployment",
+		Namespace:    deployment.Namespace,
+		Name:         deployment.Name,
+		Object:       deployment,
+	}
+	if owner := ownership.Default().Resolve(ctx, clusterID, deployment.Namespace); !owner.IsZero() {
+		evt.Owner = &owner
+	}
+	plugin.Emit(ctx, evt)
+}
+
 // NewMultiClusterManager creates a new manager for multiple Kubernetes clusters
 func NewMultiClusterManager() *MultiClusterManager {
 	return &MultiClusterManager{
 		managers:    make(map[string]manager.Manager),
 		configs:     make(map[string]ClusterConfig),
 		controllers: make(map[string]controller.Controller),
+		clientsets:  make(map[string]kubernetes.Interface),
+		leaders:     make(map[string]*LeaderStatus),
+		probes:      make(map[string]*managerProbes),
+		events:      newClusterEventLog(DefaultClusterEventHistory),
+		running:     make(map[string]*runningManager),
 	}
 }
 
 // AddCluster adds a new cluster to be managed
 func (m *MultiClusterManager) AddCluster(ctx context.Context, config ClusterConfig) error {
 	// Check if cluster with this ID already exists
-	if _, exists := m.configs[config.ClusterID]; exists {
+	m.mu.RLock()
+	_, exists := m.configs[config.ClusterID]
+	m.mu.RUnlock()
+	if exists {
 		return fmt.Errorf("cluster with ID %s already exists", config.ClusterID)
 	}
 
 	// Create manager for this cluster
-	mgr, err := NewManager(config)
+	mgr, probes, err := newManager(config)
 	if err != nil {
 		return fmt.Errorf("failed to create manager for cluster %s: %w", config.ClusterID, err)
 	}
+	if err := probes.addReadyz(mgr, CheckManager, m.managerChecker(config.ClusterID)); err != nil {
+		return fmt.Errorf("failed to add readiness check for cluster %s: %w", config.ClusterID, err)
+	}
+	clientConfig := rest.CopyConfig(mgr.GetConfig())
+	clientConfig.Timeout = config.Timeout
+	clientset, err := kubernetes.NewForConfig(clientConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset for cluster %s: %w", config.ClusterID, err)
+	}
 
 	// Add deployment controller with event logging
 	err = AddDeploymentControllerWithLogging(mgr, config.ClusterID)
@@ -310,9 +521,59 @@ func (m *MultiClusterManager) AddCluster(ctx context.Context, config ClusterConf
 		return fmt.Errorf("failed to add deployment controller for cluster %s: %w", config.ClusterID, err)
 	}
 
-	// Store manager and config
+	// Report events for the configured kinds once the cluster confirms it serves them
+	if len(config.Watches) > 0 {
+		disc, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+		if err != nil {
+			return fmt.Errorf("failed to create discovery client for cluster %s: %w", config.ClusterID, err)
+		}
+		if err := ValidateWatches(disc, config.Watches); err != nil {
+			return fmt.Errorf("invalid watches for cluster %s: %w", config.ClusterID, err)
+		}
+		for _, gvk := range config.Watches {
+			if err := AddWatchController(mgr, config.ClusterID, gvk); err != nil {
+				return fmt.Errorf("failed to watch %s in cluster %s: %w", gvk.Kind, config.ClusterID, err)
+			}
+		}
+	}
+
+	// Delete finished Jobs after their TTL when enabled for this cluster
+	if config.JobGC.AppliesTo(config.ClusterID) {
+		if err := AddJobGCController(mgr, config.ClusterID, config.JobGC); err != nil {
+			return fmt.Errorf("failed to add job garbage collector for cluster %s: %w", config.ClusterID, err)
+		}
+	}
+
+	// Manage ephemeral environments when enabled and the Environment CRD is installed
+	if config.Environments.AppliesTo(config.ClusterID) {
+		disc, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+		if err != nil {
+			return fmt.Errorf("failed to create discovery client for cluster %s: %w", config.ClusterID, err)
+		}
+		if err := ValidateWatches(disc, []schema.GroupVersionKind{environment.GVK}); err != nil {
+			return fmt.Errorf("environments in cluster %s need the CRD from config/crd: %w", config.ClusterID, err)
+		}
+		if err := AddEnvironmentController(mgr, config.ClusterID, config.Environments); err != nil {
+			return fmt.Errorf("failed to add environment controller for cluster %s: %w", config.ClusterID, err)
+		}
+	}
+
+	// Add reconcilers contributed by controller plugins
+	if err := plugin.SetupControllers(mgr, config.ClusterID); err != nil {
+		return fmt.Errorf("failed to add plugin controllers for cluster %s: %w", config.ClusterID, err)
+	}
+
+	// Store manager and config, unless the same cluster was added meanwhile
+	m.mu.Lock()
+	if _, exists := m.configs[config.ClusterID]; exists {
+		m.mu.Unlock()
+		return fmt.Errorf("cluster with ID %s already exists", config.ClusterID)
+	}
 	m.managers[config.ClusterID] = mgr
 	m.configs[config.ClusterID] = config
+	m.probes[config.ClusterID] = probes
+	m.clientsets[config.ClusterID] = clientset
+	m.mu.Unlock()
 
 	log.Info().
 		Str("cluster_id", config.ClusterID).
@@ -320,55 +581,82 @@ func (m *MultiClusterManager) AddCluster(ctx context.Context, config ClusterConf
 		Bool("in_cluster", config.InCluster).
 		Str("namespace", config.Namespace).
 		Msg("Added cluster to multi-cluster manager")
+	m.events.added(config.ClusterID)
 
 	return nil
 }
 
-// RemoveCluster removes a cluster from management
+// RemoveCluster removes a cluster from management at once, stopping its manager if it
+// runs without waiting for in-flight work; see RemoveClusterGracefully
 func (m *MultiClusterManager) RemoveCluster(clusterID string) error {
-	// Check if cluster exists
-	_, exists := m.managers[clusterID]
-	if !exists {
-		return fmt.Errorf("cluster with ID %s does not exist", clusterID)
-	}
+	_, err := m.RemoveClusterGracefully(context.Background(), clusterID, RemoveOptions{Force: true})
+	return err
+}
 
-	// Clean up resources
+// removeCluster drops everything kept for a cluster except its lifecycle history. The
+// locks order it after StartAll's loop and the leader state updates of a stopping manager.
+func (m *MultiClusterManager) removeCluster(clusterID string) {
+	m.leaderMu.Lock()
+	m.mu.Lock()
 	delete(m.managers, clusterID)
 	delete(m.configs, clusterID)
 	delete(m.controllers, clusterID)
-
-	log.Info().
-		Str("cluster_id", clusterID).
-		Msg("Removed cluster from multi-cluster manager")
-
-	return nil
+	delete(m.probes, clusterID)
+	delete(m.clientsets, clusterID)
+	m.mu.Unlock()
+	m.leaderMu.Unlock()
+	m.forgetLeader(clusterID)
+	jobsCleaned.DeletePartialMatch(prometheus.Labels{"cluster_id": clusterID})
+	ownership.Default().RemoveCluster(clusterID)
 }
 
 // StartAll starts all cluster managers
 func (m *MultiClusterManager) StartAll(ctx context.Context) error {
-	if len(m.managers) == 0 {
+	clusterCount := m.GetClusterCount()
+	if clusterCount == 0 {
 		log.Warn().Msg("No cluster managers to start")
 		return nil
 	}
 
 	// Log the number of clusters we're managing
 	log.Info().
-		Int("cluster_count", len(m.managers)).
+		Int("cluster_count", clusterCount).
 		Msg("Starting multi-cluster manager")
 
 	// Create wait group to track all manager goroutines
 	wg := &sync.WaitGroup{}
-	errorCh := make(chan error, len(m.managers))
 	doneCh := make(chan struct{})
 
-	// Start each manager in its own goroutine
+	// Start each manager in its own goroutine; removals wait until all are started
+	m.runMu.Lock()
+	m.mu.RLock()
+	errorCh := make(chan error, len(m.managers))
 	for clusterID, mgr := range m.managers {
 		wg.Add(1)
-		go func(id string, manager manager.Manager) {
+		go func(id string, manager manager.Manager, probes *managerProbes) {
 			defer wg.Done()
 			log.Info().Str("cluster_id", id).Msg("Starting manager for cluster")
 
-			if err := manager.Start(ctx); err != nil && ctx.Err() == nil {
+			// Each manager runs under its own context so removing its cluster can stop it
+			mgrCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			run := m.setRunning(id, cancel)
+
+			// Follow the manager's leader election while it runs
+			stopped := make(chan struct{})
+			go m.trackLeadership(mgrCtx, id, manager, stopped)
+			if probes != nil {
+				go m.monitorConnection(mgrCtx, id, probes, stopped)
+			}
+			err := manager.Start(mgrCtx)
+			close(stopped)
+			m.managerStopped(id, err)
+			m.clearRunning(id, run)
+
+			if err != nil && mgrCtx.Err() == nil {
+				if err.Error() != errLeaderElectionLost {
+					m.events.degraded(id, fmt.Sprintf("manager stopped: %s", err))
+				}
 				log.Error().
 					Str("cluster_id", id).
 					Err(err).
@@ -380,8 +668,10 @@ func (m *MultiClusterManager) StartAll(ctx context.Context) error {
 			}
 
 			log.Info().Str("cluster_id", id).Msg("Manager stopped")
-		}(clusterID, mgr)
+		}(clusterID, mgr, m.probes[clusterID])
 	}
+	m.mu.RUnlock()
+	m.runMu.Unlock()
 
 	// Wait for all managers to complete in a separate goroutine
 	go func() {
@@ -415,6 +705,8 @@ func (m *MultiClusterManager) StartAll(ctx context.Context) error {
 
 // StopAll gracefully shuts down all cluster managers
 func (m *MultiClusterManager) StopAll(ctx context.Context) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	clusterCount := len(m.managers)
 	if clusterCount == 0 {
 		log.Debug().Msg("No cluster managers to stop")
@@ -440,6 +732,8 @@ func (m *MultiClusterManager) StopAll(ctx context.Context) {
 
 // GetClusters returns a list of all configured clusters
 func (m *MultiClusterManager) GetClusters() []ClusterConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	configs := make([]ClusterConfig, 0, len(m.configs))
 	for _, cfg := range m.configs {
 		configs = append(configs, cfg)
@@ -447,7 +741,67 @@ func (m *MultiClusterManager) GetClusters() []ClusterConfig {
 	return configs
 }
 
+// GetClientset returns the shared Kubernetes clientset of a managed cluster
+func (m *MultiClusterManager) GetClientset(clusterID string) (kubernetes.Interface, error) {
+	m.mu.RLock()
+	clientset, ok := m.clientsets[clusterID]
+	m.mu.RUnlock()
+	if ok {
+		return clientset, nil
+	}
+	mgr, _, err := m.cluster(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(mgr.GetConfig())
+}
+
+// cluster returns the manager and configuration of a managed cluster
+func (m *MultiClusterManager) cluster(clusterID string) (manager.Manager, ClusterConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	mgr, exists := m.managers[clusterID]
+	if !exists || mgr == nil {
+		return nil, ClusterConfig{}, fmt.Errorf("cluster with ID %s does not exist", clusterID)
+	}
+	return mgr, m.configs[clusterID], nil
+}
+
+// GetDynamicClient returns a dynamic client of a managed cluster, for resources without typed clients
+func (m *MultiClusterManager) GetDynamicClient(clusterID string) (dynamic.Interface, error) {
+	mgr, cfg, err := m.cluster(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig := rest.CopyConfig(mgr.GetConfig())
+	clientConfig.Timeout = cfg.Timeout
+	return dynamic.NewForConfig(clientConfig)
+}
+
+// GetRestConfig returns a copy of the REST config of a managed cluster, without a request
+// timeout so long-lived streams such as exec sessions are not cut off
+func (m *MultiClusterManager) GetRestConfig(clusterID string) (*rest.Config, error) {
+	mgr, _, err := m.cluster(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	config := rest.CopyConfig(mgr.GetConfig())
+	config.Timeout = 0
+	return config, nil
+}
+
+// GetCache returns the informer cache of a managed cluster; reads fail until the manager is started
+func (m *MultiClusterManager) GetCache(clusterID string) (cache.Cache, error) {
+	mgr, _, err := m.cluster(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.GetCache(), nil
+}
+
 // GetClusterCount returns the number of configured clusters
 func (m *MultiClusterManager) GetClusterCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return len(m.configs)
 }
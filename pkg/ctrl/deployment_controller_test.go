@@ -2,8 +2,10 @@ package ctrl
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -15,6 +17,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -162,6 +165,19 @@ func TestAddRemoveCluster(t *testing.T) {
 	require.Equal(t, 1, count)
 }
 
+// TestGetCacheUnknownCluster tests that caches are only returned for managed clusters
+func TestGetCacheUnknownCluster(t *testing.T) {
+	manager := NewMultiClusterManager()
+
+	_, err := manager.GetCache("missing")
+	require.Error(t, err)
+
+	// Clusters registered without a running manager have no cache either
+	require.NoError(t, addClusterForTest(context.Background(), manager, ClusterConfig{ClusterID: "test-id"}))
+	_, err = manager.GetCache("test-id")
+	require.Error(t, err)
+}
+
 // Test helper function to add a cluster without using the real NewManager
 func addClusterForTest(ctx context.Context, m *MultiClusterManager, cfg ClusterConfig) error {
 	// Add the config directly - no mutex in the struct
@@ -306,3 +322,129 @@ func TestDeploymentController_EventLogging(t *testing.T) {
 	assert.True(t, reconcileFound, "DeploymentController.Reconcile method not found in the code")
 	assert.True(t, logStatementsFound, "Required logging statements not found in the Reconcile method")
 }
+
+// writeTestKubeconfig writes a kubeconfig for an unreachable API server; managers are only created, not started
+func writeTestKubeconfig(t *testing.T) string {
+	path := t.TempDir() + "/kubeconfig"
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:1
+contexts:
+- name: test
+  context: {cluster: test, user: test}
+current-context: test
+users:
+- name: test
+  user: {token: test}
+`
+	require.NoError(t, os.WriteFile(path, []byte(kubeconfig), 0o600))
+	return path
+}
+
+// TestClusterClientTuning tests that per-cluster client settings reach the manager and clientset
+func TestClusterClientTuning(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+
+	mgr, err := NewManager(ClusterConfig{ClusterID: "tuned", KubeConfig: kubeconfig, QPS: 25, Burst: 40, Timeout: 15 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, float32(25), mgr.GetConfig().QPS)
+	assert.Equal(t, 40, mgr.GetConfig().Burst)
+	assert.Zero(t, mgr.GetConfig().Timeout, "watches must not be cut off by the request timeout")
+
+	_, err = NewManager(ClusterConfig{ClusterID: "negative", KubeConfig: kubeconfig, QPS: -1})
+	assert.ErrorContains(t, err, "must not be negative")
+
+	// The cluster's clientset is shared and applies the timeout
+	m := NewMultiClusterManager()
+	require.NoError(t, m.AddCluster(context.Background(), ClusterConfig{ClusterID: "tuned", KubeConfig: kubeconfig, QPS: 25, Timeout: 15 * time.Second}))
+	assert.Equal(t, 25, m.managers["tuned"].GetConfig().Burst, "a QPS without burst allows one second's worth")
+	clientset, err := m.GetClientset("tuned")
+	require.NoError(t, err)
+	again, err := m.GetClientset("tuned")
+	require.NoError(t, err)
+	assert.Same(t, clientset, again)
+	restClient, ok := clientset.AppsV1().RESTClient().(*rest.RESTClient)
+	require.True(t, ok)
+	assert.Equal(t, 15*time.Second, restClient.Client.Timeout)
+}
+
+// TestMultiClusterManager_ConcurrentAccess tests that clusters can be added and removed while
+// API requests read them; run with -race
+func TestMultiClusterManager_ConcurrentAccess(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+	m := NewMultiClusterManager()
+	require.NoError(t, m.AddCluster(context.Background(), ClusterConfig{ClusterID: "stable", KubeConfig: kubeconfig}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		clusterID := fmt.Sprintf("cluster-%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				if assert.NoError(t, m.AddCluster(context.Background(), ClusterConfig{ClusterID: clusterID, KubeConfig: kubeconfig})) {
+					assert.NoError(t, m.RemoveCluster(clusterID))
+				}
+			}
+		}()
+	}
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				assert.GreaterOrEqual(t, m.GetClusterCount(), 1)
+				assert.NotEmpty(t, m.GetClusters())
+				_, err := m.GetClientset("stable")
+				assert.NoError(t, err)
+				_, err = m.GetRestConfig("stable")
+				assert.NoError(t, err)
+				_, err = m.GetCache("stable")
+				assert.NoError(t, err)
+				m.GetClientset("cluster-0")
+				m.GetDynamicClient("cluster-1")
+				m.GetLeaderStatus("cluster-2")
+				m.ClusterEvents("cluster-3", "", 0)
+				m.Probe(context.Background())
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+	assert.Equal(t, 1, m.GetClusterCount())
+
+	// A cluster is added once even when added concurrently
+	var added sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		added.Add(1)
+		go func() {
+			defer added.Done()
+			errs <- m.AddCluster(context.Background(), ClusterConfig{ClusterID: "twice", KubeConfig: kubeconfig})
+		}()
+	}
+	added.Wait()
+	close(errs)
+	failed := 0
+	for err := range errs {
+		if err != nil {
+			assert.ErrorContains(t, err, "already exists")
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, 2, m.GetClusterCount())
+}
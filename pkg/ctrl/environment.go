@@ -0,0 +1,311 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/environment"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/freeze"
+)
+
+// EnvironmentConfig configures ephemeral environments: Environment resources that clone a
+// source namespace's workloads into a new namespace, deleted with it after a TTL
+type EnvironmentConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Clusters   []string      `mapstructure:"clusters"`    // Cluster IDs to manage environments in, empty for every cluster
+	DefaultTTL time.Duration `mapstructure:"default_ttl"` // Lifetime of environments that set no ttl
+	MaxTTL     time.Duration `mapstructure:"max_ttl"`     // Upper bound of any ttl, zero for none
+}
+
+// AppliesTo reports whether Environments of a cluster are reconciled
+func (c EnvironmentConfig) AppliesTo(clusterID string) bool {
+	return c.Enabled && (len(c.Clusters) == 0 || slices.Contains(c.Clusters, clusterID))
+}
+
+// Validate requires a positive default TTL within the maximum
+func (c EnvironmentConfig) Validate() error {
+	if c.DefaultTTL <= 0 {
+		return errors.New("environments default_ttl must be positive")
+	}
+	if c.MaxTTL < 0 {
+		return errors.New("environments max_ttl must not be negative")
+	}
+	if c.MaxTTL > 0 && c.DefaultTTL > c.MaxTTL {
+		return fmt.Errorf("environments default_ttl %s exceeds max_ttl %s", c.DefaultTTL, c.MaxTTL)
+	}
+	return nil
+}
+
+// environmentsExpired is served by the controller-runtime metrics endpoint
+var environmentsExpired = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "k8s_custom_controller_environments_expired_total",
+	Help: "Ephemeral environments deleted with their namespace after their TTL",
+}, []string{"cluster_id"})
+
+func init() {
+	metrics.Registry.MustRegister(environmentsExpired)
+}
+
+// environmentReconciler clones the source namespace of new Environments and deletes them
+// once they expire
+type environmentReconciler struct {
+	client    client.Client
+	reader    client.Reader // Uncached, so source namespaces need no informers
+	clusterID string
+	config    EnvironmentConfig
+	now       func() time.Time
+}
+
+// newEnvironmentObject returns an empty unstructured Environment
+func newEnvironmentObject() *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(environment.GVK)
+	return u
+}
+
+// AddEnvironmentController manages the cluster's Environments. The Environment CRD must be
+// installed and the manager's scheme must include the core group.
+func AddEnvironmentController(mgr manager.Manager, clusterID string, cfg EnvironmentConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	r := &environmentReconciler{
+		client:    mgr.GetClient(),
+		reader:    mgr.GetAPIReader(),
+		clusterID: clusterID,
+		config:    cfg,
+		now:       time.Now,
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("environment").
+		For(newEnvironmentObject()).
+		Complete(traced("environment", r.clusterID, r))
+}
+
+// Reconcile clones a pending Environment, deletes an expired one, or requeues it for when it
+// expires. Its namespace is owned by it and is garbage collected with it.
+func (r *environmentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	defer clusterWork.begin(r.clusterID)()
+
+	u := newEnvironmentObject()
+	if err := r.client.Get(ctx, req.NamespacedName, u); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if u.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+	env, err := environment.FromUnstructured(u)
+	if err == nil {
+		err = env.Validate()
+	}
+	var ttl time.Duration
+	if err == nil {
+		ttl, err = env.Lifetime(r.config.DefaultTTL, r.config.MaxTTL)
+	}
+	if err != nil {
+		// Invalid Environments wait for their spec to be fixed; the status is only written
+		// when it changes, as every write triggers another reconcile
+		phase, _, _ := unstructured.NestedString(u.Object, "status", "phase")
+		message, _, _ := unstructured.NestedString(u.Object, "status", "message")
+		if phase == environment.PhaseFailed && message == err.Error() {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, r.setStatus(ctx, u, environment.Status{Phase: environment.PhaseFailed, Message: err.Error()})
+	}
+	expires := env.CreationTimestamp.Add(ttl)
+
+	if r.now().Before(expires) {
+		if env.Status.Phase == environment.PhaseFailed {
+			return ctrl.Result{RequeueAfter: expires.Sub(r.now())}, nil
+		}
+		if env.Status.Phase != environment.PhaseReady {
+			status := environment.Status{Phase: environment.PhaseReady, Namespace: env.TargetNamespace(), ExpiresAt: &metav1.Time{Time: expires}}
+			status.Objects, err = r.clone(ctx, env)
+			if err != nil {
+				var failed *cloneError
+				if !errors.As(err, &failed) {
+					return ctrl.Result{}, err
+				}
+				status = environment.Status{Phase: environment.PhaseFailed, ExpiresAt: &metav1.Time{Time: expires}, Message: err.Error()}
+			}
+			if err := r.setStatus(ctx, u, status); err != nil {
+				return ctrl.Result{}, err
+			}
+			log.Info().
+				Str("cluster_id", r.clusterID).
+				Str("environment", env.Name).
+				Str("source", env.Spec.Source).
+				Str("namespace", status.Namespace).
+				Int("objects", status.Objects).
+				Str("phase", status.Phase).
+				Time("expires", expires).
+				Msg("Reconciled environment")
+		}
+		return ctrl.Result{RequeueAfter: expires.Sub(r.now())}, nil
+	}
+
+	// Deletions wait until any change freeze for this cluster has ended
+	var freezeErr *freeze.Error
+	if err := freeze.Check(r.clusterID); errors.As(err, &freezeErr) {
+		return ctrl.Result{RequeueAfter: time.Until(freezeErr.Until)}, nil
+	}
+
+	// The UID precondition keeps an Environment recreated under the same name
+	uid := u.GetUID()
+	propagation := metav1.DeletePropagationBackground
+	err = r.client.Delete(ctx, u, &client.DeleteOptions{
+		PropagationPolicy: &propagation,
+		Preconditions:     &metav1.Preconditions{UID: &uid},
+	})
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	environmentsExpired.WithLabelValues(r.clusterID).Inc()
+	log.Info().
+		Str("cluster_id", r.clusterID).
+		Str("environment", env.Name).
+		Str("namespace", env.TargetNamespace()).
+		Time("expired", expires).
+		Msg("Deleted environment after its TTL")
+	return ctrl.Result{}, nil
+}
+
+// setStatus writes the status subresource of an Environment
+func (r *environmentReconciler) setStatus(ctx context.Context, u *unstructured.Unstructured, status environment.Status) error {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return err
+	}
+	u.Object["status"] = obj
+	return r.client.Status().Update(ctx, u)
+}
+
+// cloneError is a failure the Environment reports in its status instead of being retried
+type cloneError struct {
+	err error
+}
+
+func (e *cloneError) Error() string { return e.err.Error() }
+func (e *cloneError) Unwrap() error { return e.err }
+
+// clone creates the Environment's namespace and copies the ConfigMaps, Secrets when
+// requested, Services, Deployments and StatefulSets of its source into it. Objects that
+// already exist are kept, so an interrupted clone resumes. It returns the number of objects
+// in the namespace.
+func (r *environmentReconciler) clone(ctx context.Context, env *environment.Environment) (int, error) {
+	var source corev1.Namespace
+	if err := r.reader.Get(ctx, client.ObjectKey{Name: env.Spec.Source}, &source); err != nil {
+		if apierrors.IsNotFound(err) {
+			return 0, &cloneError{fmt.Errorf("source namespace %s not found", env.Spec.Source)}
+		}
+		return 0, err
+	}
+
+	cloner := environment.NewCloner(env)
+	controller := true
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   cloner.Namespace,
+		Labels: map[string]string{environment.NameLabel: env.Name},
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: environment.GVK.GroupVersion().String(),
+			Kind:       environment.Kind,
+			Name:       env.Name,
+			UID:        env.UID,
+			Controller: &controller,
+		}},
+	}}
+	if err := r.client.Create(ctx, namespace); apierrors.IsAlreadyExists(err) {
+		// Never take over a namespace some other Environment or anybody else created
+		var existing corev1.Namespace
+		if err := r.reader.Get(ctx, client.ObjectKey{Name: cloner.Namespace}, &existing); err != nil {
+			return 0, err
+		}
+		if owner := metav1.GetControllerOf(&existing); owner == nil || owner.UID != env.UID {
+			return 0, &cloneError{fmt.Errorf("namespace %s already exists and does not belong to this environment", cloner.Namespace)}
+		}
+	} else if err != nil {
+		return 0, err
+	}
+
+	var objects []client.Object
+	in := client.InNamespace(env.Spec.Source)
+
+	var configMaps corev1.ConfigMapList
+	if err := r.reader.List(ctx, &configMaps, in); err != nil {
+		return 0, err
+	}
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
+		if cm.Name == "kube-root-ca.crt" {
+			continue // Published into every namespace by Kubernetes
+		}
+		cloner.ConfigMaps[cm.Name] = true
+		objects = append(objects, cloner.ConfigMap(cm))
+	}
+
+	if env.Spec.IncludeSecrets {
+		var secrets corev1.SecretList
+		if err := r.reader.List(ctx, &secrets, in); err != nil {
+			return 0, err
+		}
+		for i := range secrets.Items {
+			secret := &secrets.Items[i]
+			if secret.Type == corev1.SecretTypeServiceAccountToken {
+				continue // Bound to service accounts of the source namespace
+			}
+			cloner.Secrets[secret.Name] = true
+			objects = append(objects, cloner.Secret(secret))
+		}
+	}
+
+	var services corev1.ServiceList
+	if err := r.reader.List(ctx, &services, in); err != nil {
+		return 0, err
+	}
+	for i := range services.Items {
+		cloner.Services[services.Items[i].Name] = true
+		objects = append(objects, cloner.Service(&services.Items[i]))
+	}
+
+	// Workloads are cloned last so their references to the objects above are rewritten
+	var deployments appsv1.DeploymentList
+	if err := r.reader.List(ctx, &deployments, in); err != nil {
+		return 0, err
+	}
+	for i := range deployments.Items {
+		objects = append(objects, cloner.Deployment(&deployments.Items[i]))
+	}
+	var statefulSets appsv1.StatefulSetList
+	if err := r.reader.List(ctx, &statefulSets, in); err != nil {
+		return 0, err
+	}
+	for i := range statefulSets.Items {
+		objects = append(objects, cloner.StatefulSet(&statefulSets.Items[i]))
+	}
+
+	for _, obj := range objects {
+		if err := r.client.Create(ctx, obj); err != nil && !apierrors.IsAlreadyExists(err) {
+			if apierrors.IsInvalid(err) || apierrors.IsForbidden(err) {
+				return 0, &cloneError{fmt.Errorf("failed to clone %s: %w", obj.GetName(), err)}
+			}
+			return 0, err
+		}
+	}
+	return len(objects), nil
+}
@@ -0,0 +1,177 @@
+package ctrl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/environment"
+)
+
+func newEnvironmentClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, appsv1.AddToScheme(scheme))
+	scheme.AddKnownTypeWithName(environment.GVK, &unstructured.Unstructured{})
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(environment.GVK, meta.RESTScopeRoot)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	for _, kind := range []string{"ConfigMap", "Secret", "Service"} {
+		mapper.Add(corev1.SchemeGroupVersion.WithKind(kind), meta.RESTScopeNamespace)
+	}
+	for _, kind := range []string{"Deployment", "StatefulSet"} {
+		mapper.Add(appsv1.SchemeGroupVersion.WithKind(kind), meta.RESTScopeNamespace)
+	}
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRESTMapper(mapper).
+		WithObjects(objs...).
+		WithStatusSubresource(newEnvironmentObject()).
+		Build()
+}
+
+func newEnvironment(t *testing.T, name string, created time.Time, spec environment.Spec) *unstructured.Unstructured {
+	t.Helper()
+	env := &environment.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("env-" + name), CreationTimestamp: metav1.NewTime(created)},
+		Spec:       spec,
+	}
+	u, err := env.ToUnstructured()
+	require.NoError(t, err)
+	return u
+}
+
+func getEnvironment(t *testing.T, c client.Client, name string) *environment.Environment {
+	t.Helper()
+	u := newEnvironmentObject()
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: name}, u))
+	env, err := environment.FromUnstructured(u)
+	require.NoError(t, err)
+	return env
+}
+
+func TestEnvironmentConfig_Validate(t *testing.T) {
+	cfg := EnvironmentConfig{Enabled: true, Clusters: []string{"staging"}, DefaultTTL: 72 * time.Hour, MaxTTL: 336 * time.Hour}
+	assert.NoError(t, cfg.Validate())
+	assert.True(t, cfg.AppliesTo("staging"))
+	assert.False(t, cfg.AppliesTo("production"))
+
+	assert.Error(t, EnvironmentConfig{}.Validate(), "default ttl is required")
+	assert.Error(t, EnvironmentConfig{DefaultTTL: time.Hour, MaxTTL: -time.Hour}.Validate())
+	assert.Error(t, EnvironmentConfig{DefaultTTL: 48 * time.Hour, MaxTTL: 24 * time.Hour}.Validate())
+}
+
+func TestEnvironmentReconciler_Clone(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	replicas := int32(1)
+	c := newEnvironmentClient(t,
+		newEnvironment(t, "pr-42", now.Add(-time.Minute), environment.Spec{
+			Source: "shop", TTL: "2h", Images: map[string]string{"web": "registry/web:pr-42"},
+		}),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "web-config", Namespace: "shop"}, Data: map[string]string{"mode": "preview"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: "shop"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "web-tls", Namespace: "shop"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}, Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{Replicas: &replicas, Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "web", Image: "registry/web:1.0"}},
+			}}},
+		},
+	)
+	r := &environmentReconciler{
+		client: c, reader: c, clusterID: "environment-test",
+		config: EnvironmentConfig{DefaultTTL: 72 * time.Hour},
+		now:    func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "pr-42"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour-time.Minute, result.RequeueAfter)
+
+	env := getEnvironment(t, c, "pr-42")
+	assert.Equal(t, environment.PhaseReady, env.Status.Phase)
+	assert.Equal(t, "shop-pr-42", env.Status.Namespace)
+	assert.Equal(t, 3, env.Status.Objects, "the config map, service and deployment; secrets are opt-in")
+	assert.True(t, now.Add(2*time.Hour-time.Minute).Equal(env.Status.ExpiresAt.Time))
+
+	var namespace corev1.Namespace
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Name: "shop-pr-42"}, &namespace))
+	owner := metav1.GetControllerOf(&namespace)
+	require.NotNil(t, owner)
+	assert.Equal(t, types.UID("env-pr-42"), owner.UID)
+
+	var deploy appsv1.Deployment
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "shop-pr-42", Name: "web-pr-42"}, &deploy))
+	assert.Equal(t, "registry/web:pr-42", deploy.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, "pr-42", deploy.Labels[environment.NameLabel])
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "shop-pr-42", Name: "web-config-pr-42"}, &corev1.ConfigMap{}))
+	err = c.Get(ctx, client.ObjectKey{Namespace: "shop-pr-42", Name: "web-tls-pr-42"}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// A second Environment may not take over the namespace
+	require.NoError(t, c.Create(ctx, newEnvironment(t, "copy", now, environment.Spec{Source: "shop", Namespace: "shop-pr-42"})))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "copy"}})
+	require.NoError(t, err)
+	env = getEnvironment(t, c, "copy")
+	assert.Equal(t, environment.PhaseFailed, env.Status.Phase)
+	assert.Contains(t, env.Status.Message, "does not belong to this environment")
+}
+
+func TestEnvironmentReconciler_Invalid(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	c := newEnvironmentClient(t,
+		newEnvironment(t, "missing", now, environment.Spec{Source: "nowhere"}),
+		newEnvironment(t, "invalid", now, environment.Spec{}),
+	)
+	r := &environmentReconciler{client: c, reader: c, clusterID: "environment-test", config: EnvironmentConfig{DefaultTTL: time.Hour}, now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "missing"}})
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, result.RequeueAfter, "deleted once expired")
+	env := getEnvironment(t, c, "missing")
+	assert.Equal(t, environment.PhaseFailed, env.Status.Phase)
+	assert.Equal(t, "source namespace nowhere not found", env.Status.Message)
+
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "invalid"}})
+	require.NoError(t, err)
+	env = getEnvironment(t, c, "invalid")
+	assert.Equal(t, environment.PhaseFailed, env.Status.Phase)
+	assert.Equal(t, "source namespace is required", env.Status.Message)
+}
+
+func TestEnvironmentReconciler_Expire(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	c := newEnvironmentClient(t, newEnvironment(t, "old", now.Add(-3*time.Hour), environment.Spec{Source: "shop", TTL: "2h"}))
+	r := &environmentReconciler{client: c, reader: c, clusterID: "environment-expire-test", config: EnvironmentConfig{DefaultTTL: time.Hour}, now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "old"}})
+	require.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	err = c.Get(ctx, client.ObjectKey{Name: "old"}, newEnvironmentObject())
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(environmentsExpired.WithLabelValues("environment-expire-test")))
+
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "old"}})
+	assert.NoError(t, err)
+}
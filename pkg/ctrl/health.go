@@ -0,0 +1,173 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// Probe checks registered on every cluster manager
+const (
+	CheckPing      = "ping"       // Liveness: the manager's process answers
+	CheckManager   = "manager"    // Readiness: the manager is running
+	CheckCacheSync = "cache-sync" // Readiness: the informer caches have synced
+	CheckWebhook   = "webhook"    // Readiness: the webhook server serves, once a controller uses it
+)
+
+// cacheSyncTimeout bounds how long a readiness check waits for informer caches to sync
+const cacheSyncTimeout = 2 * time.Second
+
+// ProbeCheck is the outcome of one health or readiness check
+type ProbeCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// ClusterProbe aggregates the health and readiness checks of one cluster's manager
+type ClusterProbe struct {
+	ClusterID string       `json:"cluster_id"`
+	Healthy   bool         `json:"healthy"`
+	Ready     bool         `json:"ready"`
+	Healthz   []ProbeCheck `json:"healthz"`
+	Readyz    []ProbeCheck `json:"readyz"`
+}
+
+// namedCheck is a probe check in registration order
+type namedCheck struct {
+	name  string
+	check healthz.Checker
+}
+
+// managerProbes keeps the checks registered on a manager, which serves them on its
+// health probe address, so they can also be evaluated in-process for every cluster
+type managerProbes struct {
+	healthz []namedCheck
+	readyz  []namedCheck
+}
+
+func (p *managerProbes) addHealthz(mgr manager.Manager, name string, check healthz.Checker) error {
+	if err := mgr.AddHealthzCheck(name, check); err != nil {
+		return err
+	}
+	p.healthz = append(p.healthz, namedCheck{name: name, check: check})
+	return nil
+}
+
+func (p *managerProbes) addReadyz(mgr manager.Manager, name string, check healthz.Checker) error {
+	if err := mgr.AddReadyzCheck(name, check); err != nil {
+		return err
+	}
+	p.readyz = append(p.readyz, namedCheck{name: name, check: check})
+	return nil
+}
+
+// trackedWebhookServer records whether the manager started its webhook server, which only
+// happens once a controller or plugin asks the manager for it
+type trackedWebhookServer struct {
+	webhook.Server
+	started atomic.Bool
+}
+
+// Start implements manager.Runnable
+func (s *trackedWebhookServer) Start(ctx context.Context) error {
+	s.started.Store(true)
+	return s.Server.Start(ctx)
+}
+
+// checker passes while no webhooks are served and otherwise reports whether the server is reachable
+func (s *trackedWebhookServer) checker(req *http.Request) error {
+	if !s.started.Load() {
+		return nil
+	}
+	return s.Server.StartedChecker()(req)
+}
+
+// cacheSyncChecker fails until the informer caches have synced
+func cacheSyncChecker(informers cache.Informers) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), cacheSyncTimeout)
+		defer cancel()
+		if !informers.WaitForCacheSync(ctx) {
+			return errors.New("informer caches have not synced")
+		}
+		return nil
+	}
+}
+
+// managerChecker fails unless the cluster's manager is running
+func (m *MultiClusterManager) managerChecker(clusterID string) healthz.Checker {
+	return func(*http.Request) error {
+		status, ok := m.GetLeaderStatus(clusterID)
+		if !ok {
+			return errors.New("cluster is no longer managed")
+		}
+		switch status.State {
+		case LeaderStateNotStarted:
+			return errors.New("manager has not started")
+		case LeaderStateLost:
+			return errors.New("manager stopped after losing its leader-election lease")
+		case LeaderStateStopped:
+			return errors.New("manager stopped")
+		}
+		return nil
+	}
+}
+
+// Probe runs the health and readiness checks of every cluster's manager, sorted by
+// cluster ID. Clusters are probed concurrently since cache checks may wait.
+func (m *MultiClusterManager) Probe(ctx context.Context) []ClusterProbe {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/readyz", nil)
+
+	m.mu.RLock()
+	clusterProbes := make(map[string]*managerProbes, len(m.probes))
+	for clusterID, probes := range m.probes {
+		clusterProbes[clusterID] = probes
+	}
+	m.mu.RUnlock()
+
+	results := make([]ClusterProbe, 0, len(clusterProbes))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for clusterID, probes := range clusterProbes {
+		wg.Add(1)
+		go func(id string, probes *managerProbes) {
+			defer wg.Done()
+			result := ClusterProbe{ClusterID: id}
+			result.Healthz, result.Healthy = runChecks(req, probes.healthz)
+			result.Readyz, result.Ready = runChecks(req, probes.readyz)
+
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}(clusterID, probes)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].ClusterID < results[j].ClusterID })
+	return results
+}
+
+func runChecks(req *http.Request, checks []namedCheck) ([]ProbeCheck, bool) {
+	results := make([]ProbeCheck, 0, len(checks))
+	ok := true
+	for _, c := range checks {
+		result := ProbeCheck{Name: c.name, OK: true}
+		if err := c.check(req); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+			ok = false
+		}
+		results = append(results, result)
+	}
+	return results, ok
+}
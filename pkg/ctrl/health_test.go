@@ -0,0 +1,92 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func TestProbe_AggregatesClusters(t *testing.T) {
+	m := NewMultiClusterManager()
+	require.NoError(t, addClusterForTest(context.Background(), m, ClusterConfig{ClusterID: "b-stuck"}))
+	require.NoError(t, addClusterForTest(context.Background(), m, ClusterConfig{ClusterID: "a-ready"}))
+
+	synced, unsynced := true, false
+	m.probes["a-ready"] = &managerProbes{
+		healthz: []namedCheck{{name: CheckPing, check: healthz.Ping}},
+		readyz:  []namedCheck{{name: CheckCacheSync, check: cacheSyncChecker(&informertest.FakeInformers{Synced: &synced})}},
+	}
+	m.probes["b-stuck"] = &managerProbes{
+		healthz: []namedCheck{{name: CheckPing, check: healthz.Ping}},
+		readyz: []namedCheck{
+			{name: CheckManager, check: m.managerChecker("b-stuck")},
+			{name: CheckCacheSync, check: cacheSyncChecker(&informertest.FakeInformers{Synced: &unsynced})},
+		},
+	}
+
+	probes := m.Probe(context.Background())
+	require.Len(t, probes, 2)
+
+	assert.Equal(t, "a-ready", probes[0].ClusterID)
+	assert.True(t, probes[0].Healthy)
+	assert.True(t, probes[0].Ready)
+
+	stuck := probes[1]
+	assert.Equal(t, "b-stuck", stuck.ClusterID)
+	assert.True(t, stuck.Healthy)
+	assert.False(t, stuck.Ready)
+	require.Len(t, stuck.Readyz, 2)
+	assert.Equal(t, ProbeCheck{Name: CheckManager, Error: "manager has not started"}, stuck.Readyz[0])
+	assert.Equal(t, ProbeCheck{Name: CheckCacheSync, Error: "informer caches have not synced"}, stuck.Readyz[1])
+
+	// A removed cluster is no longer probed
+	require.NoError(t, m.RemoveCluster("b-stuck"))
+	assert.Len(t, m.Probe(context.Background()), 1)
+}
+
+func TestManagerChecker_States(t *testing.T) {
+	m := NewMultiClusterManager()
+	require.NoError(t, addClusterForTest(context.Background(), m, ClusterConfig{ClusterID: "c1"}))
+	check := m.managerChecker("c1")
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+
+	assert.Error(t, check(req))
+	m.setLeaderState("c1", LeaderStatePending)
+	assert.NoError(t, check(req), "a standby replica waiting for the lease is ready")
+	m.setLeaderState("c1", LeaderStateLeading)
+	assert.NoError(t, check(req))
+	m.setLeaderState("c1", LeaderStateStopped)
+	assert.EqualError(t, check(req), "manager stopped")
+
+	require.NoError(t, m.RemoveCluster("c1"))
+	assert.EqualError(t, check(req), "cluster is no longer managed")
+}
+
+// fakeWebhookServer reports itself unreachable once started
+type fakeWebhookServer struct {
+	webhook.Server
+}
+
+func (fakeWebhookServer) Start(context.Context) error { return nil }
+
+func (fakeWebhookServer) StartedChecker() healthz.Checker {
+	return func(*http.Request) error { return errors.New("webhook server is not reachable") }
+}
+
+func TestTrackedWebhookServer_Checker(t *testing.T) {
+	server := &trackedWebhookServer{Server: fakeWebhookServer{}}
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+
+	// Unused webhook servers never start and never fail readiness
+	assert.NoError(t, server.checker(req))
+
+	require.NoError(t, server.Start(context.Background()))
+	assert.EqualError(t, server.checker(req), "webhook server is not reachable")
+}
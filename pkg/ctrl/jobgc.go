@@ -0,0 +1,194 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/freeze"
+)
+
+// JobGCPolicy sets how long finished Jobs of a namespace are kept
+type JobGCPolicy struct {
+	TTL       time.Duration `mapstructure:"ttl"`        // After completing, zero keeps completed Jobs
+	FailedTTL time.Duration `mapstructure:"failed_ttl"` // After failing, zero uses TTL
+	Disabled  bool          `mapstructure:"disabled"`   // Never delete Jobs of the namespace
+}
+
+// JobGCConfig configures the deletion of finished Jobs for clusters whose API servers lack
+// or disable the TTL-after-finished controller. Jobs that set ttlSecondsAfterFinished are
+// left to Kubernetes; where the feature is missing or disabled the API server drops the
+// field, so those Jobs are deleted here too.
+type JobGCConfig struct {
+	Enabled            bool                   `mapstructure:"enabled"`
+	Clusters           []string               `mapstructure:"clusters"`             // Cluster IDs to clean, empty for every cluster
+	IncludeCronJobJobs bool                   `mapstructure:"include_cronjob_jobs"` // CronJobs trim their own history by default
+	TTL                time.Duration          `mapstructure:"ttl"`                  // Default for namespaces without a policy
+	FailedTTL          time.Duration          `mapstructure:"failed_ttl"`           // Default for namespaces without a policy
+	Namespaces         map[string]JobGCPolicy `mapstructure:"namespaces"`           // Exact names or glob patterns such as ci-*
+}
+
+// AppliesTo reports whether finished Jobs of a cluster are deleted
+func (c JobGCConfig) AppliesTo(clusterID string) bool {
+	return c.Enabled && (len(c.Clusters) == 0 || slices.Contains(c.Clusters, clusterID))
+}
+
+// Validate rejects negative TTLs
+func (c JobGCConfig) Validate() error {
+	if c.TTL < 0 || c.FailedTTL < 0 {
+		return errors.New("job_gc ttl and failed_ttl must not be negative")
+	}
+	for namespace, p := range c.Namespaces {
+		if p.TTL < 0 || p.FailedTTL < 0 {
+			return fmt.Errorf("job_gc namespace %s: ttl and failed_ttl must not be negative", namespace)
+		}
+		if _, err := path.Match(namespace, ""); err != nil {
+			return fmt.Errorf("job_gc namespace %s: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// policy returns the namespace's policy, preferring exact matches over patterns
+func (c JobGCConfig) policy(namespace string) JobGCPolicy {
+	if p, ok := c.Namespaces[namespace]; ok {
+		return p
+	}
+	patterns := make([]string, 0, len(c.Namespaces))
+	for pattern := range c.Namespaces {
+		patterns = append(patterns, pattern)
+	}
+	slices.Sort(patterns) // Deterministic when several patterns match
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return c.Namespaces[pattern]
+		}
+	}
+	return JobGCPolicy{TTL: c.TTL, FailedTTL: c.FailedTTL}
+}
+
+// jobsCleaned is served by the controller-runtime metrics endpoint
+var jobsCleaned = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "k8s_custom_controller_jobs_cleaned_total",
+	Help: "Finished Jobs deleted after their TTL by the Job garbage collector",
+}, []string{"cluster_id", "namespace", "status"})
+
+func init() {
+	metrics.Registry.MustRegister(jobsCleaned)
+}
+
+// jobGCReconciler deletes finished Jobs once their namespace's TTL has passed
+type jobGCReconciler struct {
+	client    client.Client
+	clusterID string
+	config    JobGCConfig
+	now       func() time.Time
+}
+
+// AddJobGCController deletes the cluster's finished Jobs after their TTL. The manager's
+// scheme must include the batch group.
+func AddJobGCController(mgr manager.Manager, clusterID string, cfg JobGCConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	r := &jobGCReconciler{client: mgr.GetClient(), clusterID: clusterID, config: cfg, now: time.Now}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("job-gc").
+		For(&batchv1.Job{}).
+		Complete(traced("job-gc", clusterID, r))
+}
+
+// Reconcile deletes a finished Job whose TTL has passed, or requeues it for when it will
+func (r *jobGCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	defer clusterWork.begin(r.clusterID)()
+
+	var job batchv1.Job
+	if err := r.client.Get(ctx, req.NamespacedName, &job); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	status, expires, ok := r.expiry(&job)
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+	if wait := expires.Sub(r.now()); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	// Deletions wait until any change freeze for this cluster has ended
+	var freezeErr *freeze.Error
+	if err := freeze.Check(r.clusterID); errors.As(err, &freezeErr) {
+		return ctrl.Result{RequeueAfter: time.Until(freezeErr.Until)}, nil
+	}
+
+	// The UID precondition keeps a Job recreated under the same name
+	propagation := metav1.DeletePropagationBackground
+	err := r.client.Delete(ctx, &job, &client.DeleteOptions{
+		PropagationPolicy: &propagation,
+		Preconditions:     &metav1.Preconditions{UID: &job.UID},
+	})
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	jobsCleaned.WithLabelValues(r.clusterID, job.Namespace, status).Inc()
+	log.Info().
+		Str("cluster_id", r.clusterID).
+		Str("namespace", job.Namespace).
+		Str("name", job.Name).
+		Str("status", status).
+		Time("expired", expires).
+		Msg("Deleted finished job after its TTL")
+	return ctrl.Result{}, nil
+}
+
+// expiry returns whether a Job completed or failed and when it is due for deletion. ok is
+// false for running Jobs and Jobs this controller leaves alone.
+func (r *jobGCReconciler) expiry(job *batchv1.Job) (status string, expires time.Time, ok bool) {
+	if job.DeletionTimestamp != nil || job.Spec.TTLSecondsAfterFinished != nil {
+		return "", time.Time{}, false
+	}
+	if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == "CronJob" && !r.config.IncludeCronJobJobs {
+		return "", time.Time{}, false
+	}
+
+	var finished time.Time
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			status, finished = "complete", c.LastTransitionTime.Time
+		case batchv1.JobFailed:
+			status, finished = "failed", c.LastTransitionTime.Time
+		}
+	}
+	if status == "" {
+		return "", time.Time{}, false
+	}
+	if status == "complete" && job.Status.CompletionTime != nil {
+		finished = job.Status.CompletionTime.Time
+	}
+
+	p := r.config.policy(job.Namespace)
+	ttl := p.TTL
+	if status == "failed" && p.FailedTTL > 0 {
+		ttl = p.FailedTTL
+	}
+	if p.Disabled || ttl <= 0 {
+		return "", time.Time{}, false
+	}
+	return status, finished.Add(ttl), true
+}
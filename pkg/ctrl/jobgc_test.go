@@ -0,0 +1,121 @@
+package ctrl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func finishedJob(namespace, name string, condition batchv1.JobConditionType, finished time.Time) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: types.UID(namespace + "-" + name)},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+			{Type: condition, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(finished)},
+		}},
+	}
+}
+
+func TestJobGCConfig_Policy(t *testing.T) {
+	cfg := JobGCConfig{
+		Enabled:  true,
+		Clusters: []string{"legacy"},
+		TTL:      24 * time.Hour,
+		Namespaces: map[string]JobGCPolicy{
+			"ci-*":      {TTL: time.Hour},
+			"ci-audit":  {Disabled: true},
+			"nightly-?": {FailedTTL: time.Minute},
+		},
+	}
+	assert.True(t, cfg.AppliesTo("legacy"))
+	assert.False(t, cfg.AppliesTo("primary-cluster"))
+
+	assert.Equal(t, JobGCPolicy{TTL: 24 * time.Hour}, cfg.policy("default"))
+	assert.Equal(t, JobGCPolicy{TTL: time.Hour}, cfg.policy("ci-build"))
+	assert.Equal(t, JobGCPolicy{Disabled: true}, cfg.policy("ci-audit"), "exact names win over patterns")
+	assert.NoError(t, cfg.Validate())
+
+	assert.Error(t, JobGCConfig{TTL: -time.Second}.Validate())
+	assert.Error(t, JobGCConfig{Namespaces: map[string]JobGCPolicy{"[": {TTL: time.Hour}}}.Validate())
+}
+
+func TestJobGCReconciler_Expiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	r := &jobGCReconciler{config: JobGCConfig{
+		TTL:        time.Hour,
+		FailedTTL:  3 * time.Hour,
+		Namespaces: map[string]JobGCPolicy{"keep": {Disabled: true}, "failures": {FailedTTL: time.Hour}},
+	}}
+
+	status, expires, ok := r.expiry(finishedJob("default", "done", batchv1.JobComplete, now))
+	require.True(t, ok)
+	assert.Equal(t, "complete", status)
+	assert.Equal(t, now.Add(time.Hour), expires)
+
+	status, expires, ok = r.expiry(finishedJob("default", "broken", batchv1.JobFailed, now))
+	require.True(t, ok)
+	assert.Equal(t, "failed", status)
+	assert.Equal(t, now.Add(3*time.Hour), expires)
+
+	_, _, ok = r.expiry(finishedJob("keep", "done", batchv1.JobComplete, now))
+	assert.False(t, ok, "disabled namespace")
+	_, _, ok = r.expiry(finishedJob("failures", "done", batchv1.JobComplete, now))
+	assert.False(t, ok, "namespace without a ttl keeps completed jobs")
+
+	running := finishedJob("default", "running", batchv1.JobComplete, now)
+	running.Status.Conditions = nil
+	_, _, ok = r.expiry(running)
+	assert.False(t, ok)
+
+	withTTL := finishedJob("default", "ttl", batchv1.JobComplete, now)
+	ttl := int32(60)
+	withTTL.Spec.TTLSecondsAfterFinished = &ttl
+	_, _, ok = r.expiry(withTTL)
+	assert.False(t, ok, "left to the TTL-after-finished controller")
+
+	scheduled := finishedJob("default", "nightly-1", batchv1.JobComplete, now)
+	scheduled.OwnerReferences = []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "CronJob", Name: "nightly", UID: "c1", Controller: &[]bool{true}[0]}}
+	_, _, ok = r.expiry(scheduled)
+	assert.False(t, ok, "cronjob history is trimmed by the cronjob")
+	r.config.IncludeCronJobJobs = true
+	_, _, ok = r.expiry(scheduled)
+	assert.True(t, ok)
+}
+
+func TestJobGCReconciler_Reconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, batchv1.AddToScheme(scheme))
+	now := time.Now().Truncate(time.Second) // Stored condition times have second precision
+	expired := finishedJob("default", "expired", batchv1.JobComplete, now.Add(-2*time.Hour))
+	recent := finishedJob("default", "recent", batchv1.JobComplete, now.Add(-30*time.Minute))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(expired, recent).Build()
+
+	r := &jobGCReconciler{client: c, clusterID: "jobgc-test", config: JobGCConfig{TTL: time.Hour}, now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "expired"}})
+	require.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	err = c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "expired"}, &batchv1.Job{})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(jobsCleaned.WithLabelValues("jobgc-test", "default", "complete")))
+
+	result, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "recent"}})
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, result.RequeueAfter)
+	assert.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "recent"}, &batchv1.Job{}))
+
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "gone"}})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,217 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/tools/leaderelection"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+)
+
+// Leader election states of a cluster manager
+const (
+	LeaderStateNotStarted = "not_started"
+	LeaderStatePending    = "pending" // Started and waiting to acquire the lease
+	LeaderStateLeading    = "leading"
+	LeaderStateLost       = "lost"    // The lease was lost and the manager stopped
+	LeaderStateStopped    = "stopped" // The manager stopped without losing the lease
+)
+
+// Event types sent to sink plugins on leadership transitions
+const (
+	EventLeaderElected = "LEADER_ELECTED"
+	EventLeaderLost    = "LEADER_LOST"
+)
+
+// errLeaderElectionLost is the message controller-runtime stops a manager with after losing its lease
+const errLeaderElectionLost = "leader election lost"
+
+// leaderGauge is served by the controller-runtime metrics endpoint
+var leaderGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "k8s_custom_controller_cluster_leader",
+	Help: "Whether the controller manager of a cluster holds its leader-election lease (1) or not (0)",
+}, []string{"cluster_id"})
+
+func init() {
+	metrics.Registry.MustRegister(leaderGauge)
+}
+
+// Lease timings controller-runtime uses when none are configured
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// ValidateLeaderElectionTimings checks lease timings the way client-go's leader election
+// does at startup, so a bad configuration is reported before the manager is created:
+// the lease must outlast the renew deadline, which must exceed the jittered retry period.
+// Zero values stand for the defaults.
+func ValidateLeaderElectionTimings(leaseDuration, renewDeadline, retryPeriod time.Duration) error {
+	if leaseDuration < 0 || renewDeadline < 0 || retryPeriod < 0 {
+		return errors.New("leader election timings must not be negative")
+	}
+	if leaseDuration == 0 {
+		leaseDuration = DefaultLeaseDuration
+	}
+	if renewDeadline == 0 {
+		renewDeadline = DefaultRenewDeadline
+	}
+	if retryPeriod == 0 {
+		retryPeriod = DefaultRetryPeriod
+	}
+
+	if leaseDuration <= renewDeadline {
+		return fmt.Errorf("leader election lease_duration (%s) must be greater than renew_deadline (%s)", leaseDuration, renewDeadline)
+	}
+	if minDeadline := time.Duration(leaderelection.JitterFactor * float64(retryPeriod)); renewDeadline <= minDeadline {
+		return fmt.Errorf("leader election renew_deadline (%s) must be greater than %.1f times retry_period (%s)", renewDeadline, leaderelection.JitterFactor, retryPeriod)
+	}
+	return nil
+}
+
+// LeaderStatus reports whether a cluster's manager currently holds its leader-election lease
+type LeaderStatus struct {
+	ClusterID      string    `json:"cluster_id"`
+	LeaderElection bool      `json:"leader_election"` // Without election a running manager always leads
+	Lease          string    `json:"lease,omitempty"` // Namespace and name of the lease
+	Leader         bool      `json:"leader"`
+	State          string    `json:"state"`
+	Since          time.Time `json:"since"`       // Time of the last state change
+	Transitions    int       `json:"transitions"` // Times the lease was acquired or lost
+}
+
+// GetLeaderStatus returns the leader-election status of a managed cluster
+func (m *MultiClusterManager) GetLeaderStatus(clusterID string) (LeaderStatus, bool) {
+	m.leaderMu.Lock()
+	defer m.leaderMu.Unlock()
+
+	m.mu.RLock()
+	cfg, exists := m.configs[clusterID]
+	m.mu.RUnlock()
+	if !exists {
+		return LeaderStatus{}, false
+	}
+	if status, ok := m.leaders[clusterID]; ok {
+		return *status, true
+	}
+	return newLeaderStatus(cfg), true
+}
+
+// trackLeadership records a manager's state from start until it stops: pending until its
+// lease is acquired, then leading until the lease is lost or the manager stops
+func (m *MultiClusterManager) trackLeadership(ctx context.Context, clusterID string, mgr manager.Manager, stopped <-chan struct{}) {
+	m.setLeaderState(clusterID, LeaderStatePending)
+
+	select {
+	case <-mgr.Elected():
+		m.setLeaderState(clusterID, LeaderStateLeading)
+	case <-stopped:
+	case <-ctx.Done():
+	}
+}
+
+// managerStopped records why a manager's Start returned
+func (m *MultiClusterManager) managerStopped(clusterID string, err error) {
+	if err != nil && err.Error() == errLeaderElectionLost {
+		m.setLeaderState(clusterID, LeaderStateLost)
+		return
+	}
+	m.setLeaderState(clusterID, LeaderStateStopped)
+}
+
+// setLeaderState updates the status and gauge, and logs and emits an event when the lease
+// is acquired or lost
+func (m *MultiClusterManager) setLeaderState(clusterID, state string) {
+	m.leaderMu.Lock()
+	m.mu.RLock()
+	cfg, exists := m.configs[clusterID]
+	m.mu.RUnlock()
+	if !exists {
+		// The cluster was removed while its manager was running
+		m.leaderMu.Unlock()
+		return
+	}
+	status, ok := m.leaders[clusterID]
+	if !ok {
+		fresh := newLeaderStatus(cfg)
+		status = &fresh
+		m.leaders[clusterID] = status
+	}
+	if status.State == state {
+		m.leaderMu.Unlock()
+		return
+	}
+
+	status.State = state
+	status.Since = time.Now()
+	status.Leader = state == LeaderStateLeading
+	if state == LeaderStateLeading || state == LeaderStateLost {
+		status.Transitions++
+	}
+	snapshot := *status
+	m.leaderMu.Unlock()
+
+	if snapshot.Leader {
+		leaderGauge.WithLabelValues(clusterID).Set(1)
+	} else {
+		leaderGauge.WithLabelValues(clusterID).Set(0)
+	}
+
+	// Managers without leader election lead as soon as they start, which is not worth an event
+	if !snapshot.LeaderElection {
+		return
+	}
+	switch state {
+	case LeaderStateLeading:
+		log.Info().Str("cluster_id", clusterID).Str("lease", snapshot.Lease).Msg("Acquired leader-election lease")
+		emitLeaderEvent(EventLeaderElected, cfg, snapshot)
+		m.events.record(clusterID, ClusterEventLeaderAcquired, "acquired lease "+snapshot.Lease)
+	case LeaderStateLost:
+		log.Warn().Str("cluster_id", clusterID).Str("lease", snapshot.Lease).Msg("Lost leader-election lease, controller stopped")
+		emitLeaderEvent(EventLeaderLost, cfg, snapshot)
+		m.events.record(clusterID, ClusterEventLeaderLost, "lost lease "+snapshot.Lease+", controller stopped")
+	}
+}
+
+// forgetLeader drops the status and gauge series of a removed cluster
+func (m *MultiClusterManager) forgetLeader(clusterID string) {
+	m.leaderMu.Lock()
+	delete(m.leaders, clusterID)
+	m.leaderMu.Unlock()
+	leaderGauge.DeleteLabelValues(clusterID)
+}
+
+func newLeaderStatus(cfg ClusterConfig) LeaderStatus {
+	status := LeaderStatus{
+		ClusterID:      cfg.ClusterID,
+		LeaderElection: cfg.LeaderElection.Enabled,
+		State:          LeaderStateNotStarted,
+	}
+	if cfg.LeaderElection.Enabled {
+		status.Lease = cfg.LeaderElection.Namespace + "/" + cfg.LeaderElection.ID
+	}
+	return status
+}
+
+// emitLeaderEvent forwards a leadership transition to registered sink plugins
+func emitLeaderEvent(eventType string, cfg ClusterConfig, status LeaderStatus) {
+	defer clusterWork.begin(cfg.ClusterID)()
+	plugin.Emit(context.Background(), plugin.Event{
+		ID:           uuid.New().String(),
+		ClusterID:    cfg.ClusterID,
+		Type:         eventType,
+		ResourceType: "Lease",
+		Namespace:    cfg.LeaderElection.Namespace,
+		Name:         cfg.LeaderElection.ID,
+		Object:       status,
+	})
+}
@@ -0,0 +1,152 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+)
+
+// fakeElectionManager implements only the parts of manager.Manager that StartAll uses
+type fakeElectionManager struct {
+	manager.Manager
+	elected chan struct{}
+	result  chan error
+}
+
+func (f *fakeElectionManager) Elected() <-chan struct{} { return f.elected }
+
+func (f *fakeElectionManager) Start(ctx context.Context) error {
+	select {
+	case err := <-f.result:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// leaderSink records leadership events
+type leaderSink struct {
+	mu     sync.Mutex
+	events []plugin.Event
+}
+
+func (s *leaderSink) Name() string { return "leader-test" }
+
+func (s *leaderSink) Send(_ context.Context, event plugin.Event) error {
+	if event.ResourceType != "Lease" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *leaderSink) types() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var types []string
+	for _, e := range s.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestLeaderStatus_Transitions(t *testing.T) {
+	sink := &leaderSink{}
+	plugin.RegisterSink(sink)
+
+	m := NewMultiClusterManager()
+	cfg := ClusterConfig{ClusterID: "leader-test"}
+	cfg.LeaderElection.Enabled = true
+	cfg.LeaderElection.Namespace = "kube-system"
+	cfg.LeaderElection.ID = "controller"
+	require.NoError(t, addClusterForTest(context.Background(), m, cfg))
+
+	fake := &fakeElectionManager{elected: make(chan struct{}), result: make(chan error, 1)}
+	m.managers[cfg.ClusterID] = fake
+
+	status, ok := m.GetLeaderStatus(cfg.ClusterID)
+	require.True(t, ok)
+	assert.Equal(t, LeaderStateNotStarted, status.State)
+	assert.Equal(t, "kube-system/controller", status.Lease)
+
+	done := make(chan error, 1)
+	go func() { done <- m.StartAll(context.Background()) }()
+
+	require.Eventually(t, func() bool {
+		status, _ := m.GetLeaderStatus(cfg.ClusterID)
+		return status.State == LeaderStatePending
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0.0, promtestutil.ToFloat64(leaderGauge.WithLabelValues(cfg.ClusterID)))
+
+	close(fake.elected)
+	require.Eventually(t, func() bool {
+		status, _ := m.GetLeaderStatus(cfg.ClusterID)
+		return status.Leader
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(leaderGauge.WithLabelValues(cfg.ClusterID)))
+
+	fake.result <- errors.New(errLeaderElectionLost)
+	require.Error(t, <-done)
+
+	status, _ = m.GetLeaderStatus(cfg.ClusterID)
+	assert.Equal(t, LeaderStateLost, status.State)
+	assert.False(t, status.Leader)
+	assert.Equal(t, 2, status.Transitions)
+	assert.Equal(t, 0.0, promtestutil.ToFloat64(leaderGauge.WithLabelValues(cfg.ClusterID)))
+	assert.Equal(t, []string{EventLeaderElected, EventLeaderLost}, sink.types())
+
+	// Removing the cluster drops its status and metric series
+	series := promtestutil.CollectAndCount(leaderGauge)
+	require.NoError(t, m.RemoveCluster(cfg.ClusterID))
+	_, ok = m.GetLeaderStatus(cfg.ClusterID)
+	assert.False(t, ok)
+	assert.Equal(t, series-1, promtestutil.CollectAndCount(leaderGauge))
+}
+
+func TestLeaderStatus_GracefulStop(t *testing.T) {
+	m := NewMultiClusterManager()
+	require.NoError(t, addClusterForTest(context.Background(), m, ClusterConfig{ClusterID: "no-election"}))
+	fake := &fakeElectionManager{elected: make(chan struct{}), result: make(chan error, 1)}
+	close(fake.elected)
+	m.managers["no-election"] = fake
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.StartAll(ctx) }()
+
+	require.Eventually(t, func() bool {
+		status, _ := m.GetLeaderStatus("no-election")
+		return status.Leader
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+	status, _ := m.GetLeaderStatus("no-election")
+	assert.Equal(t, LeaderStateStopped, status.State)
+	assert.False(t, status.LeaderElection)
+	assert.Equal(t, 1, status.Transitions)
+}
+
+func TestValidateLeaderElectionTimings(t *testing.T) {
+	assert.NoError(t, ValidateLeaderElectionTimings(0, 0, 0))
+	assert.NoError(t, ValidateLeaderElectionTimings(60*time.Second, 40*time.Second, 5*time.Second))
+	// Only the lease is raised; the defaults fill in the rest
+	assert.NoError(t, ValidateLeaderElectionTimings(30*time.Second, 0, 0))
+
+	assert.ErrorContains(t, ValidateLeaderElectionTimings(10*time.Second, 10*time.Second, time.Second), "lease_duration")
+	// A 20s renew deadline outlasts the default 15s lease
+	assert.ErrorContains(t, ValidateLeaderElectionTimings(0, 20*time.Second, 0), "lease_duration")
+	assert.ErrorContains(t, ValidateLeaderElectionTimings(30*time.Second, 12*time.Second, 10*time.Second), "retry_period")
+	assert.Error(t, ValidateLeaderElectionTimings(-time.Second, 0, 0))
+}
@@ -0,0 +1,219 @@
+package ctrl
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Cluster lifecycle event types. They describe the fleet rather than its workloads and
+// are kept apart from the resource events sent to sink plugins.
+const (
+	ClusterEventAdded          = "added"
+	ClusterEventConnected      = "connected" // The manager's readiness checks pass
+	ClusterEventDegraded       = "degraded"  // Readiness checks fail after connecting, or the manager failed
+	ClusterEventLeaderAcquired = "leader_acquired"
+	ClusterEventLeaderLost     = "leader_lost"
+	ClusterEventRemoved        = "removed"
+)
+
+// ClusterEventTypes lists the lifecycle event types in the order a cluster usually goes through them
+var ClusterEventTypes = []string{
+	ClusterEventAdded,
+	ClusterEventConnected,
+	ClusterEventDegraded,
+	ClusterEventLeaderAcquired,
+	ClusterEventLeaderLost,
+	ClusterEventRemoved,
+}
+
+// DefaultClusterEventHistory is the number of lifecycle events kept per cluster
+const DefaultClusterEventHistory = 100
+
+// connectionCheckInterval is how often a running manager's readiness checks are run to
+// detect connected and degraded transitions
+var connectionCheckInterval = 10 * time.Second
+
+// ClusterEvent is a recorded lifecycle transition of a managed cluster
+type ClusterEvent struct {
+	ID        string    `json:"id"`
+	ClusterID string    `json:"cluster_id"`
+	Type      string    `json:"type"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+// clusterEventLog keeps the recent lifecycle events of every cluster, including removed
+// ones, so a cluster's history survives its removal
+type clusterEventLog struct {
+	mu        sync.Mutex
+	limit     int
+	events    map[string][]ClusterEvent // Oldest first, at most limit per cluster
+	active    map[string]bool           // Clusters currently managed; only they get connectivity events
+	connected map[string]bool           // Last reported connectivity; absent until the first check
+}
+
+func newClusterEventLog(limit int) *clusterEventLog {
+	if limit <= 0 {
+		limit = DefaultClusterEventHistory
+	}
+	return &clusterEventLog{
+		limit:     limit,
+		events:    make(map[string][]ClusterEvent),
+		active:    make(map[string]bool),
+		connected: make(map[string]bool),
+	}
+}
+
+// record appends an event, dropping the oldest once the cluster's history is full
+func (l *clusterEventLog) record(clusterID, eventType, message string) ClusterEvent {
+	event := ClusterEvent{
+		ID:        uuid.New().String(),
+		ClusterID: clusterID,
+		Type:      eventType,
+		Message:   message,
+		Time:      time.Now(),
+	}
+
+	l.mu.Lock()
+	events := append(l.events[clusterID], event)
+	if len(events) > l.limit {
+		events = append([]ClusterEvent(nil), events[len(events)-l.limit:]...)
+	}
+	l.events[clusterID] = events
+	l.mu.Unlock()
+
+	log.Info().
+		Str("cluster_id", clusterID).
+		Str("event_type", eventType).
+		Str("message", message).
+		Msg("Cluster lifecycle event")
+	return event
+}
+
+// added records that a cluster is now managed
+func (l *clusterEventLog) added(clusterID string) {
+	l.mu.Lock()
+	l.active[clusterID] = true
+	l.mu.Unlock()
+	l.record(clusterID, ClusterEventAdded, "cluster added to the multi-cluster manager")
+}
+
+// removed records that a cluster is no longer managed. Its history is kept, but its
+// manager, which may still be stopping, reports no further connectivity changes.
+func (l *clusterEventLog) removed(clusterID, message string) {
+	l.mu.Lock()
+	delete(l.active, clusterID)
+	delete(l.connected, clusterID)
+	l.mu.Unlock()
+	l.record(clusterID, ClusterEventRemoved, message)
+}
+
+// setConnected records connected the first time a cluster's checks pass after being
+// unknown or failing, and degraded when they fail after passing. A cluster that has not
+// connected yet is still starting, so its failures are not reported.
+func (l *clusterEventLog) setConnected(clusterID string, connected bool, message string) {
+	l.mu.Lock()
+	if !l.active[clusterID] {
+		l.mu.Unlock()
+		return
+	}
+	previous, known := l.connected[clusterID]
+	l.connected[clusterID] = connected
+	l.mu.Unlock()
+
+	switch {
+	case connected && (!known || !previous):
+		l.record(clusterID, ClusterEventConnected, message)
+	case !connected && known && previous:
+		l.record(clusterID, ClusterEventDegraded, message)
+	}
+}
+
+// degraded records a failure regardless of the previous connectivity
+func (l *clusterEventLog) degraded(clusterID, message string) {
+	l.mu.Lock()
+	if !l.active[clusterID] {
+		l.mu.Unlock()
+		return
+	}
+	l.connected[clusterID] = false
+	l.mu.Unlock()
+	l.record(clusterID, ClusterEventDegraded, message)
+}
+
+// list returns a cluster's events newest first, optionally filtered by type, up to limit
+func (l *clusterEventLog) list(clusterID, eventType string, limit int) ([]ClusterEvent, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	history, ok := l.events[clusterID]
+	events := make([]ClusterEvent, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		if eventType != "" && history[i].Type != eventType {
+			continue
+		}
+		events = append(events, history[i])
+		if limit > 0 && len(events) == limit {
+			break
+		}
+	}
+	return events, ok
+}
+
+// ClusterEvents returns a cluster's lifecycle events newest first, optionally filtered by
+// type, up to limit. It reports false for clusters that were never managed.
+func (m *MultiClusterManager) ClusterEvents(clusterID, eventType string, limit int) ([]ClusterEvent, bool) {
+	events, ok := m.events.list(clusterID, eventType, limit)
+	if !ok {
+		m.mu.RLock()
+		_, ok = m.configs[clusterID]
+		m.mu.RUnlock()
+	}
+	return events, ok
+}
+
+// monitorConnection runs a started manager's readiness checks until it stops, recording
+// when the cluster connects and when it degrades
+func (m *MultiClusterManager) monitorConnection(ctx context.Context, clusterID string, probes *managerProbes, stopped <-chan struct{}) {
+	ticker := time.NewTicker(connectionCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stopped:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/readyz", nil)
+		checks, ready := runChecks(req, probes.readyz)
+		select {
+		case <-stopped:
+			// The checks raced with the manager stopping, which managerStopped reports
+			return
+		default:
+		}
+		m.events.setConnected(clusterID, ready, checksMessage(checks, ready))
+	}
+}
+
+// checksMessage summarises readiness checks for a connected or degraded event
+func checksMessage(checks []ProbeCheck, ready bool) string {
+	if ready {
+		return "readiness checks passed"
+	}
+	var failures []string
+	for _, check := range checks {
+		if !check.OK {
+			failures = append(failures, check.Name+": "+check.Error)
+		}
+	}
+	return "readiness checks failed: " + strings.Join(failures, "; ")
+}
@@ -0,0 +1,132 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventTypes returns the types of events in order
+func eventTypes(events []ClusterEvent) []string {
+	types := make([]string, 0, len(events))
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestClusterEventLog_HistoryAndFilters(t *testing.T) {
+	l := newClusterEventLog(3)
+	l.record("a", ClusterEventAdded, "added")
+	l.record("a", ClusterEventConnected, "up")
+	l.record("b", ClusterEventAdded, "added")
+	l.record("a", ClusterEventDegraded, "down")
+	l.record("a", ClusterEventConnected, "up again")
+
+	// The oldest event of a is dropped, b keeps its own history
+	events, ok := l.list("a", "", 0)
+	require.True(t, ok)
+	assert.Equal(t, []string{ClusterEventConnected, ClusterEventDegraded, ClusterEventConnected}, eventTypes(events))
+	assert.Equal(t, "up again", events[0].Message)
+
+	events, _ = l.list("a", ClusterEventConnected, 1)
+	require.Len(t, events, 1)
+	assert.Equal(t, "up again", events[0].Message)
+
+	events, ok = l.list("c", "", 0)
+	assert.False(t, ok)
+	assert.Empty(t, events)
+}
+
+func TestClusterEventLog_Connectivity(t *testing.T) {
+	l := newClusterEventLog(0)
+	l.setConnected("a", true, "ready")
+	l.added("a")
+
+	// Failures before the first successful check are part of starting up
+	l.setConnected("a", false, "caches syncing")
+	l.setConnected("a", true, "ready")
+	l.setConnected("a", true, "ready")
+	l.setConnected("a", false, "cache lost")
+	l.setConnected("a", false, "cache lost")
+	l.setConnected("a", true, "ready")
+	l.degraded("a", "manager stopped")
+
+	// A removed cluster's manager may keep running but is no longer reported
+	l.removed("a", "removed")
+	l.setConnected("a", true, "ready")
+
+	events, _ := l.list("a", "", 0)
+	assert.Equal(t, []string{
+		ClusterEventRemoved, ClusterEventDegraded, ClusterEventConnected, ClusterEventDegraded, ClusterEventConnected, ClusterEventAdded,
+	}, eventTypes(events))
+	assert.Equal(t, "manager stopped", events[1].Message)
+}
+
+func TestClusterEvents_Lifecycle(t *testing.T) {
+	previous := connectionCheckInterval
+	connectionCheckInterval = 10 * time.Millisecond
+	t.Cleanup(func() { connectionCheckInterval = previous })
+
+	m := NewMultiClusterManager()
+	cfg := ClusterConfig{ClusterID: "fleet-test"}
+	cfg.LeaderElection.Enabled = true
+	cfg.LeaderElection.Namespace = "kube-system"
+	cfg.LeaderElection.ID = "controller"
+	require.NoError(t, addClusterForTest(context.Background(), m, cfg))
+	m.events.added(cfg.ClusterID)
+
+	var ready atomic.Bool
+	m.probes[cfg.ClusterID] = &managerProbes{readyz: []namedCheck{{name: CheckCacheSync, check: func(*http.Request) error {
+		if !ready.Load() {
+			return errors.New("informer caches have not synced")
+		}
+		return nil
+	}}}}
+	fake := &fakeElectionManager{elected: make(chan struct{}), result: make(chan error, 1)}
+	m.managers[cfg.ClusterID] = fake
+
+	done := make(chan error, 1)
+	go func() { done <- m.StartAll(context.Background()) }()
+
+	waitFor := func(eventType string) ClusterEvent {
+		var event ClusterEvent
+		require.Eventually(t, func() bool {
+			events, _ := m.ClusterEvents(cfg.ClusterID, eventType, 1)
+			if len(events) == 0 {
+				return false
+			}
+			event = events[0]
+			return true
+		}, 5*time.Second, 10*time.Millisecond)
+		return event
+	}
+
+	ready.Store(true)
+	assert.Equal(t, "readiness checks passed", waitFor(ClusterEventConnected).Message)
+	close(fake.elected)
+	assert.Equal(t, "acquired lease kube-system/controller", waitFor(ClusterEventLeaderAcquired).Message)
+	ready.Store(false)
+	assert.Equal(t, "readiness checks failed: cache-sync: informer caches have not synced", waitFor(ClusterEventDegraded).Message)
+
+	fake.result <- errors.New(errLeaderElectionLost)
+	require.Error(t, <-done)
+	require.NoError(t, m.RemoveCluster(cfg.ClusterID))
+
+	// The history outlives the cluster
+	events, ok := m.ClusterEvents(cfg.ClusterID, "", 0)
+	require.True(t, ok)
+	assert.Equal(t, []string{
+		ClusterEventRemoved, ClusterEventLeaderLost, ClusterEventDegraded,
+		ClusterEventLeaderAcquired, ClusterEventConnected, ClusterEventAdded,
+	}, eventTypes(events))
+
+	_, ok = m.ClusterEvents("unknown", "", 0)
+	assert.False(t, ok)
+}
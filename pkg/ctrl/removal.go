@@ -0,0 +1,169 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrClusterNotFound is returned for operations on clusters that are not managed
+var ErrClusterNotFound = errors.New("cluster not found")
+
+// ErrDrainTimeout is returned when a cluster's work did not finish before the removal's
+// context expired. The manager stays stopped and the cluster registered, so the removal
+// can be retried or forced.
+var ErrDrainTimeout = errors.New("cluster did not drain in time")
+
+// drainPollInterval is how often a removal checks whether a cluster's work has finished
+var drainPollInterval = 50 * time.Millisecond
+
+// RemoveOptions controls how a cluster is removed
+type RemoveOptions struct {
+	Force bool // Remove at once without waiting for the manager and in-flight work
+}
+
+// RemovalReport describes what removing a cluster did
+type RemovalReport struct {
+	ClusterID      string `json:"cluster_id"`
+	Forced         bool   `json:"forced"`
+	StoppedManager bool   `json:"stopped_manager"` // The manager was running and was told to stop
+	LeaseReleased  bool   `json:"lease_released"`  // The manager held its lease and gave it up on stopping
+	InFlight       int    `json:"in_flight"`       // Reconciles and sink notifications running when the removal began
+	Abandoned      int    `json:"abandoned"`       // Work still running when a forced removal completed
+	Duration       string `json:"duration"`
+}
+
+// runningManager lets a removal stop a started manager and wait for it
+type runningManager struct {
+	cancel context.CancelFunc
+	done   chan struct{} // Closed once Start has returned and the stop is recorded
+}
+
+// workTracker counts the reconciles and sink notifications running for each cluster
+type workTracker struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// clusterWork tracks the work of every cluster's controllers
+var clusterWork = &workTracker{counts: make(map[string]int)}
+
+// begin records the start of a unit of work and returns the function that ends it
+func (t *workTracker) begin(clusterID string) func() {
+	t.mu.Lock()
+	t.counts[clusterID]++
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		if t.counts[clusterID]--; t.counts[clusterID] <= 0 {
+			delete(t.counts, clusterID)
+		}
+		t.mu.Unlock()
+	}
+}
+
+// inFlight returns the number of running units of work of a cluster
+func (t *workTracker) inFlight(clusterID string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.counts[clusterID]
+}
+
+// wait blocks until a cluster has no running work or the context is done
+func (t *workTracker) wait(ctx context.Context, clusterID string) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for t.inFlight(clusterID) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// setRunning registers a manager that is about to start under a cancelable context
+func (m *MultiClusterManager) setRunning(clusterID string, cancel context.CancelFunc) *runningManager {
+	run := &runningManager{cancel: cancel, done: make(chan struct{})}
+	m.runMu.Lock()
+	m.running[clusterID] = run
+	m.runMu.Unlock()
+	return run
+}
+
+// clearRunning unregisters a manager whose Start has returned
+func (m *MultiClusterManager) clearRunning(clusterID string, run *runningManager) {
+	m.runMu.Lock()
+	if m.running[clusterID] == run {
+		delete(m.running, clusterID)
+	}
+	m.runMu.Unlock()
+	close(run.done)
+}
+
+// RemoveClusterGracefully stops a cluster's manager, which finishes in-flight reconciles
+// and releases its leader-election lease, waits for reconciles and sink notifications
+// still running, and then stops managing the cluster. The context bounds the wait; when it
+// expires ErrDrainTimeout is returned and the cluster stays registered with its manager
+// stopped. Forced removals skip the wait and report the work they abandoned.
+func (m *MultiClusterManager) RemoveClusterGracefully(ctx context.Context, clusterID string, opts RemoveOptions) (RemovalReport, error) {
+	start := time.Now()
+	report := RemovalReport{ClusterID: clusterID, Forced: opts.Force}
+	m.mu.RLock()
+	_, exists := m.managers[clusterID]
+	m.mu.RUnlock()
+	if !exists {
+		return report, fmt.Errorf("%w: %s", ErrClusterNotFound, clusterID)
+	}
+	report.InFlight = clusterWork.inFlight(clusterID)
+	leader, _ := m.GetLeaderStatus(clusterID)
+
+	m.runMu.Lock()
+	run := m.running[clusterID]
+	m.runMu.Unlock()
+	if run != nil {
+		log.Info().Str("cluster_id", clusterID).Bool("force", opts.Force).Msg("Stopping cluster manager for removal")
+		run.cancel()
+		report.StoppedManager = true
+	}
+
+	if opts.Force {
+		report.Abandoned = clusterWork.inFlight(clusterID)
+	} else {
+		if run != nil {
+			select {
+			case <-run.done:
+				report.LeaseReleased = leader.LeaderElection && leader.Leader
+			case <-ctx.Done():
+				return report, fmt.Errorf("%w: manager of cluster %s is still stopping", ErrDrainTimeout, clusterID)
+			}
+		}
+		if err := clusterWork.wait(ctx, clusterID); err != nil {
+			return report, fmt.Errorf("%w: %d reconciles or notifications still running for cluster %s",
+				ErrDrainTimeout, clusterWork.inFlight(clusterID), clusterID)
+		}
+	}
+
+	m.removeCluster(clusterID)
+	report.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	message := "cluster removed from the multi-cluster manager after draining"
+	if opts.Force {
+		message = fmt.Sprintf("cluster force-removed from the multi-cluster manager, abandoning %d in-flight tasks", report.Abandoned)
+	}
+	m.events.removed(clusterID, message)
+	log.Info().
+		Str("cluster_id", clusterID).
+		Bool("forced", report.Forced).
+		Bool("lease_released", report.LeaseReleased).
+		Int("in_flight", report.InFlight).
+		Int("abandoned", report.Abandoned).
+		Str("duration", report.Duration).
+		Msg("Removed cluster from multi-cluster manager")
+	return report, nil
+}
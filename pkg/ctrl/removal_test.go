@@ -0,0 +1,117 @@
+package ctrl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startLeadingForTest starts a fake manager with leader election and waits until it leads
+func startLeadingForTest(t *testing.T, m *MultiClusterManager, clusterID string) <-chan error {
+	cfg := ClusterConfig{ClusterID: clusterID}
+	cfg.LeaderElection.Enabled = true
+	cfg.LeaderElection.Namespace = "kube-system"
+	cfg.LeaderElection.ID = clusterID
+	require.NoError(t, addClusterForTest(context.Background(), m, cfg))
+	m.events.added(clusterID)
+
+	fake := &fakeElectionManager{elected: make(chan struct{}), result: make(chan error, 1)}
+	close(fake.elected)
+	m.managers[clusterID] = fake
+
+	done := make(chan error, 1)
+	go func() { done <- m.StartAll(context.Background()) }()
+	require.Eventually(t, func() bool {
+		status, _ := m.GetLeaderStatus(clusterID)
+		return status.Leader
+	}, 5*time.Second, 10*time.Millisecond)
+	return done
+}
+
+func TestRemoveClusterGracefully_Drains(t *testing.T) {
+	m := NewMultiClusterManager()
+	started := startLeadingForTest(t, m, "drain-test")
+	finish := clusterWork.begin("drain-test")
+
+	type result struct {
+		report RemovalReport
+		err    error
+	}
+	removed := make(chan result, 1)
+	go func() {
+		report, err := m.RemoveClusterGracefully(context.Background(), "drain-test", RemoveOptions{})
+		removed <- result{report, err}
+	}()
+
+	// The manager stops at once but the removal waits for the running notification
+	require.NoError(t, <-started)
+	select {
+	case <-removed:
+		t.Fatal("removal finished before in-flight work")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	finish()
+	res := <-removed
+	require.NoError(t, res.err)
+	assert.True(t, res.report.StoppedManager)
+	assert.True(t, res.report.LeaseReleased)
+	assert.False(t, res.report.Forced)
+	assert.Equal(t, 1, res.report.InFlight)
+	assert.Zero(t, res.report.Abandoned)
+
+	_, ok := m.GetLeaderStatus("drain-test")
+	assert.False(t, ok)
+	events, _ := m.ClusterEvents("drain-test", ClusterEventRemoved, 1)
+	require.Len(t, events, 1)
+	assert.Equal(t, "cluster removed from the multi-cluster manager after draining", events[0].Message)
+}
+
+func TestRemoveClusterGracefully_TimeoutThenRetry(t *testing.T) {
+	m := NewMultiClusterManager()
+	started := startLeadingForTest(t, m, "timeout-test")
+	finish := clusterWork.begin("timeout-test")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	report, err := m.RemoveClusterGracefully(ctx, "timeout-test", RemoveOptions{})
+	assert.ErrorIs(t, err, ErrDrainTimeout)
+	assert.True(t, report.StoppedManager)
+	require.NoError(t, <-started)
+
+	// The cluster stays registered with its manager stopped
+	status, ok := m.GetLeaderStatus("timeout-test")
+	require.True(t, ok)
+	assert.Equal(t, LeaderStateStopped, status.State)
+
+	finish()
+	report, err = m.RemoveClusterGracefully(context.Background(), "timeout-test", RemoveOptions{})
+	require.NoError(t, err)
+	assert.False(t, report.StoppedManager)
+	assert.Zero(t, report.InFlight)
+}
+
+func TestRemoveClusterGracefully_Force(t *testing.T) {
+	m := NewMultiClusterManager()
+	started := startLeadingForTest(t, m, "force-test")
+	finish := clusterWork.begin("force-test")
+	defer finish()
+
+	report, err := m.RemoveClusterGracefully(context.Background(), "force-test", RemoveOptions{Force: true})
+	require.NoError(t, err)
+	assert.True(t, report.Forced)
+	assert.True(t, report.StoppedManager)
+	assert.False(t, report.LeaseReleased)
+	assert.Equal(t, 1, report.Abandoned)
+	require.NoError(t, <-started)
+
+	events, _ := m.ClusterEvents("force-test", ClusterEventRemoved, 1)
+	require.Len(t, events, 1)
+	assert.Contains(t, events[0].Message, "abandoning 1 in-flight tasks")
+
+	_, err = m.RemoveClusterGracefully(context.Background(), "force-test", RemoveOptions{})
+	assert.ErrorIs(t, err, ErrClusterNotFound)
+}
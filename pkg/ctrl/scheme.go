@@ -0,0 +1,174 @@
+package ctrl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+)
+
+// schemeGroups maps the API group names accepted in configuration to their typed schemes
+var schemeGroups = map[string]func(*runtime.Scheme) error{
+	"core":        corev1.AddToScheme,
+	"apps":        appsv1.AddToScheme,
+	"batch":       batchv1.AddToScheme,
+	"networking":  networkingv1.AddToScheme,
+	"policy":      policyv1.AddToScheme,
+	"rbac":        rbacv1.AddToScheme,
+	"autoscaling": autoscalingv2.AddToScheme,
+	"storage":     storagev1.AddToScheme,
+}
+
+// DefaultSchemeGroups are registered when a cluster configures none
+var DefaultSchemeGroups = []string{"core", "apps"}
+
+// NewScheme builds a scheme from API group names such as core, apps, batch or networking.
+// Apps is always included because the deployment controller needs it. Custom resources
+// need no registration; they are watched as unstructured objects.
+func NewScheme(groups []string) (*runtime.Scheme, error) {
+	if len(groups) == 0 {
+		groups = DefaultSchemeGroups
+	}
+
+	scheme := runtime.NewScheme()
+	for _, group := range append([]string{"apps"}, groups...) {
+		addToScheme, ok := schemeGroups[strings.ToLower(group)]
+		if !ok {
+			return nil, fmt.Errorf("unknown scheme group %q, expected one of %s", group, strings.Join(SchemeGroupNames(), ", "))
+		}
+		if err := addToScheme(scheme); err != nil {
+			return nil, fmt.Errorf("failed to register scheme group %s: %w", group, err)
+		}
+	}
+	return scheme, nil
+}
+
+// SchemeGroupNames returns the sorted API group names NewScheme accepts
+func SchemeGroupNames() []string {
+	names := make([]string, 0, len(schemeGroups))
+	for name := range schemeGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateWatches checks that the cluster serves every watched kind, so a typo or a
+// missing CRD is reported when the cluster is added rather than as a stuck cache
+func ValidateWatches(disc discovery.DiscoveryInterface, watches []schema.GroupVersionKind) error {
+	for _, gvk := range watches {
+		if gvk.Version == "" || gvk.Kind == "" {
+			return fmt.Errorf("watch %q needs a version and a kind", gvk.String())
+		}
+		resources, err := disc.ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("watch %s: the cluster does not serve %s", gvk.Kind, gvk.GroupVersion())
+		}
+		if err != nil {
+			return fmt.Errorf("watch %s: failed to discover %s: %w", gvk.Kind, gvk.GroupVersion(), err)
+		}
+
+		served := false
+		for _, resource := range resources.APIResources {
+			// Subresources such as deployments/scale report the parent's kind
+			if resource.Kind == gvk.Kind && !strings.Contains(resource.Name, "/") {
+				served = true
+				break
+			}
+		}
+		if !served {
+			return fmt.Errorf("watch %s: kind %s is not served by %s", gvk.Kind, gvk.Kind, gvk.GroupVersion())
+		}
+	}
+	return nil
+}
+
+// watchReconciler ignores reconcile requests; watched kinds are only reported as events
+type watchReconciler struct{}
+
+func (watchReconciler) Reconcile(context.Context, ctrl.Request) (ctrl.Result, error) {
+	return ctrl.Result{}, nil
+}
+
+// AddWatchController logs and emits events for a configured kind. Kinds registered in the
+// manager's scheme are watched as typed objects, everything else, such as custom
+// resources, as unstructured objects.
+func AddWatchController(mgr manager.Manager, clusterID string, gvk schema.GroupVersionKind) error {
+	var obj client.Object
+	if typed, err := mgr.GetScheme().New(gvk); err == nil {
+		var ok bool
+		if obj, ok = typed.(client.Object); !ok {
+			return fmt.Errorf("kind %s is not an object", gvk)
+		}
+	} else {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		obj = u
+	}
+
+	emit := func(eventType string, o client.Object) bool {
+		defer clusterWork.begin(clusterID)()
+		eventID := uuid.New().String()
+		log.Info().
+			Str("event_id", eventID).
+			Str("cluster_id", clusterID).
+			Str("event_type", eventType).
+			Str("resource_type", gvk.Kind).
+			Str("namespace", o.GetNamespace()).
+			Str("name", o.GetName()).
+			Msg("Watched resource event")
+		plugin.Emit(context.Background(), plugin.Event{
+			ID:           eventID,
+			ClusterID:    clusterID,
+			Type:         eventType,
+			ResourceType: gvk.Kind,
+			Namespace:    o.GetNamespace(),
+			Name:         o.GetName(),
+			Object:       o,
+		})
+		return true
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(watchControllerName(gvk)).
+		For(obj).
+		WithEventFilter(predicate.Funcs{
+			CreateFunc:  func(e event.CreateEvent) bool { return emit("CREATE", e.Object) },
+			UpdateFunc:  func(e event.UpdateEvent) bool { return emit("UPDATE", e.ObjectNew) },
+			DeleteFunc:  func(e event.DeleteEvent) bool { return emit("DELETE", e.Object) },
+			GenericFunc: func(e event.GenericEvent) bool { return false },
+		}).
+		Complete(watchReconciler{})
+}
+
+// watchControllerName derives a unique controller name such as watch-cronjob-batch-v1
+func watchControllerName(gvk schema.GroupVersionKind) string {
+	name := "watch-" + strings.ToLower(gvk.Kind)
+	if gvk.Group != "" {
+		name += "-" + strings.ReplaceAll(gvk.Group, ".", "-")
+	}
+	return name + "-" + gvk.Version
+}
@@ -0,0 +1,73 @@
+package ctrl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestNewScheme(t *testing.T) {
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	cronJob := schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}
+
+	scheme, err := NewScheme(nil)
+	require.NoError(t, err)
+	assert.True(t, scheme.Recognizes(deployment))
+	assert.True(t, scheme.Recognizes(pod))
+	assert.False(t, scheme.Recognizes(cronJob))
+
+	// Apps is kept even when the configured groups leave it out
+	scheme, err = NewScheme([]string{"batch", "Networking"})
+	require.NoError(t, err)
+	assert.True(t, scheme.Recognizes(deployment))
+	assert.True(t, scheme.Recognizes(cronJob))
+	assert.True(t, scheme.Recognizes(schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}))
+	assert.False(t, scheme.Recognizes(pod))
+
+	_, err = NewScheme([]string{"core", "example.com"})
+	assert.ErrorContains(t, err, `unknown scheme group "example.com"`)
+}
+
+func TestValidateWatches(t *testing.T) {
+	disc := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	disc.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "pods", Kind: "Pod"}, {Name: "pods/log", Kind: "Pod"}},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment"}, {Name: "deployments/scale", Kind: "Scale"}},
+		},
+		{
+			GroupVersion: "example.com/v1alpha1",
+			APIResources: []metav1.APIResource{{Name: "widgets", Kind: "Widget"}},
+		},
+	}
+
+	assert.NoError(t, ValidateWatches(disc, []schema.GroupVersionKind{
+		{Version: "v1", Kind: "Pod"},
+		{Group: "example.com", Version: "v1alpha1", Kind: "Widget"},
+	}))
+
+	err := ValidateWatches(disc, []schema.GroupVersionKind{{Group: "example.com", Version: "v1", Kind: "Widget"}})
+	assert.ErrorContains(t, err, "the cluster does not serve example.com/v1")
+
+	err = ValidateWatches(disc, []schema.GroupVersionKind{{Group: "apps", Version: "v1", Kind: "Scale"}})
+	assert.ErrorContains(t, err, "kind Scale is not served by apps/v1")
+
+	err = ValidateWatches(disc, []schema.GroupVersionKind{{Group: "apps", Kind: "Deployment"}})
+	assert.ErrorContains(t, err, "needs a version and a kind")
+}
+
+func TestWatchControllerName(t *testing.T) {
+	assert.Equal(t, "watch-pod-v1", watchControllerName(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}))
+	assert.Equal(t, "watch-widget-example-com-v1alpha1",
+		watchControllerName(schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Widget"}))
+}
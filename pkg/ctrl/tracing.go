@@ -0,0 +1,46 @@
+package ctrl
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/tracing"
+)
+
+// tracedReconciler records a span for every reconcile. The Kubernetes API calls made with
+// its context appear below it, so a slow reconcile shows which call it waited on.
+type tracedReconciler struct {
+	controller string
+	clusterID  string
+	reconciler reconcile.Reconciler
+}
+
+// traced wraps a reconciler of a cluster's controller in a span per reconcile
+func traced(controller, clusterID string, r reconcile.Reconciler) reconcile.Reconciler {
+	return &tracedReconciler{controller: controller, clusterID: clusterID, reconciler: r}
+}
+
+// Reconcile implements reconcile.Reconciler
+func (t *tracedReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "reconcile "+t.controller, trace.WithAttributes(
+		attribute.String("k8s.cluster.id", t.clusterID),
+		attribute.String("controller", t.controller),
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.object.name", req.Name)))
+	defer span.End()
+
+	result, err := t.reconciler.Reconcile(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if result.RequeueAfter > 0 {
+		span.SetAttributes(attribute.String("requeue_after", result.RequeueAfter.String()))
+	}
+	return result, err
+}
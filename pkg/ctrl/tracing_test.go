@@ -0,0 +1,29 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTracedReconciler_PassesThrough(t *testing.T) {
+	var seen ctrl.Request
+	inner := reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		seen = req
+		return ctrl.Result{RequeueAfter: time.Minute}, errors.New("conflict")
+	})
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "prod", Name: "web"}}
+
+	// Without a tracer the wrapped reconciler behaves exactly like the inner one
+	result, err := traced("deployment", "east", inner).Reconcile(context.Background(), req)
+	require.EqualError(t, err, "conflict")
+	assert.Equal(t, time.Minute, result.RequeueAfter)
+	assert.Equal(t, req, seen)
+}
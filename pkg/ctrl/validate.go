@@ -0,0 +1,129 @@
+package ctrl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// validateProbeTimeout bounds the request that checks a cluster's API server is reachable
+var validateProbeTimeout = 5 * time.Second
+
+// FieldError describes an invalid field of a cluster configuration
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned for cluster configurations with invalid fields
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		messages = append(messages, fe.Field+": "+fe.Message)
+	}
+	return "invalid cluster configuration: " + strings.Join(messages, "; ")
+}
+
+// ValidateClusterConfig checks a cluster configuration before it is added: the cluster ID
+// is a DNS subdomain, in-cluster and kubeconfig settings are not mixed, the context exists
+// in the kubeconfig and the client tuning is not negative. When those pass, it checks the
+// cluster's API server answers. Invalid fields are returned as a *ValidationError.
+func ValidateClusterConfig(ctx context.Context, cfg ClusterConfig) error {
+	var errs []FieldError
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if cfg.ClusterID == "" {
+		add("ClusterID", "is required")
+	} else if msgs := validation.IsDNS1123Subdomain(cfg.ClusterID); len(msgs) > 0 {
+		add("ClusterID", "%s", strings.Join(msgs, ", "))
+	}
+
+	switch {
+	case cfg.InCluster && cfg.KubeConfig != "":
+		add("KubeConfig", "must be empty when InCluster is set")
+	case cfg.InCluster && cfg.Context != "":
+		add("Context", "must be empty when InCluster is set")
+	case !cfg.InCluster && cfg.KubeConfig == "":
+		add("KubeConfig", "is required unless InCluster is set")
+	case !cfg.InCluster:
+		kubeconfig, err := clientcmd.LoadFromFile(cfg.KubeConfig)
+		if err != nil {
+			add("KubeConfig", "cannot be loaded: %v", err)
+			break
+		}
+		contextName, field := cfg.Context, "Context"
+		if contextName == "" {
+			contextName, field = kubeconfig.CurrentContext, "KubeConfig"
+			if contextName == "" {
+				add("Context", "is required, the kubeconfig has no current context")
+				break
+			}
+		}
+		if _, ok := kubeconfig.Contexts[contextName]; !ok {
+			names := make([]string, 0, len(kubeconfig.Contexts))
+			for name := range kubeconfig.Contexts {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			add(field, "context %q not found in kubeconfig, available: %s", contextName, strings.Join(names, ", "))
+		}
+	}
+
+	if cfg.APIEndpoint != "" {
+		if u, err := url.Parse(cfg.APIEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("APIEndpoint", "must be an http or https URL")
+		}
+	}
+	if cfg.QPS < 0 {
+		add("QPS", "must not be negative")
+	}
+	if cfg.Burst < 0 {
+		add("Burst", "must not be negative")
+	}
+	if cfg.Timeout < 0 {
+		add("Timeout", "must not be negative")
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+
+	// Only probe a configuration that is otherwise valid
+	field := "KubeConfig"
+	if cfg.InCluster {
+		field = "InCluster"
+	}
+	config, err := clusterRestConfig(cfg)
+	if err != nil {
+		return &ValidationError{Errors: []FieldError{{Field: field, Message: err.Error()}}}
+	}
+	if err := probeAPIServer(ctx, config); err != nil {
+		return &ValidationError{Errors: []FieldError{{Field: field, Message: fmt.Sprintf("API server %s is not reachable: %v", config.Host, err)}}}
+	}
+	return nil
+}
+
+// probeAPIServer requests the API server's version
+func probeAPIServer(ctx context.Context, config *rest.Config) error {
+	ctx, cancel := context.WithTimeout(ctx, validateProbeTimeout)
+	defer cancel()
+	clientset, err := kubernetes.NewForConfig(rest.CopyConfig(config))
+	if err != nil {
+		return err
+	}
+	_, err = clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	return err
+}
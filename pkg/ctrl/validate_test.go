@@ -0,0 +1,87 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeServerKubeconfig writes a kubeconfig with contexts blue and green for the server
+func writeServerKubeconfig(t *testing.T, server string) string {
+	path := t.TempDir() + "/kubeconfig"
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: ` + server + `
+contexts:
+- name: blue
+  context: {cluster: test, user: test}
+- name: green
+  context: {cluster: test, user: test}
+current-context: blue
+users:
+- name: test
+  user: {token: test}
+`
+	require.NoError(t, os.WriteFile(path, []byte(kubeconfig), 0o600))
+	return path
+}
+
+// fieldErrors returns the field errors of a validation error
+func fieldErrors(t *testing.T, err error) []FieldError {
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "expected a validation error, got %v", err)
+	return validationErr.Errors
+}
+
+// TestValidateClusterConfig tests the field checks and the API server probe
+func TestValidateClusterConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"major":"1","minor":"33","gitVersion":"v1.33.2"}`))
+	}))
+	defer srv.Close()
+	kubeconfig := writeServerKubeconfig(t, srv.URL)
+
+	assert.NoError(t, ValidateClusterConfig(context.Background(), ClusterConfig{ClusterID: "blue", KubeConfig: kubeconfig}))
+	assert.NoError(t, ValidateClusterConfig(context.Background(), ClusterConfig{ClusterID: "green", KubeConfig: kubeconfig, Context: "green"}))
+
+	err := ValidateClusterConfig(context.Background(), ClusterConfig{ClusterID: "red", KubeConfig: kubeconfig, Context: "red"})
+	assert.Equal(t, []FieldError{{Field: "Context", Message: `context "red" not found in kubeconfig, available: blue, green`}}, fieldErrors(t, err))
+
+	err = ValidateClusterConfig(context.Background(), ClusterConfig{ClusterID: "Not_Valid", InCluster: true, KubeConfig: kubeconfig, QPS: -1})
+	errs := fieldErrors(t, err)
+	require.Len(t, errs, 3)
+	assert.Equal(t, "ClusterID", errs[0].Field)
+	assert.Equal(t, FieldError{Field: "KubeConfig", Message: "must be empty when InCluster is set"}, errs[1])
+	assert.Equal(t, FieldError{Field: "QPS", Message: "must not be negative"}, errs[2])
+	assert.ErrorContains(t, err, "QPS: must not be negative")
+
+	err = ValidateClusterConfig(context.Background(), ClusterConfig{ClusterID: "blue"})
+	assert.Equal(t, []FieldError{{Field: "KubeConfig", Message: "is required unless InCluster is set"}}, fieldErrors(t, err))
+
+	err = ValidateClusterConfig(context.Background(), ClusterConfig{ClusterID: "blue", KubeConfig: kubeconfig, APIEndpoint: "not a url"})
+	assert.Equal(t, "APIEndpoint", fieldErrors(t, err)[0].Field)
+}
+
+// TestValidateClusterConfigUnreachable tests that a configuration whose API server does not
+// answer is rejected
+func TestValidateClusterConfigUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	kubeconfig := writeServerKubeconfig(t, srv.URL)
+	srv.Close()
+
+	err := ValidateClusterConfig(context.Background(), ClusterConfig{ClusterID: "blue", KubeConfig: kubeconfig})
+	errs := fieldErrors(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "KubeConfig", errs[0].Field)
+	assert.Contains(t, errs[0].Message, "API server "+srv.URL+" is not reachable")
+}
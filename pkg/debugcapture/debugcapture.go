@@ -0,0 +1,334 @@
+// Package debugcapture records full requests and responses while an administrator has a
+// capture running, so client integration problems can be reproduced from exactly what the
+// server received and returned. Captures are bounded in time, count and body size, and
+// credentials are redacted before anything is stored.
+package debugcapture
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/redact"
+)
+
+// Config holds debug capture settings
+type Config struct {
+	Enabled         bool          `mapstructure:"enabled"`                     // Let admins start captures through /admin/debug/captures
+	MaxBodyBytes    int           `mapstructure:"max_body_bytes" unit:"bytes"` // Bodies are truncated beyond this size
+	MaxCaptures     int           `mapstructure:"max_captures"`                // Exchanges kept; the oldest are dropped first
+	DefaultDuration time.Duration `mapstructure:"default_duration"`            // Window of a capture started without a duration
+	MaxDuration     time.Duration `mapstructure:"max_duration"`                // Longest window an admin may request
+}
+
+// Filter selects the requests a capture records; empty fields match everything
+type Filter struct {
+	Method     string `json:"method,omitempty"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+	ClientIP   string `json:"client_ip,omitempty"`
+	Principal  string `json:"principal,omitempty"`
+	MinStatus  int    `json:"min_status,omitempty"` // e.g. 400 to record failed requests only
+}
+
+// Session is a running or finished capture window
+type Session struct {
+	Filter    Filter    `json:"filter"`
+	StartedBy string    `json:"started_by,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Until     time.Time `json:"until"`
+	Limit     int       `json:"limit,omitempty"` // Exchanges after which the capture stops, 0 for none
+	Captured  int       `json:"captured"`
+}
+
+// Message is one side of a captured exchange
+type Message struct {
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      string            `json:"body,omitempty"`
+	Encoding  string            `json:"encoding,omitempty"` // base64 for bodies that are not UTF-8 text
+	Size      int               `json:"size"`               // Size of the original body in bytes
+	Truncated bool              `json:"truncated,omitempty"`
+	Streamed  bool              `json:"streamed,omitempty"` // Streamed or hijacked responses have no body to capture
+}
+
+// Exchange is a captured request and its response
+type Exchange struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	ClientIP   string    `json:"client_ip"`
+	Principal  string    `json:"principal,omitempty"`
+	Status     int       `json:"status"`
+	DurationMs int64     `json:"duration_ms"`
+	Request    Message   `json:"request"`
+	Response   Message   `json:"response"`
+}
+
+// Recorder keeps the exchanges of the current capture window
+type Recorder struct {
+	config   Config
+	redactor *redact.Redactor
+	now      func() time.Time
+
+	mu        sync.Mutex
+	session   *Session
+	exchanges []Exchange // Ring buffer of the most recent exchanges
+	next      int
+}
+
+// New creates a recorder with defaults applied to unset settings. The redactor decides
+// which headers, query parameters and JSON fields are masked; it must not be nil.
+func New(cfg Config, redactor *redact.Redactor) *Recorder {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 64 << 10
+	}
+	if cfg.MaxCaptures <= 0 {
+		cfg.MaxCaptures = 200
+	}
+	if cfg.DefaultDuration <= 0 {
+		cfg.DefaultDuration = 5 * time.Minute
+	}
+	if cfg.MaxDuration < cfg.DefaultDuration {
+		cfg.MaxDuration = cfg.DefaultDuration
+	}
+	return &Recorder{config: cfg, redactor: redactor, now: time.Now}
+}
+
+// Start opens a capture window, replacing a running one. A zero duration uses the
+// default, and durations beyond MaxDuration are refused.
+func (r *Recorder) Start(filter Filter, duration time.Duration, limit int, startedBy string) (Session, error) {
+	if duration <= 0 {
+		duration = r.config.DefaultDuration
+	}
+	if duration > r.config.MaxDuration {
+		return Session{}, fmt.Errorf("duration %s exceeds the maximum of %s", duration, r.config.MaxDuration)
+	}
+	if limit < 0 {
+		return Session{}, errors.New("limit must not be negative")
+	}
+	filter.Method = strings.ToUpper(filter.Method)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.session = &Session{
+		Filter:    filter,
+		StartedBy: startedBy,
+		StartedAt: now,
+		Until:     now.Add(duration),
+		Limit:     limit,
+	}
+	return *r.session, nil
+}
+
+// Stop ends the running capture and returns it
+func (r *Recorder) Stop() (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.activeLocked()
+	if ok {
+		r.session.Until = r.now()
+		session.Until = r.session.Until
+	}
+	return session, ok
+}
+
+// Active returns the running capture, if any
+func (r *Recorder) Active() (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.activeLocked()
+}
+
+// Latest returns the running or most recently finished capture, nil when none was
+// started, and whether it is still running
+func (r *Recorder) Latest() (*Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.session == nil {
+		return nil, false
+	}
+	session := *r.session
+	_, running := r.activeLocked()
+	return &session, running
+}
+
+// Wants is a cheap check, made before bodies are copied, whether a request may be
+// recorded. The principal and status filters are applied by Record.
+func (r *Recorder) Wants(method, path, clientIP string) bool {
+	session, ok := r.Active()
+	return ok && session.Filter.matchesRequest(method, path, clientIP)
+}
+
+// Record redacts and truncates an exchange and stores it when the running capture
+// matches it. Raw bodies are passed in; the recorder never keeps the caller's slices.
+func (r *Recorder) Record(e Exchange, requestBody, responseBody []byte) bool {
+	session, ok := r.Active()
+	if !ok || !session.Filter.matchesRequest(e.Method, e.Path, e.ClientIP) {
+		return false
+	}
+	if f := session.Filter; (f.Principal != "" && f.Principal != e.Principal) || e.Status < f.MinStatus {
+		return false
+	}
+
+	e.Query = r.redactQuery(e.Query)
+	e.Request.Headers = r.redactHeaders(e.Request.Headers)
+	e.Response.Headers = r.redactHeaders(e.Response.Headers)
+	r.setBody(&e.Request, requestBody, e.Request.Headers["Content-Type"])
+	if !e.Response.Streamed {
+		r.setBody(&e.Response, responseBody, e.Response.Headers["Content-Type"])
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The capture may have ended while the exchange was being prepared
+	if _, ok := r.activeLocked(); !ok {
+		return false
+	}
+	if r.exchanges == nil {
+		r.exchanges = make([]Exchange, 0, r.config.MaxCaptures)
+	}
+	if len(r.exchanges) < r.config.MaxCaptures {
+		r.exchanges = append(r.exchanges, e)
+	} else {
+		r.exchanges[r.next] = e
+	}
+	r.next = (r.next + 1) % r.config.MaxCaptures
+
+	r.session.Captured++
+	if r.session.Limit > 0 && r.session.Captured >= r.session.Limit {
+		r.session.Until = r.now()
+	}
+	return true
+}
+
+// Exchanges returns up to limit recorded exchanges, newest first, optionally only those
+// whose path starts with pathPrefix
+func (r *Recorder) Exchanges(pathPrefix string, limit int) []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exchanges := make([]Exchange, 0, len(r.exchanges))
+	for i := 0; i < len(r.exchanges); i++ {
+		// Walk backwards from the most recently written slot
+		e := r.exchanges[(r.next-1-i+len(r.exchanges))%len(r.exchanges)]
+		if !strings.HasPrefix(e.Path, pathPrefix) {
+			continue
+		}
+		exchanges = append(exchanges, e)
+		if limit > 0 && len(exchanges) == limit {
+			break
+		}
+	}
+	return exchanges
+}
+
+// Clear drops the recorded exchanges and returns how many there were
+func (r *Recorder) Clear() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.exchanges)
+	r.exchanges, r.next = nil, 0
+	return n
+}
+
+// matchesRequest applies the filters known before the request is handled
+func (f Filter) matchesRequest(method, path, clientIP string) bool {
+	return (f.Method == "" || f.Method == method) &&
+		strings.HasPrefix(path, f.PathPrefix) &&
+		(f.ClientIP == "" || f.ClientIP == clientIP)
+}
+
+// activeLocked returns the session while its window is open
+func (r *Recorder) activeLocked() (Session, bool) {
+	if r.session == nil || !r.now().Before(r.session.Until) {
+		return Session{}, false
+	}
+	return *r.session, true
+}
+
+// redactHeaders masks sensitive headers such as Authorization, Cookie and X-API-Key, and
+// credentials embedded in other header values
+func (r *Recorder) redactHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if r.redactor.Sensitive(name) {
+			value = r.redactor.Replacement()
+		} else {
+			value = r.redactor.RedactText(value)
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// redactQuery masks the values of sensitive query parameters, e.g. ?token=
+func (r *Recorder) redactQuery(query string) string {
+	if query == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return r.redactor.RedactText(query)
+	}
+	masked := false
+	for key := range values {
+		if r.redactor.Sensitive(key) {
+			for i := range values[key] {
+				values[key][i] = r.redactor.Replacement()
+			}
+			masked = true
+		}
+	}
+	if !masked {
+		return query
+	}
+	return values.Encode()
+}
+
+// setBody stores a redacted copy of the body, truncated to MaxBodyBytes. JSON bodies are
+// redacted field by field before truncation, so a cut never hides a sensitive key.
+func (r *Recorder) setBody(m *Message, body []byte, contentType string) {
+	m.Size = len(body)
+	if len(body) == 0 {
+		return
+	}
+
+	switch mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0])); {
+	case mediaType == "application/x-www-form-urlencoded":
+		body = []byte(r.redactQuery(string(body)))
+	case utf8.Valid(body):
+		body = r.redactor.Redact(body)
+	}
+
+	text := utf8.Valid(body)
+	if len(body) > r.config.MaxBodyBytes {
+		cut := r.config.MaxBodyBytes
+		// Keep text bodies valid UTF-8 by not splitting a character
+		for text && cut > 0 && !utf8.RuneStart(body[cut]) {
+			cut--
+		}
+		body = body[:cut]
+		m.Truncated = true
+	}
+	if text {
+		m.Body = r.redactor.RedactText(string(body))
+		return
+	}
+	m.Body = base64.StdEncoding.EncodeToString(body)
+	m.Encoding = "base64"
+}
@@ -0,0 +1,185 @@
+package debugcapture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/internal/testclock"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/redact"
+)
+
+func newTestRecorder(cfg Config) (*Recorder, *testclock.Clock) {
+	clock := testclock.New(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	r := New(cfg, redact.New(redact.Config{Enabled: true}))
+	r.now = clock.Now
+	return r, clock
+}
+
+func exchange(method, path string, status int) Exchange {
+	return Exchange{Method: method, Path: path, ClientIP: "10.0.0.1", Status: status}
+}
+
+func TestRecord_OnlyWhileActive(t *testing.T) {
+	r, clock := newTestRecorder(Config{})
+
+	assert.False(t, r.Wants("GET", "/pods", "10.0.0.1"))
+	assert.False(t, r.Record(exchange("GET", "/pods", 200), nil, nil))
+
+	session, err := r.Start(Filter{}, time.Minute, 0, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, clock.Now().Add(time.Minute), session.Until)
+	assert.Equal(t, "alice", session.StartedBy)
+
+	assert.True(t, r.Record(exchange("GET", "/pods", 200), nil, nil))
+
+	clock.Advance(time.Minute)
+	assert.False(t, r.Record(exchange("GET", "/pods", 200), nil, nil))
+	assert.Len(t, r.Exchanges("", 0), 1)
+
+	latest, running := r.Latest()
+	require.NotNil(t, latest)
+	assert.False(t, running)
+	assert.Equal(t, 1, latest.Captured)
+}
+
+func TestStart_Duration(t *testing.T) {
+	r, clock := newTestRecorder(Config{DefaultDuration: 2 * time.Minute, MaxDuration: 10 * time.Minute})
+
+	session, err := r.Start(Filter{}, 0, 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, clock.Now().Add(2*time.Minute), session.Until)
+
+	_, err = r.Start(Filter{}, time.Hour, 0, "")
+	assert.ErrorContains(t, err, "exceeds the maximum")
+
+	_, err = r.Start(Filter{}, time.Minute, -1, "")
+	assert.Error(t, err)
+}
+
+func TestRecord_Filter(t *testing.T) {
+	r, _ := newTestRecorder(Config{})
+	_, err := r.Start(Filter{Method: "post", PathPrefix: "/deployments", Principal: "ci", MinStatus: 400}, time.Minute, 0, "")
+	require.NoError(t, err)
+
+	assert.False(t, r.Wants("GET", "/deployments", "10.0.0.1"))
+	assert.False(t, r.Wants("POST", "/pods", "10.0.0.1"))
+	assert.True(t, r.Wants("POST", "/deployments", "10.0.0.1"))
+
+	failed := exchange("POST", "/deployments", 422)
+	assert.False(t, r.Record(failed, nil, nil), "principal does not match")
+	failed.Principal = "ci"
+	assert.True(t, r.Record(failed, nil, nil))
+
+	succeeded := exchange("POST", "/deployments", 201)
+	succeeded.Principal = "ci"
+	assert.False(t, r.Record(succeeded, nil, nil), "status below min_status")
+}
+
+func TestRecord_LimitStopsCapture(t *testing.T) {
+	r, _ := newTestRecorder(Config{})
+	_, err := r.Start(Filter{}, time.Minute, 2, "")
+	require.NoError(t, err)
+
+	assert.True(t, r.Record(exchange("GET", "/a", 200), nil, nil))
+	assert.True(t, r.Record(exchange("GET", "/b", 200), nil, nil))
+	assert.False(t, r.Record(exchange("GET", "/c", 200), nil, nil))
+
+	_, running := r.Active()
+	assert.False(t, running)
+}
+
+func TestRecord_Redacts(t *testing.T) {
+	r, _ := newTestRecorder(Config{})
+	_, err := r.Start(Filter{}, time.Minute, 0, "")
+	require.NoError(t, err)
+
+	e := exchange("POST", "/clusters", 201)
+	e.Query = "id=prod&token=abc"
+	e.Request.Headers = map[string]string{
+		"Authorization": "Bearer abc.def",
+		"X-Api-Key":     "k",
+		"Content-Type":  "application/json",
+		"X-Forwarded":   "Basic dXNlcjpwYXNz",
+	}
+	e.Response.Headers = map[string]string{"Set-Cookie": "session=1", "Content-Type": "application/x-www-form-urlencoded"}
+	require.True(t, r.Record(e,
+		[]byte(`{"id":"prod","kubeconfig":"apiVersion: v1","nested":{"password":"p"}}`),
+		[]byte(`name=web&client_secret=s`)))
+
+	got := r.Exchanges("", 0)[0]
+	assert.Equal(t, "id=prod&token=%5BREDACTED%5D", got.Query)
+	assert.Equal(t, redact.DefaultReplacement, got.Request.Headers["Authorization"])
+	assert.Equal(t, redact.DefaultReplacement, got.Request.Headers["X-Api-Key"])
+	assert.Equal(t, "Basic [REDACTED]", got.Request.Headers["X-Forwarded"])
+	assert.Equal(t, "application/json", got.Request.Headers["Content-Type"])
+	assert.Equal(t, redact.DefaultReplacement, got.Response.Headers["Set-Cookie"])
+	assert.Equal(t, `{"id":"prod","kubeconfig":"[REDACTED]","nested":{"password":"[REDACTED]"}}`, got.Request.Body)
+	assert.Equal(t, "client_secret=%5BREDACTED%5D&name=web", got.Response.Body)
+
+	// The caller's headers are left untouched
+	assert.Equal(t, "Bearer abc.def", e.Request.Headers["Authorization"])
+}
+
+func TestRecord_TruncatesBodies(t *testing.T) {
+	r, _ := newTestRecorder(Config{MaxBodyBytes: 8})
+	_, err := r.Start(Filter{}, time.Minute, 0, "")
+	require.NoError(t, err)
+
+	e := exchange("GET", "/pods", 200)
+	e.Response.Streamed = true
+	require.True(t, r.Record(e, []byte("héééééé"), []byte("ignored")))
+	require.True(t, r.Record(exchange("GET", "/pods", 200), []byte{0xff, 0xfe, 0, 1}, nil))
+
+	exchanges := r.Exchanges("", 0)
+	binary, text := exchanges[0], exchanges[1]
+
+	assert.Equal(t, "hééé", text.Request.Body, "cut before a split character")
+	assert.True(t, text.Request.Truncated)
+	assert.Equal(t, 13, text.Request.Size)
+	assert.Empty(t, text.Response.Body)
+	assert.Equal(t, 0, text.Response.Size)
+
+	assert.Equal(t, "base64", binary.Request.Encoding)
+	assert.Equal(t, "//4AAQ==", binary.Request.Body)
+	assert.False(t, binary.Request.Truncated)
+}
+
+func TestExchanges_RingBuffer(t *testing.T) {
+	r, _ := newTestRecorder(Config{MaxCaptures: 3})
+	_, err := r.Start(Filter{}, time.Minute, 0, "")
+	require.NoError(t, err)
+
+	for _, path := range []string{"/a", "/b", "/pods/1", "/d", "/pods/2"} {
+		r.Record(exchange("GET", path, 200), nil, nil)
+	}
+
+	var paths []string
+	for _, e := range r.Exchanges("", 0) {
+		paths = append(paths, e.Path)
+	}
+	assert.Equal(t, []string{"/pods/2", "/d", "/pods/1"}, paths)
+
+	pods := r.Exchanges("/pods", 1)
+	require.Len(t, pods, 1)
+	assert.Equal(t, "/pods/2", pods[0].Path)
+
+	assert.Equal(t, 3, r.Clear())
+	assert.Empty(t, r.Exchanges("", 0))
+}
+
+func TestStop(t *testing.T) {
+	r, _ := newTestRecorder(Config{})
+
+	_, stopped := r.Stop()
+	assert.False(t, stopped)
+
+	_, err := r.Start(Filter{PathPrefix: "/pods"}, time.Minute, 0, "")
+	require.NoError(t, err)
+	session, stopped := r.Stop()
+	assert.True(t, stopped)
+	assert.Equal(t, "/pods", session.Filter.PathPrefix)
+	assert.False(t, r.Wants("GET", "/pods", ""))
+}
@@ -0,0 +1,268 @@
+// Package deprecation finds objects written through Kubernetes APIs that are deprecated or
+// removed in a cluster's version, or in the version it is being upgraded to
+package deprecation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Statuses of a deprecated API relative to a cluster version
+const (
+	StatusRemoved    = "removed"    // The cluster no longer serves the API; manifests using it fail to apply
+	StatusDeprecated = "deprecated" // Served with warnings and removed in a later version
+	StatusScheduled  = "scheduled"  // Deprecated in a later version than the cluster's
+)
+
+// Statuses lists every status, most urgent first
+var Statuses = []string{StatusRemoved, StatusDeprecated, StatusScheduled}
+
+// lastAppliedAnnotation holds the manifest of the last kubectl apply, with the apiVersion it used
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// API is a deprecated group version of a kind
+type API struct {
+	GroupVersion string `mapstructure:"group_version" json:"group_version"` // e.g. extensions/v1beta1
+	Kind         string `mapstructure:"kind" json:"kind"`                   // Empty for every kind of the group version
+	DeprecatedIn string `mapstructure:"deprecated_in" json:"deprecated_in"` // Kubernetes minor version, e.g. 1.16
+	RemovedIn    string `mapstructure:"removed_in" json:"removed_in"`
+	Replacement  string `mapstructure:"replacement" json:"replacement,omitempty"`
+}
+
+// DefaultAPIs lists the removals of the Kubernetes deprecated API migration guide for the kinds
+// workloads are usually deployed with
+var DefaultAPIs = []API{
+	{GroupVersion: "extensions/v1beta1", Kind: "Deployment", DeprecatedIn: "1.9", RemovedIn: "1.16", Replacement: "apps/v1"},
+	{GroupVersion: "extensions/v1beta1", Kind: "DaemonSet", DeprecatedIn: "1.9", RemovedIn: "1.16", Replacement: "apps/v1"},
+	{GroupVersion: "extensions/v1beta1", Kind: "ReplicaSet", DeprecatedIn: "1.9", RemovedIn: "1.16", Replacement: "apps/v1"},
+	{GroupVersion: "extensions/v1beta1", Kind: "NetworkPolicy", DeprecatedIn: "1.9", RemovedIn: "1.16", Replacement: "networking.k8s.io/v1"},
+	{GroupVersion: "extensions/v1beta1", Kind: "Ingress", DeprecatedIn: "1.14", RemovedIn: "1.22", Replacement: "networking.k8s.io/v1"},
+	{GroupVersion: "apps/v1beta1", DeprecatedIn: "1.9", RemovedIn: "1.16", Replacement: "apps/v1"},
+	{GroupVersion: "apps/v1beta2", DeprecatedIn: "1.9", RemovedIn: "1.16", Replacement: "apps/v1"},
+	{GroupVersion: "networking.k8s.io/v1beta1", DeprecatedIn: "1.19", RemovedIn: "1.22", Replacement: "networking.k8s.io/v1"},
+	{GroupVersion: "rbac.authorization.k8s.io/v1beta1", DeprecatedIn: "1.17", RemovedIn: "1.22", Replacement: "rbac.authorization.k8s.io/v1"},
+	{GroupVersion: "admissionregistration.k8s.io/v1beta1", DeprecatedIn: "1.16", RemovedIn: "1.22", Replacement: "admissionregistration.k8s.io/v1"},
+	{GroupVersion: "apiextensions.k8s.io/v1beta1", DeprecatedIn: "1.16", RemovedIn: "1.22", Replacement: "apiextensions.k8s.io/v1"},
+	{GroupVersion: "scheduling.k8s.io/v1beta1", DeprecatedIn: "1.14", RemovedIn: "1.22", Replacement: "scheduling.k8s.io/v1"},
+	{GroupVersion: "batch/v1beta1", Kind: "CronJob", DeprecatedIn: "1.21", RemovedIn: "1.25", Replacement: "batch/v1"},
+	{GroupVersion: "policy/v1beta1", Kind: "PodDisruptionBudget", DeprecatedIn: "1.21", RemovedIn: "1.25", Replacement: "policy/v1"},
+	{GroupVersion: "policy/v1beta1", Kind: "PodSecurityPolicy", DeprecatedIn: "1.21", RemovedIn: "1.25", Replacement: "Pod Security Admission"},
+	{GroupVersion: "discovery.k8s.io/v1beta1", Kind: "EndpointSlice", DeprecatedIn: "1.21", RemovedIn: "1.25", Replacement: "discovery.k8s.io/v1"},
+	{GroupVersion: "autoscaling/v2beta1", Kind: "HorizontalPodAutoscaler", DeprecatedIn: "1.22", RemovedIn: "1.25", Replacement: "autoscaling/v2"},
+	{GroupVersion: "autoscaling/v2beta2", Kind: "HorizontalPodAutoscaler", DeprecatedIn: "1.23", RemovedIn: "1.26", Replacement: "autoscaling/v2"},
+	{GroupVersion: "flowcontrol.apiserver.k8s.io/v1beta1", DeprecatedIn: "1.23", RemovedIn: "1.26", Replacement: "flowcontrol.apiserver.k8s.io/v1"},
+	{GroupVersion: "storage.k8s.io/v1beta1", Kind: "CSIStorageCapacity", DeprecatedIn: "1.24", RemovedIn: "1.27", Replacement: "storage.k8s.io/v1"},
+	{GroupVersion: "flowcontrol.apiserver.k8s.io/v1beta2", DeprecatedIn: "1.26", RemovedIn: "1.29", Replacement: "flowcontrol.apiserver.k8s.io/v1"},
+	{GroupVersion: "flowcontrol.apiserver.k8s.io/v1beta3", DeprecatedIn: "1.29", RemovedIn: "1.32", Replacement: "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// Config holds deprecation settings
+type Config struct {
+	APIs []API `mapstructure:"apis"` // More deprecations, e.g. of CRDs, on top of DefaultAPIs
+}
+
+// Object is an object and the API versions it was written through
+type Object struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Writers   []Writer
+}
+
+// Writer is an API version an object was written through, and by whom
+type Writer struct {
+	APIVersion string
+	Manager    string // Field manager, or kubectl for the last-applied configuration
+}
+
+// Finding is an object written through a deprecated API
+type Finding struct {
+	Cluster      string `json:"cluster"`
+	Kind         string `json:"kind"`
+	Namespace    string `json:"namespace,omitempty"`
+	Name         string `json:"name"`
+	APIVersion   string `json:"api_version"`
+	Manager      string `json:"manager"` // Who last wrote through the API, so the manifest or tool can be found
+	DeprecatedIn string `json:"deprecated_in"`
+	RemovedIn    string `json:"removed_in"`
+	Replacement  string `json:"replacement,omitempty"`
+	Status       string `json:"status"`             // Relative to the cluster's version
+	Blocking     bool   `json:"blocking,omitempty"` // Removed in the target version
+}
+
+// Table matches API versions against known deprecations
+type Table struct {
+	apis []API
+}
+
+// New validates the configured deprecations and appends them to DefaultAPIs
+func New(cfg Config) (*Table, error) {
+	apis := append([]API{}, DefaultAPIs...)
+	for i, api := range cfg.APIs {
+		if api.GroupVersion == "" {
+			return nil, fmt.Errorf("apis[%d]: group_version is required", i)
+		}
+		for _, v := range []string{api.DeprecatedIn, api.RemovedIn} {
+			if _, err := ParseVersion(v); err != nil {
+				return nil, fmt.Errorf("apis[%d] %s: %w", i, api.GroupVersion, err)
+			}
+		}
+		apis = append(apis, api)
+	}
+	return &Table{apis: apis}, nil
+}
+
+// Lookup returns the deprecation of a kind in a group version
+func (t *Table) Lookup(groupVersion, kind string) (API, bool) {
+	for _, api := range t.apis {
+		if api.GroupVersion == groupVersion && (api.Kind == "" || api.Kind == kind) {
+			return api, true
+		}
+	}
+	return API{}, false
+}
+
+// Check returns the findings of a cluster running version. With a target version, findings
+// removed in it are blocking. Each object is reported once per deprecated API version.
+func (t *Table) Check(clusterID string, version, target Version, objects []Object) []Finding {
+	findings := make([]Finding, 0)
+	for _, obj := range objects {
+		seen := map[string]bool{}
+		for _, w := range obj.Writers {
+			api, ok := t.Lookup(w.APIVersion, obj.Kind)
+			if !ok || seen[w.APIVersion] {
+				continue
+			}
+			seen[w.APIVersion] = true
+			findings = append(findings, Finding{
+				Cluster:      clusterID,
+				Kind:         obj.Kind,
+				Namespace:    obj.Namespace,
+				Name:         obj.Name,
+				APIVersion:   w.APIVersion,
+				Manager:      w.Manager,
+				DeprecatedIn: api.DeprecatedIn,
+				RemovedIn:    api.RemovedIn,
+				Replacement:  api.Replacement,
+				Status:       status(api, version),
+				Blocking:     !target.IsZero() && !target.Less(MustParseVersion(api.RemovedIn)),
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		fi, fj := findings[i], findings[j]
+		if rank(fi.Status) != rank(fj.Status) {
+			return rank(fi.Status) < rank(fj.Status)
+		}
+		return fi.Cluster+"/"+fi.Kind+"/"+fi.Namespace+"/"+fi.Name < fj.Cluster+"/"+fj.Kind+"/"+fj.Namespace+"/"+fj.Name
+	})
+	return findings
+}
+
+// Served returns the deprecated APIs among the group versions a cluster serves
+func (t *Table) Served(groupVersions []string) []API {
+	served := make([]API, 0)
+	for _, gv := range groupVersions {
+		for _, api := range t.apis {
+			if api.GroupVersion == gv {
+				served = append(served, api)
+			}
+		}
+	}
+	return served
+}
+
+// ObjectFrom lists the API versions in the managed fields and last-applied configuration
+// of an object
+func ObjectFrom(kind string, meta metav1.Object) Object {
+	obj := Object{Kind: kind, Namespace: meta.GetNamespace(), Name: meta.GetName()}
+	for _, entry := range meta.GetManagedFields() {
+		if entry.APIVersion != "" {
+			obj.Writers = append(obj.Writers, Writer{APIVersion: entry.APIVersion, Manager: entry.Manager})
+		}
+	}
+	if raw := meta.GetAnnotations()[lastAppliedAnnotation]; raw != "" {
+		var applied struct {
+			APIVersion string `json:"apiVersion"`
+		}
+		if json.Unmarshal([]byte(raw), &applied) == nil && applied.APIVersion != "" {
+			obj.Writers = append(obj.Writers, Writer{APIVersion: applied.APIVersion, Manager: "kubectl"})
+		}
+	}
+	return obj
+}
+
+func status(api API, version Version) string {
+	switch {
+	case !version.Less(MustParseVersion(api.RemovedIn)):
+		return StatusRemoved
+	case !version.Less(MustParseVersion(api.DeprecatedIn)):
+		return StatusDeprecated
+	default:
+		return StatusScheduled
+	}
+}
+
+func rank(status string) int {
+	for i, s := range Statuses {
+		if s == status {
+			return i
+		}
+	}
+	return len(Statuses)
+}
+
+// Version is a Kubernetes major and minor version
+type Version struct {
+	Major int
+	Minor int
+}
+
+// ParseVersion parses 1.31, v1.31.2 or a git version such as v1.31.2-eks-1a2b3c
+func ParseVersion(s string) (Version, error) {
+	parts := strings.SplitN(strings.TrimPrefix(s, "v"), ".", 3)
+	if len(parts) < 2 {
+		return Version{}, fmt.Errorf("invalid Kubernetes version %q, expected major.minor such as 1.31", s)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return Version{}, fmt.Errorf("invalid Kubernetes version %q, expected major.minor such as 1.31", s)
+	}
+	// Minor versions of some distributions carry a suffix, e.g. 31+
+	minor, err := strconv.Atoi(strings.TrimRight(parts[1], "+"))
+	if err != nil {
+		return Version{}, fmt.Errorf("invalid Kubernetes version %q, expected major.minor such as 1.31", s)
+	}
+	return Version{Major: major, Minor: minor}, nil
+}
+
+// MustParseVersion parses a version that is known to be valid, such as one of DefaultAPIs
+func MustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Less reports whether v is an earlier version than other
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	return v.Minor < other.Minor
+}
+
+// IsZero reports whether the version is unset
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
@@ -0,0 +1,81 @@
+package deprecation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseVersion(t *testing.T) {
+	for input, want := range map[string]Version{
+		"1.31":                {1, 31},
+		"v1.29.3":             {1, 29},
+		"v1.28.5-eks-5e0fdde": {1, 28},
+		"1.27+":               {1, 27},
+	} {
+		got, err := ParseVersion(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+	_, err := ParseVersion("latest")
+	assert.Error(t, err)
+	assert.True(t, Version{1, 24}.Less(Version{1, 25}))
+	assert.Equal(t, "1.31", Version{1, 31}.String())
+}
+
+func TestObjectFrom(t *testing.T) {
+	meta := &metav1.ObjectMeta{
+		Namespace: "batch",
+		Name:      "nightly",
+		ManagedFields: []metav1.ManagedFieldsEntry{
+			{Manager: "helm", APIVersion: "batch/v1beta1"},
+			{Manager: "kube-controller-manager", APIVersion: "batch/v1"},
+		},
+		Annotations: map[string]string{lastAppliedAnnotation: `{"apiVersion":"batch/v1beta1","kind":"CronJob"}`},
+	}
+	obj := ObjectFrom("CronJob", meta)
+	assert.Equal(t, Object{Kind: "CronJob", Namespace: "batch", Name: "nightly", Writers: []Writer{
+		{APIVersion: "batch/v1beta1", Manager: "helm"},
+		{APIVersion: "batch/v1", Manager: "kube-controller-manager"},
+		{APIVersion: "batch/v1beta1", Manager: "kubectl"},
+	}}, obj)
+}
+
+func TestCheck(t *testing.T) {
+	table, err := New(Config{APIs: []API{{GroupVersion: "example.com/v1alpha1", Kind: "Widget", DeprecatedIn: "1.30", RemovedIn: "1.33"}}})
+	require.NoError(t, err)
+
+	objects := []Object{
+		{Kind: "CronJob", Namespace: "batch", Name: "nightly", Writers: []Writer{{APIVersion: "batch/v1beta1", Manager: "helm"}, {APIVersion: "batch/v1beta1", Manager: "kubectl"}}},
+		{Kind: "HorizontalPodAutoscaler", Namespace: "shop", Name: "web", Writers: []Writer{{APIVersion: "autoscaling/v2beta2", Manager: "argocd"}}},
+		{Kind: "Widget", Name: "w", Writers: []Writer{{APIVersion: "example.com/v1alpha1", Manager: "operator"}}},
+		{Kind: "Deployment", Namespace: "shop", Name: "web", Writers: []Writer{{APIVersion: "apps/v1", Manager: "kubectl"}}},
+	}
+
+	findings := table.Check("prod", Version{1, 24}, Version{1, 26}, objects)
+	require.Len(t, findings, 3)
+	assert.Equal(t, Finding{Cluster: "prod", Kind: "CronJob", Namespace: "batch", Name: "nightly", APIVersion: "batch/v1beta1", Manager: "helm",
+		DeprecatedIn: "1.21", RemovedIn: "1.25", Replacement: "batch/v1", Status: StatusDeprecated, Blocking: true}, findings[0])
+	assert.Equal(t, "HorizontalPodAutoscaler", findings[1].Kind)
+	assert.True(t, findings[1].Blocking)
+	assert.Equal(t, StatusScheduled, findings[2].Status)
+	assert.False(t, findings[2].Blocking)
+
+	findings = table.Check("prod", Version{1, 25}, Version{}, objects)
+	assert.Equal(t, StatusRemoved, findings[0].Status)
+	assert.False(t, findings[0].Blocking)
+
+	served := table.Served([]string{"v1", "batch/v1beta1", "flowcontrol.apiserver.k8s.io/v1beta3"})
+	require.Len(t, served, 2)
+	assert.Equal(t, "CronJob", served[0].Kind)
+	assert.Equal(t, "1.32", served[1].RemovedIn)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(Config{APIs: []API{{GroupVersion: "example.com/v1", DeprecatedIn: "soon", RemovedIn: "1.40"}}})
+	assert.Error(t, err)
+	_, err = New(Config{APIs: []API{{DeprecatedIn: "1.30", RemovedIn: "1.33"}}})
+	assert.Error(t, err)
+}
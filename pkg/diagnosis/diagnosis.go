@@ -0,0 +1,204 @@
+// Package diagnosis collects the triage data of restarting containers: the last termination
+// state, what its exit code usually means and hints at the root cause
+package diagnosis
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ReasonCrashLoopBackOff is the waiting reason of a container the kubelet is backing off
+const ReasonCrashLoopBackOff = "CrashLoopBackOff"
+
+// quickExit is how soon after starting a container exit counts as failing at startup
+const quickExit = 10 * time.Second
+
+// Termination is how a container last exited
+type Termination struct {
+	ExitCode       int32     `json:"exit_code"`
+	Signal         int32     `json:"signal,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	StartedAt      time.Time `json:"started_at,omitempty"`
+	FinishedAt     time.Time `json:"finished_at,omitempty"`
+	RuntimeSeconds float64   `json:"runtime_seconds,omitempty"` // Time between start and exit
+	Meaning        string    `json:"meaning"`                   // What the exit code usually means
+}
+
+// Container is the diagnosis of one container of the pod
+type Container struct {
+	Name            string       `json:"name"`
+	Init            bool         `json:"init,omitempty"`
+	Image           string       `json:"image"`
+	Ready           bool         `json:"ready"`
+	RestartCount    int32        `json:"restart_count"`
+	State           string       `json:"state"` // waiting, running, terminated or unknown
+	Reason          string       `json:"reason,omitempty"`
+	Message         string       `json:"message,omitempty"`
+	CrashLooping    bool         `json:"crash_looping"`
+	LastTermination *Termination `json:"last_termination,omitempty"`
+	Hints           []string     `json:"hints,omitempty"`
+	Logs            []string     `json:"logs,omitempty"`          // Log tail, from the previous instance when it restarted
+	LogsPrevious    bool         `json:"logs_previous,omitempty"` // Logs come from the instance that exited
+	LogsError       string       `json:"logs_error,omitempty"`    // Why the log tail is missing
+}
+
+// Report is the diagnosis of a pod
+type Report struct {
+	Namespace    string          `json:"namespace"`
+	Name         string          `json:"name"`
+	Phase        corev1.PodPhase `json:"phase"`
+	Node         string          `json:"node,omitempty"`
+	CrashLooping bool            `json:"crash_looping"`
+	Restarts     int32           `json:"restarts"`
+	Containers   []Container     `json:"containers"`
+}
+
+// Diagnose collects the triage data of every container of the pod, init containers first
+func Diagnose(pod *corev1.Pod) Report {
+	report := Report{
+		Namespace:  pod.Namespace,
+		Name:       pod.Name,
+		Phase:      pod.Status.Phase,
+		Node:       pod.Spec.NodeName,
+		Containers: []Container{},
+	}
+
+	specs := make(map[string]*corev1.Container)
+	for i := range pod.Spec.InitContainers {
+		specs[pod.Spec.InitContainers[i].Name] = &pod.Spec.InitContainers[i]
+	}
+	for i := range pod.Spec.Containers {
+		specs[pod.Spec.Containers[i].Name] = &pod.Spec.Containers[i]
+	}
+
+	add := func(statuses []corev1.ContainerStatus, init bool) {
+		for i := range statuses {
+			c := diagnoseContainer(&statuses[i], specs[statuses[i].Name])
+			c.Init = init
+			report.Restarts += c.RestartCount
+			report.CrashLooping = report.CrashLooping || c.CrashLooping
+			report.Containers = append(report.Containers, c)
+		}
+	}
+	add(pod.Status.InitContainerStatuses, true)
+	add(pod.Status.ContainerStatuses, false)
+	return report
+}
+
+// ExitCodeMeaning describes what an exit code usually means
+func ExitCodeMeaning(code int32) string {
+	switch {
+	case code == 0:
+		return "Exited successfully"
+	case code == 1:
+		return "Application error"
+	case code == 2:
+		return "Misuse of a shell builtin or invalid arguments"
+	case code == 126:
+		return "Command found but not executable"
+	case code == 127:
+		return "Command not found"
+	case code == 137:
+		return "Killed by SIGKILL, usually the OOM killer or a failed liveness probe"
+	case code == 139:
+		return "Segmentation fault (SIGSEGV)"
+	case code == 143:
+		return "Terminated by SIGTERM"
+	case code > 128 && code < 160:
+		return fmt.Sprintf("Killed by signal %d", code-128)
+	}
+	return "Application-specific error"
+}
+
+func diagnoseContainer(status *corev1.ContainerStatus, spec *corev1.Container) Container {
+	c := Container{
+		Name:         status.Name,
+		Image:        status.Image,
+		Ready:        status.Ready,
+		RestartCount: status.RestartCount,
+		State:        "unknown",
+	}
+	switch {
+	case status.State.Waiting != nil:
+		c.State, c.Reason, c.Message = "waiting", status.State.Waiting.Reason, status.State.Waiting.Message
+	case status.State.Running != nil:
+		c.State = "running"
+	case status.State.Terminated != nil:
+		c.State, c.Reason, c.Message = "terminated", status.State.Terminated.Reason, status.State.Terminated.Message
+	}
+	c.CrashLooping = c.Reason == ReasonCrashLoopBackOff
+
+	// The previous instance's exit, or the current one of a container that has not restarted
+	if t := status.LastTerminationState.Terminated; t != nil {
+		c.LastTermination = newTermination(t)
+	} else if t := status.State.Terminated; t != nil && t.ExitCode != 0 {
+		c.LastTermination = newTermination(t)
+	}
+	c.Hints = hints(&c, spec)
+	return c
+}
+
+func newTermination(t *corev1.ContainerStateTerminated) *Termination {
+	term := &Termination{
+		ExitCode:   t.ExitCode,
+		Signal:     t.Signal,
+		Reason:     t.Reason,
+		Message:    t.Message,
+		StartedAt:  t.StartedAt.Time,
+		FinishedAt: t.FinishedAt.Time,
+		Meaning:    ExitCodeMeaning(t.ExitCode),
+	}
+	if !t.StartedAt.IsZero() && !t.FinishedAt.IsZero() {
+		term.RuntimeSeconds = math.Round(t.FinishedAt.Sub(t.StartedAt.Time).Seconds()*10) / 10
+	}
+	return term
+}
+
+// hints suggests likely root causes from the container's state and spec
+func hints(c *Container, spec *corev1.Container) []string {
+	var hints []string
+	switch c.Reason {
+	case "ImagePullBackOff", "ErrImagePull", "InvalidImageName":
+		hints = append(hints, fmt.Sprintf("The image %q cannot be pulled; check the name, tag and imagePullSecrets", c.Image))
+	case "CreateContainerConfigError":
+		hints = append(hints, "A ConfigMap, Secret or key referenced by the container's env or volumes is missing")
+	case "RunContainerError", "StartError":
+		hints = append(hints, "The runtime could not start the container; check the command, working directory and volume mounts")
+	}
+
+	t := c.LastTermination
+	if t == nil {
+		return hints
+	}
+	switch {
+	case t.Reason == "OOMKilled":
+		hint := "The container ran out of memory"
+		if spec != nil {
+			if limit, ok := spec.Resources.Limits[corev1.ResourceMemory]; ok {
+				hint += fmt.Sprintf(" (limit %s)", limit.String())
+			}
+		}
+		hints = append(hints, hint+"; raise the memory limit or reduce usage")
+	case t.ExitCode == 137:
+		if spec != nil && spec.LivenessProbe != nil {
+			hints = append(hints, "Killed by SIGKILL; a failing liveness probe is likely, check the probe's timing against startup time")
+		} else {
+			hints = append(hints, "Killed by SIGKILL without an OOM record; check node pressure and evictions")
+		}
+	case t.ExitCode == 143 && spec != nil && spec.LivenessProbe != nil:
+		hints = append(hints, "Terminated by SIGTERM; a failing liveness probe restarts the container this way")
+	case t.ExitCode == 126 || t.ExitCode == 127:
+		hints = append(hints, "The container's command or entrypoint is wrong or missing from the image")
+	case t.ExitCode != 0 && t.RuntimeSeconds > 0 && t.RuntimeSeconds < quickExit.Seconds():
+		hints = append(hints, "The container exits right after starting; check its configuration, environment and the log tail")
+	case t.ExitCode != 0:
+		hints = append(hints, "The application exited with an error; the log tail usually names it")
+	case c.CrashLooping:
+		hints = append(hints, "The container exits successfully but is restarted; a long-running process is expected under restartPolicy Always")
+	}
+	return hints
+}
@@ -0,0 +1,108 @@
+package diagnosis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func crashLoopingPod(last corev1.ContainerStateTerminated, spec corev1.Container) *corev1.Pod {
+	spec.Name = "app"
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec:       corev1.PodSpec{NodeName: "node-1", Containers: []corev1.Container{spec}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:                 "app",
+				Image:                "shop/web:1.2",
+				RestartCount:         7,
+				State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: ReasonCrashLoopBackOff, Message: "back-off 5m0s"}},
+				LastTerminationState: corev1.ContainerState{Terminated: &last},
+			}},
+		},
+	}
+}
+
+func TestDiagnose_OOMKilled(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	pod := crashLoopingPod(
+		corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled", StartedAt: metav1.NewTime(start), FinishedAt: metav1.NewTime(start.Add(95 * time.Second))},
+		corev1.Container{Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}}},
+	)
+
+	report := Diagnose(pod)
+	assert.True(t, report.CrashLooping)
+	assert.Equal(t, int32(7), report.Restarts)
+	require.Len(t, report.Containers, 1)
+
+	c := report.Containers[0]
+	assert.Equal(t, "waiting", c.State)
+	assert.Equal(t, ReasonCrashLoopBackOff, c.Reason)
+	require.NotNil(t, c.LastTermination)
+	assert.Equal(t, int32(137), c.LastTermination.ExitCode)
+	assert.Equal(t, 95.0, c.LastTermination.RuntimeSeconds)
+	require.Len(t, c.Hints, 1)
+	assert.Contains(t, c.Hints[0], "limit 256Mi")
+}
+
+func TestDiagnose_Hints(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	probe := &corev1.Probe{}
+	cases := []struct {
+		name string
+		last corev1.ContainerStateTerminated
+		spec corev1.Container
+		want string
+	}{
+		{"liveness kill", corev1.ContainerStateTerminated{ExitCode: 137}, corev1.Container{LivenessProbe: probe}, "liveness probe"},
+		{"sigkill", corev1.ContainerStateTerminated{ExitCode: 137}, corev1.Container{}, "evictions"},
+		{"missing command", corev1.ContainerStateTerminated{ExitCode: 127}, corev1.Container{}, "entrypoint"},
+		{"quick exit", corev1.ContainerStateTerminated{ExitCode: 1, StartedAt: metav1.NewTime(start), FinishedAt: metav1.NewTime(start.Add(2 * time.Second))}, corev1.Container{}, "right after starting"},
+		{"app error", corev1.ContainerStateTerminated{ExitCode: 1}, corev1.Container{}, "log tail"},
+		{"clean exit", corev1.ContainerStateTerminated{ExitCode: 0}, corev1.Container{}, "restartPolicy"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := Diagnose(crashLoopingPod(tc.last, tc.spec)).Containers[0]
+			require.Len(t, c.Hints, 1)
+			assert.Contains(t, c.Hints[0], tc.want)
+		})
+	}
+}
+
+func TestDiagnose_WaitingReasonsAndInitContainers(t *testing.T) {
+	pod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "migrate",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2, Reason: "Error"}},
+			}},
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				Image: "shop/web:missing",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			}},
+		},
+	}
+
+	report := Diagnose(pod)
+	assert.False(t, report.CrashLooping)
+	require.Len(t, report.Containers, 2)
+	assert.True(t, report.Containers[0].Init)
+	require.NotNil(t, report.Containers[0].LastTermination, "a failed exit without restarts is still reported")
+	assert.Equal(t, int32(2), report.Containers[0].LastTermination.ExitCode)
+	assert.Contains(t, report.Containers[1].Hints[0], "shop/web:missing")
+}
+
+func TestExitCodeMeaning(t *testing.T) {
+	assert.Equal(t, "Command not found", ExitCodeMeaning(127))
+	assert.Equal(t, "Killed by signal 6", ExitCodeMeaning(134))
+	assert.Equal(t, "Application-specific error", ExitCodeMeaning(42))
+}
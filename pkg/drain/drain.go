@@ -0,0 +1,107 @@
+// Package drain tracks the requests and long-lived streams an API server is serving, so a
+// shutdown can stop taking new work and wait for what is in flight before it closes
+// connections
+package drain
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// pollInterval is how often a wait checks whether the tracked work has finished
+var pollInterval = 50 * time.Millisecond
+
+// Tracker counts in-flight requests and streams. A nil Tracker tracks nothing.
+type Tracker struct {
+	requests atomic.Int64
+	streams  atomic.Int64
+	draining atomic.Bool
+
+	streamCtx  context.Context // Cancelled by EndStreams
+	endStreams context.CancelFunc
+}
+
+// New creates a tracker
+func New() *Tracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Tracker{streamCtx: ctx, endStreams: cancel}
+}
+
+// TrackRequest counts a request until the returned function is called
+func (t *Tracker) TrackRequest() func() {
+	if t == nil {
+		return func() {}
+	}
+	t.requests.Add(1)
+	return func() { t.requests.Add(-1) }
+}
+
+// TrackStream counts a stream until the returned function is called. Streams outlive the
+// handler that starts them, so they are tracked apart from requests.
+func (t *Tracker) TrackStream() func() {
+	if t == nil {
+		return func() {}
+	}
+	t.streams.Add(1)
+	return func() { t.streams.Add(-1) }
+}
+
+// InFlight returns the number of requests and streams being served
+func (t *Tracker) InFlight() (requests, streams int64) {
+	if t == nil {
+		return 0, 0
+	}
+	return t.requests.Load(), t.streams.Load()
+}
+
+// Start begins draining; it cannot be undone
+func (t *Tracker) Start() {
+	if t != nil {
+		t.draining.Store(true)
+	}
+}
+
+// Draining reports whether Start was called
+func (t *Tracker) Draining() bool {
+	return t != nil && t.draining.Load()
+}
+
+// StreamContext returns a context that EndStreams cancels, for streams that read from
+// upstream connections rather than from subscriptions closed on shutdown
+func (t *Tracker) StreamContext() context.Context {
+	if t == nil {
+		return context.Background()
+	}
+	return t.streamCtx
+}
+
+// EndStreams cancels the stream context
+func (t *Tracker) EndStreams() {
+	if t != nil {
+		t.endStreams()
+	}
+}
+
+// WaitRequests waits until no request is in flight or the context is done
+func (t *Tracker) WaitRequests(ctx context.Context) error {
+	return t.wait(ctx, func() int64 { requests, _ := t.InFlight(); return requests })
+}
+
+// WaitStreams waits until no stream is open or the context is done
+func (t *Tracker) WaitStreams(ctx context.Context) error {
+	return t.wait(ctx, func() int64 { _, streams := t.InFlight(); return streams })
+}
+
+func (t *Tracker) wait(ctx context.Context, count func() int64) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for count() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package drain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	pollInterval = time.Millisecond
+}
+
+// TestTracker tests counting requests and streams
+func TestTracker(t *testing.T) {
+	tracker := New()
+	doneRequest := tracker.TrackRequest()
+	doneStream := tracker.TrackStream()
+	tracker.TrackStream()()
+
+	requests, streams := tracker.InFlight()
+	assert.Equal(t, int64(1), requests)
+	assert.Equal(t, int64(1), streams)
+
+	doneRequest()
+	doneStream()
+	requests, streams = tracker.InFlight()
+	assert.Zero(t, requests)
+	assert.Zero(t, streams)
+
+	assert.False(t, tracker.Draining())
+	tracker.Start()
+	assert.True(t, tracker.Draining())
+}
+
+// TestTracker_Wait tests that waits return once the work finishes or the context is done
+func TestTracker_Wait(t *testing.T) {
+	tracker := New()
+	done := tracker.TrackRequest()
+	time.AfterFunc(10*time.Millisecond, done)
+	require.NoError(t, tracker.WaitRequests(context.Background()))
+
+	tracker.TrackStream()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, tracker.WaitStreams(ctx), context.DeadlineExceeded)
+	assert.NoError(t, tracker.WaitRequests(ctx), "open streams do not hold up requests")
+}
+
+// TestTracker_EndStreams tests that ending streams cancels the stream context
+func TestTracker_EndStreams(t *testing.T) {
+	tracker := New()
+	require.NoError(t, tracker.StreamContext().Err())
+	tracker.EndStreams()
+	assert.ErrorIs(t, tracker.StreamContext().Err(), context.Canceled)
+}
+
+// TestTracker_Nil tests that a nil tracker tracks nothing
+func TestTracker_Nil(t *testing.T) {
+	var tracker *Tracker
+	tracker.TrackRequest()()
+	tracker.TrackStream()()
+	tracker.Start()
+	tracker.EndStreams()
+	assert.False(t, tracker.Draining())
+	assert.NoError(t, tracker.StreamContext().Err())
+	assert.NoError(t, tracker.WaitRequests(context.Background()))
+}
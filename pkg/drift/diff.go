@@ -0,0 +1,168 @@
+package drift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// FieldDiff is a single field whose live value differs from the desired value
+type FieldDiff struct {
+	Path    string      `json:"path"`
+	Desired interface{} `json:"desired"`
+	Live    interface{} `json:"live"`
+}
+
+// ParseManifests decodes a multi-document YAML or JSON stream into objects.
+// Empty documents and List kinds are flattened.
+func ParseManifests(data []byte) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+
+	var objects []*unstructured.Unstructured
+	for {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		}
+		raw.Raw = bytes.TrimSpace(raw.Raw)
+		if len(raw.Raw) == 0 || bytes.Equal(raw.Raw, []byte("null")) {
+			continue
+		}
+
+		// UnmarshalJSON keeps integers as int64, matching objects read from the API
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw.Raw); err != nil {
+			return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, err
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("manifest is missing apiVersion, kind or metadata.name")
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// ignoredMetadata are server-managed metadata fields never compared
+var ignoredMetadata = map[string]bool{
+	"creationTimestamp": true,
+	"generation":        true,
+	"managedFields":     true,
+	"resourceVersion":   true,
+	"selfLink":          true,
+	"uid":               true,
+	"namespace":         true,
+}
+
+// Compare returns the fields set in desired whose live values differ.
+// Only fields present in the desired manifest are compared, so defaults
+// filled in by the API server do not count as drift.
+func Compare(desired, live *unstructured.Unstructured) []FieldDiff {
+	var diffs []FieldDiff
+	for key, value := range desired.Object {
+		if key == "status" || key == "apiVersion" || key == "kind" {
+			continue
+		}
+		if key == "metadata" {
+			meta, _ := value.(map[string]interface{})
+			liveMeta, _ := live.Object["metadata"].(map[string]interface{})
+			for field, v := range meta {
+				if ignoredMetadata[field] {
+					continue
+				}
+				diffs = append(diffs, compareValue("metadata."+field, v, liveMeta[field])...)
+			}
+			continue
+		}
+		diffs = append(diffs, compareValue(key, value, live.Object[key])...)
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+func compareValue(path string, desired, live interface{}) []FieldDiff {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return []FieldDiff{{Path: path, Desired: desired, Live: live}}
+		}
+		var diffs []FieldDiff
+		for key, value := range d {
+			diffs = append(diffs, compareValue(path+"."+escapeKey(key), value, l[key])...)
+		}
+		return diffs
+
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return []FieldDiff{{Path: path, Desired: desired, Live: live}}
+		}
+		var diffs []FieldDiff
+		for i := range d {
+			diffs = append(diffs, compareValue(fmt.Sprintf("%s[%d]", path, i), d[i], l[i])...)
+		}
+		return diffs
+
+	default:
+		if !scalarEqual(desired, live) {
+			return []FieldDiff{{Path: path, Desired: desired, Live: live}}
+		}
+		return nil
+	}
+}
+
+// scalarEqual compares scalars, treating numbers of different Go types as equal
+func scalarEqual(a, b interface{}) bool {
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		return ok && af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// escapeKey quotes keys containing dots, such as label and annotation names
+func escapeKey(key string) string {
+	if strings.Contains(key, ".") {
+		return "[" + key + "]"
+	}
+	return key
+}
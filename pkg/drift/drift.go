@@ -0,0 +1,368 @@
+// Package drift compares desired-state manifests with live cluster objects
+package drift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Resource states reported by a drift check
+const (
+	StatusInSync  = "in-sync"
+	StatusDrifted = "drifted"
+	StatusMissing = "missing"
+	StatusError   = "error"
+)
+
+// GitSource clones a repository and reads manifests below Path
+type GitSource struct {
+	URL  string `mapstructure:"url"`
+	Ref  string `mapstructure:"ref"`  // Branch or tag, defaults to the remote HEAD
+	Path string `mapstructure:"path"` // Directory inside the repository
+}
+
+// Source points at desired manifests for a namespace
+type Source struct {
+	Namespace string    `mapstructure:"namespace"`
+	Path      string    `mapstructure:"path"` // Local directory or file, e.g. a git-sync checkout
+	Git       GitSource `mapstructure:"git"`
+}
+
+// Config holds drift detection settings
+type Config struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Interval        time.Duration `mapstructure:"interval"`
+	Sources         []Source      `mapstructure:"sources"`
+	GitCacheDir     string        `mapstructure:"git_cache_dir"`     // Where Git sources are cloned
+	AlertWebhookURL string        `mapstructure:"alert_webhook_url"` // Slack-compatible webhook for new drift
+}
+
+// ResourceReport is the drift state of one desired object
+type ResourceReport struct {
+	Namespace  string      `json:"namespace"`
+	APIVersion string      `json:"api_version"`
+	Kind       string      `json:"kind"`
+	Name       string      `json:"name"`
+	Status     string      `json:"status"`
+	Error      string      `json:"error,omitempty"`
+	Diffs      []FieldDiff `json:"diffs,omitempty"`
+}
+
+// key identifies the resource across checks
+func (r ResourceReport) key() string {
+	return strings.Join([]string{r.Namespace, r.APIVersion, r.Kind, r.Name}, "/")
+}
+
+// LiveGetter fetches the live version of a desired object
+type LiveGetter func(ctx context.Context, desired *unstructured.Unstructured) (*unstructured.Unstructured, error)
+
+// Runner executes a command in a directory
+type Runner func(ctx context.Context, dir, name string, args ...string) error
+
+func execRunner(ctx context.Context, dir, name string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Detector keeps desired bundles per namespace and compares them with live objects
+type Detector struct {
+	config Config
+	get    LiveGetter
+	run    Runner
+	client *http.Client
+
+	checkMu   sync.Mutex // Serializes checks triggered by the ticker and by uploads
+	mu        sync.RWMutex
+	uploaded  map[string][]*unstructured.Unstructured // Bundles uploaded through the API
+	reports   []ResourceReport
+	lastCheck time.Time
+}
+
+// NewDetector creates a detector; a nil runner uses the git binary
+func NewDetector(cfg Config, get LiveGetter, run Runner) *Detector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Minute
+	}
+	if cfg.GitCacheDir == "" {
+		cfg.GitCacheDir = filepath.Join(os.TempDir(), "k8s-custom-controller-drift")
+	}
+	if run == nil {
+		run = execRunner
+	}
+	return &Detector{
+		config:   cfg,
+		get:      get,
+		run:      run,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		uploaded: make(map[string][]*unstructured.Unstructured),
+	}
+}
+
+// SetBundle replaces the uploaded desired manifests for a namespace
+func (d *Detector) SetBundle(namespace string, objects []*unstructured.Unstructured) {
+	for _, obj := range objects {
+		obj.SetNamespace(namespace)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.uploaded[namespace] = objects
+}
+
+// DeleteBundle removes the uploaded manifests for a namespace
+func (d *Detector) DeleteBundle(namespace string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.uploaded[namespace]
+	delete(d.uploaded, namespace)
+	return ok
+}
+
+// Start checks immediately and then on every interval until the context is canceled
+func (d *Detector) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Check(ctx); err != nil {
+			log.Error().Err(err).Msg("Drift check failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check compares all desired bundles with live objects and alerts on newly drifted resources
+func (d *Detector) Check(ctx context.Context) error {
+	d.checkMu.Lock()
+	defer d.checkMu.Unlock()
+
+	bundles, err := d.desired(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reports []ResourceReport
+	for namespace, objects := range bundles {
+		for _, obj := range objects {
+			reports = append(reports, d.checkObject(ctx, namespace, obj))
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].key() < reports[j].key() })
+
+	d.mu.Lock()
+	previous := make(map[string]string, len(d.reports))
+	for _, r := range d.reports {
+		previous[r.key()] = r.Status
+	}
+	firstCheck := d.lastCheck.IsZero()
+	d.reports = reports
+	d.lastCheck = time.Now()
+	d.mu.Unlock()
+
+	// Alert on resources that were in sync (or unknown) and now differ
+	var changed []string
+	for _, r := range reports {
+		if r.Status != StatusDrifted && r.Status != StatusMissing {
+			continue
+		}
+		if prev, seen := previous[r.key()]; firstCheck || !seen || prev != r.Status {
+			changed = append(changed, fmt.Sprintf("%s %s/%s is %s", r.Kind, r.Namespace, r.Name, r.Status))
+		}
+	}
+	log.Info().Int("resources", len(reports)).Int("new_drift", len(changed)).Msg("Drift check completed")
+	if len(changed) > 0 {
+		d.alert(ctx, changed)
+	}
+	return nil
+}
+
+// Reports returns the latest drift reports, optionally limited to a namespace
+func (d *Detector) Reports(namespace string) []ResourceReport {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]ResourceReport, 0, len(d.reports))
+	for _, r := range d.reports {
+		if namespace == "" || r.Namespace == namespace {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// LastCheck returns when the last check completed
+func (d *Detector) LastCheck() time.Time {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.lastCheck
+}
+
+func (d *Detector) checkObject(ctx context.Context, namespace string, desired *unstructured.Unstructured) ResourceReport {
+	report := ResourceReport{
+		Namespace:  namespace,
+		APIVersion: desired.GetAPIVersion(),
+		Kind:       desired.GetKind(),
+		Name:       desired.GetName(),
+	}
+
+	live, err := d.get(ctx, desired)
+	switch {
+	case apierrors.IsNotFound(err):
+		report.Status = StatusMissing
+	case err != nil:
+		report.Status = StatusError
+		report.Error = err.Error()
+	default:
+		report.Diffs = Compare(desired, live)
+		report.Status = StatusInSync
+		if len(report.Diffs) > 0 {
+			report.Status = StatusDrifted
+		}
+	}
+	return report
+}
+
+// desired merges configured sources with uploaded bundles; uploads win for the same namespace
+func (d *Detector) desired(ctx context.Context) (map[string][]*unstructured.Unstructured, error) {
+	bundles := make(map[string][]*unstructured.Unstructured)
+	for _, source := range d.config.Sources {
+		objects, err := d.loadSource(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load desired state for namespace %s: %w", source.Namespace, err)
+		}
+		for _, obj := range objects {
+			obj.SetNamespace(source.Namespace)
+		}
+		bundles[source.Namespace] = append(bundles[source.Namespace], objects...)
+	}
+
+	d.mu.RLock()
+	for namespace, objects := range d.uploaded {
+		bundles[namespace] = objects
+	}
+	d.mu.RUnlock()
+	return bundles, nil
+}
+
+func (d *Detector) loadSource(ctx context.Context, source Source) ([]*unstructured.Unstructured, error) {
+	path := source.Path
+	if source.Git.URL != "" {
+		checkout, err := d.syncGit(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(checkout, source.Git.Path)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("source needs a path or git url")
+	}
+	return loadPath(path)
+}
+
+// syncGit clones the repository on first use and fetches the ref afterwards
+func (d *Detector) syncGit(ctx context.Context, source Source) (string, error) {
+	dir := filepath.Join(d.config.GitCacheDir, source.Namespace)
+	ref := source.Git.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if err := os.MkdirAll(d.config.GitCacheDir, 0750); err != nil {
+			return "", err
+		}
+		if err := d.run(ctx, d.config.GitCacheDir, "git", "clone", "--quiet", "--depth", "1", source.Git.URL, dir); err != nil {
+			return "", err
+		}
+	}
+	if err := d.run(ctx, dir, "git", "fetch", "--quiet", "--depth", "1", "origin", ref); err != nil {
+		return "", err
+	}
+	if err := d.run(ctx, dir, "git", "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// loadPath reads .yaml, .yml and .json manifests from a file or directory tree
+func loadPath(path string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	err := filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		parsed, err := ParseManifests(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		objects = append(objects, parsed...)
+		return nil
+	})
+	return objects, err
+}
+
+// alert posts drift changes to the webhook and logs them
+func (d *Detector) alert(ctx context.Context, changes []string) {
+	log.Warn().Strs("resources", changes).Msg("Unexpected drift from desired state")
+	if d.config.AlertWebhookURL == "" {
+		return
+	}
+
+	text := fmt.Sprintf("%d resources drifted from desired state:\n• %s", len(changes), strings.Join(changes, "\n• "))
+	body, _ := json.Marshal(map[string]string{"text": text})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.config.AlertWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build drift alert")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send drift alert")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Error().Int("status", resp.StatusCode).Msg("Drift alert webhook rejected the request")
+	}
+}
@@ -0,0 +1,165 @@
+package drift
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const bundle = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app.kubernetes.io/name: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.25
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: production
+`
+
+func liveObjects(t *testing.T, objects ...string) LiveGetter {
+	live := map[string]*unstructured.Unstructured{}
+	for _, o := range objects {
+		parsed, err := ParseManifests([]byte(o))
+		require.NoError(t, err)
+		live[parsed[0].GetKind()+"/"+parsed[0].GetName()] = parsed[0]
+	}
+	return func(ctx context.Context, desired *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+		if obj, ok := live[desired.GetKind()+"/"+desired.GetName()]; ok {
+			return obj, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: desired.GetKind()}, desired.GetName())
+	}
+}
+
+func TestParseManifests(t *testing.T) {
+	objects, err := ParseManifests([]byte(bundle + "\n---\n"))
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "Deployment", objects[0].GetKind())
+	assert.Equal(t, "settings", objects[1].GetName())
+
+	_, err = ParseManifests([]byte("kind: Deployment\n"))
+	assert.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	desired, err := ParseManifests([]byte(bundle))
+	require.NoError(t, err)
+
+	live := desired[0].DeepCopy()
+	live.SetResourceVersion("42")
+	live.Object["status"] = map[string]interface{}{"replicas": int64(2)}
+	require.NoError(t, unstructured.SetNestedField(live.Object, int64(3), "spec", "replicas"))
+	require.NoError(t, unstructured.SetNestedField(live.Object, int64(600), "spec", "progressDeadlineSeconds"))
+	assert.Empty(t, Compare(desired[0], live), "server defaults and status are not drift")
+
+	require.NoError(t, unstructured.SetNestedField(live.Object, int64(5), "spec", "replicas"))
+	live.SetLabels(map[string]string{"app.kubernetes.io/name": "api"})
+	diffs := Compare(desired[0], live)
+	require.Len(t, diffs, 2)
+	assert.Equal(t, "metadata.labels.[app.kubernetes.io/name]", diffs[0].Path)
+	assert.Equal(t, FieldDiff{Path: "spec.replicas", Desired: int64(3), Live: int64(5)}, diffs[1])
+}
+
+func TestDetector_Check(t *testing.T) {
+	var alerts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		alerts = append(alerts, body["text"])
+	}))
+	defer server.Close()
+
+	get := liveObjects(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app.kubernetes.io/name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.25
+`)
+	d := NewDetector(Config{AlertWebhookURL: server.URL}, get, nil)
+	objects, err := ParseManifests([]byte(bundle))
+	require.NoError(t, err)
+	d.SetBundle("shop", objects)
+
+	require.NoError(t, d.Check(context.Background()))
+	reports := d.Reports("shop")
+	require.Len(t, reports, 2)
+	assert.Equal(t, StatusDrifted, reports[0].Status)
+	assert.Equal(t, "spec.replicas", reports[0].Diffs[0].Path)
+	assert.Equal(t, "ConfigMap", reports[1].Kind)
+	assert.Equal(t, StatusMissing, reports[1].Status)
+	require.Len(t, alerts, 1)
+
+	// Unchanged drift is not alerted again
+	require.NoError(t, d.Check(context.Background()))
+	assert.Len(t, alerts, 1)
+	assert.Empty(t, d.Reports("other"))
+
+	assert.True(t, d.DeleteBundle("shop"))
+	require.NoError(t, d.Check(context.Background()))
+	assert.Empty(t, d.Reports(""))
+}
+
+func TestDetector_GitSource(t *testing.T) {
+	cache := t.TempDir()
+	var commands [][]string
+	runner := func(ctx context.Context, dir, name string, args ...string) error {
+		commands = append(commands, args)
+		if args[0] == "clone" {
+			checkout := args[len(args)-1]
+			require.NoError(t, os.MkdirAll(filepath.Join(checkout, ".git"), 0750))
+			require.NoError(t, os.MkdirAll(filepath.Join(checkout, "apps", "shop"), 0750))
+			return os.WriteFile(filepath.Join(checkout, "apps", "shop", "bundle.yaml"), []byte(bundle), 0600)
+		}
+		return nil
+	}
+
+	d := NewDetector(Config{
+		GitCacheDir: cache,
+		Sources: []Source{{
+			Namespace: "shop",
+			Git:       GitSource{URL: "https://example.com/desired.git", Ref: "main", Path: "apps/shop"},
+		}},
+	}, liveObjects(t), runner)
+
+	require.NoError(t, d.Check(context.Background()))
+	assert.Len(t, d.Reports("shop"), 2)
+	assert.Equal(t, "clone", commands[0][0])
+	assert.Equal(t, []string{"fetch", "--quiet", "--depth", "1", "origin", "main"}, commands[1])
+
+	// The checkout is reused on the next check
+	require.NoError(t, d.Check(context.Background()))
+	assert.Equal(t, "fetch", commands[3][0])
+}
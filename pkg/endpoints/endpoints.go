@@ -0,0 +1,169 @@
+// Package endpoints decides which groups of API routes are exposed, so operators can remove
+// endpoints such as secrets, exec or every write operation at deploy time
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GroupWrite holds every route whose method is not GET, HEAD or OPTIONS
+const GroupWrite = "write"
+
+// Config holds route exposure settings
+type Config struct {
+	// Groups whose routes are removed: write, the first path segment of a route such as
+	// secrets or clusters, or a segment following a path parameter such as exec, logs or proxy
+	Disabled []string `mapstructure:"disabled"`
+}
+
+// Validate checks that every disabled group is named
+func (c Config) Validate() error {
+	for i, group := range c.Disabled {
+		if group == "" || strings.ContainsAny(group, "/{} ") {
+			return fmt.Errorf("disabled[%d]: invalid group %q, expected a name such as secrets, exec or write", i, group)
+		}
+	}
+	return nil
+}
+
+// Groups returns the groups of a route pattern: its first segment, every static segment
+// following a parameter, and write for methods that change state. Parameters are written
+// as {name}, as in the router and the OpenAPI document.
+func Groups(method, pattern string) []string {
+	var groups []string
+	afterParam := false
+	for i, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if strings.HasPrefix(segment, "{") {
+			afterParam = true
+			continue
+		}
+		if segment != "" && (i == 0 || afterParam) {
+			groups = append(groups, segment)
+		}
+	}
+	switch strings.ToUpper(method) {
+	case "GET", "HEAD", "OPTIONS":
+	default:
+		groups = append(groups, GroupWrite)
+	}
+	return groups
+}
+
+// Policy answers whether routes are disabled. A nil Policy disables nothing.
+type Policy struct {
+	disabled map[string]bool
+	matched  map[string]bool // Disabled groups that matched a route
+}
+
+// New creates a policy from validated settings, or nil when no group is disabled
+func New(cfg Config) *Policy {
+	if len(cfg.Disabled) == 0 {
+		return nil
+	}
+	p := &Policy{disabled: make(map[string]bool), matched: make(map[string]bool)}
+	for _, group := range cfg.Disabled {
+		p.disabled[strings.ToLower(group)] = true
+	}
+	return p
+}
+
+// Disabled reports whether a route pattern belongs to a disabled group and remembers the
+// groups it matched. It is meant for registering routes, which happens once; FilterOpenAPI
+// may run concurrently.
+func (p *Policy) Disabled(method, pattern string) bool {
+	if p == nil {
+		return false
+	}
+	disabled := false
+	for _, group := range Groups(method, pattern) {
+		if p.disabled[group] {
+			p.matched[group] = true
+			disabled = true
+		}
+	}
+	return disabled
+}
+
+// disables reports whether a route pattern belongs to a disabled group
+func (p *Policy) disables(method, pattern string) bool {
+	for _, group := range Groups(method, pattern) {
+		if p.disabled[group] {
+			return true
+		}
+	}
+	return false
+}
+
+// Unmatched returns the disabled groups that matched none of the routes registered,
+// which are most likely misspelled
+func (p *Policy) Unmatched() []string {
+	if p == nil {
+		return nil
+	}
+	var groups []string
+	for group := range p.disabled {
+		if !p.matched[group] {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// FilterOpenAPI removes the operations of disabled routes from an OpenAPI or Swagger
+// document, and paths left without operations
+func (p *Policy) FilterOpenAPI(doc []byte) ([]byte, error) {
+	if p == nil {
+		return doc, nil
+	}
+	var spec map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &spec); err != nil {
+		return nil, err
+	}
+	raw, ok := spec["paths"]
+	if !ok {
+		return doc, nil
+	}
+	var paths map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &paths); err != nil {
+		return nil, err
+	}
+	for path, operations := range paths {
+		for method := range operations {
+			if isOperation(method) && p.disables(method, path) {
+				delete(operations, method)
+			}
+		}
+		if !hasOperation(operations) {
+			delete(paths, path)
+		}
+	}
+	raw, err := json.Marshal(paths)
+	if err != nil {
+		return nil, err
+	}
+	spec["paths"] = raw
+	return json.Marshal(spec)
+}
+
+func hasOperation(operations map[string]json.RawMessage) bool {
+	for key := range operations {
+		if isOperation(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOperation reports whether a key of a path item is a method rather than a path-level
+// entry such as parameters or an extension
+func isOperation(key string) bool {
+	switch key {
+	case "parameters", "servers", "summary", "description", "$ref":
+		return false
+	}
+	return !strings.HasPrefix(key, "x-")
+}
@@ -0,0 +1,65 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGroups tests the groups of route patterns
+func TestGroups(t *testing.T) {
+	assert.Equal(t, []string{"secrets"}, Groups("GET", "/secrets/{namespace}/{name}"))
+	assert.Equal(t, []string{"pods", "exec"}, Groups("GET", "/pods/{namespace}/{name}/exec"))
+	assert.Equal(t, []string{"pods", "proxy", "write"}, Groups("POST", "/pods/{namespace}/{name}/proxy/{port}/{path:*}"))
+	assert.Equal(t, []string{"history"}, Groups("GET", "/history/deployments/{namespace}/{name}"))
+	assert.Equal(t, []string{"deployments", "scale", "write"}, Groups("patch", "/deployments/{namespace}/{name}/scale"))
+	assert.Empty(t, Groups("GET", "/"))
+}
+
+// TestPolicy tests disabling routes and reporting groups that matched none
+func TestPolicy(t *testing.T) {
+	require.NoError(t, Config{Disabled: []string{"secrets", "Exec", "write", "secret"}}.Validate())
+	p := New(Config{Disabled: []string{"secrets", "Exec", "write", "secret"}})
+
+	assert.True(t, p.Disabled("GET", "/secrets"))
+	assert.True(t, p.Disabled("GET", "/pods/{namespace}/{name}/exec"))
+	assert.True(t, p.Disabled("DELETE", "/clusters"))
+	assert.False(t, p.Disabled("GET", "/clusters"))
+	assert.False(t, p.Disabled("HEAD", "/pods/{namespace}/{name}/logs"))
+	assert.Equal(t, []string{"secret"}, p.Unmatched())
+
+	var none *Policy
+	assert.False(t, none.Disabled("POST", "/clusters"))
+	assert.Nil(t, New(Config{}))
+
+	assert.Error(t, Config{Disabled: []string{"/secrets"}}.Validate())
+	assert.Error(t, Config{Disabled: []string{""}}.Validate())
+}
+
+// TestPolicy_FilterOpenAPI tests that disabled operations and emptied paths are removed
+func TestPolicy_FilterOpenAPI(t *testing.T) {
+	doc := `{"swagger":"2.0","paths":{
+		"/clusters":{"get":{"summary":"list"},"post":{"summary":"add"}},
+		"/secrets":{"get":{"summary":"secrets"}},
+		"/pods/{namespace}/{name}/exec":{"parameters":[],"get":{"summary":"exec"}}
+	}}`
+	filtered, err := New(Config{Disabled: []string{"write", "exec"}}).FilterOpenAPI([]byte(doc))
+	require.NoError(t, err)
+
+	var spec struct {
+		Swagger string                            `json:"swagger"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(filtered, &spec))
+	assert.Equal(t, "2.0", spec.Swagger)
+	assert.Len(t, spec.Paths, 2)
+	assert.Contains(t, spec.Paths["/clusters"], "get")
+	assert.NotContains(t, spec.Paths["/clusters"], "post")
+	assert.Contains(t, spec.Paths, "/secrets")
+
+	unchanged, err := (*Policy)(nil).FilterOpenAPI([]byte(doc))
+	require.NoError(t, err)
+	assert.Equal(t, doc, string(unchanged))
+}
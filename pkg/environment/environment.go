@@ -0,0 +1,309 @@
+// Package environment defines the Environment custom resource, an ephemeral copy of a
+// namespace's workloads such as a per-PR preview, and the transforms that clone them
+package environment
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// API coordinates of the Environment custom resource, installed from config/crd
+const (
+	Group    = "k8s-custom-controller.io"
+	Version  = "v1alpha1"
+	Kind     = "Environment"
+	Resource = "environments"
+)
+
+// GVK and GVR identify Environments for unstructured and dynamic clients
+var (
+	GVK = schema.GroupVersionKind{Group: Group, Version: Version, Kind: Kind}
+	GVR = schema.GroupVersionResource{Group: Group, Version: Version, Resource: Resource}
+)
+
+// NameLabel marks the namespace and objects created for an Environment with its name
+const NameLabel = "environments.k8s-custom-controller.io/name"
+
+// lastAppliedAnnotation is not copied, so kubectl apply of the source does not diff against clones
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Phases of an Environment
+const (
+	PhasePending = "Pending" // Not cloned yet
+	PhaseReady   = "Ready"   // Cloned; deleted with its namespace once expired
+	PhaseFailed  = "Failed"  // Could not be cloned, see the message
+)
+
+// Spec is the desired state of an Environment
+type Spec struct {
+	Source         string            `json:"source"`                   // Namespace whose workloads are cloned
+	Namespace      string            `json:"namespace,omitempty"`      // Namespace created for the clone, <source>-<name> when empty
+	Suffix         string            `json:"suffix,omitempty"`         // Appended to cloned object names as -<suffix>, the Environment name when empty
+	Images         map[string]string `json:"images,omitempty"`         // Image overrides by container name, e.g. web: registry/web:pr-42
+	TTL            string            `json:"ttl,omitempty"`            // Lifetime after creation, e.g. 48h; the controller default when empty
+	IncludeSecrets bool              `json:"includeSecrets,omitempty"` // Also clone the source's Secrets
+}
+
+// Status is the observed state of an Environment
+type Status struct {
+	Phase     string       `json:"phase,omitempty"`
+	Namespace string       `json:"namespace,omitempty"`
+	Objects   int          `json:"objects,omitempty"` // Objects cloned into the namespace
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	Message   string       `json:"message,omitempty"`
+}
+
+// Environment is a cluster-scoped request for an ephemeral clone of a namespace
+type Environment struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              Spec   `json:"spec"`
+	Status            Status `json:"status,omitempty"`
+}
+
+// FromUnstructured converts an Environment read through a dynamic or unstructured client
+func FromUnstructured(u *unstructured.Unstructured) (*Environment, error) {
+	var env Environment
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &env); err != nil {
+		return nil, fmt.Errorf("invalid environment %s: %w", u.GetName(), err)
+	}
+	return &env, nil
+}
+
+// ToUnstructured converts an Environment for dynamic and unstructured clients
+func (e *Environment) ToUnstructured() (*unstructured.Unstructured, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(e)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	u.SetGroupVersionKind(GVK)
+	return u, nil
+}
+
+// TargetNamespace returns the namespace the clone is created in
+func (e *Environment) TargetNamespace() string {
+	if e.Spec.Namespace != "" {
+		return e.Spec.Namespace
+	}
+	return e.Spec.Source + "-" + e.Name
+}
+
+// NameSuffix returns the suffix appended to cloned object names
+func (e *Environment) NameSuffix() string {
+	if e.Spec.Suffix != "" {
+		return e.Spec.Suffix
+	}
+	return e.Name
+}
+
+// Lifetime returns how long the Environment lives after creation: its TTL, or defaultTTL when
+// unset, capped at maxTTL when that is set
+func (e *Environment) Lifetime(defaultTTL, maxTTL time.Duration) (time.Duration, error) {
+	ttl := defaultTTL
+	if e.Spec.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(e.Spec.TTL); err != nil {
+			return 0, fmt.Errorf("invalid ttl %q: %w", e.Spec.TTL, err)
+		}
+	}
+	if maxTTL > 0 && (ttl <= 0 || ttl > maxTTL) {
+		ttl = maxTTL
+	}
+	return ttl, nil
+}
+
+// Validate checks the name, namespaces, suffix and TTL
+func (e *Environment) Validate() error {
+	if errs := validation.IsDNS1123Label(e.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid environment name %q: %s", e.Name, strings.Join(errs, "; "))
+	}
+	if e.Spec.Source == "" {
+		return fmt.Errorf("source namespace is required")
+	}
+	target := e.TargetNamespace()
+	if errs := validation.IsDNS1123Label(target); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace %q: %s", target, strings.Join(errs, "; "))
+	}
+	if target == e.Spec.Source {
+		return fmt.Errorf("namespace must differ from the source namespace")
+	}
+	if errs := validation.IsDNS1123Label(e.NameSuffix()); len(errs) > 0 {
+		return fmt.Errorf("invalid suffix %q: %s", e.NameSuffix(), strings.Join(errs, "; "))
+	}
+	if e.Spec.TTL != "" {
+		ttl, err := time.ParseDuration(e.Spec.TTL)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid ttl %q, expected a positive duration such as 48h", e.Spec.TTL)
+		}
+	}
+	return nil
+}
+
+// Cloner copies objects of the source namespace into an Environment's namespace. Names get the
+// suffix, and references between cloned ConfigMaps, Secrets and Services follow them.
+type Cloner struct {
+	Environment string
+	Namespace   string
+	Suffix      string
+	Images      map[string]string
+	ConfigMaps  map[string]bool // Names of the cloned ConfigMaps
+	Secrets     map[string]bool // Names of the cloned Secrets
+	Services    map[string]bool // Names of the cloned Services
+}
+
+// NewCloner returns a cloner for an Environment
+func NewCloner(e *Environment) *Cloner {
+	return &Cloner{
+		Environment: e.Name,
+		Namespace:   e.TargetNamespace(),
+		Suffix:      e.NameSuffix(),
+		Images:      e.Spec.Images,
+		ConfigMaps:  map[string]bool{},
+		Secrets:     map[string]bool{},
+		Services:    map[string]bool{},
+	}
+}
+
+// Name returns the name of the clone of an object
+func (c *Cloner) Name(name string) string {
+	return name + "-" + c.Suffix
+}
+
+// meta copies names, labels and annotations, dropping what belongs to the source object
+func (c *Cloner) meta(src metav1.ObjectMeta) metav1.ObjectMeta {
+	labels := make(map[string]string, len(src.Labels)+1)
+	for k, v := range src.Labels {
+		labels[k] = v
+	}
+	labels[NameLabel] = c.Environment
+	var annotations map[string]string
+	for k, v := range src.Annotations {
+		if k == lastAppliedAnnotation {
+			continue
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[k] = v
+	}
+	return metav1.ObjectMeta{Name: c.Name(src.Name), Namespace: c.Namespace, Labels: labels, Annotations: annotations}
+}
+
+// podSpec points references to cloned objects at the clones and applies image overrides
+func (c *Cloner) podSpec(spec *corev1.PodSpec) {
+	rename := func(names map[string]bool, name string) string {
+		if names[name] {
+			return c.Name(name)
+		}
+		return name
+	}
+	for i := range spec.Volumes {
+		v := &spec.Volumes[i]
+		if v.ConfigMap != nil {
+			v.ConfigMap.Name = rename(c.ConfigMaps, v.ConfigMap.Name)
+		}
+		if v.Secret != nil {
+			v.Secret.SecretName = rename(c.Secrets, v.Secret.SecretName)
+		}
+		if v.Projected != nil {
+			for j := range v.Projected.Sources {
+				s := &v.Projected.Sources[j]
+				if s.ConfigMap != nil {
+					s.ConfigMap.Name = rename(c.ConfigMaps, s.ConfigMap.Name)
+				}
+				if s.Secret != nil {
+					s.Secret.Name = rename(c.Secrets, s.Secret.Name)
+				}
+			}
+		}
+	}
+	for i := range spec.ImagePullSecrets {
+		spec.ImagePullSecrets[i].Name = rename(c.Secrets, spec.ImagePullSecrets[i].Name)
+	}
+	containers := func(list []corev1.Container) {
+		for i := range list {
+			container := &list[i]
+			if image, ok := c.Images[container.Name]; ok {
+				container.Image = image
+			}
+			for j := range container.EnvFrom {
+				from := &container.EnvFrom[j]
+				if from.ConfigMapRef != nil {
+					from.ConfigMapRef.Name = rename(c.ConfigMaps, from.ConfigMapRef.Name)
+				}
+				if from.SecretRef != nil {
+					from.SecretRef.Name = rename(c.Secrets, from.SecretRef.Name)
+				}
+			}
+			for j := range container.Env {
+				if from := container.Env[j].ValueFrom; from != nil {
+					if from.ConfigMapKeyRef != nil {
+						from.ConfigMapKeyRef.Name = rename(c.ConfigMaps, from.ConfigMapKeyRef.Name)
+					}
+					if from.SecretKeyRef != nil {
+						from.SecretKeyRef.Name = rename(c.Secrets, from.SecretKeyRef.Name)
+					}
+				}
+			}
+		}
+	}
+	containers(spec.InitContainers)
+	containers(spec.Containers)
+}
+
+// ConfigMap clones a ConfigMap
+func (c *Cloner) ConfigMap(src *corev1.ConfigMap) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: c.meta(src.ObjectMeta), Data: src.Data, BinaryData: src.BinaryData}
+}
+
+// Secret clones a Secret
+func (c *Cloner) Secret(src *corev1.Secret) *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: c.meta(src.ObjectMeta), Type: src.Type, Data: src.Data}
+}
+
+// Service clones a Service as a ClusterIP service, so previews allocate no node ports or
+// cloud load balancers
+func (c *Cloner) Service(src *corev1.Service) *corev1.Service {
+	ports := make([]corev1.ServicePort, len(src.Spec.Ports))
+	for i, p := range src.Spec.Ports {
+		p.NodePort = 0
+		ports[i] = p
+	}
+	spec := corev1.ServiceSpec{
+		Type:            corev1.ServiceTypeClusterIP,
+		Selector:        src.Spec.Selector,
+		Ports:           ports,
+		SessionAffinity: src.Spec.SessionAffinity,
+	}
+	if src.Spec.ClusterIP == corev1.ClusterIPNone {
+		spec.ClusterIP = corev1.ClusterIPNone
+	}
+	return &corev1.Service{ObjectMeta: c.meta(src.ObjectMeta), Spec: spec}
+}
+
+// Deployment clones a Deployment
+func (c *Cloner) Deployment(src *appsv1.Deployment) *appsv1.Deployment {
+	spec := *src.Spec.DeepCopy()
+	c.podSpec(&spec.Template.Spec)
+	return &appsv1.Deployment{ObjectMeta: c.meta(src.ObjectMeta), Spec: spec}
+}
+
+// StatefulSet clones a StatefulSet; its governing Service is the clone when one was made
+func (c *Cloner) StatefulSet(src *appsv1.StatefulSet) *appsv1.StatefulSet {
+	spec := *src.Spec.DeepCopy()
+	c.podSpec(&spec.Template.Spec)
+	if c.Services[spec.ServiceName] {
+		spec.ServiceName = c.Name(spec.ServiceName)
+	}
+	return &appsv1.StatefulSet{ObjectMeta: c.meta(src.ObjectMeta), Spec: spec}
+}
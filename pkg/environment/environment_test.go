@@ -0,0 +1,157 @@
+package environment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestEnvironment_Defaults(t *testing.T) {
+	env := &Environment{ObjectMeta: metav1.ObjectMeta{Name: "pr-42"}, Spec: Spec{Source: "shop"}}
+	assert.Equal(t, "shop-pr-42", env.TargetNamespace())
+	assert.Equal(t, "pr-42", env.NameSuffix())
+	require.NoError(t, env.Validate())
+
+	env.Spec.Namespace, env.Spec.Suffix = "preview-42", "p42"
+	assert.Equal(t, "preview-42", env.TargetNamespace())
+	assert.Equal(t, "p42", env.NameSuffix())
+}
+
+func TestEnvironment_Validate(t *testing.T) {
+	for name, spec := range map[string]Spec{
+		"missing source": {},
+		"same namespace": {Source: "shop", Namespace: "shop"},
+		"bad namespace":  {Source: "shop", Namespace: "Shop_PR"},
+		"bad suffix":     {Source: "shop", Suffix: "-x"},
+		"bad ttl":        {Source: "shop", TTL: "two days"},
+		"negative ttl":   {Source: "shop", TTL: "-1h"},
+	} {
+		env := &Environment{ObjectMeta: metav1.ObjectMeta{Name: "pr-42"}, Spec: spec}
+		assert.Error(t, env.Validate(), name)
+	}
+	env := &Environment{ObjectMeta: metav1.ObjectMeta{Name: "PR 42"}, Spec: Spec{Source: "shop"}}
+	assert.Error(t, env.Validate())
+}
+
+func TestEnvironment_Lifetime(t *testing.T) {
+	env := &Environment{}
+	ttl, err := env.Lifetime(72*time.Hour, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 72*time.Hour, ttl)
+
+	env.Spec.TTL = "2h"
+	ttl, _ = env.Lifetime(72*time.Hour, 0)
+	assert.Equal(t, 2*time.Hour, ttl)
+
+	env.Spec.TTL = "720h"
+	ttl, _ = env.Lifetime(72*time.Hour, 336*time.Hour)
+	assert.Equal(t, 336*time.Hour, ttl, "capped at the maximum")
+
+	env.Spec.TTL = "soon"
+	_, err = env.Lifetime(time.Hour, 0)
+	assert.Error(t, err)
+}
+
+func TestEnvironment_Unstructured(t *testing.T) {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": Group + "/" + Version,
+		"kind":       Kind,
+		"metadata":   map[string]interface{}{"name": "pr-42"},
+		"spec": map[string]interface{}{
+			"source": "shop",
+			"images": map[string]interface{}{"web": "registry/web:pr-42"},
+			"ttl":    "48h",
+		},
+	}}
+	env, err := FromUnstructured(u)
+	require.NoError(t, err)
+	assert.Equal(t, "pr-42", env.Name)
+	assert.Equal(t, Spec{Source: "shop", Images: map[string]string{"web": "registry/web:pr-42"}, TTL: "48h"}, env.Spec)
+
+	env.Status.Phase = PhaseReady
+	back, err := env.ToUnstructured()
+	require.NoError(t, err)
+	assert.Equal(t, GVK, back.GroupVersionKind())
+	phase, _, _ := unstructured.NestedString(back.Object, "status", "phase")
+	assert.Equal(t, PhaseReady, phase)
+}
+
+func TestCloner(t *testing.T) {
+	env := &Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "pr-42"},
+		Spec:       Spec{Source: "shop", Images: map[string]string{"web": "registry/web:pr-42"}},
+	}
+	c := NewCloner(env)
+	c.ConfigMaps["web-config"] = true
+	c.Secrets["web-tls"] = true
+	c.Services["db"] = true
+
+	svc := c.Service(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "shop", UID: "u1", ResourceVersion: "7",
+			Labels:      map[string]string{"app": "web"},
+			Annotations: map[string]string{lastAppliedAnnotation: "{}", "team": "checkout"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:       corev1.ServiceTypeLoadBalancer,
+			ClusterIP:  "10.0.0.12",
+			ClusterIPs: []string{"10.0.0.12"},
+			Selector:   map[string]string{"app": "web"},
+			Ports:      []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 31080}},
+		},
+	})
+	assert.Equal(t, metav1.ObjectMeta{
+		Name: "web-pr-42", Namespace: "shop-pr-42",
+		Labels:      map[string]string{"app": "web", NameLabel: "pr-42"},
+		Annotations: map[string]string{"team": "checkout"},
+	}, svc.ObjectMeta)
+	assert.Equal(t, corev1.ServiceTypeClusterIP, svc.Spec.Type)
+	assert.Empty(t, svc.Spec.ClusterIP)
+	assert.Zero(t, svc.Spec.Ports[0].NodePort)
+	assert.Equal(t, map[string]string{"app": "web"}, svc.Spec.Selector, "pods keep their labels, so selectors are unchanged")
+
+	headless := c.Service(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "db"}, Spec: corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone}})
+	assert.Equal(t, corev1.ClusterIPNone, headless.Spec.ClusterIP)
+
+	src := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}}}},
+				{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "web-tls"}}},
+			},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+			Containers: []corev1.Container{
+				{
+					Name:    "web",
+					Image:   "registry/web:1.0",
+					EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}}}},
+					Env: []corev1.EnvVar{{Name: "KEY", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "web-tls"}, Key: "key",
+					}}}},
+				},
+				{Name: "proxy", Image: "envoy:1.30"},
+			},
+		}}},
+	}
+	deploy := c.Deployment(src)
+	spec := deploy.Spec.Template.Spec
+	assert.Equal(t, "web-pr-42", deploy.Name)
+	assert.Equal(t, "web-config-pr-42", spec.Volumes[0].ConfigMap.Name)
+	assert.Equal(t, "web-tls-pr-42", spec.Volumes[1].Secret.SecretName)
+	assert.Equal(t, "registry", spec.ImagePullSecrets[0].Name, "secrets that were not cloned keep their names")
+	assert.Equal(t, "registry/web:pr-42", spec.Containers[0].Image)
+	assert.Equal(t, "envoy:1.30", spec.Containers[1].Image)
+	assert.Equal(t, "web-config-pr-42", spec.Containers[0].EnvFrom[0].ConfigMapRef.Name)
+	assert.Equal(t, "web-tls-pr-42", spec.Containers[0].Env[0].ValueFrom.SecretKeyRef.Name)
+	assert.Equal(t, "registry/web:1.0", src.Spec.Template.Spec.Containers[0].Image, "the source is not modified")
+
+	sts := c.StatefulSet(&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db"}, Spec: appsv1.StatefulSetSpec{ServiceName: "db"}})
+	assert.Equal(t, "db-pr-42", sts.Spec.ServiceName)
+}
@@ -0,0 +1,508 @@
+// Package exposure finds services and pods reachable through node or load balancer
+// addresses and flags public exposure that no policy expects
+package exposure
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Kinds of exposure
+const (
+	KindNodePort     = "NodePort"     // Service opening a port on every node
+	KindLoadBalancer = "LoadBalancer" // Service with a cloud load balancer
+	KindHostNetwork  = "HostNetwork"  // Pods sharing the node's network namespace
+	KindHostPort     = "HostPort"     // Pods mapping container ports to node ports
+)
+
+// Kinds lists every kind of exposure
+var Kinds = []string{KindNodePort, KindLoadBalancer, KindHostNetwork, KindHostPort}
+
+// Reasons of port conflicts
+const (
+	ConflictHostPort = "host_port" // Workloads on the same node bind the same host port
+	ConflictNodePort = "node_port" // A host port is also a service's node port, which kube-proxy claims on every node
+)
+
+// internalAnnotations mark load balancers of common cloud providers as internal
+var internalAnnotations = map[string]string{
+	"service.beta.kubernetes.io/aws-load-balancer-internal":              "true",
+	"service.beta.kubernetes.io/aws-load-balancer-scheme":                "internal",
+	"service.beta.kubernetes.io/azure-load-balancer-internal":            "true",
+	"networking.gke.io/load-balancer-type":                               "Internal",
+	"cloud.google.com/load-balancer-type":                                "Internal",
+	"service.beta.kubernetes.io/oci-load-balancer-internal":              "true",
+	"service.beta.kubernetes.io/openstack-internal-load-balancer":        "true",
+	"service.beta.kubernetes.io/alibaba-cloud-loadbalancer-address-type": "intranet",
+}
+
+// Rule allows matching exposure. Empty fields match everything.
+type Rule struct {
+	Name       string   `mapstructure:"name"`       // Shown on the exposure the rule allows
+	Clusters   []string `mapstructure:"clusters"`   // Cluster ID patterns
+	Namespaces []string `mapstructure:"namespaces"` // Namespace patterns, e.g. ingress-*
+	Kinds      []string `mapstructure:"kinds"`      // NodePort, LoadBalancer, HostNetwork or HostPort
+	Resources  []string `mapstructure:"resources"`  // Service or workload name patterns
+	Ports      []int32  `mapstructure:"ports"`      // Allowed only when every exposed port is listed
+}
+
+// Config holds exposure policies
+type Config struct {
+	Allow               []Rule            `mapstructure:"allow"`                // Public exposure expected by policy
+	InternalAnnotations map[string]string `mapstructure:"internal_annotations"` // More annotations marking load balancers internal
+}
+
+// Port is a port reachable from outside the pod network
+type Port struct {
+	Name     string `json:"name,omitempty"`
+	Protocol string `json:"protocol"`
+	Port     int32  `json:"port"`                // Service port, or container port of pods
+	NodePort int32  `json:"node_port,omitempty"` // Port opened on every node by a service
+	HostPort int32  `json:"host_port,omitempty"` // Port bound on the pod's node
+}
+
+// Exposure is a service or workload reachable through node or load balancer addresses
+type Exposure struct {
+	Cluster    string   `json:"cluster"`
+	Kind       string   `json:"kind"`
+	Namespace  string   `json:"namespace"`
+	Resource   string   `json:"resource"` // Service name, or kind/name of the pods' workload
+	Ports      []Port   `json:"ports"`
+	Nodes      []string `json:"nodes,omitempty"`     // Nodes running the pods
+	Addresses  []string `json:"addresses,omitempty"` // Load balancer ingress or public node addresses
+	Public     bool     `json:"public"`
+	Reason     string   `json:"reason"`               // Why the exposure is considered public or internal
+	AllowedBy  string   `json:"allowed_by,omitempty"` // Rule expecting the exposure
+	Unexpected bool     `json:"unexpected"`           // Public and not allowed by any rule
+	Conflicts  int      `json:"conflicts,omitempty"`  // Port conflicts involving the exposure
+}
+
+// Conflict is a node port bound by more than one owner
+type Conflict struct {
+	Cluster   string   `json:"cluster"`
+	Reason    string   `json:"reason"`
+	Protocol  string   `json:"protocol"`
+	Port      int32    `json:"port"`
+	Node      string   `json:"node,omitempty"` // Node of a host port conflict
+	Resources []string `json:"resources"`      // namespace/resource of each owner
+}
+
+// Cluster is the state of one cluster to analyze
+type Cluster struct {
+	ID       string
+	Nodes    []corev1.Node
+	Pods     []corev1.Pod
+	Services []corev1.Service
+}
+
+// Policy classifies exposure according to the configured rules
+type Policy struct {
+	rules    []Rule
+	internal map[string]string
+}
+
+// New validates the rules
+func New(cfg Config) (*Policy, error) {
+	rules := make([]Rule, len(cfg.Allow))
+	for i, rule := range cfg.Allow {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("#%d", i+1)
+		}
+		rules[i] = rule
+		for _, kind := range rule.Kinds {
+			if !slices.Contains(Kinds, kind) {
+				return nil, fmt.Errorf("rule %s: unknown kind %q, expected one of %s", rule.Name, kind, strings.Join(Kinds, ", "))
+			}
+		}
+		for _, patterns := range [][]string{rule.Clusters, rule.Namespaces, rule.Resources} {
+			for _, p := range patterns {
+				if _, err := path.Match(p, ""); err != nil {
+					return nil, fmt.Errorf("rule %s: pattern %q: %w", rule.Name, p, err)
+				}
+			}
+		}
+	}
+
+	internal := make(map[string]string, len(internalAnnotations)+len(cfg.InternalAnnotations))
+	for k, v := range internalAnnotations {
+		internal[k] = v
+	}
+	for k, v := range cfg.InternalAnnotations {
+		internal[k] = v
+	}
+	return &Policy{rules: rules, internal: internal}, nil
+}
+
+// Analyze returns the exposure and port conflicts of a cluster, sorted by namespace, kind
+// and resource
+func (p *Policy) Analyze(c Cluster) ([]Exposure, []Conflict) {
+	publicNodes := make(map[string][]string)
+	for i := range c.Nodes {
+		if addrs := publicAddresses(&c.Nodes[i]); len(addrs) > 0 {
+			publicNodes[c.Nodes[i].Name] = addrs
+		}
+	}
+
+	var exposures []Exposure
+	nodePorts := make(map[string][]string) // protocol/port to services opening it
+	for i := range c.Services {
+		e, ok := p.service(c.ID, &c.Services[i], publicNodes)
+		if !ok {
+			continue
+		}
+		for _, port := range e.Ports {
+			if port.NodePort > 0 {
+				key := portKey(port.Protocol, port.NodePort)
+				nodePorts[key] = append(nodePorts[key], e.Namespace+"/"+e.Resource)
+			}
+		}
+		exposures = append(exposures, e)
+	}
+
+	workloads, uses := hostExposure(c, publicNodes)
+	exposures = append(exposures, workloads...)
+
+	conflicts := portConflicts(c.ID, uses, nodePorts)
+	involved := make(map[string]int)
+	for _, conflict := range conflicts {
+		for _, r := range conflict.Resources {
+			involved[r]++
+		}
+	}
+
+	for i := range exposures {
+		e := &exposures[i]
+		e.Conflicts = involved[e.Namespace+"/"+e.Resource]
+		e.AllowedBy = p.allowedBy(e)
+		e.Unexpected = e.Public && e.AllowedBy == ""
+	}
+	sort.Slice(exposures, func(i, j int) bool {
+		a, b := exposures[i], exposures[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Resource < b.Resource
+	})
+	return exposures, conflicts
+}
+
+// service describes a NodePort or LoadBalancer service
+func (p *Policy) service(clusterID string, svc *corev1.Service, publicNodes map[string][]string) (Exposure, bool) {
+	if svc.Spec.Type != corev1.ServiceTypeNodePort && svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return Exposure{}, false
+	}
+	e := Exposure{
+		Cluster:   clusterID,
+		Kind:      string(svc.Spec.Type),
+		Namespace: svc.Namespace,
+		Resource:  svc.Name,
+		Ports:     make([]Port, 0, len(svc.Spec.Ports)),
+	}
+	for _, port := range svc.Spec.Ports {
+		e.Ports = append(e.Ports, Port{Name: port.Name, Protocol: protocol(port.Protocol), Port: port.Port, NodePort: port.NodePort})
+	}
+
+	if e.Kind == KindNodePort {
+		e.Addresses = nodeAddresses(publicNodes)
+		e.Public = len(e.Addresses) > 0
+		e.Reason = "no node has a public address"
+		if e.Public {
+			e.Reason = "node ports are open on nodes with public addresses"
+		}
+		return e, true
+	}
+
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		switch {
+		case ingress.IP != "":
+			e.Addresses = append(e.Addresses, ingress.IP)
+		case ingress.Hostname != "":
+			e.Addresses = append(e.Addresses, ingress.Hostname)
+		}
+	}
+	e.Public, e.Reason = p.loadBalancerPublic(svc)
+	return e, true
+}
+
+// loadBalancerPublic reports whether a load balancer accepts traffic from the internet
+func (p *Policy) loadBalancerPublic(svc *corev1.Service) (bool, string) {
+	for k, v := range p.internal {
+		if strings.EqualFold(svc.Annotations[k], v) {
+			return false, fmt.Sprintf("internal load balancer (%s=%s)", k, v)
+		}
+	}
+	if ranges := svc.Spec.LoadBalancerSourceRanges; len(ranges) > 0 {
+		for _, r := range ranges {
+			_, cidr, err := net.ParseCIDR(strings.TrimSpace(r))
+			if err != nil || !cidr.IP.IsPrivate() && !cidr.IP.IsLoopback() {
+				return true, "source ranges include public addresses"
+			}
+		}
+		return false, "source ranges are private"
+	}
+
+	ingress := svc.Status.LoadBalancer.Ingress
+	if len(ingress) == 0 {
+		return true, "load balancer is not provisioned yet and accepts any source"
+	}
+	for _, in := range ingress {
+		ip := net.ParseIP(in.IP)
+		if ip == nil || !ip.IsPrivate() {
+			return true, "load balancer accepts any source"
+		}
+	}
+	return false, "load balancer addresses are private"
+}
+
+// hostPortUse is a host port bound by a workload on a node
+type hostPortUse struct {
+	node     string
+	key      string // protocol/port
+	resource string // namespace/kind/name
+}
+
+// hostExposure groups hostNetwork and hostPort pods by workload
+func hostExposure(c Cluster, publicNodes map[string][]string) ([]Exposure, []hostPortUse) {
+	byWorkload := make(map[string]*Exposure)
+	var order []string
+	var uses []hostPortUse
+	for i := range c.Pods {
+		pod := &c.Pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		ports := hostPorts(pod)
+		if !pod.Spec.HostNetwork && len(ports) == 0 {
+			continue
+		}
+
+		kind := KindHostPort
+		if pod.Spec.HostNetwork {
+			kind = KindHostNetwork
+		}
+		resource := workload(pod)
+		key := kind + "/" + pod.Namespace + "/" + resource
+		e, ok := byWorkload[key]
+		if !ok {
+			e = &Exposure{Cluster: c.ID, Kind: kind, Namespace: pod.Namespace, Resource: resource, Ports: ports}
+			byWorkload[key] = e
+			order = append(order, key)
+		}
+		if node := pod.Spec.NodeName; node != "" {
+			if !slices.Contains(e.Nodes, node) {
+				e.Nodes = append(e.Nodes, node)
+			}
+			for _, port := range ports {
+				uses = append(uses, hostPortUse{node: node, key: portKey(port.Protocol, port.HostPort), resource: pod.Namespace + "/" + resource})
+			}
+		}
+	}
+
+	exposures := make([]Exposure, 0, len(order))
+	for _, key := range order {
+		e := byWorkload[key]
+		sort.Strings(e.Nodes)
+		for _, node := range e.Nodes {
+			e.Addresses = append(e.Addresses, publicNodes[node]...)
+		}
+		e.Public = len(e.Addresses) > 0
+		switch {
+		case e.Public:
+			e.Reason = "pods run on nodes with public addresses"
+		case len(e.Nodes) == 0:
+			e.Reason = "pods are not scheduled"
+		default:
+			e.Reason = "pods run on nodes without public addresses"
+		}
+		exposures = append(exposures, *e)
+	}
+	return exposures, uses
+}
+
+// hostPorts returns the ports a pod binds on its node. With hostNetwork every container port
+// is a host port.
+func hostPorts(pod *corev1.Pod) []Port {
+	var ports []Port
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, c := range containers {
+		for _, port := range c.Ports {
+			hostPort := port.HostPort
+			if pod.Spec.HostNetwork && hostPort == 0 {
+				hostPort = port.ContainerPort
+			}
+			if hostPort == 0 {
+				continue
+			}
+			ports = append(ports, Port{Name: port.Name, Protocol: protocol(port.Protocol), Port: port.ContainerPort, HostPort: hostPort})
+		}
+	}
+	return ports
+}
+
+// portConflicts finds host ports bound by several workloads on a node and host ports that
+// are also node ports
+func portConflicts(clusterID string, uses []hostPortUse, nodePorts map[string][]string) []Conflict {
+	byNode := make(map[string][]string) // node and protocol/port to workloads
+	for _, u := range uses {
+		key := u.node + " " + u.key
+		if !slices.Contains(byNode[key], u.resource) {
+			byNode[key] = append(byNode[key], u.resource)
+		}
+	}
+
+	var conflicts []Conflict
+	for key, resources := range byNode {
+		if len(resources) < 2 {
+			continue
+		}
+		node, port, _ := strings.Cut(key, " ")
+		sort.Strings(resources)
+		c := Conflict{Cluster: clusterID, Reason: ConflictHostPort, Node: node, Resources: resources}
+		c.Protocol, c.Port = splitPortKey(port)
+		conflicts = append(conflicts, c)
+	}
+
+	hostUsers := make(map[string][]string) // protocol/port to workloads on any node
+	for _, u := range uses {
+		if !slices.Contains(hostUsers[u.key], u.resource) {
+			hostUsers[u.key] = append(hostUsers[u.key], u.resource)
+		}
+	}
+	for key, services := range nodePorts {
+		workloads, ok := hostUsers[key]
+		if !ok {
+			continue
+		}
+		resources := append(append([]string{}, services...), workloads...)
+		sort.Strings(resources)
+		c := Conflict{Cluster: clusterID, Reason: ConflictNodePort, Resources: resources}
+		c.Protocol, c.Port = splitPortKey(key)
+		conflicts = append(conflicts, c)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		a, b := conflicts[i], conflicts[j]
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		if a.Reason != b.Reason {
+			return a.Reason < b.Reason
+		}
+		return a.Node < b.Node
+	})
+	return conflicts
+}
+
+// allowedBy returns the name of the first rule matching the exposure
+func (p *Policy) allowedBy(e *Exposure) string {
+	for _, rule := range p.rules {
+		if matches(rule.Clusters, e.Cluster) &&
+			matches(rule.Namespaces, e.Namespace) &&
+			(len(rule.Kinds) == 0 || slices.Contains(rule.Kinds, e.Kind)) &&
+			matches(rule.Resources, e.Resource) &&
+			portsAllowed(rule.Ports, e.Ports) {
+			return rule.Name
+		}
+	}
+	return ""
+}
+
+// portsAllowed reports whether every exposed port is listed. Service ports match by port or
+// node port, pod ports by host port.
+func portsAllowed(allowed []int32, ports []Port) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, port := range ports {
+		switch {
+		case port.HostPort > 0:
+			if !slices.Contains(allowed, port.HostPort) {
+				return false
+			}
+		case !slices.Contains(allowed, port.Port) && (port.NodePort == 0 || !slices.Contains(allowed, port.NodePort)):
+			return false
+		}
+	}
+	return true
+}
+
+// publicAddresses returns the node's external addresses that are not private
+func publicAddresses(node *corev1.Node) []string {
+	var addrs []string
+	for _, a := range node.Status.Addresses {
+		if a.Type != corev1.NodeExternalIP {
+			continue
+		}
+		if ip := net.ParseIP(a.Address); ip != nil && !ip.IsPrivate() && !ip.IsLoopback() {
+			addrs = append(addrs, a.Address)
+		}
+	}
+	return addrs
+}
+
+// nodeAddresses returns the public addresses of every node, sorted
+func nodeAddresses(publicNodes map[string][]string) []string {
+	var addrs []string
+	for _, a := range publicNodes {
+		addrs = append(addrs, a...)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// workload names the controller of a pod as kind/name, resolving ReplicaSets of
+// Deployments through the pod-template-hash label
+func workload(pod *corev1.Pod) string {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ref.Kind == "ReplicaSet" && hash != "" {
+			if name, ok := strings.CutSuffix(ref.Name, "-"+hash); ok {
+				return "Deployment/" + name
+			}
+		}
+		return ref.Kind + "/" + ref.Name
+	}
+	return "Pod/" + pod.Name
+}
+
+func protocol(p corev1.Protocol) string {
+	if p == "" {
+		return string(corev1.ProtocolTCP)
+	}
+	return string(p)
+}
+
+func portKey(protocol string, port int32) string {
+	return fmt.Sprintf("%s/%d", protocol, port)
+}
+
+func splitPortKey(key string) (string, int32) {
+	protocol, port, _ := strings.Cut(key, "/")
+	var n int32
+	fmt.Sscanf(port, "%d", &n)
+	return protocol, n
+}
+
+func matches(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if matched, _ := path.Match(p, value); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package exposure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func node(name string, addresses ...corev1.NodeAddress) corev1.Node {
+	return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: corev1.NodeStatus{Addresses: addresses}}
+}
+
+func service(namespace, name string, typ corev1.ServiceType, port, nodePort int32) corev1.Service {
+	return corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: corev1.ServiceSpec{
+			Type:  typ,
+			Ports: []corev1.ServicePort{{Port: port, NodePort: nodePort}},
+		},
+	}
+}
+
+func hostPod(namespace, name, nodeName string, hostNetwork bool, ports ...corev1.ContainerPort) corev1.Pod {
+	controller := true
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{"pod-template-hash": "7d4b9"},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ReplicaSet", Name: "agent-7d4b9", Controller: &controller},
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:    nodeName,
+			HostNetwork: hostNetwork,
+			Containers:  []corev1.Container{{Name: "main", Ports: ports}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func find(t *testing.T, exposures []Exposure, kind, resource string) Exposure {
+	t.Helper()
+	for _, e := range exposures {
+		if e.Kind == kind && e.Resource == resource {
+			return e
+		}
+	}
+	t.Fatalf("no %s exposure of %s", kind, resource)
+	return Exposure{}
+}
+
+func TestAnalyze_Services(t *testing.T) {
+	p, err := New(Config{Allow: []Rule{{Name: "ingress", Namespaces: []string{"ingress-*"}, Kinds: []string{KindLoadBalancer}, Ports: []int32{80, 443}}}})
+	require.NoError(t, err)
+
+	internal := service("shop", "internal", corev1.ServiceTypeLoadBalancer, 80, 30080)
+	internal.Annotations = map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"}
+	restricted := service("shop", "restricted", corev1.ServiceTypeLoadBalancer, 80, 30081)
+	restricted.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+	ingress := service("ingress-nginx", "controller", corev1.ServiceTypeLoadBalancer, 443, 30443)
+	ingress.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "203.0.113.10"}}
+	admin := service("shop", "admin", corev1.ServiceTypeLoadBalancer, 8443, 30843)
+
+	exposures, conflicts := p.Analyze(Cluster{
+		ID:    "prod",
+		Nodes: []corev1.Node{node("a", corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.1"})},
+		Services: []corev1.Service{
+			internal, restricted, ingress, admin,
+			service("shop", "web", corev1.ServiceTypeNodePort, 80, 30000),
+			service("shop", "db", corev1.ServiceTypeClusterIP, 5432, 0),
+		},
+	})
+	assert.Empty(t, conflicts)
+	require.Len(t, exposures, 5)
+
+	e := find(t, exposures, KindLoadBalancer, "internal")
+	assert.False(t, e.Public)
+	assert.Contains(t, e.Reason, "internal load balancer")
+	assert.False(t, find(t, exposures, KindLoadBalancer, "restricted").Public)
+
+	e = find(t, exposures, KindLoadBalancer, "controller")
+	assert.True(t, e.Public)
+	assert.Equal(t, "ingress", e.AllowedBy)
+	assert.False(t, e.Unexpected)
+	assert.Equal(t, []string{"203.0.113.10"}, e.Addresses)
+
+	e = find(t, exposures, KindLoadBalancer, "admin")
+	assert.True(t, e.Public)
+	assert.True(t, e.Unexpected)
+
+	e = find(t, exposures, KindNodePort, "web")
+	assert.False(t, e.Public, "nodes have no public address")
+	assert.False(t, e.Unexpected)
+}
+
+func TestAnalyze_HostPortsAndConflicts(t *testing.T) {
+	p, err := New(Config{})
+	require.NoError(t, err)
+
+	public := node("edge", corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "198.51.100.7"})
+	exposures, conflicts := p.Analyze(Cluster{
+		ID:    "prod",
+		Nodes: []corev1.Node{public, node("inner")},
+		Pods: []corev1.Pod{
+			hostPod("monitoring", "agent-7d4b9-a", "edge", true, corev1.ContainerPort{ContainerPort: 9100}),
+			hostPod("monitoring", "agent-7d4b9-b", "inner", true, corev1.ContainerPort{ContainerPort: 9100}),
+			hostPod("shop", "proxy", "edge", false, corev1.ContainerPort{ContainerPort: 8080, HostPort: 9100}),
+			hostPod("shop", "relay", "inner", false, corev1.ContainerPort{ContainerPort: 8080, HostPort: 30000}),
+			hostPod("shop", "plain", "inner", false, corev1.ContainerPort{ContainerPort: 8080}),
+		},
+		Services: []corev1.Service{service("shop", "web", corev1.ServiceTypeNodePort, 80, 30000)},
+	})
+
+	agent := find(t, exposures, KindHostNetwork, "Deployment/agent")
+	assert.Equal(t, []string{"edge", "inner"}, agent.Nodes)
+	assert.True(t, agent.Public)
+	assert.True(t, agent.Unexpected)
+	assert.Equal(t, int32(9100), agent.Ports[0].HostPort)
+	assert.Equal(t, 1, agent.Conflicts)
+
+	web := find(t, exposures, KindNodePort, "web")
+	assert.True(t, web.Public, "node ports are open on every node")
+	assert.Equal(t, []string{"198.51.100.7"}, web.Addresses)
+
+	require.Len(t, conflicts, 2)
+	assert.Equal(t, Conflict{Cluster: "prod", Reason: ConflictHostPort, Protocol: "TCP", Port: 9100, Node: "edge",
+		Resources: []string{"monitoring/Deployment/agent", "shop/Deployment/agent"}}, conflicts[0])
+	assert.Equal(t, ConflictNodePort, conflicts[1].Reason)
+	assert.Equal(t, int32(30000), conflicts[1].Port)
+	assert.Equal(t, []string{"shop/Deployment/agent", "shop/web"}, conflicts[1].Resources)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(Config{Allow: []Rule{{Kinds: []string{"ClusterIP"}}}})
+	assert.Error(t, err)
+	_, err = New(Config{Allow: []Rule{{Namespaces: []string{"["}}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,315 @@
+// Package fixture records Kubernetes API responses into sanitized JSON fixtures and replays
+// them through a real clientset, so tests exercise the API server's handlers against the
+// responses of an actual cluster without needing one.
+package fixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/redact"
+)
+
+// lastAppliedAnnotation holds a copy of the applied manifest, Secret data included
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Fixture is one recorded API response, stored as a JSON file
+type Fixture struct {
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Query       string          `json:"query,omitempty"` // Sorted query string; empty matches any query
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"` // JSON responses
+	Text        string          `json:"text,omitempty"` // Other responses, such as pod logs
+}
+
+// FileName returns the name a fixture is stored under, derived from the request it answers
+func (f *Fixture) FileName() string {
+	name := f.Method + strings.ReplaceAll(f.Path, "/", "_")
+	if f.Query != "" {
+		sum := sha256.Sum256([]byte(f.Query))
+		name += "__" + hex.EncodeToString(sum[:4])
+	}
+	return unsafeFileChars.ReplaceAllString(name, "-") + ".json"
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// normalizeQuery sorts the query parameters so equal requests map to the same fixture
+func normalizeQuery(raw string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return raw
+	}
+	return values.Encode()
+}
+
+// Record returns a rest.Config WrapTransport function saving every API response into dir.
+// Responses are sanitized before they are written: Secret data, sensitive environment
+// variables, managed fields and last-applied annotations are masked or dropped. Watches and
+// followed log streams are passed through unrecorded.
+func Record(dir string) func(http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &recorder{next: rt, dir: dir, redactor: redact.New(redact.Config{Enabled: true})}
+	}
+}
+
+type recorder struct {
+	next     http.RoundTripper
+	dir      string
+	redactor *redact.Redactor
+	mu       sync.Mutex
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil || streaming(req) {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	f := &Fixture{
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Query:       normalizeQuery(req.URL.RawQuery),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if sanitized, ok := r.sanitize(body); ok {
+		f.Body = sanitized
+	} else {
+		f.Text = string(body)
+	}
+	if err := r.write(f); err != nil {
+		log.Warn().Err(err).Str("path", req.URL.Path).Msg("Failed to record fixture")
+	}
+	return resp, nil
+}
+
+// streaming reports whether a request keeps its response open
+func streaming(req *http.Request) bool {
+	q := req.URL.Query()
+	return q.Get("watch") == "true" || q.Get("follow") == "true" || req.Header.Get("Upgrade") != ""
+}
+
+func (r *recorder) write(f *Fixture) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return err
+	}
+	// Write through a temporary file so a replay never reads a partial fixture
+	path := filepath.Join(r.dir, f.FileName())
+	if err := os.WriteFile(path+".tmp", append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// sanitize masks credentials in a JSON response body. It returns false for bodies that are
+// not JSON.
+func (r *recorder) sanitize(body []byte) (json.RawMessage, bool) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if len(bytes.TrimSpace(body)) == 0 || decoder.Decode(&value) != nil {
+		return nil, false
+	}
+	r.sanitizeValue(value)
+	out, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// redactedData is stored in place of Secret values. It is valid base64, so the recorded
+// Secrets still decode.
+var redactedData = base64.StdEncoding.EncodeToString([]byte(redact.DefaultReplacement))
+
+func (r *recorder) sanitizeValue(value interface{}) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			r.sanitizeValue(item)
+		}
+	case map[string]interface{}:
+		switch v["kind"] {
+		case "Secret":
+			maskSecret(v)
+		case "SecretList":
+			// List items carry no kind of their own
+			items, _ := v["items"].([]interface{})
+			for _, item := range items {
+				if secret, ok := item.(map[string]interface{}); ok {
+					maskSecret(secret)
+				}
+			}
+		}
+		if metadata, ok := v["metadata"].(map[string]interface{}); ok {
+			delete(metadata, "managedFields")
+			if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+				delete(annotations, lastAppliedAnnotation)
+			}
+		}
+		// Environment variables such as DB_PASSWORD carry their value inline
+		if name, ok := v["name"].(string); ok && r.redactor.Sensitive(name) {
+			if _, ok := v["value"].(string); ok {
+				v["value"] = redact.DefaultReplacement
+			}
+		}
+		for _, item := range v {
+			r.sanitizeValue(item)
+		}
+	}
+}
+
+func maskSecret(secret map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		if data, ok := secret[field].(map[string]interface{}); ok {
+			for key := range data {
+				data[key] = redactedData
+			}
+		}
+	}
+}
+
+// Replayer is an http.RoundTripper answering API requests from recorded fixtures. Requests
+// without a fixture get a 404 Status, as the API server answers for missing objects.
+type Replayer struct {
+	fixtures map[string]*Fixture
+
+	mu     sync.Mutex
+	missed []string
+}
+
+// Load reads every fixture in dir
+func Load(dir string) (*Replayer, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	r := &Replayer{fixtures: make(map[string]*Fixture, len(paths))}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var f Fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if f.Method == "" || f.Path == "" || f.Status == 0 {
+			return nil, fmt.Errorf("%s: method, path and status are required", path)
+		}
+		f.Query = normalizeQuery(f.Query)
+		r.fixtures[key(f.Method, f.Path, f.Query)] = &f
+	}
+	return r, nil
+}
+
+func key(method, path, query string) string {
+	return method + " " + path + "?" + query
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	query := normalizeQuery(req.URL.RawQuery)
+	f, ok := r.fixtures[key(req.Method, req.URL.Path, query)]
+	if !ok {
+		f, ok = r.fixtures[key(req.Method, req.URL.Path, "")]
+	}
+	if !ok {
+		r.mu.Lock()
+		r.missed = append(r.missed, key(req.Method, req.URL.Path, query))
+		r.mu.Unlock()
+		f = notFound(req)
+	}
+
+	body := []byte(f.Body)
+	if f.Body == nil {
+		body = []byte(f.Text)
+	}
+	header := http.Header{}
+	if f.ContentType != "" {
+		header.Set("Content-Type", f.ContentType)
+	} else if f.Body != nil {
+		header.Set("Content-Type", "application/json")
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Missed returns the requests that had no fixture, e.g. to report what a test still needs
+// to record
+func (r *Replayer) Missed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.missed...)
+}
+
+// notFound is the Status the API server answers with for a missing object
+func notFound(req *http.Request) *Fixture {
+	body, _ := json.Marshal(map[string]interface{}{
+		"kind":       "Status",
+		"apiVersion": "v1",
+		"status":     "Failure",
+		"message":    fmt.Sprintf("no fixture for %s %s", req.Method, req.URL.RequestURI()),
+		"reason":     "NotFound",
+		"code":       http.StatusNotFound,
+	})
+	return &Fixture{Status: http.StatusNotFound, Body: body}
+}
+
+// NewClientset returns a clientset answering every request from the fixtures in dir
+func NewClientset(dir string) (*kubernetes.Clientset, *Replayer, error) {
+	replayer, err := Load(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: "http://fixtures.invalid", Transport: replayer})
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientset, replayer, nil
+}
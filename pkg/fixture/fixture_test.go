@@ -0,0 +1,115 @@
+package fixture
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// cluster answers like an API server holding one Secret and one Pod
+func cluster(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/namespaces/prod/secrets":
+			w.Write([]byte(`{"kind":"SecretList","apiVersion":"v1","metadata":{},"items":[{"metadata":{"name":"db","namespace":"prod","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"data\":{\"password\":\"aHVudGVyMg==\"}}","team":"data"},"managedFields":[{"manager":"kubectl"}]},"data":{"password":"aHVudGVyMg=="},"type":"Opaque"}]}`))
+		case "/api/v1/namespaces/prod/pods/web":
+			w.Write([]byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"web","namespace":"prod"},"spec":{"containers":[{"name":"web","image":"nginx","env":[{"name":"API_TOKEN","value":"s3cr3t"},{"name":"LOG_LEVEL","value":"debug"}]}]}}`))
+		case "/api/v1/namespaces/prod/pods/web/log":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("started\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	config := &rest.Config{Host: cluster(t).URL}
+	config.Wrap(Record(dir))
+	recording, err := kubernetes.NewForConfig(config)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = recording.CoreV1().Secrets("prod").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	pod, err := recording.CoreV1().Pods("prod").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", pod.Spec.Containers[0].Env[0].Value, "the caller sees the real response")
+	_, err = recording.CoreV1().Pods("prod").GetLogs("web", &corev1.PodLogOptions{}).DoRaw(ctx)
+	require.NoError(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Len(t, files, 3)
+	for _, file := range files {
+		data, _ := os.ReadFile(file)
+		assert.NotContains(t, string(data), "aHVudGVyMg==", file)
+		assert.NotContains(t, string(data), "s3cr3t", file)
+		assert.NotContains(t, string(data), "managedFields", file)
+	}
+
+	replay, replayer, err := NewClientset(dir)
+	require.NoError(t, err)
+	secrets, err := replay.CoreV1().Secrets("prod").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 1)
+	assert.Equal(t, "[REDACTED]", string(secrets.Items[0].Data["password"]))
+	assert.Equal(t, map[string]string{"team": "data"}, secrets.Items[0].Annotations)
+
+	pod, err = replay.CoreV1().Pods("prod").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "[REDACTED]", pod.Spec.Containers[0].Env[0].Value)
+	assert.Equal(t, "debug", pod.Spec.Containers[0].Env[1].Value)
+
+	logs, err := replay.CoreV1().Pods("prod").GetLogs("web", &corev1.PodLogOptions{}).DoRaw(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "started\n", string(logs))
+	assert.Empty(t, replayer.Missed())
+
+	_, err = replay.CoreV1().Pods("prod").Get(ctx, "api", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.Equal(t, []string{"GET /api/v1/namespaces/prod/pods/api?"}, replayer.Missed())
+}
+
+func TestReplay_QueryMatching(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("any.json", `{"method":"GET","path":"/api/v1/namespaces","status":200,"body":{"kind":"NamespaceList","apiVersion":"v1","metadata":{},"items":[{"metadata":{"name":"default"}}]}}`)
+	write("selected.json", `{"method":"GET","path":"/api/v1/namespaces","query":"labelSelector=team%3Ddata&limit=10","status":200,"body":{"kind":"NamespaceList","apiVersion":"v1","metadata":{},"items":[{"metadata":{"name":"data"}}]}}`)
+
+	replay, _, err := NewClientset(dir)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	list, err := replay.CoreV1().Namespaces().List(ctx, metav1.ListOptions{Limit: 10, LabelSelector: "team=data"})
+	require.NoError(t, err)
+	assert.Equal(t, "data", list.Items[0].Name, "an exact query match wins")
+
+	list, err = replay.CoreV1().Namespaces().List(ctx, metav1.ListOptions{Limit: 50})
+	require.NoError(t, err)
+	assert.Equal(t, "default", list.Items[0].Name, "a fixture without a query answers any query")
+}
+
+func TestLoad_RejectsIncompleteFixtures(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"path":"/api/v1/pods"}`), 0o644))
+	_, err := Load(dir)
+	assert.ErrorContains(t, err, "method, path and status are required")
+}
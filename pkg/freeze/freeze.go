@@ -0,0 +1,199 @@
+// Package freeze implements change-freeze windows during which mutating
+// operations are refused
+package freeze
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"time"
+	_ "time/tzdata" // Window timezones must resolve in minimal container images
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/cron"
+)
+
+// Window is a period during which changes are refused. A window is either a
+// fixed Start/End range or a recurring Cron schedule that lasts Duration.
+type Window struct {
+	Name     string        `mapstructure:"name" json:"name"`
+	Reason   string        `mapstructure:"reason" json:"reason,omitempty"`
+	Clusters []string      `mapstructure:"clusters" json:"clusters,omitempty"` // Cluster IDs or glob patterns, empty matches all
+	Start    string        `mapstructure:"start" json:"start,omitempty"`       // RFC3339 start of a fixed window
+	End      string        `mapstructure:"end" json:"end,omitempty"`           // RFC3339 end of a fixed window
+	Cron     string        `mapstructure:"cron" json:"cron,omitempty"`         // Five-field cron expression opening a recurring window
+	Duration time.Duration `mapstructure:"duration" json:"duration,omitempty"` // Length of each recurring window
+	Timezone string        `mapstructure:"timezone" json:"timezone,omitempty"` // IANA zone for Cron, defaults to UTC
+}
+
+// MaxDuration caps the length of recurring windows
+const MaxDuration = 31 * 24 * time.Hour
+
+// Config holds the configured freeze windows
+type Config struct {
+	Enabled bool     `mapstructure:"enabled" json:"enabled"`
+	Windows []Window `mapstructure:"windows" json:"windows"`
+}
+
+// Error is returned when a change is refused because a freeze is in effect
+type Error struct {
+	Window    string
+	Reason    string
+	ClusterID string
+	Until     time.Time
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("change freeze %q is in effect for cluster %s until %s", e.Window, e.ClusterID, e.Until.UTC().Format(time.RFC3339))
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+type compiledWindow struct {
+	Window
+	start, end time.Time
+	schedule   *cron.Schedule
+	location   *time.Location
+}
+
+// Checker evaluates freeze windows for clusters
+type Checker struct {
+	enabled bool
+	windows []compiledWindow
+}
+
+// NewChecker validates the configuration and builds a Checker
+func NewChecker(cfg Config) (*Checker, error) {
+	c := &Checker{enabled: cfg.Enabled}
+	for i, w := range cfg.Windows {
+		if w.Name == "" {
+			w.Name = fmt.Sprintf("window-%d", i)
+		}
+		cw := compiledWindow{Window: w, location: time.UTC}
+
+		if w.Timezone != "" {
+			loc, err := time.LoadLocation(w.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("windows[%d]: invalid timezone: %w", i, err)
+			}
+			cw.location = loc
+		}
+
+		switch {
+		case w.Cron != "":
+			sched, err := cron.Parse(w.Cron)
+			if err != nil {
+				return nil, fmt.Errorf("windows[%d]: invalid cron: %w", i, err)
+			}
+			if w.Duration <= 0 {
+				return nil, fmt.Errorf("windows[%d]: cron windows require a positive duration", i)
+			}
+			if w.Duration > MaxDuration {
+				return nil, fmt.Errorf("windows[%d]: duration must not exceed %s, got %s", i, MaxDuration, w.Duration)
+			}
+			cw.schedule = sched
+		case w.Start != "" && w.End != "":
+			start, err := time.Parse(time.RFC3339, w.Start)
+			if err != nil {
+				return nil, fmt.Errorf("windows[%d]: invalid start: %w", i, err)
+			}
+			end, err := time.Parse(time.RFC3339, w.End)
+			if err != nil {
+				return nil, fmt.Errorf("windows[%d]: invalid end: %w", i, err)
+			}
+			if !end.After(start) {
+				return nil, fmt.Errorf("windows[%d]: end must be after start", i)
+			}
+			cw.start, cw.end = start, end
+		default:
+			return nil, fmt.Errorf("windows[%d]: either start and end or cron and duration are required", i)
+		}
+
+		c.windows = append(c.windows, cw)
+	}
+	return c, nil
+}
+
+// Check returns an *Error when a freeze window covers the cluster at the given time
+func (c *Checker) Check(clusterID string, now time.Time) error {
+	if c == nil || !c.enabled {
+		return nil
+	}
+	for _, w := range c.windows {
+		if !w.appliesTo(clusterID) {
+			continue
+		}
+		if until, ok := w.activeUntil(now); ok {
+			return &Error{Window: w.Name, Reason: w.Reason, ClusterID: clusterID, Until: until}
+		}
+	}
+	return nil
+}
+
+// Active returns the windows currently in effect for the cluster
+func (c *Checker) Active(clusterID string, now time.Time) []Window {
+	if c == nil || !c.enabled {
+		return nil
+	}
+	var active []Window
+	for _, w := range c.windows {
+		if _, ok := w.activeUntil(now); ok && w.appliesTo(clusterID) {
+			active = append(active, w.Window)
+		}
+	}
+	return active
+}
+
+func (w compiledWindow) appliesTo(clusterID string) bool {
+	if len(w.Clusters) == 0 {
+		return true
+	}
+	for _, pattern := range w.Clusters {
+		if matched, _ := path.Match(pattern, clusterID); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// activeUntil reports whether the window covers now and when it ends
+func (w compiledWindow) activeUntil(now time.Time) (time.Time, bool) {
+	if w.schedule == nil {
+		if !now.Before(w.start) && now.Before(w.end) {
+			return w.end, true
+		}
+		return time.Time{}, false
+	}
+
+	// The window is open when the schedule last fired less than Duration ago
+	if start, ok := w.schedule.Previous(now.In(w.location), now.Add(-w.Duration)); ok {
+		return start.Add(w.Duration), true
+	}
+	return time.Time{}, false
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultChecker *Checker
+)
+
+// SetDefault replaces the process-wide checker
+func SetDefault(c *Checker) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultChecker = c
+}
+
+// Default returns the process-wide checker, nil when freezes are not configured
+func Default() *Checker {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultChecker
+}
+
+// Check evaluates the process-wide checker for the cluster at the current time
+func Check(clusterID string) error {
+	return Default().Check(clusterID, time.Now())
+}
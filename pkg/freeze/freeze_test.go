@@ -0,0 +1,84 @@
+package freeze
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	require.NoError(t, err)
+	return parsed
+}
+
+func TestChecker_FixedWindow(t *testing.T) {
+	c, err := NewChecker(Config{
+		Enabled: true,
+		Windows: []Window{{
+			Name:     "year-end",
+			Reason:   "Year-end release freeze",
+			Clusters: []string{"prod-*"},
+			Start:    "2025-12-20T00:00:00Z",
+			End:      "2026-01-05T00:00:00Z",
+		}},
+	})
+	require.NoError(t, err)
+
+	err = c.Check("prod-eu", mustTime(t, "2025-12-24T12:00:00Z"))
+	var freezeErr *Error
+	require.True(t, errors.As(err, &freezeErr))
+	assert.Equal(t, "year-end", freezeErr.Window)
+	assert.Equal(t, mustTime(t, "2026-01-05T00:00:00Z"), freezeErr.Until)
+	assert.Contains(t, err.Error(), "Year-end release freeze")
+
+	assert.NoError(t, c.Check("staging", mustTime(t, "2025-12-24T12:00:00Z")), "other clusters are not frozen")
+	assert.NoError(t, c.Check("prod-eu", mustTime(t, "2026-01-05T00:00:00Z")), "end is exclusive")
+}
+
+func TestChecker_CronWindow(t *testing.T) {
+	// Every Friday from 18:00 for the weekend
+	c, err := NewChecker(Config{
+		Enabled: true,
+		Windows: []Window{{Name: "weekend", Cron: "0 18 * * 5", Duration: 62 * time.Hour}},
+	})
+	require.NoError(t, err)
+
+	// 2025-06-06 is a Friday
+	assert.NoError(t, c.Check("any", mustTime(t, "2025-06-06T17:59:00Z")))
+	assert.Error(t, c.Check("any", mustTime(t, "2025-06-06T18:00:00Z")))
+	assert.Error(t, c.Check("any", mustTime(t, "2025-06-08T23:00:00Z")))
+	assert.NoError(t, c.Check("any", mustTime(t, "2025-06-09T08:00:00Z")))
+
+	assert.Len(t, c.Active("any", mustTime(t, "2025-06-07T10:00:00Z")), 1)
+}
+
+func TestChecker_Disabled(t *testing.T) {
+	c, err := NewChecker(Config{Windows: []Window{{Cron: "* * * * *", Duration: time.Hour}}})
+	require.NoError(t, err)
+	assert.NoError(t, c.Check("any", time.Now()))
+
+	var nilChecker *Checker
+	assert.NoError(t, nilChecker.Check("any", time.Now()))
+}
+
+func TestNewChecker_Invalid(t *testing.T) {
+	tests := []Window{
+		{Name: "missing"},
+		{Cron: "0 18 * *", Duration: time.Hour},
+		{Cron: "0 25 * * *", Duration: time.Hour},
+		{Cron: "0 18 * * 5"},
+		{Start: "2025-01-02T00:00:00Z", End: "2025-01-01T00:00:00Z"},
+		{Start: "yesterday", End: "2025-01-01T00:00:00Z"},
+		{Cron: "0 18 * * 5", Duration: time.Hour, Timezone: "Nowhere/City"},
+		{Cron: "0 0 1 * *", Duration: MaxDuration + time.Minute},
+	}
+	for _, w := range tests {
+		_, err := NewChecker(Config{Enabled: true, Windows: []Window{w}})
+		assert.Error(t, err, "%+v", w)
+	}
+}
@@ -0,0 +1,381 @@
+// Package history keeps time series of deployment replicas and node readiness in an
+// embedded store. Samples are downsampled as they age and the series are persisted to a
+// file, so charts of the last weeks need no external monitoring.
+package history
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Kinds of series
+const (
+	KindDeployment = "deployment"
+	KindNode       = "node"
+)
+
+// Fields of the values recorded for each kind, in order
+var Fields = map[string][]string{
+	KindDeployment: {"desired", "ready", "available", "updated"},
+	KindNode:       {"ready", "unschedulable"}, // 1 or 0, the share of the time once downsampled
+}
+
+// Config holds history settings
+type Config struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Interval      time.Duration `mapstructure:"interval"`       // Time between samples
+	Retention     time.Duration `mapstructure:"retention"`      // Age after which hourly points are dropped, at least 7 days
+	Path          string        `mapstructure:"path"`           // File the series are persisted to, empty keeps them in memory only
+	FlushInterval time.Duration `mapstructure:"flush_interval"` // Time between writes of the file
+}
+
+// tier is a resolution of the stored points; points older than keep move to the next tier
+type tier struct {
+	step time.Duration // Zero for raw samples
+	keep time.Duration
+}
+
+// Key identifies a series
+type Key struct {
+	Kind      string
+	Cluster   string
+	Namespace string // Empty for nodes
+	Name      string
+}
+
+// Sample is the current values of one series
+type Sample struct {
+	Key    Key
+	Values []float64 // In the order of Fields[Key.Kind]
+}
+
+// Collector returns the current samples of every cluster
+type Collector func(ctx context.Context) ([]Sample, error)
+
+// Point is the values of a series at a time, averaged over the step once downsampled
+type Point struct {
+	Time   time.Time
+	Values []float64
+}
+
+// Result is the points of a series within a range
+type Result struct {
+	Step   time.Duration // Resolution of the points, the sampling interval for raw samples
+	Points []Point
+}
+
+type series struct {
+	Key   Key
+	Tiers [][]Point // Finest first; every tier is in time order and older than the finer ones
+}
+
+// snapshot is the persisted form of the store
+type snapshot struct {
+	Version int
+	Series  []*series
+}
+
+const snapshotVersion = 1
+
+// Store holds the series in memory and persists them to the configured file
+type Store struct {
+	config  Config
+	tiers   []tier
+	collect Collector
+
+	mu        sync.RWMutex
+	series    map[Key]*series
+	lastFlush time.Time
+}
+
+// New creates a store with defaults applied to unset settings and loads the persisted
+// series. A file that cannot be read is an error; a missing one starts an empty history.
+func New(cfg Config, collect Collector) (*Store, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Retention < 7*24*time.Hour {
+		cfg.Retention = 30 * 24 * time.Hour
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Minute
+	}
+	s := &Store{
+		config: cfg,
+		tiers: []tier{
+			{step: 0, keep: 24 * time.Hour},
+			{step: 10 * time.Minute, keep: 7 * 24 * time.Hour},
+			{step: time.Hour, keep: cfg.Retention},
+		},
+		collect: collect,
+		series:  make(map[Key]*series),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start samples immediately and then on every interval until the context is canceled,
+// writing the file every flush interval and once more when stopping
+func (s *Store) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sample(ctx, time.Now()); err != nil {
+			log.Error().Err(err).Msg("History sampling failed")
+		}
+		if time.Since(s.lastFlushed()) >= s.config.FlushInterval {
+			if err := s.Flush(); err != nil {
+				log.Error().Err(err).Str("path", s.config.Path).Msg("Failed to persist history")
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				log.Error().Err(err).Str("path", s.config.Path).Msg("Failed to persist history")
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sample records the collected samples and downsamples aged points
+func (s *Store) Sample(ctx context.Context, now time.Time) error {
+	samples, err := s.collect(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		s.Record(sample.Key, now, sample.Values)
+	}
+	s.Compact(now)
+	return nil
+}
+
+// Record adds a raw point to a series
+func (s *Store) Record(key Key, at time.Time, values []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ser, ok := s.series[key]
+	if !ok {
+		ser = &series{Key: key, Tiers: make([][]Point, len(s.tiers))}
+		s.series[key] = ser
+	}
+	ser.Tiers[0] = append(ser.Tiers[0], Point{Time: at, Values: values})
+}
+
+// Compact moves points older than their tier keeps into the next tier, averaged over its
+// step, drops points beyond the retention and forgets empty series such as those of
+// deleted deployments
+func (s *Store) Compact(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, ser := range s.series {
+		for i, t := range s.tiers {
+			points := ser.Tiers[i]
+			cutoff := now.Add(-t.keep)
+			if i+1 < len(s.tiers) {
+				// Move whole buckets of the next tier, so each is averaged once
+				cutoff = cutoff.Truncate(s.tiers[i+1].step)
+			}
+			n := sort.Search(len(points), func(j int) bool { return !points[j].Time.Before(cutoff) })
+			if n == 0 {
+				continue
+			}
+			if i+1 < len(s.tiers) {
+				ser.Tiers[i+1] = append(ser.Tiers[i+1], downsample(points[:n], s.tiers[i+1].step)...)
+			}
+			ser.Tiers[i] = append([]Point(nil), points[n:]...)
+		}
+		empty := true
+		for _, points := range ser.Tiers {
+			empty = empty && len(points) == 0
+		}
+		if empty {
+			delete(s.series, key)
+		}
+	}
+}
+
+// Query returns the points of a series from the given time on. The points have the
+// resolution of the finest tier that holds the whole range; finer points are averaged to
+// it. ok is false when the series is unknown.
+func (s *Store) Query(key Key, from, now time.Time) (Result, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ser, ok := s.series[key]
+	if !ok {
+		return Result{}, false
+	}
+
+	chosen := len(s.tiers) - 1
+	for i, t := range s.tiers {
+		if now.Sub(from) <= t.keep {
+			chosen = i
+			break
+		}
+	}
+	var points []Point
+	for i := len(ser.Tiers) - 1; i >= 0; i-- {
+		for _, p := range ser.Tiers[i] {
+			if !p.Time.Before(from) && !p.Time.After(now) {
+				points = append(points, p)
+			}
+		}
+	}
+
+	step := s.tiers[chosen].step
+	if step == 0 {
+		return Result{Step: s.config.Interval, Points: points}, true
+	}
+	return Result{Step: step, Points: downsample(points, step)}, true
+}
+
+// downsample averages time-ordered points over buckets of the step
+func downsample(points []Point, step time.Duration) []Point {
+	var result []Point
+	var sums []float64
+	count := 0
+	emit := func() {
+		if count == 0 {
+			return
+		}
+		last := &result[len(result)-1]
+		for i := range sums {
+			last.Values[i] = sums[i] / float64(count)
+		}
+	}
+	for _, p := range points {
+		bucket := p.Time.Truncate(step)
+		if len(result) == 0 || !result[len(result)-1].Time.Equal(bucket) {
+			emit()
+			result = append(result, Point{Time: bucket, Values: make([]float64, len(p.Values))})
+			sums, count = make([]float64, len(p.Values)), 0
+		}
+		if len(p.Values) != len(sums) {
+			continue // Recorded with other fields; skipped rather than misread
+		}
+		for i, v := range p.Values {
+			sums[i] += v
+		}
+		count++
+	}
+	emit()
+	return result
+}
+
+// Series returns the number of series held
+func (s *Store) Series() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.series)
+}
+
+func (s *Store) lastFlushed() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastFlush
+}
+
+// Flush writes the series to the configured file through a temporary file, so a restart
+// never reads a partial history
+func (s *Store) Flush() error {
+	if s.config.Path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.config.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(s.config.Path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := s.encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(s.config.Path+".tmp", s.config.Path); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.lastFlush = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// encode writes the series; sampling waits until they are written
+func (s *Store) encode(f *os.File) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snap := snapshot{Version: snapshotVersion, Series: make([]*series, 0, len(s.series))}
+	for _, ser := range s.series {
+		snap.Series = append(snap.Series, ser)
+	}
+	return gob.NewEncoder(f).Encode(snap)
+}
+
+// load reads the series persisted by a previous run
+func (s *Store) load() error {
+	if s.config.Path == "" {
+		return nil
+	}
+	f, err := os.Open(s.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var snap snapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return fmt.Errorf("reading history from %s: %w", s.config.Path, err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("history file %s has version %d, expected %d", s.config.Path, snap.Version, snapshotVersion)
+	}
+	for _, ser := range snap.Series {
+		// Fit the tiers to the current ones should their number change
+		tiers := make([][]Point, len(s.tiers))
+		copy(tiers, ser.Tiers)
+		ser.Tiers = tiers
+		s.series[ser.Key] = ser
+	}
+	s.lastFlush = time.Now()
+	return nil
+}
+
+// ParseRange parses a range such as 7d, 12h or 30m; days are not understood by
+// time.ParseDuration
+func ParseRange(value string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		var n float64
+		n, err = strconv.ParseFloat(days, 64)
+		d = time.Duration(n * float64(24*time.Hour))
+	} else {
+		d, err = time.ParseDuration(value)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid range %q: use a value such as 7d, 12h or 30m", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,115 @@
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var web = Key{Kind: KindDeployment, Cluster: "prod", Namespace: "shop", Name: "web"}
+
+func newTestStore(t *testing.T, cfg Config, collect Collector) *Store {
+	s, err := New(cfg, collect)
+	require.NoError(t, err)
+	return s
+}
+
+// TestStore_Query tests raw points and points averaged for longer ranges
+func TestStore_Query(t *testing.T) {
+	s := newTestStore(t, Config{Interval: 5 * time.Minute}, nil)
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		s.Record(web, now.Add(time.Duration(i-4)*5*time.Minute), []float64{3, float64(i), float64(i), 3})
+	}
+
+	result, ok := s.Query(web, now.Add(-time.Hour), now)
+	require.True(t, ok)
+	assert.Equal(t, 5*time.Minute, result.Step)
+	require.Len(t, result.Points, 4)
+	assert.Equal(t, []float64{3, 3, 3, 3}, result.Points[3].Values)
+
+	result, _ = s.Query(web, now.Add(-7*24*time.Hour), now)
+	assert.Equal(t, 10*time.Minute, result.Step)
+	require.Len(t, result.Points, 2)
+	assert.Equal(t, now.Add(-20*time.Minute), result.Points[0].Time)
+	assert.Equal(t, []float64{3, 0.5, 0.5, 3}, result.Points[0].Values)
+	assert.Equal(t, []float64{3, 2.5, 2.5, 3}, result.Points[1].Values)
+
+	_, ok = s.Query(Key{Kind: KindDeployment, Cluster: "prod", Namespace: "shop", Name: "api"}, now.Add(-time.Hour), now)
+	assert.False(t, ok)
+}
+
+// TestStore_Compact tests that aged points are downsampled and dropped beyond the retention
+func TestStore_Compact(t *testing.T) {
+	s := newTestStore(t, Config{Interval: 5 * time.Minute, Retention: 10 * 24 * time.Hour}, nil)
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	node := Key{Kind: KindNode, Cluster: "prod", Name: "node-1"}
+	for i := 0; i < 12; i++ {
+		ready := 1.0
+		if i%2 == 1 {
+			ready = 0
+		}
+		s.Record(node, start.Add(time.Duration(i)*5*time.Minute), []float64{ready, 0})
+	}
+
+	// A day later the hour of samples has moved to 10-minute points
+	s.Compact(start.Add(25 * time.Hour))
+	ser := s.series[node]
+	assert.Empty(t, ser.Tiers[0])
+	require.Len(t, ser.Tiers[1], 6)
+	assert.Equal(t, []float64{0.5, 0}, ser.Tiers[1][0].Values)
+
+	// A week later, to one hourly point
+	s.Compact(start.Add(8 * 24 * time.Hour))
+	assert.Empty(t, ser.Tiers[1])
+	require.Len(t, ser.Tiers[2], 1)
+	assert.Equal(t, start, ser.Tiers[2][0].Time)
+
+	result, ok := s.Query(node, start.Add(-time.Hour), start.Add(8*24*time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, time.Hour, result.Step)
+	assert.Equal(t, []Point{{Time: start, Values: []float64{0.5, 0}}}, result.Points)
+
+	// Beyond the retention the series is forgotten
+	s.Compact(start.Add(11 * 24 * time.Hour))
+	assert.Zero(t, s.Series())
+}
+
+// TestStore_Persistence tests that series written by Flush are loaded by a new store
+func TestStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "series.gob")
+	now := time.Now().Truncate(time.Second)
+	collect := func(ctx context.Context) ([]Sample, error) {
+		return []Sample{{Key: web, Values: []float64{3, 2, 2, 3}}}, nil
+	}
+
+	s := newTestStore(t, Config{Path: path}, collect)
+	require.NoError(t, s.Sample(context.Background(), now))
+	require.NoError(t, s.Flush())
+
+	loaded := newTestStore(t, Config{Path: path}, collect)
+	result, ok := loaded.Query(web, now.Add(-time.Hour), now)
+	require.True(t, ok)
+	require.Len(t, result.Points, 1)
+	assert.True(t, now.Equal(result.Points[0].Time))
+	assert.Equal(t, []float64{3, 2, 2, 3}, result.Points[0].Values)
+}
+
+// TestParseRange tests ranges in days and Go durations
+func TestParseRange(t *testing.T) {
+	d, err := ParseRange("7d")
+	require.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, d)
+	d, err = ParseRange("90m")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+
+	for _, invalid := range []string{"", "d", "-1d", "week"} {
+		_, err := ParseRange(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,224 @@
+// Package hooksig verifies that inbound webhook requests, such as GitHub or GitLab events
+// and CI notifications, come from the sender configured for their path
+package hooksig
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Providers decide how a request proves it knows the hook's secret
+const (
+	ProviderGitHub       = "github"        // X-Hub-Signature-256: sha256=<HMAC-SHA256 of the body>
+	ProviderGitLab       = "gitlab"        // X-Gitlab-Token: <secret>
+	ProviderSharedSecret = "shared_secret" // <header>: <secret>
+)
+
+// Header names read by each provider
+const (
+	GitHubSignatureHeader     = "X-Hub-Signature-256"
+	GitLabTokenHeader         = "X-Gitlab-Token"
+	DefaultSharedSecretHeader = "X-Webhook-Secret"
+)
+
+var (
+	// ErrMissingSignature is returned when the request carries no signature or token
+	ErrMissingSignature = errors.New("missing webhook signature")
+	// ErrInvalidSignature is returned when the signature or token does not match the secret
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// SecretRef points at a key of a Kubernetes Secret in the primary cluster
+type SecretRef struct {
+	Namespace string `mapstructure:"namespace" json:"namespace"`
+	Name      string `mapstructure:"name" json:"name"`
+	Key       string `mapstructure:"key" json:"key"`
+}
+
+// Hook is an inbound webhook path and how its requests are verified
+type Hook struct {
+	Name      string    `mapstructure:"name" json:"name"`
+	Path      string    `mapstructure:"path" json:"path"`               // Exact request path, e.g. /hooks/github
+	Provider  string    `mapstructure:"provider" json:"provider"`       // github, gitlab or shared_secret
+	Header    string    `mapstructure:"header" json:"header,omitempty"` // Header carrying a shared_secret, defaults to X-Webhook-Secret
+	Secret    string    `mapstructure:"secret" json:"-"`                // Inline secret
+	SecretRef SecretRef `mapstructure:"secret_ref" json:"secret_ref"`   // Secret read from the cluster instead of Secret
+}
+
+// Config holds the verified inbound webhooks
+type Config struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	SecretTTL time.Duration `mapstructure:"secret_ttl"` // How long secrets read from the cluster are cached
+	Hooks     []Hook        `mapstructure:"hooks"`
+}
+
+// SecretLoader reads one key of a Kubernetes Secret
+type SecretLoader func(ctx context.Context, namespace, name, key string) (string, error)
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// Verifier checks requests to configured hook paths
+type Verifier struct {
+	config Config
+	hooks  map[string]Hook // Keyed by path
+	load   SecretLoader
+	now    func() time.Time
+
+	mu      sync.Mutex
+	secrets map[string]cachedSecret // Keyed by hook name
+}
+
+// New validates the hooks. load may be nil when no hook uses a secret_ref.
+func New(cfg Config, load SecretLoader) (*Verifier, error) {
+	if cfg.SecretTTL <= 0 {
+		cfg.SecretTTL = time.Minute
+	}
+	v := &Verifier{
+		config:  cfg,
+		hooks:   make(map[string]Hook, len(cfg.Hooks)),
+		load:    load,
+		now:     time.Now,
+		secrets: make(map[string]cachedSecret),
+	}
+	names := make(map[string]bool, len(cfg.Hooks))
+	for i, h := range cfg.Hooks {
+		if h.Name == "" {
+			h.Name = fmt.Sprintf("hook-%d", i)
+		}
+		if names[h.Name] {
+			return nil, fmt.Errorf("hooks[%d]: duplicate name %q", i, h.Name)
+		}
+		names[h.Name] = true
+
+		if !strings.HasPrefix(h.Path, "/") {
+			return nil, fmt.Errorf("hooks[%d]: path must start with /", i)
+		}
+		if _, ok := v.hooks[h.Path]; ok {
+			return nil, fmt.Errorf("hooks[%d]: duplicate path %s", i, h.Path)
+		}
+		switch h.Provider {
+		case ProviderGitHub, ProviderGitLab:
+		case ProviderSharedSecret:
+			if h.Header == "" {
+				h.Header = DefaultSharedSecretHeader
+			}
+		default:
+			return nil, fmt.Errorf("hooks[%d]: provider must be github, gitlab or shared_secret", i)
+		}
+
+		ref := h.SecretRef
+		hasRef := ref != (SecretRef{})
+		switch {
+		case h.Secret != "" && hasRef:
+			return nil, fmt.Errorf("hooks[%d]: set either secret or secret_ref, not both", i)
+		case h.Secret == "" && !hasRef:
+			return nil, fmt.Errorf("hooks[%d]: secret or secret_ref is required", i)
+		case hasRef && (ref.Namespace == "" || ref.Name == "" || ref.Key == ""):
+			return nil, fmt.Errorf("hooks[%d]: secret_ref requires namespace, name and key", i)
+		case hasRef && load == nil:
+			return nil, fmt.Errorf("hooks[%d]: secret_ref requires a Kubernetes client", i)
+		}
+		v.hooks[h.Path] = h
+	}
+	return v, nil
+}
+
+// Match returns the hook configured for a path
+func (v *Verifier) Match(path string) (Hook, bool) {
+	if v == nil {
+		return Hook{}, false
+	}
+	h, ok := v.hooks[path]
+	return h, ok
+}
+
+// Verify checks the request headers and body against the hook's secret. header returns
+// the value of a request header.
+func (v *Verifier) Verify(ctx context.Context, h Hook, header func(name string) string, body []byte) error {
+	secret, err := v.secret(ctx, h)
+	if err != nil {
+		return fmt.Errorf("failed to read secret of hook %s: %w", h.Name, err)
+	}
+
+	switch h.Provider {
+	case ProviderGitHub:
+		signature := header(GitHubSignatureHeader)
+		if signature == "" {
+			return ErrMissingSignature
+		}
+		digest, ok := strings.CutPrefix(signature, "sha256=")
+		if !ok {
+			return ErrInvalidSignature
+		}
+		got, err := hex.DecodeString(digest)
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		if !hmac.Equal(got, mac.Sum(nil)) {
+			return ErrInvalidSignature
+		}
+	case ProviderGitLab:
+		return compareToken(header(GitLabTokenHeader), secret)
+	case ProviderSharedSecret:
+		return compareToken(header(h.Header), secret)
+	}
+	return nil
+}
+
+// Sign returns the X-Hub-Signature-256 value GitHub sends for a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// compareToken compares a presented token with the secret in constant time
+func compareToken(token, secret string) error {
+	if token == "" {
+		return ErrMissingSignature
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// secret returns the hook's inline secret or its cached value from the cluster
+func (v *Verifier) secret(ctx context.Context, h Hook) (string, error) {
+	if h.Secret != "" {
+		return h.Secret, nil
+	}
+
+	v.mu.Lock()
+	cached, ok := v.secrets[h.Name]
+	v.mu.Unlock()
+	if ok && v.now().Before(cached.expires) {
+		return cached.value, nil
+	}
+
+	ref := h.SecretRef
+	value, err := v.load(ctx, ref.Namespace, ref.Name, ref.Key)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("key %s of secret %s/%s is empty", ref.Key, ref.Namespace, ref.Name)
+	}
+	v.mu.Lock()
+	v.secrets[h.Name] = cachedSecret{value: value, expires: v.now().Add(v.config.SecretTTL)}
+	v.mu.Unlock()
+	return value, nil
+}
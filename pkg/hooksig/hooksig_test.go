@@ -0,0 +1,106 @@
+package hooksig
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func headers(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func TestVerify_GitHub(t *testing.T) {
+	v, err := New(Config{Hooks: []Hook{{Name: "gh", Path: "/hooks/github", Provider: ProviderGitHub, Secret: "s3cret"}}}, nil)
+	require.NoError(t, err)
+	h, ok := v.Match("/hooks/github")
+	require.True(t, ok)
+	body := []byte(`{"action":"opened"}`)
+
+	assert.NoError(t, v.Verify(context.Background(), h, headers(map[string]string{GitHubSignatureHeader: Sign("s3cret", body)}), body))
+	assert.ErrorIs(t, v.Verify(context.Background(), h, headers(nil), body), ErrMissingSignature)
+	assert.ErrorIs(t, v.Verify(context.Background(), h, headers(map[string]string{GitHubSignatureHeader: Sign("other", body)}), body), ErrInvalidSignature)
+	assert.ErrorIs(t, v.Verify(context.Background(), h, headers(map[string]string{GitHubSignatureHeader: Sign("s3cret", body)}), []byte(`{}`)), ErrInvalidSignature, "tampered body")
+	assert.ErrorIs(t, v.Verify(context.Background(), h, headers(map[string]string{GitHubSignatureHeader: "sha1=abc"}), body), ErrInvalidSignature)
+
+	_, ok = v.Match("/hooks/gitlab")
+	assert.False(t, ok)
+}
+
+func TestVerify_Tokens(t *testing.T) {
+	v, err := New(Config{Hooks: []Hook{
+		{Name: "gl", Path: "/hooks/gitlab", Provider: ProviderGitLab, Secret: "token"},
+		{Name: "ci", Path: "/hooks/ci", Provider: ProviderSharedSecret, Secret: "shared"},
+		{Name: "custom", Path: "/hooks/custom", Provider: ProviderSharedSecret, Header: "X-CI-Token", Secret: "shared"},
+	}}, nil)
+	require.NoError(t, err)
+
+	gl, _ := v.Match("/hooks/gitlab")
+	assert.NoError(t, v.Verify(context.Background(), gl, headers(map[string]string{GitLabTokenHeader: "token"}), nil))
+	assert.ErrorIs(t, v.Verify(context.Background(), gl, headers(map[string]string{GitLabTokenHeader: "nope"}), nil), ErrInvalidSignature)
+
+	ci, _ := v.Match("/hooks/ci")
+	assert.NoError(t, v.Verify(context.Background(), ci, headers(map[string]string{DefaultSharedSecretHeader: "shared"}), nil))
+
+	custom, _ := v.Match("/hooks/custom")
+	assert.ErrorIs(t, v.Verify(context.Background(), custom, headers(map[string]string{DefaultSharedSecretHeader: "shared"}), nil), ErrMissingSignature)
+	assert.NoError(t, v.Verify(context.Background(), custom, headers(map[string]string{"X-CI-Token": "shared"}), nil))
+}
+
+func TestVerify_SecretRefIsCached(t *testing.T) {
+	loads := 0
+	secret := "first"
+	load := func(_ context.Context, namespace, name, key string) (string, error) {
+		loads++
+		assert.Equal(t, []string{"ci", "hooks", "token"}, []string{namespace, name, key})
+		return secret, nil
+	}
+	v, err := New(Config{SecretTTL: time.Minute, Hooks: []Hook{
+		{Name: "ci", Path: "/hooks/ci", Provider: ProviderGitLab, SecretRef: SecretRef{Namespace: "ci", Name: "hooks", Key: "token"}},
+	}}, load)
+	require.NoError(t, err)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	v.now = func() time.Time { return now }
+	h, _ := v.Match("/hooks/ci")
+
+	assert.NoError(t, v.Verify(context.Background(), h, headers(map[string]string{GitLabTokenHeader: "first"}), nil))
+	secret = "rotated"
+	assert.NoError(t, v.Verify(context.Background(), h, headers(map[string]string{GitLabTokenHeader: "first"}), nil), "cached within the TTL")
+	assert.Equal(t, 1, loads)
+
+	now = now.Add(2 * time.Minute)
+	assert.ErrorIs(t, v.Verify(context.Background(), h, headers(map[string]string{GitLabTokenHeader: "first"}), nil), ErrInvalidSignature)
+	assert.NoError(t, v.Verify(context.Background(), h, headers(map[string]string{GitLabTokenHeader: "rotated"}), nil))
+	assert.Equal(t, 2, loads)
+
+	failing, err := New(Config{Hooks: []Hook{
+		{Name: "ci", Path: "/hooks/ci", Provider: ProviderGitLab, SecretRef: SecretRef{Namespace: "ci", Name: "hooks", Key: "token"}},
+	}}, func(context.Context, string, string, string) (string, error) { return "", errors.New("forbidden") })
+	require.NoError(t, err)
+	err = failing.Verify(context.Background(), h, headers(map[string]string{GitLabTokenHeader: "first"}), nil)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	load := func(context.Context, string, string, string) (string, error) { return "", nil }
+	for _, hooks := range [][]Hook{
+		{{Path: "hooks/github", Provider: ProviderGitHub, Secret: "s"}},
+		{{Path: "/hooks/github", Provider: "bitbucket", Secret: "s"}},
+		{{Path: "/hooks/github", Provider: ProviderGitHub}},
+		{{Path: "/hooks/github", Provider: ProviderGitHub, Secret: "s", SecretRef: SecretRef{Namespace: "ci", Name: "hooks", Key: "token"}}},
+		{{Path: "/hooks/github", Provider: ProviderGitHub, SecretRef: SecretRef{Name: "hooks"}}},
+		{{Path: "/a", Provider: ProviderGitHub, Secret: "s"}, {Path: "/a", Provider: ProviderGitLab, Secret: "s"}},
+		{{Name: "x", Path: "/a", Provider: ProviderGitHub, Secret: "s"}, {Name: "x", Path: "/b", Provider: ProviderGitLab, Secret: "s"}},
+	} {
+		_, err := New(Config{Hooks: hooks}, load)
+		assert.Error(t, err, "%+v", hooks)
+	}
+
+	_, err := New(Config{Hooks: []Hook{{Path: "/a", Provider: ProviderGitHub, SecretRef: SecretRef{Namespace: "ci", Name: "hooks", Key: "token"}}}}, nil)
+	assert.Error(t, err, "secret_ref without a client")
+}
@@ -0,0 +1,99 @@
+// Package httpmetrics records Prometheus metrics of the API server's requests
+package httpmetrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Unmatched is the route label of requests that matched no route, such as 404s and handler
+// plugins, so that arbitrary paths cannot grow the number of series
+const Unmatched = "unmatched"
+
+// Exemplar labels linking an observation to the request's X-Request-ID, which also appears
+// in its log lines and audit entry, and to its trace
+const (
+	RequestIDLabel = "request_id"
+	TraceIDLabel   = "trace_id"
+)
+
+// API server metrics, served by the controller-runtime metrics endpoint and /metrics
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_custom_controller_http_requests_total",
+		Help: "API server requests by route pattern, method and status code",
+	}, []string{"route", "method", "status"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k8s_custom_controller_http_request_duration_seconds",
+		Help:    "Latency of API server requests by route pattern, method and status code",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	requestsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_custom_controller_http_requests_in_flight",
+		Help: "API server requests being served by route pattern and method",
+	}, []string{"route", "method"})
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_custom_controller_http_rate_limited_total",
+		Help: "API server requests rejected by the rate limiter by route pattern and method",
+	}, []string{"route", "method"})
+	shed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_custom_controller_http_shed_total",
+		Help: "API server requests rejected by load shedding by route pattern, method, priority class and reason",
+	}, []string{"route", "method", "priority", "reason"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(requestsTotal, requestDuration, requestsInFlight, rateLimited, shed)
+}
+
+// TrackInFlight counts a request as in flight until the returned function is called
+func TrackInFlight(route, method string) func() {
+	gauge := requestsInFlight.WithLabelValues(routeLabel(route), method)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// RateLimited counts a request rejected by the rate limiter
+func RateLimited(route, method string) {
+	rateLimited.WithLabelValues(routeLabel(route), method).Inc()
+}
+
+// Shed counts a request rejected by load shedding
+func Shed(route, method, priority, reason string) {
+	shed.WithLabelValues(routeLabel(route), method, priority, reason).Inc()
+}
+
+// ObserveRequest counts a completed request and records its latency. Non-empty exemplar
+// labels are attached to the observation; exemplars are only exposed in the OpenMetrics format.
+func ObserveRequest(route, method string, status int, latency time.Duration, exemplar map[string]string) {
+	route = routeLabel(route)
+	requestsTotal.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	observer := requestDuration.WithLabelValues(route, method, strconv.Itoa(status))
+	if len(exemplar) == 0 {
+		observer.Observe(latency.Seconds())
+		return
+	}
+	observer.(prometheus.ExemplarObserver).ObserveWithExemplar(latency.Seconds(), exemplar)
+}
+
+func routeLabel(route string) string {
+	if route == "" {
+		return Unmatched
+	}
+	return route
+}
+
+// Handler serves every metric of the controller-runtime registry. Unlike the
+// controller-runtime metrics endpoint, it negotiates OpenMetrics, the only exposition format
+// that carries exemplars.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.HTTPErrorOnError,
+		EnableOpenMetrics: true,
+	})
+}
@@ -0,0 +1,68 @@
+package httpmetrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveRequest_ExemplarInOpenMetrics(t *testing.T) {
+	ObserveRequest("/exemplar-test/{name}", "GET", 200, 30*time.Millisecond, map[string]string{RequestIDLabel: "3f0c1e9a-request", TraceIDLabel: "4bf92f3577b34da6a3ce929d0e0e4736"})
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "application/openmetrics-text")
+
+	body, _ := io.ReadAll(rec.Body)
+	var bucket string
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.Contains(line, `route="/exemplar-test/{name}"`) && strings.Contains(line, "# {") {
+			bucket = line
+			break
+		}
+	}
+	require.NotEmpty(t, bucket, "no bucket carries an exemplar")
+	assert.Contains(t, bucket, `# {request_id="3f0c1e9a-request",trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 0.03`)
+}
+
+func TestObserveRequest_WithoutRequestID(t *testing.T) {
+	ObserveRequest("", "DELETE", 404, time.Millisecond, nil)
+
+	// Clients without OpenMetrics get the text format, which has no exemplars
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `k8s_custom_controller_http_request_duration_seconds_count{method="DELETE",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, rec.Body.String(), "# {")
+}
+
+func TestTrackInFlight(t *testing.T) {
+	done := TrackInFlight("/in-flight-test", "GET")
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(requestsInFlight.WithLabelValues("/in-flight-test", "GET")))
+	done()
+	assert.Equal(t, 0.0, promtestutil.ToFloat64(requestsInFlight.WithLabelValues("/in-flight-test", "GET")))
+}
+
+func TestObserveRequest_CountsByStatus(t *testing.T) {
+	ObserveRequest("/count-test", "POST", 201, time.Millisecond, nil)
+	ObserveRequest("/count-test", "POST", 201, time.Millisecond, nil)
+	ObserveRequest("/count-test", "POST", 429, time.Millisecond, nil)
+	RateLimited("/count-test", "POST")
+
+	assert.Equal(t, 2.0, promtestutil.ToFloat64(requestsTotal.WithLabelValues("/count-test", "POST", "201")))
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(requestsTotal.WithLabelValues("/count-test", "POST", "429")))
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(rateLimited.WithLabelValues("/count-test", "POST")))
+
+	Shed("", "GET", "low", "memory")
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(shed.WithLabelValues(Unmatched, "GET", "low", "memory")))
+}
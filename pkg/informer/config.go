@@ -19,6 +19,8 @@ type InformerOptions struct {
 	Burst             int           // Maximum burst for throttle
 	Timeout           time.Duration // Timeout for operations
 	DisableInformer   bool          // Whether to disable informer
+	RecordFixtures    string        // Directory API responses are recorded into as test fixtures, empty to disable
+	ClusterID         string        // Cluster the client talks to, labelling its API call metrics and spans
 }
 
 // DefaultInformerOptions returns default options for the informer
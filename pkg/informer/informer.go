@@ -14,10 +14,23 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/fixture"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/kubemetrics"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/tracing"
 )
 
 // CreateClientset creates a Kubernetes clientset from kubeconfig or in-cluster config
 func CreateClientset(kubeconfig string, inCluster bool, opts *InformerOptions) (*kubernetes.Clientset, error) {
+	config, err := CreateRestConfig(kubeconfig, inCluster, opts)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// CreateRestConfig builds the REST config used by CreateClientset, for clients other than the typed clientset
+func CreateRestConfig(kubeconfig string, inCluster bool, opts *InformerOptions) (*rest.Config, error) {
 	var config *rest.Config
 	var err error
 
@@ -42,7 +55,22 @@ func CreateClientset(kubeconfig string, inCluster bool, opts *InformerOptions) (
 		config.Timeout = opts.Timeout
 	}
 
-	return kubernetes.NewForConfig(config)
+	var clusterID string
+	if opts != nil {
+		clusterID = opts.ClusterID
+	}
+
+	// Record the API calls made on behalf of traced requests, and the latency of every call
+	config.Wrap(tracing.WrapTransport(clusterID))
+	config.Wrap(kubemetrics.WrapTransport(clusterID))
+
+	// Save sanitized responses for replay in tests
+	if opts != nil && opts.RecordFixtures != "" {
+		log.Warn().Str("dir", opts.RecordFixtures).Msg("Recording Kubernetes API responses as fixtures")
+		config.Wrap(fixture.Record(opts.RecordFixtures))
+	}
+
+	return config, nil
 }
 
 // StartDeploymentInformer starts a shared informer for Deployments with configuration options
@@ -300,6 +328,48 @@ func ListDeploymentsInCache(informer cache.SharedIndexInformer, namespace string
 	return deployments, nil
 }
 
+// ListDeploymentsInCacheWithSelectors returns cached deployments in the namespace matching
+// the label and field selectors, mirroring what the API server would return for a list call
+func ListDeploymentsInCacheWithSelectors(informer cache.SharedIndexInformer, namespace, labelSelector, fieldSelector string) ([]*appsv1.Deployment, error) {
+	deployments, err := ListDeploymentsInCache(informer, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return FilterDeployments(deployments, labelSelector, fieldSelector)
+}
+
+// FilterDeployments applies label and field selectors to deployments read from any cache
+func FilterDeployments(deployments []*appsv1.Deployment, labelSelector, fieldSelector string) ([]*appsv1.Deployment, error) {
+	labelSel, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %s: %w", labelSelector, err)
+	}
+	fieldSel, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field selector %s: %w", fieldSelector, err)
+	}
+
+	// Deployments only support the generic metadata field selectors
+	for _, req := range fieldSel.Requirements() {
+		if req.Field != "metadata.name" && req.Field != "metadata.namespace" {
+			return nil, fmt.Errorf("field selector %s is not supported for deployments", req.Field)
+		}
+	}
+
+	var matched []*appsv1.Deployment
+	for _, deployment := range deployments {
+		fieldSet := fields.Set{
+			"metadata.name":      deployment.Name,
+			"metadata.namespace": deployment.Namespace,
+		}
+		if labelSel.Matches(labels.Set(deployment.Labels)) && fieldSel.Matches(fieldSet) {
+			matched = append(matched, deployment)
+		}
+	}
+
+	return matched, nil
+}
+
 // Utility function for getting deployment name from any object
 func getDeploymentName(obj any) string {
 	if d, ok := obj.(metav1.Object); ok {
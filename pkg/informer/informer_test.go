@@ -11,8 +11,10 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
 
 	testutil "github.com/obezsmertnyi/k8s-custom-controller/pkg/testutil"
@@ -128,6 +130,17 @@ func TestCreateClientset(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestCreateRestConfig(t *testing.T) {
+	kubeconfigPath, err := testutil.CreateTempKubeconfig()
+	require.NoError(t, err)
+
+	config, err := CreateRestConfig(kubeconfigPath, false, &InformerOptions{QPS: 123, Burst: 456, Timeout: 10 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, float32(123), config.QPS)
+	assert.Equal(t, 456, config.Burst)
+	assert.Equal(t, 10*time.Second, config.Timeout)
+}
+
 func TestStartDeploymentInformer_CoversFunction(t *testing.T) {
 	// Skip this test if SKIP_K8S_TESTS environment variable is set
 	if os.Getenv("SKIP_K8S_TESTS") != "" {
@@ -171,3 +184,29 @@ func TestStartDeploymentInformer_CoversFunction(t *testing.T) {
 	time.Sleep(1 * time.Second)
 	cancel()
 }
+
+func TestListDeploymentsInCacheWithSelectors(t *testing.T) {
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	deploymentInformer := factory.Apps().V1().Deployments().Informer()
+	for _, d := range []*appsv1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", Labels: map[string]string{"app": "web", "tier": "frontend"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop", Labels: map[string]string{"app": "api", "tier": "backend"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "blog", Labels: map[string]string{"app": "web", "tier": "frontend"}}},
+	} {
+		require.NoError(t, deploymentInformer.GetIndexer().Add(d))
+	}
+
+	matched, err := ListDeploymentsInCacheWithSelectors(deploymentInformer, "shop", "tier=frontend", "")
+	require.NoError(t, err)
+	require.Len(t, matched, 1)
+	assert.Equal(t, "web", matched[0].Name)
+
+	matched, err = ListDeploymentsInCacheWithSelectors(deploymentInformer, "", "tier in (frontend,backend)", "metadata.name!=api")
+	require.NoError(t, err)
+	assert.Len(t, matched, 2)
+
+	_, err = ListDeploymentsInCacheWithSelectors(deploymentInformer, "", "tier in frontend", "")
+	assert.Error(t, err)
+	_, err = ListDeploymentsInCacheWithSelectors(deploymentInformer, "", "", "status.replicas=1")
+	assert.Error(t, err)
+}
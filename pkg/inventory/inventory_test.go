@@ -0,0 +1,94 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testClient() *fake.Clientset {
+	replicas := int32(3)
+	return fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "payments"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{
+						Name:  "api",
+						Image: "registry.example.com/api:1.2.3",
+						Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("250m"),
+							corev1.ResourceMemory: resource.MustParse("128Mi"),
+						}},
+					},
+					{Name: "proxy", Image: "envoy:1.30"},
+				}}},
+			},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "kube-system"},
+			Spec: appsv1.DaemonSetSpec{
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "agent", Image: "agent:0.1"},
+				}}},
+			},
+			Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 5},
+		},
+	)
+}
+
+func TestCollect(t *testing.T) {
+	rows, err := Collect(context.Background(), testClient(), "prod", "")
+	require.NoError(t, err)
+	Sort(rows)
+
+	require.Len(t, rows, 3)
+	assert.Equal(t, Row{Cluster: "prod", Namespace: "kube-system", Kind: "DaemonSet", Name: "agent", Container: "agent", Image: "agent:0.1", Replicas: 5}, rows[0])
+	assert.Equal(t, Row{
+		Cluster: "prod", Namespace: "payments", Kind: "Deployment", Name: "api", Container: "api",
+		Image: "registry.example.com/api:1.2.3", Replicas: 3, CPURequestMillis: 250, MemoryRequestBytes: 128 * 1024 * 1024,
+	}, rows[1])
+	assert.Equal(t, "proxy", rows[2].Container)
+}
+
+func TestWriteCSV(t *testing.T) {
+	rows := []Row{{Cluster: "prod", Namespace: "default", Kind: "Deployment", Name: "web", Container: "web", Image: "nginx", Replicas: 2, CPURequestMillis: 100}}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatCSV, rows))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, csvHeader, records[0])
+	assert.Equal(t, []string{"prod", "default", "Deployment", "web", "web", "nginx", "2", "100", "0"}, records[1])
+}
+
+func TestWriteParquet(t *testing.T) {
+	rows := []Row{
+		{Cluster: "prod", Namespace: "default", Kind: "Deployment", Name: "web", Container: "web", Image: "nginx", Replicas: 2},
+		{Cluster: "dev", Namespace: "default", Kind: "StatefulSet", Name: "db", Container: "postgres", Image: "postgres:16", Replicas: 1},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatParquet, rows))
+
+	decoded, err := parquet.Read[Row](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, rows, decoded)
+}
+
+func TestWrite_UnsupportedFormat(t *testing.T) {
+	assert.Error(t, Write(&bytes.Buffer{}, "xlsx", nil))
+}
@@ -0,0 +1,134 @@
+// Package kubemetrics records Prometheus metrics of the Kubernetes API calls made to every
+// cluster, so a slow cluster behind an aggregated endpoint can be told apart from the others
+package kubemetrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ErrorCode is the code label of calls that got no response, e.g. on timeouts and refused
+// connections
+const ErrorCode = "<error>"
+
+// Kubernetes API client metrics, served by the controller-runtime metrics endpoint and /metrics
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_custom_controller_kubernetes_requests_total",
+		Help: "Kubernetes API calls by cluster, verb, resource and status code",
+	}, []string{"cluster", "verb", "resource", "code"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k8s_custom_controller_kubernetes_request_duration_seconds",
+		Help:    "Latency of Kubernetes API calls until the response headers arrive, by cluster, verb and resource",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"cluster", "verb", "resource"})
+	retriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_custom_controller_kubernetes_request_retries_total",
+		Help: "Kubernetes API responses asking the client to retry after a delay, by cluster, verb and resource",
+	}, []string{"cluster", "verb", "resource"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(requestsTotal, requestDuration, retriesTotal)
+}
+
+// WrapTransport returns a rest.Config WrapTransport function recording the latency, status
+// code and retries of every API call made to the cluster
+func WrapTransport(clusterID string) func(http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &transport{next: rt, clusterID: clusterID}
+	}
+}
+
+type transport struct {
+	next      http.RoundTripper
+	clusterID string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	verb, resource := Classify(req)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	requestDuration.WithLabelValues(t.clusterID, verb, resource).Observe(time.Since(start).Seconds())
+	if err != nil {
+		requestsTotal.WithLabelValues(t.clusterID, verb, resource, ErrorCode).Inc()
+		return nil, err
+	}
+	requestsTotal.WithLabelValues(t.clusterID, verb, resource, strconv.Itoa(resp.StatusCode)).Inc()
+	if retryRequested(resp) {
+		retriesTotal.WithLabelValues(t.clusterID, verb, resource).Inc()
+	}
+	return resp, nil
+}
+
+// retryRequested reports whether client-go retries the call after this response: it honors
+// a Retry-After in seconds on 429 and 5xx responses, up to ten times
+func retryRequested(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return false
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	return err == nil && seconds >= 0
+}
+
+// Classify names the Kubernetes verb of an API call and the resource it addresses, e.g. list
+// and deployments.apps for GET /apis/apps/v1/namespaces/prod/deployments, or get and pods/log
+// for GET /api/v1/namespaces/prod/pods/web/log. Paths outside the resource APIs, such as
+// /version, are named by their first segment.
+func Classify(req *http.Request) (verb, resource string) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	var group string
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 4 && parts[0] == "apis":
+		group, parts = parts[1], parts[3:]
+	default:
+		return strings.ToLower(req.Method), "/" + parts[0]
+	}
+	if parts[0] == "namespaces" && len(parts) >= 3 {
+		parts = parts[2:] // Namespaced resource; namespaces themselves keep their path
+	}
+
+	resource = parts[0]
+	if group != "" {
+		resource += "." + group
+	}
+	if len(parts) >= 3 {
+		resource += "/" + parts[2] // Subresource, e.g. deployments/scale
+	}
+	named := len(parts) >= 2
+	return kubernetesVerb(req, named), resource
+}
+
+// kubernetesVerb maps an HTTP method to the Kubernetes verb, as the API server does
+func kubernetesVerb(req *http.Request, named bool) string {
+	switch req.Method {
+	case http.MethodGet:
+		if req.URL.Query().Get("watch") == "true" {
+			return "watch"
+		}
+		if named {
+			return "get"
+		}
+		return "list"
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		if named {
+			return "delete"
+		}
+		return "deletecollection"
+	}
+	return strings.ToLower(req.Method)
+}
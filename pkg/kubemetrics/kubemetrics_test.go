@@ -0,0 +1,115 @@
+package kubemetrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		method, path   string
+		verb, resource string
+	}{
+		{"GET", "/api/v1/namespaces/prod/pods", "list", "pods"},
+		{"GET", "/api/v1/pods?watch=true", "watch", "pods"},
+		{"GET", "/api/v1/namespaces/prod/pods/web/log", "get", "pods/log"},
+		{"POST", "/api/v1/namespaces/prod/pods/web/exec", "create", "pods/exec"},
+		{"GET", "/api/v1/namespaces", "list", "namespaces"},
+		{"GET", "/api/v1/namespaces/prod", "get", "namespaces"},
+		{"DELETE", "/api/v1/nodes/worker-1", "delete", "nodes"},
+		{"GET", "/apis/apps/v1/namespaces/prod/deployments/web", "get", "deployments.apps"},
+		{"PUT", "/apis/apps/v1/namespaces/prod/deployments/web/scale", "update", "deployments.apps/scale"},
+		{"PATCH", "/apis/apps/v1/namespaces/prod/deployments/web", "patch", "deployments.apps"},
+		{"DELETE", "/apis/batch/v1/namespaces/prod/jobs", "deletecollection", "jobs.batch"},
+		{"GET", "/version", "get", "/version"},
+		{"GET", "/apis/apps/v1", "get", "/apis"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		verb, resource := Classify(req)
+		assert.Equal(t, tc.verb, verb, tc.path)
+		assert.Equal(t, tc.resource, resource, tc.path)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func respond(status int, retryAfter string) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		if retryAfter != "" {
+			header.Set("Retry-After", retryAfter)
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: http.NoBody, Request: req}, nil
+	})
+}
+
+func TestWrapTransport_RecordsCalls(t *testing.T) {
+	rt := WrapTransport("metrics-east")(respond(http.StatusOK, ""))
+	for i := 0; i < 2; i++ {
+		resp, err := rt.RoundTrip(httptest.NewRequest("GET", "/api/v1/namespaces/prod/pods", nil))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 2.0, promtestutil.ToFloat64(requestsTotal.WithLabelValues("metrics-east", "list", "pods", "200")))
+	assert.Equal(t, uint64(2), durationCount(t, "metrics-east"))
+	assert.Equal(t, 0.0, promtestutil.ToFloat64(retriesTotal.WithLabelValues("metrics-east", "list", "pods")))
+}
+
+// durationCount returns the number of latency observations of the cluster
+func durationCount(t *testing.T, cluster string) uint64 {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(requestDuration)
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	var count uint64
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "cluster" && label.GetValue() == cluster {
+					count += m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return count
+}
+
+func TestWrapTransport_CountsRetriesAndErrors(t *testing.T) {
+	req := httptest.NewRequest("GET", "/apis/apps/v1/namespaces/prod/deployments", nil)
+
+	// client-go retries 429 and 5xx responses carrying a Retry-After in seconds
+	for _, tc := range []struct {
+		status     int
+		retryAfter string
+		retried    bool
+	}{
+		{http.StatusTooManyRequests, "1", true},
+		{http.StatusServiceUnavailable, "0", true},
+		{http.StatusServiceUnavailable, "", false},
+		{http.StatusTooManyRequests, "Wed, 21 Oct 2015 07:28:00 GMT", false},
+		{http.StatusConflict, "1", false},
+	} {
+		rt := WrapTransport("metrics-retry")(respond(tc.status, tc.retryAfter))
+		before := promtestutil.ToFloat64(retriesTotal.WithLabelValues("metrics-retry", "list", "deployments.apps"))
+		_, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		after := promtestutil.ToFloat64(retriesTotal.WithLabelValues("metrics-retry", "list", "deployments.apps"))
+		assert.Equal(t, tc.retried, after > before, "%d Retry-After %q", tc.status, tc.retryAfter)
+	}
+	assert.Equal(t, 2.0, promtestutil.ToFloat64(requestsTotal.WithLabelValues("metrics-retry", "list", "deployments.apps", "429")))
+
+	failing := roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, errors.New("connection refused") })
+	_, err := WrapTransport("metrics-retry")(failing).RoundTrip(req)
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(requestsTotal.WithLabelValues("metrics-retry", "list", "deployments.apps", ErrorCode)))
+}
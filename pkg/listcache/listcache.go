@@ -0,0 +1,177 @@
+// Package listcache keeps the results of Kubernetes list calls for a short time, so bursts
+// of identical list requests share one call to the cluster's API server
+package listcache
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// DefaultMaxEntries bounds the number of cached lists when max_entries is unset
+const DefaultMaxEntries = 1000
+
+// Results of a lookup
+const (
+	ResultHit    = "hit"    // Served from the cache
+	ResultShared = "shared" // Waited for an identical list call already running
+	ResultMiss   = "miss"   // Listed from the cluster
+)
+
+// Config holds list cache settings
+type Config struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	TTL        time.Duration `mapstructure:"ttl"`         // How long a list is served from the cache
+	MaxEntries int           `mapstructure:"max_entries"` // Cached lists kept at most; the ones closest to expiry are evicted first
+	Routes     []string      `mapstructure:"routes"`      // List endpoints whose results are cached
+}
+
+// Validate checks the configured TTL, bound and routes
+func (c Config) Validate() error {
+	if c.TTL < 0 {
+		return fmt.Errorf("ttl must not be negative, got %s", c.TTL)
+	}
+	if c.MaxEntries < 0 {
+		return fmt.Errorf("max_entries must not be negative, got %d", c.MaxEntries)
+	}
+	for i, route := range c.Routes {
+		if !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("routes[%d]: %q must start with /", i, route)
+		}
+	}
+	return nil
+}
+
+// Key identifies one list call
+type Key struct {
+	Route         string
+	Cluster       string
+	Namespace     string
+	LabelSelector string
+	FieldSelector string
+}
+
+// Lookups by route and result, served by the controller-runtime metrics endpoint and /metrics
+var lookupsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "k8s_custom_controller_list_cache_lookups_total",
+	Help: "List cache lookups by route and result: hit, shared or miss",
+}, []string{"route", "result"})
+
+func init() {
+	metrics.Registry.MustRegister(lookupsTotal)
+}
+
+type entry struct {
+	value    interface{}
+	cachedAt time.Time
+}
+
+// call is a list call other lookups of the same key wait for
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// Cache holds recent list results by key
+type Cache struct {
+	config Config
+	now    func() time.Time
+
+	mu       sync.Mutex
+	entries  map[Key]entry
+	inflight map[Key]*call
+}
+
+// New creates a cache with defaults applied to unset settings
+func New(cfg Config) *Cache {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 5 * time.Second
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+	return &Cache{config: cfg, now: time.Now, entries: make(map[Key]entry), inflight: make(map[Key]*call)}
+}
+
+// Caches reports whether lists of the route are cached
+func (c *Cache) Caches(route string) bool {
+	return slices.Contains(c.config.Routes, route)
+}
+
+// Get returns the cached list of the key, or calls list and caches its result. Concurrent
+// lookups of a key that is not cached share one call. Errors are not cached. age is how
+// long ago the returned list was read from the cluster.
+func (c *Cache) Get(ctx context.Context, key Key, list func(context.Context) (interface{}, error)) (value interface{}, age time.Duration, result string, err error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		if age = c.now().Sub(e.cachedAt); age < c.config.TTL {
+			c.mu.Unlock()
+			lookupsTotal.WithLabelValues(key.Route, ResultHit).Inc()
+			return e.value, age, ResultHit, nil
+		}
+		delete(c.entries, key)
+	}
+	if running, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		lookupsTotal.WithLabelValues(key.Route, ResultShared).Inc()
+		select {
+		case <-running.done:
+			return running.value, 0, ResultShared, running.err
+		case <-ctx.Done():
+			return nil, 0, ResultShared, ctx.Err()
+		}
+	}
+	running := &call{done: make(chan struct{})}
+	c.inflight[key] = running
+	c.mu.Unlock()
+
+	lookupsTotal.WithLabelValues(key.Route, ResultMiss).Inc()
+	running.value, running.err = list(ctx)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if running.err == nil {
+		c.store(key, running.value)
+	}
+	c.mu.Unlock()
+	close(running.done)
+	return running.value, 0, ResultMiss, running.err
+}
+
+// Len returns the number of cached lists, expired ones included until they are evicted
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// store caches a list, evicting expired lists and then the oldest ones beyond max_entries.
+// The caller holds c.mu.
+func (c *Cache) store(key Key, value interface{}) {
+	now := c.now()
+	if len(c.entries) >= c.config.MaxEntries {
+		for k, e := range c.entries {
+			if now.Sub(e.cachedAt) >= c.config.TTL {
+				delete(c.entries, k)
+			}
+		}
+	}
+	for len(c.entries) >= c.config.MaxEntries {
+		var oldest Key
+		var oldestAt time.Time
+		for k, e := range c.entries {
+			if oldestAt.IsZero() || e.cachedAt.Before(oldestAt) {
+				oldest, oldestAt = k, e.cachedAt
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = entry{value: value, cachedAt: now}
+}
@@ -0,0 +1,128 @@
+package listcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/internal/testclock"
+)
+
+func newTestCache(cfg Config) (*Cache, *testclock.Clock) {
+	clock := testclock.New(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	c := New(cfg)
+	c.now = clock.Now
+	return c, clock
+}
+
+// counting returns a list function reporting how often it was called
+func counting(calls *atomic.Int32) func(context.Context) (interface{}, error) {
+	return func(context.Context) (interface{}, error) {
+		return int(calls.Add(1)), nil
+	}
+}
+
+func TestGet_TTL(t *testing.T) {
+	c, clock := newTestCache(Config{TTL: 5 * time.Second, Routes: []string{"/pods"}})
+	key := Key{Route: "/ttl-test", Cluster: "prod", Namespace: "shop", LabelSelector: "app=web"}
+	var calls atomic.Int32
+
+	value, _, result, err := c.Get(context.Background(), key, counting(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, ResultMiss, result)
+
+	clock.Advance(3 * time.Second)
+	value, age, result, _ := c.Get(context.Background(), key, counting(&calls))
+	assert.Equal(t, 1, value)
+	assert.Equal(t, ResultHit, result)
+	assert.Equal(t, 3*time.Second, age)
+
+	other := key
+	other.Namespace = "billing"
+	value, _, _, _ = c.Get(context.Background(), other, counting(&calls))
+	assert.Equal(t, 2, value, "every part of the key counts")
+
+	clock.Advance(2 * time.Second)
+	value, _, result, _ = c.Get(context.Background(), key, counting(&calls))
+	assert.Equal(t, 3, value, "expired")
+	assert.Equal(t, ResultMiss, result)
+
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(lookupsTotal.WithLabelValues("/ttl-test", ResultHit)))
+	assert.True(t, c.Caches("/pods"))
+	assert.False(t, c.Caches("/nodes"))
+}
+
+func TestGet_SharesConcurrentCalls(t *testing.T) {
+	c, _ := newTestCache(Config{})
+	key := Key{Route: "/nodes", Cluster: "prod"}
+	release := make(chan struct{})
+	var calls atomic.Int32
+	list := func(context.Context) (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return "nodes", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _, _, _ = c.Get(context.Background(), key, list)
+		}()
+	}
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond) // Let the other lookups join the running call
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, r := range results {
+		assert.Equal(t, "nodes", r)
+	}
+}
+
+func TestGet_ErrorsAreNotCached(t *testing.T) {
+	c, _ := newTestCache(Config{})
+	key := Key{Route: "/pods", Cluster: "prod"}
+	_, _, _, err := c.Get(context.Background(), key, func(context.Context) (interface{}, error) {
+		return nil, errors.New("connection refused")
+	})
+	assert.Error(t, err)
+
+	var calls atomic.Int32
+	value, _, _, err := c.Get(context.Background(), key, counting(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+}
+
+func TestGet_MaxEntries(t *testing.T) {
+	c, clock := newTestCache(Config{MaxEntries: 2})
+	var calls atomic.Int32
+	for _, ns := range []string{"a", "b", "c"} {
+		c.Get(context.Background(), Key{Route: "/pods", Namespace: ns}, counting(&calls))
+		clock.Advance(time.Second)
+	}
+	assert.Equal(t, 2, c.Len())
+
+	_, _, result, _ := c.Get(context.Background(), Key{Route: "/pods", Namespace: "a"}, counting(&calls))
+	assert.Equal(t, ResultMiss, result, "the oldest list was evicted")
+	_, _, result, _ = c.Get(context.Background(), Key{Route: "/pods", Namespace: "c"}, counting(&calls))
+	assert.Equal(t, ResultHit, result)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Config{TTL: time.Second, Routes: []string{"/pods"}}.Validate())
+	assert.Error(t, Config{TTL: -time.Second}.Validate())
+	assert.Error(t, Config{MaxEntries: -1}.Validate())
+	assert.Error(t, Config{Routes: []string{"pods"}}.Validate())
+}
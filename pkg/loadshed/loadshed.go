@@ -0,0 +1,182 @@
+// Package loadshed rejects low-priority API requests while the server is overloaded, so
+// probes and admin access keep being answered
+package loadshed
+
+import (
+	"context"
+	"fmt"
+	"runtime/metrics"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Priority classes of routes, highest first
+const (
+	Critical = "critical" // Probes; never shed
+	High     = "high"     // Admin and debug access; shed only at twice max_in_flight
+	Normal   = "normal"   // Routes no rule matches; shed at max_in_flight or above the memory limit
+	Low      = "low"      // Streams and exports; shed at half of max_in_flight or above the memory limit
+)
+
+// Priorities lists the priority classes, highest first
+var Priorities = []string{Critical, High, Normal, Low}
+
+// Reasons a request is shed
+const (
+	ReasonConcurrency = "concurrency"
+	ReasonMemory      = "memory"
+)
+
+// heapMetric is the memory occupied by live and not yet swept heap objects
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// Route sets the priority of the requests to a path
+type Route struct {
+	Path     string `mapstructure:"path"`     // Exact path or prefix; * matches one segment, e.g. /pods/*/*/logs
+	Priority string `mapstructure:"priority"` // critical, high, normal or low
+}
+
+// Config holds load shedding settings
+type Config struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	MaxInFlight    int           `mapstructure:"max_in_flight"`             // Requests served at once before normal ones are shed, 0 disables concurrency shedding
+	MemoryLimit    int64         `mapstructure:"memory_limit" unit:"bytes"` // Heap size above which normal and low requests are shed, 0 disables memory shedding
+	SampleInterval time.Duration `mapstructure:"sample_interval"`           // How often the heap size is read
+	Routes         []Route       `mapstructure:"routes"`                    // Evaluated in order, the first match wins
+}
+
+// Validate checks the configured limits, paths and priorities
+func (c Config) Validate() error {
+	if c.MaxInFlight < 0 {
+		return fmt.Errorf("max_in_flight must not be negative, got %d", c.MaxInFlight)
+	}
+	if c.MemoryLimit < 0 {
+		return fmt.Errorf("memory_limit must not be negative, got %d", c.MemoryLimit)
+	}
+	for i, r := range c.Routes {
+		if r.Path == "" || !strings.HasPrefix(r.Path, "/") {
+			return fmt.Errorf("routes[%d]: path %q must start with /", i, r.Path)
+		}
+		if !slices.Contains(Priorities, r.Priority) {
+			return fmt.Errorf("routes[%d]: invalid priority %q, expected one of %s", i, r.Priority, strings.Join(Priorities, ", "))
+		}
+	}
+	return nil
+}
+
+// Shedder admits or sheds requests by their priority and the current load
+type Shedder struct {
+	config   Config
+	inFlight atomic.Int64
+	heap     atomic.Uint64 // Heap size at the latest sample
+	readHeap func() uint64
+}
+
+// New creates a shedder with defaults applied to unset settings
+func New(cfg Config) *Shedder {
+	if cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = time.Second
+	}
+	return &Shedder{config: cfg, readHeap: readHeapBytes}
+}
+
+// Start samples the heap size on every interval until the context is canceled. Without
+// a memory limit it returns at once.
+func (s *Shedder) Start(ctx context.Context) {
+	if s.config.MemoryLimit <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.config.SampleInterval)
+	defer ticker.Stop()
+	for {
+		s.heap.Store(s.readHeap())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Priority returns the priority class of a request path
+func (s *Shedder) Priority(path string) string {
+	for _, r := range s.config.Routes {
+		if matchPath(r.Path, path) {
+			return r.Priority
+		}
+	}
+	return Normal
+}
+
+// Admit counts a request of the priority as in flight unless the server is too loaded for
+// it. When admitted, done must be called once the request is served; otherwise reason
+// tells why it was shed.
+func (s *Shedder) Admit(priority string) (done func(), reason string, ok bool) {
+	inFlight := s.inFlight.Add(1)
+	if reason = s.shed(priority, inFlight); reason != "" {
+		s.inFlight.Add(-1)
+		return nil, reason, false
+	}
+	return func() { s.inFlight.Add(-1) }, "", true
+}
+
+// InFlight returns the number of admitted requests being served
+func (s *Shedder) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+// shed returns why a request of the priority is shed with inFlight requests being served,
+// itself included, or an empty string when it is admitted
+func (s *Shedder) shed(priority string, inFlight int64) string {
+	if priority == Critical {
+		return ""
+	}
+	if limit := s.config.MemoryLimit; limit > 0 && (priority == Normal || priority == Low) && s.heap.Load() > uint64(limit) {
+		return ReasonMemory
+	}
+	if s.config.MaxInFlight <= 0 {
+		return ""
+	}
+	limit := int64(s.config.MaxInFlight)
+	switch priority {
+	case High:
+		limit *= 2
+	case Low:
+		limit = max(limit/2, 1)
+	}
+	if inFlight > limit {
+		return ReasonConcurrency
+	}
+	return ""
+}
+
+// readHeapBytes reads the heap size without stopping the world, unlike runtime.ReadMemStats
+func readHeapBytes() uint64 {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// matchPath reports whether path equals pattern or lies below it, where a * segment of
+// pattern matches any one non-empty segment
+func matchPath(pattern, path string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return true
+	}
+	patternSegments, pathSegments := strings.Split(pattern, "/"), strings.Split(path, "/")
+	if len(pathSegments) < len(patternSegments) {
+		return false
+	}
+	for i, segment := range patternSegments {
+		if segment != pathSegments[i] && (segment != "*" || pathSegments[i] == "") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package loadshed
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriority(t *testing.T) {
+	s := New(Config{Routes: []Route{
+		{Path: "/readyz", Priority: Critical},
+		{Path: "/debug", Priority: High},
+		{Path: "/pods/*/*/logs", Priority: Low},
+	}})
+	assert.Equal(t, Critical, s.Priority("/readyz"))
+	assert.Equal(t, High, s.Priority("/debug/pprof/heap"))
+	assert.Equal(t, Low, s.Priority("/pods/prod/web/logs"))
+	assert.Equal(t, Normal, s.Priority("/pods/prod/web/diagnosis"))
+	assert.Equal(t, Normal, s.Priority("/readyzz"))
+}
+
+func TestAdmit_Concurrency(t *testing.T) {
+	s := New(Config{MaxInFlight: 4})
+
+	var dones []func()
+	for i := 0; i < 2; i++ {
+		done, _, ok := s.Admit(Low)
+		require.True(t, ok)
+		dones = append(dones, done)
+	}
+	_, reason, ok := s.Admit(Low)
+	assert.False(t, ok, "low requests get half of max_in_flight")
+	assert.Equal(t, ReasonConcurrency, reason)
+
+	for i := 0; i < 2; i++ {
+		done, _, ok := s.Admit(Normal)
+		require.True(t, ok)
+		dones = append(dones, done)
+	}
+	_, _, ok = s.Admit(Normal)
+	assert.False(t, ok)
+
+	for i := 0; i < 4; i++ {
+		done, _, ok := s.Admit(High)
+		require.True(t, ok, "high requests get twice max_in_flight")
+		dones = append(dones, done)
+	}
+	_, _, ok = s.Admit(High)
+	assert.False(t, ok)
+
+	done, _, ok := s.Admit(Critical)
+	assert.True(t, ok, "probes are never shed")
+	done()
+
+	assert.Equal(t, int64(8), s.InFlight(), "shed requests are not counted")
+	for _, done := range dones {
+		done()
+	}
+	assert.Equal(t, int64(0), s.InFlight())
+	_, _, ok = s.Admit(Low)
+	assert.True(t, ok)
+}
+
+func TestAdmit_Memory(t *testing.T) {
+	var heap atomic.Uint64
+	s := New(Config{MemoryLimit: 100, SampleInterval: time.Millisecond})
+	s.readHeap = heap.Load
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Start(ctx)
+
+	heap.Store(150)
+	require.Eventually(t, func() bool { return s.heap.Load() == 150 }, time.Second, time.Millisecond)
+	for _, priority := range []string{Normal, Low} {
+		_, reason, ok := s.Admit(priority)
+		assert.False(t, ok, priority)
+		assert.Equal(t, ReasonMemory, reason)
+	}
+	for _, priority := range []string{Critical, High} {
+		done, _, ok := s.Admit(priority)
+		assert.True(t, ok, priority)
+		done()
+	}
+
+	heap.Store(50)
+	require.Eventually(t, func() bool { return s.heap.Load() == 50 }, time.Second, time.Millisecond)
+	_, _, ok := s.Admit(Low)
+	assert.True(t, ok)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Config{MaxInFlight: 10, Routes: []Route{{Path: "/ws", Priority: Low}}}.Validate())
+	assert.Error(t, Config{MaxInFlight: -1}.Validate())
+	assert.Error(t, Config{MemoryLimit: -1}.Validate())
+	assert.Error(t, Config{Routes: []Route{{Path: "ws", Priority: Low}}}.Validate())
+	assert.ErrorContains(t, Config{Routes: []Route{{Path: "/ws", Priority: "lowest"}}}.Validate(), `invalid priority "lowest"`)
+}
+
+func TestReadHeapBytes(t *testing.T) {
+	assert.Positive(t, readHeapBytes())
+}
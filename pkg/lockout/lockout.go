@@ -0,0 +1,304 @@
+// Package lockout tracks failed authentication attempts, locks out offending clients and
+// principals with exponentially growing durations and raises brute-force alerts
+package lockout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Subject kinds
+const (
+	KindIP        = "ip"
+	KindPrincipal = "principal"
+)
+
+// Event types
+const (
+	EventAuthFailure  = "auth_failure"  // Missing or invalid credentials
+	EventAuthzFailure = "authz_failure" // Authenticated principal denied by role or scope
+	EventLockout      = "lockout"
+	EventBruteForce   = "brute_force" // Failures from many addresses at once
+)
+
+// Config holds lockout and abuse detection settings
+type Config struct {
+	Enabled              bool          `mapstructure:"enabled"`
+	MaxFailures          int           `mapstructure:"max_failures"`          // Failures within the window that trigger a lockout
+	Window               time.Duration `mapstructure:"window"`                // How long failures count towards a lockout
+	BaseDuration         time.Duration `mapstructure:"base_duration"`         // First lockout; each further lockout doubles it
+	MaxDuration          time.Duration `mapstructure:"max_duration"`          // Upper bound for lockout durations
+	ForgetAfter          time.Duration `mapstructure:"forget_after"`          // Quiet period after which durations start from base again
+	DistributedThreshold int           `mapstructure:"distributed_threshold"` // Distinct failing IPs within the window that raise a brute-force alert, 0 disables
+	MaxEvents            int           `mapstructure:"max_events"`            // Security events kept for /admin/security/events
+	AlertWebhookURL      string        `mapstructure:"alert_webhook_url"`     // Slack-compatible webhook for lockouts and brute-force alerts
+}
+
+// Event is a recorded security event
+type Event struct {
+	Time     time.Time  `json:"time"`
+	Type     string     `json:"type"`
+	Kind     string     `json:"kind,omitempty"`
+	Subject  string     `json:"subject,omitempty"`
+	Failures int        `json:"failures,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+	Detail   string     `json:"detail,omitempty"`
+}
+
+// Lockout is an active lockout
+type Lockout struct {
+	Kind    string    `json:"kind"`
+	Subject string    `json:"subject"`
+	Level   int       `json:"level"` // Number of consecutive lockouts; the duration doubles with each
+	Until   time.Time `json:"until"`
+}
+
+type subjectState struct {
+	failures    []time.Time
+	level       int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// Guard tracks failures per IP address and principal
+type Guard struct {
+	config Config
+	client *http.Client
+	now    func() time.Time
+
+	mu             sync.Mutex
+	subjects       map[string]*subjectState
+	events         []Event // Ring buffer of the most recent events
+	nextEvent      int
+	lastBruteForce time.Time
+	lastPrune      time.Time
+}
+
+// New creates a guard with defaults applied to unset settings
+func New(cfg Config) *Guard {
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = 5
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Minute
+	}
+	if cfg.BaseDuration <= 0 {
+		cfg.BaseDuration = time.Minute
+	}
+	if cfg.MaxDuration < cfg.BaseDuration {
+		cfg.MaxDuration = cfg.BaseDuration
+	}
+	if cfg.ForgetAfter <= 0 {
+		cfg.ForgetAfter = 24 * time.Hour
+	}
+	if cfg.MaxEvents <= 0 {
+		cfg.MaxEvents = 1000
+	}
+	return &Guard{
+		config:   cfg,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		now:      time.Now,
+		subjects: make(map[string]*subjectState),
+	}
+}
+
+// Locked returns the remaining lockout time of a subject
+func (g *Guard) Locked(kind, subject string) (time.Duration, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	state := g.subjects[kind+"/"+subject]
+	if state == nil {
+		return 0, false
+	}
+	remaining := state.lockedUntil.Sub(g.now())
+	return remaining, remaining > 0
+}
+
+// RecordFailure counts a failure against a subject and locks it out once MaxFailures
+// failures fall within the window. It returns the lockout end when one started.
+func (g *Guard) RecordFailure(eventType, kind, subject, detail string) (time.Time, bool) {
+	var alerts []string
+
+	g.mu.Lock()
+	now := g.now()
+	g.pruneLocked(now)
+
+	key := kind + "/" + subject
+	state := g.subjects[key]
+	if state == nil {
+		state = &subjectState{}
+		g.subjects[key] = state
+	}
+	if state.level > 0 && now.Sub(state.lastFailure) > g.config.ForgetAfter {
+		state.level = 0
+	}
+	state.failures = append(recent(state.failures, now, g.config.Window), now)
+	state.lastFailure = now
+	g.recordLocked(Event{Time: now, Type: eventType, Kind: kind, Subject: subject, Failures: len(state.failures), Detail: detail})
+
+	var until time.Time
+	locked := false
+	if len(state.failures) >= g.config.MaxFailures && !now.Before(state.lockedUntil) {
+		state.level++
+		until = now.Add(g.duration(state.level))
+		state.lockedUntil = until
+		locked = true
+		g.recordLocked(Event{Time: now, Type: EventLockout, Kind: kind, Subject: subject, Failures: len(state.failures), Until: &until})
+		alerts = append(alerts, fmt.Sprintf("Locked out %s %s until %s after %d failures (%s)",
+			kind, subject, until.UTC().Format(time.RFC3339), len(state.failures), detail))
+		state.failures = nil
+	}
+
+	if kind == KindIP && g.config.DistributedThreshold > 0 && now.Sub(g.lastBruteForce) > g.config.Window {
+		if ips := g.failingIPsLocked(now); ips >= g.config.DistributedThreshold {
+			g.lastBruteForce = now
+			detail := fmt.Sprintf("%d addresses failed authentication within %s", ips, g.config.Window)
+			g.recordLocked(Event{Time: now, Type: EventBruteForce, Failures: ips, Detail: detail})
+			alerts = append(alerts, "Possible distributed brute-force attack: "+detail)
+		}
+	}
+	g.mu.Unlock()
+
+	for _, text := range alerts {
+		g.alert(text)
+	}
+	return until, locked
+}
+
+// Events returns recorded events newest first, optionally filtered by type, up to limit
+func (g *Guard) Events(eventType string, limit int) []Event {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	events := make([]Event, 0, len(g.events))
+	for i := 0; i < len(g.events); i++ {
+		// Walk backwards from the most recently written slot
+		e := g.events[(g.nextEvent-1-i+len(g.events))%len(g.events)]
+		if eventType != "" && e.Type != eventType {
+			continue
+		}
+		events = append(events, e)
+		if limit > 0 && len(events) == limit {
+			break
+		}
+	}
+	return events
+}
+
+// Lockouts returns the active lockouts ordered by end time
+func (g *Guard) Lockouts() []Lockout {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	lockouts := []Lockout{}
+	for key, state := range g.subjects {
+		if !state.lockedUntil.After(now) {
+			continue
+		}
+		kind, subject := splitKey(key)
+		lockouts = append(lockouts, Lockout{Kind: kind, Subject: subject, Level: state.level, Until: state.lockedUntil})
+	}
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].Until.Before(lockouts[j].Until) })
+	return lockouts
+}
+
+// duration is BaseDuration doubled for each lockout after the first, capped at MaxDuration
+func (g *Guard) duration(level int) time.Duration {
+	d := g.config.BaseDuration
+	for i := 1; i < level && d < g.config.MaxDuration; i++ {
+		d *= 2
+	}
+	if d > g.config.MaxDuration {
+		d = g.config.MaxDuration
+	}
+	return d
+}
+
+func (g *Guard) recordLocked(e Event) {
+	if len(g.events) < g.config.MaxEvents {
+		g.events = append(g.events, e)
+		g.nextEvent = len(g.events) % g.config.MaxEvents
+		return
+	}
+	g.events[g.nextEvent] = e
+	g.nextEvent = (g.nextEvent + 1) % g.config.MaxEvents
+}
+
+// failingIPsLocked counts addresses with failures inside the window
+func (g *Guard) failingIPsLocked(now time.Time) int {
+	count := 0
+	for key, state := range g.subjects {
+		if kind, _ := splitKey(key); kind == KindIP && now.Sub(state.lastFailure) <= g.config.Window {
+			count++
+		}
+	}
+	return count
+}
+
+// pruneLocked forgets subjects that are neither locked nor failing, at most once a minute
+func (g *Guard) pruneLocked(now time.Time) {
+	if now.Sub(g.lastPrune) < time.Minute {
+		return
+	}
+	g.lastPrune = now
+	for key, state := range g.subjects {
+		if now.After(state.lockedUntil) && now.Sub(state.lastFailure) > g.config.ForgetAfter {
+			delete(g.subjects, key)
+		}
+	}
+}
+
+// alert logs the message and posts it to the webhook in the background
+func (g *Guard) alert(text string) {
+	log.Warn().Str("alert", text).Msg("Security alert")
+	if g.config.AlertWebhookURL == "" {
+		return
+	}
+
+	go func() {
+		body, _ := json.Marshal(map[string]string{"text": text})
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, g.config.AlertWebhookURL, bytes.NewReader(body))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to build security alert")
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := g.client.Do(req)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to send security alert")
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Error().Int("status", resp.StatusCode).Msg("Security alert webhook rejected the request")
+		}
+	}()
+}
+
+func recent(times []time.Time, now time.Time, window time.Duration) []time.Time {
+	i := 0
+	for i < len(times) && now.Sub(times[i]) > window {
+		i++
+	}
+	return times[i:]
+}
+
+func splitKey(key string) (kind, subject string) {
+	for i := 0; i < len(key); i++ {
+		if key[i] == '/' {
+			return key[:i], key[i+1:]
+		}
+	}
+	return key, ""
+}
@@ -0,0 +1,154 @@
+package lockout
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/internal/testclock"
+)
+
+func newTestGuard(cfg Config) (*Guard, *testclock.Clock) {
+	clock := testclock.New(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	g := New(cfg)
+	g.now = clock.Now
+	return g, clock
+}
+
+func TestRecordFailure_LocksAfterMaxFailures(t *testing.T) {
+	g, clock := newTestGuard(Config{MaxFailures: 3, BaseDuration: time.Minute, MaxDuration: time.Hour})
+
+	for i := 0; i < 2; i++ {
+		_, locked := g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "invalid API key")
+		assert.False(t, locked)
+	}
+	_, locked := g.Locked(KindIP, "10.0.0.1")
+	assert.False(t, locked)
+
+	until, locked := g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "invalid API key")
+	require.True(t, locked)
+	assert.Equal(t, clock.Now().Add(time.Minute), until)
+
+	remaining, locked := g.Locked(KindIP, "10.0.0.1")
+	assert.True(t, locked)
+	assert.Equal(t, time.Minute, remaining)
+
+	// Other subjects are unaffected
+	_, locked = g.Locked(KindIP, "10.0.0.2")
+	assert.False(t, locked)
+	_, locked = g.Locked(KindPrincipal, "10.0.0.1")
+	assert.False(t, locked)
+
+	clock.Advance(time.Minute + time.Second)
+	_, locked = g.Locked(KindIP, "10.0.0.1")
+	assert.False(t, locked)
+}
+
+func TestRecordFailure_WindowExpiresFailures(t *testing.T) {
+	g, clock := newTestGuard(Config{MaxFailures: 2, Window: time.Minute})
+
+	g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "")
+	clock.Advance(2 * time.Minute)
+	_, locked := g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "")
+	assert.False(t, locked, "the first failure fell outside the window")
+}
+
+func TestRecordFailure_ExponentialDuration(t *testing.T) {
+	g, clock := newTestGuard(Config{MaxFailures: 1, BaseDuration: time.Minute, MaxDuration: 5 * time.Minute})
+
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, want := range expected {
+		until, locked := g.RecordFailure(EventAuthzFailure, KindPrincipal, "ci", "POST /clusters")
+		require.True(t, locked, "lockout %d", i+1)
+		assert.Equal(t, want, until.Sub(clock.Now()), "lockout %d", i+1)
+		clock.Advance(want)
+	}
+
+	lockouts := g.Lockouts()
+	require.Len(t, lockouts, 0)
+}
+
+func TestRecordFailure_ForgetAfterResetsDuration(t *testing.T) {
+	g, clock := newTestGuard(Config{MaxFailures: 1, BaseDuration: time.Minute, MaxDuration: time.Hour, ForgetAfter: time.Hour})
+
+	g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "")
+	clock.Advance(time.Minute)
+	until, _ := g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "")
+	assert.Equal(t, 2*time.Minute, until.Sub(clock.Now()))
+
+	clock.Advance(2 * time.Hour)
+	until, _ = g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "")
+	assert.Equal(t, time.Minute, until.Sub(clock.Now()))
+}
+
+func TestRecordFailure_FailuresWhileLockedDoNotExtend(t *testing.T) {
+	g, clock := newTestGuard(Config{MaxFailures: 1, BaseDuration: time.Minute, MaxDuration: time.Hour})
+
+	first, locked := g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "")
+	require.True(t, locked)
+	clock.Advance(10 * time.Second)
+	_, locked = g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "")
+	assert.False(t, locked)
+
+	lockouts := g.Lockouts()
+	require.Len(t, lockouts, 1)
+	assert.Equal(t, first, lockouts[0].Until)
+	assert.Equal(t, 1, lockouts[0].Level)
+}
+
+func TestEvents_NewestFirstAndFiltered(t *testing.T) {
+	g, clock := newTestGuard(Config{MaxFailures: 2, MaxEvents: 3})
+
+	g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "first")
+	clock.Advance(time.Second)
+	g.RecordFailure(EventAuthzFailure, KindPrincipal, "ci", "second")
+	clock.Advance(time.Second)
+	g.RecordFailure(EventAuthFailure, KindIP, "10.0.0.1", "third")
+
+	// The ring keeps the three most recent events: the lockout, third and second
+	events := g.Events("", 0)
+	require.Len(t, events, 3)
+	assert.Equal(t, EventLockout, events[0].Type)
+	require.NotNil(t, events[0].Until)
+	assert.Equal(t, "third", events[1].Detail)
+	assert.Equal(t, "second", events[2].Detail)
+
+	failures := g.Events(EventAuthFailure, 0)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "third", failures[0].Detail)
+
+	assert.Len(t, g.Events("", 1), 1)
+}
+
+func TestRecordFailure_DistributedBruteForceAlert(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body["text"]
+	}))
+	defer server.Close()
+
+	g, _ := newTestGuard(Config{MaxFailures: 10, DistributedThreshold: 3, AlertWebhookURL: server.URL})
+
+	for i := 1; i <= 5; i++ {
+		g.RecordFailure(EventAuthFailure, KindIP, fmt.Sprintf("10.0.0.%d", i), "invalid token")
+	}
+
+	alerts := g.Events(EventBruteForce, 0)
+	require.Len(t, alerts, 1, "one alert per window")
+	assert.Equal(t, 3, alerts[0].Failures)
+
+	select {
+	case text := <-received:
+		assert.Contains(t, text, "brute-force")
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
@@ -0,0 +1,183 @@
+// Package metricsapi reads current resource usage from the metrics.k8s.io API served by
+// metrics-server, without depending on its generated clients
+package metricsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// Paths of the metrics API: the usage of every node, and of the pods of every namespace
+const (
+	NodesPath = "/apis/metrics.k8s.io/v1beta1/nodes"
+	PodsPath  = "/apis/metrics.k8s.io/v1beta1/pods"
+)
+
+// ErrUnavailable is returned when the cluster does not serve the metrics API
+var ErrUnavailable = errors.New("metrics API is not available, is metrics-server installed?")
+
+// NodeUsage is the usage of a node averaged over Window, ending at Timestamp
+type NodeUsage struct {
+	Name        string
+	Timestamp   time.Time
+	Window      time.Duration
+	CPUMilli    int64
+	MemoryBytes int64
+}
+
+// PodUsage is the usage of a pod's containers averaged over Window, ending at Timestamp
+type PodUsage struct {
+	Namespace  string
+	Name       string
+	Labels     map[string]string
+	Timestamp  time.Time
+	Window     time.Duration
+	Containers []ContainerUsage
+}
+
+// ContainerUsage is the usage of one container of a pod
+type ContainerUsage struct {
+	Name        string
+	CPUMilli    int64
+	MemoryBytes int64
+}
+
+// nodeMetricsList is the subset of the metrics.k8s.io NodeMetricsList that is used
+type nodeMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Timestamp metav1.Time       `json:"timestamp"`
+		Window    metav1.Duration   `json:"window"`
+		Usage     map[string]string `json:"usage"`
+	} `json:"items"`
+}
+
+// podMetricsList is the subset of the metrics.k8s.io PodMetricsList that is used
+type podMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string            `json:"name"`
+			Namespace string            `json:"namespace"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Timestamp  metav1.Time     `json:"timestamp"`
+		Window     metav1.Duration `json:"window"`
+		Containers []struct {
+			Name  string            `json:"name"`
+			Usage map[string]string `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// Client reads the metrics API of one cluster
+type Client struct {
+	rest rest.Interface
+}
+
+// New returns a client using a REST client of the cluster, such as its discovery client's
+func New(client rest.Interface) *Client {
+	return &Client{rest: client}
+}
+
+// Nodes returns the current usage of every node that metrics-server has scraped
+func (c *Client) Nodes(ctx context.Context) ([]NodeUsage, error) {
+	raw, err := c.rest.Get().AbsPath(NodesPath).DoRaw(ctx)
+	if apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err) {
+		return nil, ErrUnavailable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node metrics: %w", err)
+	}
+
+	var list nodeMetricsList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode node metrics: %w", err)
+	}
+	nodes := make([]NodeUsage, 0, len(list.Items))
+	for _, item := range list.Items {
+		cpu, cpuErr := resource.ParseQuantity(item.Usage["cpu"])
+		memory, memErr := resource.ParseQuantity(item.Usage["memory"])
+		if cpuErr != nil || memErr != nil {
+			continue
+		}
+		nodes = append(nodes, NodeUsage{
+			Name:        item.Metadata.Name,
+			Timestamp:   item.Timestamp.UTC(),
+			Window:      item.Window.Duration,
+			CPUMilli:    cpu.MilliValue(),
+			MemoryBytes: memory.Value(),
+		})
+	}
+	return nodes, nil
+}
+
+// Pods returns the current usage of the pods of a namespace, or of every namespace when it
+// is empty, optionally limited by a label selector. Containers with unparsable usage are
+// left out.
+func (c *Client) Pods(ctx context.Context, namespace, labelSelector string) ([]PodUsage, error) {
+	path := PodsPath
+	if namespace != "" {
+		path = "/apis/metrics.k8s.io/v1beta1/namespaces/" + namespace + "/pods"
+	}
+	req := c.rest.Get().AbsPath(path)
+	if labelSelector != "" {
+		req = req.Param("labelSelector", labelSelector)
+	}
+	raw, err := req.DoRaw(ctx)
+	if apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err) {
+		return nil, ErrUnavailable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pod metrics: %w", err)
+	}
+
+	var list podMetricsList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode pod metrics: %w", err)
+	}
+	pods := make([]PodUsage, 0, len(list.Items))
+	for _, item := range list.Items {
+		pod := PodUsage{
+			Namespace:  item.Metadata.Namespace,
+			Name:       item.Metadata.Name,
+			Labels:     item.Metadata.Labels,
+			Timestamp:  item.Timestamp.UTC(),
+			Window:     item.Window.Duration,
+			Containers: make([]ContainerUsage, 0, len(item.Containers)),
+		}
+		for _, container := range item.Containers {
+			cpu, cpuErr := resource.ParseQuantity(container.Usage["cpu"])
+			memory, memErr := resource.ParseQuantity(container.Usage["memory"])
+			if cpuErr != nil || memErr != nil {
+				continue
+			}
+			pod.Containers = append(pod.Containers, ContainerUsage{
+				Name:        container.Name,
+				CPUMilli:    cpu.MilliValue(),
+				MemoryBytes: memory.Value(),
+			})
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// Percent returns used as a percentage of available, rounded to two decimals, or 0 when
+// nothing is available
+func Percent(used, available int64) float64 {
+	if available <= 0 {
+		return 0
+	}
+	return math.Round(float64(used)/float64(available)*10000) / 100
+}
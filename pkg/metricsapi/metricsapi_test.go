@@ -0,0 +1,101 @@
+package metricsapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func newClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	require.NoError(t, err)
+	return New(clientset.Discovery().RESTClient())
+}
+
+func TestNodes(t *testing.T) {
+	client := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, NodesPath, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"NodeMetricsList","items":[
+			{"metadata":{"name":"worker-1"},"timestamp":"2024-05-01T10:00:00Z","window":"20.05s","usage":{"cpu":"1250m","memory":"2Gi"}},
+			{"metadata":{"name":"worker-2"},"timestamp":"2024-05-01T10:00:00Z","window":"20s","usage":{"cpu":"314159n","memory":"512Ki"}},
+			{"metadata":{"name":"broken"},"usage":{"cpu":"lots"}}
+		]}`))
+	})
+
+	nodes, err := client.Nodes(context.Background())
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, NodeUsage{
+		Name:        "worker-1",
+		Timestamp:   time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Window:      20050 * time.Millisecond,
+		CPUMilli:    1250,
+		MemoryBytes: 2 << 30,
+	}, nodes[0])
+	assert.Equal(t, int64(1), nodes[1].CPUMilli)
+	assert.Equal(t, int64(512<<10), nodes[1].MemoryBytes)
+}
+
+func TestNodes_Unavailable(t *testing.T) {
+	client := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+	})
+	_, err := client.Nodes(context.Background())
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestPods(t *testing.T) {
+	client := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/apis/metrics.k8s.io/v1beta1/namespaces/shop/pods", r.URL.Path)
+		assert.Equal(t, "app=web", r.URL.Query().Get("labelSelector"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"PodMetricsList","items":[
+			{"metadata":{"name":"web-1","namespace":"shop","labels":{"app":"web"}},"timestamp":"2024-05-01T10:00:00Z","window":"15s","containers":[
+				{"name":"web","usage":{"cpu":"250m","memory":"128Mi"}},
+				{"name":"proxy","usage":{"cpu":"12m","memory":"bad"}}
+			]}
+		]}`))
+	})
+
+	pods, err := client.Pods(context.Background(), "shop", "app=web")
+	require.NoError(t, err)
+	require.Len(t, pods, 1)
+	assert.Equal(t, PodUsage{
+		Namespace:  "shop",
+		Name:       "web-1",
+		Labels:     map[string]string{"app": "web"},
+		Timestamp:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Window:     15 * time.Second,
+		Containers: []ContainerUsage{{Name: "web", CPUMilli: 250, MemoryBytes: 128 << 20}},
+	}, pods[0])
+}
+
+func TestPods_AllNamespaces(t *testing.T) {
+	client := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, PodsPath, r.URL.Path)
+		assert.Empty(t, r.URL.Query().Get("labelSelector"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"PodMetricsList","items":[]}`))
+	})
+	pods, err := client.Pods(context.Background(), "", "")
+	require.NoError(t, err)
+	assert.Empty(t, pods)
+}
+
+func TestPercent(t *testing.T) {
+	assert.Equal(t, 33.33, Percent(1, 3))
+	assert.Equal(t, 0.0, Percent(5, 0))
+}
@@ -0,0 +1,120 @@
+// Package middleware composes fasthttp handlers into an ordered pipeline of named stages,
+// so middleware can be inserted relative to the API server's built-in stages
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Names of the API server's built-in stages, outermost first. A stage sees the request
+// before every stage after it and the response after them.
+const (
+	RequestID       = "request-id"       // Assigns X-Request-ID and matches the route
+	Metrics         = "metrics"          // Request count, latency and in-flight metrics
+	Tracing         = "tracing"          // Server span, continuing an incoming traceparent
+	Audit           = "audit"            // Audit log entry of mutating requests
+	DebugCapture    = "debug-capture"    // Full exchanges matching an admin's capture
+	Logging         = "logging"          // Request received and completed log lines
+	Recovery        = "recovery"         // Turns handler panics into 500 responses
+	LoadShedding    = "load-shedding"    // Sheds low-priority requests under overload
+	RateLimit       = "rate-limit"       // Global, per-IP and per-route rate limits
+	SecurityHeaders = "security-headers" // CSP, HSTS and related headers
+	CORS            = "cors"             // Cross-origin headers and preflights
+	InboundWebhooks = "inbound-webhooks" // Signature checks of inbound webhooks
+	CSRF            = "csrf"             // Double-submit CSRF checks
+	Auth            = "auth"             // Authentication and authorization
+	Freeze          = "freeze"           // Refuses changes during freeze windows
+	Compression     = "compression"      // Compresses large responses
+	Format          = "format"           // YAML output and time zone rendering
+	Timeout         = "timeout"          // Per-route request deadlines
+)
+
+// Middleware wraps a handler, running code before and after it or answering the request
+// itself without calling next
+type Middleware func(next fasthttp.RequestHandler) fasthttp.RequestHandler
+
+// Stage is a named middleware in a chain
+type Stage struct {
+	Name       string
+	Middleware Middleware
+}
+
+// Chain is an ordered list of stages, outermost first
+type Chain struct {
+	stages []Stage
+}
+
+// New creates a chain of the stages in order. Stage names must be unique.
+func New(stages ...Stage) (*Chain, error) {
+	c := &Chain{}
+	for _, stage := range stages {
+		if err := c.Append(stage); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Append adds a stage after every other, closest to the handler
+func (c *Chain) Append(stage Stage) error {
+	return c.insert(len(c.stages), stage)
+}
+
+// InsertBefore adds a stage right before the named one, so it sees requests first
+func (c *Chain) InsertBefore(name string, stage Stage) error {
+	i, err := c.index(name)
+	if err != nil {
+		return err
+	}
+	return c.insert(i, stage)
+}
+
+// InsertAfter adds a stage right after the named one
+func (c *Chain) InsertAfter(name string, stage Stage) error {
+	i, err := c.index(name)
+	if err != nil {
+		return err
+	}
+	return c.insert(i+1, stage)
+}
+
+// Names returns the stage names, outermost first
+func (c *Chain) Names() []string {
+	names := make([]string, len(c.stages))
+	for i, stage := range c.stages {
+		names[i] = stage.Name
+	}
+	return names
+}
+
+// Then wraps the handler in every stage and returns the resulting handler
+func (c *Chain) Then(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	for i := len(c.stages) - 1; i >= 0; i-- {
+		handler = c.stages[i].Middleware(handler)
+	}
+	return handler
+}
+
+func (c *Chain) index(name string) (int, error) {
+	for i, stage := range c.stages {
+		if stage.Name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no middleware stage named %q, expected one of %v", name, c.Names())
+}
+
+func (c *Chain) insert(i int, stage Stage) error {
+	if stage.Name == "" || stage.Middleware == nil {
+		return errors.New("middleware stage needs a name and a middleware")
+	}
+	if _, err := c.index(stage.Name); err == nil {
+		return fmt.Errorf("middleware stage %q is already in the chain", stage.Name)
+	}
+	c.stages = slices.Insert(c.stages, i, stage)
+	return nil
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// recording returns a stage noting when it runs before and after the handler
+func recording(name string, trace *[]string) Stage {
+	return Stage{Name: name, Middleware: func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			*trace = append(*trace, name)
+			next(ctx)
+			*trace = append(*trace, "/"+name)
+		}
+	}}
+}
+
+func TestChain_Then(t *testing.T) {
+	var trace []string
+	chain, err := New(recording("outer", &trace), recording("inner", &trace))
+	require.NoError(t, err)
+
+	chain.Then(func(ctx *fasthttp.RequestCtx) { trace = append(trace, "handler") })(&fasthttp.RequestCtx{})
+	assert.Equal(t, []string{"outer", "inner", "handler", "/inner", "/outer"}, trace)
+}
+
+func TestChain_Insert(t *testing.T) {
+	var trace []string
+	chain, err := New(recording(RequestID, &trace), recording(Auth, &trace), recording(Timeout, &trace))
+	require.NoError(t, err)
+
+	require.NoError(t, chain.InsertBefore(Auth, recording("tenant", &trace)))
+	require.NoError(t, chain.InsertAfter(Auth, recording("quota", &trace)))
+	require.NoError(t, chain.Append(recording("last", &trace)))
+	assert.Equal(t, []string{RequestID, "tenant", Auth, "quota", Timeout, "last"}, chain.Names())
+
+	assert.ErrorContains(t, chain.InsertBefore("missing", recording("x", &trace)), `no middleware stage named "missing"`)
+	assert.ErrorContains(t, chain.Append(recording(Auth, &trace)), "already in the chain")
+	assert.Error(t, chain.Append(Stage{Name: "nil"}))
+
+	_, err = New(recording("a", &trace), recording("a", &trace))
+	assert.Error(t, err)
+}
+
+func TestChain_StageCanAnswer(t *testing.T) {
+	deny := Stage{Name: "deny", Middleware: func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.SetStatusCode(fasthttp.StatusForbidden)
+		}
+	}}
+	chain, err := New(deny)
+	require.NoError(t, err)
+
+	called := false
+	ctx := &fasthttp.RequestCtx{}
+	chain.Then(func(ctx *fasthttp.RequestCtx) { called = true })(ctx)
+	assert.False(t, called)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+}
@@ -0,0 +1,602 @@
+// Package migrate moves a deployment with the services and configmaps it uses from one
+// cluster to another as an asynchronous job, and mirrors whole namespaces into a warm
+// standby cluster that is activated on failover
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// Annotations recording a migration on the target and source deployments
+const (
+	MigratedFromAnnotation = "migrations.k8s-custom-controller.io/migrated-from" // Source cluster, set on the target deployment
+	MigratedToAnnotation   = "migrations.k8s-custom-controller.io/migrated-to"   // Target cluster, set on the scaled-down source deployment
+)
+
+// Annotations belonging to the source object that are not copied to the target
+var droppedAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"deployment.kubernetes.io/revision",
+	MigratedFromAnnotation,
+	MigratedToAnnotation,
+	StandbySourceAnnotation,
+	StandbyReplicasAnnotation,
+}
+
+// States of jobs and their steps
+const (
+	StatePending   = "pending"
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	StateSkipped   = "skipped" // Not requested, or not run because an earlier step failed
+)
+
+// Steps of a migration, in order
+const (
+	StepExport    = "export"            // Read the deployment and the services and configmaps it uses
+	StepApply     = "apply"             // Create or update them on the target cluster
+	StepWaitReady = "wait_ready"        // Wait until the target deployment has rolled out
+	StepScaleDown = "scale_down_source" // Scale the source deployment to zero replicas
+)
+
+// Actions taken on target objects
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+)
+
+// Config holds migration settings
+type Config struct {
+	ReadyTimeout time.Duration `mapstructure:"ready_timeout"` // How long to wait for the target deployment to become available
+	MaxJobs      int           `mapstructure:"max_jobs"`      // Finished jobs kept for GET /migrate, oldest dropped first
+}
+
+// Request names the deployment to migrate and the clusters involved
+type Request struct {
+	Source          string `json:"source"`     // Cluster the deployment is exported from
+	Target          string `json:"target"`     // Cluster it is applied to
+	Namespace       string `json:"namespace"`  // Namespace of the deployment, created on the target when missing
+	Deployment      string `json:"deployment"` // Deployment name
+	ScaleDownSource bool   `json:"scale_down_source,omitempty"`
+}
+
+// Validate checks that the clusters differ and the names are valid
+func (r Request) Validate() error {
+	if r.Source == "" || r.Target == "" {
+		return fmt.Errorf("source and target clusters are required")
+	}
+	if r.Source == r.Target {
+		return fmt.Errorf("source and target clusters must differ")
+	}
+	if errs := validation.IsDNS1123Label(r.Namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace %q: %s", r.Namespace, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsDNS1123Subdomain(r.Deployment); len(errs) > 0 {
+		return fmt.Errorf("invalid deployment name %q: %s", r.Deployment, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Step is the progress of one step of a job
+type Step struct {
+	Name       string     `json:"name"`
+	State      string     `json:"state"`
+	Message    string     `json:"message,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Object is an object applied to the target cluster
+type Object struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"` // created or updated
+}
+
+// Job is a migration and its progress
+type Job struct {
+	ID string `json:"id"`
+	Request
+	State      string     `json:"state"`
+	Steps      []Step     `json:"steps"`
+	Objects    []Object   `json:"objects"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Manager runs migration jobs and keeps the recent ones
+type Manager struct {
+	config       Config
+	ctx          context.Context
+	now          func() time.Time
+	pollInterval time.Duration
+
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	order []string // Job IDs, oldest first
+}
+
+// NewManager creates a manager with defaults applied to unset settings. Running jobs fail
+// once ctx is canceled.
+func NewManager(ctx context.Context, cfg Config) *Manager {
+	if cfg.ReadyTimeout <= 0 {
+		cfg.ReadyTimeout = 5 * time.Minute
+	}
+	if cfg.MaxJobs <= 0 {
+		cfg.MaxJobs = 100
+	}
+	return &Manager{
+		config:       cfg,
+		ctx:          ctx,
+		now:          time.Now,
+		pollInterval: 2 * time.Second,
+		jobs:         make(map[string]*Job),
+	}
+}
+
+// Start runs a migration in the background and returns the job as started. The request
+// must be valid.
+func (m *Manager) Start(req Request, source, target kubernetes.Interface) Job {
+	job := &Job{
+		ID:        uuid.NewString(),
+		Request:   req,
+		State:     StatePending,
+		Objects:   []Object{},
+		CreatedAt: m.now(),
+	}
+	for _, name := range []string{StepExport, StepApply, StepWaitReady, StepScaleDown} {
+		job.Steps = append(job.Steps, Step{Name: name, State: StatePending})
+	}
+
+	m.mu.Lock()
+	m.jobs[job.ID] = job
+	m.order = append(m.order, job.ID)
+	m.prune()
+	snapshot := job.snapshot()
+	m.mu.Unlock()
+
+	go m.run(job, source, target)
+	return snapshot
+}
+
+// Get returns a job by ID
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.snapshot(), true
+}
+
+// List returns the kept jobs, newest first
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]Job, 0, len(m.order))
+	for i := len(m.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, m.jobs[m.order[i]].snapshot())
+	}
+	return jobs
+}
+
+// prune drops the oldest finished jobs beyond max_jobs; running jobs are always kept. The
+// caller holds m.mu.
+func (m *Manager) prune() {
+	excess := len(m.order) - m.config.MaxJobs
+	kept := m.order[:0]
+	for _, id := range m.order {
+		if excess > 0 && m.jobs[id].FinishedAt != nil {
+			delete(m.jobs, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	m.order = kept
+}
+
+// snapshot copies a job so it can be read while the migration goes on. The caller holds m.mu.
+func (j *Job) snapshot() Job {
+	c := *j
+	c.Steps = append([]Step(nil), j.Steps...)
+	c.Objects = append([]Object{}, j.Objects...)
+	return c
+}
+
+// run performs the steps of a job, skipping the remaining ones after a failure
+func (m *Manager) run(job *Job, source, target kubernetes.Interface) {
+	req := job.Request
+	logger := log.With().
+		Str("job", job.ID).
+		Str("source", req.Source).
+		Str("target", req.Target).
+		Str("namespace", req.Namespace).
+		Str("deployment", req.Deployment).
+		Logger()
+	logger.Info().Bool("scale_down_source", req.ScaleDownSource).Msg("Migration started")
+	m.update(job, func() { job.State = StateRunning })
+
+	var w *workload
+	steps := []func() (string, error){
+		func() (string, error) {
+			var err error
+			if w, err = export(m.ctx, source, req.Namespace, req.Deployment); err != nil {
+				return "", err
+			}
+			return w.summary(), nil
+		},
+		func() (string, error) {
+			return "", w.apply(m.ctx, target, func(d *appsv1.Deployment) {
+				d.Annotations[MigratedFromAnnotation] = req.Source
+			}, func(o Object) {
+				m.update(job, func() { job.Objects = append(job.Objects, o) })
+			})
+		},
+		func() (string, error) {
+			return m.waitReady(target, req.Namespace, req.Deployment)
+		},
+		func() (string, error) {
+			if !req.ScaleDownSource {
+				return "", errSkipped
+			}
+			return "", scaleDown(m.ctx, source, req.Namespace, req.Deployment, req.Target)
+		},
+	}
+
+	var failure error
+	for i, step := range steps {
+		if failure != nil {
+			m.update(job, func() { job.Steps[i].State = StateSkipped })
+			continue
+		}
+		startedAt := m.now()
+		m.update(job, func() {
+			job.Steps[i].State = StateRunning
+			job.Steps[i].StartedAt = &startedAt
+		})
+		message, err := step()
+		finishedAt := m.now()
+		m.update(job, func() {
+			s := &job.Steps[i]
+			s.FinishedAt = &finishedAt
+			switch {
+			case errors.Is(err, errSkipped):
+				s.State, s.Message = StateSkipped, "not requested"
+			case err != nil:
+				s.State, s.Message = StateFailed, err.Error()
+			default:
+				s.State, s.Message = StateSucceeded, message
+			}
+		})
+		if err != nil && !errors.Is(err, errSkipped) {
+			failure = fmt.Errorf("%s: %w", job.Steps[i].Name, err)
+		}
+	}
+
+	finishedAt := m.now()
+	m.update(job, func() {
+		job.FinishedAt = &finishedAt
+		job.State = StateSucceeded
+		if failure != nil {
+			job.State, job.Error = StateFailed, failure.Error()
+		}
+	})
+	if failure != nil {
+		logger.Error().Err(failure).Msg("Migration failed")
+		return
+	}
+	logger.Info().Dur("duration", finishedAt.Sub(job.CreatedAt)).Msg("Migration succeeded")
+}
+
+// errSkipped marks a step that was not requested
+var errSkipped = errors.New("skipped")
+
+// update changes a job under the manager's lock
+func (m *Manager) update(job *Job, change func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	change()
+}
+
+// waitReady polls the target deployment until its rollout is done or ready_timeout passes
+func (m *Manager) waitReady(client kubernetes.Interface, namespace, name string) (string, error) {
+	var last *appsv1.Deployment
+	err := wait.PollUntilContextTimeout(m.ctx, m.pollInterval, m.config.ReadyTimeout, true, func(ctx context.Context) (bool, error) {
+		d, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		last = d
+		return deploymentAvailable(d), nil
+	})
+	if err != nil && last != nil && wait.Interrupted(err) && m.ctx.Err() == nil {
+		return "", fmt.Errorf("not available after %s: %d/%d replicas updated, %d available",
+			m.config.ReadyTimeout, last.Status.UpdatedReplicas, desiredReplicas(last), last.Status.AvailableReplicas)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d replicas available", last.Status.AvailableReplicas), nil
+}
+
+// deploymentAvailable reports whether the controller has observed the latest spec and all
+// desired replicas are updated and available
+func deploymentAvailable(d *appsv1.Deployment) bool {
+	desired := desiredReplicas(d)
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas >= desired &&
+		d.Status.AvailableReplicas >= desired
+}
+
+func desiredReplicas(d *appsv1.Deployment) int32 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+// scaleDown sets the source deployment to zero replicas and records where it went
+func scaleDown(ctx context.Context, client kubernetes.Interface, namespace, name, target string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		d, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		zero := int32(0)
+		d.Spec.Replicas = &zero
+		if d.Annotations == nil {
+			d.Annotations = map[string]string{}
+		}
+		d.Annotations[MigratedToAnnotation] = target
+		_, err = client.AppsV1().Deployments(namespace).Update(ctx, d, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// workload is a deployment with the services selecting its pods and the configmaps it uses
+type workload struct {
+	Deployment *appsv1.Deployment
+	Services   []corev1.Service
+	ConfigMaps []corev1.ConfigMap
+}
+
+// summary describes what was exported
+func (w *workload) summary() string {
+	return fmt.Sprintf("deployment with %d services and %d configmaps", len(w.Services), len(w.ConfigMaps))
+}
+
+// export reads a deployment, the services whose selectors match its pod template and the
+// configmaps its pods reference. Missing optional configmaps are left out; a missing
+// required one fails the export.
+func export(ctx context.Context, client kubernetes.Interface, namespace, name string) (*workload, error) {
+	deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	services, err := client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing services: %w", err)
+	}
+	return collect(ctx, client, deployment, services.Items)
+}
+
+// collect builds the workload of a deployment from the services of its namespace, reading
+// the configmaps its pods reference
+func collect(ctx context.Context, client kubernetes.Interface, deployment *appsv1.Deployment, services []corev1.Service) (*workload, error) {
+	w := &workload{Deployment: deployment}
+	namespace := deployment.Namespace
+	podLabels := labels.Set(deployment.Spec.Template.Labels)
+	for _, svc := range services {
+		if len(svc.Spec.Selector) > 0 && labels.SelectorFromSet(svc.Spec.Selector).Matches(podLabels) {
+			w.Services = append(w.Services, svc)
+		}
+	}
+
+	refs := configMapRefs(&deployment.Spec.Template.Spec)
+	names := make([]string, 0, len(refs))
+	for cmName := range refs {
+		names = append(names, cmName)
+	}
+	sort.Strings(names)
+	for _, cmName := range names {
+		cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, cmName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) && refs[cmName] {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("configmap %s: %w", cmName, err)
+		}
+		w.ConfigMaps = append(w.ConfigMaps, *cm)
+	}
+	return w, nil
+}
+
+// configMapRefs returns the configmaps a pod spec references, mapped to whether every
+// reference to them is optional
+func configMapRefs(spec *corev1.PodSpec) map[string]bool {
+	refs := map[string]bool{}
+	add := func(name string, optional *bool) {
+		isOptional := optional != nil && *optional
+		if prev, ok := refs[name]; ok {
+			isOptional = prev && isOptional
+		}
+		refs[name] = isOptional
+	}
+	for _, v := range spec.Volumes {
+		if v.ConfigMap != nil {
+			add(v.ConfigMap.Name, v.ConfigMap.Optional)
+		}
+		if v.Projected != nil {
+			for _, s := range v.Projected.Sources {
+				if s.ConfigMap != nil {
+					add(s.ConfigMap.Name, s.ConfigMap.Optional)
+				}
+			}
+		}
+	}
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for _, c := range containers {
+			for _, from := range c.EnvFrom {
+				if from.ConfigMapRef != nil {
+					add(from.ConfigMapRef.Name, from.ConfigMapRef.Optional)
+				}
+			}
+			for _, env := range c.Env {
+				if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+					add(env.ValueFrom.ConfigMapKeyRef.Name, env.ValueFrom.ConfigMapKeyRef.Optional)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// ensureNamespace creates a namespace on the target cluster when it is missing and reports
+// whether it did
+func ensureNamespace(ctx context.Context, client kubernetes.Interface, namespace string) (bool, error) {
+	_, err := client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	_, err = client.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// apply creates or updates the workload on the target cluster, configmaps and services
+// before the deployment so its pods start with what they need. mutate adjusts the target
+// deployment before it is written, and applied is called for every object written.
+func (w *workload) apply(ctx context.Context, client kubernetes.Interface, mutate func(*appsv1.Deployment), applied func(Object)) error {
+	namespace := w.Deployment.Namespace
+	created, err := ensureNamespace(ctx, client, namespace)
+	if err != nil {
+		return fmt.Errorf("namespace %s: %w", namespace, err)
+	}
+	if created {
+		applied(Object{Kind: "Namespace", Name: namespace, Action: ActionCreated})
+	}
+
+	for i := range w.ConfigMaps {
+		src := &w.ConfigMaps[i]
+		cm := &corev1.ConfigMap{ObjectMeta: objectMeta(src.ObjectMeta), Data: src.Data, BinaryData: src.BinaryData, Immutable: src.Immutable}
+		action, err := createOrUpdate(ctx, cm, client.CoreV1().ConfigMaps(namespace), func(existing *corev1.ConfigMap) {
+			cm.ResourceVersion = existing.ResourceVersion
+		})
+		if err != nil {
+			return fmt.Errorf("configmap %s: %w", cm.Name, err)
+		}
+		applied(Object{Kind: "ConfigMap", Name: cm.Name, Action: action})
+	}
+
+	for i := range w.Services {
+		svc := targetService(&w.Services[i])
+		action, err := createOrUpdate(ctx, svc, client.CoreV1().Services(namespace), func(existing *corev1.Service) {
+			// The cluster IPs of a service are immutable
+			svc.ResourceVersion = existing.ResourceVersion
+			svc.Spec.ClusterIP = existing.Spec.ClusterIP
+			svc.Spec.ClusterIPs = existing.Spec.ClusterIPs
+			svc.Spec.IPFamilies = existing.Spec.IPFamilies
+			svc.Spec.IPFamilyPolicy = existing.Spec.IPFamilyPolicy
+		})
+		if err != nil {
+			return fmt.Errorf("service %s: %w", svc.Name, err)
+		}
+		applied(Object{Kind: "Service", Name: svc.Name, Action: action})
+	}
+
+	deployment := &appsv1.Deployment{ObjectMeta: objectMeta(w.Deployment.ObjectMeta), Spec: *w.Deployment.Spec.DeepCopy()}
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	mutate(deployment)
+	action, err := createOrUpdate(ctx, deployment, client.AppsV1().Deployments(namespace), func(existing *appsv1.Deployment) {
+		deployment.ResourceVersion = existing.ResourceVersion
+	})
+	if err != nil {
+		return fmt.Errorf("deployment %s: %w", deployment.Name, err)
+	}
+	applied(Object{Kind: "Deployment", Name: deployment.Name, Action: action})
+	return nil
+}
+
+// typedClient is the part of a typed namespaced client createOrUpdate uses
+type typedClient[T any] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	Create(ctx context.Context, obj T, opts metav1.CreateOptions) (T, error)
+	Update(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error)
+}
+
+// createOrUpdate creates obj, or updates it when it exists after merge copies what must
+// be kept from the existing object
+func createOrUpdate[T metav1.Object](ctx context.Context, obj T, client typedClient[T], merge func(existing T)) (string, error) {
+	existing, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+		return ActionCreated, err
+	}
+	if err != nil {
+		return "", err
+	}
+	merge(existing)
+	_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+	return ActionUpdated, err
+}
+
+// objectMeta copies the name, namespace, labels and annotations of a source object, leaving
+// out what the source cluster set
+func objectMeta(src metav1.ObjectMeta) metav1.ObjectMeta {
+	var annotations map[string]string
+	for k, v := range src.Annotations {
+		if slices.Contains(droppedAnnotations, k) {
+			continue
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[k] = v
+	}
+	return metav1.ObjectMeta{Name: src.Name, Namespace: src.Namespace, Labels: src.Labels, Annotations: annotations}
+}
+
+// targetService copies a service without the cluster IPs, IP families and node ports
+// allocated by the source cluster; the target allocates its own
+func targetService(src *corev1.Service) *corev1.Service {
+	spec := *src.Spec.DeepCopy()
+	if spec.ClusterIP != corev1.ClusterIPNone {
+		spec.ClusterIP = ""
+	}
+	spec.ClusterIPs = nil
+	spec.IPFamilies = nil
+	spec.IPFamilyPolicy = nil
+	spec.HealthCheckNodePort = 0
+	for i := range spec.Ports {
+		spec.Ports[i].NodePort = 0
+	}
+	return &corev1.Service{ObjectMeta: objectMeta(src.ObjectMeta), Spec: spec}
+}
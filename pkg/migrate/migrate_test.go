@@ -0,0 +1,259 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func boolPtr(b bool) *bool { return &b }
+
+// sourceObjects returns a deployment using two configmaps, one of them optional and
+// missing, with a service selecting its pods and one that does not
+func sourceObjects() []runtime.Object {
+	return []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "web", Namespace: "shop", UID: "src-uid", ResourceVersion: "42", Generation: 7,
+				Annotations: map[string]string{"deployment.kubernetes.io/revision": "7", "team": "checkout"},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: int32Ptr(3),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web", "tier": "frontend"}},
+					Spec: corev1.PodSpec{
+						Volumes: []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}},
+						}}},
+						Containers: []corev1.Container{{
+							Name:  "web",
+							Image: "registry/web:1.2",
+							EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "web-flags"}, Optional: boolPtr(true),
+							}}},
+						}},
+					},
+				},
+			},
+			Status: appsv1.DeploymentStatus{ObservedGeneration: 7, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3},
+		},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "web-config", Namespace: "shop", ResourceVersion: "5"}, Data: map[string]string{"port": "8080"}},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", ResourceVersion: "9"},
+			Spec: corev1.ServiceSpec{
+				Type:       corev1.ServiceTypeNodePort,
+				Selector:   map[string]string{"app": "web"},
+				ClusterIP:  "10.0.0.12",
+				ClusterIPs: []string{"10.0.0.12"},
+				Ports:      []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "api"}},
+		},
+	}
+}
+
+// rollsOut makes deployments written to a fake clientset report themselves available, as
+// the deployment controller of a real cluster would
+func rollsOut(client *fake.Clientset) {
+	ready := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		d := action.(k8stesting.CreateAction).GetObject().(*appsv1.Deployment)
+		replicas := desiredReplicas(d)
+		d.Status = appsv1.DeploymentStatus{ObservedGeneration: d.Generation, Replicas: replicas, UpdatedReplicas: replicas, AvailableReplicas: replicas}
+		return false, nil, nil
+	}
+	client.PrependReactor("create", "deployments", ready)
+	client.PrependReactor("update", "deployments", ready)
+}
+
+func newTestManager(cfg Config) *Manager {
+	m := NewManager(context.Background(), cfg)
+	m.pollInterval = time.Millisecond
+	return m
+}
+
+// waitFinished waits for a job to finish and returns it
+func waitFinished(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	var job Job
+	require.Eventually(t, func() bool {
+		job, _ = m.Get(id)
+		return job.FinishedAt != nil
+	}, 5*time.Second, time.Millisecond)
+	return job
+}
+
+func stepStates(job Job) map[string]string {
+	states := map[string]string{}
+	for _, s := range job.Steps {
+		states[s.Name] = s.State
+	}
+	return states
+}
+
+func TestExport(t *testing.T) {
+	w, err := export(context.Background(), fake.NewSimpleClientset(sourceObjects()...), "shop", "web")
+	require.NoError(t, err)
+	assert.Equal(t, "web", w.Deployment.Name)
+	require.Len(t, w.Services, 1)
+	assert.Equal(t, "web", w.Services[0].Name)
+	require.Len(t, w.ConfigMaps, 1, "the missing optional configmap is left out")
+	assert.Equal(t, "web-config", w.ConfigMaps[0].Name)
+
+	objects := sourceObjects()
+	objects[0].(*appsv1.Deployment).Spec.Template.Spec.Containers[0].EnvFrom[0].ConfigMapRef.Optional = nil
+	_, err = export(context.Background(), fake.NewSimpleClientset(objects...), "shop", "web")
+	assert.ErrorContains(t, err, "configmap web-flags")
+}
+
+func TestConfigMapRefs(t *testing.T) {
+	spec := &corev1.PodSpec{
+		Volumes: []corev1.Volume{{VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+			Sources: []corev1.VolumeProjection{{ConfigMap: &corev1.ConfigMapProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "shared"}, Optional: boolPtr(true),
+			}}},
+		}}}},
+		InitContainers: []corev1.Container{{Env: []corev1.EnvVar{{Name: "MODE", ValueFrom: &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "shared"}, Key: "mode"},
+		}}}}},
+		Containers: []corev1.Container{{Env: []corev1.EnvVar{{Name: "LEVEL", ValueFrom: &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "logging"}, Key: "level", Optional: boolPtr(true)},
+		}}}}},
+	}
+	assert.Equal(t, map[string]bool{"shared": false, "logging": true}, configMapRefs(spec), "required once is required")
+}
+
+func TestManager_Migrates(t *testing.T) {
+	source := fake.NewSimpleClientset(sourceObjects()...)
+	target := fake.NewSimpleClientset()
+	rollsOut(target)
+	m := newTestManager(Config{})
+
+	started := m.Start(Request{Source: "eu", Target: "us", Namespace: "shop", Deployment: "web", ScaleDownSource: true}, source, target)
+	assert.Equal(t, StatePending, started.State)
+	job := waitFinished(t, m, started.ID)
+
+	assert.Equal(t, StateSucceeded, job.State, job.Error)
+	assert.Equal(t, map[string]string{
+		StepExport:    StateSucceeded,
+		StepApply:     StateSucceeded,
+		StepWaitReady: StateSucceeded,
+		StepScaleDown: StateSucceeded,
+	}, stepStates(job))
+	assert.Equal(t, []Object{
+		{Kind: "Namespace", Name: "shop", Action: ActionCreated},
+		{Kind: "ConfigMap", Name: "web-config", Action: ActionCreated},
+		{Kind: "Service", Name: "web", Action: ActionCreated},
+		{Kind: "Deployment", Name: "web", Action: ActionCreated},
+	}, job.Objects)
+
+	ctx := context.Background()
+	migrated, err := target.AppsV1().Deployments("shop").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, migrated.UID)
+	assert.Equal(t, int32(3), *migrated.Spec.Replicas)
+	assert.Equal(t, map[string]string{"team": "checkout", MigratedFromAnnotation: "eu"}, migrated.Annotations)
+
+	svc, err := target.CoreV1().Services("shop").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, svc.Spec.ClusterIP)
+	assert.Zero(t, svc.Spec.Ports[0].NodePort)
+	_, err = target.CoreV1().Services("shop").Get(ctx, "api", metav1.GetOptions{})
+	assert.Error(t, err, "services of other pods are not migrated")
+
+	original, err := source.AppsV1().Deployments("shop").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), *original.Spec.Replicas)
+	assert.Equal(t, "us", original.Annotations[MigratedToAnnotation])
+}
+
+func TestManager_UpdatesExistingObjects(t *testing.T) {
+	target := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.1.0.7", ClusterIPs: []string{"10.1.0.7"}},
+		},
+	)
+	rollsOut(target)
+	m := newTestManager(Config{})
+
+	started := m.Start(Request{Source: "eu", Target: "us", Namespace: "shop", Deployment: "web"}, fake.NewSimpleClientset(sourceObjects()...), target)
+	job := waitFinished(t, m, started.ID)
+
+	assert.Equal(t, StateSucceeded, job.State, job.Error)
+	assert.Equal(t, StateSkipped, stepStates(job)[StepScaleDown])
+	assert.Contains(t, job.Objects, Object{Kind: "Service", Name: "web", Action: ActionUpdated})
+	assert.NotContains(t, job.Objects, Object{Kind: "Namespace", Name: "shop", Action: ActionCreated})
+
+	svc, err := target.CoreV1().Services("shop").Get(context.Background(), "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.0.7", svc.Spec.ClusterIP, "the target's cluster IP is kept")
+	assert.Equal(t, map[string]string{"app": "web"}, svc.Spec.Selector)
+}
+
+func TestManager_Failures(t *testing.T) {
+	m := newTestManager(Config{ReadyTimeout: 20 * time.Millisecond})
+
+	started := m.Start(Request{Source: "eu", Target: "us", Namespace: "shop", Deployment: "cart"}, fake.NewSimpleClientset(sourceObjects()...), fake.NewSimpleClientset())
+	job := waitFinished(t, m, started.ID)
+	assert.Equal(t, StateFailed, job.State)
+	assert.Contains(t, job.Error, "export")
+	assert.Equal(t, StateSkipped, stepStates(job)[StepApply])
+
+	// Without a deployment controller the target never becomes available
+	source := fake.NewSimpleClientset(sourceObjects()...)
+	started = m.Start(Request{Source: "eu", Target: "us", Namespace: "shop", Deployment: "web", ScaleDownSource: true}, source, fake.NewSimpleClientset())
+	job = waitFinished(t, m, started.ID)
+	assert.Equal(t, StateFailed, job.State)
+	assert.Equal(t, StateFailed, stepStates(job)[StepWaitReady])
+	assert.Equal(t, StateSkipped, stepStates(job)[StepScaleDown])
+	assert.Contains(t, job.Error, "not available after 20ms")
+
+	original, err := source.AppsV1().Deployments("shop").Get(context.Background(), "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), *original.Spec.Replicas, "the source keeps serving")
+}
+
+func TestManager_List(t *testing.T) {
+	m := newTestManager(Config{MaxJobs: 2})
+	var ids []string
+	for i := 0; i < 3; i++ {
+		job := m.Start(Request{Source: "eu", Target: "us", Namespace: "shop", Deployment: "missing"}, fake.NewSimpleClientset(), fake.NewSimpleClientset())
+		waitFinished(t, m, job.ID)
+		ids = append(ids, job.ID)
+	}
+
+	jobs := m.List()
+	require.Len(t, jobs, 2, "the oldest finished job is dropped")
+	assert.Equal(t, ids[2], jobs[0].ID)
+	assert.Equal(t, ids[1], jobs[1].ID)
+	_, ok := m.Get(ids[0])
+	assert.False(t, ok)
+}
+
+func TestRequest_Validate(t *testing.T) {
+	assert.NoError(t, Request{Source: "eu", Target: "us", Namespace: "shop", Deployment: "web"}.Validate())
+	for name, req := range map[string]Request{
+		"missing target":     {Source: "eu", Namespace: "shop", Deployment: "web"},
+		"same cluster":       {Source: "eu", Target: "eu", Namespace: "shop", Deployment: "web"},
+		"bad namespace":      {Source: "eu", Target: "us", Namespace: "Shop", Deployment: "web"},
+		"missing deployment": {Source: "eu", Target: "us", Namespace: "shop"},
+	} {
+		assert.Error(t, req.Validate(), name)
+	}
+}
@@ -0,0 +1,372 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// Annotations kept on the standby cluster
+const (
+	StandbySourceAnnotation     = "standby.k8s-custom-controller.io/source"         // Cluster a deployment is mirrored from
+	StandbyReplicasAnnotation   = "standby.k8s-custom-controller.io/replicas"       // Replicas of the source deployment, restored on failover
+	StandbyFailedOverAnnotation = "standby.k8s-custom-controller.io/failed-over-at" // Set on namespaces that were failed over, which are no longer mirrored
+)
+
+// States of a standby
+const (
+	StandbyStateMirroring  = "mirroring"
+	StandbyStateFailedOver = "failed_over"
+)
+
+// StandbyConfig holds warm standby settings
+type StandbyConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Source     string        `mapstructure:"source"`     // Cluster the namespaces are mirrored from
+	Target     string        `mapstructure:"target"`     // Standby cluster they are mirrored to
+	Namespaces []string      `mapstructure:"namespaces"` // Namespaces to mirror
+	Interval   time.Duration `mapstructure:"interval"`   // Time between mirroring passes
+}
+
+// Validate checks that an enabled standby names two clusters and what to mirror
+func (c StandbyConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Source == "" || c.Target == "" {
+		return errors.New("standby source and target clusters are required")
+	}
+	if c.Source == c.Target {
+		return errors.New("standby source and target must be different clusters")
+	}
+	if len(c.Namespaces) == 0 {
+		return errors.New("standby namespaces must not be empty")
+	}
+	if c.Interval <= 0 {
+		return errors.New("standby interval must be positive")
+	}
+	return nil
+}
+
+// ClientFunc returns the client of a registered cluster
+type ClientFunc func(clusterID string) (kubernetes.Interface, error)
+
+// StandbyStatus describes the mirroring of a standby
+type StandbyStatus struct {
+	Source       string     `json:"source"`
+	Target       string     `json:"target"`
+	Namespaces   []string   `json:"namespaces"`
+	State        string     `json:"state"`
+	Deployments  int        `json:"deployments"`              // Deployments mirrored by the latest pass
+	LastSync     *time.Time `json:"last_sync,omitempty"`      // End of the latest pass without errors
+	LastError    string     `json:"last_error,omitempty"`     // Error of the latest pass
+	FailedOverAt *time.Time `json:"failed_over_at,omitempty"` // When mirroring stopped for a failover
+}
+
+// ActivatedDeployment is a standby deployment scaled up by a failover
+type ActivatedDeployment struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Replicas  int32  `json:"replicas"`
+	Error     string `json:"error,omitempty"`
+}
+
+// FailoverResult lists the deployments a failover activated
+type FailoverResult struct {
+	Target      string                `json:"target"`
+	Activated   int                   `json:"activated"`
+	Failed      int                   `json:"failed"`
+	Deployments []ActivatedDeployment `json:"deployments"`
+}
+
+// Standby mirrors the deployments of selected namespaces, with the services and configmaps
+// they use, into a standby cluster with zero replicas, and activates them on failover
+type Standby struct {
+	cfg     StandbyConfig
+	clients ClientFunc
+
+	syncMu sync.Mutex // Serializes mirroring passes and failovers
+
+	mu         sync.Mutex
+	status     StandbyStatus
+	cancelPass context.CancelFunc // Cancels the running pass, nil between passes
+}
+
+// NewStandby creates a standby; Run starts mirroring
+func NewStandby(cfg StandbyConfig, clients ClientFunc) *Standby {
+	return &Standby{
+		cfg:     cfg,
+		clients: clients,
+		status: StandbyStatus{
+			Source:     cfg.Source,
+			Target:     cfg.Target,
+			Namespaces: cfg.Namespaces,
+			State:      StandbyStateMirroring,
+		},
+	}
+}
+
+// Run mirrors the namespaces every interval until the context is cancelled or the
+// standby fails over
+func (s *Standby) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sync(ctx); err != nil {
+			log.Warn().Err(err).Str("source", s.cfg.Source).Str("target", s.cfg.Target).Msg("Standby mirroring pass failed")
+		}
+		if s.Status().State == StandbyStateFailedOver {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the current mirroring status
+func (s *Standby) Status() StandbyStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Sync runs one mirroring pass. Namespaces marked as failed over on the standby cluster,
+// by Failover or before a restart, stop the mirroring.
+func (s *Standby) Sync(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	s.mu.Lock()
+	if s.status.State == StandbyStateFailedOver {
+		s.mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.cancelPass = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.cancelPass = nil
+		s.mu.Unlock()
+	}()
+
+	count, err := s.mirror(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status.State == StandbyStateFailedOver {
+		return nil
+	}
+	s.status.Deployments = count
+	s.status.LastError = ""
+	if err != nil {
+		s.status.LastError = err.Error()
+		return err
+	}
+	now := time.Now()
+	s.status.LastSync = &now
+	return nil
+}
+
+// mirror copies every namespace to the standby cluster and returns the number of
+// deployments mirrored
+func (s *Standby) mirror(ctx context.Context) (int, error) {
+	source, err := s.clients(s.cfg.Source)
+	if err != nil {
+		return 0, err
+	}
+	target, err := s.clients(s.cfg.Target)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var errs []error
+	for _, namespace := range s.cfg.Namespaces {
+		ns, err := target.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if err == nil && ns.Annotations[StandbyFailedOverAnnotation] != "" {
+			s.markFailedOver(ns.Annotations[StandbyFailedOverAnnotation])
+			return count, nil
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespace, err))
+			continue
+		}
+
+		n, err := s.mirrorNamespace(ctx, source, target, namespace)
+		count += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespace, err))
+		}
+	}
+	return count, errors.Join(errs...)
+}
+
+// mirrorNamespace applies the deployments of a namespace to the standby cluster with zero
+// replicas and deletes mirrored deployments whose source is gone
+func (s *Standby) mirrorNamespace(ctx context.Context, source, target kubernetes.Interface, namespace string) (int, error) {
+	deployments, err := source.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("listing deployments: %w", err)
+	}
+	services, err := source.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("listing services: %w", err)
+	}
+
+	count := 0
+	var errs []error
+	inSource := make(map[string]bool, len(deployments.Items))
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		inSource[d.Name] = true
+		w, err := collect(ctx, source, d, services.Items)
+		if err == nil {
+			replicas := desiredReplicas(d)
+			err = w.apply(ctx, target, func(mirrored *appsv1.Deployment) {
+				zero := int32(0)
+				mirrored.Spec.Replicas = &zero
+				mirrored.Annotations[StandbySourceAnnotation] = s.cfg.Source
+				mirrored.Annotations[StandbyReplicasAnnotation] = strconv.Itoa(int(replicas))
+			}, func(Object) {})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deployment %s: %w", d.Name, err))
+			continue
+		}
+		count++
+	}
+
+	mirrored, err := target.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return count, errors.Join(append(errs, fmt.Errorf("listing standby deployments: %w", err))...)
+	}
+	for _, d := range mirrored.Items {
+		if d.Annotations[StandbySourceAnnotation] != s.cfg.Source || inSource[d.Name] {
+			continue
+		}
+		err := target.AppsV1().Deployments(namespace).Delete(ctx, d.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("deleting deployment %s: %w", d.Name, err))
+		}
+	}
+	return count, errors.Join(errs...)
+}
+
+// markFailedOver stops the mirroring; at is the failover time recorded on a namespace
+func (s *Standby) markFailedOver(at string) {
+	failedOverAt, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		failedOverAt = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status.State != StandbyStateFailedOver {
+		s.status.State = StandbyStateFailedOver
+		s.status.FailedOverAt = &failedOverAt
+	}
+}
+
+// Failover stops the mirroring and scales every mirrored deployment on the standby cluster
+// to the replicas of its source. The namespaces are marked first so a restarted controller
+// does not resume mirroring. Only the standby cluster is contacted, and failing over again
+// retries deployments that could not be scaled.
+func (s *Standby) Failover(ctx context.Context) (FailoverResult, error) {
+	// Stop a running pass rather than wait for a source cluster that may be down
+	s.mu.Lock()
+	if s.cancelPass != nil {
+		s.cancelPass()
+	}
+	s.mu.Unlock()
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	result := FailoverResult{Target: s.cfg.Target, Deployments: []ActivatedDeployment{}}
+	target, err := s.clients(s.cfg.Target)
+	if err != nil {
+		return result, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	s.markFailedOver(now.Format(time.RFC3339))
+	failedOverAt := s.Status().FailedOverAt.Format(time.RFC3339)
+	for _, namespace := range s.cfg.Namespaces {
+		if err := markNamespace(ctx, target, namespace, failedOverAt); err != nil {
+			return result, fmt.Errorf("namespace %s: %w", namespace, err)
+		}
+	}
+
+	for _, namespace := range s.cfg.Namespaces {
+		deployments, err := target.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return result, fmt.Errorf("namespace %s: listing deployments: %w", namespace, err)
+		}
+		for _, d := range deployments.Items {
+			if d.Annotations[StandbySourceAnnotation] != s.cfg.Source {
+				continue
+			}
+			activated := ActivatedDeployment{Namespace: namespace, Name: d.Name}
+			replicas, err := strconv.ParseInt(d.Annotations[StandbyReplicasAnnotation], 10, 32)
+			if err == nil {
+				activated.Replicas = int32(replicas)
+				err = scaleTo(ctx, target, namespace, d.Name, activated.Replicas)
+			}
+			if err != nil {
+				activated.Error = err.Error()
+				result.Failed++
+			} else {
+				result.Activated++
+			}
+			result.Deployments = append(result.Deployments, activated)
+		}
+	}
+	return result, nil
+}
+
+// markNamespace records the failover on a standby namespace
+func markNamespace(ctx context.Context, client kubernetes.Interface, namespace, at string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ns, err := client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil // Never mirrored, nothing to activate
+		}
+		if err != nil {
+			return err
+		}
+		if ns.Annotations[StandbyFailedOverAnnotation] != "" {
+			return nil
+		}
+		if ns.Annotations == nil {
+			ns.Annotations = map[string]string{}
+		}
+		ns.Annotations[StandbyFailedOverAnnotation] = at
+		_, err = client.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// scaleTo sets the replicas of a deployment
+func scaleTo(ctx context.Context, client kubernetes.Interface, namespace, name string, replicas int32) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		d, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		d.Spec.Replicas = &replicas
+		_, err = client.AppsV1().Deployments(namespace).Update(ctx, d, metav1.UpdateOptions{})
+		return err
+	})
+}
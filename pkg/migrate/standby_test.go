@@ -0,0 +1,127 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestStandby mirrors the shop namespace from the source to the target clientset
+func newTestStandby(source, target *fake.Clientset) *Standby {
+	clients := map[string]kubernetes.Interface{"prod": source, "dr": target}
+	return NewStandby(StandbyConfig{
+		Enabled: true, Source: "prod", Target: "dr", Namespaces: []string{"shop"}, Interval: time.Minute,
+	}, func(clusterID string) (kubernetes.Interface, error) {
+		if client, ok := clients[clusterID]; ok {
+			return client, nil
+		}
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	})
+}
+
+// TestStandbyConfig_Validate tests the checks on standby settings
+func TestStandbyConfig_Validate(t *testing.T) {
+	valid := StandbyConfig{Enabled: true, Source: "prod", Target: "dr", Namespaces: []string{"shop"}, Interval: time.Minute}
+	assert.NoError(t, valid.Validate())
+	assert.NoError(t, StandbyConfig{}.Validate(), "a disabled standby is not checked")
+
+	same := valid
+	same.Target = "prod"
+	assert.ErrorContains(t, same.Validate(), "different clusters")
+	noNamespaces := valid
+	noNamespaces.Namespaces = nil
+	assert.ErrorContains(t, noNamespaces.Validate(), "namespaces")
+}
+
+// TestStandbySync tests that deployments are mirrored with zero replicas and deleted from
+// the standby when their source is gone
+func TestStandbySync(t *testing.T) {
+	ctx := context.Background()
+	source := fake.NewSimpleClientset(sourceObjects()...)
+	target := fake.NewSimpleClientset()
+	s := newTestStandby(source, target)
+
+	require.NoError(t, s.Sync(ctx))
+	mirrored, err := target.AppsV1().Deployments("shop").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), *mirrored.Spec.Replicas)
+	assert.Equal(t, "prod", mirrored.Annotations[StandbySourceAnnotation])
+	assert.Equal(t, "3", mirrored.Annotations[StandbyReplicasAnnotation])
+	assert.Equal(t, "checkout", mirrored.Annotations["team"])
+	_, err = target.CoreV1().ConfigMaps("shop").Get(ctx, "web-config", metav1.GetOptions{})
+	assert.NoError(t, err)
+	_, err = target.CoreV1().Services("shop").Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	status := s.Status()
+	assert.Equal(t, StandbyStateMirroring, status.State)
+	assert.Equal(t, 1, status.Deployments)
+	assert.NotNil(t, status.LastSync)
+	assert.Empty(t, status.LastError)
+
+	// A deployment created on the standby by hand is left alone
+	_, err = target.AppsV1().Deployments("shop").Create(ctx, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "shop"}}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, source.AppsV1().Deployments("shop").Delete(ctx, "web", metav1.DeleteOptions{}))
+	require.NoError(t, s.Sync(ctx))
+	deployments, err := target.AppsV1().Deployments("shop").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, deployments.Items, 1)
+	assert.Equal(t, "local", deployments.Items[0].Name)
+}
+
+// TestStandbySyncUnknownCluster tests that a pass fails while a cluster is not registered
+func TestStandbySyncUnknownCluster(t *testing.T) {
+	s := newTestStandby(fake.NewSimpleClientset(), fake.NewSimpleClientset())
+	s.cfg.Target = "missing"
+
+	assert.ErrorContains(t, s.Sync(context.Background()), "cluster missing not found")
+	assert.Equal(t, "cluster missing not found", s.Status().LastError)
+	assert.Nil(t, s.Status().LastSync)
+}
+
+// TestStandbyFailover tests that a failover restores the source replicas on the standby and
+// stops the mirroring, also for a standby created after a restart
+func TestStandbyFailover(t *testing.T) {
+	ctx := context.Background()
+	source := fake.NewSimpleClientset(sourceObjects()...)
+	target := fake.NewSimpleClientset()
+	s := newTestStandby(source, target)
+	require.NoError(t, s.Sync(ctx))
+
+	result, err := s.Failover(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Activated)
+	assert.Zero(t, result.Failed)
+	assert.Equal(t, []ActivatedDeployment{{Namespace: "shop", Name: "web", Replicas: 3}}, result.Deployments)
+
+	activated, err := target.AppsV1().Deployments("shop").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), *activated.Spec.Replicas)
+	ns, err := target.CoreV1().Namespaces().Get(ctx, "shop", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, ns.Annotations[StandbyFailedOverAnnotation])
+	assert.Equal(t, StandbyStateFailedOver, s.Status().State)
+	require.NotNil(t, s.Status().FailedOverAt)
+
+	// Later passes leave the activated deployments alone
+	require.NoError(t, s.Sync(ctx))
+	activated, err = target.AppsV1().Deployments("shop").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), *activated.Spec.Replicas)
+
+	restarted := newTestStandby(source, target)
+	require.NoError(t, restarted.Sync(ctx))
+	assert.Equal(t, StandbyStateFailedOver, restarted.Status().State)
+	activated, err = target.AppsV1().Deployments("shop").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), *activated.Spec.Replicas)
+}
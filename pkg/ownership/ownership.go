@@ -0,0 +1,215 @@
+// Package ownership resolves team ownership metadata for namespaces
+package ownership
+
+import (
+	"context"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultAnnotationPrefix is used when no annotation prefix is configured
+const DefaultAnnotationPrefix = "ownership.k8s-custom-controller.io/"
+
+// LookupTimeout bounds reading the annotations of a namespace, so a slow or unreachable
+// cluster does not hold up the event or check that asked for its owner
+const LookupTimeout = 2 * time.Second
+
+// Annotation keys, appended to the configured prefix
+const (
+	AnnotationTeam         = "team"
+	AnnotationOwner        = "owner"
+	AnnotationSlackChannel = "slack-channel"
+)
+
+// Owner describes who is responsible for a namespace
+type Owner struct {
+	Team         string `mapstructure:"team" json:"team,omitempty"`
+	Owner        string `mapstructure:"owner" json:"owner,omitempty"`
+	SlackChannel string `mapstructure:"slack_channel" json:"slack_channel,omitempty"`
+}
+
+// IsZero reports whether no ownership information is set
+func (o Owner) IsZero() bool {
+	return o.Team == "" && o.Owner == "" && o.SlackChannel == ""
+}
+
+// merge fills empty fields of o from other
+func (o Owner) merge(other Owner) Owner {
+	if o.Team == "" {
+		o.Team = other.Team
+	}
+	if o.Owner == "" {
+		o.Owner = other.Owner
+	}
+	if o.SlackChannel == "" {
+		o.SlackChannel = other.SlackChannel
+	}
+	return o
+}
+
+// Config maps namespaces to owners. Keys may be exact names or glob patterns
+// such as "payments-*"; namespace annotations override configured values.
+type Config struct {
+	AnnotationPrefix string           `mapstructure:"annotation_prefix"`
+	Namespaces       map[string]Owner `mapstructure:"namespaces"`
+	CacheTTL         time.Duration    `mapstructure:"cache_ttl"` // How long namespace annotations are cached
+}
+
+// NamespaceGetter returns the annotations of a namespace
+type NamespaceGetter func(ctx context.Context, name string) (map[string]string, error)
+
+type cacheKey struct {
+	cluster   string
+	namespace string
+}
+
+type cacheEntry struct {
+	owner   Owner
+	expires time.Time
+}
+
+// Resolver combines configured ownership with namespace annotations. Annotations are read
+// with the getter of the namespace's cluster, since namespaces of the same name in different
+// clusters may belong to different teams.
+type Resolver struct {
+	config   Config
+	patterns []string // Glob keys sorted for deterministic matching
+
+	mu      sync.Mutex
+	getters map[string]NamespaceGetter // By cluster ID
+	cache   map[cacheKey]cacheEntry
+}
+
+// NewResolver creates a resolver that uses configuration only until getters are set
+func NewResolver(cfg Config) *Resolver {
+	if cfg.AnnotationPrefix == "" {
+		cfg.AnnotationPrefix = DefaultAnnotationPrefix
+	}
+
+	r := &Resolver{config: cfg, getters: make(map[string]NamespaceGetter), cache: make(map[cacheKey]cacheEntry)}
+	for key := range cfg.Namespaces {
+		if isPattern(key) {
+			r.patterns = append(r.patterns, key)
+		}
+	}
+	sort.Strings(r.patterns)
+	return r
+}
+
+// SetGetter sets how namespace annotations of a cluster are read, replacing its previous
+// getter and the owners cached with it
+func (r *Resolver) SetGetter(clusterID string, getter NamespaceGetter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.getters[clusterID] = getter
+	r.forget(clusterID)
+}
+
+// RemoveCluster drops the getter and the cached owners of a cluster
+func (r *Resolver) RemoveCluster(clusterID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.getters, clusterID)
+	r.forget(clusterID)
+}
+
+// forget drops the cached owners of a cluster; r.mu must be held
+func (r *Resolver) forget(clusterID string) {
+	for key := range r.cache {
+		if key.cluster == clusterID {
+			delete(r.cache, key)
+		}
+	}
+}
+
+// FromAnnotations resolves the owner for a namespace whose annotations are already known
+func (r *Resolver) FromAnnotations(namespace string, annotations map[string]string) Owner {
+	owner := Owner{
+		Team:         annotations[r.config.AnnotationPrefix+AnnotationTeam],
+		Owner:        annotations[r.config.AnnotationPrefix+AnnotationOwner],
+		SlackChannel: annotations[r.config.AnnotationPrefix+AnnotationSlackChannel],
+	}
+	return owner.merge(r.fromConfig(namespace))
+}
+
+// Resolve looks up the annotations (cached) of a namespace in a cluster and returns its
+// owner. Clusters without a getter use configuration only, as does a lookup that fails or
+// takes longer than LookupTimeout.
+func (r *Resolver) Resolve(ctx context.Context, clusterID, namespace string) Owner {
+	if namespace == "" {
+		return r.fromConfig(namespace)
+	}
+
+	key := cacheKey{cluster: clusterID, namespace: namespace}
+	r.mu.Lock()
+	getter := r.getters[clusterID]
+	entry, ok := r.cache[key]
+	r.mu.Unlock()
+	if getter == nil {
+		return r.fromConfig(namespace)
+	}
+	if ok && time.Now().Before(entry.expires) {
+		return entry.owner
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, LookupTimeout)
+	defer cancel()
+	annotations, err := getter(ctx, namespace)
+	if err != nil {
+		log.Debug().Err(err).Str("cluster_id", clusterID).Str("namespace", namespace).Msg("Failed to read namespace annotations for ownership")
+		return r.fromConfig(namespace)
+	}
+
+	owner := r.FromAnnotations(namespace, annotations)
+	if r.config.CacheTTL > 0 {
+		r.mu.Lock()
+		r.cache[key] = cacheEntry{owner: owner, expires: time.Now().Add(r.config.CacheTTL)}
+		r.mu.Unlock()
+	}
+	return owner
+}
+
+// fromConfig returns the configured owner, preferring exact matches over patterns
+func (r *Resolver) fromConfig(namespace string) Owner {
+	if owner, ok := r.config.Namespaces[namespace]; ok {
+		return owner
+	}
+	for _, pattern := range r.patterns {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return r.config.Namespaces[pattern]
+		}
+	}
+	return Owner{}
+}
+
+func isPattern(key string) bool {
+	for _, c := range key {
+		if c == '*' || c == '?' || c == '[' {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	defaultMu       sync.RWMutex
+	defaultResolver = NewResolver(Config{})
+)
+
+// SetDefault replaces the process-wide resolver
+func SetDefault(r *Resolver) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultResolver = r
+}
+
+// Default returns the process-wide resolver
+func Default() *Resolver {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultResolver
+}
@@ -0,0 +1,102 @@
+package ownership
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfig() Config {
+	return Config{
+		Namespaces: map[string]Owner{
+			"payments":   {Team: "payments", SlackChannel: "#payments-alerts"},
+			"payments-*": {Team: "payments", Owner: "alice"},
+			"team-?":     {Team: "single-letter"},
+		},
+		CacheTTL: time.Minute,
+	}
+}
+
+func TestResolver_FromConfig(t *testing.T) {
+	r := NewResolver(testConfig())
+
+	assert.Equal(t, Owner{Team: "payments", SlackChannel: "#payments-alerts"}, r.Resolve(context.Background(), "c1", "payments"))
+	assert.Equal(t, "alice", r.Resolve(context.Background(), "c1", "payments-staging").Owner)
+	assert.Equal(t, "single-letter", r.Resolve(context.Background(), "c1", "team-a").Team)
+	assert.True(t, r.Resolve(context.Background(), "c1", "unknown").IsZero())
+}
+
+func TestResolver_AnnotationsOverrideConfig(t *testing.T) {
+	r := NewResolver(testConfig())
+
+	owner := r.FromAnnotations("payments", map[string]string{
+		DefaultAnnotationPrefix + AnnotationOwner: "bob",
+		DefaultAnnotationPrefix + AnnotationTeam:  "billing",
+	})
+
+	assert.Equal(t, "billing", owner.Team)
+	assert.Equal(t, "bob", owner.Owner)
+	assert.Equal(t, "#payments-alerts", owner.SlackChannel, "missing annotations fall back to config")
+}
+
+func TestResolver_GetterIsCached(t *testing.T) {
+	calls := 0
+	getter := func(ctx context.Context, name string) (map[string]string, error) {
+		calls++
+		return map[string]string{"custom/team": "platform"}, nil
+	}
+
+	cfg := testConfig()
+	cfg.AnnotationPrefix = "custom/"
+	r := NewResolver(cfg)
+	r.SetGetter("c1", getter)
+
+	assert.Equal(t, "platform", r.Resolve(context.Background(), "c1", "infra").Team)
+	assert.Equal(t, "platform", r.Resolve(context.Background(), "c1", "infra").Team)
+	assert.Equal(t, 1, calls)
+}
+
+func TestResolver_GetterErrorFallsBackToConfig(t *testing.T) {
+	getter := func(ctx context.Context, name string) (map[string]string, error) {
+		return nil, errors.New("forbidden")
+	}
+	r := NewResolver(testConfig())
+	r.SetGetter("c1", getter)
+
+	assert.Equal(t, "payments", r.Resolve(context.Background(), "c1", "payments").Team)
+}
+
+func TestResolver_PerCluster(t *testing.T) {
+	teams := map[string]string{"c1": "payments", "c2": "search"}
+	getterFor := func(clusterID string) NamespaceGetter {
+		return func(ctx context.Context, name string) (map[string]string, error) {
+			return map[string]string{DefaultAnnotationPrefix + AnnotationTeam: teams[clusterID]}, nil
+		}
+	}
+	r := NewResolver(Config{CacheTTL: time.Minute})
+	r.SetGetter("c1", getterFor("c1"))
+	r.SetGetter("c2", getterFor("c2"))
+
+	assert.Equal(t, "payments", r.Resolve(context.Background(), "c1", "shared").Team)
+	assert.Equal(t, "search", r.Resolve(context.Background(), "c2", "shared").Team, "owners are cached per cluster")
+	assert.True(t, r.Resolve(context.Background(), "c3", "shared").IsZero(), "clusters without a getter use configuration only")
+
+	r.RemoveCluster("c2")
+	assert.True(t, r.Resolve(context.Background(), "c2", "shared").IsZero())
+	assert.Equal(t, "payments", r.Resolve(context.Background(), "c1", "shared").Team)
+}
+
+func TestResolver_LookupIsBounded(t *testing.T) {
+	r := NewResolver(testConfig())
+	r.SetGetter("c1", func(ctx context.Context, name string) (map[string]string, error) {
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(LookupTimeout), deadline, time.Second)
+		return nil, ctx.Err()
+	})
+
+	assert.Equal(t, "payments", r.Resolve(context.Background(), "c1", "payments").Team)
+}
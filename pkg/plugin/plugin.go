@@ -0,0 +1,378 @@
+// Package plugin defines extension points for custom API handlers,
+// controllers and event sinks
+package plugin
+
+import (
+	"context"
+	"fmt"
+	goplugin "plugin"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ownership"
+)
+
+// RegisterSymbol is the symbol looked up in Go plugin (.so) files.
+// It must be a func() that registers the plugin's components.
+const RegisterSymbol = "Register"
+
+// Event delivery limits. Events are queued for a background worker so controllers never wait
+// on sinks; events arriving while the queue is full are dropped.
+const (
+	QueueSize   = 1000
+	SendTimeout = 5 * time.Second // Per sink and event
+)
+
+// Route describes a single HTTP endpoint contributed by a handler plugin
+type Route struct {
+	Method  string                  // HTTP method, empty matches any method
+	Path    string                  // Exact request path, e.g. /teams/status
+	Handler fasthttp.RequestHandler // Handler invoked for matching requests
+}
+
+// HandlerPlugin contributes custom endpoints to the API server
+type HandlerPlugin interface {
+	Name() string
+	Routes() []Route
+}
+
+// ControllerPlugin adds custom reconcilers to every cluster manager
+type ControllerPlugin interface {
+	Name() string
+	SetupWithManager(mgr manager.Manager, clusterID string) error
+}
+
+// MiddlewarePlugin wraps every API request. It runs right before the built-in middleware
+// stage named by Before, one of the names in the middleware package such as "auth" to see
+// requests before they are authenticated, or right before the endpoint when Before is empty.
+// Plugins with the same Before run in the order of their names.
+type MiddlewarePlugin interface {
+	Name() string
+	Before() string
+	Middleware(next fasthttp.RequestHandler) fasthttp.RequestHandler
+}
+
+// Event is a resource event delivered to sink plugins
+type Event struct {
+	ID           string           `json:"id"`
+	ClusterID    string           `json:"cluster_id"`
+	Type         string           `json:"type"` // CREATE, UPDATE, DELETE or GENERIC, or LEADER_ELECTED and LEADER_LOST for leases, or ROLLOUT_STUCK and ROLLOUT_RECOVERED for deployments
+	ResourceType string           `json:"resource_type"`
+	Namespace    string           `json:"namespace"`
+	Name         string           `json:"name"`
+	Owner        *ownership.Owner `json:"owner,omitempty"` // Owner of the namespace, when known
+	Object       interface{}      `json:"object,omitempty"`
+}
+
+// SinkPlugin receives resource events emitted by the controllers
+type SinkPlugin interface {
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+// Registry holds registered plugins
+type Registry struct {
+	mu          sync.RWMutex
+	handlers    map[string]HandlerPlugin
+	controllers map[string]ControllerPlugin
+	sinks       map[string]SinkPlugin
+	middlewares map[string]MiddlewarePlugin
+
+	queueMu sync.RWMutex  // Guards sends on queue against Close
+	queue   chan delivery // Events waiting for the delivery worker
+	closed  bool
+	once    sync.Once     // Starts the worker with the first event
+	done    chan struct{} // Closed when the worker has stopped
+}
+
+// delivery is a queued event, or a flush marker when done is set
+type delivery struct {
+	ctx   context.Context
+	event Event
+	done  chan struct{}
+}
+
+// NewRegistry creates an empty plugin registry
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers:    make(map[string]HandlerPlugin),
+		controllers: make(map[string]ControllerPlugin),
+		sinks:       make(map[string]SinkPlugin),
+		middlewares: make(map[string]MiddlewarePlugin),
+		queue:       make(chan delivery, QueueSize),
+		done:        make(chan struct{}),
+	}
+}
+
+// defaultRegistry is used by the package-level helpers
+var defaultRegistry = NewRegistry()
+
+// Default returns the process-wide plugin registry
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// RegisterHandler adds a handler plugin, replacing any plugin with the same name
+func (r *Registry) RegisterHandler(p HandlerPlugin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[p.Name()] = p
+	log.Debug().Str("plugin", p.Name()).Int("routes", len(p.Routes())).Msg("Registered handler plugin")
+}
+
+// RegisterController adds a controller plugin, replacing any plugin with the same name
+func (r *Registry) RegisterController(p ControllerPlugin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.controllers[p.Name()] = p
+	log.Debug().Str("plugin", p.Name()).Msg("Registered controller plugin")
+}
+
+// RegisterSink adds a sink plugin, replacing any plugin with the same name
+func (r *Registry) RegisterSink(p SinkPlugin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sinks[p.Name()] = p
+	log.Debug().Str("plugin", p.Name()).Msg("Registered sink plugin")
+}
+
+// RegisterMiddleware adds a middleware plugin, replacing any plugin with the same name
+func (r *Registry) RegisterMiddleware(p MiddlewarePlugin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middlewares[p.Name()] = p
+	log.Debug().Str("plugin", p.Name()).Str("before", p.Before()).Msg("Registered middleware plugin")
+}
+
+// Handlers returns registered handler plugins sorted by name
+func (r *Registry) Handlers() []HandlerPlugin {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]HandlerPlugin, 0, len(r.handlers))
+	for _, name := range sortedKeys(r.handlers) {
+		result = append(result, r.handlers[name])
+	}
+	return result
+}
+
+// Controllers returns registered controller plugins sorted by name
+func (r *Registry) Controllers() []ControllerPlugin {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]ControllerPlugin, 0, len(r.controllers))
+	for _, name := range sortedKeys(r.controllers) {
+		result = append(result, r.controllers[name])
+	}
+	return result
+}
+
+// Sinks returns registered sink plugins sorted by name
+func (r *Registry) Sinks() []SinkPlugin {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]SinkPlugin, 0, len(r.sinks))
+	for _, name := range sortedKeys(r.sinks) {
+		result = append(result, r.sinks[name])
+	}
+	return result
+}
+
+// Middlewares returns registered middleware plugins sorted by name
+func (r *Registry) Middlewares() []MiddlewarePlugin {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]MiddlewarePlugin, 0, len(r.middlewares))
+	for _, name := range sortedKeys(r.middlewares) {
+		result = append(result, r.middlewares[name])
+	}
+	return result
+}
+
+// LookupHandler finds a plugin route matching the method and path
+func (r *Registry) LookupHandler(method, path string) (fasthttp.RequestHandler, bool) {
+	for _, p := range r.Handlers() {
+		for _, route := range p.Routes() {
+			if route.Path != path {
+				continue
+			}
+			if route.Method != "" && !strings.EqualFold(route.Method, method) {
+				continue
+			}
+			return route.Handler, true
+		}
+	}
+	return nil, false
+}
+
+// SetupControllers runs every controller plugin against the manager
+func (r *Registry) SetupControllers(mgr manager.Manager, clusterID string) error {
+	for _, p := range r.Controllers() {
+		if err := p.SetupWithManager(mgr, clusterID); err != nil {
+			return fmt.Errorf("controller plugin %s: %w", p.Name(), err)
+		}
+		log.Info().Str("plugin", p.Name()).Str("cluster_id", clusterID).Msg("Controller plugin added to manager")
+	}
+	return nil
+}
+
+// Emit queues the event for delivery to all sink plugins without blocking the caller. Sinks
+// receive events in the order they were emitted, with the values but not the cancellation of
+// ctx, and each send is bounded by SendTimeout.
+func (r *Registry) Emit(ctx context.Context, event Event) {
+	r.once.Do(func() { go r.run() })
+	r.queueMu.RLock()
+	defer r.queueMu.RUnlock()
+	if r.closed {
+		return
+	}
+	select {
+	case r.queue <- delivery{ctx: context.WithoutCancel(ctx), event: event}:
+	default:
+		log.Warn().Str("event_id", event.ID).Str("type", event.Type).Msg("Plugin event queue full, dropping event")
+	}
+}
+
+// Flush waits until the events emitted so far have been delivered, or ctx is done
+func (r *Registry) Flush(ctx context.Context) error {
+	r.once.Do(func() { go r.run() })
+	done := make(chan struct{})
+	if err := r.enqueueMarker(ctx, done); err != nil {
+		return err
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueueMarker queues a marker behind the events emitted so far, closing done once delivered
+func (r *Registry) enqueueMarker(ctx context.Context, done chan struct{}) error {
+	r.queueMu.RLock()
+	defer r.queueMu.RUnlock()
+	if r.closed {
+		// Close has delivered everything emitted before it
+		close(done)
+		return nil
+	}
+	select {
+	case r.queue <- delivery{done: done}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close delivers the queued events and stops the delivery worker. Events emitted afterwards
+// are dropped.
+func (r *Registry) Close() {
+	r.once.Do(func() { go r.run() })
+	r.queueMu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.queueMu.Unlock()
+	<-r.done
+}
+
+// run delivers queued events, logging delivery failures
+func (r *Registry) run() {
+	defer close(r.done)
+	for d := range r.queue {
+		if d.done != nil {
+			close(d.done)
+			continue
+		}
+		for _, sink := range r.Sinks() {
+			r.send(d.ctx, sink, d.event)
+		}
+	}
+}
+
+func (r *Registry) send(ctx context.Context, sink SinkPlugin, event Event) {
+	ctx, cancel := context.WithTimeout(ctx, SendTimeout)
+	defer cancel()
+	if err := sink.Send(ctx, event); err != nil {
+		log.Warn().Err(err).
+			Str("plugin", sink.Name()).
+			Str("event_id", event.ID).
+			Msg("Sink plugin failed to process event")
+	}
+}
+
+// LoadFile opens a Go plugin (.so) and calls its Register function
+func (r *Registry) LoadFile(path string) error {
+	p, err := goplugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open plugin %s: %w", path, err)
+	}
+
+	sym, err := p.Lookup(RegisterSymbol)
+	if err != nil {
+		return fmt.Errorf("plugin %s does not export %s: %w", path, RegisterSymbol, err)
+	}
+
+	register, ok := sym.(func())
+	if !ok {
+		return fmt.Errorf("plugin %s: %s has type %T, expected func()", path, RegisterSymbol, sym)
+	}
+
+	register()
+	log.Info().Str("path", path).Msg("Loaded plugin")
+	return nil
+}
+
+// RegisterHandler adds a handler plugin to the default registry
+func RegisterHandler(p HandlerPlugin) { defaultRegistry.RegisterHandler(p) }
+
+// RegisterController adds a controller plugin to the default registry
+func RegisterController(p ControllerPlugin) { defaultRegistry.RegisterController(p) }
+
+// RegisterSink adds a sink plugin to the default registry
+func RegisterSink(p SinkPlugin) { defaultRegistry.RegisterSink(p) }
+
+// RegisterMiddleware adds a middleware plugin to the default registry
+func RegisterMiddleware(p MiddlewarePlugin) { defaultRegistry.RegisterMiddleware(p) }
+
+// LookupHandler finds a route in the default registry
+func LookupHandler(method, path string) (fasthttp.RequestHandler, bool) {
+	return defaultRegistry.LookupHandler(method, path)
+}
+
+// SetupControllers runs the default registry's controller plugins
+func SetupControllers(mgr manager.Manager, clusterID string) error {
+	return defaultRegistry.SetupControllers(mgr, clusterID)
+}
+
+// Emit queues the event for the default registry's sinks
+func Emit(ctx context.Context, event Event) { defaultRegistry.Emit(ctx, event) }
+
+// Flush waits until the events emitted to the default registry have been delivered
+func Flush(ctx context.Context) error { return defaultRegistry.Flush(ctx) }
+
+// LoadFiles loads each Go plugin file into the default registry
+func LoadFiles(paths []string) error {
+	for _, path := range paths {
+		if err := defaultRegistry.LoadFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+type testHandlerPlugin struct {
+	routes []Route
+}
+
+func (p *testHandlerPlugin) Name() string    { return "test-handler" }
+func (p *testHandlerPlugin) Routes() []Route { return p.routes }
+
+type testControllerPlugin struct {
+	clusters []string
+	err      error
+}
+
+func (p *testControllerPlugin) Name() string { return "test-controller" }
+func (p *testControllerPlugin) SetupWithManager(mgr manager.Manager, clusterID string) error {
+	p.clusters = append(p.clusters, clusterID)
+	return p.err
+}
+
+type testSinkPlugin struct {
+	name   string
+	events []Event
+	err    error
+}
+
+func (p *testSinkPlugin) Name() string { return p.name }
+func (p *testSinkPlugin) Send(ctx context.Context, event Event) error {
+	p.events = append(p.events, event)
+	return p.err
+}
+
+type testMiddlewarePlugin struct {
+	name, before string
+}
+
+func (p *testMiddlewarePlugin) Name() string   { return p.name }
+func (p *testMiddlewarePlugin) Before() string { return p.before }
+func (p *testMiddlewarePlugin) Middleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return next
+}
+
+func TestRegistry_Middlewares(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterMiddleware(&testMiddlewarePlugin{name: "tenant", before: "auth"})
+	r.RegisterMiddleware(&testMiddlewarePlugin{name: "geoip"})
+	r.RegisterMiddleware(&testMiddlewarePlugin{name: "tenant", before: "csrf"})
+
+	middlewares := r.Middlewares()
+	require.Len(t, middlewares, 2)
+	assert.Equal(t, "geoip", middlewares[0].Name())
+	assert.Equal(t, "csrf", middlewares[1].Before(), "a plugin with the same name replaces the earlier one")
+}
+
+func TestRegistry_LookupHandler(t *testing.T) {
+	r := NewRegistry()
+	called := false
+	r.RegisterHandler(&testHandlerPlugin{routes: []Route{
+		{Method: "GET", Path: "/custom", Handler: func(ctx *fasthttp.RequestCtx) { called = true }},
+		{Path: "/any", Handler: func(ctx *fasthttp.RequestCtx) {}},
+	}})
+
+	handler, ok := r.LookupHandler("get", "/custom")
+	require.True(t, ok)
+	handler(&fasthttp.RequestCtx{})
+	assert.True(t, called)
+
+	_, ok = r.LookupHandler("POST", "/custom")
+	assert.False(t, ok, "method mismatch should not match")
+
+	_, ok = r.LookupHandler("DELETE", "/any")
+	assert.True(t, ok, "empty method should match any method")
+
+	_, ok = r.LookupHandler("GET", "/missing")
+	assert.False(t, ok)
+}
+
+func TestRegistry_SetupControllers(t *testing.T) {
+	r := NewRegistry()
+	p := &testControllerPlugin{}
+	r.RegisterController(p)
+
+	require.NoError(t, r.SetupControllers(nil, "cluster-a"))
+	assert.Equal(t, []string{"cluster-a"}, p.clusters)
+
+	p.err = errors.New("boom")
+	err := r.SetupControllers(nil, "cluster-b")
+	assert.ErrorContains(t, err, "test-controller")
+}
+
+func TestRegistry_EmitContinuesAfterSinkError(t *testing.T) {
+	r := NewRegistry()
+	failing := &testSinkPlugin{name: "a-failing", err: errors.New("unavailable")}
+	working := &testSinkPlugin{name: "b-working"}
+	r.RegisterSink(failing)
+	r.RegisterSink(working)
+
+	r.Emit(context.Background(), Event{ID: "1", Type: "CREATE", Name: "web"})
+	require.NoError(t, r.Flush(context.Background()))
+
+	assert.Len(t, failing.events, 1)
+	assert.Len(t, working.events, 1)
+	assert.Equal(t, "web", working.events[0].Name)
+}
+
+// blockingSinkPlugin holds every send until it is released or its context is done
+type blockingSinkPlugin struct {
+	release  chan struct{}
+	deadline chan time.Time
+}
+
+func (p *blockingSinkPlugin) Name() string { return "blocking" }
+func (p *blockingSinkPlugin) Send(ctx context.Context, event Event) error {
+	deadline, _ := ctx.Deadline()
+	p.deadline <- deadline
+	select {
+	case <-p.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRegistry_EmitDoesNotWaitForSinks(t *testing.T) {
+	r := NewRegistry()
+	sink := &blockingSinkPlugin{release: make(chan struct{}), deadline: make(chan time.Time, 1)}
+	r.RegisterSink(sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Emit(ctx, Event{ID: "1"})
+	cancel() // The caller's cancellation does not reach the sink
+
+	select {
+	case deadline := <-sink.deadline:
+		assert.WithinDuration(t, time.Now().Add(SendTimeout), deadline, time.Second, "sends are bounded")
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+	close(sink.release)
+	r.Close()
+}
+
+func TestRegistry_EmitAfterClose(t *testing.T) {
+	r := NewRegistry()
+	sink := &testSinkPlugin{name: "sink"}
+	r.RegisterSink(sink)
+
+	r.Emit(context.Background(), Event{ID: "1"})
+	r.Close()
+	assert.Len(t, sink.events, 1, "queued events are delivered on close")
+
+	assert.NotPanics(t, func() {
+		r.Emit(context.Background(), Event{ID: "2"})
+		r.Close()
+	})
+	require.NoError(t, r.Flush(context.Background()))
+	assert.Len(t, sink.events, 1)
+}
+
+func TestRegistry_LoadFileMissing(t *testing.T) {
+	r := NewRegistry()
+	err := r.LoadFile("/nonexistent/plugin.so")
+	assert.Error(t, err)
+}
@@ -0,0 +1,286 @@
+// Package podaudit flags pod specs that weaken the isolation between containers and their
+// nodes, such as privileged containers, host namespaces and dangerous capabilities
+package podaudit
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Severities of findings
+const (
+	SeverityCritical = "critical" // Equivalent to root on the node
+	SeverityHigh     = "high"     // Access to other workloads or kernel interfaces of the node
+	SeverityMedium   = "medium"   // Weakens isolation when combined with a vulnerability
+	SeverityLow      = "low"      // Relies on runtime defaults
+)
+
+// Severities lists every severity, most severe first
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow}
+
+// Checks
+const (
+	CheckPrivileged             = "privileged"
+	CheckHostPID                = "host_pid"
+	CheckHostIPC                = "host_ipc"
+	CheckCapabilities           = "dangerous_capabilities"
+	CheckPrivilegeEscalation    = "privilege_escalation"
+	CheckRunAsRoot              = "run_as_root"
+	CheckMissingSecurityContext = "missing_security_context"
+)
+
+// Checks lists every check
+var Checks = []string{
+	CheckPrivileged, CheckHostPID, CheckHostIPC, CheckCapabilities,
+	CheckPrivilegeEscalation, CheckRunAsRoot, CheckMissingSecurityContext,
+}
+
+// criticalCapabilities grant control over the node on their own
+var criticalCapabilities = []string{"ALL", "SYS_ADMIN", "SYS_MODULE"}
+
+// dangerousCapabilities are flagged as high severity when added to a container
+var dangerousCapabilities = []string{
+	"BPF", "DAC_READ_SEARCH", "NET_ADMIN", "NET_RAW", "PERFMON", "SYS_BOOT",
+	"SYS_PTRACE", "SYS_RAWIO", "SYS_TIME",
+}
+
+// Config holds audit settings
+type Config struct {
+	IgnoreNamespaces      []string `mapstructure:"ignore_namespaces"`      // Namespace patterns not audited, e.g. kube-system
+	DangerousCapabilities []string `mapstructure:"dangerous_capabilities"` // More capabilities flagged as high severity
+}
+
+// Finding is one check failed by a container of a workload
+type Finding struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Workload  string `json:"workload"`            // kind/name of the pods' controller
+	Container string `json:"container,omitempty"` // Empty for pod-level checks
+	Check     string `json:"check"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+}
+
+// Namespace rolls up the findings of one namespace
+type Namespace struct {
+	Cluster    string         `json:"cluster"`
+	Namespace  string         `json:"namespace"`
+	Workloads  int            `json:"workloads"` // Workloads with at least one finding
+	Findings   int            `json:"findings"`
+	Severities map[string]int `json:"severities"`
+	Highest    string         `json:"highest"` // Most severe finding
+}
+
+// Auditor checks pod specs
+type Auditor struct {
+	ignore     []string
+	dangerous  []string
+	severities map[string]int // Rank of each severity, 0 is the most severe
+}
+
+// New validates the configuration
+func New(cfg Config) (*Auditor, error) {
+	for _, p := range cfg.IgnoreNamespaces {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("ignore_namespaces: pattern %q: %w", p, err)
+		}
+	}
+	dangerous := slices.Clone(dangerousCapabilities)
+	for _, c := range cfg.DangerousCapabilities {
+		dangerous = append(dangerous, normalizeCapability(c))
+	}
+
+	severities := make(map[string]int, len(Severities))
+	for i, s := range Severities {
+		severities[s] = i
+	}
+	return &Auditor{ignore: cfg.IgnoreNamespaces, dangerous: dangerous, severities: severities}, nil
+}
+
+// Audit checks the pods of a cluster. Pods of the same workload share a spec, so each
+// workload is reported once, from its first pod.
+func (a *Auditor) Audit(clusterID string, pods []corev1.Pod) []Finding {
+	seen := map[string]bool{}
+	findings := make([]Finding, 0)
+	for i := range pods {
+		pod := &pods[i]
+		if a.ignored(pod.Namespace) {
+			continue
+		}
+		name := workload(pod)
+		key := pod.Namespace + "/" + name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		add := func(container, check, severity, message string) {
+			findings = append(findings, Finding{
+				Cluster:   clusterID,
+				Namespace: pod.Namespace,
+				Workload:  name,
+				Container: container,
+				Check:     check,
+				Severity:  severity,
+				Message:   message,
+			})
+		}
+		if pod.Spec.HostPID {
+			add("", CheckHostPID, SeverityHigh, "Shares the node's process namespace and can inspect every process on it")
+		}
+		if pod.Spec.HostIPC {
+			add("", CheckHostIPC, SeverityHigh, "Shares the node's IPC namespace and can read shared memory of other workloads")
+		}
+		for _, c := range containers(pod) {
+			a.auditContainer(pod.Spec.SecurityContext, c, add)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		fi, fj := findings[i], findings[j]
+		if a.severities[fi.Severity] != a.severities[fj.Severity] {
+			return a.severities[fi.Severity] < a.severities[fj.Severity]
+		}
+		return fi.Cluster+"/"+fi.Namespace+"/"+fi.Workload+"/"+fi.Container < fj.Cluster+"/"+fj.Namespace+"/"+fj.Workload+"/"+fj.Container
+	})
+	return findings
+}
+
+// auditContainer runs the container-level checks, falling back to the pod's security context
+// where the container does not set a field
+func (a *Auditor) auditContainer(podCtx *corev1.PodSecurityContext, c corev1.Container, add func(container, check, severity, message string)) {
+	sc := c.SecurityContext
+	if sc == nil && podCtx == nil {
+		add(c.Name, CheckMissingSecurityContext, SeverityLow, "Sets no securityContext, so it runs with the image's user and the runtime's defaults")
+		return
+	}
+	if sc != nil && sc.Privileged != nil && *sc.Privileged {
+		add(c.Name, CheckPrivileged, SeverityCritical, "Runs privileged with full access to the node's devices")
+	}
+
+	if sc != nil && sc.Capabilities != nil {
+		var critical, high []string
+		for _, raw := range sc.Capabilities.Add {
+			capability := normalizeCapability(string(raw))
+			switch {
+			case slices.Contains(criticalCapabilities, capability):
+				critical = append(critical, capability)
+			case slices.Contains(a.dangerous, capability):
+				high = append(high, capability)
+			}
+		}
+		if len(critical) > 0 {
+			add(c.Name, CheckCapabilities, SeverityCritical, "Adds capabilities "+strings.Join(append(critical, high...), ", "))
+		} else if len(high) > 0 {
+			add(c.Name, CheckCapabilities, SeverityHigh, "Adds capabilities "+strings.Join(high, ", "))
+		}
+	}
+
+	if sc != nil && sc.AllowPrivilegeEscalation != nil && *sc.AllowPrivilegeEscalation {
+		add(c.Name, CheckPrivilegeEscalation, SeverityMedium, "Allows privilege escalation through setuid binaries")
+	}
+
+	var runAsUser *int64
+	var runAsNonRoot *bool
+	if podCtx != nil {
+		runAsUser, runAsNonRoot = podCtx.RunAsUser, podCtx.RunAsNonRoot
+	}
+	if sc != nil && sc.RunAsUser != nil {
+		runAsUser = sc.RunAsUser
+	}
+	if sc != nil && sc.RunAsNonRoot != nil {
+		runAsNonRoot = sc.RunAsNonRoot
+	}
+	if runAsUser != nil && *runAsUser == 0 && (runAsNonRoot == nil || !*runAsNonRoot) {
+		add(c.Name, CheckRunAsRoot, SeverityMedium, "Runs as user 0 (root)")
+	}
+}
+
+// Rollup summarizes findings per namespace, most severe namespaces first
+func (a *Auditor) Rollup(findings []Finding) []Namespace {
+	byNamespace := map[string]*Namespace{}
+	workloads := map[string]map[string]bool{}
+	for _, f := range findings {
+		key := f.Cluster + "/" + f.Namespace
+		ns, ok := byNamespace[key]
+		if !ok {
+			ns = &Namespace{Cluster: f.Cluster, Namespace: f.Namespace, Severities: map[string]int{}, Highest: f.Severity}
+			byNamespace[key] = ns
+			workloads[key] = map[string]bool{}
+		}
+		ns.Findings++
+		ns.Severities[f.Severity]++
+		if a.severities[f.Severity] < a.severities[ns.Highest] {
+			ns.Highest = f.Severity
+		}
+		workloads[key][f.Workload] = true
+	}
+
+	rollup := make([]Namespace, 0, len(byNamespace))
+	for key, ns := range byNamespace {
+		ns.Workloads = len(workloads[key])
+		rollup = append(rollup, *ns)
+	}
+	sort.Slice(rollup, func(i, j int) bool {
+		ri, rj := rollup[i], rollup[j]
+		if a.severities[ri.Highest] != a.severities[rj.Highest] {
+			return a.severities[ri.Highest] < a.severities[rj.Highest]
+		}
+		if ri.Findings != rj.Findings {
+			return ri.Findings > rj.Findings
+		}
+		return ri.Cluster+"/"+ri.Namespace < rj.Cluster+"/"+rj.Namespace
+	})
+	return rollup
+}
+
+// AtLeast reports whether severity is at least as severe as min
+func (a *Auditor) AtLeast(severity, min string) bool {
+	return a.severities[severity] <= a.severities[min]
+}
+
+func (a *Auditor) ignored(namespace string) bool {
+	for _, p := range a.ignore {
+		if ok, _ := path.Match(p, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// containers returns the init, regular and ephemeral containers of a pod
+func containers(pod *corev1.Pod) []corev1.Container {
+	all := append(slices.Clone(pod.Spec.InitContainers), pod.Spec.Containers...)
+	for _, c := range pod.Spec.EphemeralContainers {
+		all = append(all, corev1.Container(c.EphemeralContainerCommon))
+	}
+	return all
+}
+
+// normalizeCapability uppercases a capability and drops the CAP_ prefix
+func normalizeCapability(c string) string {
+	return strings.TrimPrefix(strings.ToUpper(c), "CAP_")
+}
+
+// workload names the controller of a pod as kind/name, resolving ReplicaSets of
+// Deployments through the pod-template-hash label
+func workload(pod *corev1.Pod) string {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ref.Kind == "ReplicaSet" && hash != "" {
+			if name, ok := strings.CutSuffix(ref.Name, "-"+hash); ok {
+				return "Deployment/" + name
+			}
+		}
+		return ref.Kind + "/" + ref.Name
+	}
+	return "Pod/" + pod.Name
+}
@@ -0,0 +1,102 @@
+package podaudit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func pod(namespace, name string, spec corev1.PodSpec) corev1.Pod {
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}, Spec: spec}
+}
+
+func replica(namespace, name, deployment string, spec corev1.PodSpec) corev1.Pod {
+	controller := true
+	p := pod(namespace, name, spec)
+	p.Labels = map[string]string{"pod-template-hash": "5f7c9"}
+	p.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: deployment + "-5f7c9", Controller: &controller}}
+	return p
+}
+
+func checks(findings []Finding) []string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.Workload+"/"+f.Container+":"+f.Check+":"+f.Severity)
+	}
+	return out
+}
+
+func TestAudit(t *testing.T) {
+	a, err := New(Config{IgnoreNamespaces: []string{"kube-*"}, DangerousCapabilities: []string{"cap_chown"}})
+	require.NoError(t, err)
+
+	yes, root := true, int64(0)
+	agent := corev1.PodSpec{
+		HostPID: true,
+		Containers: []corev1.Container{{
+			Name: "agent",
+			SecurityContext: &corev1.SecurityContext{
+				Privileged:   &yes,
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN", "CAP_SYS_ADMIN"}},
+			},
+		}},
+	}
+	web := corev1.PodSpec{
+		SecurityContext: &corev1.PodSecurityContext{RunAsUser: &root},
+		InitContainers:  []corev1.Container{{Name: "init", SecurityContext: &corev1.SecurityContext{AllowPrivilegeEscalation: &yes}}},
+		Containers: []corev1.Container{{
+			Name:            "app",
+			SecurityContext: &corev1.SecurityContext{Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"CHOWN"}}},
+		}},
+	}
+
+	findings := a.Audit("prod", []corev1.Pod{
+		replica("shop", "web-5f7c9-a", "web", web),
+		replica("shop", "web-5f7c9-b", "web", web),
+		pod("monitoring", "agent", agent),
+		pod("monitoring", "plain", corev1.PodSpec{Containers: []corev1.Container{{Name: "main"}}}),
+		pod("kube-system", "proxy", agent),
+	})
+
+	assert.Equal(t, []string{
+		"Pod/agent/agent:privileged:critical",
+		"Pod/agent/agent:dangerous_capabilities:critical",
+		"Pod/agent/:host_pid:high",
+		"Deployment/web/app:dangerous_capabilities:high",
+		"Deployment/web/app:run_as_root:medium",
+		"Deployment/web/init:privilege_escalation:medium",
+		"Deployment/web/init:run_as_root:medium",
+		"Pod/plain/main:missing_security_context:low",
+	}, checks(findings))
+	assert.Equal(t, "Adds capabilities SYS_ADMIN, NET_ADMIN", findings[1].Message)
+	assert.Equal(t, "prod", findings[0].Cluster)
+}
+
+func TestRollup(t *testing.T) {
+	a, err := New(Config{})
+	require.NoError(t, err)
+
+	rollup := a.Rollup([]Finding{
+		{Cluster: "prod", Namespace: "shop", Workload: "Deployment/web", Severity: SeverityMedium},
+		{Cluster: "prod", Namespace: "shop", Workload: "Deployment/api", Severity: SeverityLow},
+		{Cluster: "prod", Namespace: "shop", Workload: "Deployment/api", Severity: SeverityMedium},
+		{Cluster: "prod", Namespace: "monitoring", Workload: "DaemonSet/agent", Severity: SeverityCritical},
+	})
+
+	require.Len(t, rollup, 2)
+	assert.Equal(t, Namespace{Cluster: "prod", Namespace: "monitoring", Workloads: 1, Findings: 1,
+		Severities: map[string]int{SeverityCritical: 1}, Highest: SeverityCritical}, rollup[0])
+	assert.Equal(t, Namespace{Cluster: "prod", Namespace: "shop", Workloads: 2, Findings: 3,
+		Severities: map[string]int{SeverityMedium: 2, SeverityLow: 1}, Highest: SeverityMedium}, rollup[1])
+
+	assert.True(t, a.AtLeast(SeverityCritical, SeverityHigh))
+	assert.False(t, a.AtLeast(SeverityLow, SeverityMedium))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(Config{IgnoreNamespaces: []string{"["}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,232 @@
+// Package precompute keeps expensive aggregations computed ahead of requests. Results are
+// warmed on startup and refreshed on a schedule, so the first request after a restart is
+// answered from memory instead of fanning out to every cluster.
+package precompute
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Sources of a served result
+const (
+	SourcePrecomputed = "precomputed" // Computed in the background before the request
+	SourceLive        = "live"        // Computed for the request
+)
+
+// Config holds precomputation settings
+type Config struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Interval     time.Duration `mapstructure:"interval"`      // Time between refreshes
+	MaxStaleness time.Duration `mapstructure:"max_staleness"` // Age after which results are reported stale
+	Timeout      time.Duration `mapstructure:"timeout"`       // Bound on a single computation
+}
+
+// Func computes an aggregation
+type Func func(ctx context.Context) (interface{}, error)
+
+// Metadata describes how fresh a served result is
+type Metadata struct {
+	Source      string     `json:"source"`
+	ComputedAt  time.Time  `json:"computed_at"`
+	AgeSeconds  float64    `json:"age_seconds"`
+	Stale       bool       `json:"stale"`                  // Older than MaxStaleness, e.g. because refreshes fail
+	DurationMs  int64      `json:"duration_ms"`            // Time the computation took
+	NextRefresh *time.Time `json:"next_refresh,omitempty"` // Omitted for live results without a schedule
+	LastError   string     `json:"last_error,omitempty"`   // Error of the latest refresh; the previous result is kept
+}
+
+// Status is the state of one registered aggregation
+type Status struct {
+	Name string `json:"name"`
+	Metadata
+	Ready bool `json:"ready"` // Computed at least once
+}
+
+type entry struct {
+	compute    Func
+	value      interface{}
+	computedAt time.Time
+	duration   time.Duration
+	lastErr    error
+	inflight   chan struct{} // Closed when the running computation finishes
+}
+
+// Cache holds the latest result of every registered aggregation
+type Cache struct {
+	config Config
+	now    func() time.Time
+
+	mu          sync.Mutex
+	entries     map[string]*entry
+	nextRefresh time.Time
+}
+
+// New creates a cache with defaults applied to unset settings
+func New(cfg Config) *Cache {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.MaxStaleness <= 0 {
+		cfg.MaxStaleness = 2 * cfg.Interval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &Cache{config: cfg, now: time.Now, entries: make(map[string]*entry)}
+}
+
+// Register adds an aggregation; it is computed by the next warm-up or refresh
+func (c *Cache) Register(name string, compute Func) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[name] = &entry{compute: compute}
+}
+
+// Start warms every aggregation immediately and then refreshes them on every interval
+// until the context is canceled
+func (c *Cache) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.mu.Lock()
+		c.nextRefresh = c.now().Add(c.config.Interval)
+		c.mu.Unlock()
+
+		c.RefreshAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshAll recomputes every aggregation concurrently and waits for them
+func (c *Cache) RefreshAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, name := range c.names() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Refresh(ctx, name); err != nil {
+				log.Warn().Err(err).Str("aggregation", name).Msg("Failed to precompute aggregation, keeping the previous result")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Refresh recomputes one aggregation. A refresh already running for it is joined instead
+// of starting another, so concurrent cold requests share one fan-out.
+func (c *Cache) Refresh(ctx context.Context, name string) error {
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	if !ok {
+		c.mu.Unlock()
+		return fmt.Errorf("unknown aggregation %q", name)
+	}
+	if wait := e.inflight; wait != nil {
+		c.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return e.lastErr
+	}
+	done := make(chan struct{})
+	e.inflight = done
+	c.mu.Unlock()
+
+	computeCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	start := c.now()
+	value, err := e.compute(computeCtx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.lastErr = err
+	if err == nil {
+		e.value, e.computedAt, e.duration = value, start, c.now().Sub(start)
+	}
+	e.inflight = nil
+	close(done)
+	return err
+}
+
+// Get returns the latest result of an aggregation and its staleness, or false while it
+// has never been computed
+func (c *Cache) Get(name string) (interface{}, Metadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[name]
+	if !ok || e.computedAt.IsZero() {
+		return nil, Metadata{}, false
+	}
+	return e.value, c.metadataLocked(e, SourcePrecomputed), true
+}
+
+// Statuses reports every registered aggregation, sorted by name
+func (c *Cache) Statuses() []Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statuses := make([]Status, 0, len(c.entries))
+	for name, e := range c.entries {
+		status := Status{Name: name, Ready: !e.computedAt.IsZero()}
+		if status.Ready {
+			status.Metadata = c.metadataLocked(e, SourcePrecomputed)
+		} else if e.lastErr != nil {
+			status.LastError = e.lastErr.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// LiveMetadata describes a result computed for the request without a cache
+func LiveMetadata(computedAt time.Time, duration time.Duration) Metadata {
+	return Metadata{Source: SourceLive, ComputedAt: computedAt.UTC(), DurationMs: duration.Milliseconds()}
+}
+
+func (c *Cache) names() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (c *Cache) metadataLocked(e *entry, source string) Metadata {
+	age := c.now().Sub(e.computedAt)
+	meta := Metadata{
+		Source:     source,
+		ComputedAt: e.computedAt.UTC(),
+		AgeSeconds: math.Round(age.Seconds()*10) / 10,
+		Stale:      age > c.config.MaxStaleness,
+		DurationMs: e.duration.Milliseconds(),
+	}
+	if !c.nextRefresh.IsZero() {
+		next := c.nextRefresh.UTC()
+		meta.NextRefresh = &next
+	}
+	if e.lastErr != nil {
+		meta.LastError = e.lastErr.Error()
+	}
+	return meta
+}
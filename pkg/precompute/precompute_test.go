@@ -0,0 +1,118 @@
+package precompute
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/internal/testclock"
+)
+
+func newTestCache(cfg Config) (*Cache, *testclock.Clock) {
+	clock := testclock.New(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	c := New(cfg)
+	c.now = clock.Now
+	return c, clock
+}
+
+func TestGet_BeforeAndAfterRefresh(t *testing.T) {
+	c, clock := newTestCache(Config{Interval: time.Minute, MaxStaleness: 2 * time.Minute})
+	c.Register("overview", func(context.Context) (interface{}, error) { return 42, nil })
+
+	_, _, ok := c.Get("overview")
+	assert.False(t, ok, "not warmed yet")
+
+	require.NoError(t, c.Refresh(context.Background(), "overview"))
+	value, meta, ok := c.Get("overview")
+	require.True(t, ok)
+	assert.Equal(t, 42, value)
+	assert.Equal(t, SourcePrecomputed, meta.Source)
+	assert.Equal(t, clock.Now(), meta.ComputedAt)
+	assert.False(t, meta.Stale)
+
+	clock.Advance(3 * time.Minute)
+	_, meta, _ = c.Get("overview")
+	assert.Equal(t, 180.0, meta.AgeSeconds)
+	assert.True(t, meta.Stale)
+}
+
+func TestRefresh_FailureKeepsPreviousResult(t *testing.T) {
+	c, _ := newTestCache(Config{})
+	fail := false
+	c.Register("overview", func(context.Context) (interface{}, error) {
+		if fail {
+			return nil, errors.New("cluster unreachable")
+		}
+		return "ok", nil
+	})
+
+	require.NoError(t, c.Refresh(context.Background(), "overview"))
+	fail = true
+	assert.Error(t, c.Refresh(context.Background(), "overview"))
+
+	value, meta, ok := c.Get("overview")
+	require.True(t, ok)
+	assert.Equal(t, "ok", value)
+	assert.Equal(t, "cluster unreachable", meta.LastError)
+
+	assert.ErrorContains(t, c.Refresh(context.Background(), "missing"), "unknown aggregation")
+}
+
+func TestRefresh_ConcurrentCallsShareOneComputation(t *testing.T) {
+	c, _ := newTestCache(Config{})
+	var calls atomic.Int32
+	release := make(chan struct{})
+	c.Register("overview", func(context.Context) (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return "ok", nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.Refresh(context.Background(), "overview"))
+		}()
+	}
+	// Let the callers reach the in-flight computation before it finishes
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestStart_WarmsImmediately(t *testing.T) {
+	c := New(Config{Interval: time.Hour})
+	c.Register("a", func(context.Context) (interface{}, error) { return 1, nil })
+	c.Register("b", func(context.Context) (interface{}, error) { return nil, errors.New("boom") })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		_, _, ok := c.Get("a")
+		return ok
+	}, time.Second, time.Millisecond)
+
+	_, meta, _ := c.Get("a")
+	require.NotNil(t, meta.NextRefresh)
+
+	statuses := c.Statuses()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "a", statuses[0].Name)
+	assert.True(t, statuses[0].Ready)
+	assert.Equal(t, "b", statuses[1].Name)
+	assert.False(t, statuses[1].Ready)
+	assert.Eventually(t, func() bool { return c.Statuses()[1].LastError == "boom" }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,77 @@
+// Package rawapi decides which resources the generic /raw endpoints may serve through the
+// dynamic client
+package rawapi
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CoreGroup names the core API group, whose real name is empty, in paths and allowlists
+const CoreGroup = "core"
+
+// denied are never served, whatever the allowlist says: their values are not redacted
+var denied = []schema.GroupResource{
+	{Group: "", Resource: "secrets"},
+}
+
+// Config lists the resources served under /raw
+type Config struct {
+	Enabled bool     `mapstructure:"enabled"`
+	Allow   []string `mapstructure:"allow"` // group/version/resource patterns, e.g. cert-manager.io/v1/certificates or argoproj.io/*/*
+}
+
+// Allowlist matches group/version/resource triples against the configured patterns
+type Allowlist struct {
+	patterns []string
+}
+
+// New validates the patterns
+func New(cfg Config) (*Allowlist, error) {
+	for _, p := range cfg.Allow {
+		if strings.Count(p, "/") != 2 {
+			return nil, fmt.Errorf("allow pattern %q must have the form group/version/resource", p)
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("allow pattern %q: %w", p, err)
+		}
+	}
+	return &Allowlist{patterns: cfg.Allow}, nil
+}
+
+// Resource maps a path's group, version and resource to the API resource, turning the core
+// alias into the empty group
+func Resource(group, version, resource string) schema.GroupVersionResource {
+	if group == CoreGroup {
+		group = ""
+	}
+	return schema.GroupVersionResource{Group: group, Version: version, Resource: resource}
+}
+
+// Key returns the group/version/resource form of a resource used in paths and allowlists
+func Key(gvr schema.GroupVersionResource) string {
+	group := gvr.Group
+	if group == "" {
+		group = CoreGroup
+	}
+	return group + "/" + gvr.Version + "/" + gvr.Resource
+}
+
+// Allowed reports whether a resource may be served
+func (a *Allowlist) Allowed(gvr schema.GroupVersionResource) bool {
+	for _, d := range denied {
+		if gvr.GroupResource() == d {
+			return false
+		}
+	}
+	key := Key(gvr)
+	for _, p := range a.patterns {
+		if matched, _ := path.Match(p, key); matched {
+			return true
+		}
+	}
+	return false
+}
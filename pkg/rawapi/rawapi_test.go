@@ -0,0 +1,34 @@
+package rawapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowlist_Allowed(t *testing.T) {
+	a, err := New(Config{Allow: []string{"cert-manager.io/v1/certificates", "argoproj.io/*/*", "core/v1/*"}})
+	require.NoError(t, err)
+
+	assert.True(t, a.Allowed(Resource("cert-manager.io", "v1", "certificates")))
+	assert.False(t, a.Allowed(Resource("cert-manager.io", "v1", "issuers")))
+	assert.True(t, a.Allowed(Resource("argoproj.io", "v1alpha1", "applications")))
+	assert.True(t, a.Allowed(Resource(CoreGroup, "v1", "events")))
+	assert.True(t, a.Allowed(Resource("", "v1", "configmaps")), "empty group is the core group")
+	assert.False(t, a.Allowed(Resource(CoreGroup, "v1", "secrets")), "secrets are never served")
+	assert.False(t, a.Allowed(Resource("apps", "v1", "deployments")))
+}
+
+func TestResource(t *testing.T) {
+	assert.Equal(t, "", Resource(CoreGroup, "v1", "pods").Group)
+	assert.Equal(t, "apps", Resource("apps", "v1", "deployments").Group)
+	assert.Equal(t, "core/v1/pods", Key(Resource(CoreGroup, "v1", "pods")))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for _, p := range []string{"certificates", "cert-manager.io/certificates", "a/b/c/d", "[/v1/x"} {
+		_, err := New(Config{Allow: []string{p}})
+		assert.Error(t, err, p)
+	}
+}
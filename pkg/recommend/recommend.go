@@ -0,0 +1,420 @@
+// Package recommend derives resource right-sizing recommendations from usage history
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DefaultAnnotationPrefix is used when no annotation prefix is configured
+const DefaultAnnotationPrefix = "recommendations.k8s-custom-controller.io/"
+
+// Recommendation states
+const (
+	StatusOK               = "ok"
+	StatusOverProvisioned  = "over-provisioned"
+	StatusUnderProvisioned = "under-provisioned"
+	StatusMissingRequests  = "missing-requests"
+	StatusInsufficientData = "insufficient-data"
+)
+
+// Minimum recommended requests, so idle containers are not starved
+const (
+	minCPUMilli    = 10
+	minMemoryBytes = 16 * 1024 * 1024
+)
+
+// Thresholds of ClassifyUsage, as shares of the requests and the memory limit
+const (
+	IdleRequestRatio = 0.3 // CPU and memory usage below this share of their requests is over-provisioned
+	MemoryLimitRatio = 0.9 // Memory usage above this share of the limit risks OOM kills
+)
+
+// Config holds recommendation settings
+type Config struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Interval         time.Duration `mapstructure:"interval"`          // Time between usage samples
+	Window           time.Duration `mapstructure:"window"`            // How much usage history is kept
+	MinSamples       int           `mapstructure:"min_samples"`       // Samples needed before recommending
+	CPUPercentile    float64       `mapstructure:"cpu_percentile"`    // CPU usage percentile the request should cover, e.g. 0.95
+	Headroom         float64       `mapstructure:"headroom"`          // Extra margin on top of observed usage, e.g. 0.15
+	Tolerance        float64       `mapstructure:"tolerance"`         // Relative difference reported as over or under provisioned
+	Annotate         bool          `mapstructure:"annotate"`          // Dry run: write recommendations as workload annotations, never change resources
+	AnnotationPrefix string        `mapstructure:"annotation_prefix"` // Prefix of recommendation annotations
+}
+
+// Resources are CPU in millicores and memory in bytes; zero means unset
+type Resources struct {
+	CPURequestMilli    int64 `json:"-"`
+	CPULimitMilli      int64 `json:"-"`
+	MemoryRequestBytes int64 `json:"-"`
+	MemoryLimitBytes   int64 `json:"-"`
+
+	CPURequest    string `json:"cpu_request,omitempty"`
+	CPULimit      string `json:"cpu_limit,omitempty"`
+	MemoryRequest string `json:"memory_request,omitempty"`
+	MemoryLimit   string `json:"memory_limit,omitempty"`
+}
+
+// format fills the quantity strings from the numeric values
+func (r Resources) format() Resources {
+	r.CPURequest = formatCPU(r.CPURequestMilli)
+	r.CPULimit = formatCPU(r.CPULimitMilli)
+	r.MemoryRequest = formatMemory(r.MemoryRequestBytes)
+	r.MemoryLimit = formatMemory(r.MemoryLimitBytes)
+	return r
+}
+
+// Container identifies a container of a workload
+type Container struct {
+	Namespace string `json:"namespace"`
+	Workload  string `json:"workload"` // Deployment name
+	Container string `json:"container"`
+}
+
+func (c Container) String() string {
+	return c.Namespace + "/" + c.Workload + "/" + c.Container
+}
+
+// Usage is the current usage of one container replica
+type Usage struct {
+	Container
+	CPUMilli    int64
+	MemoryBytes int64
+}
+
+// Spec is the configured resources of a workload container
+type Spec struct {
+	Container
+	Resources Resources
+}
+
+// Recommendation compares configured resources with observed usage
+type Recommendation struct {
+	Container
+	Status      string    `json:"status"`
+	Samples     int       `json:"samples"`
+	CPUUsage    string    `json:"cpu_usage_percentile,omitempty"`
+	MemoryPeak  string    `json:"memory_usage_peak,omitempty"`
+	Current     Resources `json:"current"`
+	Recommended Resources `json:"recommended"`
+}
+
+// UsageSource returns the current usage of all running containers
+type UsageSource func(ctx context.Context) ([]Usage, error)
+
+// SpecSource returns the configured resources of all workload containers
+type SpecSource func(ctx context.Context) ([]Spec, error)
+
+// Annotator writes annotations to a workload
+type Annotator func(ctx context.Context, namespace, workload string, annotations map[string]string) error
+
+type sample struct {
+	at          time.Time
+	cpuMilli    int64
+	memoryBytes int64
+}
+
+// Recommender samples usage periodically and recommends requests and limits
+type Recommender struct {
+	config   Config
+	usage    UsageSource
+	specs    SpecSource
+	annotate Annotator
+
+	mu              sync.RWMutex
+	history         map[Container][]sample
+	recommendations []Recommendation
+	annotated       map[string]map[string]string // Annotations last written per namespace/workload
+	lastSample      time.Time
+}
+
+// NewRecommender creates a recommender; annotate may be nil when annotations are disabled
+func NewRecommender(cfg Config, usage UsageSource, specs SpecSource, annotate Annotator) *Recommender {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 24 * time.Hour
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 30
+	}
+	if cfg.CPUPercentile <= 0 || cfg.CPUPercentile > 1 {
+		cfg.CPUPercentile = 0.95
+	}
+	if cfg.Headroom < 0 {
+		cfg.Headroom = 0
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = 0.2
+	}
+	if cfg.AnnotationPrefix == "" {
+		cfg.AnnotationPrefix = DefaultAnnotationPrefix
+	}
+	return &Recommender{
+		config:    cfg,
+		usage:     usage,
+		specs:     specs,
+		annotate:  annotate,
+		history:   make(map[Container][]sample),
+		annotated: make(map[string]map[string]string),
+	}
+}
+
+// Start samples immediately and then on every interval until the context is canceled
+func (r *Recommender) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Run(ctx, time.Now()); err != nil {
+			log.Error().Err(err).Msg("Resource recommendation run failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run records a usage sample, recomputes recommendations and writes annotations when enabled
+func (r *Recommender) Run(ctx context.Context, now time.Time) error {
+	usage, err := r.usage(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read resource usage: %w", err)
+	}
+	specs, err := r.specs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read workload resources: %w", err)
+	}
+
+	r.mu.Lock()
+	r.record(usage, now)
+	recommendations := r.compute(specs)
+	r.recommendations = recommendations
+	r.lastSample = now
+	r.mu.Unlock()
+
+	if r.config.Annotate && r.annotate != nil {
+		r.writeAnnotations(ctx, recommendations, now)
+	}
+	return nil
+}
+
+// Recommendations returns the latest recommendations, optionally limited to a namespace
+func (r *Recommender) Recommendations(namespace string) []Recommendation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]Recommendation, 0, len(r.recommendations))
+	for _, rec := range r.recommendations {
+		if namespace == "" || rec.Namespace == namespace {
+			result = append(result, rec)
+		}
+	}
+	return result
+}
+
+// LastSample returns when usage was last sampled
+func (r *Recommender) LastSample() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastSample
+}
+
+// record appends usage to the history and drops samples older than the window.
+// Replicas of a workload are sampled individually, so each replica adds a sample.
+func (r *Recommender) record(usage []Usage, now time.Time) {
+	for _, u := range usage {
+		r.history[u.Container] = append(r.history[u.Container], sample{at: now, cpuMilli: u.CPUMilli, memoryBytes: u.MemoryBytes})
+	}
+
+	cutoff := now.Add(-r.config.Window)
+	for key, samples := range r.history {
+		i := 0
+		for i < len(samples) && samples[i].at.Before(cutoff) {
+			i++
+		}
+		if i == len(samples) {
+			delete(r.history, key)
+			continue
+		}
+		r.history[key] = samples[i:]
+	}
+}
+
+// compute builds a recommendation for every workload container in specs
+func (r *Recommender) compute(specs []Spec) []Recommendation {
+	recommendations := make([]Recommendation, 0, len(specs))
+	for _, spec := range specs {
+		samples := r.history[spec.Container]
+		rec := Recommendation{
+			Container: spec.Container,
+			Samples:   len(samples),
+			Current:   spec.Resources.format(),
+		}
+
+		if len(samples) < r.config.MinSamples {
+			rec.Status = StatusInsufficientData
+			recommendations = append(recommendations, rec)
+			continue
+		}
+
+		cpu := make([]int64, len(samples))
+		var memoryPeak int64
+		for i, s := range samples {
+			cpu[i] = s.cpuMilli
+			memoryPeak = max(memoryPeak, s.memoryBytes)
+		}
+		cpuUsage := percentile(cpu, r.config.CPUPercentile)
+		rec.CPUUsage = formatCPU(cpuUsage)
+		rec.MemoryPeak = formatMemory(memoryPeak)
+
+		recommended := Resources{
+			CPURequestMilli:    roundUp(withHeadroom(cpuUsage, r.config.Headroom, minCPUMilli), 5),
+			MemoryRequestBytes: roundUp(withHeadroom(memoryPeak, r.config.Headroom, minMemoryBytes), 1024*1024),
+		}
+		// Limits keep the configured limit-to-request ratio and are only recommended when set
+		recommended.CPULimitMilli = scaleLimit(spec.Resources.CPULimitMilli, spec.Resources.CPURequestMilli, recommended.CPURequestMilli, 5)
+		recommended.MemoryLimitBytes = scaleLimit(spec.Resources.MemoryLimitBytes, spec.Resources.MemoryRequestBytes, recommended.MemoryRequestBytes, 1024*1024)
+		rec.Recommended = recommended.format()
+		rec.Status = r.status(spec.Resources, recommended)
+
+		recommendations = append(recommendations, rec)
+	}
+
+	sort.Slice(recommendations, func(i, j int) bool {
+		return recommendations[i].Container.String() < recommendations[j].Container.String()
+	})
+	return recommendations
+}
+
+// status classifies current requests against the recommended ones
+func (r *Recommender) status(current, recommended Resources) string {
+	if current.CPURequestMilli == 0 || current.MemoryRequestBytes == 0 {
+		return StatusMissingRequests
+	}
+
+	under := func(cur, rec int64) bool { return float64(cur) < float64(rec)*(1-r.config.Tolerance) }
+	over := func(cur, rec int64) bool { return float64(cur) > float64(rec)*(1+r.config.Tolerance) }
+
+	// Under-provisioning risks throttling and OOM kills, so it takes precedence
+	if under(current.CPURequestMilli, recommended.CPURequestMilli) || under(current.MemoryRequestBytes, recommended.MemoryRequestBytes) {
+		return StatusUnderProvisioned
+	}
+	if over(current.CPURequestMilli, recommended.CPURequestMilli) || over(current.MemoryRequestBytes, recommended.MemoryRequestBytes) {
+		return StatusOverProvisioned
+	}
+	return StatusOK
+}
+
+// ClassifyUsage judges a single usage sample against configured resources. Unlike the
+// recommender it needs no history: usage above a request, or memory close to its limit, is
+// under-provisioned, and usage well below both requests is over-provisioned.
+func ClassifyUsage(cpuMilli, memoryBytes int64, r Resources) string {
+	if r.CPURequestMilli == 0 || r.MemoryRequestBytes == 0 {
+		return StatusMissingRequests
+	}
+	if cpuMilli > r.CPURequestMilli || memoryBytes > r.MemoryRequestBytes ||
+		(r.MemoryLimitBytes > 0 && float64(memoryBytes) > float64(r.MemoryLimitBytes)*MemoryLimitRatio) {
+		return StatusUnderProvisioned
+	}
+	if float64(cpuMilli) < float64(r.CPURequestMilli)*IdleRequestRatio && float64(memoryBytes) < float64(r.MemoryRequestBytes)*IdleRequestRatio {
+		return StatusOverProvisioned
+	}
+	return StatusOK
+}
+
+// writeAnnotations records recommendations on their workloads when they changed since the last write
+func (r *Recommender) writeAnnotations(ctx context.Context, recommendations []Recommendation, now time.Time) {
+	byWorkload := make(map[string]map[string]string)
+	workloads := make(map[string]Container)
+	for _, rec := range recommendations {
+		if rec.Status == StatusInsufficientData {
+			continue
+		}
+		key := rec.Namespace + "/" + rec.Workload
+		if byWorkload[key] == nil {
+			byWorkload[key] = make(map[string]string)
+			workloads[key] = rec.Container
+		}
+		prefix := r.config.AnnotationPrefix + rec.Container.Container + "."
+		for name, value := range map[string]string{
+			"cpu-request":    rec.Recommended.CPURequest,
+			"cpu-limit":      rec.Recommended.CPULimit,
+			"memory-request": rec.Recommended.MemoryRequest,
+			"memory-limit":   rec.Recommended.MemoryLimit,
+		} {
+			if value != "" {
+				byWorkload[key][prefix+name] = value
+			}
+		}
+	}
+
+	for key, annotations := range byWorkload {
+		if maps.Equal(r.annotated[key], annotations) {
+			continue
+		}
+		target := workloads[key]
+		patch := maps.Clone(annotations)
+		patch[r.config.AnnotationPrefix+"updated-at"] = now.UTC().Format(time.RFC3339)
+
+		if err := r.annotate(ctx, target.Namespace, target.Workload, patch); err != nil {
+			log.Warn().Err(err).Str("namespace", target.Namespace).Str("workload", target.Workload).Msg("Failed to annotate workload with recommendations")
+			continue
+		}
+		r.annotated[key] = annotations
+	}
+}
+
+// percentile returns the nearest-rank percentile of values
+func percentile(values []int64, p float64) int64 {
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+func withHeadroom(value int64, headroom float64, minimum int64) int64 {
+	return max(value+int64(math.Round(float64(value)*headroom)), minimum)
+}
+
+func roundUp(value, step int64) int64 {
+	return (value + step - 1) / step * step
+}
+
+// scaleLimit applies the current limit-to-request ratio to the recommended request
+func scaleLimit(limit, request, recommendedRequest, step int64) int64 {
+	if limit == 0 {
+		return 0
+	}
+	ratio := 1.0
+	if request > 0 {
+		ratio = math.Max(float64(limit)/float64(request), 1)
+	}
+	return roundUp(int64(math.Ceil(float64(recommendedRequest)*ratio)), step)
+}
+
+func formatCPU(milli int64) string {
+	if milli == 0 {
+		return ""
+	}
+	return resource.NewMilliQuantity(milli, resource.DecimalSI).String()
+}
+
+func formatMemory(bytes int64) string {
+	if bytes == 0 {
+		return ""
+	}
+	return resource.NewQuantity(bytes, resource.BinarySI).String()
+}
@@ -0,0 +1,118 @@
+package recommend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var web = Container{Namespace: "shop", Workload: "web", Container: "nginx"}
+
+func staticSpecs(specs ...Spec) SpecSource {
+	return func(ctx context.Context) ([]Spec, error) { return specs, nil }
+}
+
+func TestRecommender_Run(t *testing.T) {
+	cpu := int64(100)
+	usage := func(ctx context.Context) ([]Usage, error) {
+		cpu += 10
+		return []Usage{{Container: web, CPUMilli: cpu, MemoryBytes: 100 * 1024 * 1024}}, nil
+	}
+	r := NewRecommender(Config{MinSamples: 10, CPUPercentile: 0.9, Headroom: 0.1}, usage, staticSpecs(Spec{
+		Container: web,
+		Resources: Resources{CPURequestMilli: 1000, CPULimitMilli: 2000, MemoryRequestBytes: 128 * 1024 * 1024},
+	}), nil)
+
+	start := time.Now()
+	require.NoError(t, r.Run(context.Background(), start))
+	recs := r.Recommendations("")
+	require.Len(t, recs, 1)
+	assert.Equal(t, StatusInsufficientData, recs[0].Status)
+	assert.Equal(t, "1", recs[0].Current.CPURequest)
+
+	for i := 1; i < 10; i++ {
+		require.NoError(t, r.Run(context.Background(), start.Add(time.Duration(i)*time.Minute)))
+	}
+	rec := r.Recommendations("shop")[0]
+	assert.Equal(t, 10, rec.Samples)
+	assert.Equal(t, StatusOverProvisioned, rec.Status)
+	// 90th percentile of 110..200m is 190m, plus 10% headroom rounded up to 5m
+	assert.Equal(t, "190m", rec.CPUUsage)
+	assert.Equal(t, "210m", rec.Recommended.CPURequest)
+	assert.Equal(t, "420m", rec.Recommended.CPULimit, "limit keeps the 2:1 ratio")
+	assert.Equal(t, "110Mi", rec.Recommended.MemoryRequest)
+	assert.Empty(t, rec.Recommended.MemoryLimit, "unset limits are not recommended")
+	assert.Empty(t, r.Recommendations("other"))
+}
+
+func TestRecommender_WindowAndStatus(t *testing.T) {
+	usage := func(ctx context.Context) ([]Usage, error) {
+		return []Usage{{Container: web, CPUMilli: 500, MemoryBytes: 512 * 1024 * 1024}}, nil
+	}
+	r := NewRecommender(Config{MinSamples: 2, Window: time.Hour}, usage, staticSpecs(
+		Spec{Container: web, Resources: Resources{CPURequestMilli: 100, MemoryRequestBytes: 256 * 1024 * 1024}},
+		Spec{Container: Container{Namespace: "shop", Workload: "api", Container: "api"}},
+	), nil)
+
+	start := time.Now()
+	require.NoError(t, r.Run(context.Background(), start))
+	require.NoError(t, r.Run(context.Background(), start.Add(time.Minute)))
+	recs := r.Recommendations("shop")
+	require.Len(t, recs, 2)
+	assert.Equal(t, "api", recs[0].Workload)
+	assert.Equal(t, StatusInsufficientData, recs[0].Status)
+	assert.Equal(t, StatusUnderProvisioned, recs[1].Status)
+
+	// Samples older than the window are dropped
+	require.NoError(t, r.Run(context.Background(), start.Add(2*time.Hour)))
+	assert.Equal(t, 1, r.Recommendations("shop")[1].Samples)
+}
+
+func TestRecommender_Annotate(t *testing.T) {
+	usage := func(ctx context.Context) ([]Usage, error) {
+		return []Usage{{Container: web, CPUMilli: 50, MemoryBytes: 64 * 1024 * 1024}}, nil
+	}
+	var writes []map[string]string
+	annotate := func(ctx context.Context, namespace, workload string, annotations map[string]string) error {
+		assert.Equal(t, "shop", namespace)
+		assert.Equal(t, "web", workload)
+		writes = append(writes, annotations)
+		return nil
+	}
+	r := NewRecommender(Config{MinSamples: 1, Annotate: true}, usage, staticSpecs(Spec{Container: web}), annotate)
+
+	require.NoError(t, r.Run(context.Background(), time.Now()))
+	require.Len(t, writes, 1)
+	assert.Equal(t, "50m", writes[0][DefaultAnnotationPrefix+"nginx.cpu-request"])
+	assert.Equal(t, "64Mi", writes[0][DefaultAnnotationPrefix+"nginx.memory-request"])
+	assert.Contains(t, writes[0], DefaultAnnotationPrefix+"updated-at")
+
+	// Unchanged recommendations are not written again
+	require.NoError(t, r.Run(context.Background(), time.Now()))
+	assert.Len(t, writes, 1)
+}
+
+func TestRecommender_SourceError(t *testing.T) {
+	usage := func(ctx context.Context) ([]Usage, error) { return nil, errors.New("metrics API unavailable") }
+	r := NewRecommender(Config{}, usage, staticSpecs(), nil)
+	assert.ErrorContains(t, r.Run(context.Background(), time.Now()), "metrics API unavailable")
+}
+
+func TestClassifyUsage(t *testing.T) {
+	const mi = 1024 * 1024
+	resources := Resources{CPURequestMilli: 200, MemoryRequestBytes: 256 * mi, MemoryLimitBytes: 280 * mi}
+
+	assert.Equal(t, StatusOK, ClassifyUsage(120, 128*mi, resources))
+	assert.Equal(t, StatusUnderProvisioned, ClassifyUsage(250, 128*mi, resources), "cpu above its request")
+	assert.Equal(t, StatusUnderProvisioned, ClassifyUsage(100, 260*mi, resources), "memory above its request")
+	assert.Equal(t, StatusOverProvisioned, ClassifyUsage(20, 32*mi, resources))
+	assert.Equal(t, StatusOK, ClassifyUsage(20, 128*mi, resources), "only idle when both are well below requests")
+	assert.Equal(t, StatusMissingRequests, ClassifyUsage(20, 32*mi, Resources{CPURequestMilli: 100}))
+
+	resources = Resources{CPURequestMilli: 200, MemoryRequestBytes: 512 * mi, MemoryLimitBytes: 512 * mi}
+	assert.Equal(t, StatusUnderProvisioned, ClassifyUsage(100, 480*mi, resources), "memory close to its limit")
+}
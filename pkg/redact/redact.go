@@ -0,0 +1,238 @@
+// Package redact masks credentials in structured log output
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DefaultReplacement is written in place of redacted values
+const DefaultReplacement = "[REDACTED]"
+
+// DefaultPatterns are matched against field names, case-insensitively and as substrings
+var DefaultPatterns = []string{
+	"token",
+	"password",
+	"passwd",
+	"secret",
+	"authorization",
+	"api_key",
+	"apikey",
+	"kubeconfig",
+	"cookie",
+	"private_key",
+}
+
+// credentialValue matches credentials embedded in free text, such as a logged header value
+var credentialValue = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`)
+
+// Config holds log redaction settings
+type Config struct {
+	Enabled     bool     `mapstructure:"enabled"`
+	Patterns    []string `mapstructure:"patterns"` // Field name fragments whose values are masked
+	Replacement string   `mapstructure:"replacement"`
+}
+
+// Redactor masks the values of sensitive fields in JSON log lines
+type Redactor struct {
+	patterns    []string
+	replacement string
+}
+
+// New creates a redactor, or returns nil when redaction is disabled
+func New(cfg Config) *Redactor {
+	if !cfg.Enabled {
+		return nil
+	}
+	patterns := cfg.Patterns
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+	r := &Redactor{replacement: cfg.Replacement}
+	if r.replacement == "" {
+		r.replacement = DefaultReplacement
+	}
+	for _, p := range patterns {
+		if p = normalize(p); p != "" {
+			r.patterns = append(r.patterns, p)
+		}
+	}
+	return r
+}
+
+// Sensitive reports whether a field name matches one of the patterns. Dashes and dots are
+// treated as underscores, so X-API-Key and api.key both match api_key.
+func (r *Redactor) Sensitive(field string) bool {
+	field = normalize(field)
+	for _, p := range r.patterns {
+		if strings.Contains(field, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns the log line with sensitive values masked. Field order is preserved, and
+// lines that are not JSON objects are returned unchanged.
+func (r *Redactor) Redact(line []byte) []byte {
+	if !r.mayContainSecret(line) {
+		return line
+	}
+
+	trimmed := bytes.TrimRight(line, "\r\n")
+	out, err := r.redactValue(trimmed)
+	if err != nil {
+		return line
+	}
+	return append(out, line[len(trimmed):]...)
+}
+
+// RedactText masks bearer and basic credentials in free text such as a header value
+func (r *Redactor) RedactText(s string) string {
+	return credentialValue.ReplaceAllString(s, "$1 "+r.replacement)
+}
+
+// Replacement returns the text written in place of redacted values
+func (r *Redactor) Replacement() string {
+	return r.replacement
+}
+
+// mayContainSecret is a cheap pre-check so ordinary lines skip JSON decoding
+func (r *Redactor) mayContainSecret(line []byte) bool {
+	return r.Sensitive(string(line)) || credentialValue.Match(line)
+}
+
+// redactValue rewrites a JSON value, masking sensitive object fields at any depth
+func (r *Redactor) redactValue(raw []byte) ([]byte, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return raw, nil
+	}
+
+	switch raw[0] {
+	case '{':
+		return r.redactObject(raw)
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			redacted, err := r.redactValue(item)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(redacted)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		if !credentialValue.MatchString(s) {
+			return raw, nil
+		}
+		return marshalString(credentialValue.ReplaceAllString(s, "$1 "+r.replacement))
+	default:
+		return raw, nil
+	}
+}
+
+// redactObject walks the object's fields in order so the output keeps the original layout
+func (r *Redactor) redactObject(raw []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for first := true; dec.More(); first = false {
+		keyToken, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := keyToken.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		if !first {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := marshalString(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+
+		if r.Sensitive(key) {
+			value, err = marshalString(r.replacement)
+		} else {
+			value, err = r.redactValue(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Writer redacts each log line before passing it on. A zerolog.Hook cannot read or
+// replace fields that were already added to an event, so redaction sits between the
+// logger and its output instead.
+type Writer struct {
+	redactor *Redactor
+	out      io.Writer
+}
+
+// NewWriter wraps out; a nil redactor passes lines through unchanged
+func NewWriter(r *Redactor, out io.Writer) *Writer {
+	return &Writer{redactor: r, out: out}
+}
+
+// Write redacts one log line. It reports the length of the original line so the logger
+// does not treat a shorter or longer redacted line as a short write.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.redactor == nil {
+		return w.out.Write(p)
+	}
+	if _, err := w.out.Write(w.redactor.Redact(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// separators are folded to underscores before matching
+var separators = strings.NewReplacer("-", "_", ".", "_")
+
+func normalize(s string) string {
+	return separators.Replace(strings.ToLower(strings.TrimSpace(s)))
+}
+
+func marshalString(s string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
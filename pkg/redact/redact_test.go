@@ -0,0 +1,66 @@
+package redact
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_Disabled(t *testing.T) {
+	assert.Nil(t, New(Config{}))
+}
+
+func TestSensitive(t *testing.T) {
+	r := New(Config{Enabled: true})
+
+	assert.True(t, r.Sensitive("Authorization"))
+	assert.True(t, r.Sensitive("X-API-Key"))
+	assert.True(t, r.Sensitive("csrf_token"))
+	assert.True(t, r.Sensitive("kubeconfig"))
+	assert.False(t, r.Sensitive("namespace"))
+
+	custom := New(Config{Enabled: true, Patterns: []string{"ssn"}})
+	assert.True(t, custom.Sensitive("user_ssn"))
+	assert.False(t, custom.Sensitive("password"))
+}
+
+func TestRedact(t *testing.T) {
+	r := New(Config{Enabled: true})
+
+	line := []byte(`{"level":"info","password":"hunter2","config":{"api_key":"abc","port":8080},"items":[{"token":"t"}],"message":"ok"}` + "\n")
+	assert.Equal(t,
+		`{"level":"info","password":"[REDACTED]","config":{"api_key":"[REDACTED]","port":8080},"items":[{"token":"[REDACTED]"}],"message":"ok"}`+"\n",
+		string(r.Redact(line)))
+
+	// Credentials inside free text are masked too
+	assert.Equal(t, `{"header":"Bearer [REDACTED]"}`, string(r.Redact([]byte(`{"header":"Bearer eyJhbGciOi.J9.x"}`))))
+
+	// Lines without sensitive fields and non-JSON lines are untouched
+	plain := []byte(`{"level":"info","b":1,"a":2}`)
+	assert.Equal(t, plain, r.Redact(plain))
+	assert.Equal(t, "password: x", string(r.Redact([]byte("password: x"))))
+}
+
+func TestRedactText(t *testing.T) {
+	r := New(Config{Enabled: true, Replacement: "***"})
+
+	assert.Equal(t, "Bearer ***", r.RedactText("Bearer eyJhbGciOi.J9.x"))
+	assert.Equal(t, "retry with Basic *** later", r.RedactText("retry with Basic dXNlcjpwYXNz later"))
+	assert.Equal(t, "application/json", r.RedactText("application/json"))
+	assert.Equal(t, "***", r.Replacement())
+}
+
+func TestWriter_WithZerolog(t *testing.T) {
+	var out bytes.Buffer
+	logger := zerolog.New(NewWriter(New(Config{Enabled: true, Replacement: "***"}), &out))
+
+	logger.Info().Str("Authorization", "Basic dXNlcjpwYXNz").Str("namespace", "default").Msg("request")
+	assert.Equal(t, `{"level":"info","Authorization":"***","namespace":"default","message":"request"}`+"\n", out.String())
+
+	out.Reset()
+	passthrough := zerolog.New(NewWriter(nil, &out))
+	passthrough.Info().Str("password", "x").Msg("")
+	assert.Contains(t, out.String(), `"password":"x"`)
+}
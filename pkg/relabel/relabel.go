@@ -0,0 +1,127 @@
+// Package relabel computes the label and annotation changes of bulk relabeling requests as
+// merge patches, so objects already in the desired state are left untouched
+package relabel
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DefaultMaxObjects bounds how many objects one request may change
+const DefaultMaxObjects = 500
+
+// Config holds bulk relabeling settings
+type Config struct {
+	MaxObjects int `mapstructure:"max_objects"` // Requests matching more objects are refused without changing any
+}
+
+// Change adds and removes keys of a label or annotation map
+type Change struct {
+	Add    map[string]string `json:"add,omitempty"`
+	Remove []string          `json:"remove,omitempty"`
+}
+
+// Spec is the label and annotation change applied to every matched object
+type Spec struct {
+	Labels      Change `json:"labels"`
+	Annotations Change `json:"annotations"`
+}
+
+// Diff is what a spec changes on one object
+type Diff struct {
+	Added   map[string]string `json:"added,omitempty"`   // Keys set or given a new value
+	Removed []string          `json:"removed,omitempty"` // Keys that were present and are deleted
+}
+
+// Empty reports whether the diff changes nothing
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// Validate checks label keys and values and annotation keys like the API server does, and
+// that no key is both added and removed
+func (s Spec) Validate() error {
+	if s.Labels.empty() && s.Annotations.empty() {
+		return fmt.Errorf("no labels or annotations to add or remove")
+	}
+	for key, value := range s.Labels.Add {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid value %q of label %s: %s", value, key, strings.Join(errs, "; "))
+		}
+	}
+	for key := range s.Annotations.Add {
+		if errs := validation.IsQualifiedName(strings.ToLower(key)); len(errs) > 0 {
+			return fmt.Errorf("invalid annotation key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	for _, c := range []struct {
+		what   string
+		change Change
+	}{{"label", s.Labels}, {"annotation", s.Annotations}} {
+		for _, key := range c.change.Remove {
+			if _, ok := c.change.Add[key]; ok {
+				return fmt.Errorf("%s %s is both added and removed", c.what, key)
+			}
+		}
+	}
+	return nil
+}
+
+// Patch returns the JSON merge patch applying the spec to an object, and the label and
+// annotation diffs. The patch is nil when the object already matches.
+func (s Spec) Patch(obj metav1.Object) ([]byte, Diff, Diff, error) {
+	labels, labelDiff := s.Labels.apply(obj.GetLabels())
+	annotations, annotationDiff := s.Annotations.apply(obj.GetAnnotations())
+	if labelDiff.Empty() && annotationDiff.Empty() {
+		return nil, labelDiff, annotationDiff, nil
+	}
+
+	metadata := map[string]interface{}{}
+	if len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	// The resource version makes the patch fail instead of overwriting a concurrent change
+	metadata["resourceVersion"] = obj.GetResourceVersion()
+	patch, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	return patch, labelDiff, annotationDiff, err
+}
+
+func (c Change) empty() bool {
+	return len(c.Add) == 0 && len(c.Remove) == 0
+}
+
+// apply returns the merge patch entries for current: new values, and nil for removed keys
+func (c Change) apply(current map[string]string) (map[string]interface{}, Diff) {
+	patch := map[string]interface{}{}
+	diff := Diff{}
+	for key, value := range c.Add {
+		if existing, ok := current[key]; ok && existing == value {
+			continue
+		}
+		patch[key] = value
+		if diff.Added == nil {
+			diff.Added = map[string]string{}
+		}
+		diff.Added[key] = value
+	}
+	for _, key := range c.Remove {
+		if _, ok := current[key]; !ok {
+			continue
+		}
+		patch[key] = nil
+		diff.Removed = append(diff.Removed, key)
+	}
+	sort.Strings(diff.Removed)
+	return patch, diff
+}
@@ -0,0 +1,51 @@
+package relabel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Spec{
+		Labels:      Change{Add: map[string]string{"team": "payments", "example.com/tier": "gold"}, Remove: []string{"owner"}},
+		Annotations: Change{Add: map[string]string{"example.com/Contact": "Payments team <pay@example.com>"}},
+	}
+	assert.NoError(t, valid.Validate())
+
+	for name, spec := range map[string]Spec{
+		"empty":             {},
+		"bad label key":     {Labels: Change{Add: map[string]string{"bad key": "x"}}},
+		"bad label value":   {Labels: Change{Add: map[string]string{"team": "not a value"}}},
+		"bad annotation":    {Annotations: Change{Add: map[string]string{"/x": "y"}}},
+		"added and removed": {Labels: Change{Add: map[string]string{"team": "a"}, Remove: []string{"team"}}},
+	} {
+		assert.Error(t, spec.Validate(), name)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	spec := Spec{
+		Labels:      Change{Add: map[string]string{"team": "payments", "env": "prod"}, Remove: []string{"owner", "missing"}},
+		Annotations: Change{Remove: []string{"legacy"}},
+	}
+	obj := &metav1.ObjectMeta{
+		ResourceVersion: "42",
+		Labels:          map[string]string{"env": "prod", "owner": "alice"},
+	}
+
+	patch, labels, annotations, err := spec.Patch(obj)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"metadata":{"labels":{"team":"payments","owner":null},"resourceVersion":"42"}}`, string(patch))
+	assert.Equal(t, Diff{Added: map[string]string{"team": "payments"}, Removed: []string{"owner"}}, labels)
+	assert.True(t, annotations.Empty())
+
+	// Objects already in the desired state are not patched
+	obj.Labels = map[string]string{"env": "prod", "team": "payments"}
+	patch, labels, _, err = spec.Patch(obj)
+	require.NoError(t, err)
+	assert.Nil(t, patch)
+	assert.True(t, labels.Empty())
+}
@@ -0,0 +1,197 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Delivery channels
+const (
+	ChannelEmail   = "email"
+	ChannelSlack   = "slack"
+	ChannelWebhook = "webhook"
+)
+
+// maxSlackSummaryLines bounds the summary posted to Slack, which cannot receive attachments
+const maxSlackSummaryLines = 10
+
+// mailSender sends a message through an SMTP server, smtp.SendMail outside tests
+type mailSender func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// webhookPayload is the body posted to a definition's webhook_url
+type webhookPayload struct {
+	*Report
+	Format      string `json:"format"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"` // Rendered attachment, base64-encoded
+}
+
+// deliver sends the report through every channel the definition configures and returns the
+// channels that succeeded with the first error
+func (s *Scheduler) deliver(ctx context.Context, def Definition, r *Report) ([]string, error) {
+	format := def.Format
+	attachment, err := r.Render(format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+
+	var delivered []string
+	var firstErr error
+	record := func(channel string, err error) {
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s delivery failed: %w", channel, err)
+			}
+			return
+		}
+		delivered = append(delivered, channel)
+	}
+	if len(def.Email) > 0 {
+		record(ChannelEmail, s.sendEmail(def, r, attachment, format))
+	}
+	if def.SlackWebhookURL != "" {
+		record(ChannelSlack, s.postJSON(ctx, def.SlackWebhookURL, map[string]string{"text": slackText(r)}))
+	}
+	if def.WebhookURL != "" {
+		record(ChannelWebhook, s.postJSON(ctx, def.WebhookURL, webhookPayload{
+			Report:      r,
+			Format:      format,
+			Filename:    r.Filename(format),
+			ContentType: ContentType(format),
+			Content:     attachment,
+		}))
+	}
+	return delivered, firstErr
+}
+
+// sendEmail mails the report as an HTML body with the rendered attachment
+func (s *Scheduler) sendEmail(def Definition, r *Report, attachment []byte, format string) error {
+	smtpCfg := s.config.SMTP
+	if smtpCfg.Host == "" || smtpCfg.From == "" {
+		return fmt.Errorf("reports.smtp.host and reports.smtp.from are required for email delivery")
+	}
+	body, err := r.Render(FormatHTML)
+	if err != nil {
+		return err
+	}
+	msg, err := mailMessage(smtpCfg.From, def.Email, r.Title, body, r.Filename(format), ContentType(format), attachment)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if smtpCfg.Username != "" {
+		auth = smtp.PlainAuth("", smtpCfg.Username, smtpCfg.Password, smtpCfg.Host)
+	}
+	port := smtpCfg.Port
+	if port == 0 {
+		port = 587
+	}
+	return s.sendMail(smtpCfg.Host+":"+strconv.Itoa(port), auth, smtpCfg.From, def.Email, msg)
+}
+
+// mailMessage builds a multipart/mixed message with an HTML body and one attachment
+func mailMessage(from string, to []string, subject string, html []byte, filename, contentType string, attachment []byte) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(part, html); err != nil {
+		return nil, err
+	}
+
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": filename})},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(part, attachment); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// writeBase64 writes data base64-encoded in lines of 76 characters
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:76]); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := fmt.Fprintf(w, "%s\r\n", encoded)
+	return err
+}
+
+// slackText summarizes the report for a Slack-compatible webhook
+func slackText(r *Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*", r.Title)
+	for i, line := range r.Summary {
+		if i == maxSlackSummaryLines {
+			fmt.Fprintf(&b, "\n• … and %d more", len(r.Summary)-i)
+			break
+		}
+		b.WriteString("\n• " + line)
+	}
+	fmt.Fprintf(&b, "\n%d rows, generated %s", len(r.Rows), r.GeneratedAt.UTC().Format(time.RFC3339))
+	return b.String()
+}
+
+// postJSON posts a JSON body and treats non-2xx responses as errors
+func (s *Scheduler) postJSON(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
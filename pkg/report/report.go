@@ -0,0 +1,147 @@
+// Package report renders scheduled reports such as inventory, drift, SLO and cost as HTML or
+// CSV and delivers them by email, Slack or webhook
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// Report kinds
+const (
+	KindInventory = "inventory"
+	KindDrift     = "drift"
+	KindSLO       = "slo"
+	KindCost      = "cost"
+)
+
+// Formats reports are rendered in
+const (
+	FormatHTML = "html"
+	FormatCSV  = "csv"
+)
+
+// Config holds the report definitions and how they are delivered
+type Config struct {
+	Enabled bool         `mapstructure:"enabled"`
+	SMTP    SMTPConfig   `mapstructure:"smtp"`
+	Pricing Pricing      `mapstructure:"pricing"`
+	Reports []Definition `mapstructure:"reports"`
+}
+
+// SMTPConfig is the mail server email deliveries are sent through
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"` // Empty sends without authentication
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// Pricing sets the unit prices cost reports multiply resource requests by
+type Pricing struct {
+	Currency         string  `mapstructure:"currency"`
+	CPUCoreMonthly   float64 `mapstructure:"cpu_core_monthly"`   // Price of one requested CPU core for a month
+	MemoryGiBMonthly float64 `mapstructure:"memory_gib_monthly"` // Price of one requested GiB of memory for a month
+}
+
+// Definition is one configured report
+type Definition struct {
+	Name            string   `mapstructure:"name" json:"name"`
+	Kind            string   `mapstructure:"kind" json:"kind"`                       // inventory, drift, slo or cost
+	Cron            string   `mapstructure:"cron" json:"cron"`                       // Five-field cron expression
+	Timezone        string   `mapstructure:"timezone" json:"timezone,omitempty"`     // IANA zone for Cron, defaults to UTC
+	Format          string   `mapstructure:"format" json:"format"`                   // Attachment format: html (default) or csv
+	Cluster         string   `mapstructure:"cluster" json:"cluster,omitempty"`       // Cluster to report on, all clusters when empty
+	Namespace       string   `mapstructure:"namespace" json:"namespace,omitempty"`   // Namespace to report on, all namespaces when empty
+	SLOTarget       float64  `mapstructure:"slo_target" json:"slo_target,omitempty"` // Availability target in percent for slo reports
+	Email           []string `mapstructure:"email" json:"email,omitempty"`           // Recipients
+	SlackWebhookURL string   `mapstructure:"slack_webhook_url" json:"-"`             // Slack-compatible incoming webhook
+	WebhookURL      string   `mapstructure:"webhook_url" json:"-"`                   // Receives the rendered report as JSON
+}
+
+// Report is a rendered table with a title and summary lines
+type Report struct {
+	Name        string     `json:"name"`
+	Kind        string     `json:"kind"`
+	Title       string     `json:"title"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Summary     []string   `json:"summary,omitempty"`
+	Columns     []string   `json:"columns"`
+	Rows        [][]string `json:"rows"`
+}
+
+// Source builds the title, summary and rows of a definition's report; the scheduler fills in
+// its name, kind and generation time. Sources are registered per kind.
+type Source func(ctx context.Context, def Definition) (*Report, error)
+
+// Filename names the attachment of a report in the given format
+func (r *Report) Filename(format string) string {
+	return fmt.Sprintf("%s-%s.%s", r.Name, r.GeneratedAt.UTC().Format("20060102-1504"), format)
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "text/html; charset=utf-8"
+}
+
+// Render encodes the report in the given format
+func (r *Report) Render(format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case FormatCSV:
+		w := csv.NewWriter(&buf)
+		if err := w.Write(r.Columns); err != nil {
+			return nil, err
+		}
+		if err := w.WriteAll(r.Rows); err != nil {
+			return nil, err
+		}
+	case FormatHTML, "":
+		if err := htmlTemplate.Execute(&buf, r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	return buf.Bytes(), nil
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+</style>
+</head>
+<body>
+<h2>{{.Title}}</h2>
+<p>Generated {{.GeneratedAt.UTC.Format "2006-01-02 15:04 MST"}}</p>
+{{- if .Summary}}
+<ul>
+{{- range .Summary}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
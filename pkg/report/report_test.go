@@ -0,0 +1,164 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func staticSource(ctx context.Context, def Definition) (*Report, error) {
+	return &Report{
+		Title:   "Inventory of " + def.Namespace,
+		Summary: []string{"2 workloads"},
+		Columns: []string{"name", "image"},
+		Rows:    [][]string{{"web", "web:1"}, {"api", "<api:2>"}},
+	}, nil
+}
+
+func TestReport_Render(t *testing.T) {
+	r, _ := staticSource(context.Background(), Definition{Namespace: "shop"})
+
+	out, err := r.Render(FormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, "name,image\nweb,web:1\napi,<api:2>\n", string(out))
+
+	out, err = r.Render(FormatHTML)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "<h2>Inventory of shop</h2>")
+	assert.Contains(t, string(out), "<td>&lt;api:2&gt;</td>", "cell values are escaped")
+	assert.Contains(t, string(out), "<li>2 workloads</li>")
+
+	_, err = r.Render("pdf")
+	assert.Error(t, err)
+}
+
+func TestNewScheduler_Invalid(t *testing.T) {
+	sources := map[string]Source{KindInventory: staticSource, KindSLO: staticSource}
+	valid := Definition{Name: "weekly", Kind: KindInventory, Cron: "0 8 * * 1", WebhookURL: "http://example.com"}
+
+	for _, mutate := range []func(d *Definition){
+		func(d *Definition) { d.Name = "" },
+		func(d *Definition) { d.Kind = KindCost },
+		func(d *Definition) { d.Cron = "weekly" },
+		func(d *Definition) { d.Timezone = "Mars/Olympus" },
+		func(d *Definition) { d.Format = "pdf" },
+		func(d *Definition) { d.WebhookURL = "" },
+		func(d *Definition) { d.Kind, d.SLOTarget = KindSLO, 120 },
+	} {
+		def := valid
+		mutate(&def)
+		_, err := NewScheduler(Config{Reports: []Definition{def}}, sources)
+		assert.Error(t, err, "%+v", def)
+	}
+
+	_, err := NewScheduler(Config{Reports: []Definition{valid, valid}}, sources)
+	assert.Error(t, err, "duplicate names")
+
+	slo := valid
+	slo.Kind = KindSLO
+	s, err := NewScheduler(Config{Reports: []Definition{slo}}, sources)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSLOTarget, s.reports[0].SLOTarget)
+	assert.Equal(t, FormatHTML, s.reports[0].Format)
+}
+
+func TestScheduler_Due(t *testing.T) {
+	s, err := NewScheduler(Config{Reports: []Definition{
+		{Name: "utc", Kind: KindInventory, Cron: "0 8 * * *", WebhookURL: "http://example.com"},
+		{Name: "kyiv", Kind: KindInventory, Cron: "0 8 * * *", Timezone: "Europe/Kyiv", WebhookURL: "http://example.com"},
+	}}, map[string]Source{KindInventory: staticSource})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"utc"}, s.due(time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)))
+	assert.Equal(t, []string{"kyiv"}, s.due(time.Date(2025, 6, 2, 5, 0, 0, 0, time.UTC)), "08:00 in Kyiv is 05:00 UTC in summer")
+
+	statuses := s.Status(time.Date(2025, 6, 2, 7, 0, 0, 0, time.UTC))
+	require.Len(t, statuses, 2)
+	assert.Equal(t, time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC), statuses[0].NextRun.UTC())
+	assert.Equal(t, []string{ChannelWebhook}, statuses[0].Channels)
+}
+
+func TestScheduler_Run(t *testing.T) {
+	var slack map[string]string
+	var webhook map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slack":
+			json.NewDecoder(r.Body).Decode(&slack)
+		case "/hook":
+			json.NewDecoder(r.Body).Decode(&webhook)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewScheduler(Config{
+		SMTP: SMTPConfig{Host: "smtp.example.com", From: "reports@example.com"},
+		Reports: []Definition{{
+			Name: "weekly", Kind: KindInventory, Cron: "0 8 * * 1", Format: FormatCSV, Namespace: "shop",
+			Email: []string{"team@example.com"}, SlackWebhookURL: server.URL + "/slack", WebhookURL: server.URL + "/hook",
+		}},
+	}, map[string]Source{KindInventory: staticSource})
+	require.NoError(t, err)
+
+	var mail string
+	s.sendMail = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		assert.Equal(t, "smtp.example.com:587", addr)
+		assert.Nil(t, auth, "no credentials configured")
+		assert.Equal(t, []string{"team@example.com"}, to)
+		mail = string(msg)
+		return nil
+	}
+
+	run, err := s.Run(context.Background(), "weekly", TriggerManual)
+	require.NoError(t, err)
+	assert.Equal(t, []string{ChannelEmail, ChannelSlack, ChannelWebhook}, run.Delivered)
+	assert.Equal(t, 2, run.Rows)
+
+	assert.Contains(t, mail, "Subject: Inventory of shop")
+	assert.Contains(t, mail, `filename=weekly-`)
+	assert.Contains(t, mail, "Content-Type: text/csv")
+	assert.True(t, strings.HasPrefix(slack["text"], "*Inventory of shop*\n• 2 workloads"))
+	assert.Equal(t, "weekly", webhook["name"])
+	assert.Equal(t, "text/csv; charset=utf-8", webhook["content_type"])
+
+	_, err = s.Run(context.Background(), "missing", TriggerManual)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, run, s.Status(time.Now())[0].LastRun)
+}
+
+func TestScheduler_RunDeliveryFailure(t *testing.T) {
+	s, err := NewScheduler(Config{Reports: []Definition{
+		{Name: "weekly", Kind: KindInventory, Cron: "0 8 * * 1", Email: []string{"team@example.com"}, WebhookURL: "http://example.com"},
+	}}, map[string]Source{KindInventory: staticSource})
+	require.NoError(t, err)
+	s.client = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})}
+
+	run, err := s.Run(context.Background(), "weekly", TriggerManual)
+	require.Error(t, err, "email without smtp settings fails")
+	assert.Equal(t, []string{ChannelWebhook}, run.Delivered, "other channels are still delivered")
+	assert.Contains(t, run.Error, "email delivery failed")
+
+	failing := map[string]Source{KindInventory: func(context.Context, Definition) (*Report, error) { return nil, errors.New("boom") }}
+	s, err = NewScheduler(Config{Reports: []Definition{{Name: "weekly", Kind: KindInventory, Cron: "0 8 * * 1", WebhookURL: "http://example.com"}}}, failing)
+	require.NoError(t, err)
+	run, err = s.Run(context.Background(), "weekly", TriggerSchedule)
+	require.Error(t, err)
+	assert.Empty(t, run.Delivered)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
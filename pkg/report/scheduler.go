@@ -0,0 +1,258 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"sync"
+	"time"
+	_ "time/tzdata" // Report timezones must resolve in minimal container images
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/cron"
+)
+
+// DefaultSLOTarget is the availability target of slo reports that do not set one
+const DefaultSLOTarget = 99.9
+
+// runTimeout bounds building and delivering one report
+const runTimeout = 5 * time.Minute
+
+// Triggers of a run
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var (
+	// ErrNotFound is returned by Run for names no definition has
+	ErrNotFound = errors.New("report not found")
+	// ErrRunning is returned by Run while the report is already being generated
+	ErrRunning = errors.New("report is already running")
+)
+
+// Run is the outcome of generating and delivering a report once
+type Run struct {
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Trigger    string    `json:"trigger"`
+	Rows       int       `json:"rows"`
+	Delivered  []string  `json:"delivered"` // Channels the report reached
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status is a definition with its next scheduled and last completed run
+type Status struct {
+	Definition
+	Channels []string   `json:"channels"`
+	NextRun  *time.Time `json:"next_run,omitempty"`
+	LastRun  *Run       `json:"last_run,omitempty"`
+}
+
+type scheduledReport struct {
+	Definition
+	schedule *cron.Schedule
+	location *time.Location
+}
+
+// Scheduler generates reports on their cron schedules and delivers them
+type Scheduler struct {
+	config   Config
+	sources  map[string]Source
+	reports  []scheduledReport
+	client   *http.Client
+	sendMail mailSender
+
+	mu      sync.Mutex
+	running map[string]bool
+	last    map[string]*Run
+}
+
+// NewScheduler validates the report definitions against the registered sources
+func NewScheduler(cfg Config, sources map[string]Source) (*Scheduler, error) {
+	s := &Scheduler{
+		config:   cfg,
+		sources:  sources,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		sendMail: smtp.SendMail,
+		running:  make(map[string]bool),
+		last:     make(map[string]*Run),
+	}
+
+	names := make(map[string]bool)
+	for i, def := range cfg.Reports {
+		if def.Name == "" {
+			return nil, fmt.Errorf("reports[%d]: name is required", i)
+		}
+		if names[def.Name] {
+			return nil, fmt.Errorf("reports[%d]: duplicate name %q", i, def.Name)
+		}
+		names[def.Name] = true
+
+		if _, ok := sources[def.Kind]; !ok {
+			return nil, fmt.Errorf("reports[%d]: unsupported kind %q", i, def.Kind)
+		}
+		sched, err := cron.Parse(def.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("reports[%d]: invalid cron: %w", i, err)
+		}
+		location := time.UTC
+		if def.Timezone != "" {
+			if location, err = time.LoadLocation(def.Timezone); err != nil {
+				return nil, fmt.Errorf("reports[%d]: invalid timezone: %w", i, err)
+			}
+		}
+		switch def.Format {
+		case "":
+			def.Format = FormatHTML
+		case FormatHTML, FormatCSV:
+		default:
+			return nil, fmt.Errorf("reports[%d]: format must be html or csv", i)
+		}
+		if len(channels(def)) == 0 {
+			return nil, fmt.Errorf("reports[%d]: at least one of email, slack_webhook_url or webhook_url is required", i)
+		}
+		if def.Kind == KindSLO {
+			if def.SLOTarget == 0 {
+				def.SLOTarget = DefaultSLOTarget
+			}
+			if def.SLOTarget < 0 || def.SLOTarget > 100 {
+				return nil, fmt.Errorf("reports[%d]: slo_target must be between 0 and 100", i)
+			}
+		}
+
+		s.reports = append(s.reports, scheduledReport{Definition: def, schedule: sched, location: location})
+	}
+	return s, nil
+}
+
+// Start runs due reports at the start of every minute until the context is canceled
+func (s *Scheduler) Start(ctx context.Context) {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case now = <-timer.C:
+		}
+		for _, name := range s.due(now) {
+			go func() {
+				if _, err := s.Run(ctx, name, TriggerSchedule); err != nil && !errors.Is(err, ErrRunning) {
+					log.Error().Err(err).Str("report", name).Msg("Scheduled report failed")
+				}
+			}()
+		}
+	}
+}
+
+// due returns the reports whose schedule fires at the minute of now
+func (s *Scheduler) due(now time.Time) []string {
+	var names []string
+	for _, r := range s.reports {
+		if r.schedule.Matches(now.In(r.location)) {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
+
+// Run generates the named report and delivers it. The returned run is also recorded as the
+// report's last run; its Error is set when generation or any delivery failed.
+func (s *Scheduler) Run(ctx context.Context, name, trigger string) (*Run, error) {
+	def, ok := s.definition(name)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	s.mu.Lock()
+	if s.running[name] {
+		s.mu.Unlock()
+		return nil, ErrRunning
+	}
+	s.running[name] = true
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+
+	run := &Run{StartedAt: time.Now(), Trigger: trigger, Delivered: []string{}}
+	err := s.generate(ctx, def, run)
+	run.DurationMs = time.Since(run.StartedAt).Milliseconds()
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	delete(s.running, name)
+	s.last[name] = run
+	s.mu.Unlock()
+
+	if err != nil {
+		return run, err
+	}
+	log.Info().
+		Str("report", name).
+		Str("trigger", trigger).
+		Int("rows", run.Rows).
+		Strs("delivered", run.Delivered).
+		Int64("duration_ms", run.DurationMs).
+		Msg("Report delivered")
+	return run, nil
+}
+
+func (s *Scheduler) generate(ctx context.Context, def Definition, run *Run) error {
+	r, err := s.sources[def.Kind](ctx, def)
+	if err != nil {
+		return fmt.Errorf("failed to build report: %w", err)
+	}
+	r.Name, r.Kind, r.GeneratedAt = def.Name, def.Kind, run.StartedAt
+	run.Rows = len(r.Rows)
+
+	delivered, err := s.deliver(ctx, def, r)
+	run.Delivered = append(run.Delivered, delivered...)
+	return err
+}
+
+// Status returns every definition in configuration order with its next and last run
+func (s *Scheduler) Status(now time.Time) []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.reports))
+	for _, r := range s.reports {
+		status := Status{Definition: r.Definition, Channels: channels(r.Definition), LastRun: s.last[r.Name]}
+		if next := r.schedule.Next(now.In(r.location)); !next.IsZero() {
+			status.NextRun = &next
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (s *Scheduler) definition(name string) (Definition, bool) {
+	for _, r := range s.reports {
+		if r.Name == name {
+			return r.Definition, true
+		}
+	}
+	return Definition{}, false
+}
+
+// channels lists the delivery channels a definition configures
+func channels(def Definition) []string {
+	var list []string
+	if len(def.Email) > 0 {
+		list = append(list, ChannelEmail)
+	}
+	if def.SlackWebhookURL != "" {
+		list = append(list, ChannelSlack)
+	}
+	if def.WebhookURL != "" {
+		list = append(list, ChannelWebhook)
+	}
+	return list
+}
@@ -0,0 +1,359 @@
+// Package rollout detects deployments whose rollouts have stopped making progress
+package rollout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/ownership"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+)
+
+// Reasons a rollout is reported as stuck
+const (
+	ReasonDeadlineExceeded = "progress_deadline_exceeded" // Kubernetes gave up: Progressing is False with ProgressDeadlineExceeded
+	ReasonNoProgress       = "no_progress"                // No replica was updated or became ready within the window
+)
+
+// Plugin event types sent to sink plugins when a rollout gets stuck and when it recovers
+const (
+	EventStuck     = "ROLLOUT_STUCK"
+	EventRecovered = "ROLLOUT_RECOVERED"
+)
+
+// Reasons of the Progressing condition set by the deployment controller
+const (
+	progressDeadlineExceeded = "ProgressDeadlineExceeded" // After spec.progressDeadlineSeconds without progress
+	newReplicaSetAvailable   = "NewReplicaSetAvailable"   // The latest rollout finished
+)
+
+// Config holds stuck rollout detection settings
+type Config struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Interval        time.Duration `mapstructure:"interval"`          // How often deployments are checked
+	Window          time.Duration `mapstructure:"window"`            // How long a rollout may go without progress
+	AlertWebhookURL string        `mapstructure:"alert_webhook_url"` // Slack-compatible webhook, posted to the owner's channel when known
+}
+
+// Stuck is a deployment whose rollout stopped making progress
+type Stuck struct {
+	Cluster           string           `json:"cluster"`
+	Namespace         string           `json:"namespace"`
+	Name              string           `json:"name"`
+	Reason            string           `json:"reason"`
+	Message           string           `json:"message,omitempty"` // Message of the Progressing condition
+	Since             time.Time        `json:"since"`             // Last observed progress
+	StuckSeconds      int64            `json:"stuck_seconds"`
+	DesiredReplicas   int32            `json:"desired_replicas"`
+	UpdatedReplicas   int32            `json:"updated_replicas"`
+	ReadyReplicas     int32            `json:"ready_replicas"`
+	AvailableReplicas int32            `json:"available_replicas"`
+	Images            []string         `json:"images"`
+	Owner             *ownership.Owner `json:"owner,omitempty"`
+}
+
+// Lister returns the deployments of every watched cluster, keyed by cluster ID
+type Lister func(ctx context.Context) (map[string][]appsv1.Deployment, error)
+
+// progress is the rollout state of a deployment at its last change
+type progress struct {
+	snapshot [6]int64
+	since    time.Time
+}
+
+// Detector tracks rollout progress between checks
+type Detector struct {
+	config Config
+	list   Lister
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	progress  map[string]progress // Keyed by cluster/namespace/name
+	stuck     map[string]Stuck
+	lastCheck time.Time
+}
+
+// NewDetector creates a detector, defaulting the interval to a minute and the window to ten
+func NewDetector(cfg Config, list Lister) *Detector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Minute
+	}
+	return &Detector{
+		config:   cfg,
+		list:     list,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		now:      time.Now,
+		progress: make(map[string]progress),
+		stuck:    make(map[string]Stuck),
+	}
+}
+
+// Start checks deployments every interval until the context is canceled
+func (d *Detector) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Check(ctx); err != nil {
+			log.Error().Err(err).Msg("Stuck rollout check failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check evaluates every deployment and alerts on rollouts that got stuck or recovered
+func (d *Detector) Check(ctx context.Context) error {
+	clusters, err := d.list(ctx)
+	if err != nil {
+		return err
+	}
+	now := d.now()
+
+	d.mu.Lock()
+	seen := make(map[string]bool)
+	current := make(map[string]Stuck)
+	var newlyStuck []Stuck
+	for clusterID, deployments := range clusters {
+		for i := range deployments {
+			dep := &deployments[i]
+			key := clusterID + "/" + dep.Namespace + "/" + dep.Name
+			seen[key] = true
+			s, ok := d.evaluate(key, clusterID, dep, now)
+			if !ok {
+				continue
+			}
+			current[key] = s
+			if _, was := d.stuck[key]; !was {
+				newlyStuck = append(newlyStuck, s)
+			}
+		}
+	}
+	var recovered []Stuck
+	for key, s := range d.stuck {
+		if _, still := current[key]; !still {
+			recovered = append(recovered, s)
+		}
+	}
+	for key := range d.progress {
+		if !seen[key] {
+			delete(d.progress, key)
+		}
+	}
+	d.stuck = current
+	d.lastCheck = now
+	d.mu.Unlock()
+
+	for _, s := range newlyStuck {
+		d.notify(ctx, EventStuck, s)
+	}
+	for _, s := range recovered {
+		// Deleted deployments are not recoveries worth telling anyone about
+		if seen[s.Cluster+"/"+s.Namespace+"/"+s.Name] {
+			d.notify(ctx, EventRecovered, s)
+		}
+	}
+	return nil
+}
+
+// evaluate records a deployment's progress and reports whether its rollout is stuck. The
+// caller holds d.mu.
+func (d *Detector) evaluate(key, clusterID string, dep *appsv1.Deployment, now time.Time) (Stuck, bool) {
+	desired := int32(1)
+	if dep.Spec.Replicas != nil {
+		desired = *dep.Spec.Replicas
+	}
+	st := dep.Status
+	snapshot := [6]int64{dep.Generation, st.ObservedGeneration, int64(st.Replicas), int64(st.UpdatedReplicas), int64(st.ReadyReplicas), int64(st.AvailableReplicas)}
+
+	var progressing *appsv1.DeploymentCondition
+	for i := range st.Conditions {
+		if st.Conditions[i].Type == appsv1.DeploymentProgressing {
+			progressing = &st.Conditions[i]
+		}
+	}
+
+	p, ok := d.progress[key]
+	if !ok || p.snapshot != snapshot {
+		p = progress{snapshot: snapshot, since: now}
+		// The controller stamps the condition on every bit of progress, which survives restarts
+		if !ok && progressing != nil && !progressing.LastUpdateTime.IsZero() {
+			p.since = progressing.LastUpdateTime.Time
+		}
+		d.progress[key] = p
+	}
+
+	s := Stuck{
+		Cluster:           clusterID,
+		Namespace:         dep.Namespace,
+		Name:              dep.Name,
+		Since:             p.since,
+		StuckSeconds:      int64(now.Sub(p.since).Seconds()),
+		DesiredReplicas:   desired,
+		UpdatedReplicas:   st.UpdatedReplicas,
+		ReadyReplicas:     st.ReadyReplicas,
+		AvailableReplicas: st.AvailableReplicas,
+		Images:            make([]string, 0, len(dep.Spec.Template.Spec.Containers)),
+	}
+	for _, c := range dep.Spec.Template.Spec.Containers {
+		s.Images = append(s.Images, c.Image)
+	}
+	if progressing != nil {
+		s.Message = progressing.Message
+	}
+
+	switch {
+	case dep.Spec.Paused:
+		return Stuck{}, false
+	case progressing != nil && progressing.Status == corev1.ConditionFalse && progressing.Reason == progressDeadlineExceeded:
+		s.Reason = ReasonDeadlineExceeded
+	case rolledOut(dep, desired, progressing):
+		return Stuck{}, false
+	case now.Sub(p.since) >= d.config.Window:
+		s.Reason = ReasonNoProgress
+	default:
+		return Stuck{}, false
+	}
+	if owner := ownership.Default().Resolve(context.Background(), clusterID, dep.Namespace); !owner.IsZero() {
+		s.Owner = &owner
+	}
+	return s, true
+}
+
+// rolledOut reports whether the deployment's latest rollout has finished. Pods lost after
+// that are an availability problem, not a stuck rollout.
+func rolledOut(dep *appsv1.Deployment, desired int32, progressing *appsv1.DeploymentCondition) bool {
+	st := dep.Status
+	if st.ObservedGeneration < dep.Generation {
+		return false
+	}
+	if progressing != nil && progressing.Reason == newReplicaSetAvailable {
+		return true
+	}
+	return st.UpdatedReplicas == desired && st.Replicas == desired && st.AvailableReplicas == desired
+}
+
+// List returns the stuck rollouts found by the last check, longest stuck first, optionally
+// limited to a cluster and namespace
+func (d *Detector) List(clusterID, namespace string) []Stuck {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	now := d.now()
+	items := make([]Stuck, 0, len(d.stuck))
+	for _, s := range d.stuck {
+		if (clusterID != "" && s.Cluster != clusterID) || (namespace != "" && s.Namespace != namespace) {
+			continue
+		}
+		s.StuckSeconds = int64(now.Sub(s.Since).Seconds())
+		items = append(items, s)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Since.Equal(items[j].Since) {
+			return items[i].Since.Before(items[j].Since)
+		}
+		return items[i].Cluster+"/"+items[i].Namespace+"/"+items[i].Name < items[j].Cluster+"/"+items[j].Namespace+"/"+items[j].Name
+	})
+	return items
+}
+
+// LastCheck returns when deployments were last checked
+func (d *Detector) LastCheck() time.Time {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.lastCheck
+}
+
+// notify tells sink plugins and the alert webhook that a rollout got stuck or recovered
+func (d *Detector) notify(ctx context.Context, eventType string, s Stuck) {
+	logEvent := log.Warn()
+	if eventType == EventRecovered {
+		logEvent = log.Info()
+	}
+	logEvent.
+		Str("cluster_id", s.Cluster).
+		Str("namespace", s.Namespace).
+		Str("name", s.Name).
+		Str("reason", s.Reason).
+		Time("since", s.Since).
+		Msg(eventMessage(eventType))
+
+	plugin.Emit(ctx, plugin.Event{
+		ID:           uuid.New().String(),
+		ClusterID:    s.Cluster,
+		Type:         eventType,
+		ResourceType: "Deployment",
+		Namespace:    s.Namespace,
+		Name:         s.Name,
+		Owner:        s.Owner,
+		Object:       s,
+	})
+
+	if d.config.AlertWebhookURL != "" {
+		d.postAlert(ctx, eventType, s)
+	}
+}
+
+func eventMessage(eventType string) string {
+	if eventType == EventRecovered {
+		return "Stuck rollout recovered"
+	}
+	return "Rollout is stuck"
+}
+
+// postAlert posts to the Slack-compatible webhook, addressing the owner's channel when known
+func (d *Detector) postAlert(ctx context.Context, eventType string, s Stuck) {
+	text := fmt.Sprintf("Rollout of deployment %s/%s in cluster %s recovered", s.Namespace, s.Name, s.Cluster)
+	if eventType == EventStuck {
+		text = fmt.Sprintf("Rollout of deployment %s/%s in cluster %s is stuck (%s) since %s: %d of %d replicas updated, %d available",
+			s.Namespace, s.Name, s.Cluster, s.Reason, s.Since.UTC().Format(time.RFC3339), s.UpdatedReplicas, s.DesiredReplicas, s.AvailableReplicas)
+		if s.Message != "" {
+			text += "\n" + s.Message
+		}
+	}
+	payload := map[string]string{"text": text}
+	if s.Owner != nil {
+		if s.Owner.SlackChannel != "" {
+			payload["channel"] = s.Owner.SlackChannel
+		}
+		if s.Owner.Team != "" {
+			payload["text"] += "\nOwner: " + s.Owner.Team
+		}
+	}
+
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.config.AlertWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build stuck rollout alert")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send stuck rollout alert")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Error().Int("status", resp.StatusCode).Msg("Stuck rollout alert webhook rejected the request")
+	}
+}
@@ -0,0 +1,112 @@
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func deployment(name string, replicas, updated, available int32) appsv1.Deployment {
+	return appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           replicas,
+			UpdatedReplicas:    updated,
+			ReadyReplicas:      available,
+			AvailableReplicas:  available,
+		},
+	}
+}
+
+func TestDetector_NoProgress(t *testing.T) {
+	deployments := []appsv1.Deployment{deployment("web", 3, 1, 2), deployment("api", 2, 2, 2)}
+	d := NewDetector(Config{Window: 10 * time.Minute}, func(context.Context) (map[string][]appsv1.Deployment, error) {
+		return map[string][]appsv1.Deployment{"primary": deployments}, nil
+	})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	require.NoError(t, d.Check(context.Background()))
+	assert.Empty(t, d.List("", ""), "within the window")
+
+	now = now.Add(11 * time.Minute)
+	require.NoError(t, d.Check(context.Background()))
+	stuck := d.List("", "")
+	require.Len(t, stuck, 1)
+	assert.Equal(t, "web", stuck[0].Name)
+	assert.Equal(t, ReasonNoProgress, stuck[0].Reason)
+	assert.Equal(t, int64(660), stuck[0].StuckSeconds)
+	assert.Empty(t, d.List("other", ""))
+
+	// Progress resets the window
+	deployments[0].Status.UpdatedReplicas = 2
+	now = now.Add(time.Minute)
+	require.NoError(t, d.Check(context.Background()))
+	assert.Empty(t, d.List("", ""))
+}
+
+func TestDetector_DeadlineExceededAndCompletedRollouts(t *testing.T) {
+	exceeded := deployment("web", 3, 1, 2)
+	exceeded.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded",
+		Message: `ReplicaSet "web-7d4b9" has timed out progressing.`,
+	}}
+	degraded := deployment("api", 3, 3, 1)
+	degraded.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "NewReplicaSetAvailable",
+	}}
+	paused := deployment("batch", 3, 1, 1)
+	paused.Spec.Paused = true
+
+	d := NewDetector(Config{}, func(context.Context) (map[string][]appsv1.Deployment, error) {
+		return map[string][]appsv1.Deployment{"primary": {exceeded, degraded, paused}}, nil
+	})
+	require.NoError(t, d.Check(context.Background()))
+	stuck := d.List("primary", "shop")
+	require.Len(t, stuck, 1)
+	assert.Equal(t, "web", stuck[0].Name)
+	assert.Equal(t, ReasonDeadlineExceeded, stuck[0].Reason)
+	assert.Contains(t, stuck[0].Message, "timed out")
+}
+
+func TestDetector_Alerts(t *testing.T) {
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		texts = append(texts, payload["text"])
+	}))
+	defer srv.Close()
+
+	dep := deployment("web", 3, 1, 2)
+	d := NewDetector(Config{Window: time.Minute, AlertWebhookURL: srv.URL}, func(context.Context) (map[string][]appsv1.Deployment, error) {
+		return map[string][]appsv1.Deployment{"primary": {dep}}, nil
+	})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	require.NoError(t, d.Check(context.Background()))
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, d.Check(context.Background()))
+	now = now.Add(time.Minute)
+	require.NoError(t, d.Check(context.Background()))
+	require.Len(t, texts, 1, "alerted once while stuck")
+	assert.Contains(t, texts[0], "shop/web")
+	assert.Contains(t, texts[0], "1 of 3 replicas updated")
+
+	dep.Status.UpdatedReplicas, dep.Status.AvailableReplicas = 3, 3
+	require.NoError(t, d.Check(context.Background()))
+	require.Len(t, texts, 2)
+	assert.Contains(t, texts[1], "recovered")
+}
@@ -0,0 +1,108 @@
+// Package runtimeinfo reports the Go runtime's goroutine, heap and garbage collector
+// statistics, to follow the memory growth of a long-running controller
+package runtimeinfo
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"time"
+)
+
+// Snapshot is the state of the Go runtime at one point in time
+type Snapshot struct {
+	Time       time.Time `json:"time"`
+	Uptime     string    `json:"uptime"`
+	GoVersion  string    `json:"go_version"`
+	PID        int       `json:"pid"`
+	NumCPU     int       `json:"num_cpu"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	Goroutines int       `json:"goroutines"`
+	CgoCalls   int64     `json:"cgo_calls"`
+	Memory     Memory    `json:"memory"`
+	GC         GC        `json:"gc"`
+}
+
+// Memory reports heap and total memory obtained from the OS, in bytes
+type Memory struct {
+	HeapAlloc    uint64 `json:"heap_alloc_bytes"`    // Bytes of live and not yet collected objects
+	HeapInuse    uint64 `json:"heap_inuse_bytes"`    // Bytes in spans holding objects
+	HeapIdle     uint64 `json:"heap_idle_bytes"`     // Bytes in spans holding no objects
+	HeapReleased uint64 `json:"heap_released_bytes"` // Idle bytes returned to the OS
+	HeapObjects  uint64 `json:"heap_objects"`
+	StackInuse   uint64 `json:"stack_inuse_bytes"`
+	Sys          uint64 `json:"sys_bytes"`             // Everything obtained from the OS
+	TotalAlloc   uint64 `json:"total_alloc_bytes"`     // Cumulative, grows with allocation rate
+	Mallocs      uint64 `json:"mallocs"`               // Cumulative
+	Frees        uint64 `json:"frees"`                 // Cumulative
+	Limit        int64  `json:"limit_bytes,omitempty"` // GOMEMLIMIT, omitted when unlimited
+}
+
+// GC reports garbage collector activity
+type GC struct {
+	NumGC         uint32    `json:"num_gc"`
+	NumForcedGC   uint32    `json:"num_forced_gc"`
+	LastGC        time.Time `json:"last_gc,omitempty"`
+	NextGC        uint64    `json:"next_gc_bytes"` // Heap size that triggers the next cycle
+	PauseTotal    string    `json:"pause_total"`
+	LastPause     string    `json:"last_pause"`
+	CPUFraction   float64   `json:"cpu_fraction"`   // Share of CPU time spent in GC since start
+	TargetPercent int       `json:"target_percent"` // GOGC, -1 when collection is off
+}
+
+var start = time.Now()
+
+// Take reads the current runtime statistics. It briefly stops the world to read memory
+// statistics, so it suits on-demand diagnosis rather than tight polling.
+func Take() Snapshot {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	now := time.Now()
+
+	s := Snapshot{
+		Time:       now.UTC(),
+		Uptime:     now.Sub(start).Round(time.Second).String(),
+		GoVersion:  runtime.Version(),
+		PID:        os.Getpid(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		CgoCalls:   runtime.NumCgoCall(),
+		Memory: Memory{
+			HeapAlloc:    m.HeapAlloc,
+			HeapInuse:    m.HeapInuse,
+			HeapIdle:     m.HeapIdle,
+			HeapReleased: m.HeapReleased,
+			HeapObjects:  m.HeapObjects,
+			StackInuse:   m.StackInuse,
+			Sys:          m.Sys,
+			TotalAlloc:   m.TotalAlloc,
+			Mallocs:      m.Mallocs,
+			Frees:        m.Frees,
+		},
+		GC: GC{
+			NumGC:       m.NumGC,
+			NumForcedGC: m.NumForcedGC,
+			NextGC:      m.NextGC,
+			PauseTotal:  time.Duration(m.PauseTotalNs).String(),
+			LastPause:   time.Duration(m.PauseNs[(m.NumGC+255)%256]).String(),
+			CPUFraction: m.GCCPUFraction,
+		},
+	}
+	if m.LastGC > 0 {
+		s.GC.LastGC = time.Unix(0, int64(m.LastGC)).UTC()
+	}
+	// The GOGC and GOMEMLIMIT settings, which the debug package can only read by changing them
+	samples := []metrics.Sample{{Name: "/gc/gogc:percent"}, {Name: "/gc/gomemlimit:bytes"}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		s.GC.TargetPercent = int(samples[0].Value.Uint64())
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		if limit := samples[1].Value.Uint64(); limit < math.MaxInt64 {
+			s.Memory.Limit = int64(limit)
+		}
+	}
+	return s
+}
@@ -0,0 +1,36 @@
+package runtimeinfo
+
+import (
+	"encoding/json"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTake(t *testing.T) {
+	runtime.GC()
+	s := Take()
+
+	assert.Equal(t, runtime.Version(), s.GoVersion)
+	assert.Positive(t, s.Goroutines)
+	assert.Positive(t, s.Memory.HeapAlloc)
+	assert.GreaterOrEqual(t, s.Memory.Sys, s.Memory.HeapInuse)
+	assert.GreaterOrEqual(t, s.GC.NumForcedGC, uint32(1))
+	assert.False(t, s.GC.LastGC.IsZero())
+}
+
+func TestTake_Settings(t *testing.T) {
+	defer debug.SetGCPercent(debug.SetGCPercent(50))
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(1 << 30))
+
+	s := Take()
+	assert.Equal(t, 50, s.GC.TargetPercent)
+	assert.Equal(t, int64(1<<30), s.Memory.Limit)
+
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"limit_bytes":1073741824`)
+}
@@ -0,0 +1,289 @@
+// Package scheduling explains why pods stay Pending by classifying the scheduler's
+// FailedScheduling messages and the pod's own status into causes with remedies
+package scheduling
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Causes of a pod not being scheduled or not starting
+const (
+	CauseInsufficientCPU      = "insufficient_cpu"
+	CauseInsufficientMemory   = "insufficient_memory"
+	CauseInsufficientResource = "insufficient_resource" // Extended resources, ephemeral storage or pod slots
+	CauseTaints               = "taints"
+	CauseNodeAffinity         = "node_affinity"
+	CausePodAffinity          = "pod_affinity"
+	CauseTopologySpread       = "topology_spread"
+	CauseVolumeBinding        = "volume_binding"
+	CauseUnschedulableNodes   = "unschedulable_nodes"
+	CauseHostPorts            = "host_ports"
+	CauseSchedulingGated      = "scheduling_gated"
+	CauseUnknown              = "unknown"
+
+	// The pod is bound to a node but its containers have not started
+	CauseNotStarted = "scheduled_not_started"
+)
+
+// explanations describe each cause and what usually resolves it
+var explanations = map[string]string{
+	CauseInsufficientCPU:      "No node has enough unrequested CPU for the pod's requests. Lower the CPU requests, free capacity or add nodes.",
+	CauseInsufficientMemory:   "No node has enough unrequested memory for the pod's requests. Lower the memory requests, free capacity or add nodes.",
+	CauseInsufficientResource: "Nodes lack a requested resource such as GPUs, ephemeral storage or pod slots. Check the requests against node allocatable.",
+	CauseTaints:               "Nodes carry taints the pod does not tolerate. Add tolerations or schedule onto untainted nodes.",
+	CauseNodeAffinity:         "No node matches the pod's nodeSelector or required node affinity. Check the node labels the pod requires.",
+	CausePodAffinity:          "The pod's required pod affinity or anti-affinity rules exclude every node. Relax the rules or add nodes in other topology domains.",
+	CauseTopologySpread:       "Placing the pod would violate its topology spread constraints. Add nodes in the missing zones or use whenUnsatisfiable: ScheduleAnyway.",
+	CauseVolumeBinding:        "The pod's PersistentVolumeClaims cannot be bound or their volumes are not reachable from any node. Check the claims, storage class and volume zones.",
+	CauseUnschedulableNodes:   "Nodes are cordoned. Uncordon them or wait for maintenance to finish.",
+	CauseHostPorts:            "The host ports the pod requests are already in use on every node.",
+	CauseSchedulingGated:      "The pod has scheduling gates, so the scheduler does not consider it until they are removed.",
+	CauseUnknown:              "The scheduler gave a reason that is not recognised; see the message.",
+	CauseNotStarted:           "The pod is scheduled but its containers are not running yet; see the waiting reasons.",
+}
+
+// patterns map fragments of scheduler messages to causes, checked in order
+var patterns = []struct {
+	fragment string
+	cause    string
+}{
+	{"insufficient cpu", CauseInsufficientCPU},
+	{"insufficient memory", CauseInsufficientMemory},
+	{"insufficient ", CauseInsufficientResource},
+	{"too many pods", CauseInsufficientResource},
+	{"taint", CauseTaints},
+	// Volume reasons come first since "volume node affinity conflict" also names node affinity
+	{"persistentvolumeclaim", CauseVolumeBinding},
+	{"persistent volume", CauseVolumeBinding},
+	{"volume", CauseVolumeBinding},
+	{"node affinity", CauseNodeAffinity},
+	{"node selector", CauseNodeAffinity},
+	{"pod anti-affinity", CausePodAffinity},
+	{"pod affinity", CausePodAffinity},
+	{"topology spread", CauseTopologySpread},
+	{"unschedulable", CauseUnschedulableNodes},
+	{"free ports", CauseHostPorts},
+	{"schedulinggated", CauseSchedulingGated},
+}
+
+// nodesAvailable matches the "0/5 nodes are available: " prefix of scheduler messages
+var nodesAvailable = regexp.MustCompile(`^\s*(\d+)/(\d+) nodes are available:\s*`)
+
+// leadingCount matches the node count in front of each reason, e.g. "3 Insufficient cpu"
+var leadingCount = regexp.MustCompile(`^(\d+)\s+(.*)$`)
+
+// Cause is one reason the pod cannot be scheduled or started
+type Cause struct {
+	Reason      string `json:"reason"`
+	Nodes       int    `json:"nodes,omitempty"` // Nodes rejecting the pod for this reason
+	Detail      string `json:"detail"`          // Fragment of the message the cause was read from
+	Explanation string `json:"explanation"`
+}
+
+// Event is a FailedScheduling event of the pod
+type Event struct {
+	Time    time.Time `json:"time"`
+	Count   int32     `json:"count"`
+	Message string    `json:"message"`
+}
+
+// Analysis explains why a pod is pending
+type Analysis struct {
+	Namespace      string            `json:"namespace"`
+	Name           string            `json:"name"`
+	Phase          corev1.PodPhase   `json:"phase"`
+	Pending        bool              `json:"pending"`
+	Scheduled      bool              `json:"scheduled"`
+	Node           string            `json:"node,omitempty"`
+	PendingSeconds int64             `json:"pending_seconds,omitempty"`
+	Message        string            `json:"message,omitempty"`     // Latest scheduler message
+	TotalNodes     int               `json:"total_nodes,omitempty"` // Nodes the scheduler considered
+	Causes         []Cause           `json:"causes"`
+	Waiting        map[string]string `json:"waiting,omitempty"` // Waiting reason per container of a scheduled pod
+	Events         []Event           `json:"events,omitempty"`
+}
+
+// Explanation returns the description of a cause
+func Explanation(cause string) string {
+	if text, ok := explanations[cause]; ok {
+		return text
+	}
+	return explanations[CauseUnknown]
+}
+
+// ParseMessage classifies a FailedScheduling message such as "0/3 nodes are available:
+// 1 Insufficient cpu, 2 node(s) had untolerated taint {dedicated: gpu}. preemption: ...".
+// It returns the number of nodes considered, or 0 when the message does not say, and one
+// cause per reason, merging reasons of the same cause.
+func ParseMessage(message string) (int, []Cause) {
+	body := message
+	total := 0
+	if m := nodesAvailable.FindStringSubmatch(body); m != nil {
+		total, _ = strconv.Atoi(m[2])
+		body = body[len(m[0]):]
+	}
+	// Preemption details repeat the reasons and are not causes themselves
+	if i := strings.Index(strings.ToLower(body), "preemption:"); i >= 0 {
+		body = body[:i]
+	}
+	body = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(body), "."))
+
+	var causes []Cause
+	index := make(map[string]int)
+	for _, part := range splitReasons(body) {
+		nodes := 0
+		if m := leadingCount.FindStringSubmatch(part); m != nil {
+			nodes, _ = strconv.Atoi(m[1])
+			part = m[2]
+		}
+		reason := classify(part)
+		if i, ok := index[reason]; ok {
+			causes[i].Nodes += nodes
+			causes[i].Detail += ", " + part
+			continue
+		}
+		index[reason] = len(causes)
+		causes = append(causes, Cause{Reason: reason, Nodes: nodes, Detail: part, Explanation: Explanation(reason)})
+	}
+	sort.SliceStable(causes, func(i, j int) bool { return causes[i].Nodes > causes[j].Nodes })
+	return total, causes
+}
+
+// Analyze explains why a pod is pending from its status and its FailedScheduling events.
+// The scheduler's PodScheduled condition is preferred over events, which may be older.
+func Analyze(pod *corev1.Pod, events []corev1.Event, now time.Time) Analysis {
+	a := Analysis{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Phase:     pod.Status.Phase,
+		Pending:   pod.Status.Phase == corev1.PodPending,
+		Node:      pod.Spec.NodeName,
+		Scheduled: pod.Spec.NodeName != "",
+		Causes:    []Cause{},
+	}
+	if !a.Pending {
+		return a
+	}
+	if !pod.CreationTimestamp.IsZero() {
+		a.PendingSeconds = int64(now.Sub(pod.CreationTimestamp.Time).Seconds())
+	}
+
+	sort.Slice(events, func(i, j int) bool { return eventTime(&events[i]).After(eventTime(&events[j])) })
+	for i := range events {
+		if events[i].Reason != "FailedScheduling" {
+			continue
+		}
+		a.Events = append(a.Events, Event{Time: eventTime(&events[i]), Count: events[i].Count, Message: events[i].Message})
+	}
+
+	switch {
+	case a.Scheduled:
+		a.Waiting = waitingReasons(pod)
+		a.Causes = append(a.Causes, Cause{Reason: CauseNotStarted, Detail: joinWaiting(a.Waiting), Explanation: Explanation(CauseNotStarted)})
+	case len(pod.Spec.SchedulingGates) > 0:
+		gates := make([]string, 0, len(pod.Spec.SchedulingGates))
+		for _, g := range pod.Spec.SchedulingGates {
+			gates = append(gates, g.Name)
+		}
+		a.Causes = append(a.Causes, Cause{Reason: CauseSchedulingGated, Detail: strings.Join(gates, ", "), Explanation: Explanation(CauseSchedulingGated)})
+	default:
+		a.Message = schedulerMessage(pod)
+		if a.Message == "" && len(a.Events) > 0 {
+			a.Message = a.Events[0].Message
+		}
+		if a.Message != "" {
+			a.TotalNodes, a.Causes = ParseMessage(a.Message)
+		}
+	}
+	return a
+}
+
+// schedulerMessage returns the message of a false PodScheduled condition
+func schedulerMessage(pod *corev1.Pod) string {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse {
+			return c.Message
+		}
+	}
+	return ""
+}
+
+// waitingReasons returns why each container of a scheduled pod is not running
+func waitingReasons(pod *corev1.Pod) map[string]string {
+	waiting := make(map[string]string)
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		if s.State.Waiting != nil {
+			reason := s.State.Waiting.Reason
+			if s.State.Waiting.Message != "" {
+				reason += ": " + s.State.Waiting.Message
+			}
+			waiting[s.Name] = reason
+		}
+	}
+	return waiting
+}
+
+func joinWaiting(waiting map[string]string) string {
+	parts := make([]string, 0, len(waiting))
+	for name, reason := range waiting {
+		parts = append(parts, name+": "+reason)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
+
+// splitReasons splits a comma-separated reason list, keeping commas inside braces such
+// as taint lists intact
+func splitReasons(body string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range body {
+		switch r {
+		case '{', '[', '(':
+			depth++
+		case '}', ']', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				if part := strings.TrimSpace(body[start:i]); part != "" {
+					parts = append(parts, part)
+				}
+				start = i + 1
+			}
+		}
+	}
+	if part := strings.TrimSpace(body[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+func classify(reason string) string {
+	lower := strings.ToLower(reason)
+	for _, p := range patterns {
+		if strings.Contains(lower, p.fragment) {
+			return p.cause
+		}
+	}
+	return CauseUnknown
+}
+
+// eventTime returns when an event last occurred, whichever timestamp field is set
+func eventTime(e *corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case e.Series != nil && !e.Series.LastObservedTime.IsZero():
+		return e.Series.LastObservedTime.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	}
+	return e.CreationTimestamp.Time
+}
@@ -0,0 +1,126 @@
+package scheduling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseMessage(t *testing.T) {
+	total, causes := ParseMessage("0/6 nodes are available: 1 Insufficient memory, 2 Insufficient cpu, " +
+		"3 node(s) had untolerated taint {dedicated: gpu, team: ml}. preemption: 0/6 nodes are available: " +
+		"3 No preemption victims found for incoming pod, 3 Preemption is not helpful for scheduling.")
+
+	assert.Equal(t, 6, total)
+	require.Len(t, causes, 3)
+	assert.Equal(t, CauseTaints, causes[0].Reason)
+	assert.Equal(t, 3, causes[0].Nodes)
+	assert.Equal(t, "node(s) had untolerated taint {dedicated: gpu, team: ml}", causes[0].Detail)
+	assert.Equal(t, CauseInsufficientCPU, causes[1].Reason)
+	assert.Equal(t, 2, causes[1].Nodes)
+	assert.Equal(t, CauseInsufficientMemory, causes[2].Reason)
+	assert.NotEmpty(t, causes[2].Explanation)
+}
+
+func TestParseMessage_Classification(t *testing.T) {
+	cases := map[string]string{
+		"1 Insufficient nvidia.com/gpu":                                CauseInsufficientResource,
+		"1 Too many pods":                                              CauseInsufficientResource,
+		"2 node(s) didn't match Pod's node affinity/selector":          CauseNodeAffinity,
+		"1 node(s) didn't match pod anti-affinity rules":               CausePodAffinity,
+		"1 node(s) didn't match pod topology spread constraints":       CauseTopologySpread,
+		"pod has unbound immediate PersistentVolumeClaims":             CauseVolumeBinding,
+		"1 node(s) had volume node affinity conflict":                  CauseVolumeBinding,
+		"1 node(s) were unschedulable":                                 CauseUnschedulableNodes,
+		"1 node(s) didn't have free ports for the requested pod ports": CauseHostPorts,
+		"1 something the scheduler added in a later release":           CauseUnknown,
+	}
+	for message, want := range cases {
+		_, causes := ParseMessage(message)
+		require.Len(t, causes, 1, message)
+		assert.Equal(t, want, causes[0].Reason, message)
+	}
+}
+
+func TestParseMessage_MergesSameCause(t *testing.T) {
+	_, causes := ParseMessage("0/4 nodes are available: 1 node(s) had untolerated taint {a: b}, 3 node(s) had untolerated taint {c: d}.")
+	require.Len(t, causes, 1)
+	assert.Equal(t, 4, causes[0].Nodes)
+}
+
+func TestAnalyze_Unscheduled(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", CreationTimestamp: metav1.NewTime(now.Add(-90 * time.Second))},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{{
+				Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable",
+				Message: "0/3 nodes are available: 3 Insufficient cpu.",
+			}},
+		},
+	}
+	events := []corev1.Event{
+		{Reason: "FailedScheduling", Message: "older", LastTimestamp: metav1.NewTime(now.Add(-time.Minute))},
+		{Reason: "FailedScheduling", Message: "newer", LastTimestamp: metav1.NewTime(now.Add(-time.Second))},
+		{Reason: "Scheduled", Message: "ignored"},
+	}
+
+	a := Analyze(pod, events, now)
+	assert.True(t, a.Pending)
+	assert.False(t, a.Scheduled)
+	assert.Equal(t, int64(90), a.PendingSeconds)
+	assert.Equal(t, 3, a.TotalNodes)
+	require.Len(t, a.Causes, 1)
+	assert.Equal(t, CauseInsufficientCPU, a.Causes[0].Reason)
+	require.Len(t, a.Events, 2)
+	assert.Equal(t, "newer", a.Events[0].Message)
+}
+
+func TestAnalyze_FallsBackToEvents(t *testing.T) {
+	pod := &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}}
+	events := []corev1.Event{{Reason: "FailedScheduling", Message: "0/1 nodes are available: 1 node(s) were unschedulable."}}
+
+	a := Analyze(pod, events, time.Now())
+	require.Len(t, a.Causes, 1)
+	assert.Equal(t, CauseUnschedulableNodes, a.Causes[0].Reason)
+}
+
+func TestAnalyze_ScheduledButNotStarted(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			}},
+		},
+	}
+
+	a := Analyze(pod, nil, time.Now())
+	assert.True(t, a.Scheduled)
+	assert.Equal(t, map[string]string{"app": "ImagePullBackOff"}, a.Waiting)
+	require.Len(t, a.Causes, 1)
+	assert.Equal(t, CauseNotStarted, a.Causes[0].Reason)
+	assert.Equal(t, "app: ImagePullBackOff", a.Causes[0].Detail)
+}
+
+func TestAnalyze_GatedAndRunning(t *testing.T) {
+	gated := &corev1.Pod{
+		Spec:   corev1.PodSpec{SchedulingGates: []corev1.PodSchedulingGate{{Name: "example.com/quota"}}},
+		Status: corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	a := Analyze(gated, nil, time.Now())
+	require.Len(t, a.Causes, 1)
+	assert.Equal(t, CauseSchedulingGated, a.Causes[0].Reason)
+
+	running := &corev1.Pod{Spec: corev1.PodSpec{NodeName: "node-1"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+	a = Analyze(running, nil, time.Now())
+	assert.False(t, a.Pending)
+	assert.Empty(t, a.Causes)
+}
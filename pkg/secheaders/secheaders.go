@@ -0,0 +1,150 @@
+// Package secheaders computes the security response headers for each route
+package secheaders
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Header names set by the policy
+const (
+	ContentSecurityPolicy   = "Content-Security-Policy"
+	StrictTransportSecurity = "Strict-Transport-Security"
+	ReferrerPolicy          = "Referrer-Policy"
+	PermissionsPolicy       = "Permissions-Policy"
+	ContentTypeOptions      = "X-Content-Type-Options"
+	FrameOptions            = "X-Frame-Options"
+)
+
+// Defaults for unset configuration. JSON responses never need to load anything, so the
+// default CSP denies everything.
+const (
+	DefaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+	DefaultReferrerPolicy        = "no-referrer"
+	DefaultPermissionsPolicy     = "accelerometer=(), camera=(), geolocation=(), gyroscope=(), microphone=(), payment=(), usb=()"
+	DefaultHSTSMaxAge            = 365 * 24 * time.Hour
+)
+
+// RouteOverride replaces headers for requests under a path prefix. An empty value removes
+// the header from matching responses.
+type RouteOverride struct {
+	Path    string            `mapstructure:"path"`
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// Config holds security header settings
+type Config struct {
+	Enabled               bool            `mapstructure:"enabled"`
+	ContentSecurityPolicy string          `mapstructure:"content_security_policy"`
+	ReferrerPolicy        string          `mapstructure:"referrer_policy"`
+	PermissionsPolicy     string          `mapstructure:"permissions_policy"`
+	HSTSMaxAge            time.Duration   `mapstructure:"hsts_max_age"` // Only sent over TLS
+	HSTSIncludeSubdomains bool            `mapstructure:"hsts_include_subdomains"`
+	HSTSPreload           bool            `mapstructure:"hsts_preload"`
+	Routes                []RouteOverride `mapstructure:"routes"` // Applied in order, so later entries win
+}
+
+// Header is a single response header
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Policy resolves the headers for a request
+type Policy struct {
+	base   map[string]string
+	hsts   string
+	routes []RouteOverride
+}
+
+// New creates a policy with defaults applied to unset settings
+func New(cfg Config) *Policy {
+	if cfg.ContentSecurityPolicy == "" {
+		cfg.ContentSecurityPolicy = DefaultContentSecurityPolicy
+	}
+	if cfg.ReferrerPolicy == "" {
+		cfg.ReferrerPolicy = DefaultReferrerPolicy
+	}
+	if cfg.PermissionsPolicy == "" {
+		cfg.PermissionsPolicy = DefaultPermissionsPolicy
+	}
+	if cfg.HSTSMaxAge <= 0 {
+		cfg.HSTSMaxAge = DefaultHSTSMaxAge
+	}
+
+	hsts := fmt.Sprintf("max-age=%d", int64(cfg.HSTSMaxAge.Seconds()))
+	if cfg.HSTSIncludeSubdomains {
+		hsts += "; includeSubDomains"
+	}
+	if cfg.HSTSPreload {
+		hsts += "; preload"
+	}
+
+	return &Policy{
+		base: map[string]string{
+			ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+			ReferrerPolicy:        cfg.ReferrerPolicy,
+			PermissionsPolicy:     cfg.PermissionsPolicy,
+			ContentTypeOptions:    "nosniff",
+			FrameOptions:          "DENY",
+		},
+		hsts:   hsts,
+		routes: cfg.Routes,
+	}
+}
+
+// Headers returns the headers for a request path sorted by name. HSTS is only included on
+// TLS connections because browsers ignore it over plain HTTP.
+func (p *Policy) Headers(path string, tls bool) []Header {
+	values := make(map[string]string, len(p.base)+1)
+	for name, value := range p.base {
+		values[name] = value
+	}
+	if tls {
+		values[StrictTransportSecurity] = p.hsts
+	}
+
+	for _, route := range p.routes {
+		if !matchesPrefix(path, route.Path) {
+			continue
+		}
+		for name, value := range route.Headers {
+			name = canonicalName(name, values)
+			if value == "" {
+				delete(values, name)
+			} else {
+				values[name] = value
+			}
+		}
+	}
+
+	headers := make([]Header, 0, len(values))
+	for name, value := range values {
+		headers = append(headers, Header{Name: name, Value: value})
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+// canonicalName maps an override name onto an existing header regardless of case, since
+// viper lowercases map keys read from YAML
+func canonicalName(name string, values map[string]string) string {
+	for existing := range values {
+		if strings.EqualFold(existing, name) {
+			return existing
+		}
+	}
+	for _, known := range []string{ContentSecurityPolicy, StrictTransportSecurity, ReferrerPolicy, PermissionsPolicy, ContentTypeOptions, FrameOptions} {
+		if strings.EqualFold(known, name) {
+			return known
+		}
+	}
+	return name
+}
+
+func matchesPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
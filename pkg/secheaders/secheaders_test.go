@@ -0,0 +1,52 @@
+package secheaders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func headerMap(headers []Header) map[string]string {
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		m[h.Name] = h.Value
+	}
+	return m
+}
+
+func TestHeaders_Defaults(t *testing.T) {
+	p := New(Config{Enabled: true})
+
+	h := headerMap(p.Headers("/deployments", false))
+	assert.Equal(t, DefaultContentSecurityPolicy, h[ContentSecurityPolicy])
+	assert.Equal(t, DefaultReferrerPolicy, h[ReferrerPolicy])
+	assert.Equal(t, DefaultPermissionsPolicy, h[PermissionsPolicy])
+	assert.Equal(t, "nosniff", h[ContentTypeOptions])
+	assert.Equal(t, "DENY", h[FrameOptions])
+	assert.NotContains(t, h, StrictTransportSecurity)
+}
+
+func TestHeaders_HSTSOnlyOverTLS(t *testing.T) {
+	p := New(Config{HSTSMaxAge: time.Hour, HSTSIncludeSubdomains: true, HSTSPreload: true})
+
+	h := headerMap(p.Headers("/", true))
+	assert.Equal(t, "max-age=3600; includeSubDomains; preload", h[StrictTransportSecurity])
+}
+
+func TestHeaders_RouteOverrides(t *testing.T) {
+	p := New(Config{Routes: []RouteOverride{
+		{Path: "/docs", Headers: map[string]string{"content-security-policy": "default-src 'self'", "X-Frame-Options": ""}},
+		{Path: "/docs/raw", Headers: map[string]string{"Cache-Control": "no-store"}},
+	}})
+
+	h := headerMap(p.Headers("/docs/raw", false))
+	assert.Equal(t, "default-src 'self'", h[ContentSecurityPolicy])
+	assert.NotContains(t, h, FrameOptions)
+	assert.Equal(t, "no-store", h["Cache-Control"])
+
+	// Prefixes match whole path segments
+	h = headerMap(p.Headers("/docsify", false))
+	assert.Equal(t, DefaultContentSecurityPolicy, h[ContentSecurityPolicy])
+	assert.Equal(t, "DENY", h[FrameOptions])
+}
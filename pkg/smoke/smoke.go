@@ -0,0 +1,196 @@
+// Package smoke exercises the core endpoints of a running API server, for post-deploy
+// validation in CD pipelines
+package smoke
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// LabelKey is the label the dry-run relabeling check would add; it is never persisted
+const LabelKey = "k8s-cli.io/smoke"
+
+// maxErrorBody caps how much of an unexpected response is quoted in a result
+const maxErrorBody = 200
+
+// Check is one request and the verdict on its response
+type Check struct {
+	Name   string
+	Method string
+	Path   string      // Path and query, relative to the server URL
+	Body   interface{} // Sent as JSON when set
+	Verify func(body map[string]interface{}) error
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name      string `json:"name"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Status    int    `json:"status,omitempty"`
+	Passed    bool   `json:"passed"`
+	LatencyMs int64  `json:"latency_ms"`
+	RequestID string `json:"request_id,omitempty"` // X-Request-ID, to find the request in logs and audit entries
+	Error     string `json:"error,omitempty"`
+}
+
+// Options select what the core checks read and, in dry-run, would change
+type Options struct {
+	Namespace string // Namespace of the deployments list and the dry-run relabeling
+	Cluster   string // Cluster ID, empty for the primary cluster
+	Selector  string // Label selector of the deployments the dry-run relabeling targets
+	RunID     string // Value of the dry-run label, identifying the run in audit entries
+}
+
+// CoreChecks returns the checks of a post-deploy validation: liveness, readiness, the
+// cluster inventory, a deployments list and a dry-run relabeling, which goes through
+// authorization, freeze windows and admission without persisting anything
+func CoreChecks(opts Options) []Check {
+	query := url.Values{}
+	query.Set("namespace", opts.Namespace)
+	if opts.Cluster != "" {
+		query.Set("cluster", opts.Cluster)
+	}
+	relabel := map[string]interface{}{
+		"namespaces": []string{opts.Namespace},
+		"kinds":      []string{"deployments"},
+		"selector":   opts.Selector,
+		"labels":     map[string]interface{}{"add": map[string]string{LabelKey: opts.RunID}},
+		"dry_run":    true,
+	}
+	if opts.Cluster != "" {
+		relabel["clusters"] = []string{opts.Cluster}
+	}
+
+	return []Check{
+		{Name: "health", Method: http.MethodGet, Path: "/health", Verify: healthy},
+		{Name: "readiness", Method: http.MethodGet, Path: "/readyz"},
+		{Name: "clusters", Method: http.MethodGet, Path: "/clusters"},
+		{Name: "deployments", Method: http.MethodGet, Path: "/deployments?" + query.Encode()},
+		{Name: "dry-run relabel", Method: http.MethodPost, Path: "/labels/apply", Body: relabel, Verify: relabeled},
+	}
+}
+
+// Runner sends checks to one API server
+type Runner struct {
+	URL    string // Base URL, e.g. https://kcc.example.com
+	Token  string // Bearer token, empty when authentication is disabled
+	Client *http.Client
+}
+
+// Run performs the checks in order. Every check runs, whatever the outcome of the others.
+func (r *Runner) Run(ctx context.Context, checks []Check) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, check := range checks {
+		results = append(results, r.run(ctx, check))
+	}
+	return results
+}
+
+func (r *Runner) run(ctx context.Context, check Check) (result Result) {
+	result = Result{Name: check.Name, Method: check.Method, Path: check.Path}
+	start := time.Now()
+	defer func() { result.LatencyMs = time.Since(start).Milliseconds() }()
+
+	var body io.Reader
+	if check.Body != nil {
+		data, err := json.Marshal(check.Body)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, check.Method, strings.TrimSuffix(r.URL, "/")+check.Path, body)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Accept", "application/json")
+	if check.Body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	result.Status = resp.StatusCode
+	result.RequestID = resp.Header.Get("X-Request-ID")
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read response: %v", err)
+		return result
+	}
+	if resp.StatusCode != http.StatusOK {
+		result.Error = fmt.Sprintf("expected status 200, got %d: %s", resp.StatusCode, truncate(strings.TrimSpace(string(data))))
+		return result
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		result.Error = fmt.Sprintf("response is not a JSON object: %v", err)
+		return result
+	}
+	if check.Verify != nil {
+		if err := check.Verify(decoded); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	result.Passed = true
+	return result
+}
+
+// Passed reports whether every check passed
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// healthy accepts the statuses /health reports while the API server is serving
+func healthy(body map[string]interface{}) error {
+	switch body["status"] {
+	case "ok", "degraded":
+		return nil
+	}
+	return fmt.Errorf("unexpected health status %v", body["status"])
+}
+
+// relabeled fails when the dry run could not be planned or validated on every object
+func relabeled(body map[string]interface{}) error {
+	if errs, _ := body["errors"].(map[string]interface{}); len(errs) > 0 {
+		return fmt.Errorf("clusters could not be read: %v", errs)
+	}
+	if failed, _ := body["failed"].(float64); failed > 0 {
+		return fmt.Errorf("%d of %v objects failed dry-run validation", int(failed), body["matched"])
+	}
+	return nil
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorBody {
+		return s[:maxErrorBody] + "..."
+	}
+	return s
+}
@@ -0,0 +1,75 @@
+package smoke
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_CoreChecks(t *testing.T) {
+	var relabel map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.Header().Set("X-Request-ID", "req-"+r.URL.Path)
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"status":"ok"}`))
+		case "/readyz":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"not_ready"}`))
+		case "/clusters", "/deployments":
+			w.Write([]byte(`{"items":[]}`))
+		case "/labels/apply":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&relabel))
+			w.Write([]byte(`{"dry_run":true,"matched":2,"failed":1,"errors":{}}`))
+		}
+	}))
+	defer srv.Close()
+
+	runner := &Runner{URL: srv.URL + "/", Token: "secret"}
+	results := runner.Run(context.Background(), CoreChecks(Options{Namespace: "prod", Cluster: "east", Selector: "app", RunID: "run-1"}))
+	require.Len(t, results, 5)
+
+	assert.True(t, results[0].Passed)
+	assert.Equal(t, "req-/health", results[0].RequestID)
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, http.StatusServiceUnavailable, results[1].Status)
+	assert.Contains(t, results[1].Error, "not_ready")
+	assert.True(t, results[2].Passed)
+	assert.Equal(t, "/deployments?cluster=east&namespace=prod", results[3].Path)
+	assert.True(t, results[3].Passed)
+	assert.False(t, results[4].Passed)
+	assert.Equal(t, "1 of 2 objects failed dry-run validation", results[4].Error)
+	assert.False(t, Passed(results))
+
+	assert.Equal(t, true, relabel["dry_run"])
+	assert.Equal(t, []interface{}{"east"}, relabel["clusters"])
+	assert.Equal(t, map[string]interface{}{"add": map[string]interface{}{LabelKey: "run-1"}}, relabel["labels"])
+}
+
+func TestRunner_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	results := (&Runner{URL: srv.URL}).Run(context.Background(), []Check{{Name: "health", Method: http.MethodGet, Path: "/health"}})
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed)
+	assert.Zero(t, results[0].Status)
+	assert.NotEmpty(t, results[0].Error)
+}
+
+func TestRunner_NonJSONResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>login</html>"))
+	}))
+	defer srv.Close()
+
+	results := (&Runner{URL: srv.URL}).Run(context.Background(), []Check{{Name: "health", Method: http.MethodGet, Path: "/health"}})
+	assert.False(t, results[0].Passed)
+	assert.Contains(t, results[0].Error, "not a JSON object")
+}
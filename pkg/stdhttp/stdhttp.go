@@ -0,0 +1,270 @@
+// Package stdhttp serves fasthttp request handlers from a net/http server, for clients and
+// integrations that need HTTP/2, h2c or net/http middleware where fasthttp does not apply
+package stdhttp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+)
+
+// Server implementations
+const (
+	FastHTTP = "fasthttp"
+	NetHTTP  = "net/http"
+)
+
+// Config holds the choice of HTTP server
+type Config struct {
+	Implementation string `mapstructure:"implementation"` // fasthttp or net/http
+	H2C            bool   `mapstructure:"h2c"`            // Accept HTTP/2 without TLS in net/http mode, e.g. behind a TLS-terminating proxy
+}
+
+// Validate checks the implementation and that h2c is only asked of net/http
+func (c Config) Validate() error {
+	switch c.Implementation {
+	case "", FastHTTP, NetHTTP:
+	default:
+		return fmt.Errorf("implementation must be %s or %s, got %q", FastHTTP, NetHTTP, c.Implementation)
+	}
+	if c.H2C && c.Implementation != NetHTTP {
+		return fmt.Errorf("h2c requires implementation %s", NetHTTP)
+	}
+	return nil
+}
+
+// NewServer creates a net/http server serving the handler of a fasthttp server with its
+// timeouts and body limits. HTTP/2 is negotiated over TLS, and accepted over cleartext
+// with h2c.
+func NewServer(cfg Config, fast *fasthttp.Server, tlsConfig *tls.Config) *http.Server {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(cfg.H2C)
+	return &http.Server{
+		Handler:           Handler(fast),
+		ReadHeaderTimeout: fast.ReadTimeout,
+		ReadTimeout:       fast.ReadTimeout,
+		IdleTimeout:       fast.IdleTimeout,
+		// Write deadlines are set per request, so streams can outlive the write timeout
+		TLSConfig: tlsConfig,
+		Protocols: protocols,
+	}
+}
+
+// Handler returns a net/http handler serving requests with the fasthttp server's handler.
+// Upgrade requests such as WebSockets are handed to the fasthttp server on the hijacked
+// connection, since fasthttp handlers take connections over through their own hijacking.
+func Handler(fast *fasthttp.Server) http.Handler {
+	return &adapter{server: fast}
+}
+
+type adapter struct {
+	server *fasthttp.Server
+}
+
+func (a *adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor == 1 && r.Header.Get("Upgrade") != "" {
+		a.upgrade(w, r)
+		return
+	}
+
+	var ctx fasthttp.RequestCtx
+	ctx.Init2(newRequestConn(r), logger{}, false)
+	req := &ctx.Request
+	req.Header.SetMethod(r.Method)
+	req.SetRequestURI(r.URL.RequestURI())
+	req.Header.SetHost(r.Host)
+	for name, values := range r.Header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	// Apply the per-request limits the fasthttp server would, such as longer write timeouts for streams
+	var config fasthttp.RequestConfig
+	if a.server.HeaderReceived != nil {
+		config = a.server.HeaderReceived(&req.Header)
+	}
+	writeTimeout := a.server.WriteTimeout
+	if config.WriteTimeout > 0 {
+		writeTimeout = config.WriteTimeout
+	}
+	if writeTimeout > 0 {
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(writeTimeout))
+	}
+	maxBodySize := a.server.MaxRequestBodySize
+	if config.MaxRequestBodySize > 0 {
+		maxBodySize = config.MaxRequestBodySize
+	}
+	if maxBodySize <= 0 {
+		maxBodySize = fasthttp.DefaultMaxRequestBodySize
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(maxBodySize)+1))
+	switch {
+	case err != nil:
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	case len(body) > maxBodySize:
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	req.SetBody(body)
+
+	a.server.Handler(&ctx)
+	if ctx.Hijacked() {
+		// Taking the connection over needs an HTTP/1.1 upgrade, which never reaches this point
+		http.Error(w, "Connection upgrades require HTTP/1.1", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	a.writeResponse(w, r, &ctx.Response)
+}
+
+// writeResponse copies a fasthttp response to the net/http writer, flushing streamed
+// bodies as they are written
+func (a *adapter) writeResponse(w http.ResponseWriter, r *http.Request, resp *fasthttp.Response) {
+	header := w.Header()
+	resp.Header.VisitAll(func(key, value []byte) {
+		switch string(key) {
+		case fasthttp.HeaderContentLength, fasthttp.HeaderTransferEncoding:
+			return
+		case fasthttp.HeaderConnection:
+			// Connection-specific headers are not allowed in HTTP/2
+			if r.ProtoMajor != 1 {
+				return
+			}
+		}
+		header.Add(string(key), string(value))
+	})
+	if header.Get(fasthttp.HeaderServer) == "" && a.server.Name != "" && !a.server.NoDefaultServerHeader {
+		header.Set(fasthttp.HeaderServer, a.server.Name)
+	}
+
+	if resp.IsBodyStream() {
+		w.WriteHeader(resp.StatusCode())
+		if err := resp.BodyWriteTo(flushWriter{w: w, rc: http.NewResponseController(w)}); err != nil {
+			log.Debug().Err(err).Str("path", r.URL.Path).Msg("Streamed response ended")
+		}
+		return
+	}
+	body := resp.Body()
+	if len(body) > 0 {
+		header.Set(fasthttp.HeaderContentLength, strconv.Itoa(len(body)))
+	}
+	w.WriteHeader(resp.StatusCode())
+	w.Write(body)
+}
+
+// upgrade hands the connection of an upgrade request to the fasthttp server, which reads
+// the request again from a replay of its head
+func (a *adapter) upgrade(w http.ResponseWriter, r *http.Request) {
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "Connection upgrades are not supported", http.StatusInternalServerError)
+		return
+	}
+	// The fasthttp server sets its own deadlines
+	conn.SetDeadline(time.Time{})
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "%s %s HTTP/1.1\r\nHost: %s\r\n", r.Method, r.RequestURI, r.Host)
+	r.Header.Write(&head)
+	head.WriteString("\r\n")
+
+	// Bytes the client sent after the request head are still buffered in rw
+	replay := &replayConn{Conn: conn, r: io.MultiReader(&head, rw.Reader)}
+	var served net.Conn = replay
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		served = &tlsReplayConn{replayConn: replay, tls: tlsConn}
+	}
+	if err := a.server.ServeConn(served); err != nil && !errors.Is(err, io.EOF) {
+		log.Debug().Err(err).Str("path", r.URL.Path).Msg("Upgraded connection ended")
+	}
+}
+
+// flushWriter flushes every write, so streamed events reach the client as they happen
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err == nil {
+		err = f.rc.Flush()
+	}
+	return n, err
+}
+
+// requestConn stands in for the connection of a net/http request, so fasthttp handlers see
+// its addresses. Nothing is read from or written to it.
+type requestConn struct {
+	local, remote net.Addr
+}
+
+func newRequestConn(r *http.Request) net.Conn {
+	c := &requestConn{remote: &net.TCPAddr{}, local: &net.TCPAddr{}}
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		c.remote = addr
+	}
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		c.local = addr
+	}
+	if r.TLS != nil {
+		return &tlsRequestConn{requestConn: c, state: *r.TLS}
+	}
+	return c
+}
+
+func (c *requestConn) Read([]byte) (int, error)         { return 0, io.EOF }
+func (c *requestConn) Write(p []byte) (int, error)      { return len(p), nil }
+func (c *requestConn) Close() error                     { return nil }
+func (c *requestConn) LocalAddr() net.Addr              { return c.local }
+func (c *requestConn) RemoteAddr() net.Addr             { return c.remote }
+func (c *requestConn) SetDeadline(time.Time) error      { return nil }
+func (c *requestConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *requestConn) SetWriteDeadline(time.Time) error { return nil }
+
+// tlsRequestConn reports the TLS state of the request, so fasthttp handlers see it as
+// served over TLS
+type tlsRequestConn struct {
+	*requestConn
+	state tls.ConnectionState
+}
+
+func (c *tlsRequestConn) Handshake() error                     { return nil }
+func (c *tlsRequestConn) ConnectionState() tls.ConnectionState { return c.state }
+
+// replayConn reads the replayed request head before the rest of the connection
+type replayConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *replayConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// tlsReplayConn keeps the TLS state of an upgraded connection visible to fasthttp
+type tlsReplayConn struct {
+	*replayConn
+	tls *tls.Conn
+}
+
+func (c *tlsReplayConn) Handshake() error                     { return c.tls.Handshake() }
+func (c *tlsReplayConn) ConnectionState() tls.ConnectionState { return c.tls.ConnectionState() }
+
+// logger writes fasthttp messages about adapted requests to the debug log
+type logger struct{}
+
+func (logger) Printf(format string, args ...interface{}) {
+	log.Debug().Msgf(format, args...)
+}
@@ -0,0 +1,167 @@
+package stdhttp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// newTestServer serves a fasthttp handler through the adapter, over h2c as well as HTTP/1.1
+func newTestServer(t *testing.T, handler fasthttp.RequestHandler) *httptest.Server {
+	fast := &fasthttp.Server{Handler: handler, Name: "test-server", MaxRequestBodySize: 16}
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config = NewServer(Config{Implementation: NetHTTP, H2C: true}, fast, nil)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestHandler tests that requests and buffered responses are copied both ways
+func TestHandler(t *testing.T) {
+	srv := newTestServer(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set("X-Remote", ctx.RemoteIP().String())
+		ctx.Response.Header.SetCookie(func() *fasthttp.Cookie {
+			c := fasthttp.AcquireCookie()
+			c.SetKey("session")
+			c.SetValue("abc")
+			return c
+		}())
+		ctx.SetContentType("application/json")
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		fmt.Fprintf(ctx, `{"method":%q,"path":%q,"query":%q,"token":%q,"cookie":%q,"body":%q}`,
+			ctx.Method(), ctx.Path(), ctx.QueryArgs().Peek("q"), ctx.Request.Header.Peek("X-Token"),
+			ctx.Request.Header.Cookie("id"), ctx.PostBody())
+	})
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/items?q=1", strings.NewReader("hello"))
+	require.NoError(t, err)
+	req.Header.Set("X-Token", "secret")
+	req.AddCookie(&http.Cookie{Name: "id", Value: "42"})
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, `{"method":"POST","path":"/items","query":"1","token":"secret","cookie":"42","body":"hello"}`, string(body))
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "127.0.0.1", resp.Header.Get("X-Remote"))
+	assert.Equal(t, "test-server", resp.Header.Get("Server"))
+	assert.Contains(t, resp.Header.Get("Set-Cookie"), "session=abc")
+
+	// Bodies beyond the server's limit are refused
+	resp, err = srv.Client().Post(srv.URL+"/items", "text/plain", strings.NewReader(strings.Repeat("x", 17)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+// TestHandler_H2C tests that HTTP/2 is served over cleartext with h2c
+func TestHandler_H2C(t *testing.T) {
+	srv := newTestServer(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyString("ok")
+	})
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.Equal(t, "ok", string(body))
+}
+
+// TestHandler_Stream tests that streamed bodies are flushed as they are written
+func TestHandler_Stream(t *testing.T) {
+	next := make(chan struct{})
+	srv := newTestServer(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/event-stream")
+		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			for i := 0; i < 2; i++ {
+				fmt.Fprintf(w, "data: %d\n\n", i)
+				w.Flush()
+				<-next
+			}
+		})
+	})
+
+	resp, err := srv.Client().Get(srv.URL + "/watch")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	for i := 0; i < 2; i++ {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("data: %d\n", i), line)
+		reader.ReadString('\n')
+		next <- struct{}{}
+	}
+}
+
+// TestHandler_Upgrade tests that upgrade requests are served by the fasthttp server on the
+// hijacked connection
+func TestHandler_Upgrade(t *testing.T) {
+	srv := newTestServer(t, func(ctx *fasthttp.RequestCtx) {
+		if string(ctx.Request.Header.Peek("Upgrade")) != "echo" {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			return
+		}
+		ctx.SetStatusCode(fasthttp.StatusSwitchingProtocols)
+		ctx.Response.Header.Set("Upgrade", "echo")
+		ctx.Hijack(func(c net.Conn) {
+			io.Copy(c, c)
+		})
+	})
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /echo HTTP/1.1\r\nHost: example\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\nping")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(reader, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+}
+
+// TestHandler_TLS tests that handlers see requests served over TLS as such
+func TestHandler_TLS(t *testing.T) {
+	fast := &fasthttp.Server{Handler: func(ctx *fasthttp.RequestCtx) {
+		fmt.Fprintf(ctx, "%t", ctx.IsTLS())
+	}}
+	srv := httptest.NewUnstartedServer(Handler(fast))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.Equal(t, "true", string(body))
+}
+
+// TestConfig_Validate tests the implementation and h2c checks
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.NoError(t, Config{Implementation: NetHTTP, H2C: true}.Validate())
+	assert.Error(t, Config{Implementation: "nethttp"}.Validate())
+	assert.Error(t, Config{Implementation: FastHTTP, H2C: true}.Validate())
+}
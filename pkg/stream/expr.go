@@ -0,0 +1,335 @@
+package stream
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MaxExpressionLength bounds filter expressions sent by clients
+const MaxExpressionLength = 2048
+
+// maxExpressionDepth bounds nested parentheses and NOT operators
+const maxExpressionDepth = 32
+
+// Expression is a parsed filter such as
+//
+//	cluster=prod* AND kind=Deployment AND namespace!=kube-system
+//
+// Comparisons take a field, = or != and a value in which * matches any run of characters.
+// Fields are cluster, type, kind, namespace, name and label.<key>; a missing label compares
+// as empty. Comparisons combine with AND, OR, NOT and parentheses, where NOT binds tightest
+// and AND binds tighter than OR. Keywords are case-insensitive and values containing spaces
+// or operators are quoted with " or '.
+type Expression struct {
+	source string
+	root   exprNode // nil matches every event
+}
+
+// ParseExpression parses a filter expression; an empty expression matches every event
+func ParseExpression(expr string) (*Expression, error) {
+	if len(expr) > MaxExpressionLength {
+		return nil, fmt.Errorf("filter is longer than %d characters", MaxExpressionLength)
+	}
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	e := &Expression{source: strings.TrimSpace(expr)}
+	if len(tokens) == 0 {
+		return e, nil
+	}
+	if e.root, err = p.parseOr(0); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos)
+	}
+	return e, nil
+}
+
+// String returns the expression as it was given
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Matches reports whether the event passes the expression
+func (e *Expression) Matches(event Event) bool {
+	return e.root == nil || e.root.matches(event)
+}
+
+// exprNode is a node of a parsed expression
+type exprNode interface {
+	matches(event Event) bool
+}
+
+type andNode struct{ left, right exprNode }
+type orNode struct{ left, right exprNode }
+type notNode struct{ operand exprNode }
+
+// comparison tests one field against a glob pattern
+type comparison struct {
+	field   string
+	label   string // Label key for label.<key> fields
+	pattern string
+	negate  bool
+}
+
+func (n andNode) matches(event Event) bool { return n.left.matches(event) && n.right.matches(event) }
+func (n orNode) matches(event Event) bool  { return n.left.matches(event) || n.right.matches(event) }
+func (n notNode) matches(event Event) bool { return !n.operand.matches(event) }
+
+func (c comparison) matches(event Event) bool {
+	var value string
+	switch c.field {
+	case "cluster":
+		value = event.Cluster
+	case "type":
+		value = event.Type
+	case "kind":
+		value = event.Kind
+	case "namespace":
+		value = event.Namespace
+	case "name":
+		value = event.Name
+	case "label":
+		if obj, ok := event.Object.(metav1.Object); ok {
+			value = obj.GetLabels()[c.label]
+		}
+	}
+	return globMatch(c.pattern, value) != c.negate
+}
+
+// globMatch reports whether the value matches a pattern in which * matches any run of
+// characters, including none
+func globMatch(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return len(value) >= len(last) && strings.HasSuffix(value, last)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString // Quoted value, never a keyword
+	tokenEqual
+	tokenNotEqual
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenWord, tokenString:
+		return fmt.Sprintf("%q", t.value)
+	case tokenEqual:
+		return "'='"
+	case tokenNotEqual:
+		return "'!='"
+	case tokenOpen:
+		return "'('"
+	default:
+		return "')'"
+	}
+}
+
+// isKeyword reports whether the token is an unquoted AND, OR or NOT
+func (t token) isKeyword(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.value, keyword)
+}
+
+// tokenize splits an expression into words, quoted strings, operators and parentheses
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, pos: i})
+			i++
+		case c == '=':
+			tokens = append(tokens, token{kind: tokenEqual, pos: i})
+			i++
+		case c == '!':
+			if i+1 >= len(expr) || expr[i+1] != '=' {
+				return nil, fmt.Errorf("expected '!=' at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenNotEqual, pos: i})
+			i += 2
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: expr[i+1 : i+1+end], pos: i})
+			i += end + 2
+		default:
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t\n\r()=!\"'", rune(expr[i])) {
+				i++
+			}
+			word := expr[start:i]
+			for _, r := range word {
+				if unicode.IsControl(r) {
+					return nil, fmt.Errorf("invalid character at position %d", start)
+				}
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: word, pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+// exprParser is a recursive descent parser over the tokens of an expression
+type exprParser struct {
+	tokens []token
+	next   int
+}
+
+func (p *exprParser) peek() token {
+	if p.next < len(p.tokens) {
+		return p.tokens[p.next]
+	}
+	return token{kind: tokenEOF, pos: p.end()}
+}
+
+func (p *exprParser) advance() token {
+	t := p.peek()
+	if p.next < len(p.tokens) {
+		p.next++
+	}
+	return t
+}
+
+// end returns the position just after the last token
+func (p *exprParser) end() int {
+	if len(p.tokens) == 0 {
+		return 0
+	}
+	last := p.tokens[len(p.tokens)-1]
+	return last.pos + len(last.value) + 1
+}
+
+func (p *exprParser) parseOr(depth int) (exprNode, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("OR") {
+		p.advance()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd(depth int) (exprNode, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("AND") {
+		p.advance()
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot(depth int) (exprNode, error) {
+	if depth > maxExpressionDepth {
+		return nil, fmt.Errorf("filter is nested deeper than %d levels", maxExpressionDepth)
+	}
+	t := p.peek()
+	if t.isKeyword("NOT") {
+		p.advance()
+		operand, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	if t.kind == tokenOpen {
+		p.advance()
+		inner, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if t := p.advance(); t.kind != tokenClose {
+			return nil, fmt.Errorf("expected ')' at position %d, got %s", t.pos, t)
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	t := p.advance()
+	if t.kind != tokenWord || t.isKeyword("AND") || t.isKeyword("OR") {
+		return nil, fmt.Errorf("expected a field at position %d, got %s", t.pos, t)
+	}
+	c := comparison{field: strings.ToLower(t.value)}
+	switch {
+	case c.field == "cluster" || c.field == "type" || c.field == "kind" || c.field == "namespace" || c.field == "name":
+	case strings.HasPrefix(c.field, "label."):
+		c.field, c.label = "label", t.value[len("label."):]
+		if c.label == "" {
+			return nil, fmt.Errorf("label field at position %d has no key", t.pos)
+		}
+	default:
+		return nil, fmt.Errorf("unknown field %q at position %d, expected cluster, type, kind, namespace, name or label.<key>", t.value, t.pos)
+	}
+
+	switch op := p.advance(); op.kind {
+	case tokenEqual:
+	case tokenNotEqual:
+		c.negate = true
+	default:
+		return nil, fmt.Errorf("expected '=' or '!=' after %s at position %d, got %s", t.value, op.pos, op)
+	}
+
+	value := p.advance()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, fmt.Errorf("expected a value at position %d, got %s", value.pos, value)
+	}
+	c.pattern = value.value
+	return c, nil
+}
@@ -0,0 +1,99 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExpression_Matches(t *testing.T) {
+	web := Event{
+		Type: EventModified, Cluster: "prod-eu", Kind: "Deployment", Namespace: "shop", Name: "web",
+		Object: &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web", "tier": "frontend"}}},
+	}
+	dns := Event{Type: EventAdded, Cluster: "prod-us", Kind: "Deployment", Namespace: "kube-system", Name: "coredns"}
+	pod := Event{Type: EventDeleted, Cluster: "staging", Kind: "Pod", Namespace: "shop", Name: "web-5d8f9-x2x"}
+
+	tests := []struct {
+		expr    string
+		matches []bool // web, dns, pod
+	}{
+		{"", []bool{true, true, true}},
+		{"cluster=prod* AND kind=Deployment AND namespace!=kube-system", []bool{true, false, false}},
+		{"kind=Pod OR namespace=kube-system", []bool{false, true, true}},
+		{"not kind=Pod", []bool{true, true, false}},
+		{"NOT (kind=Pod OR namespace=kube-system)", []bool{true, false, false}},
+		{"kind=Deployment and (cluster=*-us or label.app=web)", []bool{true, true, false}},
+		{"name=web-*-x2x", []bool{false, false, true}},
+		{"name=*dns*", []bool{false, true, false}},
+		{"label.tier='frontend'", []bool{true, false, false}},
+		{"label.app!=web", []bool{false, true, true}},
+		{"type=DELETED", []bool{false, false, true}},
+		{`namespace="shop"`, []bool{true, false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := ParseExpression(tt.expr)
+			require.NoError(t, err)
+			for i, event := range []Event{web, dns, pod} {
+				assert.Equal(t, tt.matches[i], e.Matches(event), "event %s", event.Name)
+			}
+		})
+	}
+}
+
+func TestExpression_Precedence(t *testing.T) {
+	// AND binds tighter than OR: a OR (b AND c)
+	e, err := ParseExpression("kind=Pod OR kind=Deployment AND cluster=staging")
+	require.NoError(t, err)
+	assert.True(t, e.Matches(Event{Kind: "Pod", Cluster: "prod"}))
+	assert.False(t, e.Matches(Event{Kind: "Deployment", Cluster: "prod"}))
+	assert.True(t, e.Matches(Event{Kind: "Deployment", Cluster: "staging"}))
+}
+
+func TestParseExpression_Errors(t *testing.T) {
+	for expr, message := range map[string]string{
+		"kind":                    "expected '=' or '!='",
+		"kind=":                   "expected a value",
+		"kind=Pod AND":            "expected a field",
+		"kind=Pod Deployment":     "unexpected",
+		"(kind=Pod":               "expected ')'",
+		"owner=team-a":            "unknown field",
+		"label.=web":              "has no key",
+		"kind!Pod":                "expected '!='",
+		`name="web`:               "unterminated string",
+		"kind=Pod OR OR kind=Pod": "expected a field",
+	} {
+		_, err := ParseExpression(expr)
+		if assert.Error(t, err, expr) {
+			assert.Contains(t, err.Error(), message, expr)
+		}
+	}
+
+	deep := ""
+	for i := 0; i <= maxExpressionDepth; i++ {
+		deep += "("
+	}
+	_, err := ParseExpression(deep + "kind=Pod")
+	assert.ErrorContains(t, err, "nested deeper")
+
+	long := make([]byte, MaxExpressionLength+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	_, err = ParseExpression(string(long))
+	assert.ErrorContains(t, err, "longer than")
+}
+
+func TestGlobMatch(t *testing.T) {
+	assert.True(t, globMatch("prod*", "prod"))
+	assert.True(t, globMatch("*", ""))
+	assert.True(t, globMatch("a*b*c", "abc"))
+	assert.True(t, globMatch("*b*b", "abb"))
+	assert.False(t, globMatch("*b*b", "ab"))
+	assert.False(t, globMatch("prod", "production"))
+	assert.False(t, globMatch("a*a", "a"))
+}
@@ -0,0 +1,64 @@
+package stream
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Filter selects events by kind, namespace, labels and metadata fields
+type Filter struct {
+	Kind      string // Empty matches every kind
+	Namespace string // Empty matches every namespace
+	labels    labels.Selector
+	fields    fields.Selector
+}
+
+// NewFilter parses the selectors; field selectors support metadata.name and metadata.namespace
+func NewFilter(kind, namespace, labelSelector, fieldSelector string) (Filter, error) {
+	f := Filter{Kind: kind, Namespace: namespace, labels: labels.Everything(), fields: fields.Everything()}
+
+	if labelSelector != "" {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			return f, fmt.Errorf("invalid labelSelector: %v", err)
+		}
+		f.labels = selector
+	}
+
+	if fieldSelector != "" {
+		selector, err := fields.ParseSelector(fieldSelector)
+		if err != nil {
+			return f, fmt.Errorf("invalid fieldSelector: %v", err)
+		}
+		for _, r := range selector.Requirements() {
+			if r.Field != "metadata.name" && r.Field != "metadata.namespace" {
+				return f, fmt.Errorf("invalid fieldSelector: field %q is not supported", r.Field)
+			}
+		}
+		f.fields = selector
+	}
+	return f, nil
+}
+
+// Matches reports whether the event passes the filter
+func (f Filter) Matches(event Event) bool {
+	if f.Kind != "" && event.Kind != f.Kind {
+		return false
+	}
+	if f.Namespace != "" && event.Namespace != f.Namespace {
+		return false
+	}
+	if f.fields != nil && !f.fields.Matches(fields.Set{"metadata.name": event.Name, "metadata.namespace": event.Namespace}) {
+		return false
+	}
+	if f.labels != nil && !f.labels.Empty() {
+		obj, ok := event.Object.(metav1.Object)
+		if !ok || !f.labels.Matches(labels.Set(obj.GetLabels())) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package stream
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Overflow policies for clients whose buffer is full
+const (
+	OverflowDisconnect = "disconnect"  // End the stream; clients resume from their last event
+	OverflowDropOldest = "drop-oldest" // Discard the oldest queued event to make room
+)
+
+// Slow consumer metrics, served by the controller-runtime metrics endpoint
+var (
+	streamSubscribers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_custom_controller_stream_subscribers",
+		Help: "Clients subscribed to a stream",
+	}, []string{"stream"})
+	streamDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_custom_controller_stream_dropped_events_total",
+		Help: "Events discarded for clients that fell behind under the drop-oldest policy",
+	}, []string{"stream"})
+	streamDisconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_custom_controller_stream_slow_disconnects_total",
+		Help: "Clients disconnected because they fell behind under the disconnect policy",
+	}, []string{"stream"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(streamSubscribers, streamDropped, streamDisconnects)
+}
+
+// Validate checks the overflow policy; other settings fall back to defaults when unset
+func (c Config) Validate() error {
+	switch c.OverflowPolicy {
+	case "", OverflowDisconnect, OverflowDropOldest:
+		return nil
+	}
+	return fmt.Errorf("unknown overflow_policy %q, expected %s or %s", c.OverflowPolicy, OverflowDisconnect, OverflowDropOldest)
+}
+
+// Offer queues a value without blocking. When the queue is full, the drop-oldest policy
+// discards queued values until it fits; the disconnect policy returns false and the caller
+// ends the client's stream. Drops and disconnects are counted for the named stream.
+func Offer[T any](queue chan T, value T, policy, stream string) bool {
+	for {
+		select {
+		case queue <- value:
+			return true
+		default:
+		}
+		if policy != OverflowDropOldest {
+			streamDisconnects.WithLabelValues(stream).Inc()
+			return false
+		}
+		select {
+		case <-queue:
+			streamDropped.WithLabelValues(stream).Inc()
+		default:
+			// Drained by the client in the meantime
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package stream
+
+import (
+	"testing"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcaster_DropOldest(t *testing.T) {
+	b := NewBroadcaster("drop-oldest-test", Config{BufferSize: 2, OverflowPolicy: OverflowDropOldest})
+	slow, _, err := b.Subscribe("")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(streamSubscribers.WithLabelValues("drop-oldest-test")))
+
+	for rv := 1; rv <= 5; rv++ {
+		b.Publish(event(rv))
+	}
+
+	// The slow subscriber stays connected with the newest events
+	assert.Equal(t, "4", (<-slow.Events()).ResourceVersion)
+	assert.Equal(t, "5", (<-slow.Events()).ResourceVersion)
+	assert.False(t, slow.Overflowed())
+	assert.Equal(t, 3.0, promtestutil.ToFloat64(streamDropped.WithLabelValues("drop-oldest-test")))
+
+	b.Unsubscribe(slow)
+	b.Unsubscribe(slow)
+	assert.Equal(t, 0.0, promtestutil.ToFloat64(streamSubscribers.WithLabelValues("drop-oldest-test")))
+}
+
+func TestBroadcaster_DisconnectIsCounted(t *testing.T) {
+	b := NewBroadcaster("disconnect-test", Config{BufferSize: 1})
+	slow, _, err := b.Subscribe("")
+	require.NoError(t, err)
+
+	b.Publish(event(1))
+	b.Publish(event(2))
+
+	assert.True(t, slow.Overflowed())
+	assert.Equal(t, 1.0, promtestutil.ToFloat64(streamDisconnects.WithLabelValues("disconnect-test")))
+	assert.Equal(t, 0.0, promtestutil.ToFloat64(streamSubscribers.WithLabelValues("disconnect-test")))
+}
+
+func TestOffer(t *testing.T) {
+	queue := make(chan int, 2)
+	assert.True(t, Offer(queue, 1, OverflowDisconnect, "offer-test"))
+	assert.True(t, Offer(queue, 2, OverflowDisconnect, "offer-test"))
+	assert.False(t, Offer(queue, 3, OverflowDisconnect, "offer-test"))
+
+	assert.True(t, Offer(queue, 3, OverflowDropOldest, "offer-test"))
+	assert.Equal(t, []int{2, 3}, []int{<-queue, <-queue})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.NoError(t, Config{OverflowPolicy: OverflowDropOldest}.Validate())
+	assert.Error(t, Config{OverflowPolicy: "block"}.Validate())
+}
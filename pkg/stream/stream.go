@@ -0,0 +1,265 @@
+// Package stream fans informer events out to streaming API clients with resourceVersion resume
+package stream
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Event types, matching the Kubernetes watch event types
+const (
+	EventAdded    = "ADDED"
+	EventModified = "MODIFIED"
+	EventDeleted  = "DELETED"
+)
+
+// ErrResourceVersionTooOld is returned when a resume point is no longer in the history
+var ErrResourceVersionTooOld = errors.New("resource version too old")
+
+// Config holds streaming settings
+type Config struct {
+	HistorySize int           `mapstructure:"history_size"` // Events kept for resuming clients
+	BufferSize  int           `mapstructure:"buffer_size"`  // Events queued per client before it is disconnected
+	Heartbeat   time.Duration `mapstructure:"heartbeat"`    // Interval of keep-alive comments on idle streams
+	MaxDuration time.Duration `mapstructure:"max_duration"` // Streams are closed after this long and clients resume
+
+	// What happens when a client falls buffer_size events behind: disconnect (default), after
+	// which it resumes from its last event, or drop-oldest, which keeps it connected but
+	// loses events
+	OverflowPolicy string `mapstructure:"overflow_policy"`
+}
+
+// Event is a single change of a watched object
+type Event struct {
+	Type            string      `json:"type"`
+	Cluster         string      `json:"cluster,omitempty"` // Set on events combined from several clusters
+	Kind            string      `json:"kind"`
+	Namespace       string      `json:"namespace"`
+	Name            string      `json:"name"`
+	ResourceVersion string      `json:"resourceVersion"`
+	Time            time.Time   `json:"time"` // When the change was observed
+	Object          interface{} `json:"object"`
+}
+
+// Subscription receives events published after it was created
+type Subscription struct {
+	events     chan Event
+	once       sync.Once
+	overflowed bool // Set before events is closed when the subscriber fell behind
+}
+
+// Events returns the channel of events, closed when the subscription ends
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Overflowed reports whether the subscription ended because the client fell behind
+func (s *Subscription) Overflowed() bool {
+	return s.overflowed
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() { close(s.events) })
+}
+
+// Broadcaster keeps a bounded history of events and delivers new ones to subscribers
+type Broadcaster struct {
+	name   string // Labels the stream's metrics
+	config Config
+
+	mu          sync.Mutex
+	history     []Event
+	evicted     uint64 // Highest resourceVersion dropped from the history
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewBroadcaster creates a broadcaster with defaults applied to unset settings. The name
+// labels its metrics.
+func NewBroadcaster(name string, cfg Config) *Broadcaster {
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = 1000
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 256
+	}
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = 15 * time.Second
+	}
+	if cfg.MaxDuration <= 0 {
+		cfg.MaxDuration = time.Hour
+	}
+	if cfg.OverflowPolicy == "" {
+		cfg.OverflowPolicy = OverflowDisconnect
+	}
+	return &Broadcaster{
+		name:        name,
+		config:      cfg,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Heartbeat returns the interval between keep-alive messages on idle streams
+func (b *Broadcaster) Heartbeat() time.Duration {
+	return b.config.Heartbeat
+}
+
+// MaxDuration returns how long a single stream stays open
+func (b *Broadcaster) MaxDuration() time.Duration {
+	return b.config.MaxDuration
+}
+
+// OverflowPolicy returns how clients that fall behind are handled
+func (b *Broadcaster) OverflowPolicy() string {
+	return b.config.OverflowPolicy
+}
+
+// Publish records the event and delivers it to every subscriber. A subscriber whose buffer
+// is full loses its oldest events or is disconnected, depending on the overflow policy, so
+// a slow client cannot block the informer.
+func (b *Broadcaster) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.history = append(b.history, event)
+	if excess := len(b.history) - b.config.HistorySize; excess > 0 {
+		for _, dropped := range b.history[:excess] {
+			b.evicted = max(b.evicted, versionOf(dropped))
+		}
+		b.history = b.history[excess:]
+	}
+
+	for sub := range b.subscribers {
+		if !Offer(sub.events, event, b.config.OverflowPolicy, b.name) {
+			sub.overflowed = true
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber. With a resourceVersion, events newer than it are returned
+// for replay; ErrResourceVersionTooOld means the client must start over without one.
+func (b *Broadcaster) Subscribe(resourceVersion string) (*Subscription, []Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Event
+	if resourceVersion != "" {
+		since, err := strconv.ParseUint(resourceVersion, 10, 64)
+		if err != nil {
+			return nil, nil, ErrResourceVersionTooOld
+		}
+		// Events after the resume point that were already dropped cannot be replayed
+		if since < b.evicted {
+			return nil, nil, ErrResourceVersionTooOld
+		}
+		for _, event := range b.history {
+			if versionOf(event) > since {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	sub := &Subscription{events: make(chan Event, b.config.BufferSize)}
+	if b.closed {
+		sub.close()
+		return sub, replay, nil
+	}
+	b.subscribers[sub] = struct{}{}
+	streamSubscribers.WithLabelValues(b.name).Inc()
+	return sub, replay, nil
+}
+
+// History returns the retained events that match, oldest first
+func (b *Broadcaster) History(match func(Event) bool) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var events []Event
+	for _, event := range b.history {
+		if match == nil || match(event) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Unsubscribe stops delivery to the subscription and closes its channel
+func (b *Broadcaster) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// Close ends every subscription; later publishes are ignored
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subscribers {
+		b.remove(sub)
+	}
+}
+
+// remove ends a subscription; callers hold the lock
+func (b *Broadcaster) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		streamSubscribers.WithLabelValues(b.name).Dec()
+	}
+	sub.close()
+}
+
+// versionOf returns the numeric resourceVersion of an event, zero when it is not numeric
+func versionOf(event Event) uint64 {
+	v, _ := strconv.ParseUint(event.ResourceVersion, 10, 64)
+	return v
+}
+
+// NewEvent builds an event for a Kubernetes object
+func NewEvent(eventType, kind string, obj metav1.Object) Event {
+	return Event{
+		Type:            eventType,
+		Kind:            kind,
+		Namespace:       obj.GetNamespace(),
+		Name:            obj.GetName(),
+		ResourceVersion: obj.GetResourceVersion(),
+		Time:            time.Now(),
+		Object:          obj,
+	}
+}
+
+// EventHandler publishes informer events for objects of the given kind to the broadcaster
+func EventHandler(b *Broadcaster, kind string) cache.ResourceEventHandlerFuncs {
+	publish := func(eventType string, obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if o, ok := obj.(metav1.Object); ok {
+			b.Publish(NewEvent(eventType, kind, o))
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { publish(EventAdded, obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Periodic resyncs redeliver unchanged objects
+			oldMeta, okOld := oldObj.(metav1.Object)
+			newMeta, okNew := newObj.(metav1.Object)
+			if okOld && okNew && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+			publish(EventModified, newObj)
+		},
+		DeleteFunc: func(obj interface{}) { publish(EventDeleted, obj) },
+	}
+}
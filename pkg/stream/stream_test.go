@@ -0,0 +1,131 @@
+package stream
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func event(rv int) Event {
+	return Event{Type: EventModified, Kind: "Deployment", Namespace: "default", Name: "web", ResourceVersion: strconv.Itoa(rv)}
+}
+
+func TestBroadcaster_PublishAndResume(t *testing.T) {
+	b := NewBroadcaster("test", Config{HistorySize: 3, BufferSize: 10})
+
+	live, replay, err := b.Subscribe("")
+	require.NoError(t, err)
+	assert.Empty(t, replay)
+
+	for rv := 10; rv <= 14; rv++ {
+		b.Publish(event(rv))
+	}
+	for rv := 10; rv <= 14; rv++ {
+		assert.Equal(t, strconv.Itoa(rv), (<-live.Events()).ResourceVersion)
+	}
+
+	// History keeps 12, 13 and 14, so resuming after 11 or later works
+	_, replay, err = b.Subscribe("12")
+	require.NoError(t, err)
+	require.Len(t, replay, 2)
+	assert.Equal(t, "13", replay[0].ResourceVersion)
+	assert.Equal(t, "14", replay[1].ResourceVersion)
+
+	_, replay, err = b.Subscribe("11")
+	require.NoError(t, err)
+	assert.Len(t, replay, 3)
+
+	// Event 11 was dropped, so a client that last saw 10 missed it
+	_, _, err = b.Subscribe("10")
+	assert.ErrorIs(t, err, ErrResourceVersionTooOld)
+	_, _, err = b.Subscribe("not-a-version")
+	assert.ErrorIs(t, err, ErrResourceVersionTooOld)
+}
+
+func TestBroadcaster_SlowSubscriberIsDisconnected(t *testing.T) {
+	b := NewBroadcaster("test", Config{BufferSize: 2})
+	slow, _, err := b.Subscribe("")
+	require.NoError(t, err)
+	fast, _, err := b.Subscribe("")
+	require.NoError(t, err)
+
+	for rv := 1; rv <= 3; rv++ {
+		b.Publish(event(rv))
+		<-fast.Events()
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	assert.Equal(t, 2, received)
+	assert.True(t, slow.Overflowed())
+	assert.False(t, fast.Overflowed())
+}
+
+func TestBroadcaster_Close(t *testing.T) {
+	b := NewBroadcaster("test", Config{})
+	sub, _, err := b.Subscribe("")
+	require.NoError(t, err)
+
+	b.Close()
+	_, open := <-sub.Events()
+	assert.False(t, open)
+
+	// Unsubscribing after close and publishing to a closed broadcaster are harmless
+	b.Unsubscribe(sub)
+	b.Publish(event(1))
+
+	late, _, err := b.Subscribe("")
+	require.NoError(t, err)
+	_, open = <-late.Events()
+	assert.False(t, open)
+}
+
+func TestBroadcaster_History(t *testing.T) {
+	b := NewBroadcaster("test", Config{HistorySize: 3})
+	for rv := 1; rv <= 5; rv++ {
+		b.Publish(event(rv))
+	}
+
+	all := b.History(nil)
+	require.Len(t, all, 3)
+	assert.Equal(t, "3", all[0].ResourceVersion)
+
+	odd := b.History(func(e Event) bool { return versionOf(e)%2 == 1 })
+	require.Len(t, odd, 2)
+	assert.Equal(t, "5", odd[1].ResourceVersion)
+}
+
+func TestFilter_Matches(t *testing.T) {
+	web := NewEvent(EventAdded, "Deployment", &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name: "web", Namespace: "default", ResourceVersion: "7", Labels: map[string]string{"app": "web"},
+	}})
+	assert.Equal(t, "7", web.ResourceVersion)
+	assert.False(t, web.Time.IsZero())
+
+	f, err := NewFilter("Deployment", "default", "app=web", "metadata.name=web")
+	require.NoError(t, err)
+	assert.True(t, f.Matches(web))
+
+	f, err = NewFilter("Pod", "", "", "")
+	require.NoError(t, err)
+	assert.False(t, f.Matches(web))
+
+	f, err = NewFilter("", "kube-system", "", "")
+	require.NoError(t, err)
+	assert.False(t, f.Matches(web))
+
+	f, err = NewFilter("", "", "app=api", "")
+	require.NoError(t, err)
+	assert.False(t, f.Matches(web))
+
+	_, err = NewFilter("", "", "app in (", "")
+	assert.Error(t, err)
+	_, err = NewFilter("", "", "", "spec.replicas=1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,213 @@
+// Package terminal bridges WebSocket connections to the exec streams of containers, so web
+// terminals can run commands in pods
+package terminal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// Channels prefixing binary frames, as in the Kubernetes channel.k8s.io protocol
+const (
+	ChannelStdin  byte = 0 // Client to server: input of the command
+	ChannelStdout byte = 1 // Server to client: output, including stderr with a TTY
+	ChannelStderr byte = 2 // Server to client: error output without a TTY
+	ChannelResize byte = 4 // Client to server: {"Width":80,"Height":24}
+)
+
+// writeTimeout bounds how long a single frame may take to reach the client
+const writeTimeout = 10 * time.Second
+
+// Options configure a session
+type Options struct {
+	Stdin        bool
+	TTY          bool
+	PingInterval time.Duration // Keep-alive pings; clients that stop answering are disconnected
+	IdleTimeout  time.Duration // Sessions without input or output for this long are ended, 0 disables
+}
+
+// Exit is the text frame sent when the command ends
+type Exit struct {
+	Type     string `json:"type"`            // Always exit
+	ExitCode int    `json:"exit_code"`       // -1 when the command did not report one
+	Error    string `json:"error,omitempty"` // Why the session ended without an exit code
+}
+
+// session is one connection bridged to one command
+type session struct {
+	conn     *websocket.Conn
+	ctx      context.Context
+	writeMu  sync.Mutex
+	sizes    chan remotecommand.TerminalSize
+	activity atomic.Int64 // Unix nanoseconds of the last input or output
+	idle     atomic.Bool
+}
+
+// Serve runs the command with its streams bridged to the connection until the command exits,
+// the client disconnects or the session goes idle. It sends the exit frame and a close frame
+// before returning; the caller closes the connection.
+func Serve(ctx context.Context, conn *websocket.Conn, executor remotecommand.Executor, opts Options) Exit {
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &session{conn: conn, ctx: ctx, sizes: make(chan remotecommand.TerminalSize, 1)}
+	s.touch()
+
+	stdinReader, stdinWriter := io.Pipe()
+	defer stdinReader.Close()
+	go func() {
+		s.readLoop(stdinWriter, opts.PingInterval)
+		// The client went away, so nobody is left to see the command's output
+		cancel()
+	}()
+	go s.keepAlive(cancel, opts)
+
+	streamOpts := remotecommand.StreamOptions{
+		Stdout: frameWriter{s, ChannelStdout},
+		Tty:    opts.TTY,
+	}
+	if opts.Stdin {
+		streamOpts.Stdin = stdinReader
+	}
+	if opts.TTY {
+		streamOpts.TerminalSizeQueue = s
+	} else {
+		streamOpts.Stderr = frameWriter{s, ChannelStderr}
+	}
+
+	exit := s.exitStatus(executor.StreamWithContext(ctx, streamOpts))
+	s.writeMu.Lock()
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if conn.WriteJSON(exit) == nil {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	}
+	s.writeMu.Unlock()
+	return exit
+}
+
+// exitStatus turns the result of the stream into the exit frame
+func (s *session) exitStatus(err error) Exit {
+	exit := Exit{Type: "exit", ExitCode: -1}
+	var exitErr exec.ExitError
+	switch {
+	case err == nil:
+		exit.ExitCode = 0
+	case errors.As(err, &exitErr) && exitErr.Exited():
+		exit.ExitCode = exitErr.ExitStatus()
+	case s.idle.Load():
+		exit.Error = "session idle"
+	case s.ctx.Err() != nil:
+		exit.Error = "client disconnected"
+	default:
+		exit.Error = err.Error()
+	}
+	return exit
+}
+
+// readLoop forwards stdin and resize frames until the connection ends
+func (s *session) readLoop(stdin *io.PipeWriter, pingInterval time.Duration) {
+	defer stdin.Close()
+
+	// Clients must answer pings; a missing pong means the connection is dead
+	s.conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+	})
+
+	for {
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType != websocket.BinaryMessage || len(data) == 0 {
+			continue
+		}
+		s.touch()
+		switch data[0] {
+		case ChannelStdin:
+			if _, err := stdin.Write(data[1:]); err != nil {
+				return
+			}
+		case ChannelResize:
+			var size remotecommand.TerminalSize
+			if json.Unmarshal(data[1:], &size) != nil || size.Width == 0 || size.Height == 0 {
+				continue
+			}
+			// Only the latest size matters
+			select {
+			case <-s.sizes:
+			default:
+			}
+			s.sizes <- size
+		}
+	}
+}
+
+// keepAlive pings the client and ends idle sessions
+func (s *session) keepAlive(cancel context.CancelFunc, opts Options) {
+	ticker := time.NewTicker(opts.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if opts.IdleTimeout > 0 && time.Since(time.Unix(0, s.activity.Load())) >= opts.IdleTimeout {
+			s.idle.Store(true)
+			cancel()
+			return
+		}
+		if s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)) != nil {
+			cancel()
+			return
+		}
+	}
+}
+
+// Next returns the latest terminal size sent by the client, nil once the session ends
+func (s *session) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-s.sizes:
+		return &size
+	case <-s.ctx.Done():
+		return nil
+	}
+}
+
+func (s *session) touch() {
+	s.activity.Store(time.Now().UnixNano())
+}
+
+// frameWriter writes output of the command as binary frames of one channel
+type frameWriter struct {
+	s       *session
+	channel byte
+}
+
+func (w frameWriter) Write(p []byte) (int, error) {
+	w.s.touch()
+	frame := make([]byte, 0, len(p)+1)
+	frame = append(append(frame, w.channel), p...)
+
+	w.s.writeMu.Lock()
+	defer w.s.writeMu.Unlock()
+	w.s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := w.s.conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
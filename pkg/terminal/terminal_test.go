@@ -0,0 +1,111 @@
+package terminal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// fakeExecutor echoes a line of stdin to stdout in upper case and records terminal sizes
+type fakeExecutor struct {
+	sizes chan remotecommand.TerminalSize
+	err   error
+	block bool
+}
+
+func (f *fakeExecutor) Stream(opts remotecommand.StreamOptions) error {
+	return f.StreamWithContext(context.Background(), opts)
+}
+
+func (f *fakeExecutor) StreamWithContext(ctx context.Context, opts remotecommand.StreamOptions) error {
+	if opts.TerminalSizeQueue != nil {
+		go func() {
+			for size := opts.TerminalSizeQueue.Next(); size != nil; size = opts.TerminalSizeQueue.Next() {
+				f.sizes <- *size
+			}
+		}()
+	}
+	if opts.Stderr != nil {
+		opts.Stderr.Write([]byte("warning\n"))
+	}
+	if f.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if opts.Stdin != nil {
+		line, _ := bufio.NewReader(opts.Stdin).ReadString('\n')
+		opts.Stdout.Write([]byte(strings.ToUpper(line)))
+	}
+	return f.err
+}
+
+// serve starts a WebSocket server bridging to the executor and dials it
+func serve(t *testing.T, executor remotecommand.Executor, opts Options) (*websocket.Conn, <-chan Exit) {
+	t.Helper()
+	exits := make(chan Exit, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		exits <- Serve(context.Background(), conn, executor, opts)
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, exits
+}
+
+func TestServe_BridgesStreams(t *testing.T) {
+	executor := &fakeExecutor{sizes: make(chan remotecommand.TerminalSize, 1), err: exec.CodeExitError{Err: errors.New("exit 3"), Code: 3}}
+	conn, exits := serve(t, executor, Options{Stdin: true, TTY: true})
+
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, append([]byte{ChannelResize}, `{"Width":120,"Height":40}`...)))
+	assert.Equal(t, remotecommand.TerminalSize{Width: 120, Height: 40}, <-executor.sizes)
+
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, append([]byte{ChannelStdin}, "l"...)))
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, append([]byte{ChannelStdin}, "s\n"...)))
+
+	messageType, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, messageType)
+	assert.Equal(t, append([]byte{ChannelStdout}, "LS\n"...), data)
+
+	var exit Exit
+	require.NoError(t, conn.ReadJSON(&exit))
+	assert.Equal(t, Exit{Type: "exit", ExitCode: 3}, exit)
+	assert.Equal(t, exit, <-exits)
+}
+
+func TestServe_StderrWithoutTTY(t *testing.T) {
+	conn, _ := serve(t, &fakeExecutor{}, Options{})
+
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte{ChannelStderr}, "warning\n"...), data)
+
+	var exit Exit
+	require.NoError(t, conn.ReadJSON(&exit))
+	assert.Equal(t, 0, exit.ExitCode)
+}
+
+func TestServe_IdleTimeout(t *testing.T) {
+	conn, exits := serve(t, &fakeExecutor{block: true}, Options{Stdin: true, TTY: true, PingInterval: 20 * time.Millisecond, IdleTimeout: 50 * time.Millisecond})
+
+	var exit Exit
+	require.NoError(t, conn.ReadJSON(&exit))
+	assert.Equal(t, Exit{Type: "exit", ExitCode: -1, Error: "session idle"}, exit)
+	<-exits
+}
@@ -0,0 +1,247 @@
+// Package timeline merges the history of a workload from several sources into one
+// chronological list: changes observed by the informer, ReplicaSet revisions,
+// Kubernetes events and audited API calls
+package timeline
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/audit"
+)
+
+// Sources of timeline entries
+const (
+	SourceObject    = "object"    // The workload's own metadata
+	SourceWatch     = "watch"     // Changes observed by the informer while the server ran
+	SourceRevisions = "revisions" // ReplicaSet revision history
+	SourceEvents    = "events"    // Kubernetes events
+	SourceAudit     = "audit"     // Audited API calls
+)
+
+// Types of timeline entries
+const (
+	TypeCreated      = "created"
+	TypeScaled       = "scaled"
+	TypeImageChanged = "image_changed"
+	TypeRestarted    = "restarted"
+	TypeSpecChanged  = "spec_changed"
+	TypeDeleted      = "deleted"
+	TypeRevision     = "revision"
+	TypeAlert        = "alert" // Warning events
+	TypeEvent        = "event"
+	TypeAPICall      = "api_call"
+)
+
+// Annotations read from deployments and ReplicaSets
+const (
+	revisionAnnotation    = "deployment.kubernetes.io/revision"
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt" // Set on the pod template by kubectl rollout restart
+)
+
+// Entry is one point on the timeline
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Type    string            `json:"type"`
+	Source  string            `json:"source"`
+	Summary string            `json:"summary"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Change is a version of a deployment observed at a point in time
+type Change struct {
+	Time       time.Time
+	Deleted    bool
+	Deployment *appsv1.Deployment
+}
+
+// Created returns the entry for a deployment's creation
+func Created(d *appsv1.Deployment) Entry {
+	return Entry{
+		Time:    d.CreationTimestamp.Time,
+		Type:    TypeCreated,
+		Source:  SourceObject,
+		Summary: fmt.Sprintf("Deployment created with %d replicas", replicas(d)),
+		Details: map[string]string{"images": strings.Join(images(&d.Spec.Template.Spec), ", ")},
+	}
+}
+
+// DeploymentChanges compares consecutive observed versions of a deployment and reports
+// scalings, image changes, restarts, other template changes and deletion. The first
+// version is the baseline and yields no entry.
+func DeploymentChanges(changes []Change) []Entry {
+	var entries []Entry
+	var prev *appsv1.Deployment
+	for _, c := range changes {
+		if c.Deleted {
+			entries = append(entries, Entry{Time: c.Time, Type: TypeDeleted, Source: SourceWatch, Summary: "Deployment deleted"})
+			prev = nil
+			continue
+		}
+		cur := c.Deployment
+		if prev != nil {
+			entries = append(entries, compare(c.Time, prev, cur)...)
+		}
+		prev = cur
+	}
+	return entries
+}
+
+func compare(at time.Time, prev, cur *appsv1.Deployment) []Entry {
+	var entries []Entry
+	if from, to := replicas(prev), replicas(cur); from != to {
+		entries = append(entries, Entry{
+			Time: at, Type: TypeScaled, Source: SourceWatch,
+			Summary: fmt.Sprintf("Scaled from %d to %d replicas", from, to),
+			Details: map[string]string{"from": strconv.Itoa(int(from)), "to": strconv.Itoa(int(to))},
+		})
+	}
+
+	templateChanged := false
+	before := containerImages(&prev.Spec.Template.Spec)
+	for _, c := range cur.Spec.Template.Spec.Containers {
+		if old, ok := before[c.Name]; ok && old != c.Image {
+			templateChanged = true
+			entries = append(entries, Entry{
+				Time: at, Type: TypeImageChanged, Source: SourceWatch,
+				Summary: fmt.Sprintf("Container %s image changed from %s to %s", c.Name, old, c.Image),
+				Details: map[string]string{"container": c.Name, "from": old, "to": c.Image},
+			})
+		}
+	}
+
+	if restartedAt := cur.Spec.Template.Annotations[restartedAtAnnotation]; restartedAt != prev.Spec.Template.Annotations[restartedAtAnnotation] && restartedAt != "" {
+		templateChanged = true
+		entries = append(entries, Entry{
+			Time: at, Type: TypeRestarted, Source: SourceWatch,
+			Summary: "Rollout restart requested",
+			Details: map[string]string{"restarted_at": restartedAt},
+		})
+	}
+
+	// Other pod template edits, such as env or resources, still start a rollout
+	if !templateChanged && cur.Generation > prev.Generation && replicas(prev) == replicas(cur) {
+		entries = append(entries, Entry{
+			Time: at, Type: TypeSpecChanged, Source: SourceWatch,
+			Summary: fmt.Sprintf("Spec changed (generation %d to %d)", prev.Generation, cur.Generation),
+			Details: map[string]string{"from": strconv.FormatInt(prev.Generation, 10), "to": strconv.FormatInt(cur.Generation, 10)},
+		})
+	}
+	return entries
+}
+
+// Revisions reports one entry per ReplicaSet revision of a deployment
+func Revisions(replicaSets []appsv1.ReplicaSet) []Entry {
+	entries := make([]Entry, 0, len(replicaSets))
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		revision := rs.Annotations[revisionAnnotation]
+		if revision == "" {
+			continue
+		}
+		details := map[string]string{
+			"revision":   revision,
+			"replicaset": rs.Name,
+			"images":     strings.Join(images(&rs.Spec.Template.Spec), ", "),
+		}
+		summary := fmt.Sprintf("Revision %s rolled out (%s)", revision, details["images"])
+		if cause := rs.Annotations[changeCauseAnnotation]; cause != "" {
+			details["change_cause"] = cause
+			summary += ": " + cause
+		}
+		entries = append(entries, Entry{Time: rs.CreationTimestamp.Time, Type: TypeRevision, Source: SourceRevisions, Summary: summary, Details: details})
+	}
+	return entries
+}
+
+// Events reports Kubernetes events; warnings become alerts
+func Events(events []corev1.Event) []Entry {
+	entries := make([]Entry, 0, len(events))
+	for i := range events {
+		e := &events[i]
+		entryType := TypeEvent
+		if e.Type == corev1.EventTypeWarning {
+			entryType = TypeAlert
+		}
+		details := map[string]string{
+			"reason": e.Reason,
+			"object": e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name,
+		}
+		if e.Count > 1 {
+			details["count"] = strconv.Itoa(int(e.Count))
+		}
+		entries = append(entries, Entry{
+			Time:    eventTime(e),
+			Type:    entryType,
+			Source:  SourceEvents,
+			Summary: fmt.Sprintf("%s: %s", e.Reason, e.Message),
+			Details: details,
+		})
+	}
+	return entries
+}
+
+// AuditEntries reports audited API calls
+func AuditEntries(entries []audit.Entry) []Entry {
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		details := map[string]string{"request_id": e.RequestID, "result": e.Result}
+		if e.Principal != "" {
+			details["principal"] = e.Principal
+		}
+		summary := fmt.Sprintf("%s %s returned %d", e.Method, e.Path, e.Status)
+		if e.Principal != "" {
+			summary += " for " + e.Principal
+		}
+		out = append(out, Entry{Time: e.Time, Type: TypeAPICall, Source: SourceAudit, Summary: summary, Details: details})
+	}
+	return out
+}
+
+// Sort orders entries chronologically, keeping the order of entries at the same time
+func Sort(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+}
+
+func replicas(d *appsv1.Deployment) int32 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+func images(spec *corev1.PodSpec) []string {
+	images := make([]string, 0, len(spec.Containers))
+	for _, c := range spec.Containers {
+		images = append(images, c.Image)
+	}
+	return images
+}
+
+func containerImages(spec *corev1.PodSpec) map[string]string {
+	images := make(map[string]string, len(spec.Containers))
+	for _, c := range spec.Containers {
+		images[c.Name] = c.Image
+	}
+	return images
+}
+
+// eventTime returns when an event last occurred, whichever timestamp field is set
+func eventTime(e *corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case e.Series != nil && !e.Series.LastObservedTime.IsZero():
+		return e.Series.LastObservedTime.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	}
+	return e.CreationTimestamp.Time
+}
@@ -0,0 +1,97 @@
+package timeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/audit"
+)
+
+var t0 = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func deployment(generation int64, replicas int32, image, restartedAt string) *appsv1.Deployment {
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", Generation: generation, CreationTimestamp: metav1.NewTime(t0)},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+			},
+		},
+	}
+	if restartedAt != "" {
+		d.Spec.Template.Annotations = map[string]string{restartedAtAnnotation: restartedAt}
+	}
+	return d
+}
+
+func TestDeploymentChanges(t *testing.T) {
+	entries := DeploymentChanges([]Change{
+		{Time: t0, Deployment: deployment(1, 2, "web:1", "")},
+		{Time: t0.Add(time.Minute), Deployment: deployment(2, 5, "web:1", "")},
+		{Time: t0.Add(2 * time.Minute), Deployment: deployment(3, 5, "web:2", "")},
+		{Time: t0.Add(3 * time.Minute), Deployment: deployment(4, 5, "web:2", "2025-01-01T12:03:00Z")},
+		{Time: t0.Add(4 * time.Minute), Deployment: deployment(5, 5, "web:2", "2025-01-01T12:03:00Z")},
+		{Time: t0.Add(5 * time.Minute), Deployment: deployment(5, 5, "web:2", "2025-01-01T12:03:00Z")}, // Status-only update
+		{Time: t0.Add(6 * time.Minute), Deleted: true},
+	})
+
+	types := make([]string, 0, len(entries))
+	for _, e := range entries {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []string{TypeScaled, TypeImageChanged, TypeRestarted, TypeSpecChanged, TypeDeleted}, types)
+	assert.Equal(t, "Scaled from 2 to 5 replicas", entries[0].Summary)
+	assert.Equal(t, map[string]string{"container": "app", "from": "web:1", "to": "web:2"}, entries[1].Details)
+	assert.Equal(t, t0.Add(6*time.Minute), entries[4].Time)
+}
+
+func TestRevisions(t *testing.T) {
+	entries := Revisions([]appsv1.ReplicaSet{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-abc", CreationTimestamp: metav1.NewTime(t0), Annotations: map[string]string{
+				revisionAnnotation: "3", changeCauseAnnotation: "bump to web:3",
+			}},
+			Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "web:3"}}}}},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "unrelated"}},
+	})
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Revision 3 rolled out (web:3): bump to web:3", entries[0].Summary)
+	assert.Equal(t, "web-abc", entries[0].Details["replicaset"])
+}
+
+func TestEventsAndAudit(t *testing.T) {
+	events := Events([]corev1.Event{
+		{Type: corev1.EventTypeWarning, Reason: "BackOff", Message: "Back-off restarting failed container", Count: 4,
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "web-abc-1"}, LastTimestamp: metav1.NewTime(t0)},
+		{Type: corev1.EventTypeNormal, Reason: "ScalingReplicaSet", Message: "Scaled up"},
+	})
+	require.Len(t, events, 2)
+	assert.Equal(t, TypeAlert, events[0].Type)
+	assert.Equal(t, "4", events[0].Details["count"])
+	assert.Equal(t, TypeEvent, events[1].Type)
+
+	calls := AuditEntries([]audit.Entry{{Time: t0, Method: "PATCH", Path: "/deployments/shop/web/scale", Status: 200, Principal: "ci", Result: "success"}})
+	require.Len(t, calls, 1)
+	assert.Equal(t, "PATCH /deployments/shop/web/scale returned 200 for ci", calls[0].Summary)
+}
+
+func TestSort(t *testing.T) {
+	entries := []Entry{
+		{Time: t0.Add(time.Minute), Summary: "b"},
+		{Time: t0, Summary: "a"},
+		{Time: t0.Add(time.Minute), Summary: "c"},
+	}
+	Sort(entries)
+	assert.Equal(t, "a", entries[0].Summary)
+	assert.Equal(t, "b", entries[1].Summary)
+	assert.Equal(t, "c", entries[2].Summary)
+}
@@ -0,0 +1,90 @@
+// Package timeout selects how long the API server may spend on a request, by route
+package timeout
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds requests no route rule matches
+const DefaultTimeout = 60 * time.Second
+
+// Route sets the timeout of the requests to a path and set of methods
+type Route struct {
+	Path    string        `mapstructure:"path"`    // Exact path or prefix; * matches one segment, e.g. /pods/*/*/logs
+	Methods []string      `mapstructure:"methods"` // Empty matches all methods
+	Timeout time.Duration `mapstructure:"timeout"` // 0 disables the timeout for the route
+}
+
+// Config holds request timeout settings
+type Config struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Default time.Duration `mapstructure:"default"` // Timeout of requests matching no route, 0 for none
+	Routes  []Route       `mapstructure:"routes"`  // Evaluated in order, the first match wins
+}
+
+// Validate checks the configured durations and paths
+func (c Config) Validate() error {
+	if c.Default < 0 {
+		return fmt.Errorf("default must not be negative, got %s", c.Default)
+	}
+	for i, r := range c.Routes {
+		if r.Path == "" || !strings.HasPrefix(r.Path, "/") {
+			return fmt.Errorf("routes[%d]: path %q must start with /", i, r.Path)
+		}
+		if r.Timeout < 0 {
+			return fmt.Errorf("routes[%d]: timeout must not be negative, got %s", i, r.Timeout)
+		}
+	}
+	return nil
+}
+
+// ErrTimeout is the cause of a request context canceled by its route's timeout
+var ErrTimeout = errors.New("request timed out")
+
+// For returns the timeout of a request, 0 when it is not bounded
+func (c Config) For(method, path string) time.Duration {
+	if !c.Enabled {
+		return 0
+	}
+	for _, r := range c.Routes {
+		if !matchPath(r.Path, path) {
+			continue
+		}
+		if len(r.Methods) > 0 && !containsFold(r.Methods, method) {
+			continue
+		}
+		return r.Timeout
+	}
+	return c.Default
+}
+
+// matchPath reports whether path equals pattern or lies below it, where a * segment of
+// pattern matches any one non-empty segment
+func matchPath(pattern, path string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return true
+	}
+	patternSegments, pathSegments := strings.Split(pattern, "/"), strings.Split(path, "/")
+	if len(pathSegments) < len(patternSegments) {
+		return false
+	}
+	for i, segment := range patternSegments {
+		if segment != pathSegments[i] && (segment != "*" || pathSegments[i] == "") {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
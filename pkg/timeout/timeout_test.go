@@ -0,0 +1,38 @@
+package timeout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_For(t *testing.T) {
+	c := Config{
+		Enabled: true,
+		Default: 30 * time.Second,
+		Routes: []Route{
+			{Path: "/pods/*/*/logs", Timeout: 5 * time.Minute},
+			{Path: "/clusters", Methods: []string{"post"}, Timeout: 2 * time.Minute},
+			{Path: "/firehose", Timeout: 0},
+		},
+	}
+
+	assert.Equal(t, 5*time.Minute, c.For("GET", "/pods/shop/web/logs"))
+	assert.Equal(t, 30*time.Second, c.For("GET", "/pods/shop/web"))
+	assert.Equal(t, 30*time.Second, c.For("GET", "/pods//web/logs"), "* needs a segment")
+	assert.Equal(t, 2*time.Minute, c.For("POST", "/clusters"))
+	assert.Equal(t, 30*time.Second, c.For("GET", "/clusters"))
+	assert.Equal(t, 30*time.Second, c.For("GET", "/clustersx"))
+	assert.Equal(t, time.Duration(0), c.For("GET", "/firehose"), "a zero timeout disables it for the route")
+
+	c.Enabled = false
+	assert.Equal(t, time.Duration(0), c.For("GET", "/pods/shop/web/logs"))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{Default: time.Second, Routes: []Route{{Path: "/pods", Timeout: time.Second}}}.Validate())
+	assert.Error(t, Config{Default: -time.Second}.Validate())
+	assert.Error(t, Config{Routes: []Route{{Path: "pods", Timeout: time.Second}}}.Validate())
+	assert.Error(t, Config{Routes: []Route{{Path: "/pods", Timeout: -time.Second}}}.Validate())
+}
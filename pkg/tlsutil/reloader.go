@@ -0,0 +1,187 @@
+// Package tlsutil provides TLS certificate loading with automatic reload
+package tlsutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CertSource loads a certificate together with a version string that
+// changes whenever the underlying certificate material changes
+type CertSource interface {
+	Load(ctx context.Context) (*tls.Certificate, string, error)
+	String() string
+}
+
+// FileSource loads a PEM certificate and key from disk
+type FileSource struct {
+	CertFile string
+	KeyFile  string
+}
+
+// Load reads both files and uses their content digest as the version
+func (f FileSource) Load(ctx context.Context) (*tls.Certificate, string, error) {
+	certPEM, err := os.ReadFile(f.CertFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	keyPEM, err := os.ReadFile(f.KeyFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse key pair: %w", err)
+	}
+
+	digest := sha256.New()
+	digest.Write(certPEM)
+	digest.Write(keyPEM)
+	return &cert, hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+func (f FileSource) String() string {
+	return "file:" + f.CertFile
+}
+
+// SecretSource loads a kubernetes.io/tls Secret
+type SecretSource struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+}
+
+// Load fetches the Secret and uses its resourceVersion as the version
+func (s SecretSource) Load(ctx context.Context) (*tls.Certificate, string, error) {
+	secret, err := s.Client.CoreV1().Secrets(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get secret %s/%s: %w", s.Namespace, s.Name, err)
+	}
+
+	cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse key pair from secret %s/%s: %w", s.Namespace, s.Name, err)
+	}
+
+	return &cert, secret.ResourceVersion, nil
+}
+
+func (s SecretSource) String() string {
+	return "secret:" + s.Namespace + "/" + s.Name
+}
+
+// Reloader serves the current certificate and periodically reloads it from its source
+type Reloader struct {
+	source   CertSource
+	interval time.Duration
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	version string
+}
+
+// NewReloader loads the initial certificate; it fails if the source is unusable
+func NewReloader(ctx context.Context, source CertSource, interval time.Duration) (*Reloader, error) {
+	r := &Reloader{source: source, interval: interval}
+	cert, version, err := source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.cert = cert
+	r.version = version
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Version returns the version of the certificate currently served
+func (r *Reloader) Version() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
+// Reload loads the certificate once and swaps it in if it changed.
+// It returns true when a new certificate was installed.
+func (r *Reloader) Reload(ctx context.Context) (bool, error) {
+	cert, version, err := r.source.Load(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if version == r.version {
+		return false, nil
+	}
+	r.cert = cert
+	r.version = version
+	return true, nil
+}
+
+// Start polls the source until the context is canceled. A failed reload keeps
+// serving the previous certificate.
+func (r *Reloader) Start(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.Reload(ctx)
+			if err != nil {
+				log.Warn().Err(err).Str("source", r.source.String()).Msg("Failed to reload TLS certificate, keeping current one")
+				continue
+			}
+			if changed {
+				log.Info().Str("source", r.source.String()).Msg("TLS certificate reloaded")
+			}
+		}
+	}
+}
+
+// ServerConfig returns a TLS configuration that serves the reloader's certificate
+func (r *Reloader) ServerConfig(minVersion uint16) *tls.Config {
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	return &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// ParseMinVersion converts "1.2" or "1.3" into a tls version constant
+func ParseMinVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS min_version %q (use 1.2 or 1.3)", v)
+	}
+}
@@ -0,0 +1,129 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// generateCert returns a self-signed PEM certificate and key for the common name
+func generateCert(t *testing.T, commonName string) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return parsed.Subject.CommonName
+}
+
+func TestReloader_FileSource(t *testing.T) {
+	dir := t.TempDir()
+	source := FileSource{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+
+	certPEM, keyPEM := generateCert(t, "first")
+	require.NoError(t, os.WriteFile(source.CertFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(source.KeyFile, keyPEM, 0600))
+
+	ctx := context.Background()
+	r, err := NewReloader(ctx, source, time.Minute)
+	require.NoError(t, err)
+
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "first", commonName(t, cert))
+
+	// Unchanged files do not trigger a reload
+	changed, err := r.Reload(ctx)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	certPEM, keyPEM = generateCert(t, "second")
+	require.NoError(t, os.WriteFile(source.CertFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(source.KeyFile, keyPEM, 0600))
+
+	changed, err = r.Reload(ctx)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	cert, _ = r.GetCertificate(nil)
+	assert.Equal(t, "second", commonName(t, cert))
+
+	// A broken key keeps the previous certificate
+	require.NoError(t, os.WriteFile(source.KeyFile, []byte("garbage"), 0600))
+	_, err = r.Reload(ctx)
+	assert.Error(t, err)
+	cert, _ = r.GetCertificate(nil)
+	assert.Equal(t, "second", commonName(t, cert))
+}
+
+func TestReloader_SecretSource(t *testing.T) {
+	certPEM, keyPEM := generateCert(t, "from-secret")
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-tls", Namespace: "kcc", ResourceVersion: "1"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		},
+	})
+
+	r, err := NewReloader(context.Background(), SecretSource{Client: client, Namespace: "kcc", Name: "api-tls"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "1", r.Version())
+
+	cert, _ := r.GetCertificate(nil)
+	assert.Equal(t, "from-secret", commonName(t, cert))
+}
+
+func TestNewReloader_MissingFiles(t *testing.T) {
+	_, err := NewReloader(context.Background(), FileSource{CertFile: "/missing.crt", KeyFile: "/missing.key"}, 0)
+	assert.Error(t, err)
+}
+
+func TestParseMinVersion(t *testing.T) {
+	v, err := ParseMinVersion("")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), v)
+
+	v, err = ParseMinVersion("1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), v)
+
+	_, err = ParseMinVersion("1.0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+// Package tracing sets up the OpenTelemetry SDK to export spans of API requests, Kubernetes
+// API calls and reconciles over OTLP/HTTP. Trace context is propagated in the W3C
+// traceparent header, so a request fanning out to several clusters is one trace.
+package tracing
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans this module records
+const ScopeName = "github.com/obezsmertnyi/k8s-custom-controller"
+
+// Default settings applied when the configuration leaves them unset
+const (
+	DefaultServiceName    = "k8s-custom-controller"
+	DefaultExportInterval = 5 * time.Second
+	DefaultTimeout        = 10 * time.Second
+)
+
+// Config holds tracing settings
+type Config struct {
+	Enabled        bool              `mapstructure:"enabled"`
+	Endpoint       string            `mapstructure:"endpoint"`        // OTLP/HTTP collector, e.g. http://otel-collector:4318
+	Headers        map[string]string `mapstructure:"headers"`         // Sent with every export, e.g. authorization
+	ServiceName    string            `mapstructure:"service_name"`    // service.name resource attribute
+	SampleRatio    float64           `mapstructure:"sample_ratio"`    // Share of new traces recorded; incoming sampled flags are honored
+	ExportInterval time.Duration     `mapstructure:"export_interval"` // Time between exports of queued spans
+	Timeout        time.Duration     `mapstructure:"timeout"`         // Bound on a single export
+}
+
+// Validate checks the settings of an enabled configuration
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Endpoint == "" {
+		return errors.New("endpoint is required when tracing is enabled")
+	}
+	if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
+		return fmt.Errorf("endpoint %q must be an http or https URL", c.Endpoint)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("sample_ratio must be between 0 and 1, got %v", c.SampleRatio)
+	}
+	return nil
+}
+
+// NewProvider creates a tracer provider batching spans to the collector's /v1/traces. New
+// traces are sampled by sample_ratio and children follow their parent's decision. Call
+// Shutdown to export the spans still queued.
+func NewProvider(cfg Config) (*sdktrace.TracerProvider, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = DefaultServiceName
+	}
+	if cfg.ExportInterval <= 0 {
+		cfg.ExportInterval = DefaultExportInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(cfg.Endpoint, "/")+"/v1/traces"),
+		otlptracehttp.WithHeaders(cfg.Headers),
+		otlptracehttp.WithTimeout(cfg.Timeout))
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(cfg.ExportInterval), sdktrace.WithExportTimeout(cfg.Timeout)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithIDGenerator(idGenerator{}),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+	), nil
+}
+
+// SetDefault makes the provider the process-wide one, propagating W3C trace context
+func SetDefault(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+// Tracer returns the tracer of the process-wide provider, which records nothing until
+// SetDefault is called
+func Tracer() trace.Tracer {
+	return otel.Tracer(ScopeName)
+}
+
+// TraceIDFromUUID derives a trace ID from a UUID such as an X-Request-ID, so that the
+// request's logs and audit entry lead straight to its trace
+func TraceIDFromUUID(id string) (trace.TraceID, bool) {
+	var t trace.TraceID
+	b, err := hex.DecodeString(strings.ReplaceAll(id, "-", ""))
+	if err != nil || len(b) != len(t) {
+		return t, false
+	}
+	copy(t[:], b)
+	return t, t.IsValid()
+}
+
+type traceIDKey struct{}
+
+// ContextWithTraceID returns a copy of ctx in which a new root span takes the trace ID
+func ContextWithTraceID(ctx context.Context, id trace.TraceID) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// idGenerator issues random IDs, taking the trace ID of a root span from its context when
+// ContextWithTraceID set one
+type idGenerator struct{}
+
+// NewIDs implements sdktrace.IDGenerator
+func (g idGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	id, ok := ctx.Value(traceIDKey{}).(trace.TraceID)
+	if !ok || !id.IsValid() {
+		crand.Read(id[:])
+	}
+	return id, g.NewSpanID(ctx, id)
+}
+
+// NewSpanID implements sdktrace.IDGenerator
+func (idGenerator) NewSpanID(context.Context, trace.TraceID) trace.SpanID {
+	var id trace.SpanID
+	for !id.IsValid() {
+		crand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,183 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// collector is a fake OTLP/HTTP endpoint recording the exports it receives
+type collector struct {
+	mu      sync.Mutex
+	paths   []string
+	headers http.Header
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paths = append(c.paths, r.URL.Path)
+	c.headers = r.Header.Clone()
+}
+
+func newTestProvider(t *testing.T, ratio float64) (*sdktrace.TracerProvider, *collector) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	provider, err := NewProvider(Config{Enabled: true, Endpoint: srv.URL + "/", SampleRatio: ratio, Headers: map[string]string{"Authorization": "Bearer t"}})
+	require.NoError(t, err)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return provider, c
+}
+
+// setRecorder makes a provider recording spans in memory the process-wide one for a test
+func setRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	SetDefault(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder), sdktrace.WithIDGenerator(idGenerator{})))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+	return recorder
+}
+
+func TestTraceIDFromUUID(t *testing.T) {
+	id, ok := TraceIDFromUUID("4c2b1ee6-5202-468b-a67b-1bbe21feae45")
+	require.True(t, ok)
+	assert.Equal(t, "4c2b1ee65202468ba67b1bbe21feae45", id.String())
+
+	_, ok = TraceIDFromUUID("not-a-uuid")
+	assert.False(t, ok)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.Error(t, Config{Enabled: true}.Validate())
+	assert.Error(t, Config{Enabled: true, Endpoint: "otel:4318"}.Validate())
+	assert.Error(t, Config{Enabled: true, Endpoint: "http://otel:4318", SampleRatio: 1.5}.Validate())
+	assert.NoError(t, Config{Enabled: true, Endpoint: "http://otel:4318", SampleRatio: 0.1}.Validate())
+
+	_, err := NewProvider(Config{Enabled: true})
+	assert.Error(t, err)
+}
+
+func TestNewProvider_Exports(t *testing.T) {
+	provider, c := newTestProvider(t, 1)
+
+	_, span := provider.Tracer(ScopeName).Start(context.Background(), "GET /pods")
+	span.End()
+	require.NoError(t, provider.ForceFlush(context.Background()))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Equal(t, []string{"/v1/traces"}, c.paths)
+	assert.Equal(t, "Bearer t", c.headers.Get("Authorization"))
+	assert.Equal(t, "application/x-protobuf", c.headers.Get("Content-Type"))
+}
+
+func TestNewProvider_Sampling(t *testing.T) {
+	provider, _ := newTestProvider(t, 0)
+	tracer := provider.Tracer(ScopeName)
+
+	// New traces are not recorded at ratio 0
+	_, span := tracer.Start(context.Background(), "root")
+	assert.False(t, span.SpanContext().IsSampled())
+
+	// An incoming sampled flag wins over the ratio
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	_, span = tracer.Start(ctx, "sampled")
+	assert.True(t, span.SpanContext().IsSampled())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+}
+
+func TestContextWithTraceID(t *testing.T) {
+	recorder := setRecorder(t)
+	id, _ := TraceIDFromUUID("4c2b1ee6-5202-468b-a67b-1bbe21feae45")
+
+	ctx, root := Tracer().Start(ContextWithTraceID(context.Background(), id), "root")
+	_, child := Tracer().Start(ctx, "child")
+	child.End()
+	root.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, id, spans[1].SpanContext().TraceID())
+	assert.Equal(t, id, spans[0].SpanContext().TraceID())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+
+	// A propagated parent keeps its trace
+	ctx = propagation.TraceContext{}.Extract(ContextWithTraceID(context.Background(), id), propagation.MapCarrier{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	_, remote := Tracer().Start(ctx, "remote")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", remote.SpanContext().TraceID().String())
+}
+
+func TestWrapTransport(t *testing.T) {
+	recorder := setRecorder(t)
+
+	var traceparent string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer api.Close()
+	client := &http.Client{Transport: WrapTransport("east")(http.DefaultTransport)}
+
+	// Calls without a trace are passed through
+	resp, err := client.Get(api.URL + "/api/v1/namespaces")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, traceparent)
+	assert.Empty(t, recorder.Ended())
+
+	ctx, parent := Tracer().Start(context.Background(), "GET /pods/{namespace}/{name}", trace.WithSpanKind(trace.SpanKindServer))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, api.URL+"/api/v1/namespaces/prod/pods/web/log", nil)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	parent.End()
+	assert.Empty(t, req.Header.Get("traceparent"), "the caller's request is not modified")
+
+	var span sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "kubernetes GET v1 pods/log" {
+			span = s
+		}
+	}
+	require.NotNil(t, span)
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Contains(t, span.Attributes(), attribute.String("k8s.cluster.id", "east"))
+	assert.Equal(t, "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01", traceparent)
+}
+
+func TestAPIResource(t *testing.T) {
+	for path, want := range map[string]string{
+		"/api/v1/namespaces":                                  "v1 namespaces",
+		"/api/v1/namespaces/prod":                             "v1 namespaces",
+		"/api/v1/nodes/worker-1":                              "v1 nodes",
+		"/apis/apps/v1/namespaces/prod/deployments":           "apps/v1 deployments",
+		"/apis/apps/v1/namespaces/prod/deployments/web/scale": "apps/v1 deployments/scale",
+		"/version": "/version",
+	} {
+		assert.Equal(t, want, apiResource(path), path)
+	}
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WrapTransport returns a rest.Config WrapTransport function recording a client span for
+// every Kubernetes API call made with a traced context, and injecting traceparent so API
+// servers with tracing enabled continue the trace. Calls without a span in their context,
+// such as informer list-watches, are passed through untouched.
+func WrapTransport(clusterID string) func(http.RoundTripper) http.RoundTripper {
+	opts := []otelhttp.Option{
+		otelhttp.WithFilter(func(req *http.Request) bool {
+			return trace.SpanContextFromContext(req.Context()).IsValid()
+		}),
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return "kubernetes " + req.Method + " " + apiResource(req.URL.Path)
+		}),
+	}
+	if clusterID != "" {
+		opts = append(opts, otelhttp.WithSpanOptions(trace.WithAttributes(attribute.String("k8s.cluster.id", clusterID))))
+	}
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return otelhttp.NewTransport(rt, opts...)
+	}
+}
+
+// apiResource names the kind of resource a Kubernetes API path addresses, without
+// namespaces and object names, e.g. apps/v1 deployments for
+// /apis/apps/v1/namespaces/prod/deployments/web
+func apiResource(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	var group []string
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		group, parts = parts[1:2], parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		group, parts = parts[1:3], parts[3:]
+	default:
+		return path
+	}
+	if len(parts) >= 2 && parts[0] == "namespaces" && len(parts) != 2 {
+		parts = parts[2:]
+	}
+	name := strings.Join(group, "/")
+	if len(parts) > 0 {
+		name += " " + parts[0]
+		if len(parts) == 3 {
+			name += "/" + parts[2] // Subresource, e.g. deployments/scale
+		}
+	}
+	return name
+}
@@ -0,0 +1,128 @@
+// Package tunnel opens connections to ports of pods through the Kubernetes portforward
+// subresource, without listening on local ports
+package tunnel
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// remoteErrorWait bounds how long a finished connection waits for the error the kubelet reports
+const remoteErrorWait = 5 * time.Second
+
+// Tunnel is a port-forward session to one pod. Every Open is a new connection to a port of it.
+type Tunnel struct {
+	conn      httpstream.Connection
+	requestID atomic.Int64
+}
+
+// Dial upgrades the portforward subresource URL of a pod to a multiplexed SPDY connection
+func Dial(config *rest.Config, portForwardURL *url.URL) (*Tunnel, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, err
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, portForwardURL)
+	conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upgrade port-forward connection: %w", err)
+	}
+	return &Tunnel{conn: conn}, nil
+}
+
+// Open connects to a port of the pod
+func (t *Tunnel) Open(port int32) (*Conn, error) {
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(int(port)))
+	headers.Set(corev1.PortForwardRequestIDHeader, strconv.FormatInt(t.requestID.Add(1), 10))
+	errorStream, err := t.conn.CreateStream(headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create error stream for port %d: %w", port, err)
+	}
+	// Nothing is written to the error stream
+	errorStream.Close()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := t.conn.CreateStream(headers)
+	if err != nil {
+		t.conn.RemoveStreams(errorStream)
+		return nil, fmt.Errorf("failed to create data stream for port %d: %w", port, err)
+	}
+
+	c := &Conn{tunnel: t, port: port, data: dataStream, errors: errorStream, remoteErr: make(chan error, 1)}
+	go func() {
+		message, err := io.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			c.remoteErr <- fmt.Errorf("failed to read error stream of port %d: %w", port, err)
+		case len(message) > 0:
+			c.remoteErr <- fmt.Errorf("port %d: %s", port, message)
+		}
+		close(c.remoteErr)
+	}()
+	return c, nil
+}
+
+// Done is closed when the connection to the pod ends
+func (t *Tunnel) Done() <-chan bool {
+	return t.conn.CloseChan()
+}
+
+// Close ends every connection of the tunnel
+func (t *Tunnel) Close() error {
+	return t.conn.Close()
+}
+
+// Conn is a connection to a port of a pod
+type Conn struct {
+	tunnel    *Tunnel
+	port      int32
+	data      httpstream.Stream
+	errors    httpstream.Stream
+	remoteErr chan error // Error reported by the kubelet, such as a refused connection; closed when its stream ends
+}
+
+// Read reads from the port. Once the port closes the connection it returns the error the
+// kubelet reported, if any, instead of io.EOF.
+func (c *Conn) Read(p []byte) (int, error) {
+	n, err := c.data.Read(p)
+	if err == io.EOF {
+		select {
+		case remoteErr, ok := <-c.remoteErr:
+			if ok && remoteErr != nil {
+				return n, remoteErr
+			}
+		case <-time.After(remoteErrorWait):
+		}
+	}
+	return n, err
+}
+
+// Write writes to the port
+func (c *Conn) Write(p []byte) (int, error) {
+	return c.data.Write(p)
+}
+
+// CloseWrite tells the port no more data is sent, while responses can still be read
+func (c *Conn) CloseWrite() error {
+	return c.data.Close()
+}
+
+// Close discards unsent data and ends the connection
+func (c *Conn) Close() error {
+	err := c.data.Reset()
+	c.tunnel.conn.RemoveStreams(c.data, c.errors)
+	return err
+}
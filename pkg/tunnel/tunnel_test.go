@@ -0,0 +1,106 @@
+package tunnel
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+)
+
+// fakeKubelet serves the portforward protocol: port 80 echoes, other ports are refused
+func fakeKubelet(t *testing.T) *Tunnel {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := httpstream.Handshake(r, w, []string{portforward.PortForwardProtocolV1Name}); err != nil {
+			return
+		}
+		streams := make(chan httpstream.Stream, 8)
+		conn := spdy.NewResponseUpgrader().UpgradeResponse(w, r, func(stream httpstream.Stream, replySent <-chan struct{}) error {
+			streams <- stream
+			return nil
+		})
+		if conn == nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			select {
+			case <-conn.CloseChan():
+				return
+			case stream := <-streams:
+				go serveStream(stream)
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL + "/api/v1/namespaces/shop/pods/web-1/portforward")
+	require.NoError(t, err)
+	tun, err := Dial(&rest.Config{Host: srv.URL}, u)
+	require.NoError(t, err)
+	t.Cleanup(func() { tun.Close() })
+	return tun
+}
+
+func serveStream(stream httpstream.Stream) {
+	refused := stream.Headers().Get(corev1.PortHeader) != "80"
+	switch stream.Headers().Get(corev1.StreamType) {
+	case corev1.StreamTypeError:
+		if refused {
+			stream.Write([]byte("connection refused"))
+		}
+		stream.Close()
+	case corev1.StreamTypeData:
+		if !refused {
+			io.Copy(stream, stream)
+		}
+		stream.Close()
+	}
+}
+
+func TestOpen_Echo(t *testing.T) {
+	tun := fakeKubelet(t)
+
+	conn, err := tun.Open(80)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	require.NoError(t, conn.CloseWrite())
+
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(data))
+
+	// Connections of one tunnel are independent
+	second, err := tun.Open(80)
+	require.NoError(t, err)
+	defer second.Close()
+	second.Write([]byte("pong"))
+	second.CloseWrite()
+	data, err = io.ReadAll(second)
+	require.NoError(t, err)
+	assert.Equal(t, "pong", string(data))
+}
+
+func TestOpen_RemoteError(t *testing.T) {
+	tun := fakeKubelet(t)
+
+	conn, err := tun.Open(81)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = io.ReadAll(conn)
+	assert.EqualError(t, err, "port 81: connection refused")
+}
@@ -0,0 +1,177 @@
+// Package units parses human-friendly durations and sizes in configuration values
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Units of configuration options, named by the unit struct tag of their field
+const (
+	Duration  = "duration"  // time.Duration; bare numbers are seconds
+	Seconds   = "seconds"   // Integer seconds, also written as a duration such as 2m
+	Bytes     = "bytes"     // Integer bytes, also written as a size such as 64KiB
+	Megabytes = "megabytes" // Integer mebibytes, also written as a size such as 1GiB
+)
+
+// byteUnits are the accepted size suffixes, matched case-insensitively. Decimal suffixes
+// are powers of 1000 and binary ones powers of 1024, as in Kubernetes quantities.
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+}
+
+// Parse converts a raw configuration value, as read from YAML or the environment, to the
+// type the unit is stored as: time.Duration for durations, int for seconds and megabytes
+// and int64 for bytes.
+func Parse(unit string, value interface{}) (interface{}, error) {
+	switch unit {
+	case Duration:
+		return ParseDuration(value)
+	case Seconds:
+		return ParseSeconds(value)
+	case Bytes:
+		return ParseBytes(value)
+	case Megabytes:
+		return ParseMegabytes(value)
+	}
+	return nil, fmt.Errorf("unknown unit %q", unit)
+}
+
+// ParseDuration accepts durations such as 30s, 10m or 1h30m, and numbers of seconds
+func ParseDuration(value interface{}) (time.Duration, error) {
+	var d time.Duration
+	switch v := value.(type) {
+	case time.Duration:
+		d = v
+	case string:
+		s := strings.TrimSpace(v)
+		if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+			d = secondsToDuration(seconds)
+			break
+		}
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: use a number of seconds or a value such as 30s, 10m or 1h30m", v)
+		}
+		d = parsed
+	default:
+		seconds, ok := toFloat(value)
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %v: use a number of seconds or a value such as 30s, 10m or 1h30m", value)
+		}
+		d = secondsToDuration(seconds)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid duration %v: must not be negative", value)
+	}
+	return d, nil
+}
+
+// ParseSeconds accepts whole numbers of seconds written as numbers or durations
+func ParseSeconds(value interface{}) (int, error) {
+	d, err := ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d%time.Second != 0 {
+		return 0, fmt.Errorf("invalid duration %v: must be a whole number of seconds", value)
+	}
+	return int(d / time.Second), nil
+}
+
+// ParseBytes accepts numbers of bytes and sizes such as 512, 64KiB, 1.5MB or 2Gi
+func ParseBytes(value interface{}) (int64, error) {
+	if n, ok := toFloat(value); ok {
+		return wholeBytes(n, value)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid size %v: use a number of bytes or a value such as 64KiB or 10MB", value)
+	}
+	trimmed := strings.TrimSpace(s)
+	split := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if split < 0 {
+		split = len(trimmed)
+	}
+	number, err := strconv.ParseFloat(trimmed[:split], 64)
+	multiplier, known := byteUnits[strings.ToLower(strings.TrimSpace(trimmed[split:]))]
+	if err != nil || !known {
+		return 0, fmt.Errorf("invalid size %q: use a number of bytes or a value such as 64KiB or 10MB", s)
+	}
+	return wholeBytes(number*multiplier, value)
+}
+
+// ParseMegabytes accepts numbers of mebibytes and sizes such as 512MiB or 1GiB, rounding
+// sizes up to whole mebibytes
+func ParseMegabytes(value interface{}) (int, error) {
+	if n, ok := toFloat(value); ok {
+		if n < 0 || n != math.Trunc(n) {
+			return 0, fmt.Errorf("invalid size %v: must be a whole, non-negative number of megabytes", value)
+		}
+		return int(n), nil
+	}
+	if s, ok := value.(string); ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			return ParseMegabytes(n)
+		}
+	}
+	b, err := ParseBytes(value)
+	if err != nil {
+		return 0, err
+	}
+	return int((b + 1<<20 - 1) >> 20), nil
+}
+
+// toFloat converts the numeric types YAML and viper produce
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// secondsToDuration converts a number of seconds, which may be fractional
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// wholeBytes checks that a size is a non-negative whole number of bytes
+func wholeBytes(n float64, value interface{}) (int64, error) {
+	if n < 0 || n != math.Trunc(n) || n > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %v: must be a whole, non-negative number of bytes", value)
+	}
+	return int64(n), nil
+}
@@ -0,0 +1,89 @@
+package units
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	for value, want := range map[interface{}]time.Duration{
+		"30s":           30 * time.Second,
+		"1h30m":         90 * time.Minute,
+		" 10m ":         10 * time.Minute,
+		"45":            45 * time.Second,
+		"0.5":           500 * time.Millisecond,
+		60:              time.Minute,
+		int64(2):        2 * time.Second,
+		1.5:             1500 * time.Millisecond,
+		5 * time.Second: 5 * time.Second,
+	} {
+		got, err := ParseDuration(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	for _, value := range []interface{}{"soon", "-5s", -1, true} {
+		_, err := ParseDuration(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseSeconds(t *testing.T) {
+	got, err := ParseSeconds("2m")
+	require.NoError(t, err)
+	assert.Equal(t, 120, got)
+
+	got, err = ParseSeconds(30)
+	require.NoError(t, err)
+	assert.Equal(t, 30, got)
+
+	_, err = ParseSeconds("1500ms")
+	assert.ErrorContains(t, err, "whole number of seconds")
+}
+
+func TestParseBytes(t *testing.T) {
+	for value, want := range map[interface{}]int64{
+		"512":    512,
+		1024:     1024,
+		"64KiB":  64 << 10,
+		"64ki":   64 << 10,
+		"10MB":   10e6,
+		"1.5MiB": 3 << 19,
+		"2 Gi":   2 << 30,
+		"100b":   100,
+	} {
+		got, err := ParseBytes(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	for _, value := range []interface{}{"10XB", "KiB", "-1", 0.5, "1.5"} {
+		_, err := ParseBytes(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseMegabytes(t *testing.T) {
+	for value, want := range map[interface{}]int{
+		100:      100,
+		"100":    100,
+		"1GiB":   1024,
+		"512MiB": 512,
+		"100MB":  96, // Rounded up to whole mebibytes
+	} {
+		got, err := ParseMegabytes(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	_, err := ParseMegabytes(-1)
+	assert.Error(t, err)
+}
+
+func TestParse_UnknownUnit(t *testing.T) {
+	_, err := Parse("furlongs", 1)
+	assert.Error(t, err)
+}
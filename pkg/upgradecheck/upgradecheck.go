@@ -0,0 +1,265 @@
+// Package upgradecheck decides whether a cluster is ready for a Kubernetes version upgrade from
+// its deprecated API usage, disruption budgets and the capacity left while nodes are replaced
+package upgradecheck
+
+import (
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/capacity"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/deprecation"
+)
+
+// Verdicts
+const (
+	VerdictReady             = "ready"
+	VerdictReadyWithWarnings = "ready_with_warnings"
+	VerdictBlocked           = "blocked"
+)
+
+// Severities of findings
+const (
+	SeverityBlocking = "blocking" // Must be fixed before upgrading
+	SeverityWarning  = "warning"  // Causes disruption or follow-up work during the upgrade
+)
+
+// Checks
+const (
+	CheckVersionSkew    = "version_skew"     // The target is not the next minor version
+	CheckKubeletSkew    = "kubelet_skew"     // Kubelets would lag the target control plane too far
+	CheckDeprecatedAPI  = "deprecated_api"   // Workloads written through APIs removed or deprecated by the target
+	CheckPDBBlocksDrain = "pdb_blocks_drain" // A budget allows no disruptions, so node drains hang
+	CheckMissingPDB     = "missing_pdb"      // Replicated workloads without a budget can lose every replica at once
+	CheckSingleReplica  = "single_replica"   // Workloads with one replica go down while their node drains
+	CheckSurgeCapacity  = "surge_capacity"   // Pods of a drained node do not fit on the rest of its pool
+)
+
+// Config holds upgrade check settings
+type Config struct {
+	SurgeNodes  int `mapstructure:"surge_nodes"`  // Nodes added per pool while nodes are replaced, e.g. the max surge of managed node groups
+	KubeletSkew int `mapstructure:"kubelet_skew"` // Minor versions kubelets may lag the control plane
+}
+
+// Finding is one reason the upgrade is blocked or needs care
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Resource string `json:"resource,omitempty"` // namespace/name, node or pool the finding is about
+	Message  string `json:"message"`
+}
+
+// Input is the state of the cluster to check
+type Input struct {
+	Version         deprecation.Version
+	Target          deprecation.Version
+	Deprecations    []deprecation.Finding
+	KubeletVersions map[string]string // Kubelet version by node name
+	Nodes           []capacity.Node
+	PoolLabels      []string
+	Deployments     []appsv1.Deployment
+	StatefulSets    []appsv1.StatefulSet
+	PDBs            []policyv1.PodDisruptionBudget
+}
+
+// Report is the readiness verdict of a cluster
+type Report struct {
+	Version  string    `json:"version"`
+	Target   string    `json:"target"`
+	Verdict  string    `json:"verdict"`
+	Blocking int       `json:"blocking"`
+	Warnings int       `json:"warnings"`
+	Findings []Finding `json:"findings"`
+}
+
+// Check runs every check and returns the verdict, blocking findings first
+func Check(cfg Config, in Input) Report {
+	var findings []Finding
+	findings = append(findings, versionFindings(cfg, in)...)
+	findings = append(findings, deprecationFindings(in.Deprecations, in.Target)...)
+	findings = append(findings, disruptionFindings(in)...)
+	findings = append(findings, surgeFindings(cfg, in)...)
+	if findings == nil {
+		findings = []Finding{}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity == SeverityBlocking && findings[j].Severity != SeverityBlocking
+	})
+
+	report := Report{Version: in.Version.String(), Target: in.Target.String(), Findings: findings}
+	for _, f := range findings {
+		if f.Severity == SeverityBlocking {
+			report.Blocking++
+		} else {
+			report.Warnings++
+		}
+	}
+	switch {
+	case report.Blocking > 0:
+		report.Verdict = VerdictBlocked
+	case report.Warnings > 0:
+		report.Verdict = VerdictReadyWithWarnings
+	default:
+		report.Verdict = VerdictReady
+	}
+	return report
+}
+
+// versionFindings checks that the control plane moves one minor version and kubelets stay
+// within the supported skew of the target
+func versionFindings(cfg Config, in Input) []Finding {
+	var findings []Finding
+	if !in.Version.Less(in.Target) {
+		findings = append(findings, Finding{Check: CheckVersionSkew, Severity: SeverityBlocking,
+			Message: fmt.Sprintf("Target %s is not newer than the cluster version %s", in.Target, in.Version)})
+	} else if in.Target.Major != in.Version.Major || in.Target.Minor > in.Version.Minor+1 {
+		findings = append(findings, Finding{Check: CheckVersionSkew, Severity: SeverityBlocking,
+			Message: fmt.Sprintf("The control plane upgrades one minor version at a time; upgrade %s to %d.%d first", in.Version, in.Version.Major, in.Version.Minor+1)})
+	}
+
+	names := make([]string, 0, len(in.KubeletVersions))
+	for name := range in.KubeletVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		kubelet, err := deprecation.ParseVersion(in.KubeletVersions[name])
+		if err != nil {
+			continue
+		}
+		if kubelet.Major == in.Target.Major && in.Target.Minor-kubelet.Minor > cfg.KubeletSkew {
+			findings = append(findings, Finding{Check: CheckKubeletSkew, Severity: SeverityBlocking, Resource: name,
+				Message: fmt.Sprintf("Kubelet %s is more than %d minor versions older than %s; upgrade the node first", kubelet, cfg.KubeletSkew, in.Target)})
+		}
+	}
+	return findings
+}
+
+// deprecationFindings blocks on APIs removed by the target and warns about APIs it deprecates
+func deprecationFindings(deprecations []deprecation.Finding, target deprecation.Version) []Finding {
+	var findings []Finding
+	for _, d := range deprecations {
+		resource := d.Kind + "/" + d.Name
+		if d.Namespace != "" {
+			resource = d.Namespace + "/" + resource
+		}
+		deprecatedIn, err := deprecation.ParseVersion(d.DeprecatedIn)
+		if err != nil {
+			continue
+		}
+		switch {
+		case d.Blocking:
+			findings = append(findings, Finding{Check: CheckDeprecatedAPI, Severity: SeverityBlocking, Resource: resource,
+				Message: fmt.Sprintf("%s writes %s, removed in %s; migrate to %s", d.Manager, d.APIVersion, d.RemovedIn, d.Replacement)})
+		case d.Status != deprecation.StatusScheduled || !target.Less(deprecatedIn):
+			findings = append(findings, Finding{Check: CheckDeprecatedAPI, Severity: SeverityWarning, Resource: resource,
+				Message: fmt.Sprintf("%s writes %s, deprecated in %s and removed in %s", d.Manager, d.APIVersion, d.DeprecatedIn, d.RemovedIn)})
+		}
+	}
+	return findings
+}
+
+// disruptionFindings checks that node drains can evict pods without taking workloads down
+func disruptionFindings(in Input) []Finding {
+	var findings []Finding
+	type selectorPDB struct {
+		pdb      *policyv1.PodDisruptionBudget
+		selector labels.Selector
+	}
+	var pdbs []selectorPDB
+	for i := range in.PDBs {
+		pdb := &in.PDBs[i]
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		pdbs = append(pdbs, selectorPDB{pdb, selector})
+		if pdb.Status.ExpectedPods > 0 && pdb.Status.DisruptionsAllowed == 0 {
+			findings = append(findings, Finding{Check: CheckPDBBlocksDrain, Severity: SeverityBlocking, Resource: pdb.Namespace + "/" + pdb.Name,
+				Message: fmt.Sprintf("Allows no disruptions with %d of %d pods healthy, so draining their nodes hangs", pdb.Status.CurrentHealthy, pdb.Status.ExpectedPods)})
+		}
+	}
+
+	covered := func(namespace string, podLabels map[string]string) bool {
+		for _, p := range pdbs {
+			if p.pdb.Namespace == namespace && p.selector.Matches(labels.Set(podLabels)) {
+				return true
+			}
+		}
+		return false
+	}
+	check := func(kind, namespace, name string, replicas *int32, podLabels map[string]string) {
+		resource := namespace + "/" + kind + "/" + name
+		switch {
+		case replicas != nil && *replicas == 0:
+		case replicas == nil || *replicas == 1:
+			findings = append(findings, Finding{Check: CheckSingleReplica, Severity: SeverityWarning, Resource: resource,
+				Message: "Runs one replica, which is unavailable while its node drains"})
+		case !covered(namespace, podLabels):
+			findings = append(findings, Finding{Check: CheckMissingPDB, Severity: SeverityWarning, Resource: resource,
+				Message: fmt.Sprintf("Runs %d replicas without a PodDisruptionBudget, so concurrent drains can evict all of them", *replicas)})
+		}
+	}
+	for _, d := range in.Deployments {
+		check("Deployment", d.Namespace, d.Name, d.Spec.Replicas, d.Spec.Template.Labels)
+	}
+	for _, s := range in.StatefulSets {
+		check("StatefulSet", s.Namespace, s.Name, s.Spec.Replicas, s.Spec.Template.Labels)
+	}
+	return findings
+}
+
+// surgeFindings checks per pool that the requests of its busiest node fit on the rest of the
+// pool plus the surge nodes, assumed as large as the pool's largest node
+func surgeFindings(cfg Config, in Input) []Finding {
+	pools := map[string][]capacity.Node{}
+	for _, node := range in.Nodes {
+		pools[poolOf(node, in.PoolLabels)] = append(pools[poolOf(node, in.PoolLabels)], node)
+	}
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var findings []Finding
+	for _, name := range names {
+		nodes := pools[name]
+		var freeCPU, freeMemory, largestCPU, largestMemory int64
+		busiest := nodes[0]
+		for _, n := range nodes {
+			freeCPU += n.AllocatableCPUMilli - n.RequestedCPUMilli
+			freeMemory += n.AllocatableMemoryBytes - n.RequestedMemoryBytes
+			largestCPU = max(largestCPU, n.AllocatableCPUMilli)
+			largestMemory = max(largestMemory, n.AllocatableMemoryBytes)
+			if n.RequestedCPUMilli+n.RequestedMemoryBytes/(1<<20) > busiest.RequestedCPUMilli+busiest.RequestedMemoryBytes/(1<<20) {
+				busiest = n
+			}
+		}
+		// The drained node's own free capacity is gone while it is replaced
+		freeCPU -= busiest.AllocatableCPUMilli - busiest.RequestedCPUMilli
+		freeMemory -= busiest.AllocatableMemoryBytes - busiest.RequestedMemoryBytes
+		freeCPU += int64(cfg.SurgeNodes) * largestCPU
+		freeMemory += int64(cfg.SurgeNodes) * largestMemory
+
+		if busiest.RequestedCPUMilli > freeCPU || busiest.RequestedMemoryBytes > freeMemory {
+			findings = append(findings, Finding{Check: CheckSurgeCapacity, Severity: SeverityBlocking, Resource: name,
+				Message: fmt.Sprintf("Pods of node %s request %dm CPU and %dMi memory, but the rest of pool %s has %dm and %dMi free with %d surge nodes",
+					busiest.Name, busiest.RequestedCPUMilli, busiest.RequestedMemoryBytes>>20, name, max(freeCPU, 0), max(freeMemory, 0)>>20, cfg.SurgeNodes)})
+		}
+	}
+	return findings
+}
+
+func poolOf(node capacity.Node, poolLabels []string) string {
+	for _, label := range poolLabels {
+		if value := node.Labels[label]; value != "" {
+			return value
+		}
+	}
+	return capacity.UnlabeledPool
+}
@@ -0,0 +1,125 @@
+package upgradecheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/capacity"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/deprecation"
+)
+
+func deployment(namespace, name string, replicas int32, labels map[string]string) appsv1.Deployment {
+	return appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}},
+		},
+	}
+}
+
+func pdb(namespace, name string, labels map[string]string, expected, allowed int32) policyv1.PodDisruptionBudget {
+	return policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+		Status:     policyv1.PodDisruptionBudgetStatus{ExpectedPods: expected, CurrentHealthy: expected, DisruptionsAllowed: allowed},
+	}
+}
+
+func node(name, pool string, allocatable, requested int64) capacity.Node {
+	return capacity.Node{
+		Name:                   name,
+		Labels:                 map[string]string{"pool": pool},
+		AllocatableCPUMilli:    allocatable,
+		AllocatableMemoryBytes: allocatable << 20,
+		RequestedCPUMilli:      requested,
+		RequestedMemoryBytes:   requested << 20,
+	}
+}
+
+func TestCheck_Ready(t *testing.T) {
+	report := Check(Config{KubeletSkew: 3}, Input{
+		Version:         deprecation.Version{Major: 1, Minor: 30},
+		Target:          deprecation.Version{Major: 1, Minor: 31},
+		KubeletVersions: map[string]string{"a": "v1.30.2", "b": "v1.29.8"},
+		Nodes:           []capacity.Node{node("a", "general", 4000, 1000), node("b", "general", 4000, 1000)},
+		PoolLabels:      []string{"pool"},
+		Deployments:     []appsv1.Deployment{deployment("shop", "web", 3, map[string]string{"app": "web"})},
+		PDBs:            []policyv1.PodDisruptionBudget{pdb("shop", "web", map[string]string{"app": "web"}, 3, 1)},
+	})
+	assert.Equal(t, VerdictReady, report.Verdict)
+	assert.Equal(t, "1.30", report.Version)
+	assert.Equal(t, "1.31", report.Target)
+	assert.Empty(t, report.Findings)
+}
+
+func TestCheck_Blocked(t *testing.T) {
+	report := Check(Config{KubeletSkew: 3}, Input{
+		Version:         deprecation.Version{Major: 1, Minor: 28},
+		Target:          deprecation.Version{Major: 1, Minor: 29},
+		KubeletVersions: map[string]string{"old": "v1.25.6"},
+		Deprecations: []deprecation.Finding{
+			{Kind: "FlowSchema", Name: "custom", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta2", Manager: "helm", DeprecatedIn: "1.26", RemovedIn: "1.29", Status: deprecation.StatusDeprecated, Blocking: true},
+			{Kind: "FlowSchema", Name: "later", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta3", Manager: "helm", DeprecatedIn: "1.29", RemovedIn: "1.32", Status: deprecation.StatusScheduled},
+			{Kind: "Widget", Name: "w", APIVersion: "example.com/v1alpha1", Manager: "operator", DeprecatedIn: "1.30", RemovedIn: "1.33", Status: deprecation.StatusScheduled},
+		},
+		Nodes:      []capacity.Node{node("a", "general", 4000, 3500), node("b", "general", 4000, 3000)},
+		PoolLabels: []string{"pool"},
+		Deployments: []appsv1.Deployment{
+			deployment("shop", "web", 3, map[string]string{"app": "web"}),
+			deployment("shop", "cart", 2, map[string]string{"app": "cart"}),
+			deployment("shop", "cron", 1, map[string]string{"app": "cron"}),
+			deployment("shop", "idle", 0, map[string]string{"app": "idle"}),
+		},
+		PDBs: []policyv1.PodDisruptionBudget{pdb("shop", "web", map[string]string{"app": "web"}, 3, 0)},
+	})
+
+	assert.Equal(t, VerdictBlocked, report.Verdict)
+	assert.Equal(t, 4, report.Blocking)
+	assert.Equal(t, 3, report.Warnings)
+	require.Len(t, report.Findings, 7)
+
+	checks := make([]string, len(report.Findings))
+	for i, f := range report.Findings {
+		checks[i] = f.Severity + "/" + f.Check
+	}
+	assert.Equal(t, []string{
+		"blocking/kubelet_skew",
+		"blocking/deprecated_api",
+		"blocking/pdb_blocks_drain",
+		"blocking/surge_capacity",
+		"warning/deprecated_api",
+		"warning/missing_pdb",
+		"warning/single_replica",
+	}, checks)
+	assert.Equal(t, "old", report.Findings[0].Resource)
+	assert.Equal(t, "FlowSchema/custom", report.Findings[1].Resource)
+	assert.Equal(t, "general", report.Findings[3].Resource)
+	assert.Equal(t, "FlowSchema/later", report.Findings[4].Resource)
+	assert.Equal(t, "shop/Deployment/cart", report.Findings[5].Resource)
+
+	// A surge node makes room for the drained node's pods
+	report = Check(Config{SurgeNodes: 1, KubeletSkew: 3}, Input{
+		Version:    deprecation.Version{Major: 1, Minor: 28},
+		Target:     deprecation.Version{Major: 1, Minor: 29},
+		Nodes:      []capacity.Node{node("a", "general", 4000, 3500), node("b", "general", 4000, 3000)},
+		PoolLabels: []string{"pool"},
+	})
+	assert.Equal(t, VerdictReady, report.Verdict)
+}
+
+func TestCheck_VersionSkew(t *testing.T) {
+	for target, want := range map[string]string{"1.29": VerdictBlocked, "1.30": VerdictReady, "1.31": VerdictBlocked, "2.0": VerdictBlocked} {
+		report := Check(Config{}, Input{
+			Version: deprecation.Version{Major: 1, Minor: 29},
+			Target:  deprecation.MustParseVersion(target),
+		})
+		assert.Equal(t, want, report.Verdict, target)
+	}
+}
@@ -0,0 +1,283 @@
+// Package vulnscan scans workload images for vulnerabilities with Trivy
+package vulnscan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SeverityCritical is the Trivy severity that triggers alerts
+const SeverityCritical = "CRITICAL"
+
+// Config holds image scanning settings
+type Config struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	TrivyPath  string        `mapstructure:"trivy_path"` // Trivy binary, defaults to "trivy" on PATH
+	ServerURL  string        `mapstructure:"server_url"` // Trivy server for client/server mode, empty scans locally
+	Interval   time.Duration `mapstructure:"interval"`   // Time between scans of all running images
+	Timeout    time.Duration `mapstructure:"timeout"`    // Per-image scan timeout
+	Severities []string      `mapstructure:"severities"` // Severities to report, e.g. CRITICAL, HIGH
+
+	// Alerting on critical CVEs that were not present in the previous scan
+	AlertOnNewCritical bool   `mapstructure:"alert_on_new_critical"`
+	AlertWebhookURL    string `mapstructure:"alert_webhook_url"` // Slack-compatible incoming webhook
+}
+
+// Finding is a single vulnerability in an image
+type Finding struct {
+	VulnerabilityID  string `json:"vulnerability_id"`
+	Package          string `json:"package"`
+	InstalledVersion string `json:"installed_version"`
+	FixedVersion     string `json:"fixed_version,omitempty"`
+	Severity         string `json:"severity"`
+	Title            string `json:"title,omitempty"`
+}
+
+// ImageReport is the latest scan result for an image
+type ImageReport struct {
+	Image     string         `json:"image"`
+	Workloads []string       `json:"workloads"` // namespace/name of workloads running the image
+	ScannedAt time.Time      `json:"scanned_at"`
+	Error     string         `json:"error,omitempty"`
+	Counts    map[string]int `json:"counts"`
+	Findings  []Finding      `json:"findings"`
+}
+
+// ImageSource returns running images mapped to the workloads that use them
+type ImageSource func(ctx context.Context) (map[string][]string, error)
+
+// Runner executes a command and returns its standard output
+type Runner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// execRunner runs commands with os/exec
+func execRunner(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// Scanner periodically scans images and keeps the latest reports
+type Scanner struct {
+	config Config
+	source ImageSource
+	run    Runner
+	client *http.Client
+
+	mu       sync.RWMutex
+	reports  map[string]*ImageReport
+	critical map[string]bool // image|CVE pairs seen in the previous scan
+	lastScan time.Time
+}
+
+// NewScanner creates a scanner; a nil runner uses the trivy binary
+func NewScanner(cfg Config, source ImageSource, run Runner) *Scanner {
+	if cfg.TrivyPath == "" {
+		cfg.TrivyPath = "trivy"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 6 * time.Hour
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+	if len(cfg.Severities) == 0 {
+		cfg.Severities = []string{"CRITICAL", "HIGH"}
+	}
+	if run == nil {
+		run = execRunner
+	}
+	return &Scanner{
+		config:   cfg,
+		source:   source,
+		run:      run,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		reports:  make(map[string]*ImageReport),
+		critical: make(map[string]bool),
+	}
+}
+
+// Start scans immediately and then on every interval until the context is canceled
+func (s *Scanner) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.ScanAll(ctx); err != nil {
+			log.Error().Err(err).Msg("Image vulnerability scan failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ScanAll scans every running image and alerts on new critical findings
+func (s *Scanner) ScanAll(ctx context.Context) error {
+	images, err := s.source(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list running images: %w", err)
+	}
+
+	reports := make(map[string]*ImageReport, len(images))
+	for image, workloads := range images {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		report := s.scanImage(ctx, image)
+		report.Workloads = workloads
+		sort.Strings(report.Workloads)
+		reports[image] = report
+	}
+
+	// Compare critical findings with the previous scan
+	critical := make(map[string]bool)
+	var newCritical []string
+	for image, report := range reports {
+		for _, f := range report.Findings {
+			if f.Severity != SeverityCritical {
+				continue
+			}
+			key := image + "|" + f.VulnerabilityID
+			critical[key] = true
+			if !s.critical[key] {
+				newCritical = append(newCritical, fmt.Sprintf("%s in %s (%s)", f.VulnerabilityID, image, strings.Join(report.Workloads, ", ")))
+			}
+		}
+	}
+
+	s.mu.Lock()
+	firstScan := s.lastScan.IsZero()
+	s.reports = reports
+	s.critical = critical
+	s.lastScan = time.Now()
+	s.mu.Unlock()
+
+	log.Info().Int("images", len(reports)).Int("new_critical", len(newCritical)).Msg("Image vulnerability scan completed")
+
+	// The first scan establishes the baseline and does not alert
+	if !firstScan && len(newCritical) > 0 && s.config.AlertOnNewCritical {
+		sort.Strings(newCritical)
+		s.alert(ctx, newCritical)
+	}
+	return nil
+}
+
+// Reports returns the latest reports sorted by image
+func (s *Scanner) Reports() []ImageReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]ImageReport, 0, len(s.reports))
+	for _, r := range s.reports {
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Image < result[j].Image })
+	return result
+}
+
+// LastScan returns when the last scan completed
+func (s *Scanner) LastScan() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastScan
+}
+
+// trivyReport is the subset of Trivy's JSON output that is used
+type trivyReport struct {
+	Results []struct {
+		Target          string `json:"Target"`
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+			Title            string `json:"Title"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+func (s *Scanner) scanImage(ctx context.Context, image string) *ImageReport {
+	report := &ImageReport{Image: image, ScannedAt: time.Now().UTC(), Counts: map[string]int{}, Findings: []Finding{}}
+
+	args := []string{"image", "--quiet", "--format", "json", "--severity", strings.Join(s.config.Severities, ",")}
+	if s.config.ServerURL != "" {
+		args = append(args, "--server", s.config.ServerURL)
+	}
+	args = append(args, image)
+
+	scanCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	out, err := s.run(scanCtx, s.config.TrivyPath, args...)
+	if err != nil {
+		log.Warn().Err(err).Str("image", image).Msg("Trivy scan failed")
+		report.Error = err.Error()
+		return report
+	}
+
+	var parsed trivyReport
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		report.Error = fmt.Sprintf("failed to parse trivy output: %v", err)
+		return report
+	}
+
+	for _, result := range parsed.Results {
+		for _, v := range result.Vulnerabilities {
+			report.Findings = append(report.Findings, Finding{
+				VulnerabilityID:  v.VulnerabilityID,
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Severity:         v.Severity,
+				Title:            v.Title,
+			})
+			report.Counts[v.Severity]++
+		}
+	}
+	return report
+}
+
+// alert posts new critical findings to the webhook and logs them
+func (s *Scanner) alert(ctx context.Context, findings []string) {
+	log.Warn().Strs("findings", findings).Msg("New critical vulnerabilities affecting running workloads")
+	if s.config.AlertWebhookURL == "" {
+		return
+	}
+
+	text := fmt.Sprintf("%d new critical vulnerabilities affecting running workloads:\n• %s", len(findings), strings.Join(findings, "\n• "))
+	body, _ := json.Marshal(map[string]string{"text": text})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.AlertWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build vulnerability alert")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send vulnerability alert")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Error().Int("status", resp.StatusCode).Msg("Vulnerability alert webhook rejected the request")
+	}
+}
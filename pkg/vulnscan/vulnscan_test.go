@@ -0,0 +1,86 @@
+package vulnscan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const trivyOutput = `{
+  "Results": [{
+    "Target": "nginx:1.25 (debian 12)",
+    "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2024-0001", "PkgName": "openssl", "InstalledVersion": "3.0.1", "FixedVersion": "3.0.2", "Severity": "CRITICAL", "Title": "bad"},
+      {"VulnerabilityID": "CVE-2024-0002", "PkgName": "zlib", "InstalledVersion": "1.2", "Severity": "HIGH"}
+    ]
+  }]
+}`
+
+func staticSource(images map[string][]string) ImageSource {
+	return func(ctx context.Context) (map[string][]string, error) { return images, nil }
+}
+
+func TestScanner_ScanAll(t *testing.T) {
+	var gotArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		gotArgs = args
+		if args[len(args)-1] == "broken:1" {
+			return nil, errors.New("manifest unknown")
+		}
+		return []byte(trivyOutput), nil
+	}
+
+	s := NewScanner(Config{ServerURL: "http://trivy:4954"}, staticSource(map[string][]string{
+		"nginx:1.25": {"web/frontend", "default/nginx"},
+		"broken:1":   {"default/broken"},
+	}), runner)
+	require.NoError(t, s.ScanAll(context.Background()))
+
+	assert.True(t, slices.Contains(gotArgs, "--server"))
+	assert.True(t, slices.Contains(gotArgs, "CRITICAL,HIGH"))
+
+	reports := s.Reports()
+	require.Len(t, reports, 2)
+	assert.Equal(t, "broken:1", reports[0].Image)
+	assert.Contains(t, reports[0].Error, "manifest unknown")
+
+	nginx := reports[1]
+	assert.Equal(t, []string{"default/nginx", "web/frontend"}, nginx.Workloads)
+	assert.Equal(t, map[string]int{"CRITICAL": 1, "HIGH": 1}, nginx.Counts)
+	assert.Equal(t, "3.0.2", nginx.Findings[0].FixedVersion)
+	assert.False(t, s.LastScan().IsZero())
+}
+
+func TestScanner_AlertsOnNewCriticalOnly(t *testing.T) {
+	var alerts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		alerts = append(alerts, body["text"])
+	}))
+	defer server.Close()
+
+	images := map[string][]string{"nginx:1.25": {"default/nginx"}}
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(trivyOutput), nil
+	}
+	s := NewScanner(Config{AlertOnNewCritical: true, AlertWebhookURL: server.URL}, staticSource(images), runner)
+
+	// The first scan is the baseline, the second finds nothing new
+	require.NoError(t, s.ScanAll(context.Background()))
+	require.NoError(t, s.ScanAll(context.Background()))
+	assert.Empty(t, alerts)
+
+	// A newly deployed image with the same CVE is a new finding
+	images["nginx:1.26"] = []string{"default/nginx-canary"}
+	require.NoError(t, s.ScanAll(context.Background()))
+	require.Len(t, alerts, 1)
+	assert.Contains(t, alerts[0], "CVE-2024-0001 in nginx:1.26")
+}
@@ -45,6 +45,8 @@ func MockConfig() *cmd.Config {
 	config.APIServer.Security.WriteTimeoutSeconds = 30
 	config.APIServer.Security.IdleTimeoutSeconds = 60
 	config.APIServer.Security.DisableKeepalive = false
+	config.APIServer.Security.RateLimitMaxEntries = 10000
+	config.APIServer.Security.RateLimitIdleTTL = 10 * time.Minute
 	config.APIServer.SwaggerUI.Enabled = true
 	config.APIServer.SwaggerUI.CORSEnabled = false
 	config.APIServer.SwaggerUI.CORSAllowOrigin = "*"
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/cmd"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/fixture"
+)
+
+// replayHandler returns the real API server handler backed by the recorded fixtures
+func replayHandler(t *testing.T) (fasthttp.RequestHandler, *fixture.Replayer) {
+	clientset, replayer, err := fixture.NewClientset("testdata/fixtures")
+	require.NoError(t, err)
+	return cmd.NewHandler(clientset, nil), replayer
+}
+
+// get serves a GET request and decodes the JSON response
+func get(t *testing.T, handler fasthttp.RequestHandler, uri string) (int, map[string]interface{}) {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI(uri)
+	handler(&ctx)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(ctx.Response.Body(), &body), string(ctx.Response.Body()))
+	return ctx.Response.StatusCode(), body
+}
+
+// TestReplayPods runs the pods handler against a recorded pod list
+func TestReplayPods(t *testing.T) {
+	handler, replayer := replayHandler(t)
+
+	status, body := get(t, handler, "/pods?namespace=default")
+	assert.Equal(t, fasthttp.StatusOK, status)
+	assert.Equal(t, "kubernetes-api", body["source"])
+	assert.Equal(t, float64(2), body["count"])
+	assert.Equal(t, []interface{}{"web-7d4b9c8f6-2xkqp", "worker-5c6f7d8b9-qz7lm"}, body["names"])
+
+	items := body["items"].([]interface{})
+	web := items[0].(map[string]interface{})
+	assert.Equal(t, "Running", web["phase"])
+	assert.Equal(t, "worker-1", web["node"])
+	assert.Equal(t, "10.244.1.12", web["ip"])
+	assert.Empty(t, replayer.Missed())
+}
+
+// TestReplayDeployments runs the deployments handler, which falls back to the API without an informer
+func TestReplayDeployments(t *testing.T) {
+	handler, _ := replayHandler(t)
+
+	status, body := get(t, handler, "/deployments?namespace=default")
+	assert.Equal(t, fasthttp.StatusOK, status)
+	assert.Equal(t, "direct-api", body["source"])
+	items := body["items"].([]interface{})
+	require.Len(t, items, 1)
+	web := items[0].(map[string]interface{})
+	assert.Equal(t, "web", web["name"])
+	assert.Equal(t, float64(2), web["available"])
+}
+
+// TestReplayMissingFixture checks that requests without a fixture fail like a missing object
+func TestReplayMissingFixture(t *testing.T) {
+	handler, replayer := replayHandler(t)
+
+	status, _ := get(t, handler, "/pods?namespace=kube-system")
+	assert.Equal(t, fasthttp.StatusInternalServerError, status)
+	require.Len(t, replayer.Missed(), 1)
+	assert.Contains(t, replayer.Missed()[0], "GET /api/v1/namespaces/kube-system/pods")
+}
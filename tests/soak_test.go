@@ -0,0 +1,497 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	// Registers the workqueue metrics provider, as it is for the controllers
+	_ "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/plugin"
+	"github.com/obezsmertnyi/k8s-custom-controller/pkg/stream"
+)
+
+// Soak test sizing. SOAK_EVENTS raises the number of events for a longer run, e.g.
+// SOAK_EVENTS=500000 make test-soak.
+const (
+	soakDeployments      = 200 // Deployments created, updated and deleted in every round
+	soakUpdates          = 8   // Updates of every deployment per round
+	soakDefaultEvents    = 20000
+	soakShortEvents      = 4000
+	soakWorkers          = 4
+	soakQueueName        = "soak-deployments"
+	soakSlowBuffer       = 32
+	soakHeapGrowthBytes  = 16 << 20 // Allowed heap growth between the first and the last round
+	soakGoroutineTimeout = 10 * time.Second
+)
+
+// TestSoak_InformerPipeline drives tens of thousands of synthetic deployment events through
+// a shared informer, a workqueue, the sink plugin registry and the watch stream broadcasters.
+// It asserts that every event is delivered, that the pipeline's metrics add up, that memory
+// stays bounded across rounds and that no goroutine outlives the pipeline.
+func TestSoak_InformerPipeline(t *testing.T) {
+	events := soakDefaultEvents
+	if testing.Short() {
+		events = soakShortEvents
+	}
+	if v := os.Getenv("SOAK_EVENTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		require.NoError(t, err, "SOAK_EVENTS must be a number")
+		events = n
+	}
+	perRound := soakDeployments * (soakUpdates + 2)
+	rounds := max(2, events/perRound)
+
+	baseline := runtime.NumGoroutine()
+	droppedBefore := gatherValue(t, "k8s_custom_controller_stream_dropped_events_total", map[string]string{"stream": "soak-sink"})
+
+	p := startSoakPipeline(t)
+	var heapWarm uint64
+	start := time.Now()
+	for round := 1; round <= rounds; round++ {
+		p.runRound(t, round)
+		p.releaseSlowSubscriber(t)
+		if round == 1 {
+			heapWarm = heapInUse()
+		}
+	}
+	heapFinal := heapInUse()
+	pushed := p.pushed.Load()
+	t.Logf("Soak: %d events in %d rounds took %s; heap %d KiB after the first round, %d KiB after the last",
+		pushed, rounds, time.Since(start).Round(time.Millisecond), heapWarm>>10, heapFinal>>10)
+
+	p.stop(t)
+
+	// Every event reached the informer handlers and the watch stream, in order
+	assert.Equal(t, int64(rounds*perRound), pushed)
+	assert.Equal(t, pushed, p.handled.Load())
+	assert.Equal(t, pushed, p.fast.received.Load())
+	assert.Empty(t, p.fast.errors())
+
+	// Every queued key was processed once and delivered to both sinks
+	processed := p.processed.Load()
+	assert.Equal(t, float64(processed), p.queueAdds(t), "workqueue adds")
+	assert.Equal(t, processed, p.counter.total.Load())
+	assert.Equal(t, processed, p.published.Load())
+	assert.Equal(t, 0.0, gatherValue(t, "workqueue_depth", map[string]string{"name": soakQueueName}))
+	assert.Empty(t, p.counter.notDeleted(), "deployments whose last event is not a delete")
+
+	// The stalled subscriber lost events, and each loss was counted
+	dropped := gatherValue(t, "k8s_custom_controller_stream_dropped_events_total", map[string]string{"stream": "soak-sink"}) - droppedBefore
+	assert.Positive(t, dropped)
+	assert.Equal(t, float64(p.published.Load()), float64(p.slow.received.Load())+dropped, "slow subscriber received and dropped events")
+	for _, name := range []string{"soak-informer", "soak-sink"} {
+		assert.Equal(t, 0.0, gatherValue(t, "k8s_custom_controller_stream_subscribers", map[string]string{"stream": name}), name)
+	}
+
+	assert.LessOrEqual(t, heapFinal, heapWarm+soakHeapGrowthBytes, "heap grew across rounds")
+	assertNoGoroutineLeak(t, baseline)
+}
+
+// soakPipeline is an informer pipeline shaped like the controller's: informer handlers feed
+// a watch stream broadcaster and a rate limited workqueue, whose workers read the informer
+// cache and emit sink plugin events.
+type soakPipeline struct {
+	watcher  *watch.FakeWatcher
+	factory  informers.SharedInformerFactory
+	informer cache.SharedIndexInformer
+	queue    workqueue.TypedRateLimitingInterface[string]
+	sinks    *plugin.Registry
+	cancel   context.CancelFunc
+	workers  sync.WaitGroup
+
+	informerEvents *stream.Broadcaster // Informer events, read by a fast subscriber
+	sinkEvents     *stream.Broadcaster // Sink events, read by a subscriber stalled during rounds
+	fast           *soakSubscriber
+	slow           *soakSubscriber
+	slowGate       chan chan struct{}
+
+	counter    *countingSink
+	addsBefore float64 // workqueue_adds_total of earlier runs of the test
+	version    int64   // resourceVersion of the last pushed event
+	pushed     atomic.Int64
+	handled    atomic.Int64
+	processed  atomic.Int64
+	published  atomic.Int64
+}
+
+func startSoakPipeline(t *testing.T) *soakPipeline {
+	p := &soakPipeline{
+		watcher:        watch.NewFake(),
+		informerEvents: stream.NewBroadcaster("soak-informer", stream.Config{OverflowPolicy: stream.OverflowDisconnect}),
+		sinkEvents:     stream.NewBroadcaster("soak-sink", stream.Config{BufferSize: soakSlowBuffer, OverflowPolicy: stream.OverflowDropOldest}),
+		counter:        &countingSink{last: map[string]string{}},
+		slowGate:       make(chan chan struct{}),
+		addsBefore:     gatherValue(t, "workqueue_adds_total", map[string]string{"name": soakQueueName}),
+	}
+
+	// The fake watcher is unbuffered, so pushing an event waits for the reflector
+	client := fake.NewClientset()
+	client.PrependWatchReactor("deployments", k8stesting.DefaultWatchReactor(p.watcher, nil))
+	p.factory = informers.NewSharedInformerFactory(client, 0)
+	p.informer = p.factory.Apps().V1().Deployments().Informer()
+
+	p.queue = workqueue.NewTypedRateLimitingQueueWithConfig(
+		workqueue.DefaultTypedControllerRateLimiter[string](),
+		workqueue.TypedRateLimitingQueueConfig[string]{Name: soakQueueName},
+	)
+	_, err := p.informer.AddEventHandler(stream.EventHandler(p.informerEvents, "Deployment"))
+	require.NoError(t, err)
+	_, err = p.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { p.enqueue(obj) },
+		UpdateFunc: func(_, obj interface{}) { p.enqueue(obj) },
+		DeleteFunc: func(obj interface{}) { p.enqueue(obj) },
+	})
+	require.NoError(t, err)
+
+	p.sinks = plugin.NewRegistry()
+	p.sinks.RegisterSink(p.counter)
+	p.sinks.RegisterSink(&streamSink{events: p.sinkEvents, published: &p.published})
+
+	p.fast = subscribe(t, p.informerEvents, nil)
+	p.slow = subscribe(t, p.sinkEvents, p.slowGate)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.factory.Start(ctx.Done())
+	syncCtx, syncCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer syncCancel()
+	require.True(t, cache.WaitForCacheSync(syncCtx.Done(), p.informer.HasSynced), "informer did not sync")
+
+	for i := 0; i < soakWorkers; i++ {
+		p.workers.Add(1)
+		go p.work(ctx)
+	}
+	return p
+}
+
+func (p *soakPipeline) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	p.queue.Add(key)
+	p.handled.Add(1)
+}
+
+// work processes queued keys the way a reconciler does, from the informer cache
+func (p *soakPipeline) work(ctx context.Context) {
+	defer p.workers.Done()
+	for {
+		key, shutdown := p.queue.Get()
+		if shutdown {
+			return
+		}
+		namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+		event := plugin.Event{
+			ID:           strconv.FormatInt(p.processed.Load(), 10),
+			ClusterID:    "soak",
+			Type:         "DELETE",
+			ResourceType: "Deployment",
+			Namespace:    namespace,
+			Name:         name,
+		}
+		if obj, exists, err := p.informer.GetIndexer().GetByKey(key); err == nil && exists {
+			event.Type, event.Object = "UPDATE", obj
+		}
+		p.sinks.Emit(ctx, event)
+		p.queue.Forget(key)
+		p.queue.Done(key)
+		p.processed.Add(1)
+	}
+}
+
+// runRound creates, updates and deletes every deployment, then waits for the pipeline to drain
+func (p *soakPipeline) runRound(t *testing.T, round int) {
+	deployments := make([]*appsv1.Deployment, soakDeployments)
+	for i := range deployments {
+		deployments[i] = soakDeployment(round, i)
+		p.push(watch.Added, deployments[i])
+	}
+	for u := 1; u <= soakUpdates; u++ {
+		for i, d := range deployments {
+			d = d.DeepCopy()
+			d.Spec.Replicas = int32Ptr(int32(u))
+			deployments[i] = d
+			p.push(watch.Modified, d)
+		}
+	}
+	for _, d := range deployments {
+		p.push(watch.Deleted, d.DeepCopy())
+	}
+
+	require.Eventually(t, func() bool {
+		return p.handled.Load() == p.pushed.Load() &&
+			p.fast.received.Load() == p.pushed.Load() &&
+			p.queue.Len() == 0 &&
+			float64(p.processed.Load()) == p.queueAdds(t) &&
+			len(p.informer.GetStore().ListKeys()) == 0
+	}, 30*time.Second, 5*time.Millisecond, "round %d did not drain", round)
+}
+
+// queueAdds returns the keys added to the workqueue since the pipeline started
+func (p *soakPipeline) queueAdds(t *testing.T) float64 {
+	return gatherValue(t, "workqueue_adds_total", map[string]string{"name": soakQueueName}) - p.addsBefore
+}
+
+func (p *soakPipeline) push(eventType watch.EventType, d *appsv1.Deployment) {
+	p.version++
+	d.ResourceVersion = strconv.FormatInt(p.version, 10)
+	p.watcher.Action(eventType, d)
+	p.pushed.Add(1)
+}
+
+// releaseSlowSubscriber lets the stalled subscriber drain its buffer between rounds
+func (p *soakPipeline) releaseSlowSubscriber(t *testing.T) {
+	drained := make(chan struct{})
+	select {
+	case p.slowGate <- drained:
+	case <-time.After(10 * time.Second):
+		t.Fatal("slow subscriber is not waiting")
+	}
+	<-drained
+}
+
+// stop shuts the pipeline down in the order the controller does
+func (p *soakPipeline) stop(t *testing.T) {
+	p.cancel()
+	p.factory.Shutdown()
+	p.watcher.Stop()
+	// ShutDownWithDrain would leave the delaying queue's loop running
+	p.queue.ShutDown()
+	p.workers.Wait()
+	p.sinks.Close()
+	p.informerEvents.Close()
+	p.sinkEvents.Close()
+	close(p.slowGate)
+	p.fast.wait(t)
+	p.slow.wait(t)
+}
+
+func soakDeployment(round, i int) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("app-%d-%d", round, i),
+			Namespace: fmt.Sprintf("soak-%d", i%4),
+			Labels:    map[string]string{"app": fmt.Sprintf("app-%d", i), "round": strconv.Itoa(round)},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(0)},
+	}
+}
+
+// soakSubscriber reads a stream. Without a gate it reads continuously and resumes from its
+// last resourceVersion when disconnected for falling behind, as streaming clients do. With a
+// gate it only reads when released, standing in for a stalled client.
+type soakSubscriber struct {
+	received atomic.Int64
+	done     chan struct{}
+
+	mu   sync.Mutex
+	errs []string
+}
+
+func subscribe(t *testing.T, b *stream.Broadcaster, gate chan chan struct{}) *soakSubscriber {
+	sub, _, err := b.Subscribe("")
+	require.NoError(t, err)
+	s := &soakSubscriber{done: make(chan struct{})}
+	if gate != nil {
+		go s.readWhenReleased(sub, gate)
+	} else {
+		go s.read(b, sub)
+	}
+	return s
+}
+
+func (s *soakSubscriber) read(b *stream.Broadcaster, sub *stream.Subscription) {
+	defer close(s.done)
+	var last uint64
+	deliver := func(event stream.Event) {
+		v, _ := strconv.ParseUint(event.ResourceVersion, 10, 64)
+		if v <= last {
+			s.fail("event %d delivered after %d", v, last)
+		}
+		last = v
+		s.received.Add(1)
+	}
+	for {
+		for event := range sub.Events() {
+			deliver(event)
+		}
+		if !sub.Overflowed() {
+			return
+		}
+		var replay []stream.Event
+		var err error
+		sub, replay, err = b.Subscribe(strconv.FormatUint(last, 10))
+		if err != nil {
+			s.fail("resume from %d: %v", last, err)
+			return
+		}
+		for _, event := range replay {
+			deliver(event)
+		}
+	}
+}
+
+func (s *soakSubscriber) readWhenReleased(sub *stream.Subscription, gate chan chan struct{}) {
+	defer close(s.done)
+	for drained := range gate {
+		for pending := len(sub.Events()); pending > 0; pending-- {
+			if _, ok := <-sub.Events(); ok {
+				s.received.Add(1)
+			}
+		}
+		close(drained)
+	}
+	for range sub.Events() {
+		s.received.Add(1)
+	}
+}
+
+func (s *soakSubscriber) fail(format string, args ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.errs) < 10 {
+		s.errs = append(s.errs, fmt.Sprintf(format, args...))
+	}
+}
+
+func (s *soakSubscriber) errors() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.errs
+}
+
+func (s *soakSubscriber) wait(t *testing.T) {
+	select {
+	case <-s.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("subscriber did not finish")
+	}
+}
+
+// countingSink counts sink events and remembers the last event type of every deployment
+type countingSink struct {
+	total atomic.Int64
+
+	mu   sync.Mutex
+	last map[string]string
+}
+
+func (c *countingSink) Name() string { return "soak-counter" }
+
+func (c *countingSink) Send(_ context.Context, event plugin.Event) error {
+	c.total.Add(1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.last[event.Namespace+"/"+event.Name] = event.Type
+	return nil
+}
+
+func (c *countingSink) notDeleted() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var keys []string
+	for key, eventType := range c.last {
+		if eventType != "DELETE" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// streamSink publishes sink events to a broadcaster
+type streamSink struct {
+	events    *stream.Broadcaster
+	published *atomic.Int64
+	mu        sync.Mutex
+	version   uint64
+}
+
+func (s *streamSink) Name() string { return "soak-stream" }
+
+func (s *streamSink) Send(_ context.Context, event plugin.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version++
+	s.events.Publish(stream.Event{
+		Type:            event.Type,
+		Kind:            event.ResourceType,
+		Namespace:       event.Namespace,
+		Name:            event.Name,
+		ResourceVersion: strconv.FormatUint(s.version, 10),
+		Time:            time.Now(),
+	})
+	s.published.Add(1)
+	return nil
+}
+
+// heapInUse returns the live heap after a full collection
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse
+}
+
+// assertNoGoroutineLeak waits for the goroutine count to return to the baseline and dumps
+// the remaining goroutines when it does not
+func assertNoGoroutineLeak(t *testing.T, baseline int) {
+	deadline := time.Now().Add(soakGoroutineTimeout)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			var dump strings.Builder
+			pprof.Lookup("goroutine").WriteTo(&dump, 1)
+			t.Errorf("%d goroutines running, %d before the pipeline started:\n%s", runtime.NumGoroutine(), baseline, dump.String())
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// gatherValue sums the metric's series in the controller-runtime registry whose labels
+// include the given ones
+func gatherValue(t *testing.T, name string, labels map[string]string) float64 {
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	var sum float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			matched := 0
+			for _, pair := range m.GetLabel() {
+				if value, ok := labels[pair.GetName()]; ok && value == pair.GetValue() {
+					matched++
+				}
+			}
+			if matched == len(labels) {
+				sum += m.GetCounter().GetValue() + m.GetGauge().GetValue()
+			}
+		}
+	}
+	return sum
+}
+
+func int32Ptr(i int32) *int32 { return &i }